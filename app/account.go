@@ -127,6 +127,9 @@ func (a *App) UpdateAccount(id string, config account.AccountConfig) (*account.A
 		}()
 	}
 
+	// Sessions logged in with the old settings
+	a.smtpPool.CloseAccount(id)
+
 	log.Info().Str("account_id", id).Msg("Account updated")
 	return acc, nil
 }
@@ -135,13 +138,10 @@ func (a *App) UpdateAccount(id string, config account.AccountConfig) (*account.A
 func (a *App) RemoveAccount(id string) error {
 	log := logging.WithComponent("app")
 
-	// Stop IDLE for this account
-	if a.idleManager != nil {
-		a.idleManager.StopAccount(id)
-	}
+	a.releaseAccount(id)
 
-	// Close any IMAP connections for this account
-	a.imapPool.CloseAccount(id)
+	// Identity credentials are keyed by identity, which the delete removes
+	identities, _ := a.accountStore.GetIdentities(id)
 
 	// Delete from database (cascades to folders, messages, etc.)
 	if err := a.accountStore.Delete(id); err != nil {
@@ -153,14 +153,30 @@ func (a *App) RemoveAccount(id string) error {
 	if err := a.credStore.DeleteAllCredentials(id); err != nil {
 		log.Warn().Err(err).Str("account_id", id).Msg("Failed to delete credentials")
 	}
+	for _, identity := range identities {
+		a.credStore.DeleteIdentitySMTPPassword(identity.ID)
+	}
 
 	// Scale database connection pool after removing account
 	a.updateDBConnectionPool()
 
+	// Release attachment content only this account referenced
+	a.blobStore.ScheduleGC()
+
 	log.Info().Str("account_id", id).Msg("Account removed")
 	return nil
 }
 
+// releaseAccount stops IDLE and closes the IMAP and SMTP connections of an
+// account that is being removed
+func (a *App) releaseAccount(id string) {
+	if a.idleManager != nil {
+		a.idleManager.StopAccount(id)
+	}
+	a.imapPool.CloseAccount(id)
+	a.smtpPool.CloseAccount(id)
+}
+
 // SetAccountEnabled enables or disables an account
 func (a *App) SetAccountEnabled(id string, enabled bool) error {
 	err := a.accountStore.SetEnabled(id, enabled)
@@ -202,17 +218,45 @@ func (a *App) GetIdentity(identityID string) (*account.Identity, error) {
 
 // CreateIdentity creates a new email identity for an account
 func (a *App) CreateIdentity(accountID string, config account.IdentityConfig) (*account.Identity, error) {
-	return a.accountStore.CreateIdentity(accountID, &config)
+	identity, err := a.accountStore.CreateIdentity(accountID, &config)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.saveIdentitySMTPPassword(identity.ID, &config); err != nil {
+		return nil, err
+	}
+	return identity, nil
 }
 
-// UpdateIdentity updates an existing identity
+// UpdateIdentity updates an existing identity. An empty SMTP password keeps
+// the stored one.
 func (a *App) UpdateIdentity(identityID string, config account.IdentityConfig) (*account.Identity, error) {
-	return a.accountStore.UpdateIdentity(identityID, &config)
+	identity, err := a.accountStore.UpdateIdentity(identityID, &config)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.saveIdentitySMTPPassword(identityID, &config); err != nil {
+		return nil, err
+	}
+	a.smtpPool.CloseAccount(identity.AccountID)
+	return identity, nil
 }
 
 // DeleteIdentity deletes an identity (cannot delete the default identity)
 func (a *App) DeleteIdentity(identityID string) error {
-	return a.accountStore.DeleteIdentity(identityID)
+	if err := a.accountStore.DeleteIdentity(identityID); err != nil {
+		return err
+	}
+	return a.credStore.DeleteIdentitySMTPPassword(identityID)
+}
+
+// saveIdentitySMTPPassword stores, keeps or removes the password of an
+// identity's own SMTP server after its settings changed
+func (a *App) saveIdentitySMTPPassword(identityID string, config *account.IdentityConfig) error {
+	if config.SMTPHost == "" || config.SMTPUsername == "" {
+		return a.credStore.DeleteIdentitySMTPPassword(identityID)
+	}
+	return a.credStore.SetIdentitySMTPPassword(identityID, config.SMTPPassword)
 }
 
 // SetDefaultIdentity sets an identity as the default for sending
@@ -304,6 +304,8 @@ func (a *App) syncFlagsToIMAP(messages []*message.Message, folderID, flagType st
 		flag = goImap.FlagSeen
 	case "starred":
 		flag = goImap.FlagFlagged
+	case "muted":
+		flag = goImap.Flag(message.MutedKeyword)
 	}
 
 	return a.withIMAPRetry(messages[0].AccountID, func(conn *imap.Client) error {
@@ -692,6 +694,8 @@ func (a *App) MarkAsSpam(messageIDs []string) error {
 		return fmt.Errorf("no spam folder configured")
 	}
 
+	a.learnFromSpamAction(messageIDs, true)
+
 	return a.MoveToFolder(messageIDs, spamFolder.ID)
 }
 
@@ -714,6 +718,8 @@ func (a *App) MarkAsNotSpam(messageIDs []string) error {
 		return fmt.Errorf("no inbox folder found")
 	}
 
+	a.learnFromSpamAction(messageIDs, false)
+
 	return a.MoveToFolder(messageIDs, inboxFolder.ID)
 }
 
@@ -0,0 +1,409 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/hkdb/aerion/internal/carddav"
+	"github.com/hkdb/aerion/internal/contact"
+	"github.com/hkdb/aerion/internal/smtp"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// WritableAddressbook is an addressbook new contacts can be created in
+type WritableAddressbook struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	SourceID   string `json:"sourceId"`
+	SourceName string `json:"sourceName"`
+}
+
+// ============================================================================
+// Address Book API - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// ListAddressBook returns all full contacts: local-only contacts and those
+// synced from CardDAV addressbooks, sorted by name
+func (a *App) ListAddressBook() ([]*contact.Card, error) {
+	cards, err := a.contactStore.ListLocalCards()
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := a.carddavStore.ListCards("")
+	if err != nil {
+		return nil, err
+	}
+	cards = append(cards, remote...)
+
+	sort.SliceStable(cards, func(i, j int) bool {
+		return strings.ToLower(cards[i].DisplayName()) < strings.ToLower(cards[j].DisplayName())
+	})
+	return cards, nil
+}
+
+// GetAddressBookContact returns a full contact by source and ID
+func (a *App) GetAddressBookContact(source, id string) (*contact.Card, error) {
+	var card *contact.Card
+	var err error
+	switch source {
+	case contact.CardSourceLocal:
+		card, err = a.contactStore.GetLocalCard(id)
+	case contact.CardSourceCardDAV:
+		card, _, err = a.carddavStore.GetCard(id)
+	default:
+		return nil, fmt.Errorf("unknown contact source: %s", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if card == nil {
+		return nil, fmt.Errorf("contact not found: %s", id)
+	}
+	return card, nil
+}
+
+// SaveAddressBookContact creates or updates a full contact. CardDAV contacts
+// are written back to the server; if the server copy changed since the last
+// sync, the result carries the server version as a conflict instead. To keep
+// the local edit, save it again with the conflict's ETag.
+func (a *App) SaveAddressBookContact(card contact.Card) (*carddav.SaveCardResult, error) {
+	switch card.Source {
+	case contact.CardSourceLocal, "":
+		saved, err := a.contactStore.SaveLocalCard(&card)
+		if err != nil {
+			return nil, err
+		}
+		return &carddav.SaveCardResult{Card: saved}, nil
+	case contact.CardSourceCardDAV:
+		return a.carddavSyncer.SaveCard(&card)
+	default:
+		return nil, fmt.Errorf("unknown contact source: %s", card.Source)
+	}
+}
+
+// DeleteAddressBookContact deletes a full contact. CardDAV contacts are only
+// deleted if they haven't changed on the server since etag was synced.
+func (a *App) DeleteAddressBookContact(source, id, etag string) error {
+	switch source {
+	case contact.CardSourceLocal:
+		return a.contactStore.DeleteLocalCard(id)
+	case contact.CardSourceCardDAV:
+		return a.carddavSyncer.DeleteCard(id, etag)
+	default:
+		return fmt.Errorf("unknown contact source: %s", source)
+	}
+}
+
+// ListWritableAddressbooks returns the enabled CardDAV addressbooks contacts
+// can be created in. Google and Microsoft sources are read-only.
+func (a *App) ListWritableAddressbooks() ([]WritableAddressbook, error) {
+	sources, err := a.carddavStore.ListSources()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []WritableAddressbook{}
+	for _, source := range sources {
+		if !source.Enabled || source.Type != carddav.SourceTypeCardDAV {
+			continue
+		}
+		addressbooks, err := a.carddavStore.ListEnabledAddressbooks(source.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ab := range addressbooks {
+			result = append(result, WritableAddressbook{
+				ID:         ab.ID,
+				Name:       ab.Name,
+				SourceID:   source.ID,
+				SourceName: source.Name,
+			})
+		}
+	}
+	return result, nil
+}
+
+// ============================================================================
+// Contact Group API - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// ListContactGroups returns all contact groups: local groups plus groups
+// synced from CardDAV (group vCards) and Google (contact groups)
+func (a *App) ListContactGroups() ([]*contact.Group, error) {
+	groups, err := a.contactStore.ListLocalGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	synced, err := a.carddavStore.SearchGroups("", -1)
+	if err != nil {
+		return nil, err
+	}
+	groups = append(groups, synced...)
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].Name) < strings.ToLower(groups[j].Name)
+	})
+	return groups, nil
+}
+
+// SaveContactGroup creates or updates a local contact group.
+// Synced groups are managed on their server.
+func (a *App) SaveContactGroup(group contact.Group) (*contact.Group, error) {
+	if group.Source != "" && group.Source != contact.GroupSourceLocal {
+		return nil, fmt.Errorf("%s groups are read-only", group.Source)
+	}
+	return a.contactStore.SaveLocalGroup(&group)
+}
+
+// DeleteContactGroup deletes a local contact group
+func (a *App) DeleteContactGroup(id string) error {
+	return a.contactStore.DeleteLocalGroup(id)
+}
+
+// ExpandContactGroup returns the members of a group as recipients, e.g. to
+// add them to Bcc so members don't see each other's addresses
+func (a *App) ExpandContactGroup(source, id string) ([]*contact.Contact, error) {
+	group, err := a.contactStore.GetGroup(source, id)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("group not found: %s", id)
+	}
+	return group.AsContact().Members, nil
+}
+
+// expandBccGroups adds the members of the groups a message is sent to as
+// Bcc to its Bcc recipients. Addresses that already are recipients are
+// skipped.
+func expandBccGroups(store *contact.Store, msg *smtp.ComposeMessage) error {
+	if len(msg.BccGroups) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for _, addr := range msg.AllRecipients() {
+		seen[strings.ToLower(addr)] = true
+	}
+
+	for _, ref := range msg.BccGroups {
+		group, err := store.GetGroup(ref.Source, ref.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load contact group: %w", err)
+		}
+		if group == nil {
+			return fmt.Errorf("contact group not found: %s", ref.ID)
+		}
+		for _, m := range group.Members {
+			key := strings.ToLower(m.Email)
+			if m.Email == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			msg.Bcc = append(msg.Bcc, smtp.Address{Name: m.DisplayName, Address: m.Email})
+		}
+	}
+	msg.BccGroups = nil
+	return nil
+}
+
+// ============================================================================
+// Import / Export / Merge API - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// PickContactsFile opens a file picker for vCard and CSV contact exports
+func (a *App) PickContactsFile() (string, error) {
+	path, err := wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Import Contacts",
+		Filters: []wailsRuntime.FileFilter{
+			{
+				DisplayName: "Contacts (*.vcf, *.vcard, *.csv)",
+				Pattern:     "*.vcf;*.vcard;*.csv",
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to open file dialog: %w", err)
+	}
+	return path, nil
+}
+
+// ImportContactsFromPath imports a .vcf file or a Google/Outlook CSV export
+// into the local address book
+func (a *App) ImportContactsFromPath(path string) (*contact.ImportResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		cards, err := contact.ParseContactsCSV(f)
+		if err != nil && len(cards) == 0 {
+			return nil, err
+		}
+		result, importErr := a.contactStore.ImportCards(cards)
+		if err != nil && result != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		return result, importErr
+	}
+
+	cards, err := contact.ParseVCards(f)
+	if err != nil && len(cards) == 0 {
+		return nil, err
+	}
+	result, importErr := a.contactStore.ImportVCards(cards)
+	if err != nil && result != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	return result, importErr
+}
+
+// ExportAddressBook exports an address book to a file chosen by the user.
+// An empty addressbookID exports the local address book. format is one of
+// "vcard3", "vcard4" or "csv". Returns the saved path, or "" if cancelled.
+func (a *App) ExportAddressBook(addressbookID, format string) (string, error) {
+	var cards []vcard.Card
+	var err error
+	name := "contacts"
+	if addressbookID == "" {
+		cards, err = a.contactStore.ListLocalVCards()
+	} else {
+		ab, abErr := a.carddavStore.GetAddressbook(addressbookID)
+		if abErr != nil {
+			return "", abErr
+		}
+		if ab == nil {
+			return "", fmt.Errorf("addressbook not found: %s", addressbookID)
+		}
+		name = ab.Name
+		cards, err = a.carddavStore.ListVCards(addressbookID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	content, err := contact.ExportCards(cards, format)
+	if err != nil {
+		return "", err
+	}
+
+	filter := wailsRuntime.FileFilter{DisplayName: "vCard (*.vcf)", Pattern: "*.vcf"}
+	ext := ".vcf"
+	if format == contact.ExportFormatCSV {
+		filter = wailsRuntime.FileFilter{DisplayName: "CSV (*.csv)", Pattern: "*.csv"}
+		ext = ".csv"
+	}
+
+	savePath, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           "Export Contacts",
+		DefaultFilename: name + ext,
+		Filters:         []wailsRuntime.FileFilter{filter},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to show save dialog: %w", err)
+	}
+	if savePath == "" {
+		return "", nil
+	}
+
+	if err := os.WriteFile(savePath, []byte(content), 0600); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	return savePath, nil
+}
+
+// FindDuplicateContacts finds contacts that share an email address across
+// the local address book, synced addressbooks and sent history
+func (a *App) FindDuplicateContacts() ([]*contact.DuplicateGroup, error) {
+	entries, err := a.contactStore.ListMergeEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	synced, err := a.carddavStore.ListMergeEntries()
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, synced...)
+
+	groups := contact.FindDuplicates(entries)
+	if groups == nil {
+		groups = []*contact.DuplicateGroup{}
+	}
+	return groups, nil
+}
+
+// PreviewContactMerge shows the merged contact and which entries a merge
+// would update, delete and leave untouched
+func (a *App) PreviewContactMerge(entries []contact.MergeEntry) (*contact.MergePreview, error) {
+	current, err := a.currentMergeEntries(entries)
+	if err != nil {
+		return nil, err
+	}
+	return contact.PreviewMerge(current)
+}
+
+// MergeContacts merges duplicate entries as shown by PreviewContactMerge:
+// the target entry is updated (written back for CardDAV contacts) and the
+// other editable entries are deleted
+func (a *App) MergeContacts(entries []contact.MergeEntry) (*contact.Card, error) {
+	current, err := a.currentMergeEntries(entries)
+	if err != nil {
+		return nil, err
+	}
+	preview, err := contact.PreviewMerge(current)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.SaveAddressBookContact(*preview.Merged)
+	if err != nil {
+		return nil, err
+	}
+	if result.Conflict != nil {
+		return nil, fmt.Errorf("%s was changed on the server, review it and merge again", result.Conflict.DisplayName())
+	}
+
+	for _, e := range preview.Removed {
+		if err := a.DeleteAddressBookContact(e.Card.Source, e.Card.ID, e.Card.ETag); err != nil {
+			return nil, fmt.Errorf("merged, but failed to delete %s: %w", e.DisplayName, err)
+		}
+	}
+	return result.Card, nil
+}
+
+// currentMergeEntries reloads the cards of address book entries so a merge
+// works on their stored state rather than what the frontend last saw. Whether
+// an entry is read-only is looked up here too, not taken from the frontend.
+func (a *App) currentMergeEntries(entries []contact.MergeEntry) ([]*contact.MergeEntry, error) {
+	current := make([]*contact.MergeEntry, 0, len(entries))
+	for i := range entries {
+		e := entries[i]
+		if e.Card == nil {
+			// Sent history and contacts synced from Google/Microsoft
+			e.ReadOnly = true
+			current = append(current, &e)
+			continue
+		}
+		card, err := a.GetAddressBookContact(e.Card.Source, e.Card.ID)
+		if err != nil {
+			return nil, err
+		}
+		readOnly := false
+		if card.Source == contact.CardSourceCardDAV {
+			if readOnly, err = a.carddavStore.IsCardReadOnly(card.ID); err != nil {
+				return nil, err
+			}
+		}
+		current = append(current, contact.CardMergeEntry(card, readOnly))
+	}
+	return current, nil
+}
@@ -10,23 +10,32 @@ import (
 
 	"github.com/hkdb/aerion/internal/account"
 	"github.com/hkdb/aerion/internal/appstate"
+	"github.com/hkdb/aerion/internal/backup"
+	"github.com/hkdb/aerion/internal/blobstore"
+	"github.com/hkdb/aerion/internal/caldav"
 	"github.com/hkdb/aerion/internal/carddav"
 	"github.com/hkdb/aerion/internal/certificate"
 	"github.com/hkdb/aerion/internal/contact"
 	"github.com/hkdb/aerion/internal/credentials"
+	"github.com/hkdb/aerion/internal/crypto"
 	"github.com/hkdb/aerion/internal/database"
 	"github.com/hkdb/aerion/internal/draft"
 	"github.com/hkdb/aerion/internal/folder"
 	"github.com/hkdb/aerion/internal/imap"
 	"github.com/hkdb/aerion/internal/ipc"
+	"github.com/hkdb/aerion/internal/localapi"
 	"github.com/hkdb/aerion/internal/logging"
 	"github.com/hkdb/aerion/internal/message"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/hkdb/aerion/internal/notification"
 	"github.com/hkdb/aerion/internal/oauth2"
 	"github.com/hkdb/aerion/internal/platform"
 	"github.com/hkdb/aerion/internal/settings"
 	"github.com/hkdb/aerion/internal/pgp"
 	"github.com/hkdb/aerion/internal/smime"
+	"github.com/hkdb/aerion/internal/smtp"
+	"github.com/hkdb/aerion/internal/spam"
+	"github.com/hkdb/aerion/internal/storage"
 	"github.com/hkdb/aerion/internal/sync"
 	"github.com/hkdb/aerion/internal/undo"
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
@@ -56,6 +65,9 @@ type App struct {
 	folderStore         *folder.Store
 	messageStore        *message.Store
 	attachmentStore     *message.AttachmentStore
+	threadMuteStore     *message.ThreadMuteStore
+	quotaStore          *account.QuotaStore
+	deliveryStore       *message.DeliveryStore
 	contactStore        *contact.Store
 	draftStore          *draft.Store
 	settingsStore       *settings.Store
@@ -64,6 +76,7 @@ type App struct {
 
 	// IMAP
 	imapPool   *imap.Pool
+	smtpPool   *smtp.Pool
 	syncEngine *sync.Engine
 
 	// Background sync (polling + IDLE)
@@ -76,11 +89,27 @@ type App struct {
 	// Certificate trust store (TOFU)
 	certStore *certificate.Store
 
+	// Profile backup and restore
+	backupManager *backup.Manager
+
+	// Storage management (cache budget and database maintenance)
+	storageManager   *storage.Manager
+	storageScheduler *storage.Scheduler
+
+	// Content-addressed attachment storage
+	blobStore            *blobstore.Store
+	blobMigrationRunning goSync.Mutex
+
 	// CardDAV
 	carddavStore     *carddav.Store
 	carddavSyncer    *carddav.Syncer
 	carddavScheduler *carddav.Scheduler
 
+	// CalDAV
+	caldavStore     *caldav.Store
+	caldavSyncer    *caldav.Syncer
+	caldavScheduler *caldav.Scheduler
+
 	// S/MIME
 	smimeStore     *smime.Store
 	smimeSigner    *smime.Signer
@@ -94,14 +123,29 @@ type App struct {
 	pgpVerifier  *pgp.Verifier
 	pgpEncryptor *pgp.Encryptor
 	pgpDecryptor *pgp.Decryptor
+	autocrypt    *pgp.Autocrypt
+
+	// Local Bayesian spam classifier
+	spamClassifier *spam.Classifier
 
 	// Undo system
 	undoStack *undo.Stack
 
+	// Optional master password encrypting message data at rest
+	fieldCipher    *crypto.FieldCipher
+	vault          *crypto.Vault // nil = no master password
+	vaultRewriting bool          // existing data is being encrypted or decrypted
+	autoLockTimer  *time.Timer
+	vaultMu        goSync.Mutex
+
 	// IPC for multi-window support (composer windows)
 	ipcServer   ipc.Server
 	ipcTokenMgr *ipc.TokenManager
 
+	// Opt-in read-only HTTP API for scripts and status bar widgets
+	localAPI   *localapi.Server
+	localAPIMu goSync.RWMutex
+
 	// OAuth2 manager
 	oauth2Manager *oauth2.Manager
 
@@ -142,6 +186,15 @@ type App struct {
 	// Desktop notifications with click handling
 	notifier notification.Notifier
 
+	// Digest-mode notifications waiting to be sent as one summary
+	notifyDigest      []*message.MessageHeader
+	notifyDigestTimer *time.Timer
+	notifyDigestMu    goSync.Mutex
+
+	// Last quota warning level per account, so each level is reported once
+	quotaLevels   map[string]string
+	quotaLevelsMu goSync.Mutex
+
 	// DebugMode function reference (injected from main)
 	debugMode func() bool
 
@@ -210,13 +263,21 @@ func (a *App) Startup(ctx context.Context) {
 	a.accountStore = account.NewStore(db)
 	a.folderStore = folder.NewStore(db)
 	a.messageStore = message.NewStore(db)
-	a.attachmentStore = message.NewAttachmentStore(db)
+	a.blobStore = blobstore.NewStore(db, paths.AttachmentBlobsPath())
+	a.attachmentStore = message.NewAttachmentStore(db, a.blobStore)
+	a.messageStore.SetDeleteCallback(a.blobStore.ScheduleGC)
+	a.threadMuteStore = message.NewThreadMuteStore(db)
+	a.quotaStore = account.NewQuotaStore(db)
+	a.deliveryStore = message.NewDeliveryStore(db)
 	a.contactStore = contact.NewStore(db.DB)
 	a.draftStore = draft.NewStore(db)
 	a.settingsStore = settings.NewStore(db)
 	a.appStateStore = appstate.NewStore(db.DB)
 	a.imageAllowlistStore = settings.NewImageAllowlistStore(db)
 
+	// Encrypt message data with the master password (if set)
+	a.initMasterPassword()
+
 	// Scale database connection pool based on number of accounts
 	a.updateDBConnectionPool()
 
@@ -229,6 +290,7 @@ func (a *App) Startup(ctx context.Context) {
 
 	// Initialize CardDAV support (will be fully set up after credStore is initialized)
 	a.carddavStore = carddav.NewStore(db.DB)
+	a.caldavStore = caldav.NewStore(db.DB)
 
 	// Initialize credential store (keyring with encrypted DB fallback)
 	credStore, err := credentials.NewStore(db.DB, paths.Data)
@@ -237,6 +299,18 @@ func (a *App) Startup(ctx context.Context) {
 	}
 	a.credStore = credStore
 
+	// Route all network connections through the configured proxy
+	netproxy.SetDefault(loadGlobalProxy(a.settingsStore, a.credStore))
+
+	// Initialize profile backup (mail cache is decrypted/encrypted with the master password)
+	a.backupManager = backup.NewManager(db.DB, a.credStore)
+	a.backupManager.SetFieldCipher(a.fieldCipher)
+	a.backupManager.SetBlobStore(a.blobStore)
+
+	// Initialize storage management
+	a.storageManager = storage.NewManager(db, a.blobStore, paths.AttachmentsPath())
+	a.storageScheduler = storage.NewScheduler(a.storageManager, a.settingsStore)
+
 	// Initialize certificate trust store (TOFU)
 	a.certStore = certificate.NewStore(db.DB)
 
@@ -244,6 +318,11 @@ func (a *App) Startup(ctx context.Context) {
 	a.smimeStore = smime.NewStore(db.DB, log)
 	a.smimeSigner = smime.NewSigner(a.smimeStore, a.credStore, log)
 	a.smimeVerifier = smime.NewVerifier(a.smimeStore, log)
+	if value, err := a.settingsStore.GetSMIMERevocationPolicy(); err == nil {
+		if policy, err := smime.ParseRevocationPolicy(value); err == nil {
+			a.smimeVerifier.SetRevocationPolicy(policy)
+		}
+	}
 	a.smimeEncryptor = smime.NewEncryptor(a.smimeStore, a.credStore, log)
 	a.smimeDecryptor = smime.NewDecryptor(a.smimeStore, a.credStore, log)
 
@@ -253,17 +332,34 @@ func (a *App) Startup(ctx context.Context) {
 	a.pgpVerifier = pgp.NewVerifier(a.pgpStore, log)
 	a.pgpEncryptor = pgp.NewEncryptor(a.pgpStore, a.credStore, log)
 	a.pgpDecryptor = pgp.NewDecryptor(a.pgpStore, a.credStore, log)
+	a.autocrypt = pgp.NewAutocrypt(a.pgpStore, log)
 
 	// Initialize IMAP connection pool
 	poolConfig := imap.DefaultPoolConfig()
 	a.imapPool = imap.NewPool(poolConfig, a.getIMAPCredentials)
 
+	// Keep authenticated SMTP sessions for messages sent in quick succession
+	a.smtpPool = smtp.NewPool(smtp.DefaultPoolConfig(), connectSMTPClient)
+
 	// Initialize sync engine
 	a.syncEngine = sync.NewEngine(a.imapPool, a.folderStore, a.messageStore, a.attachmentStore)
 
 	// Wire S/MIME and PGP verifiers into sync engine for signature verification during body parsing
 	a.syncEngine.SetSMIMEVerifier(a.smimeVerifier)
 	a.syncEngine.SetPGPVerifier(a.pgpVerifier)
+	a.syncEngine.SetAutocrypt(a.autocrypt)
+
+	// Score new inbox messages with the local spam classifier during sync
+	a.initSpamClassifier()
+
+	// Mark read / archive new messages of muted conversations during sync
+	a.syncEngine.SetThreadMutes(a.threadMuteStore, a.handleMutedMessages)
+
+	// Store server quotas refreshed during folder sync and warn when nearly full
+	a.syncEngine.SetQuotaStore(a.quotaStore, a.handleQuotaUpdate)
+
+	// Track per-recipient delivery of sent messages from synced bounces and DSNs
+	a.syncEngine.SetDeliveryStore(a.deliveryStore, a.handleDeliveryUpdate)
 
 	// Set up sync progress callback to emit events to frontend
 	a.syncEngine.SetProgressCallback(func(progress sync.SyncProgress) {
@@ -274,10 +370,12 @@ func (a *App) Startup(ctx context.Context) {
 			"total":     progress.Total,
 			"phase":     progress.Phase,
 		})
+		a.publishLocalAPIEvent(localapi.EventSyncProgress, progress)
 	})
 
-	// Start connection pool cleanup routine
+	// Start connection pool cleanup routines
 	a.imapPool.StartCleanupRoutine(ctx)
+	a.smtpPool.StartCleanupRoutine(ctx)
 
 	// Start periodic WAL checkpoint routine to prevent WAL file from growing too large
 	go a.db.StartCheckpointRoutine(ctx)
@@ -306,6 +404,7 @@ func (a *App) Startup(ctx context.Context) {
 			return a.getValidContactSourceOAuthToken(sourceID)
 		},
 	)
+	a.carddavSyncer.SetProxyGetter(a.accountProxy)
 
 	// Set up CardDAV search function for contact autocomplete
 	a.contactStore.SetCardDAVSearchFunc(func(query string, limit int) ([]*contact.Contact, error) {
@@ -324,9 +423,36 @@ func (a *App) Startup(ctx context.Context) {
 		return result, nil
 	})
 
+	// Set up synced group search so group names expand in autocomplete
+	a.contactStore.SetGroupSearchFunc(a.carddavStore.SearchGroups)
+
 	// Start CardDAV background sync scheduler
 	a.carddavScheduler.Start(ctx)
 
+	// Initialize CalDAV syncer and scheduler; Google calendars use the
+	// linked email account's OAuth token
+	a.caldavSyncer = caldav.NewSyncer(a.caldavStore, a.credStore)
+	a.caldavScheduler = caldav.NewScheduler(a.caldavSyncer, a.caldavStore)
+	if a.networkMonitor != nil {
+		a.caldavScheduler.SetConnectivityCheck(a.networkMonitor.IsConnected)
+	}
+	a.caldavSyncer.SetAccessTokenGetter(func(accountID string) (string, error) {
+		tokens, err := a.getValidOAuthToken(accountID)
+		if err != nil {
+			return "", err
+		}
+		return tokens.AccessToken, nil
+	})
+	a.caldavSyncer.SetProxyGetter(a.accountProxy)
+	a.caldavSyncer.SetConsentRequiredHandler(a.handleCalendarConsentRequired)
+	a.caldavScheduler.Start(ctx)
+
+	// Start storage scheduler (cache budget and periodic maintenance)
+	a.storageScheduler.Start(ctx)
+
+	// Move attachment content still stored in the database to the blob store
+	go a.migrateAttachmentContent()
+
 	// Initialize undo stack (max 50 commands, 30 second timeout)
 	a.undoStack = undo.NewStack(50, 30*time.Second)
 
@@ -339,6 +465,9 @@ func (a *App) Startup(ctx context.Context) {
 	// Initialize IPC for multi-window support
 	a.initIPC(ctx)
 
+	// Initialize the local API (if enabled)
+	a.initLocalAPI()
+
 	// Initialize network connectivity monitor (event-driven, zero polling).
 	// Must be initialized before background sync so scheduler and IDLE
 	// can use it to skip operations when offline.
@@ -460,9 +589,18 @@ func (a *App) Shutdown(ctx context.Context) {
 			time.Sleep(500 * time.Millisecond)
 		}
 		a.ipcServer.Stop()
+		ipc.RemoveTokenFile()
 		log.Info().Msg("IPC server stopped")
 	}
 
+	// Stop local API
+	a.stopLocalAPI()
+
+	// Stop auto-lock timer
+	a.vaultMu.Lock()
+	a.stopAutoLockTimerLocked()
+	a.vaultMu.Unlock()
+
 	// Stop email sync scheduler
 	if a.syncScheduler != nil {
 		a.syncScheduler.Stop()
@@ -493,6 +631,13 @@ func (a *App) Shutdown(ctx context.Context) {
 		log.Info().Msg("Theme monitor stopped")
 	}
 
+	// Drop any pending digest notification
+	a.notifyDigestMu.Lock()
+	if a.notifyDigestTimer != nil {
+		a.notifyDigestTimer.Stop()
+	}
+	a.notifyDigestMu.Unlock()
+
 	// Stop notification listener
 	if a.notifier != nil {
 		a.notifier.Stop()
@@ -505,11 +650,29 @@ func (a *App) Shutdown(ctx context.Context) {
 		log.Info().Msg("CardDAV scheduler stopped")
 	}
 
+	// Stop CalDAV scheduler
+	if a.caldavScheduler != nil {
+		a.caldavScheduler.Stop()
+		log.Info().Msg("CalDAV scheduler stopped")
+	}
+
+	// Stop storage scheduler
+	if a.storageScheduler != nil {
+		a.storageScheduler.Stop()
+		log.Info().Msg("Storage scheduler stopped")
+	}
+	if a.blobStore != nil {
+		a.blobStore.Close()
+	}
+
 	// Close all IMAP connections
 	if a.imapPool != nil {
 		a.imapPool.CloseAll()
 		log.Info().Msg("IMAP connections closed")
 	}
+	if a.smtpPool != nil {
+		a.smtpPool.CloseAll()
+	}
 
 	if a.db != nil {
 		a.db.Close()
@@ -559,6 +722,7 @@ func (a *App) getIMAPCredentials(accountID string) (*imap.ClientConfig, error) {
 	config.Security = imap.SecurityType(acc.IMAPSecurity)
 	config.Username = acc.Username
 	config.TLSConfig = certificate.BuildTLSConfig(acc.IMAPHost, a.certStore)
+	config.Proxy = a.accountProxy(accountID)
 
 	// Handle authentication based on auth type
 	if acc.AuthType == account.AuthOAuth2 {
@@ -614,7 +778,7 @@ func (a *App) getValidOAuthToken(accountID string) (*credentials.OAuthTokens, er
 			Msg("OAuth token expiring soon, refreshing")
 
 		// Refresh the token
-		newTokenResp, err := a.oauth2Manager.RefreshToken(tokens.Provider, tokens.RefreshToken)
+		newTokenResp, err := a.oauth2Manager.RefreshToken(tokens.Provider, tokens.RefreshToken, a.accountProxy(accountID))
 		if err != nil {
 			log.Error().Err(err).
 				Str("account_id", accountID).
@@ -676,7 +840,7 @@ func (a *App) getValidContactSourceOAuthToken(sourceID string) (string, error) {
 			Msg("Contact source OAuth token expiring soon, refreshing")
 
 		// Refresh the token
-		newTokenResp, err := a.oauth2Manager.RefreshToken(tokens.Provider, tokens.RefreshToken)
+		newTokenResp, err := a.oauth2Manager.RefreshToken(tokens.Provider, tokens.RefreshToken, nil)
 		if err != nil {
 			log.Error().Err(err).
 				Str("source_id", sourceID).
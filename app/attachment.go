@@ -347,7 +347,7 @@ func (a *App) decryptMessageBody(msg *message.Message) ([]byte, error) {
 		// Unwrap signature if present
 		ct := extractContentType(innerBytes)
 		if smime.IsSMIMESigned(ct) {
-			_, unwrapped := a.smimeVerifier.VerifyAndUnwrap(innerBytes)
+			unwrapped := a.smimeVerifier.Unwrap(innerBytes)
 			if unwrapped != nil {
 				innerBytes = unwrapped
 			}
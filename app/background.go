@@ -3,13 +3,17 @@ package app
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/hkdb/aerion/internal/folder"
 	"github.com/hkdb/aerion/internal/imap"
+	"github.com/hkdb/aerion/internal/localapi"
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/message"
 	"github.com/hkdb/aerion/internal/notification"
 	"github.com/hkdb/aerion/internal/platform"
+	"github.com/hkdb/aerion/internal/settings"
 	"github.com/hkdb/aerion/internal/sync"
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -39,11 +43,20 @@ func (a *App) initBackgroundSync(ctx context.Context) {
 				"folderId":  folderID,
 				"error":     err.Error(),
 			})
+			a.publishLocalAPIEvent(localapi.EventSyncError, map[string]interface{}{
+				"accountId": accountID,
+				"folderId":  folderID,
+				"error":     err.Error(),
+			})
 		} else {
 			wailsRuntime.EventsEmit(a.ctx, "folder:synced", map[string]interface{}{
 				"accountId": accountID,
 				"folderId":  folderID,
 			})
+			a.publishLocalAPIEvent(localapi.EventSyncComplete, map[string]interface{}{
+				"accountId": accountID,
+				"folderId":  folderID,
+			})
 		}
 	})
 
@@ -104,6 +117,14 @@ func (a *App) processIdleEvents(ctx context.Context) {
 				Uint32("count", event.Count).
 				Msg("Received IDLE event")
 
+			a.publishLocalAPIEvent(localapi.EventMailbox, map[string]interface{}{
+				"event":     event.Type.String(),
+				"accountId": event.AccountID,
+				"folder":    event.Folder,
+				"count":     event.Count,
+				"seqNum":    event.SeqNum,
+			})
+
 			switch event.Type {
 			case imap.EventNewMail:
 				// New mail arrived - trigger sync for this account's INBOX
@@ -269,7 +290,7 @@ func (a *App) handleNewMailNotification(info sync.NewMailInfo) {
 		Msg("New mail notification")
 
 	// Get the most recent conversation for the notification
-	var subject, fromName, fromEmail, threadID string
+	var subject, fromName, fromEmail string
 
 	inbox, err := a.folderStore.GetByType(info.AccountID, folder.TypeInbox)
 	if err == nil && inbox != nil {
@@ -278,7 +299,6 @@ func (a *App) handleNewMailNotification(info sync.NewMailInfo) {
 		if err == nil && len(conversations) > 0 {
 			conv := conversations[0]
 			subject = conv.Subject
-			threadID = conv.ThreadID
 			// Get sender info from participants
 			if len(conv.Participants) > 0 {
 				fromName = conv.Participants[0].Name
@@ -297,30 +317,164 @@ func (a *App) handleNewMailNotification(info sync.NewMailInfo) {
 		"fromName":    fromName,
 		"fromEmail":   fromEmail,
 	})
+	a.publishLocalAPIEvent(localapi.EventNewMail, map[string]interface{}{
+		"accountId":   info.AccountID,
+		"accountName": info.AccountName,
+		"folderId":    info.FolderID,
+		"count":       info.Count,
+		"subject":     subject,
+		"fromName":    fromName,
+		"fromEmail":   fromEmail,
+	})
+
+	// Send system notification, subject to the notification policy
+	a.notifyNewMail(info)
+}
+
+// notifyNewMail applies the notification policy to newly arrived messages
+// and sends or queues the desktop notification for them
+func (a *App) notifyNewMail(info sync.NewMailInfo) {
+	log := logging.WithComponent("app.notify")
+
+	policy, err := a.settingsStore.GetNotificationPolicy()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load notification policy, using defaults")
+	}
+
+	// The messages stored by this sync are the ones that just arrived; the
+	// Date header says nothing about arrival (delayed or backdated mail)
+	headers, err := a.messageStore.ListReceivedSince(info.FolderID, info.Since, info.Count)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get new messages for notification")
+		return
+	}
 
-	// Send system notification
-	a.sendSystemNotification(info, subject, fromName, fromEmail, threadID)
+	var immediate, digest []*message.MessageHeader
+	now := time.Now()
+	for _, h := range headers {
+		if h.IsRead {
+			continue // Already read on another device
+		}
+		if a.threadMuteStore.IsMuted(h.AccountID, h.ThreadID) {
+			continue // Muted conversations never notify
+		}
+		switch policy.Evaluate(h.AccountID, h.FolderID, h.FromEmail, now) {
+		case settings.NotificationNow:
+			immediate = append(immediate, h)
+		case settings.NotificationDigest:
+			digest = append(digest, h)
+		}
+	}
+
+	log.Debug().
+		Int("new", len(headers)).
+		Int("immediate", len(immediate)).
+		Int("digest", len(digest)).
+		Msg("Applied notification policy")
+
+	if len(immediate) > 0 {
+		a.sendSystemNotification(info.AccountName, immediate)
+	}
+	if len(digest) > 0 {
+		a.queueDigestNotification(digest, policy.Digest.Window())
+	}
 }
 
-// sendSystemNotification sends a desktop notification for new mail
-func (a *App) sendSystemNotification(info sync.NewMailInfo, subject, fromName, fromEmail, threadID string) {
+// queueDigestNotification adds messages to the pending digest. The digest
+// is sent as one notification when the window that its first message
+// opened has elapsed.
+func (a *App) queueDigestNotification(messages []*message.MessageHeader, window time.Duration) {
+	a.notifyDigestMu.Lock()
+	defer a.notifyDigestMu.Unlock()
+
+	for _, m := range messages {
+		duplicate := false
+		for _, pending := range a.notifyDigest {
+			if pending.ID == m.ID {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			a.notifyDigest = append(a.notifyDigest, m)
+		}
+	}
+
+	if a.notifyDigestTimer == nil {
+		a.notifyDigestTimer = time.AfterFunc(window, a.flushDigestNotification)
+	}
+}
+
+// flushDigestNotification sends the pending digest notification
+func (a *App) flushDigestNotification() {
+	a.notifyDigestMu.Lock()
+	pending := a.notifyDigest
+	a.notifyDigest = nil
+	a.notifyDigestTimer = nil
+	a.notifyDigestMu.Unlock()
+
+	// Drop messages that were read or muted while the digest was pending
+	ids := make([]string, len(pending))
+	for i, m := range pending {
+		ids[i] = m.ID
+	}
+	current, err := a.messageStore.GetByIDs(ids)
+	if err != nil {
+		return
+	}
+	var unread []*message.MessageHeader
+	for _, m := range current {
+		if !m.IsRead && !a.threadMuteStore.IsMuted(m.AccountID, m.ThreadID) {
+			unread = append(unread, m.ToHeader())
+		}
+	}
+
+	if len(unread) > 0 {
+		a.sendSystemNotification("", unread)
+	}
+}
+
+// sendSystemNotification sends a desktop notification for new messages.
+// accountName is shown for multi-message notifications of a single account.
+func (a *App) sendSystemNotification(accountName string, messages []*message.MessageHeader) {
 	log := logging.WithComponent("app.notify")
 
+	if len(messages) == 0 {
+		return
+	}
+	first := messages[0]
+
 	// Build notification title and body
 	var title, body string
+	var actions []notification.Action
+	messageIDs := []string{first.ID}
 
-	if info.Count == 1 && subject != "" {
+	if len(messages) == 1 {
 		// Single message notification
-		sender := fromName
+		sender := first.FromName
 		if sender == "" {
-			sender = fromEmail
+			sender = first.FromEmail
 		}
 		title = "New email from " + sender
-		body = subject
+		body = first.Subject
+
+		// Actions apply to the whole conversation
+		if first.ThreadID != "" {
+			if conv, err := a.messageStore.GetConversation(first.ThreadID, first.FolderID); err == nil && conv != nil {
+				messageIDs = conv.MessageIDs
+			}
+		}
+		actions = []notification.Action{
+			notification.ActionMarkRead,
+			notification.ActionArchive,
+			notification.ActionDelete,
+			notification.ActionReply,
+		}
 	} else {
 		// Multiple messages notification
-		title = "New emails"
-		body = info.AccountName
+		title = fmt.Sprintf("%d new emails", len(messages))
+		body = digestSummary(accountName, messages)
+		messageIDs = nil
 	}
 
 	// Use the notifier if available
@@ -330,10 +484,12 @@ func (a *App) sendSystemNotification(info sync.NewMailInfo, subject, fromName, f
 			Body:  body,
 			Icon:  "mail-unread",
 			Data: notification.NotificationData{
-				AccountID: info.AccountID,
-				FolderID:  info.FolderID,
-				ThreadID:  threadID,
+				AccountID:  first.AccountID,
+				FolderID:   first.FolderID,
+				ThreadID:   first.ThreadID,
+				MessageIDs: messageIDs,
 			},
+			Actions: actions,
 		})
 		if err != nil {
 			log.Debug().Err(err).Msg("Failed to send notification")
@@ -341,6 +497,29 @@ func (a *App) sendSystemNotification(info sync.NewMailInfo, subject, fromName, f
 	}
 }
 
+// maxSummaryLines is the number of messages listed in a multi-message notification
+const maxSummaryLines = 4
+
+// digestSummary lists the senders and subjects of several new messages
+func digestSummary(accountName string, messages []*message.MessageHeader) string {
+	var lines []string
+	if accountName != "" {
+		lines = append(lines, accountName)
+	}
+	for i, m := range messages {
+		if i == maxSummaryLines {
+			lines = append(lines, fmt.Sprintf("and %d more", len(messages)-maxSummaryLines))
+			break
+		}
+		sender := m.FromName
+		if sender == "" {
+			sender = m.FromEmail
+		}
+		lines = append(lines, sender+": "+m.Subject)
+	}
+	return strings.Join(lines, "\n")
+}
+
 // ============================================================================
 // Desktop Notifications with Click Handling
 // ============================================================================
@@ -359,16 +538,13 @@ func (a *App) initNotifications(ctx context.Context) {
 			Str("threadId", data.ThreadID).
 			Msg("Notification clicked, navigating to message")
 
-		// Bring window to foreground and unminimise if needed
-		wailsRuntime.WindowUnminimise(a.ctx)
-		wailsRuntime.WindowShow(a.ctx)
+		a.navigateToConversation(data.AccountID, data.FolderID, data.ThreadID)
+	})
 
-		// Emit event to frontend to navigate to the message
-		wailsRuntime.EventsEmit(a.ctx, "notification:clicked", map[string]interface{}{
-			"accountId": data.AccountID,
-			"folderId":  data.FolderID,
-			"threadId":  data.ThreadID,
-		})
+	// Set action handler for the notification buttons
+	a.notifier.SetActionHandler(func(action notification.Action, data notification.NotificationData, replyText string) {
+		// Runs on the notifier's signal goroutine; don't block it on IMAP/SMTP
+		go a.handleNotificationAction(action, data, replyText)
 	})
 
 	// Start the notification listener
@@ -377,6 +553,124 @@ func (a *App) initNotifications(ctx context.Context) {
 	}
 }
 
+// navigateToConversation brings the main window to the foreground and
+// opens a conversation
+func (a *App) navigateToConversation(accountID, folderID, threadID string) {
+	// Bring window to foreground and unminimise if needed
+	wailsRuntime.WindowUnminimise(a.ctx)
+	wailsRuntime.WindowShow(a.ctx)
+
+	// Emit event to frontend to navigate to the message
+	wailsRuntime.EventsEmit(a.ctx, "notification:clicked", map[string]interface{}{
+		"accountId": accountID,
+		"folderId":  folderID,
+		"threadId":  threadID,
+	})
+}
+
+// handleNotificationAction performs a notification action button without
+// bringing up the window, except for a reply without inline text
+func (a *App) handleNotificationAction(action notification.Action, data notification.NotificationData, replyText string) {
+	log := logging.WithComponent("app.notify")
+
+	var err error
+	switch action {
+	case notification.ActionMarkRead:
+		err = a.MarkAsRead(data.MessageIDs)
+	case notification.ActionArchive:
+		err = a.Archive(data.MessageIDs)
+	case notification.ActionDelete:
+		err = a.Trash(data.MessageIDs)
+	case notification.ActionReply:
+		if strings.TrimSpace(replyText) == "" {
+			// No inline reply support - show the conversation and open the
+			// reply in a composer window
+			err = a.openNotificationReply(data)
+			break
+		}
+		err = a.sendInlineReply(data, replyText)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Str("action", string(action)).Msg("Notification action failed")
+		a.notifyActionFailed(action, data, err)
+	}
+}
+
+// openNotificationReply opens a notified conversation in the main window
+// and its reply in a composer window
+func (a *App) openNotificationReply(data notification.NotificationData) error {
+	a.navigateToConversation(data.AccountID, data.FolderID, data.ThreadID)
+
+	messageID := a.latestMessageID(data.MessageIDs)
+	if messageID == "" {
+		return fmt.Errorf("no message to reply to")
+	}
+	return a.OpenComposerWindow(data.AccountID, "reply", messageID, "")
+}
+
+// notifyActionFailed tells the user that a notification action failed.
+// The window may be hidden, so this is a desktop notification; clicking it
+// opens the conversation.
+func (a *App) notifyActionFailed(action notification.Action, data notification.NotificationData, actionErr error) {
+	if a.notifier == nil {
+		return
+	}
+	log := logging.WithComponent("app.notify")
+
+	_, err := a.notifier.Show(notification.Notification{
+		Title: fmt.Sprintf("%s failed", action.Label()),
+		Body:  actionErr.Error(),
+		Icon:  "dialog-error",
+		Data: notification.NotificationData{
+			AccountID: data.AccountID,
+			FolderID:  data.FolderID,
+			ThreadID:  data.ThreadID,
+		},
+	})
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to send action failure notification")
+	}
+}
+
+// sendInlineReply sends a plain-text reply to the latest message of a
+// notified conversation and marks the conversation as read
+func (a *App) sendInlineReply(data notification.NotificationData, replyText string) error {
+	messageID := a.latestMessageID(data.MessageIDs)
+	if messageID == "" {
+		return fmt.Errorf("no message to reply to")
+	}
+
+	reply, err := a.PrepareReply(messageID, "reply")
+	if err != nil {
+		return err
+	}
+	reply.TextBody = replyText + reply.TextBody
+	reply.HTMLBody = ""
+
+	if err := a.SendMessage(data.AccountID, *reply); err != nil {
+		return err
+	}
+
+	return a.MarkAsRead(data.MessageIDs)
+}
+
+// latestMessageID returns the most recent message among the given IDs
+func (a *App) latestMessageID(messageIDs []string) string {
+	messages, err := a.messageStore.GetByIDs(messageIDs)
+	if err != nil || len(messages) == 0 {
+		return ""
+	}
+
+	latest := messages[0]
+	for _, m := range messages[1:] {
+		if m.Date.After(latest.Date) {
+			latest = m
+		}
+	}
+	return latest.ID
+}
+
 // ============================================================================
 // Network Connectivity Monitoring
 // ============================================================================
@@ -433,6 +727,9 @@ func (a *App) processNetworkEvents(ctx context.Context) {
 				if a.imapPool != nil {
 					a.imapPool.CloseAll()
 				}
+				if a.smtpPool != nil {
+					a.smtpPool.CloseAll()
+				}
 			}
 		}
 	}
@@ -500,6 +797,9 @@ func (a *App) handleSystemSleep() {
 	if a.imapPool != nil {
 		a.imapPool.CloseAll()
 	}
+	if a.smtpPool != nil {
+		a.smtpPool.CloseAll()
+	}
 
 	// Invalidate the network monitor's cached state so WaitForConnection
 	// will wait for a fresh signal on wake instead of returning immediately
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hkdb/aerion/internal/backup"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ============================================================================
+// Profile Backup - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// ExportProfileBackup writes a passphrase-encrypted backup of accounts,
+// identities, settings, keys, certificates, contact sources and optionally
+// the mail cache to a file chosen by the user. Returns the saved path, or ""
+// if cancelled.
+func (a *App) ExportProfileBackup(passphrase string, includeMail bool) (string, error) {
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase is required")
+	}
+
+	savePath, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           "Export Profile Backup",
+		DefaultFilename: "aerion-profile-" + time.Now().Format("2006-01-02") + backup.FileExtension,
+		Filters:         []wailsRuntime.FileFilter{profileBackupFilter()},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to show save dialog: %w", err)
+	}
+	if savePath == "" {
+		return "", nil
+	}
+
+	f, err := os.OpenFile(savePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+
+	err = a.backupManager.Export(f, passphrase, backup.Options{IncludeMail: includeMail})
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write file: %w", closeErr)
+	}
+	if err != nil {
+		// Don't leave a partial backup behind
+		os.Remove(savePath)
+		return "", err
+	}
+	return savePath, nil
+}
+
+// PickProfileBackupFile opens a file picker for profile backups
+func (a *App) PickProfileBackupFile() (string, error) {
+	path, err := wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title:   "Import Profile Backup",
+		Filters: []wailsRuntime.FileFilter{profileBackupFilter()},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to open file dialog: %w", err)
+	}
+	return path, nil
+}
+
+// InspectProfileBackup decrypts a backup and lists its accounts, marking
+// those that already exist, so the user can choose a conflict policy
+func (a *App) InspectProfileBackup(path, passphrase string) (*backup.Summary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	return a.backupManager.Inspect(f, passphrase)
+}
+
+// ImportProfileBackup restores a backup. conflictPolicy is "skip" to keep
+// existing accounts or "replace" to overwrite them with the backed up ones.
+func (a *App) ImportProfileBackup(path, passphrase, conflictPolicy string) (*backup.ImportResult, error) {
+	log := logging.WithComponent("app.backup")
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	result, err := a.backupManager.Import(f, passphrase, backup.ConflictPolicy(conflictPolicy), a.releaseAccount)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to import profile backup")
+		return nil, err
+	}
+	if len(result.ReplacedAccounts) > 0 {
+		// Release attachment content only the replaced accounts referenced
+		a.blobStore.ScheduleGC()
+	}
+
+	// Imported settings may include a different global proxy
+	netproxy.SetDefault(loadGlobalProxy(a.settingsStore, a.credStore))
+
+	// Bring the imported accounts online
+	a.updateDBConnectionPool()
+	for _, id := range result.ImportedAccounts {
+		acc, err := a.accountStore.Get(id)
+		if err != nil || acc == nil || !acc.Enabled {
+			continue
+		}
+		if a.idleManager != nil {
+			a.idleManager.StartAccount(acc.ID, acc.Name)
+		}
+		if a.syncScheduler != nil {
+			go a.syncScheduler.TriggerSync(acc.ID)
+		}
+	}
+
+	// Imported attachment content is stored inline in the backup
+	go a.migrateAttachmentContent()
+
+	wailsRuntime.EventsEmit(a.ctx, "profile:imported", result)
+	return result, nil
+}
+
+// profileBackupFilter is the file dialog filter for profile backups
+func profileBackupFilter() wailsRuntime.FileFilter {
+	return wailsRuntime.FileFilter{
+		DisplayName: "Aerion Profile Backup (*" + backup.FileExtension + ")",
+		Pattern:     "*" + backup.FileExtension,
+	}
+}
@@ -0,0 +1,432 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hkdb/aerion/internal/account"
+	"github.com/hkdb/aerion/internal/caldav"
+	"github.com/hkdb/aerion/internal/credentials"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/oauth2"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// LinkedCalendarAccountInfo represents an email account that can be linked to a calendar source
+type LinkedCalendarAccountInfo struct {
+	AccountID        string `json:"accountId"`
+	Email            string `json:"email"`
+	Name             string `json:"name"`
+	Provider         string `json:"provider"`         // "google"
+	IsLinked         bool   `json:"isLinked"`         // Already has calendar source linked
+	HasCalendarScope bool   `json:"hasCalendarScope"` // Has required calendar scope
+}
+
+// ============================================================================
+// CalDAV Calendar Source API - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// DiscoverCalDAVCalendars discovers available calendars from a CalDAV server
+func (a *App) DiscoverCalDAVCalendars(url, username, password string) ([]caldav.CalendarInfo, error) {
+	return caldav.DiscoverCalendars(url, username, password)
+}
+
+// TestCalDAVConnection tests connection to a CalDAV server
+func (a *App) TestCalDAVConnection(url, username, password string) error {
+	return caldav.TestConnection(url, username, password)
+}
+
+// GetCalendarSources returns all configured calendar sources
+func (a *App) GetCalendarSources() ([]*caldav.Source, error) {
+	return a.caldavStore.ListSources()
+}
+
+// GetCalendarSource returns a single calendar source with its calendars
+func (a *App) GetCalendarSource(id string) (*caldav.Source, error) {
+	return a.caldavStore.GetSourceWithCalendars(id)
+}
+
+// AddCalendarSource creates a new CalDAV calendar source with calendars
+func (a *App) AddCalendarSource(config caldav.SourceConfig) (*caldav.Source, error) {
+	log := logging.WithComponent("app")
+
+	if config.Type == "" {
+		config.Type = caldav.SourceTypeCalDAV
+	}
+	if config.Type != caldav.SourceTypeCalDAV {
+		return nil, fmt.Errorf("use LinkAccountCalendarSource for %s calendars", config.Type)
+	}
+
+	source, err := a.caldavStore.CreateSource(&config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create source: %w", err)
+	}
+
+	if config.Password != "" {
+		if err := a.credStore.SetCalDAVPassword(source.ID, config.Password); err != nil {
+			// Rollback source creation
+			a.caldavStore.DeleteSource(source.ID)
+			return nil, fmt.Errorf("failed to store password: %w", err)
+		}
+	}
+
+	a.createCalendars(source.ID, config)
+
+	// Trigger initial sync
+	go a.caldavSyncer.SyncSource(source.ID)
+
+	log.Info().Str("id", source.ID).Str("name", source.Name).Msg("Calendar source created")
+	return source, nil
+}
+
+// UpdateCalendarSource updates an existing calendar source
+func (a *App) UpdateCalendarSource(id string, config caldav.SourceConfig) error {
+	log := logging.WithComponent("app")
+
+	if err := a.caldavStore.UpdateSource(id, &config); err != nil {
+		return fmt.Errorf("failed to update source: %w", err)
+	}
+
+	if config.Password != "" {
+		if err := a.credStore.SetCalDAVPassword(id, config.Password); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+	}
+
+	// Replace calendars if provided
+	if len(config.EnabledCalendars) > 0 {
+		a.caldavStore.DeleteCalendarsForSource(id)
+		a.createCalendars(id, config)
+	}
+
+	// Trigger resync
+	go a.caldavSyncer.SyncSource(id)
+
+	log.Info().Str("id", id).Msg("Calendar source updated")
+	return nil
+}
+
+// createCalendars creates the calendars enabled in a source config, named
+// as the server reports them when discovery succeeds
+func (a *App) createCalendars(sourceID string, config caldav.SourceConfig) {
+	log := logging.WithComponent("app")
+
+	discovered := make(map[string]caldav.CalendarInfo)
+	if len(config.EnabledCalendars) > 0 && config.Password != "" {
+		if infos, err := caldav.DiscoverCalendars(config.URL, config.Username, config.Password); err == nil {
+			for _, info := range infos {
+				discovered[info.Path] = info
+			}
+		}
+	}
+
+	for _, path := range config.EnabledCalendars {
+		info, ok := discovered[path]
+		if !ok {
+			info = caldav.CalendarInfo{Path: path, Name: path}
+			if parts := strings.Split(strings.Trim(path, "/"), "/"); len(parts) > 0 {
+				info.Name = parts[len(parts)-1]
+			}
+		}
+
+		if _, err := a.caldavStore.CreateCalendar(sourceID, path, info.Name, info.Components, true); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to create calendar")
+		}
+	}
+}
+
+// DeleteCalendarSource deletes a calendar source and all its data
+func (a *App) DeleteCalendarSource(id string) error {
+	log := logging.WithComponent("app")
+
+	source, _ := a.caldavStore.GetSource(id)
+
+	// Delete from database (cascades to calendars and events)
+	if err := a.caldavStore.DeleteSource(id); err != nil {
+		return fmt.Errorf("failed to delete source: %w", err)
+	}
+
+	// Linked sources use the account's token; only CalDAV sources have a password
+	if source != nil && source.Type == caldav.SourceTypeCalDAV {
+		a.credStore.DeleteCalDAVPassword(id)
+	}
+
+	log.Info().Str("id", id).Msg("Calendar source deleted")
+	return nil
+}
+
+// GetSourceCalendars returns all calendars for a source
+func (a *App) GetSourceCalendars(sourceID string) ([]*caldav.Calendar, error) {
+	return a.caldavStore.ListCalendars(sourceID)
+}
+
+// SetCalendarEnabled enables or disables a calendar
+func (a *App) SetCalendarEnabled(calendarID string, enabled bool) error {
+	return a.caldavStore.SetCalendarEnabled(calendarID, enabled)
+}
+
+// SyncCalendarSource manually triggers a sync for a source
+func (a *App) SyncCalendarSource(id string) error {
+	return a.caldavSyncer.SyncSource(id)
+}
+
+// SyncAllCalendarSources manually triggers a sync for all sources
+func (a *App) SyncAllCalendarSources() error {
+	return a.caldavSyncer.SyncAllSources()
+}
+
+// GetCalendarSourceErrors returns all calendar sources that have errors
+func (a *App) GetCalendarSourceErrors() ([]*caldav.SourceError, error) {
+	return a.caldavStore.GetSourcesWithErrors()
+}
+
+// ClearCalendarSourceError clears the error for a calendar source
+func (a *App) ClearCalendarSourceError(id string) error {
+	return a.caldavStore.ClearSourceError(id)
+}
+
+// ============================================================================
+// Calendar Events API
+// ============================================================================
+
+// GetCalendarEvents returns the event and to-do occurrences between start
+// and end, with recurring events expanded. calendarIDs optionally restricts
+// the calendars; by default all enabled calendars are included.
+func (a *App) GetCalendarEvents(start, end time.Time, calendarIDs []string) ([]caldav.Occurrence, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("invalid date range")
+	}
+	return a.caldavStore.ListOccurrences(start, end, calendarIDs...)
+}
+
+// GetCalendarTodos returns the to-dos of all enabled calendars
+func (a *App) GetCalendarTodos() ([]*caldav.Event, error) {
+	return a.caldavStore.ListTodos()
+}
+
+// GetCalendarEvent returns a stored event by ID
+func (a *App) GetCalendarEvent(id string) (*caldav.Event, error) {
+	ev, _, err := a.caldavStore.GetEvent(id)
+	return ev, err
+}
+
+// SaveCalendarEvent creates or updates an event on its calendar server. If
+// the event changed on the server since it was synced, the edit is not
+// saved and the result's Conflict holds the server copy.
+func (a *App) SaveCalendarEvent(input caldav.EventInput) (*caldav.SaveEventResult, error) {
+	return a.caldavSyncer.SaveEvent(&input)
+}
+
+// DeleteCalendarEvent deletes an event from its calendar server. etag is
+// the version the user saw; an empty etag uses the last synced version.
+func (a *App) DeleteCalendarEvent(id, etag string) error {
+	return a.caldavSyncer.DeleteEvent(id, etag)
+}
+
+// ============================================================================
+// Linked Account Calendar API - Google Calendar via the account's OAuth token
+// ============================================================================
+
+// GetLinkedAccountsForCalendarSync returns email accounts that can be linked to calendar sources
+func (a *App) GetLinkedAccountsForCalendarSync() ([]LinkedCalendarAccountInfo, error) {
+	accounts, err := a.accountStore.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+
+	sources, err := a.caldavStore.ListSources()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sources: %w", err)
+	}
+	linkedAccountIDs := make(map[string]bool)
+	for _, source := range sources {
+		if source.AccountID != nil && *source.AccountID != "" {
+			linkedAccountIDs[*source.AccountID] = true
+		}
+	}
+
+	var result []LinkedCalendarAccountInfo
+	for _, acc := range accounts {
+		if acc.AuthType != account.AuthOAuth2 {
+			continue
+		}
+
+		// Only Google offers CalDAV with the mail token; Microsoft's Outlook
+		// token can't be used for Graph calendars (audience restriction)
+		provider, err := a.credStore.GetOAuthProvider(acc.ID)
+		if err != nil || provider != "google" {
+			continue
+		}
+
+		result = append(result, LinkedCalendarAccountInfo{
+			AccountID:        acc.ID,
+			Email:            acc.Email,
+			Name:             acc.Name,
+			Provider:         provider,
+			IsLinked:         linkedAccountIDs[acc.ID],
+			HasCalendarScope: a.hasCalendarScope(acc.ID),
+		})
+	}
+
+	return result, nil
+}
+
+// hasCalendarScope reports whether an account's OAuth token grants calendar access
+func (a *App) hasCalendarScope(accountID string) bool {
+	tokens, err := a.credStore.GetOAuthTokens(accountID)
+	if err != nil || tokens == nil {
+		return false
+	}
+	for _, scope := range tokens.Scopes {
+		if scope == oauth2.GoogleCalendarScope {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantCalendarAccess signs in to a Google account again with calendar
+// access added, which is only requested once calendar sync is wanted.
+// Opens the browser and emits calendar:access-granted or
+// calendar:access-error when the flow ends.
+func (a *App) GrantCalendarAccess(accountID string) error {
+	log := logging.WithComponent("app")
+
+	acc, err := a.accountStore.Get(accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get account: %w", err)
+	}
+	if acc == nil {
+		return fmt.Errorf("account not found: %s", accountID)
+	}
+	if acc.AuthType != account.AuthOAuth2 {
+		return fmt.Errorf("account is not an OAuth account")
+	}
+
+	provider, err := a.credStore.GetOAuthProvider(accountID)
+	if err != nil || provider == "" {
+		return fmt.Errorf("could not determine OAuth provider for account")
+	}
+	if provider != "google" {
+		return fmt.Errorf("unsupported provider for calendars: %s", provider)
+	}
+
+	config := oauth2.GoogleCalendarProvider()
+	authURL, err := a.oauth2Manager.StartAuthFlowWithProvider(a.ctx, &config)
+	if err != nil {
+		return fmt.Errorf("failed to start OAuth flow: %w", err)
+	}
+	wailsRuntime.BrowserOpenURL(a.ctx, authURL)
+
+	go func() {
+		tokens, email, err := a.oauth2Manager.WaitForCallback(a.ctx)
+		if err == nil && email != "" && !strings.EqualFold(email, acc.Email) {
+			err = fmt.Errorf("signed in as %s instead of %s", email, acc.Email)
+		}
+		if err == nil {
+			err = a.credStore.SetOAuthTokens(accountID, &credentials.OAuthTokens{
+				Provider:     provider,
+				AccessToken:  tokens.AccessToken,
+				RefreshToken: tokens.RefreshToken,
+				ExpiresAt:    time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second),
+				Scopes:       grantedScopes(tokens, config),
+			})
+		}
+		if err == nil && !a.hasCalendarScope(accountID) {
+			err = fmt.Errorf("calendar access was not granted")
+		}
+		if err != nil {
+			log.Error().Err(err).Str("accountID", accountID).Msg("Failed to grant calendar access")
+			wailsRuntime.EventsEmit(a.ctx, "calendar:access-error", map[string]interface{}{
+				"accountId": accountID,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		log.Info().Str("accountID", accountID).Msg("Calendar access granted")
+		wailsRuntime.EventsEmit(a.ctx, "calendar:access-granted", map[string]interface{}{
+			"accountId": accountID,
+		})
+
+		// Resume the sync that was waiting for access
+		if source, _ := a.caldavStore.GetSourceByAccountID(accountID); source != nil {
+			a.caldavSyncer.SyncSource(source.ID)
+		}
+	}()
+
+	return nil
+}
+
+// handleCalendarConsentRequired is called by the calendar syncer when a
+// linked account's token doesn't grant calendar access (e.g. it was
+// revoked, or the account signed in again without it)
+func (a *App) handleCalendarConsentRequired(accountID string) {
+	email := ""
+	if acc, err := a.accountStore.Get(accountID); err == nil && acc != nil {
+		email = acc.Email
+	}
+	wailsRuntime.EventsEmit(a.ctx, "calendar:consent-required", map[string]interface{}{
+		"accountId": accountID,
+		"email":     email,
+	})
+}
+
+// LinkAccountCalendarSource creates a calendar source linked to an existing
+// email account. Its calendars are discovered on the first sync.
+func (a *App) LinkAccountCalendarSource(accountID string, name string, syncInterval int) (*caldav.Source, error) {
+	log := logging.WithComponent("app")
+
+	acc, err := a.accountStore.Get(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if acc == nil {
+		return nil, fmt.Errorf("account not found: %s", accountID)
+	}
+	if acc.AuthType != account.AuthOAuth2 {
+		return nil, fmt.Errorf("account is not an OAuth account")
+	}
+
+	provider, err := a.credStore.GetOAuthProvider(accountID)
+	if err != nil || provider == "" {
+		return nil, fmt.Errorf("could not determine OAuth provider for account")
+	}
+	if provider != "google" {
+		return nil, fmt.Errorf("unsupported provider for calendars: %s", provider)
+	}
+	if !a.hasCalendarScope(accountID) {
+		return nil, fmt.Errorf("account has not granted calendar access; grant calendar access to the account first")
+	}
+
+	existing, _ := a.caldavStore.GetSourceByAccountID(accountID)
+	if existing != nil {
+		return nil, fmt.Errorf("account already has a calendar source linked")
+	}
+
+	config := caldav.SourceConfig{
+		Name:         name,
+		Type:         caldav.SourceTypeGoogle,
+		URL:          caldav.GooglePrincipalURL(acc.Email),
+		AccountID:    accountID,
+		Enabled:      true,
+		SyncInterval: syncInterval,
+	}
+
+	source, err := a.caldavStore.CreateSource(&config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create source: %w", err)
+	}
+
+	// Trigger initial sync
+	go a.caldavSyncer.SyncSource(source.ID)
+
+	log.Info().
+		Str("sourceID", source.ID).
+		Str("accountID", accountID).
+		Str("provider", provider).
+		Msg("Calendar source linked to email account")
+
+	return source, nil
+}
@@ -0,0 +1,326 @@
+package app
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/hkdb/aerion/internal/account"
+	"github.com/hkdb/aerion/internal/folder"
+	"github.com/hkdb/aerion/internal/ipc"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/message"
+	"github.com/hkdb/aerion/internal/smtp"
+)
+
+// defaultCLISearchLimit is the number of search results returned when the CLI doesn't ask for a limit
+const defaultCLISearchLimit = 20
+
+// ============================================================================
+// Command-Line Interface Requests (via IPC)
+// ============================================================================
+
+// handleCLIRequest answers a request from the command-line interface
+func (a *App) handleCLIRequest(clientID string, msg ipc.Message) {
+	log := logging.WithComponent("app.cli")
+
+	result, err := a.runCLIRequest(msg)
+
+	var reply ipc.Message
+	if err != nil {
+		log.Warn().Err(err).Str("type", msg.Type).Msg("CLI request failed")
+		reply = ipc.NewErrorReply(msg, err.Error())
+	} else if reply, err = ipc.NewReply(msg, ipc.TypeCLIResult, result); err != nil {
+		reply = ipc.NewErrorReply(msg, fmt.Sprintf("failed to encode result: %v", err))
+	}
+
+	if err := a.ipcServer.Send(clientID, reply); err != nil {
+		log.Warn().Err(err).Str("clientID", clientID).Msg("Failed to send CLI reply")
+	}
+}
+
+// runCLIRequest performs a CLI request and returns the reply payload
+func (a *App) runCLIRequest(msg ipc.Message) (interface{}, error) {
+	switch msg.Type {
+	case ipc.TypeCLISend:
+		var payload ipc.CLISendPayload
+		if err := msg.ParsePayload(&payload); err != nil {
+			return nil, fmt.Errorf("invalid send request: %w", err)
+		}
+		return nil, a.cliSend(payload)
+
+	case ipc.TypeCLISearch:
+		var payload ipc.CLISearchPayload
+		if err := msg.ParsePayload(&payload); err != nil {
+			return nil, fmt.Errorf("invalid search request: %w", err)
+		}
+		return a.cliSearch(payload)
+
+	case ipc.TypeCLISync:
+		var payload ipc.CLISyncPayload
+		if err := msg.ParsePayload(&payload); err != nil {
+			return nil, fmt.Errorf("invalid sync request: %w", err)
+		}
+		return nil, a.cliSync(payload)
+
+	case ipc.TypeCLIUnreadCount:
+		return a.cliUnreadCount()
+
+	case ipc.TypeCLIOpen:
+		var payload ipc.CLIOpenPayload
+		if err := msg.ParsePayload(&payload); err != nil {
+			return nil, fmt.Errorf("invalid open request: %w", err)
+		}
+		return nil, a.cliOpen(payload)
+	}
+
+	return nil, fmt.Errorf("unknown request: %s", msg.Type)
+}
+
+// cliSend sends a message from the account's default identity
+func (a *App) cliSend(payload ipc.CLISendPayload) error {
+	if len(payload.To)+len(payload.Cc)+len(payload.Bcc) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+
+	acc, err := a.resolveCLIAccount(payload.Account)
+	if err != nil {
+		return err
+	}
+
+	from := smtp.Address{Name: acc.Name, Address: acc.Email}
+	if identities, err := a.accountStore.GetIdentities(acc.ID); err == nil {
+		for _, identity := range identities {
+			if identity.IsDefault {
+				from = smtp.Address{Name: identity.Name, Address: identity.Email}
+				break
+			}
+		}
+	}
+
+	msg := smtp.ComposeMessage{
+		From:     from,
+		To:       cliAddresses(payload.To),
+		Cc:       cliAddresses(payload.Cc),
+		Bcc:      cliAddresses(payload.Bcc),
+		Subject:  payload.Subject,
+		TextBody: payload.Body,
+	}
+	for _, att := range payload.Attachments {
+		msg.Attachments = append(msg.Attachments, smtp.Attachment{
+			Filename:    att.Filename,
+			ContentType: att.ContentType,
+			Content:     att.Content,
+		})
+	}
+
+	return a.SendMessage(acc.ID, msg)
+}
+
+// cliSearch searches all folders of one account, or of all accounts.
+// A folder restricts the search to that folder of the account.
+func (a *App) cliSearch(payload ipc.CLISearchPayload) (*ipc.CLISearchResult, error) {
+	if strings.TrimSpace(payload.Query) == "" {
+		return nil, fmt.Errorf("search query is required")
+	}
+
+	limit := payload.Limit
+	if limit <= 0 {
+		limit = defaultCLISearchLimit
+	}
+
+	var results []*message.ConversationSearchResult
+	if payload.Folder != "" {
+		acc, err := a.resolveCLIAccount(payload.Account)
+		if err != nil {
+			return nil, err
+		}
+		f, err := a.resolveCLIFolder(acc, payload.Folder)
+		if err != nil {
+			return nil, err
+		}
+		if results, _, err = a.messageStore.SearchConversations(f.ID, payload.Query, 0, limit); err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			r.AccountID = acc.ID
+			r.FolderID = f.ID
+		}
+	} else {
+		var accountID string
+		if payload.Account != "" {
+			acc, err := a.resolveCLIAccount(payload.Account)
+			if err != nil {
+				return nil, err
+			}
+			accountID = acc.ID
+		}
+		var err error
+		if results, _, err = a.messageStore.SearchConversationsAllFolders(accountID, payload.Query, 0, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ipc.CLISearchResult{Results: []ipc.CLIConversation{}}
+	for _, r := range results {
+		var from string
+		if len(r.Participants) > 0 {
+			from = r.Participants[0].Email
+			if r.Participants[0].Name != "" {
+				from = fmt.Sprintf("%s <%s>", r.Participants[0].Name, r.Participants[0].Email)
+			}
+		}
+		result.Results = append(result.Results, ipc.CLIConversation{
+			AccountID:    r.AccountID,
+			FolderID:     r.FolderID,
+			FolderName:   r.FolderName,
+			ThreadID:     r.ThreadID,
+			Subject:      r.Subject,
+			From:         from,
+			Snippet:      r.Snippet,
+			Date:         r.LatestDate,
+			MessageCount: r.MessageCount,
+			UnreadCount:  r.UnreadCount,
+			MessageIDs:   r.MessageIDs,
+		})
+	}
+	return result, nil
+}
+
+// cliSync syncs one account, or all accounts
+func (a *App) cliSync(payload ipc.CLISyncPayload) error {
+	if payload.Account == "" {
+		return a.SyncAllComplete()
+	}
+
+	acc, err := a.resolveCLIAccount(payload.Account)
+	if err != nil {
+		return err
+	}
+	return a.SyncAccountComplete(acc.ID)
+}
+
+// cliUnreadCount returns the inbox unread count of each enabled account
+func (a *App) cliUnreadCount() (*ipc.CLIUnreadCountResult, error) {
+	accounts, err := a.accountStore.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ipc.CLIUnreadCountResult{Accounts: []ipc.CLIAccountUnread{}}
+	for _, acc := range accounts {
+		if !acc.Enabled {
+			continue
+		}
+		unread := 0
+		if inbox, err := a.folderStore.GetByType(acc.ID, folder.TypeInbox); err == nil && inbox != nil {
+			unread = inbox.UnreadCount
+		}
+		result.Accounts = append(result.Accounts, ipc.CLIAccountUnread{
+			AccountID: acc.ID,
+			Name:      acc.Name,
+			Email:     acc.Email,
+			Unread:    unread,
+		})
+		result.Total += unread
+	}
+	return result, nil
+}
+
+// cliOpen shows a message in the main window. The ID is either Aerion's
+// message ID or the message's Message-ID header.
+func (a *App) cliOpen(payload ipc.CLIOpenPayload) error {
+	m, err := a.messageStore.Get(payload.MessageID)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		id, err := a.messageStore.FindIDByMessageID(payload.MessageID)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			if m, err = a.messageStore.Get(id); err != nil {
+				return err
+			}
+		}
+	}
+	if m == nil {
+		return fmt.Errorf("message not found: %s", payload.MessageID)
+	}
+
+	threadID := m.ThreadID
+	if threadID == "" {
+		threadID = m.ID
+	}
+	a.navigateToConversation(m.AccountID, m.FolderID, threadID)
+	return nil
+}
+
+// resolveCLIAccount finds an account by ID, email address or name.
+// An empty identifier selects the first enabled account.
+func (a *App) resolveCLIAccount(identifier string) (*account.Account, error) {
+	accounts, err := a.accountStore.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, acc := range accounts {
+		if identifier == "" {
+			if acc.Enabled {
+				return acc, nil
+			}
+			continue
+		}
+		if acc.ID == identifier || strings.EqualFold(acc.Email, identifier) || strings.EqualFold(acc.Name, identifier) {
+			return acc, nil
+		}
+	}
+
+	if identifier == "" {
+		return nil, fmt.Errorf("no enabled accounts")
+	}
+	return nil, fmt.Errorf("account not found: %s", identifier)
+}
+
+// resolveCLIFolder finds an account's folder by path, name or type (e.g. "inbox")
+func (a *App) resolveCLIFolder(acc *account.Account, identifier string) (*folder.Folder, error) {
+	folders, err := a.folderStore.List(acc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+
+	for _, f := range folders {
+		if f.Path == identifier {
+			return f, nil
+		}
+	}
+	for _, f := range folders {
+		if strings.EqualFold(f.Path, identifier) || strings.EqualFold(f.Name, identifier) {
+			return f, nil
+		}
+	}
+	for _, f := range folders {
+		if f.Type != folder.TypeFolder && strings.EqualFold(string(f.Type), identifier) {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("folder not found in %s: %s", acc.Name, identifier)
+}
+
+// cliAddresses converts command-line recipients ("addr" or "Name <addr>") to addresses
+func cliAddresses(values []string) []smtp.Address {
+	var addrs []smtp.Address
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if parsed, err := mail.ParseAddress(v); err == nil {
+			addrs = append(addrs, smtp.Address{Name: parsed.Name, Address: parsed.Address})
+			continue
+		}
+		addrs = append(addrs, smtp.Address{Address: v})
+	}
+	return addrs
+}
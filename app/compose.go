@@ -2,6 +2,7 @@ package app
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -52,6 +53,18 @@ func (a *App) SendMessage(accountID string, msg smtp.ComposeMessage) error {
 		return fmt.Errorf("account not found: %s", accountID)
 	}
 
+	// Send to contact groups as Bcc
+	if err := expandBccGroups(a.contactStore, &msg); err != nil {
+		return err
+	}
+
+	// Advertise our PGP key via Autocrypt
+	if header, acErr := a.autocrypt.OutgoingHeader(accountID, msg.From.Address); acErr != nil {
+		log.Warn().Err(acErr).Msg("Failed to build Autocrypt header")
+	} else {
+		msg.Autocrypt = header
+	}
+
 	// Build RFC822 message
 	rawMsg, err := msg.ToRFC822()
 	if err != nil {
@@ -89,7 +102,7 @@ func (a *App) SendMessage(accountID string, msg smtp.ComposeMessage) error {
 	}
 
 	// PGP encryption (mutually exclusive with S/MIME — only if S/MIME encrypt was not applied)
-	if !msg.EncryptMessage && a.shouldPGPEncryptMessage(accountID, msg.PGPEncryptMessage) {
+	if !msg.EncryptMessage && a.shouldPGPEncryptMessage(accountID, msg.AllRecipients(), msg.PGPEncryptMessage) {
 		encryptedMsg, encErr := a.pgpEncryptor.EncryptMessage(accountID, msg.AllRecipients(), rawMsg)
 		if encErr != nil {
 			return fmt.Errorf("failed to PGP encrypt message: %w", encErr)
@@ -98,6 +111,156 @@ func (a *App) SendMessage(accountID string, msg smtp.ComposeMessage) error {
 		log.Info().Str("accountID", accountID).Msg("Message encrypted with PGP")
 	}
 
+	client, err := a.connectSMTP(accountID, acc, msg.From.Address)
+	if err != nil {
+		return err
+	}
+	defer a.smtpPool.Release(accountID, client)
+
+	// Send
+	recipients := msg.AllRecipients()
+	if len(recipients) == 0 {
+		return fmt.Errorf("no recipients specified")
+	}
+
+	if err := client.SendMailDSN(msg.From.Address, recipients, rawMsg, msg.DSN()); err != nil {
+		return sendFailure(err)
+	}
+	if err := a.deliveryStore.RecordSent(accountID, msg.MessageID, recipients); err != nil {
+		log.Warn().Err(err).Msg("Failed to record delivery status")
+	}
+
+	// Save to Sent folder (using IMAP APPEND) if provider doesn't auto-save
+	if !providerAutoSavesSentMail(acc.IMAPHost) {
+		log.Debug().Str("host", acc.IMAPHost).Msg("Provider doesn't auto-save, using IMAP APPEND")
+		if err := a.saveToSentFolder(accountID, acc, rawMsg); err != nil {
+			log.Warn().Err(err).Msg("Failed to save message to Sent folder")
+			// Don't fail the send operation if saving fails
+		}
+	} else {
+		log.Debug().Str("host", acc.IMAPHost).Msg("Provider auto-saves sent mail, skipping manual save")
+	}
+
+	// Add recipients to local contacts
+	for _, to := range msg.To {
+		a.contactStore.AddOrUpdate(to.Address, to.Name)
+	}
+	for _, cc := range msg.Cc {
+		a.contactStore.AddOrUpdate(cc.Address, cc.Name)
+	}
+
+	// Sync sent folder to get the sent message
+	go a.syncSentFolder(accountID)
+
+	log.Info().Str("accountID", accountID).Msg("Message sent successfully")
+	return nil
+}
+
+// MessageSizeCheck is the size of a message against the limit of the SMTP
+// server it is sent through
+type MessageSizeCheck struct {
+	Size     int64 `json:"size"`     // Estimated size as sent, in bytes
+	Limit    int64 `json:"limit"`    // Server limit in bytes, 0 if the server announces none
+	TooLarge bool  `json:"tooLarge"` // SendMessage would be rejected
+}
+
+// CheckMessageSize checks a message being composed against the size limit
+// of its SMTP server, so the composer can offer to share large attachments
+// as links or send them separately before sending fails. The session opened
+// for the check is kept for the send.
+func (a *App) CheckMessageSize(accountID string, msg smtp.ComposeMessage) (*MessageSizeCheck, error) {
+	acc, err := a.accountStore.Get(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if acc == nil {
+		return nil, fmt.Errorf("account not found: %s", accountID)
+	}
+
+	rawMsg, err := msg.ToRFC822()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build message: %w", err)
+	}
+	size := sentSize(int64(len(rawMsg)),
+		a.shouldSignMessage(accountID, msg.SignMessage) || a.shouldPGPSignMessage(accountID, msg.PGPSignMessage),
+		a.shouldEncryptMessage(accountID, msg.EncryptMessage) || a.shouldPGPEncryptMessage(accountID, msg.AllRecipients(), msg.PGPEncryptMessage))
+
+	client, err := a.connectSMTP(accountID, acc, msg.From.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer a.smtpPool.Release(accountID, client)
+
+	limit := client.MaxMessageSize()
+	return &MessageSizeCheck{
+		Size:     size,
+		Limit:    limit,
+		TooLarge: limit > 0 && size > limit,
+	}, nil
+}
+
+// SplitSendProgress is how far sending a message in parts got. Passing it
+// back to SendMessageSplit after a failure sends only the remaining parts.
+type SplitSendProgress struct {
+	MessageID string `json:"messageId"`       // Message-ID of the first part, which the others reply to
+	Sent      int    `json:"sent"`            // Parts sent so far
+	Total     int    `json:"total"`           // Number of parts
+	Error     string `json:"error,omitempty"` // Why the next part couldn't be sent
+}
+
+// SendMessageSplit sends a message that is over the SMTP server's size limit
+// as several messages, one per group of attachments that fits. The first
+// keeps the body; the others carry the remaining attachments and thread with
+// it. resume is the progress of an earlier attempt, or empty. Once a part has
+// gone out, failures are reported in the returned progress rather than as an
+// error so the composer can retry the rest.
+func (a *App) SendMessageSplit(accountID string, msg smtp.ComposeMessage, resume SplitSendProgress) (*SplitSendProgress, error) {
+	acc, err := a.accountStore.Get(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if acc == nil {
+		return nil, fmt.Errorf("account not found: %s", accountID)
+	}
+
+	client, err := a.connectSMTP(accountID, acc, msg.From.Address)
+	if err != nil {
+		return nil, err
+	}
+	limit := client.MaxMessageSize()
+	a.smtpPool.Release(accountID, client)
+
+	budget := splitBudget(limit,
+		a.shouldSignMessage(accountID, msg.SignMessage) || a.shouldPGPSignMessage(accountID, msg.PGPSignMessage),
+		a.shouldEncryptMessage(accountID, msg.EncryptMessage) || a.shouldPGPEncryptMessage(accountID, msg.AllRecipients(), msg.PGPEncryptMessage))
+	return sendSplit(msg, budget, resume, func(part smtp.ComposeMessage) error {
+		return a.SendMessage(accountID, part)
+	})
+}
+
+// connectSMTP returns an authenticated session with the SMTP server used to
+// send from an address, reusing an idle one from the pool. The caller must
+// give it back with a.smtpPool.Release.
+func (a *App) connectSMTP(accountID string, acc *account.Account, from string) (*smtp.Client, error) {
+	smtpConfig, err := a.smtpConfigFor(accountID, acc, from)
+	if err != nil {
+		return nil, err
+	}
+	return a.smtpPool.Get(accountID, smtpConfig)
+}
+
+// smtpConfigFor builds the config of the SMTP server used to send from an
+// address: the identity's own server if it has one, else the account's
+func (a *App) smtpConfigFor(accountID string, acc *account.Account, from string) (smtp.ClientConfig, error) {
+	// Aliases with their own SMTP server send through it
+	identities, err := a.accountStore.GetIdentities(accountID)
+	if err != nil {
+		return smtp.ClientConfig{}, fmt.Errorf("failed to get identities: %w", err)
+	}
+	if identity := identityForAddress(identities, from); identity != nil && identity.HasSMTPServer() {
+		return identitySMTPConfig(identity, a.credStore, a.certStore, a.accountProxy(accountID))
+	}
+
 	// Create SMTP client config
 	smtpConfig := smtp.DefaultConfig()
 	smtpConfig.Host = acc.SMTPHost
@@ -105,13 +268,14 @@ func (a *App) SendMessage(accountID string, msg smtp.ComposeMessage) error {
 	smtpConfig.Security = smtp.SecurityType(acc.SMTPSecurity)
 	smtpConfig.Username = acc.Username
 	smtpConfig.TLSConfig = certificate.BuildTLSConfig(acc.SMTPHost, a.certStore)
+	smtpConfig.Proxy = a.accountProxy(accountID)
 
 	// Handle authentication based on auth type
 	if acc.AuthType == account.AuthOAuth2 {
 		// Get valid OAuth token (refreshing if needed)
 		tokens, err := a.getValidOAuthToken(accountID)
 		if err != nil {
-			return fmt.Errorf("failed to get OAuth token: %w", err)
+			return smtpConfig, fmt.Errorf("failed to get OAuth token: %w", err)
 		}
 		smtpConfig.AuthType = smtp.AuthTypeOAuth2
 		smtpConfig.AccessToken = tokens.AccessToken
@@ -119,59 +283,90 @@ func (a *App) SendMessage(accountID string, msg smtp.ComposeMessage) error {
 		// Default to password authentication
 		password, err := a.credStore.GetPassword(accountID)
 		if err != nil {
-			return fmt.Errorf("failed to get password: %w", err)
+			return smtpConfig, fmt.Errorf("failed to get password: %w", err)
 		}
 		smtpConfig.AuthType = smtp.AuthTypePassword
 		smtpConfig.Password = password
 	}
 
-	client := smtp.NewClient(smtpConfig)
+	return smtpConfig, nil
+}
 
-	// Connect
-	if err := client.Connect(); err != nil {
-		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+// sendFailure wraps an SMTP send error. Oversized messages are rejected
+// before upload, with a hint on how to get the attachments through.
+func sendFailure(err error) error {
+	var tooLarge *smtp.MessageTooLargeError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("%w. Remove attachments, share large files as links, or send them in separate messages", err)
 	}
-	defer client.Close()
+	return fmt.Errorf("failed to send message: %w", err)
+}
 
-	// Login
-	if err := client.Login(); err != nil {
-		return fmt.Errorf("failed to login to SMTP server: %w", err)
-	}
+// signatureOverhead is the room reserved for a signature part and its
+// certificates when estimating the size of a signed message
+const signatureOverhead = 16 * 1024
 
-	// Send
-	recipients := msg.AllRecipients()
-	if len(recipients) == 0 {
-		return fmt.Errorf("no recipients specified")
+// sentSize estimates the size of a message once signed and encrypted
+func sentSize(size int64, sign, encrypt bool) int64 {
+	if sign {
+		size += signatureOverhead
+	}
+	if encrypt {
+		// Encryption wraps the message in another base64 layer
+		size = size * 4 / 3
 	}
+	return size
+}
 
-	if err := client.SendMail(msg.From.Address, recipients, rawMsg); err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+// splitBudget returns the size each part of a split message may have before
+// signing and encryption, so that it is within limit once sent. It is the
+// inverse of sentSize.
+func splitBudget(limit int64, sign, encrypt bool) int64 {
+	if limit <= 0 {
+		return 0
+	}
+	if encrypt {
+		limit = limit * 3 / 4
 	}
+	if sign {
+		limit -= signatureOverhead
+	}
+	return limit
+}
 
-	// Save to Sent folder (using IMAP APPEND) if provider doesn't auto-save
-	if !providerAutoSavesSentMail(acc.IMAPHost) {
-		log.Debug().Str("host", acc.IMAPHost).Msg("Provider doesn't auto-save, using IMAP APPEND")
-		if err := a.saveToSentFolder(accountID, acc, rawMsg); err != nil {
-			log.Warn().Err(err).Msg("Failed to save message to Sent folder")
-			// Don't fail the send operation if saving fails
-		}
-	} else {
-		log.Debug().Str("host", acc.IMAPHost).Msg("Provider auto-saves sent mail, skipping manual save")
+// sendSplit splits a message into parts of at most budget bytes and sends
+// the parts resume hasn't sent yet in order with send, stopping at the first
+// failure. A failure before any part went out is returned as an error.
+func sendSplit(msg smtp.ComposeMessage, budget int64, resume SplitSendProgress, send func(smtp.ComposeMessage) error) (*SplitSendProgress, error) {
+	// Later parts must keep replying to the first one
+	if resume.MessageID != "" {
+		msg.MessageID = resume.MessageID
 	}
 
-	// Add recipients to local contacts
-	for _, to := range msg.To {
-		a.contactStore.AddOrUpdate(to.Address, to.Name)
+	parts, err := smtp.SplitMessage(msg, budget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split message: %w", err)
 	}
-	for _, cc := range msg.Cc {
-		a.contactStore.AddOrUpdate(cc.Address, cc.Name)
+	if resume.Total > 0 && resume.Total != len(parts) {
+		return nil, fmt.Errorf("the message changed after %d of %d messages were sent", resume.Sent, resume.Total)
 	}
 
-	// Sync sent folder to get the sent message
-	go a.syncSentFolder(accountID)
-
-	log.Info().Str("accountID", accountID).Msg("Message sent successfully")
-	return nil
+	progress := &SplitSendProgress{
+		MessageID: parts[0].MessageID,
+		Sent:      min(max(resume.Sent, 0), len(parts)),
+		Total:     len(parts),
+	}
+	for _, part := range parts[progress.Sent:] {
+		if err := send(part); err != nil {
+			if progress.Sent == 0 {
+				return nil, err
+			}
+			progress.Error = err.Error()
+			return progress, nil
+		}
+		progress.Sent++
+	}
+	return progress, nil
 }
 
 // syncSentFolder syncs the Sent folder for an account after sending a message
@@ -245,6 +440,7 @@ func (a *App) saveToSentFolder(accountID string, acc *account.Account, rawMsg []
 	clientConfig.Security = imap.SecurityType(acc.IMAPSecurity)
 	clientConfig.Username = acc.Username
 	clientConfig.TLSConfig = certificate.BuildTLSConfig(acc.IMAPHost, a.certStore)
+	clientConfig.Proxy = a.accountProxy(accountID)
 
 	// Handle authentication based on auth type
 	if acc.AuthType == account.AuthOAuth2 {
@@ -309,17 +505,8 @@ func (a *App) PrepareReply(messageID, mode string) (*smtp.ComposeMessage, error)
 		return nil, fmt.Errorf("failed to get identities: %w", err)
 	}
 
-	// Find the default identity or first identity
-	var fromIdentity *account.Identity
-	for _, id := range identities {
-		if id.IsDefault {
-			fromIdentity = id
-			break
-		}
-	}
-	if fromIdentity == nil && len(identities) > 0 {
-		fromIdentity = identities[0]
-	}
+	// Reply from the alias the original was addressed to, else the default identity
+	fromIdentity := replyIdentity(identities, msg)
 	if fromIdentity == nil {
 		acc, _ := a.accountStore.Get(msg.AccountID)
 		if acc != nil {
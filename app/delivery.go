@@ -0,0 +1,65 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/message"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// DeliveryUpdate is emitted when delivery reports change the state of a sent message
+type DeliveryUpdate struct {
+	AccountID string                    `json:"accountId"`
+	MessageID string                    `json:"messageId"` // Message-ID header of the sent message
+	Statuses  []*message.DeliveryStatus `json:"statuses"`  // Only the recipients that changed
+}
+
+// ============================================================================
+// Delivery Status - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// GetDeliveryStatus returns the delivery state of a sent message by
+// recipient: "sent" once the server accepted it, then "delayed",
+// "delivered", "relayed", "expanded" or "failed" (with the server's
+// diagnostic, e.g. "550 5.1.1 user unknown") as delivery reports arrive.
+// Empty if nothing is known about the message's delivery.
+func (a *App) GetDeliveryStatus(messageID string) ([]*message.DeliveryStatus, error) {
+	msg, err := a.messageStore.Get(messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if msg == nil {
+		return nil, fmt.Errorf("message not found: %s", messageID)
+	}
+	if msg.MessageID == "" {
+		return []*message.DeliveryStatus{}, nil
+	}
+	return a.deliveryStore.GetByMessageID(msg.AccountID, msg.MessageID)
+}
+
+// ============================================================================
+// Delivery reports
+// ============================================================================
+
+// handleDeliveryUpdate is called by the sync engine when a delivery report
+// changed the state of a sent message
+func (a *App) handleDeliveryUpdate(accountID, messageID string, statuses []*message.DeliveryStatus) {
+	log := logging.WithComponent("app.delivery")
+
+	for _, s := range statuses {
+		log.Info().
+			Str("accountID", accountID).
+			Str("messageID", messageID).
+			Str("recipient", s.Recipient).
+			Str("state", s.State).
+			Str("status", s.Status).
+			Msg("Delivery status updated")
+	}
+
+	wailsRuntime.EventsEmit(a.ctx, "delivery:updated", DeliveryUpdate{
+		AccountID: accountID,
+		MessageID: messageID,
+		Statuses:  statuses,
+	})
+}
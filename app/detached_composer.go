@@ -13,6 +13,7 @@ import (
 
 	goImap "github.com/emersion/go-imap/v2"
 	"github.com/hkdb/aerion/internal/account"
+	"github.com/hkdb/aerion/internal/carddav"
 	"github.com/hkdb/aerion/internal/certificate"
 	"github.com/hkdb/aerion/internal/contact"
 	"github.com/hkdb/aerion/internal/credentials"
@@ -23,6 +24,7 @@ import (
 	"github.com/hkdb/aerion/internal/ipc"
 	"github.com/hkdb/aerion/internal/logging"
 	"github.com/hkdb/aerion/internal/message"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/hkdb/aerion/internal/oauth2"
 	"github.com/hkdb/aerion/internal/platform"
 	"github.com/hkdb/aerion/internal/settings"
@@ -67,6 +69,7 @@ type ComposerApp struct {
 	accountStore  *account.Store
 	folderStore   *folder.Store
 	messageStore  *message.Store
+	deliveryStore *message.DeliveryStore
 	contactStore  *contact.Store
 	draftStore    *draft.Store
 	credStore     *credentials.Store
@@ -90,6 +93,7 @@ type ComposerApp struct {
 	pgpSigner    *pgp.Signer
 	pgpEncryptor *pgp.Encryptor
 	pgpDecryptor *pgp.Decryptor
+	autocrypt    *pgp.Autocrypt
 
 	// Paths
 	paths *platform.Paths
@@ -147,7 +151,9 @@ func (c *ComposerApp) Startup(ctx context.Context) {
 	c.accountStore = account.NewStore(db)
 	c.folderStore = folder.NewStore(db)
 	c.messageStore = message.NewStore(db)
+	c.deliveryStore = message.NewDeliveryStore(db)
 	c.contactStore = contact.NewStore(db.DB)
+	c.contactStore.SetGroupSearchFunc(carddav.NewStore(db.DB).SearchGroups)
 	c.draftStore = draft.NewStore(db)
 	c.settingsStore = settings.NewStore(db)
 
@@ -158,6 +164,9 @@ func (c *ComposerApp) Startup(ctx context.Context) {
 	}
 	c.credStore = credStore
 
+	// Route all network connections through the configured proxy
+	netproxy.SetDefault(loadGlobalProxy(c.settingsStore, c.credStore))
+
 	// Initialize certificate trust store (TOFU)
 	c.certStore = certificate.NewStore(db.DB)
 
@@ -172,6 +181,7 @@ func (c *ComposerApp) Startup(ctx context.Context) {
 	c.pgpSigner = pgp.NewSigner(c.pgpStore, credStore, log)
 	c.pgpEncryptor = pgp.NewEncryptor(c.pgpStore, credStore, log)
 	c.pgpDecryptor = pgp.NewDecryptor(c.pgpStore, credStore, log)
+	c.autocrypt = pgp.NewAutocrypt(c.pgpStore, log)
 
 	// Initialize IMAP pool for send/draft operations
 	poolConfig := imap.DefaultPoolConfig()
@@ -440,6 +450,7 @@ func (c *ComposerApp) getIMAPCredentials(accountID string) (*imap.ClientConfig,
 	config.Security = imap.SecurityType(acc.IMAPSecurity)
 	config.Username = acc.Username
 	config.TLSConfig = certificate.BuildTLSConfig(acc.IMAPHost, c.certStore)
+	config.Proxy = loadAccountProxy(c.accountStore, c.credStore, accountID)
 
 	// Handle authentication based on auth type
 	if acc.AuthType == account.AuthOAuth2 {
@@ -480,7 +491,7 @@ func (c *ComposerApp) getValidOAuthToken(accountID string) (*credentials.OAuthTo
 			Msg("OAuth token expiring soon, refreshing")
 
 		// Refresh the token
-		newTokenResp, err := c.oauth2Manager.RefreshToken(tokens.Provider, tokens.RefreshToken)
+		newTokenResp, err := c.oauth2Manager.RefreshToken(tokens.Provider, tokens.RefreshToken, loadAccountProxy(c.accountStore, c.credStore, accountID))
 		if err != nil {
 			log.Error().Err(err).
 				Str("account_id", accountID).
@@ -627,6 +638,17 @@ func (c *ComposerApp) SearchContacts(query string, limit int) ([]*contact.Contac
 
 // SendMessage sends the composed email.
 func (c *ComposerApp) SendMessage(msg smtp.ComposeMessage) error {
+	if err := c.deliverMessage(msg); err != nil {
+		return err
+	}
+	c.finishSend()
+	return nil
+}
+
+// deliverMessage signs, encrypts and sends a message, saves it to the Sent
+// folder and records its recipients. The draft is left alone, so a message
+// sent in parts keeps it until every part went out.
+func (c *ComposerApp) deliverMessage(msg smtp.ComposeMessage) error {
 	log := logging.WithComponent("composer")
 
 	log.Info().
@@ -641,6 +663,18 @@ func (c *ComposerApp) SendMessage(msg smtp.ComposeMessage) error {
 		return fmt.Errorf("failed to get account: %w", err)
 	}
 
+	// Send to contact groups as Bcc
+	if err := expandBccGroups(c.contactStore, &msg); err != nil {
+		return err
+	}
+
+	// Advertise our PGP key via Autocrypt
+	if header, acErr := c.autocrypt.OutgoingHeader(c.config.AccountID, msg.From.Address); acErr != nil {
+		log.Warn().Err(acErr).Msg("Failed to build Autocrypt header")
+	} else {
+		msg.Autocrypt = header
+	}
+
 	// Build RFC822 message
 	rawMsg, err := msg.ToRFC822()
 	if err != nil {
@@ -678,7 +712,7 @@ func (c *ComposerApp) SendMessage(msg smtp.ComposeMessage) error {
 	}
 
 	// PGP encryption (mutually exclusive with S/MIME)
-	if !msg.EncryptMessage && c.shouldPGPEncryptMessage(msg.PGPEncryptMessage) {
+	if !msg.EncryptMessage && c.shouldPGPEncryptMessage(msg.AllRecipients(), msg.PGPEncryptMessage) {
 		encryptedMsg, encErr := c.pgpEncryptor.EncryptMessage(c.config.AccountID, msg.AllRecipients(), rawMsg)
 		if encErr != nil {
 			return fmt.Errorf("failed to PGP encrypt message: %w", encErr)
@@ -687,51 +721,27 @@ func (c *ComposerApp) SendMessage(msg smtp.ComposeMessage) error {
 		log.Info().Str("accountID", c.config.AccountID).Msg("Message encrypted with PGP")
 	}
 
-	// Create SMTP client config
-	smtpConfig := smtp.DefaultConfig()
-	smtpConfig.Host = acc.SMTPHost
-	smtpConfig.Port = acc.SMTPPort
-	smtpConfig.Security = smtp.SecurityType(acc.SMTPSecurity)
-	smtpConfig.Username = acc.Username
-	smtpConfig.TLSConfig = certificate.BuildTLSConfig(acc.SMTPHost, c.certStore)
-
-	// Handle authentication based on auth type
-	if acc.AuthType == account.AuthOAuth2 {
-		// Get valid OAuth token (refreshing if needed)
-		tokens, err := c.getValidOAuthToken(c.config.AccountID)
-		if err != nil {
-			return fmt.Errorf("failed to get OAuth token: %w", err)
-		}
-		smtpConfig.AuthType = smtp.AuthTypeOAuth2
-		smtpConfig.AccessToken = tokens.AccessToken
-	} else {
-		// Default to password authentication
-		password, err := c.credStore.GetPassword(c.config.AccountID)
-		if err != nil {
-			return fmt.Errorf("failed to get password: %w", err)
-		}
-		smtpConfig.AuthType = smtp.AuthTypePassword
-		smtpConfig.Password = password
+	smtpConfig, err := c.smtpConfig(acc, msg.From.Address)
+	if err != nil {
+		return err
 	}
 
-	client := smtp.NewClient(smtpConfig)
-
-	if err := client.Connect(); err != nil {
-		return fmt.Errorf("failed to connect to SMTP: %w", err)
+	client, err := connectSMTPClient(smtpConfig)
+	if err != nil {
+		return err
 	}
 	defer client.Close()
 
-	if err := client.Login(); err != nil {
-		return fmt.Errorf("failed to login to SMTP: %w", err)
-	}
-
 	recipients := msg.AllRecipients()
 	if len(recipients) == 0 {
 		return fmt.Errorf("no recipients")
 	}
 
-	if err := client.SendMail(msg.From.Address, recipients, rawMsg); err != nil {
-		return fmt.Errorf("failed to send: %w", err)
+	if err := client.SendMailDSN(msg.From.Address, recipients, rawMsg, msg.DSN()); err != nil {
+		return sendFailure(err)
+	}
+	if err := c.deliveryStore.RecordSent(c.config.AccountID, msg.MessageID, recipients); err != nil {
+		log.Warn().Err(err).Msg("Failed to record delivery status")
 	}
 
 	// Save to Sent folder if provider doesn't auto-save
@@ -752,6 +762,12 @@ func (c *ComposerApp) SendMessage(msg smtp.ComposeMessage) error {
 		c.contactStore.AddOrUpdate(cc.Address, cc.Name)
 	}
 
+	log.Info().Msg("Message sent successfully")
+	return nil
+}
+
+// finishSend deletes the draft that was sent and tells the main window
+func (c *ComposerApp) finishSend() {
 	// Delete draft if we were editing one
 	if c.currentDraft != nil {
 		c.draftStore.Delete(c.currentDraft.ID)
@@ -766,9 +782,82 @@ func (c *ComposerApp) SendMessage(msg smtp.ComposeMessage) error {
 
 	// Notify main window
 	c.notifyMessageSent(sentFolderID)
+}
 
-	log.Info().Msg("Message sent successfully")
-	return nil
+// SendMessageSplit sends a message that is over the SMTP server's size limit
+// as several messages, one per group of attachments that fits (see
+// App.SendMessageSplit). The draft is deleted once every part was sent.
+func (c *ComposerApp) SendMessageSplit(msg smtp.ComposeMessage, resume SplitSendProgress) (*SplitSendProgress, error) {
+	acc, err := c.accountStore.Get(c.config.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if acc == nil {
+		return nil, fmt.Errorf("account not found: %s", c.config.AccountID)
+	}
+
+	smtpConfig, err := c.smtpConfig(acc, msg.From.Address)
+	if err != nil {
+		return nil, err
+	}
+	client, err := connectSMTPClient(smtpConfig)
+	if err != nil {
+		return nil, err
+	}
+	limit := client.MaxMessageSize()
+	client.Close()
+
+	budget := splitBudget(limit,
+		c.shouldSignMessage(msg.SignMessage) || c.shouldPGPSignMessage(msg.PGPSignMessage),
+		c.shouldEncryptMessage(msg.EncryptMessage) || c.shouldPGPEncryptMessage(msg.AllRecipients(), msg.PGPEncryptMessage))
+	progress, err := sendSplit(msg, budget, resume, c.deliverMessage)
+	if err != nil {
+		return nil, err
+	}
+	if progress.Sent == progress.Total {
+		c.finishSend()
+	}
+	return progress, nil
+}
+
+// smtpConfig builds the config of the SMTP server used to send from an
+// address: the identity's own server if it has one, else the account's
+func (c *ComposerApp) smtpConfig(acc *account.Account, from string) (smtp.ClientConfig, error) {
+	// Aliases with their own SMTP server send through it
+	proxy := loadAccountProxy(c.accountStore, c.credStore, c.config.AccountID)
+	identities, _ := c.accountStore.GetIdentities(c.config.AccountID)
+	if identity := identityForAddress(identities, from); identity != nil && identity.HasSMTPServer() {
+		return identitySMTPConfig(identity, c.credStore, c.certStore, proxy)
+	}
+
+	smtpConfig := smtp.DefaultConfig()
+	smtpConfig.Host = acc.SMTPHost
+	smtpConfig.Port = acc.SMTPPort
+	smtpConfig.Security = smtp.SecurityType(acc.SMTPSecurity)
+	smtpConfig.Username = acc.Username
+	smtpConfig.TLSConfig = certificate.BuildTLSConfig(acc.SMTPHost, c.certStore)
+	smtpConfig.Proxy = proxy
+
+	// Handle authentication based on auth type
+	if acc.AuthType == account.AuthOAuth2 {
+		// Get valid OAuth token (refreshing if needed)
+		tokens, err := c.getValidOAuthToken(c.config.AccountID)
+		if err != nil {
+			return smtpConfig, fmt.Errorf("failed to get OAuth token: %w", err)
+		}
+		smtpConfig.AuthType = smtp.AuthTypeOAuth2
+		smtpConfig.AccessToken = tokens.AccessToken
+	} else {
+		// Default to password authentication
+		password, err := c.credStore.GetPassword(c.config.AccountID)
+		if err != nil {
+			return smtpConfig, fmt.Errorf("failed to get password: %w", err)
+		}
+		smtpConfig.AuthType = smtp.AuthTypePassword
+		smtpConfig.Password = password
+	}
+
+	return smtpConfig, nil
 }
 
 // saveToSentFolder appends the sent message to the Sent folder via IMAP.
@@ -801,6 +890,7 @@ func (c *ComposerApp) saveToSentFolder(acc *account.Account, rawMsg []byte) erro
 	clientConfig.Security = imap.SecurityType(acc.IMAPSecurity)
 	clientConfig.Username = acc.Username
 	clientConfig.TLSConfig = certificate.BuildTLSConfig(acc.IMAPHost, c.certStore)
+	clientConfig.Proxy = loadAccountProxy(c.accountStore, c.credStore, acc.ID)
 
 	// Handle authentication
 	if acc.AuthType == account.AuthOAuth2 {
@@ -1343,18 +1433,9 @@ func (c *ComposerApp) draftToComposeMessage(d *draft.Draft) *smtp.ComposeMessage
 // buildReplyMessage builds a compose message for reply/forward.
 // This is a simplified version of the logic in app.go PrepareReply.
 func (c *ComposerApp) buildReplyMessage(msg *message.Message, mode string) *smtp.ComposeMessage {
-	// Get default identity
+	// Reply from the alias the original was addressed to, else the default identity
 	identities, _ := c.accountStore.GetIdentities(c.config.AccountID)
-	var fromIdentity *account.Identity
-	for _, id := range identities {
-		if id.IsDefault {
-			fromIdentity = id
-			break
-		}
-	}
-	if fromIdentity == nil && len(identities) > 0 {
-		fromIdentity = identities[0]
-	}
+	fromIdentity := replyIdentity(identities, msg)
 
 	from := smtp.Address{}
 	if fromIdentity != nil {
@@ -1504,7 +1585,13 @@ func (c *ComposerApp) ImportRecipientCert(email, filePath string) error {
 // HasPGPKey returns whether the account has a valid default PGP key.
 func (c *ComposerApp) HasPGPKey() bool {
 	key, _, err := c.pgpStore.GetDefaultKey(c.config.AccountID)
-	return err == nil && key != nil && !key.IsExpired
+	return err == nil && key != nil && !key.IsExpired && key.RevokedAt == nil
+}
+
+// UnlockPGPKey unlocks a passphrase-protected key for signing in this
+// composer window.
+func (c *ComposerApp) UnlockPGPKey(keyID, passphrase string) error {
+	return pgp.UnlockKey(c.credStore, keyID, passphrase)
 }
 
 // GetPGPSignPolicy returns the PGP signing policy for the account.
@@ -1648,17 +1735,30 @@ func (c *ComposerApp) shouldPGPSignMessage(perMessageOverride bool) bool {
 }
 
 // shouldPGPEncryptMessage determines whether a message should be PGP encrypted.
-func (c *ComposerApp) shouldPGPEncryptMessage(perMessageOverride bool) bool {
+// With the "auto" policy, the Autocrypt recommendation decides.
+func (c *ComposerApp) shouldPGPEncryptMessage(recipients []string, perMessageOverride bool) bool {
 	if perMessageOverride {
 		return c.HasPGPKey()
 	}
 
 	policy, err := c.pgpStore.GetEncryptPolicy(c.config.AccountID)
-	if err != nil || policy != "always" {
+	if err != nil {
 		return false
 	}
+	switch policy {
+	case "always":
+		return c.HasPGPKey()
+	case "auto":
+		return c.HasPGPKey() && autocryptSuggestsEncryption(c.autocrypt, c.config.AccountID, recipients)
+	default:
+		return false
+	}
+}
 
-	return c.HasPGPKey()
+// GetAutocryptRecommendation returns the Autocrypt encryption recommendation
+// for the given recipients, used to pre-select PGP encryption in the composer
+func (c *ComposerApp) GetAutocryptRecommendation(recipients []string) (*pgp.AutocryptRecommendation, error) {
+	return c.autocrypt.Recommend(c.config.AccountID, recipients)
 }
 
 // shouldSignMessage determines whether a message should be S/MIME signed.
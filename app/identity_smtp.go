@@ -0,0 +1,169 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hkdb/aerion/internal/account"
+	"github.com/hkdb/aerion/internal/certificate"
+	"github.com/hkdb/aerion/internal/credentials"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/message"
+	"github.com/hkdb/aerion/internal/netproxy"
+	"github.com/hkdb/aerion/internal/smtp"
+)
+
+// ============================================================================
+// Identity SMTP - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// TestIdentitySMTPConnection tests the SMTP server settings of an identity.
+// An empty password uses the identity's stored password (identityID may be
+// empty for an identity that isn't saved yet).
+func (a *App) TestIdentitySMTPConnection(accountID, identityID string, config account.IdentityConfig) error {
+	log := logging.WithComponent("app")
+
+	if config.Email == "" {
+		config.Email = "test@example.com"
+	}
+	if config.Name == "" {
+		config.Name = "Test"
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if config.SMTPHost == "" {
+		return fmt.Errorf("SMTP host is required")
+	}
+
+	password := config.SMTPPassword
+	if password == "" && config.SMTPUsername != "" && identityID != "" {
+		password, _ = a.credStore.GetIdentitySMTPPassword(identityID)
+	}
+
+	smtpConfig := identitySMTPClientConfig(&account.Identity{
+		SMTPHost:     config.SMTPHost,
+		SMTPPort:     config.SMTPPort,
+		SMTPSecurity: config.SMTPSecurity,
+		SMTPUsername: config.SMTPUsername,
+	}, a.certStore, a.accountProxy(accountID))
+	smtpConfig.Password = password
+
+	client, err := connectSMTPClient(smtpConfig)
+	if err != nil {
+		log.Error().Err(err).Str("host", config.SMTPHost).Msg("Identity SMTP connection test failed")
+		return err
+	}
+	client.Close()
+
+	log.Info().Str("host", config.SMTPHost).Msg("Identity SMTP connection test successful")
+	return nil
+}
+
+// ============================================================================
+// Send-as routing
+// ============================================================================
+
+// identityForAddress returns the identity with the given address, nil if none
+func identityForAddress(identities []*account.Identity, address string) *account.Identity {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if address == "" {
+		return nil
+	}
+	for _, id := range identities {
+		if strings.ToLower(strings.TrimSpace(id.Email)) == address {
+			return id
+		}
+	}
+	return nil
+}
+
+// defaultIdentity returns the default identity, else the first one (nil if
+// there are none)
+func defaultIdentity(identities []*account.Identity) *account.Identity {
+	for _, id := range identities {
+		if id.IsDefault {
+			return id
+		}
+	}
+	if len(identities) > 0 {
+		return identities[0]
+	}
+	return nil
+}
+
+// replyIdentity picks the identity to reply from: the alias the original was
+// addressed to (To, Cc, then Delivered-To / X-Original-To), else the default
+// identity. A message sent from one of the identities is answered from it.
+func replyIdentity(identities []*account.Identity, msg *message.Message) *account.Identity {
+	candidates := []string{msg.FromEmail}
+	for _, addr := range parseAddressList(msg.ToList) {
+		candidates = append(candidates, addr.Address)
+	}
+	for _, addr := range parseAddressList(msg.CcList) {
+		candidates = append(candidates, addr.Address)
+	}
+	if msg.DeliveredTo != "" {
+		var delivered []string
+		json.Unmarshal([]byte(msg.DeliveredTo), &delivered)
+		candidates = append(candidates, delivered...)
+	}
+
+	for _, addr := range candidates {
+		if id := identityForAddress(identities, addr); id != nil {
+			return id
+		}
+	}
+	return defaultIdentity(identities)
+}
+
+// identitySMTPClientConfig builds the SMTP config of an identity's own server,
+// without the password
+func identitySMTPClientConfig(identity *account.Identity, certStore *certificate.Store, proxy *netproxy.Config) smtp.ClientConfig {
+	config := smtp.DefaultConfig()
+	config.Host = identity.SMTPHost
+	config.Port = identity.SMTPPort
+	config.Security = smtp.SecurityType(identity.SMTPSecurity)
+	config.Username = identity.SMTPUsername
+	config.AuthType = smtp.AuthTypePassword
+	config.TLSConfig = certificate.BuildTLSConfig(identity.SMTPHost, certStore)
+	config.Proxy = proxy
+	return config
+}
+
+// identitySMTPConfig builds the SMTP config of an identity's own server with
+// its stored password
+func identitySMTPConfig(identity *account.Identity, credStore *credentials.Store, certStore *certificate.Store, proxy *netproxy.Config) (smtp.ClientConfig, error) {
+	config := identitySMTPClientConfig(identity, certStore, proxy)
+	if identity.SMTPUsername != "" {
+		password, err := credStore.GetIdentitySMTPPassword(identity.ID)
+		if err != nil {
+			return config, fmt.Errorf("failed to get identity SMTP password: %w", err)
+		}
+		config.Password = password
+	}
+	return config, nil
+}
+
+// connectSMTPClient connects to an SMTP server and logs in, unless no
+// username is configured (relays that accept mail without authentication).
+// The caller must close the returned client.
+func connectSMTPClient(config smtp.ClientConfig) (*smtp.Client, error) {
+	client := smtp.NewClient(config)
+
+	// Connect
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+
+	// Login
+	if config.Username != "" || config.AuthType == smtp.AuthTypeOAuth2 {
+		if err := client.Login(); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to login to SMTP server: %w", err)
+		}
+	}
+
+	return client, nil
+}
@@ -0,0 +1,241 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hkdb/aerion/internal/account"
+	"github.com/hkdb/aerion/internal/calendar"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/message"
+	"github.com/hkdb/aerion/internal/smtp"
+)
+
+// ============================================================================
+// Meeting Invitation API - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// GetMessageInvitation returns the meeting invitation (iMIP) carried by a
+// message, or nil if the message has none. Response and CanRespond are set
+// for the account the message belongs to.
+func (a *App) GetMessageInvitation(messageID string) (*calendar.Invitation, error) {
+	msg, err := a.messageStore.Get(messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if msg == nil {
+		return nil, fmt.Errorf("message not found: %s", messageID)
+	}
+
+	data, err := a.messageCalendarData(msg)
+	if err != nil || data == "" {
+		return nil, err
+	}
+
+	inv, err := calendar.ParseInvitation(data)
+	if err != nil {
+		return nil, err
+	}
+
+	emails, err := a.accountEmails(msg.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	isOrganizer := false
+	if inv.Organizer != nil {
+		for _, email := range emails {
+			if strings.EqualFold(inv.Organizer.Email, email) {
+				isOrganizer = true
+			}
+		}
+	}
+
+	inv.CanRespond = inv.Method == calendar.MethodRequest && inv.Organizer != nil && !isOrganizer
+	inv.Response = msg.CalendarResponse
+	if inv.Response == "" {
+		if attendee := inv.Attendee(emails...); attendee != nil && attendee.PartStat != calendar.PartStatNeedsAction {
+			inv.Response = attendee.PartStat
+		}
+	}
+	return inv, nil
+}
+
+// RespondToInvitation answers a meeting invitation. response is "ACCEPTED",
+// "TENTATIVE" or "DECLINED"; an iTIP REPLY with that participation status
+// is sent to the organizer through the account's SMTP server.
+func (a *App) RespondToInvitation(messageID, response string) error {
+	log := logging.WithComponent("app")
+	response = strings.ToUpper(strings.TrimSpace(response))
+
+	msg, err := a.messageStore.Get(messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get message: %w", err)
+	}
+	if msg == nil {
+		return fmt.Errorf("message not found: %s", messageID)
+	}
+
+	data, err := a.messageCalendarData(msg)
+	if err != nil {
+		return err
+	}
+	if data == "" {
+		return fmt.Errorf("message has no meeting invitation")
+	}
+	inv, err := calendar.ParseInvitation(data)
+	if err != nil {
+		return err
+	}
+	if inv.Method != calendar.MethodRequest {
+		return fmt.Errorf("cannot respond to a %s calendar message", strings.ToLower(inv.Method))
+	}
+	if inv.Organizer == nil || inv.Organizer.Email == "" {
+		return fmt.Errorf("invitation has no organizer")
+	}
+
+	acc, err := a.accountStore.Get(msg.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to get account: %w", err)
+	}
+	if acc == nil {
+		return fmt.Errorf("account not found: %s", msg.AccountID)
+	}
+	from, err := a.invitationIdentity(acc, inv)
+	if err != nil {
+		return err
+	}
+
+	ics, err := calendar.BuildReply(data, from.Address, from.Name, response)
+	if err != nil {
+		return err
+	}
+
+	verb := map[string]string{
+		calendar.PartStatAccepted:  "Accepted",
+		calendar.PartStatTentative: "Tentatively accepted",
+		calendar.PartStatDeclined:  "Declined",
+	}[response]
+	name := from.Name
+	if name == "" {
+		name = from.Address
+	}
+	text := fmt.Sprintf("%s has %s the invitation: %s\r\n", name, strings.ToLower(verb), inv.Summary)
+	if !inv.Start.IsZero() {
+		text += fmt.Sprintf("\r\nWhen: %s\r\n", inv.Start.Format("Mon Jan 2, 2006 15:04 MST"))
+	}
+
+	to := smtp.Address{Name: inv.Organizer.Name, Address: inv.Organizer.Email}
+	raw, err := smtp.BuildIMIP(from, to, verb+": "+inv.Summary, text, calendar.MethodReply, ics)
+	if err != nil {
+		return fmt.Errorf("failed to build reply: %w", err)
+	}
+
+	client, err := a.connectSMTP(acc.ID, acc, from.Address)
+	if err != nil {
+		return err
+	}
+	defer a.smtpPool.Release(acc.ID, client)
+
+	if err := client.SendMail(from.Address, []string{to.Address}, raw); err != nil {
+		return fmt.Errorf("failed to send reply: %w", err)
+	}
+
+	if err := a.messageStore.SetCalendarResponse(messageID, response); err != nil {
+		log.Warn().Err(err).Str("message_id", messageID).Msg("Failed to record invitation response")
+	}
+
+	log.Info().
+		Str("message_id", messageID).
+		Str("response", response).
+		Str("to", to.Address).
+		Msg("Invitation reply sent")
+
+	return nil
+}
+
+// messageCalendarData returns the iCalendar data of a message. Messages
+// synced before invitations were recognized only have the .ics attachment;
+// for those the message is fetched once and its calendar data stored.
+func (a *App) messageCalendarData(msg *message.Message) (string, error) {
+	data, err := a.messageStore.GetCalendarData(msg.ID)
+	if err != nil || data != "" || !msg.HasAttachments {
+		return data, err
+	}
+
+	atts, err := a.attachmentStore.GetByMessage(msg.ID)
+	if err != nil {
+		return "", err
+	}
+	hasCalendar := false
+	for _, att := range atts {
+		ct := strings.ToLower(att.ContentType)
+		if ct == "text/calendar" || ct == "application/ics" {
+			hasCalendar = true
+			break
+		}
+	}
+	if !hasCalendar {
+		return "", nil
+	}
+
+	raw, err := a.syncEngine.FetchRawMessage(a.ctx, msg.AccountID, msg.FolderID, msg.UID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch message: %w", err)
+	}
+	data = a.syncEngine.ParseDecryptedBody(raw, msg.ID).CalendarData
+	if data != "" {
+		if err := a.messageStore.UpdateCalendarData(msg.ID, data); err != nil {
+			log := logging.WithComponent("app")
+			log.Warn().Err(err).Str("message_id", msg.ID).Msg("Failed to save calendar data")
+		}
+	}
+	return data, nil
+}
+
+// accountEmails returns the addresses of an account and its identities
+func (a *App) accountEmails(accountID string) ([]string, error) {
+	acc, err := a.accountStore.Get(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if acc == nil {
+		return nil, fmt.Errorf("account not found: %s", accountID)
+	}
+
+	identities, err := a.accountStore.GetIdentities(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identities: %w", err)
+	}
+	emails := []string{acc.Email}
+	for _, id := range identities {
+		emails = append(emails, id.Email)
+	}
+	return emails, nil
+}
+
+// invitationIdentity picks the address to answer an invitation from: the
+// identity that was invited, else the account's default identity
+func (a *App) invitationIdentity(acc *account.Account, inv *calendar.Invitation) (smtp.Address, error) {
+	identities, err := a.accountStore.GetIdentities(acc.ID)
+	if err != nil {
+		return smtp.Address{}, fmt.Errorf("failed to get identities: %w", err)
+	}
+
+	for _, id := range identities {
+		if inv.Attendee(id.Email) != nil {
+			return smtp.Address{Name: id.Name, Address: id.Email}, nil
+		}
+	}
+	if inv.Attendee(acc.Email) != nil {
+		return smtp.Address{Name: acc.Name, Address: acc.Email}, nil
+	}
+	for _, id := range identities {
+		if id.IsDefault {
+			return smtp.Address{Name: id.Name, Address: id.Email}, nil
+		}
+	}
+	if len(identities) > 0 {
+		return smtp.Address{Name: identities[0].Name, Address: identities[0].Email}, nil
+	}
+	return smtp.Address{Name: acc.Name, Address: acc.Email}, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/hkdb/aerion/internal/folder"
 	"github.com/hkdb/aerion/internal/ipc"
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/platform"
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
@@ -34,6 +35,11 @@ func (a *App) initIPC(ctx context.Context) {
 	// Register message handler
 	a.ipcServer.OnMessage(a.handleIPCMessage)
 
+	// Publish the token for the command-line interface
+	if err := ipc.WriteTokenFile(tokenMgr.GetToken()); err != nil {
+		log.Warn().Err(err).Msg("Failed to write IPC token file, command-line interface unavailable")
+	}
+
 	// Log the address (available immediately after NewServer)
 	log.Info().Str("address", a.ipcServer.Address()).Msg("Starting IPC server")
 
@@ -92,6 +98,10 @@ func (a *App) handleIPCMessage(clientID string, msg ipc.Message) {
 		}
 		log.Info().Str("clientID", clientID).Msg("Composer window closed")
 
+	case ipc.TypeCLISend, ipc.TypeCLISearch, ipc.TypeCLISync, ipc.TypeCLIUnreadCount, ipc.TypeCLIOpen:
+		// Requests can take a while (SMTP, sync); don't block the client's read loop
+		go a.handleCLIRequest(clientID, msg)
+
 	default:
 		log.Warn().Str("type", msg.Type).Msg("Unknown IPC message type")
 	}
@@ -204,6 +214,9 @@ func (a *App) OpenComposerWindow(accountID, mode, messageID, draftID string) err
 		"--ipc-address", a.ipcServer.Address(),
 	}
 
+	// The composer opens the same profile's database
+	args = append(args, platform.ProfileArgs()...)
+
 	// Add mode-specific arguments
 	if draftID != "" {
 		args = append(args, "--draft-id", draftID)
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"path/filepath"
+
+	"github.com/hkdb/aerion/internal/localapi"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/settings"
+)
+
+// localAPITokenFile is the name of the bearer token file in the data directory
+const localAPITokenFile = "local-api.token"
+
+// LocalAPIInfo describes the running local API for the settings UI
+type LocalAPIInfo struct {
+	Running   bool   `json:"running"`
+	Address   string `json:"address,omitempty"`
+	TokenPath string `json:"tokenPath"`
+}
+
+// ============================================================================
+// Local API - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// GetLocalAPIConfig returns the local API configuration
+func (a *App) GetLocalAPIConfig() (*settings.LocalAPIConfig, error) {
+	return a.settingsStore.GetLocalAPIConfig()
+}
+
+// SetLocalAPIConfig saves the local API configuration and starts, stops or
+// restarts the server accordingly
+func (a *App) SetLocalAPIConfig(config settings.LocalAPIConfig) error {
+	if err := a.settingsStore.SetLocalAPIConfig(&config); err != nil {
+		return err
+	}
+
+	a.stopLocalAPI()
+	if config.Enabled {
+		return a.startLocalAPI(&config)
+	}
+	return nil
+}
+
+// GetLocalAPIInfo returns the state of the local API
+func (a *App) GetLocalAPIInfo() LocalAPIInfo {
+	info := LocalAPIInfo{TokenPath: a.localAPITokenPath()}
+
+	a.localAPIMu.RLock()
+	defer a.localAPIMu.RUnlock()
+	if a.localAPI != nil {
+		info.Running = true
+		info.Address = a.localAPI.Address()
+	}
+	return info
+}
+
+// GetLocalAPIToken returns the bearer token for the local API
+func (a *App) GetLocalAPIToken() (string, error) {
+	return localapi.LoadOrCreateToken(a.localAPITokenPath())
+}
+
+// RegenerateLocalAPIToken replaces the bearer token, invalidating the old one
+func (a *App) RegenerateLocalAPIToken() (string, error) {
+	token, err := localapi.RegenerateToken(a.localAPITokenPath())
+	if err != nil {
+		return "", err
+	}
+
+	// Restart a running server so it accepts the new token
+	a.localAPIMu.RLock()
+	running := a.localAPI != nil
+	a.localAPIMu.RUnlock()
+	if running {
+		config, err := a.settingsStore.GetLocalAPIConfig()
+		if err != nil {
+			return "", err
+		}
+		a.stopLocalAPI()
+		if err := a.startLocalAPI(config); err != nil {
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+// ============================================================================
+// Local API lifecycle
+// ============================================================================
+
+// initLocalAPI starts the local API if the user enabled it
+func (a *App) initLocalAPI() {
+	log := logging.WithComponent("app.localapi")
+
+	config, err := a.settingsStore.GetLocalAPIConfig()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load local API config")
+		return
+	}
+	if !config.Enabled {
+		return
+	}
+
+	if err := a.startLocalAPI(config); err != nil {
+		log.Error().Err(err).Msg("Failed to start local API")
+	}
+}
+
+// startLocalAPI starts the local API server
+func (a *App) startLocalAPI(config *settings.LocalAPIConfig) error {
+	token, err := localapi.LoadOrCreateToken(a.localAPITokenPath())
+	if err != nil {
+		return err
+	}
+
+	server := localapi.NewServer(a.accountStore, a.folderStore, a.messageStore, token)
+	if err := server.Start(config.ListenPort()); err != nil {
+		return err
+	}
+
+	a.localAPIMu.Lock()
+	a.localAPI = server
+	a.localAPIMu.Unlock()
+	return nil
+}
+
+// stopLocalAPI stops the local API server if it is running
+func (a *App) stopLocalAPI() {
+	a.localAPIMu.Lock()
+	server := a.localAPI
+	a.localAPI = nil
+	a.localAPIMu.Unlock()
+
+	if server != nil {
+		server.Stop()
+	}
+}
+
+// publishLocalAPIEvent sends an event to the local API's event streams
+func (a *App) publishLocalAPIEvent(eventType string, data interface{}) {
+	a.localAPIMu.RLock()
+	defer a.localAPIMu.RUnlock()
+	if a.localAPI != nil {
+		a.localAPI.Publish(eventType, data)
+	}
+}
+
+// localAPITokenPath returns the path of the bearer token file
+func (a *App) localAPITokenPath() string {
+	return filepath.Join(a.paths.Data, localAPITokenFile)
+}
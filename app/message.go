@@ -5,7 +5,9 @@ import (
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/hkdb/aerion/internal/email"
 	"github.com/hkdb/aerion/internal/logging"
 	"github.com/hkdb/aerion/internal/message"
 	"github.com/hkdb/aerion/internal/pgp"
@@ -29,7 +31,12 @@ func (a *App) GetMessageCount(accountID, folderID string) (int, error) {
 
 // GetMessage returns a full message by ID
 func (a *App) GetMessage(id string) (*message.Message, error) {
-	return a.messageStore.Get(id)
+	msg, err := a.messageStore.Get(id)
+	if err != nil || msg == nil {
+		return msg, err
+	}
+	a.cleanMessageLinks([]*message.Message{msg})
+	return msg, nil
 }
 
 // GetMessageSource fetches the raw RFC822 source of a message from the IMAP server
@@ -87,6 +94,10 @@ func (a *App) FetchMessageBody(messageID string) (*message.Message, error) {
 		"messageId": messageID,
 	})
 
+	if updatedMsg != nil {
+		a.cleanMessageLinks([]*message.Message{updatedMsg})
+	}
+
 	return updatedMsg, nil
 }
 
@@ -131,6 +142,7 @@ func (a *App) GetConversation(threadID, folderID string) (*message.Conversation,
 	}
 
 	if conv != nil && conv.Messages != nil {
+		a.cleanMessageLinks(conv.Messages)
 		for i, m := range conv.Messages {
 			log.Debug().
 				Int("index", i).
@@ -148,6 +160,34 @@ func (a *App) GetConversation(threadID, folderID string) (*message.Conversation,
 	return conv, nil
 }
 
+// cleanMessageLinks strips tracking parameters and unwraps click-tracking
+// redirectors in message bodies before they are handed to the frontend.
+// The stored body is left untouched so rules can be changed later.
+func (a *App) cleanMessageLinks(msgs []*message.Message) {
+	enabled, err := a.settingsStore.GetLinkCleaningEnabled()
+	if err != nil || !enabled {
+		return
+	}
+
+	rules, err := a.settingsStore.GetLinkCleanerRules()
+	if err != nil {
+		log := logging.WithComponent("app")
+		log.Warn().Err(err).Msg("Failed to load custom link cleaner rules, using defaults")
+	}
+	cleaner := email.NewLinkCleaner(rules)
+
+	for _, m := range msgs {
+		if m == nil {
+			continue
+		}
+		var htmlRemoved, textRemoved int
+		m.BodyHTML, htmlRemoved = cleaner.CleanHTML(m.BodyHTML)
+		m.BodyText, textRemoved = cleaner.CleanText(m.BodyText)
+		// Text and HTML parts usually carry the same links, so report the larger count
+		m.TrackersRemoved = max(htmlRemoved, textRemoved)
+	}
+}
+
 // DecryptedAttachment holds metadata for an attachment extracted from an encrypted message.
 // Content is never stored in DB — only returned in-memory for frontend display.
 type DecryptedAttachment struct {
@@ -166,6 +206,11 @@ type SMIMEViewResult struct {
 	SMIMESignerEmail   string                `json:"smimeSignerEmail"`
 	SMIMESignerSubject string                `json:"smimeSignerSubject"`
 	SMIMEEncrypted     bool                  `json:"smimeEncrypted"`
+	SMIMEError         string                `json:"smimeError,omitempty"`
+	SMIMEChain         []smime.ChainCert     `json:"smimeChain,omitempty"`
+	SMIMETrustSource   string                `json:"smimeTrustSource,omitempty"`
+	SMIMERevocation    string                `json:"smimeRevocation,omitempty"`
+	SMIMERevokedAt     *time.Time            `json:"smimeRevokedAt,omitempty"`
 	InlineAttachments  map[string]string     `json:"inlineAttachments,omitempty"` // contentID → dataURL
 	Attachments        []DecryptedAttachment `json:"attachments,omitempty"`       // metadata for attachment list
 }
@@ -228,6 +273,11 @@ func (a *App) ProcessSMIMEMessage(messageID string) (*SMIMEViewResult, error) {
 		result.SMIMEStatus = string(sigResult.Status)
 		result.SMIMESignerEmail = sigResult.SignerEmail
 		result.SMIMESignerSubject = sigResult.SignerName
+		result.SMIMEError = sigResult.ErrorMessage
+		result.SMIMEChain = sigResult.Chain
+		result.SMIMETrustSource = sigResult.TrustSource
+		result.SMIMERevocation = string(sigResult.RevocationStatus)
+		result.SMIMERevokedAt = sigResult.RevokedAt
 	}
 
 	// Step 4: Parse the final body using the sync engine's parser (includes attachments)
@@ -325,6 +375,13 @@ func (a *App) ProcessPGPMessage(messageID string) (*PGPViewResult, error) {
 		if isEncrypted {
 			result.PGPEncrypted = true
 			innerBytes = decrypted
+
+			// Learn keys gossiped to the other recipients
+			var recipients []string
+			for _, addr := range append(parseAddressList(msg.ToList), parseAddressList(msg.CcList)...) {
+				recipients = append(recipients, addr.Address)
+			}
+			a.autocrypt.ProcessGossip(msg.AccountID, msg.Date, recipients, decrypted)
 		}
 	}
 
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/message"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// MuteThreadOptions controls what happens to new messages of a muted thread
+type MuteThreadOptions struct {
+	MarkRead   bool `json:"markRead"`   // Mark new messages as read
+	Archive    bool `json:"archive"`    // Archive new messages
+	SetKeyword bool `json:"setKeyword"` // Tag the thread's messages with the $Muted IMAP keyword
+}
+
+// ============================================================================
+// Muted Threads API - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// MuteThread mutes a conversation. New messages in it never notify and are
+// marked read and/or archived as they arrive.
+func (a *App) MuteThread(accountID, threadID string, opts MuteThreadOptions) error {
+	log := logging.WithComponent("app.mute")
+
+	if threadID == "" {
+		return fmt.Errorf("thread ID is required")
+	}
+
+	messageIDs, err := a.messageStore.GetThreadMessageIDs(accountID, threadID)
+	if err != nil {
+		return err
+	}
+
+	var subject string
+	if len(messageIDs) > 0 {
+		if m, err := a.messageStore.Get(messageIDs[0]); err == nil && m != nil {
+			subject = m.Subject
+		}
+	}
+
+	if err := a.threadMuteStore.Mute(&message.MutedThread{
+		AccountID: accountID,
+		ThreadID:  threadID,
+		Subject:   subject,
+		MarkRead:  opts.MarkRead,
+		Archive:   opts.Archive,
+	}); err != nil {
+		return err
+	}
+
+	if opts.SetKeyword && len(messageIDs) > 0 {
+		go a.setMutedKeyword(messageIDs, true)
+	}
+
+	wailsRuntime.EventsEmit(a.ctx, "thread:muteChanged", map[string]interface{}{
+		"accountId": accountID,
+		"threadId":  threadID,
+		"muted":     true,
+	})
+
+	log.Info().Str("accountID", accountID).Str("threadID", threadID).Msg("Thread muted")
+	return nil
+}
+
+// UnmuteThread unmutes a conversation
+func (a *App) UnmuteThread(accountID, threadID string) error {
+	log := logging.WithComponent("app.mute")
+
+	if err := a.threadMuteStore.Unmute(accountID, threadID); err != nil {
+		return err
+	}
+
+	// Clear the keyword in case it was set; servers ignore removing absent
+	// keywords. Until this is done, flag sync ignores the keyword on the thread.
+	messageIDs, err := a.messageStore.GetThreadMessageIDs(accountID, threadID)
+	if err != nil {
+		log.Warn().Err(err).Str("threadID", threadID).Msg("Failed to get thread messages to clear muted keyword")
+	} else {
+		go func() {
+			if err := a.setMutedKeyword(messageIDs, false); err != nil {
+				return
+			}
+			if err := a.threadMuteStore.KeywordCleared(accountID, threadID); err != nil {
+				log.Warn().Err(err).Str("threadID", threadID).Msg("Failed to record cleared muted keyword")
+			}
+		}()
+	}
+
+	wailsRuntime.EventsEmit(a.ctx, "thread:muteChanged", map[string]interface{}{
+		"accountId": accountID,
+		"threadId":  threadID,
+		"muted":     false,
+	})
+
+	log.Info().Str("accountID", accountID).Str("threadID", threadID).Msg("Thread unmuted")
+	return nil
+}
+
+// GetMutedThreads returns all muted conversations
+func (a *App) GetMutedThreads() ([]*message.MutedThread, error) {
+	return a.threadMuteStore.List()
+}
+
+// IsThreadMuted reports whether a conversation is muted
+func (a *App) IsThreadMuted(accountID, threadID string) bool {
+	return a.threadMuteStore.IsMuted(accountID, threadID)
+}
+
+// handleMutedMessages is called by the sync engine with newly synced inbox
+// messages of a muted thread
+func (a *App) handleMutedMessages(accountID, folderID string, thread *message.MutedThread, messageIDs []string) {
+	log := logging.WithComponent("app.mute")
+
+	log.Debug().
+		Str("accountID", accountID).
+		Str("threadID", thread.ThreadID).
+		Int("count", len(messageIDs)).
+		Msg("New messages in muted thread")
+
+	// Mark read synchronously (local-first) so the new mail notification
+	// that follows the sync already sees them as read
+	if thread.MarkRead {
+		if err := a.MarkAsRead(messageIDs); err != nil {
+			log.Warn().Err(err).Msg("Failed to mark muted thread messages as read")
+		}
+	}
+
+	// Archive outside the sync call so the header sync isn't held up by IMAP MOVE
+	if thread.Archive {
+		go func() {
+			if err := a.Archive(messageIDs); err != nil {
+				log.Warn().Err(err).Msg("Failed to archive muted thread messages")
+			}
+		}()
+	}
+}
+
+// setMutedKeyword adds or removes the $Muted keyword on messages. Returns
+// the last error if the keyword couldn't be updated in every folder.
+func (a *App) setMutedKeyword(messageIDs []string, muted bool) error {
+	log := logging.WithComponent("app.mute")
+
+	messages, err := a.messageStore.GetByIDs(messageIDs)
+	if err != nil {
+		return err
+	}
+
+	byFolder := make(map[string][]*message.Message)
+	for _, m := range messages {
+		byFolder[m.FolderID] = append(byFolder[m.FolderID], m)
+	}
+
+	var lastErr error
+	for folderID, msgs := range byFolder {
+		if err := a.syncFlagsToIMAP(msgs, folderID, "muted", muted); err != nil {
+			log.Warn().Err(err).Str("folderID", folderID).Msg("Failed to sync muted keyword to IMAP")
+			lastErr = err
+		}
+	}
+	return lastErr
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/hkdb/aerion/internal/account"
@@ -30,6 +31,12 @@ type OAuthStatus struct {
 // Opens the system browser with the authorization URL and waits for callback.
 // Emits events: oauth:started, oauth:success, oauth:error
 func (a *App) StartOAuthFlow(provider string) error {
+	return a.startOAuthFlow(provider, nil)
+}
+
+// startOAuthFlow runs the OAuth2 flow for a provider, with the scopes of
+// config when given (e.g. to keep calendar access on re-authorization)
+func (a *App) startOAuthFlow(provider string, config *oauth2.ProviderConfig) error {
 	log := logging.WithComponent("app.oauth")
 
 	// Check if provider is configured
@@ -45,7 +52,13 @@ func (a *App) StartOAuthFlow(provider string) error {
 	})
 
 	// Start the OAuth flow
-	authURL, err := a.oauth2Manager.StartAuthFlow(a.ctx, provider)
+	var authURL string
+	var err error
+	if config != nil {
+		authURL, err = a.oauth2Manager.StartAuthFlowWithProvider(a.ctx, config)
+	} else {
+		authURL, err = a.oauth2Manager.StartAuthFlow(a.ctx, provider)
+	}
 	if err != nil {
 		wailsRuntime.EventsEmit(a.ctx, "oauth:error", map[string]interface{}{
 			"provider": provider,
@@ -278,7 +291,7 @@ func (a *App) SavePendingOAuthTokens(accountID string) error {
 		AccessToken:  a.pendingOAuthTokens.AccessToken,
 		RefreshToken: a.pendingOAuthTokens.RefreshToken,
 		ExpiresAt:    expiresAt,
-		Scopes:       providerConfig.Scopes,
+		Scopes:       grantedScopes(a.pendingOAuthTokens, providerConfig),
 	}
 
 	if err := a.credStore.SetOAuthTokens(accountID, tokens); err != nil {
@@ -391,10 +404,26 @@ func (a *App) ReauthorizeAccount(accountID string) error {
 		Str("provider", provider).
 		Msg("Starting re-authorization for account")
 
+	// Keep calendar access if it was granted; a new consent replaces the
+	// scopes of the old token
+	if provider == "google" && a.hasCalendarScope(accountID) {
+		config := oauth2.GoogleCalendarProvider()
+		return a.startOAuthFlow(provider, &config)
+	}
+
 	// Start OAuth flow - frontend will handle storing new tokens
 	return a.StartOAuthFlow(provider)
 }
 
+// grantedScopes returns the scopes granted with a token, or the requested
+// ones when the provider doesn't list them
+func grantedScopes(tokens *oauth2.TokenResponse, provider oauth2.ProviderConfig) []string {
+	if scopes := strings.Fields(tokens.Scope); len(scopes) > 0 {
+		return scopes
+	}
+	return provider.Scopes
+}
+
 // TestOAuthConnection tests the connection for an OAuth account.
 // This verifies that the stored tokens work for IMAP access.
 func (a *App) TestOAuthConnection(accountID string) error {
@@ -426,6 +455,7 @@ func (a *App) TestOAuthConnection(accountID string) error {
 	clientConfig.Username = acc.Username
 	clientConfig.AuthType = imap.AuthTypeOAuth2
 	clientConfig.AccessToken = tokens.AccessToken
+	clientConfig.Proxy = a.accountProxy(accountID)
 
 	client := imap.NewClient(clientConfig)
 
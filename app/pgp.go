@@ -3,7 +3,10 @@ package app
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hkdb/aerion/internal/logging"
 	"github.com/hkdb/aerion/internal/pgp"
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -41,12 +44,25 @@ func (a *App) ImportPGPKeyFromPath(accountID, filePath, passphrase string) (*pgp
 		return nil, fmt.Errorf("failed to import key: %w", err)
 	}
 
+	if err := a.savePGPKeypair(accountID, key, armoredPrivate, armoredPublic); err != nil {
+		return nil, err
+	}
+
+	return &pgp.ImportResult{
+		Key:        key,
+		HasPrivate: armoredPrivate != "",
+	}, nil
+}
+
+// savePGPKeypair stores an imported or generated keypair for an account.
+// The first key of an account becomes its default.
+func (a *App) savePGPKeypair(accountID string, key *pgp.Key, armoredPrivate, armoredPublic string) error {
 	key.AccountID = accountID
 
 	// Check if this is the first key for this account (make it default)
 	existing, err := a.pgpStore.ListKeys(accountID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing keys: %w", err)
+		return fmt.Errorf("failed to check existing keys: %w", err)
 	}
 	if len(existing) == 0 {
 		key.IsDefault = true
@@ -54,16 +70,15 @@ func (a *App) ImportPGPKeyFromPath(accountID, filePath, passphrase string) (*pgp
 
 	// Store the key metadata and public key
 	if err := a.pgpStore.SaveKey(key, armoredPublic); err != nil {
-		return nil, fmt.Errorf("failed to save key: %w", err)
+		return fmt.Errorf("failed to save key: %w", err)
 	}
 
 	// Store the private key securely
-	hasPrivate := armoredPrivate != ""
-	if hasPrivate {
+	if armoredPrivate != "" {
 		if err := a.credStore.SetPGPPrivateKey(key.ID, []byte(armoredPrivate)); err != nil {
 			// Rollback: delete the key if private key storage fails
 			a.pgpStore.DeleteKey(key.ID)
-			return nil, fmt.Errorf("failed to store private key: %w", err)
+			return fmt.Errorf("failed to store private key: %w", err)
 		}
 	}
 
@@ -72,12 +87,273 @@ func (a *App) ImportPGPKeyFromPath(accountID, filePath, passphrase string) (*pgp
 		a.pgpStore.SetDefaultKey(accountID, key.ID)
 	}
 
+	return nil
+}
+
+// GeneratePGPKey generates a new keypair bound to the selected identities of
+// an account. With a passphrase, the private key is stored protected and
+// stays unlocked for the rest of the session; without one it is stored
+// unlocked in the credential store like imported keys.
+func (a *App) GeneratePGPKey(req pgp.GenerateRequest) (*pgp.ImportResult, error) {
+	log := logging.WithComponent("pgp")
+
+	if req.ExpiryDays < 0 {
+		return nil, fmt.Errorf("invalid expiry: %d days", req.ExpiryDays)
+	}
+
+	identities, err := a.accountStore.GetIdentities(req.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identities: %w", err)
+	}
+
+	// Bind the requested identities in the order given, or all of them
+	byID := make(map[string]pgp.KeyIdentity, len(identities))
+	var all []pgp.KeyIdentity
+	for _, ident := range identities {
+		ki := pgp.KeyIdentity{Name: ident.Name, Email: ident.Email}
+		byID[ident.ID] = ki
+		all = append(all, ki)
+	}
+	bound := all
+	if len(req.IdentityIDs) > 0 {
+		bound = nil
+		for _, id := range req.IdentityIDs {
+			ki, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("identity %s does not belong to this account", id)
+			}
+			bound = append(bound, ki)
+		}
+	}
+	if len(bound) == 0 {
+		return nil, fmt.Errorf("account has no identities to bind")
+	}
+
+	entity, err := pgp.GenerateKey(bound, req.Algorithm, time.Duration(req.ExpiryDays)*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	armoredPublic, err := pgp.ArmorPublicKey(entity)
+	if err != nil {
+		return nil, err
+	}
+	var armoredPrivate string
+	if req.Passphrase != "" {
+		armoredPrivate, err = pgp.ArmorProtectedPrivateKey(entity, req.Passphrase)
+	} else {
+		armoredPrivate, err = pgp.ArmorPrivateKey(entity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := pgp.ExtractKeyMetadata(entity)
+	if err := a.savePGPKeypair(req.AccountID, key, armoredPrivate, armoredPublic); err != nil {
+		return nil, err
+	}
+	if req.Passphrase != "" {
+		if err := pgp.UnlockKey(a.credStore, key.ID, req.Passphrase); err != nil {
+			return nil, fmt.Errorf("failed to unlock generated key: %w", err)
+		}
+		key.IsProtected = true
+	}
+
+	log.Info().
+		Str("account", req.AccountID).
+		Str("fingerprint", key.Fingerprint).
+		Str("algorithm", req.Algorithm).
+		Int("identities", len(bound)).
+		Msg("Generated PGP key")
+
 	return &pgp.ImportResult{
-		Key:        key,
-		HasPrivate: hasPrivate,
+		Key:         key,
+		HasPrivate:  true,
+		SubkeyCount: len(entity.Subkeys),
 	}, nil
 }
 
+// loadPGPPrivateEntity loads one of the user's keys with its private part.
+// Protected keys must have been unlocked with UnlockPGPKey.
+func (a *App) loadPGPPrivateEntity(keyID string) (*pgp.Key, *openpgp.Entity, error) {
+	key, _, err := a.pgpStore.GetKey(keyID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("key not found: %w", err)
+	}
+
+	entity, err := pgp.LoadPrivateKey(a.credStore, keyID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load private key %s: %w", key.KeyID, err)
+	}
+	return key, entity, nil
+}
+
+// updatePGPKeyMaterial stores a re-signed key (public and private parts).
+// A protected private key stays protected.
+func (a *App) updatePGPKeyMaterial(keyID string, entity *openpgp.Entity) error {
+	armoredPublic, err := pgp.ArmorPublicKey(entity)
+	if err != nil {
+		return err
+	}
+	armoredPrivate, err := pgp.ArmorStoredPrivateKey(keyID, entity)
+	if err != nil {
+		return err
+	}
+
+	meta := pgp.ExtractKeyMetadata(entity)
+	if err := a.pgpStore.UpdateKeyMaterial(keyID, armoredPublic, meta.ExpiresAtKey); err != nil {
+		return fmt.Errorf("failed to update key: %w", err)
+	}
+	if err := a.credStore.SetPGPPrivateKey(keyID, []byte(armoredPrivate)); err != nil {
+		return fmt.Errorf("failed to store private key: %w", err)
+	}
+	return nil
+}
+
+// SetPGPKeyExpiry changes when one of the user's keys expires, counted in
+// days from now (0 = never). Re-publish the public key afterwards so
+// correspondents see the new expiry.
+func (a *App) SetPGPKeyExpiry(keyID string, expiryDays int) (*pgp.Key, error) {
+	if expiryDays < 0 {
+		return nil, fmt.Errorf("invalid expiry: %d days", expiryDays)
+	}
+
+	key, entity, err := a.loadPGPPrivateEntity(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("key has been revoked")
+	}
+
+	var expiresAt *time.Time
+	if expiryDays > 0 {
+		t := time.Now().Add(time.Duration(expiryDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+	if err := pgp.SetKeyExpiry(entity, expiresAt); err != nil {
+		return nil, err
+	}
+	if err := a.updatePGPKeyMaterial(keyID, entity); err != nil {
+		return nil, err
+	}
+
+	updated, _, err := a.pgpStore.GetKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	updated.HasPrivate = true
+	return updated, nil
+}
+
+// RevokePGPKey permanently revokes one of the user's keys. The key stops
+// being used for signing; a revoked default key is replaced by another
+// usable key of the account, if there is one. Export and publish its public
+// key so correspondents learn about the revocation.
+func (a *App) RevokePGPKey(keyID, reason, text string) (*pgp.Key, error) {
+	key, entity, err := a.loadPGPPrivateEntity(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+
+	if err := pgp.RevokeKey(entity, reason, text); err != nil {
+		return nil, err
+	}
+	if err := a.updatePGPKeyMaterial(keyID, entity); err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		reason = pgp.RevocationNoReason
+	}
+	if err := a.pgpStore.MarkKeyRevoked(keyID, reason, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to record revocation: %w", err)
+	}
+
+	updated, _, err := a.pgpStore.GetKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	updated.HasPrivate = true
+	return updated, nil
+}
+
+// ExportPGPPublicKey returns the armored public key, e.g. for copying into a
+// keyserver upload form
+func (a *App) ExportPGPPublicKey(keyID string) (string, error) {
+	_, armoredPublic, err := a.pgpStore.GetKey(keyID)
+	if err != nil {
+		return "", fmt.Errorf("key not found: %w", err)
+	}
+	return armoredPublic, nil
+}
+
+// SavePGPPublicKeyFile saves the armored public key to a file chosen by the user
+func (a *App) SavePGPPublicKeyFile(keyID string) (string, error) {
+	key, armoredPublic, err := a.pgpStore.GetKey(keyID)
+	if err != nil {
+		return "", fmt.Errorf("key not found: %w", err)
+	}
+	return a.savePGPExport("Export Public Key", fmt.Sprintf("%s.pub.asc", key.KeyID), armoredPublic)
+}
+
+// SavePGPPrivateKeyFile saves a passphrase-protected copy of the private key
+// to a file chosen by the user
+func (a *App) SavePGPPrivateKeyFile(keyID, passphrase string) (string, error) {
+	key, entity, err := a.loadPGPPrivateEntity(keyID)
+	if err != nil {
+		return "", err
+	}
+	armored, err := pgp.ArmorProtectedPrivateKey(entity, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return a.savePGPExport("Export Private Key", fmt.Sprintf("%s.sec.asc", key.KeyID), armored)
+}
+
+// SavePGPRevocationCertificate generates a revocation certificate for one of
+// the user's keys and saves it to a file chosen by the user. The key itself
+// is not revoked.
+func (a *App) SavePGPRevocationCertificate(keyID, reason string) (string, error) {
+	key, entity, err := a.loadPGPPrivateEntity(keyID)
+	if err != nil {
+		return "", err
+	}
+	cert, err := pgp.GenerateRevocationCertificate(entity, reason, "")
+	if err != nil {
+		return "", err
+	}
+	return a.savePGPExport("Save Revocation Certificate", fmt.Sprintf("%s.rev.asc", key.KeyID), cert)
+}
+
+// savePGPExport asks for a destination and writes armored key material to it.
+// Returns an empty path if the user cancelled.
+func (a *App) savePGPExport(title, defaultFilename, content string) (string, error) {
+	savePath, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+		Title:           title,
+		DefaultFilename: defaultFilename,
+		Filters: []wailsRuntime.FileFilter{
+			{
+				DisplayName: "ASCII-armored PGP (*.asc)",
+				Pattern:     "*.asc",
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to show save dialog: %w", err)
+	}
+	if savePath == "" {
+		return "", nil
+	}
+
+	if err := os.WriteFile(savePath, []byte(content), 0600); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	return savePath, nil
+}
+
 // ListPGPKeys returns all PGP keys for an account
 func (a *App) ListPGPKeys(accountID string) ([]*pgp.Key, error) {
 	keys, err := a.pgpStore.ListKeys(accountID)
@@ -87,11 +363,27 @@ func (a *App) ListPGPKeys(accountID string) ([]*pgp.Key, error) {
 	if keys == nil {
 		return []*pgp.Key{}, nil
 	}
+	for _, key := range keys {
+		key.IsProtected = pgp.IsKeyProtected(a.credStore, key.ID)
+	}
 	return keys, nil
 }
 
+// UnlockPGPKey unlocks a passphrase-protected key for signing and decryption
+// until the app quits or LockPGPKeys is called
+func (a *App) UnlockPGPKey(keyID, passphrase string) error {
+	return pgp.UnlockKey(a.credStore, keyID, passphrase)
+}
+
+// LockPGPKeys locks all unlocked passphrase-protected keys again
+func (a *App) LockPGPKeys() {
+	pgp.LockAllKeys()
+}
+
 // DeletePGPKey removes a PGP key and its private key
 func (a *App) DeletePGPKey(keyID string) error {
+	pgp.LockKey(keyID)
+
 	// Delete private key first
 	if err := a.credStore.DeletePGPPrivateKey(keyID); err != nil {
 		return fmt.Errorf("failed to delete private key: %w", err)
@@ -107,6 +399,13 @@ func (a *App) DeletePGPKey(keyID string) error {
 
 // SetDefaultPGPKey sets the default signing key for an account
 func (a *App) SetDefaultPGPKey(accountID, keyID string) error {
+	key, _, err := a.pgpStore.GetKey(keyID)
+	if err != nil {
+		return fmt.Errorf("key not found: %w", err)
+	}
+	if key.RevokedAt != nil {
+		return fmt.Errorf("key has been revoked")
+	}
 	return a.pgpStore.SetDefaultKey(accountID, keyID)
 }
 
@@ -130,10 +429,11 @@ func (a *App) GetPGPEncryptPolicy(accountID string) (string, error) {
 	return a.pgpStore.GetEncryptPolicy(accountID)
 }
 
-// SetPGPEncryptPolicy sets the PGP encryption policy ('never', 'always')
+// SetPGPEncryptPolicy sets the PGP encryption policy ('never', 'always', or
+// 'auto' to encrypt when Autocrypt recommends it)
 func (a *App) SetPGPEncryptPolicy(accountID, policy string) error {
 	switch policy {
-	case "never", "always":
+	case "never", "always", "auto":
 		return a.pgpStore.SetEncryptPolicy(accountID, policy)
 	default:
 		return fmt.Errorf("invalid encrypt policy: %s", policy)
@@ -160,7 +460,7 @@ func (a *App) DeletePGPSenderKey(keyID string) error {
 // HasPGPKey returns whether an account has a default PGP key configured
 func (a *App) HasPGPKey(accountID string) bool {
 	key, _, err := a.pgpStore.GetDefaultKey(accountID)
-	return err == nil && key != nil && !key.IsExpired
+	return err == nil && key != nil && !key.IsExpired && key.RevokedAt == nil
 }
 
 // shouldPGPSignMessage determines whether a message should be PGP signed
@@ -177,18 +477,70 @@ func (a *App) shouldPGPSignMessage(accountID string, perMessageOverride bool) bo
 	return a.HasPGPKey(accountID)
 }
 
-// shouldPGPEncryptMessage determines whether a message should be PGP encrypted
-func (a *App) shouldPGPEncryptMessage(accountID string, perMessageOverride bool) bool {
+// shouldPGPEncryptMessage determines whether a message should be PGP encrypted.
+// With the "auto" policy, the Autocrypt recommendation decides.
+func (a *App) shouldPGPEncryptMessage(accountID string, recipients []string, perMessageOverride bool) bool {
 	if perMessageOverride {
 		return a.HasPGPKey(accountID)
 	}
 
 	policy, err := a.pgpStore.GetEncryptPolicy(accountID)
-	if err != nil || policy != "always" {
+	if err != nil {
+		return false
+	}
+	switch policy {
+	case "always":
+		return a.HasPGPKey(accountID)
+	case "auto":
+		return a.HasPGPKey(accountID) && autocryptSuggestsEncryption(a.autocrypt, accountID, recipients)
+	default:
 		return false
 	}
+}
 
-	return a.HasPGPKey(accountID)
+// autocryptSuggestsEncryption reports whether the Autocrypt recommendation
+// for the recipients is "encrypt"
+func autocryptSuggestsEncryption(ac *pgp.Autocrypt, accountID string, recipients []string) bool {
+	rec, err := ac.Recommend(accountID, recipients)
+	if err != nil {
+		log := logging.WithComponent("app")
+		log.Warn().Err(err).Str("accountID", accountID).Msg("Failed to compute Autocrypt recommendation")
+		return false
+	}
+	return rec.Recommendation == pgp.RecommendEncrypt
+}
+
+// GetAutocryptRecommendation returns the Autocrypt encryption recommendation
+// for the given recipients, used to pre-select PGP encryption in the composer
+func (a *App) GetAutocryptRecommendation(accountID string, recipients []string) (*pgp.AutocryptRecommendation, error) {
+	return a.autocrypt.Recommend(accountID, recipients)
+}
+
+// GetAutocryptSettings returns the Autocrypt configuration for an account
+func (a *App) GetAutocryptSettings(accountID string) (*pgp.AutocryptSettings, error) {
+	return a.pgpStore.GetAutocryptSettings(accountID)
+}
+
+// SetAutocryptSettings updates the Autocrypt configuration for an account
+func (a *App) SetAutocryptSettings(accountID string, settings pgp.AutocryptSettings) error {
+	return a.pgpStore.SetAutocryptSettings(accountID, settings)
+}
+
+// ListAutocryptPeers returns the Autocrypt peer state learned for an account
+func (a *App) ListAutocryptPeers(accountID string) ([]*pgp.AutocryptPeer, error) {
+	peers, err := a.pgpStore.ListAutocryptPeers(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Autocrypt peers: %w", err)
+	}
+	if peers == nil {
+		return []*pgp.AutocryptPeer{}, nil
+	}
+	return peers, nil
+}
+
+// DeleteAutocryptPeer forgets the Autocrypt state for a peer address
+func (a *App) DeleteAutocryptPeer(accountID, addr string) error {
+	return a.pgpStore.DeleteAutocryptPeer(accountID, addr)
 }
 
 // CheckRecipientPGPKeys checks which recipients have PGP public keys available
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/hkdb/aerion/internal/ipc"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/platform"
+	"github.com/hkdb/aerion/internal/profile"
+)
+
+// ============================================================================
+// Profiles - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// GetActiveProfile returns the name of the profile this instance runs
+func (a *App) GetActiveProfile() string {
+	return platform.ActiveProfile()
+}
+
+// IsPortableMode reports whether data is kept in a directory given with --data-dir
+func (a *App) IsPortableMode() bool {
+	return platform.Portable()
+}
+
+// ListProfiles returns all profiles and whether they are open
+func (a *App) ListProfiles() ([]*profile.Profile, error) {
+	return profile.List()
+}
+
+// CreateProfile creates a new, empty profile
+func (a *App) CreateProfile(name string) (*profile.Profile, error) {
+	return profile.Create(name)
+}
+
+// DeleteProfile removes a profile and all of its data. The profile must not
+// be open.
+func (a *App) DeleteProfile(name string) error {
+	return profile.Delete(name)
+}
+
+// LaunchProfile opens a profile in a new Aerion instance
+func (a *App) LaunchProfile(name string) error {
+	log := logging.WithComponent("app.profile")
+
+	if err := platform.ValidateProfileName(name); err != nil {
+		return err
+	}
+	if address, err := ipc.AddressFor(platform.InstanceIDFor(name)); err == nil && ipc.IsListening(address) {
+		return fmt.Errorf("profile %q is already open", name)
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	args := []string{"--profile", name}
+	if platform.Portable() {
+		args = append(args, "--data-dir", platform.DataRoot())
+	}
+
+	cmd := exec.Command(execPath, args...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to launch profile: %w", err)
+	}
+	log.Info().Str("profile", name).Int("pid", cmd.Process.Pid).Msg("Launched profile")
+
+	// Reap the process when it exits
+	go cmd.Wait()
+	return nil
+}
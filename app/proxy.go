@@ -0,0 +1,200 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hkdb/aerion/internal/account"
+	"github.com/hkdb/aerion/internal/credentials"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
+	"github.com/hkdb/aerion/internal/settings"
+)
+
+// proxyTestTimeout limits TestProxyConnection
+const proxyTestTimeout = 15 * time.Second
+
+// ProxySettings is a proxy configuration as shown in settings. The password
+// itself is never sent to the frontend.
+type ProxySettings struct {
+	Config      *netproxy.Config `json:"config"`
+	HasPassword bool             `json:"hasPassword"`
+}
+
+// ============================================================================
+// Proxy - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// GetProxyConfig returns the global proxy used by all connections of
+// accounts without their own proxy, and by contact sync, key lookups and
+// OAuth
+func (a *App) GetProxyConfig() (*ProxySettings, error) {
+	config, err := a.settingsStore.GetProxyConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &ProxySettings{
+		Config:      config,
+		HasPassword: hasProxyPassword(a.credStore, credentials.GlobalProxyScope),
+	}, nil
+}
+
+// SetProxyConfig saves the global proxy. An empty password keeps the stored
+// one; clearing the username removes it. New connections use the proxy
+// immediately.
+func (a *App) SetProxyConfig(config netproxy.Config, password string) error {
+	if err := a.settingsStore.SetProxyConfig(&config); err != nil {
+		return err
+	}
+	if err := saveProxyPassword(a.credStore, credentials.GlobalProxyScope, &config, password); err != nil {
+		return err
+	}
+
+	netproxy.SetDefault(loadGlobalProxy(a.settingsStore, a.credStore))
+	a.smtpPool.CloseAll()
+	return nil
+}
+
+// GetAccountProxy returns the proxy of an account. Type "" means the account
+// uses the global proxy.
+func (a *App) GetAccountProxy(accountID string) (*ProxySettings, error) {
+	config, err := a.accountStore.GetProxy(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &netproxy.Config{Type: netproxy.TypeDefault}
+	}
+	return &ProxySettings{
+		Config:      config,
+		HasPassword: hasProxyPassword(a.credStore, accountID),
+	}, nil
+}
+
+// SetAccountProxy saves the proxy of an account. Type "" uses the global
+// proxy, "none" connects directly. An empty password keeps the stored one.
+// The account's IMAP and SMTP connections use it from their next connect;
+// OAuth token refreshes and linked contact and calendar sources use it from
+// their next request.
+func (a *App) SetAccountProxy(accountID string, config netproxy.Config, password string) error {
+	if err := a.accountStore.SetProxy(accountID, &config); err != nil {
+		return err
+	}
+	if err := saveProxyPassword(a.credStore, accountID, &config, password); err != nil {
+		return err
+	}
+	a.smtpPool.CloseAccount(accountID)
+	return nil
+}
+
+// TestProxyConnection checks that host:port can be reached through a proxy.
+// An empty password uses the stored password of the account, or of the
+// global proxy if accountID is empty. Type "" tests the global proxy.
+func (a *App) TestProxyConnection(accountID string, config netproxy.Config, password, host string, port int) error {
+	log := logging.WithComponent("app.proxy")
+
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if host == "" || port <= 0 {
+		return fmt.Errorf("a server host and port are required")
+	}
+
+	if config.Type != netproxy.TypeDefault && config.Username != "" && password == "" {
+		scope := accountID
+		if scope == "" {
+			scope = credentials.GlobalProxyScope
+		}
+		password, _ = a.credStore.GetProxyPassword(scope)
+	}
+	config.Password = password
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := netproxy.Dial(context.Background(), &config, proxyTestTimeout, addr)
+	if err != nil {
+		log.Warn().Err(err).Str("addr", addr).Str("type", string(config.Type)).Msg("Proxy connection test failed")
+		return fmt.Errorf("connection failed: %w", err)
+	}
+	conn.Close()
+
+	log.Info().Str("addr", addr).Str("type", string(config.Type)).Msg("Proxy connection test successful")
+	return nil
+}
+
+// ============================================================================
+// Proxy loading
+// ============================================================================
+
+// accountProxy returns the proxy for an account's IMAP and SMTP connections,
+// OAuth token refreshes and linked contact and calendar sources
+func (a *App) accountProxy(accountID string) *netproxy.Config {
+	return loadAccountProxy(a.accountStore, a.credStore, accountID)
+}
+
+// loadGlobalProxy returns the global proxy with its password, nil if
+// connections are direct
+func loadGlobalProxy(settingsStore *settings.Store, credStore *credentials.Store) *netproxy.Config {
+	log := logging.WithComponent("app.proxy")
+
+	config, err := settingsStore.GetProxyConfig()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load proxy config, connecting directly")
+		return nil
+	}
+	if !config.Enabled() {
+		return nil
+	}
+	config.Password = proxyPassword(credStore, credentials.GlobalProxyScope, config)
+	return config
+}
+
+// loadAccountProxy returns the proxy of an account with its password, nil if
+// the account uses the global proxy
+func loadAccountProxy(accountStore *account.Store, credStore *credentials.Store, accountID string) *netproxy.Config {
+	log := logging.WithComponent("app.proxy")
+
+	config, err := accountStore.GetProxy(accountID)
+	if err != nil {
+		log.Warn().Err(err).Str("accountID", accountID).Msg("Failed to load account proxy, using global proxy")
+		return nil
+	}
+	if config == nil {
+		return nil
+	}
+	config.Password = proxyPassword(credStore, accountID, config)
+	return config
+}
+
+// proxyPassword returns the stored password of a proxy that needs one
+func proxyPassword(credStore *credentials.Store, scope string, config *netproxy.Config) string {
+	if !config.Enabled() || config.Username == "" {
+		return ""
+	}
+	password, err := credStore.GetProxyPassword(scope)
+	if err != nil && err != credentials.ErrCredentialNotFound {
+		log := logging.WithComponent("app.proxy")
+		log.Warn().Err(err).Str("scope", scope).Msg("Failed to load proxy password")
+	}
+	return password
+}
+
+// hasProxyPassword reports whether a proxy password is stored for a scope
+func hasProxyPassword(credStore *credentials.Store, scope string) bool {
+	password, err := credStore.GetProxyPassword(scope)
+	return err == nil && password != ""
+}
+
+// saveProxyPassword stores, keeps or removes a proxy password after its
+// configuration changed
+func saveProxyPassword(credStore *credentials.Store, scope string, config *netproxy.Config, password string) error {
+	if !config.Enabled() || config.Username == "" {
+		return credStore.DeleteProxyPassword(scope)
+	}
+	if password == "" {
+		return nil
+	}
+	return credStore.SetProxyPassword(scope, password)
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/hkdb/aerion/internal/account"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/message"
+	"github.com/hkdb/aerion/internal/notification"
+	"github.com/hkdb/aerion/internal/settings"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// defaultLargestMessagesLimit is the number of messages listed when no limit is given
+const defaultLargestMessagesLimit = 50
+
+// QuotaStatus is the server quota of an account with its warning level
+type QuotaStatus struct {
+	AccountID string           `json:"accountId"`
+	Quotas    []*account.Quota `json:"quotas"`  // Empty if the server doesn't report quotas
+	Percent   float64          `json:"percent"` // Highest usage of any limited resource
+	Level     string           `json:"level"`   // "", "warning" or "critical"
+}
+
+// ============================================================================
+// Server Quota - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// GetAccountQuota returns the server quota of an account as of the last folder sync
+func (a *App) GetAccountQuota(accountID string) (*QuotaStatus, error) {
+	quotas, err := a.quotaStore.GetByAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+	config, err := a.settingsStore.GetQuotaWarningConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newQuotaStatus(accountID, quotas, config), nil
+}
+
+// GetQuotaWarningConfig returns the usage thresholds for quota warnings
+func (a *App) GetQuotaWarningConfig() (*settings.QuotaWarningConfig, error) {
+	return a.settingsStore.GetQuotaWarningConfig()
+}
+
+// SetQuotaWarningConfig saves the usage thresholds for quota warnings.
+// Accounts over a threshold are warned again on their next sync.
+func (a *App) SetQuotaWarningConfig(config settings.QuotaWarningConfig) error {
+	if err := a.settingsStore.SetQuotaWarningConfig(&config); err != nil {
+		return err
+	}
+
+	a.quotaLevelsMu.Lock()
+	a.quotaLevels = nil
+	a.quotaLevelsMu.Unlock()
+	return nil
+}
+
+// GetLargestMessages returns the largest messages of an account, to free
+// space on the server
+func (a *App) GetLargestMessages(accountID string, limit int) ([]*message.SizedMessage, error) {
+	if limit <= 0 {
+		limit = defaultLargestMessagesLimit
+	}
+	return a.messageStore.ListLargest(accountID, limit)
+}
+
+// GetLargestFolders returns the folders of an account by total message size
+func (a *App) GetLargestFolders(accountID string) ([]*message.FolderSize, error) {
+	return a.messageStore.FolderSizes(accountID)
+}
+
+// ============================================================================
+// Quota warnings
+// ============================================================================
+
+// newQuotaStatus summarizes quotas against the warning thresholds
+func newQuotaStatus(accountID string, quotas []*account.Quota, config *settings.QuotaWarningConfig) *QuotaStatus {
+	status := &QuotaStatus{AccountID: accountID, Quotas: quotas}
+	if status.Quotas == nil {
+		status.Quotas = []*account.Quota{}
+	}
+	for _, q := range quotas {
+		if p := q.Percent(); p > status.Percent {
+			status.Percent = p
+		}
+	}
+	status.Level = config.Level(status.Percent)
+	return status
+}
+
+// quotaLevelRank orders warning levels by severity
+func quotaLevelRank(level string) int {
+	switch level {
+	case settings.QuotaLevelCritical:
+		return 2
+	case settings.QuotaLevelWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// handleQuotaUpdate is called by the sync engine with refreshed quotas. It
+// warns once each time an account reaches a more severe level.
+func (a *App) handleQuotaUpdate(accountID string, quotas []*account.Quota) {
+	log := logging.WithComponent("app.quota")
+
+	config, err := a.settingsStore.GetQuotaWarningConfig()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load quota warning config")
+	}
+	status := newQuotaStatus(accountID, quotas, config)
+	wailsRuntime.EventsEmit(a.ctx, "quota:updated", status)
+
+	a.quotaLevelsMu.Lock()
+	if a.quotaLevels == nil {
+		a.quotaLevels = make(map[string]string)
+	}
+	previous, known := a.quotaLevels[accountID]
+	a.quotaLevels[accountID] = status.Level
+	a.quotaLevelsMu.Unlock()
+
+	if status.Level == settings.QuotaLevelOK || (known && quotaLevelRank(status.Level) <= quotaLevelRank(previous)) {
+		return
+	}
+
+	log.Info().
+		Str("accountID", accountID).
+		Float64("percent", status.Percent).
+		Str("level", status.Level).
+		Msg("Mailbox quota nearly full")
+	wailsRuntime.EventsEmit(a.ctx, "quota:warning", status)
+
+	if a.notifier != nil {
+		name := accountID
+		if acc, err := a.accountStore.Get(accountID); err == nil && acc != nil {
+			name = acc.Name
+		}
+		title := "Mailbox almost full"
+		if status.Level == settings.QuotaLevelCritical {
+			title = "Mailbox full"
+		}
+		_, err := a.notifier.Show(notification.Notification{
+			Title: title,
+			Body:  fmt.Sprintf("%s is using %.0f%% of its server quota. Delete or archive large messages to keep receiving mail.", name, status.Percent),
+			Icon:  "dialog-warning",
+			Data:  notification.NotificationData{AccountID: accountID},
+		})
+		if err != nil {
+			log.Debug().Err(err).Msg("Failed to send quota notification")
+		}
+	}
+}
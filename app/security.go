@@ -0,0 +1,386 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hkdb/aerion/internal/crypto"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/pgp"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// LockState describes the master password state for the frontend
+type LockState struct {
+	Enabled         bool `json:"enabled"`         // A master password is set
+	Locked          bool `json:"locked"`          // Encrypted data can't be read until unlocked
+	AutoLockMinutes int  `json:"autoLockMinutes"` // Lock after this much inactivity, 0 = never
+	Rewriting       bool `json:"rewriting"`       // Existing data is being encrypted or decrypted
+}
+
+// ============================================================================
+// Master Password - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// GetLockState returns whether a master password is set and whether the
+// database is locked
+func (a *App) GetLockState() LockState {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	state := LockState{
+		Enabled:   a.vault != nil,
+		Locked:    a.fieldCipher.Locked(),
+		Rewriting: a.vaultRewriting,
+	}
+	if a.vault != nil {
+		state.AutoLockMinutes = a.vault.AutoLockMinutes
+		// An interrupted removal needs the password to finish decrypting
+		if a.vault.Disabling && !a.fieldCipher.Unlocked() {
+			state.Locked = true
+		}
+	}
+	return state
+}
+
+// UnlockDatabase unlocks encrypted message data with the master password
+func (a *App) UnlockDatabase(password string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	if a.vault == nil {
+		return fmt.Errorf("no master password is set")
+	}
+
+	privateKey, err := a.vault.Unlock(password)
+	if err != nil {
+		return err
+	}
+	a.fieldCipher.Unlock(privateKey)
+	a.resetAutoLockTimerLocked()
+
+	log := logging.WithComponent("app.security")
+	log.Info().Msg("Database unlocked")
+	wailsRuntime.EventsEmit(a.ctx, "app:unlocked")
+
+	// Resume removing the password if the previous run was interrupted
+	if a.vault.Disabling && !a.vaultRewriting {
+		a.startFieldRewriteLocked(false)
+	}
+
+	// Attachment content that couldn't be moved while locked
+	go a.migrateAttachmentContent()
+	return nil
+}
+
+// LockDatabase locks encrypted message data until the master password is
+// entered again. Background sync keeps storing new mail while locked.
+func (a *App) LockDatabase() error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	if a.vault == nil {
+		return fmt.Errorf("no master password is set")
+	}
+	a.lockLocked()
+	return nil
+}
+
+// EnableMasterPassword sets a master password and encrypts existing message
+// bodies, snippets, raw S/MIME/PGP bodies, drafts and stored attachments in
+// the background. Progress is reported with "encryption:progress" events.
+// Spam classifier tokens stay in plaintext, see spam.Classifier.
+func (a *App) EnableMasterPassword(password string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	if a.vault != nil {
+		return fmt.Errorf("a master password is already set")
+	}
+	if a.vaultRewriting {
+		return fmt.Errorf("encryption changes are still in progress")
+	}
+
+	vault, privateKey, err := crypto.NewVault(password)
+	if err != nil {
+		return err
+	}
+	publicKey, err := vault.Public()
+	if err != nil {
+		return err
+	}
+	if err := vault.Save(a.paths.Data); err != nil {
+		return err
+	}
+
+	a.vault = vault
+	a.fieldCipher.Enable(publicKey)
+	a.fieldCipher.Unlock(privateKey)
+	a.resetAutoLockTimerLocked()
+
+	log := logging.WithComponent("app.security")
+	log.Info().Msg("Master password enabled")
+	a.startFieldRewriteLocked(true)
+	return nil
+}
+
+// DisableMasterPassword removes the master password and decrypts all
+// encrypted data in the background. The vault is kept, marked as disabling,
+// until every field has been decrypted; an interrupted run resumes on the
+// next unlock.
+func (a *App) DisableMasterPassword(password string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	if a.vault == nil {
+		return fmt.Errorf("no master password is set")
+	}
+	if a.vaultRewriting {
+		return fmt.Errorf("encryption changes are still in progress")
+	}
+
+	privateKey, err := a.vault.Unlock(password)
+	if err != nil {
+		return err
+	}
+	a.vault.Disabling = true
+	if err := a.vault.Save(a.paths.Data); err != nil {
+		a.vault.Disabling = false
+		return err
+	}
+	a.fieldCipher.Unlock(privateKey)
+	a.fieldCipher.Disable()
+	a.stopAutoLockTimerLocked()
+
+	log := logging.WithComponent("app.security")
+	log.Info().Msg("Master password disabled")
+	a.startFieldRewriteLocked(false)
+	return nil
+}
+
+// ChangeMasterPassword replaces the master password. Encrypted data is not
+// rewritten; only the vault's private key is re-wrapped.
+func (a *App) ChangeMasterPassword(oldPassword, newPassword string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	if a.vault == nil {
+		return fmt.Errorf("no master password is set")
+	}
+	if err := a.vault.ChangePassword(oldPassword, newPassword); err != nil {
+		return err
+	}
+	return a.vault.Save(a.paths.Data)
+}
+
+// SetAutoLockMinutes sets how long the app may be inactive before the
+// database locks itself. 0 disables auto-lock.
+func (a *App) SetAutoLockMinutes(minutes int) error {
+	if minutes < 0 {
+		return fmt.Errorf("auto-lock minutes must not be negative")
+	}
+
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	if a.vault == nil {
+		return fmt.Errorf("no master password is set")
+	}
+	a.vault.AutoLockMinutes = minutes
+	if err := a.vault.Save(a.paths.Data); err != nil {
+		return err
+	}
+	a.resetAutoLockTimerLocked()
+	return nil
+}
+
+// ReportActivity tells the backend the user is active, postponing auto-lock.
+// The frontend calls this (throttled) on input events.
+func (a *App) ReportActivity() {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+	a.resetAutoLockTimerLocked()
+}
+
+// ============================================================================
+// Master password lifecycle
+// ============================================================================
+
+// initMasterPassword sets up field encryption for the message stores. With a
+// master password set the app starts locked; new mail is still encrypted and
+// stored, but bodies can't be read until UnlockDatabase.
+func (a *App) initMasterPassword() {
+	log := logging.WithComponent("app.security")
+
+	a.fieldCipher = crypto.NewFieldCipher()
+	a.messageStore.SetFieldCipher(a.fieldCipher)
+	a.attachmentStore.SetFieldCipher(a.fieldCipher)
+	a.blobStore.SetFieldCipher(a.fieldCipher)
+	a.draftStore.SetFieldCipher(a.fieldCipher)
+
+	vault, err := crypto.LoadVault(a.paths.Data)
+	if err != nil {
+		// Don't silently fall back to plaintext storage
+		log.Fatal().Err(err).Msg("Failed to load master password vault")
+	}
+	if vault == nil {
+		return
+	}
+
+	publicKey, err := vault.Public()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load master password vault")
+	}
+
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	a.vault = vault
+
+	// A removal was interrupted: new writes stay in plaintext and the rest
+	// is decrypted once the password is entered
+	if vault.Disabling {
+		log.Info().Msg("Master password removal unfinished, database is locked")
+		return
+	}
+
+	a.fieldCipher.Enable(publicKey)
+	log.Info().Msg("Master password set, database is locked")
+
+	// Finish encrypting data stored before the password was set if the
+	// previous run was interrupted; this only needs the public key
+	if !vault.ExistingEncrypted {
+		a.startFieldRewriteLocked(true)
+	}
+}
+
+// lockLocked wipes the private key and tells the frontend. Callers must hold vaultMu.
+func (a *App) lockLocked() {
+	a.stopAutoLockTimerLocked()
+	pgp.LockAllKeys()
+	// Nothing to lock, or the password is being removed and the key is
+	// still needed to decrypt existing data
+	if !a.fieldCipher.Enabled() || a.fieldCipher.Locked() {
+		return
+	}
+	a.fieldCipher.Lock()
+
+	log := logging.WithComponent("app.security")
+	log.Info().Msg("Database locked")
+	wailsRuntime.EventsEmit(a.ctx, "app:locked")
+}
+
+// resetAutoLockTimerLocked restarts the inactivity timer if auto-lock is
+// enabled and the database is unlocked. Callers must hold vaultMu.
+func (a *App) resetAutoLockTimerLocked() {
+	a.stopAutoLockTimerLocked()
+	if a.vault == nil || a.vault.AutoLockMinutes <= 0 || a.vault.Disabling || a.fieldCipher.Locked() {
+		return
+	}
+
+	a.autoLockTimer = time.AfterFunc(time.Duration(a.vault.AutoLockMinutes)*time.Minute, func() {
+		a.vaultMu.Lock()
+		defer a.vaultMu.Unlock()
+		if a.vault != nil {
+			a.lockLocked()
+		}
+	})
+}
+
+// stopAutoLockTimerLocked stops the inactivity timer. Callers must hold vaultMu.
+func (a *App) stopAutoLockTimerLocked() {
+	if a.autoLockTimer != nil {
+		a.autoLockTimer.Stop()
+		a.autoLockTimer = nil
+	}
+}
+
+// startFieldRewriteLocked encrypts or decrypts existing data in the
+// background, then compacts the database so plaintext (or ciphertext) left in
+// freed pages is dropped. Callers must hold vaultMu.
+func (a *App) startFieldRewriteLocked(encrypt bool) {
+	a.vaultRewriting = true
+	go a.rewriteFields(encrypt)
+}
+
+// rewriteFields runs the re-key pass started by startFieldRewriteLocked
+func (a *App) rewriteFields(encrypt bool) {
+	log := logging.WithComponent("app.security")
+
+	progress := func(done, total int) {
+		wailsRuntime.EventsEmit(a.ctx, "encryption:progress", map[string]interface{}{
+			"encrypt": encrypt,
+			"done":    done,
+			"total":   total,
+		})
+	}
+
+	var err error
+	if encrypt {
+		err = a.messageStore.EncryptExistingFields(a.ctx, progress)
+		if err == nil {
+			err = a.draftStore.EncryptExisting(a.ctx, progress)
+		}
+		if err == nil {
+			err = a.blobStore.EncryptExisting(a.ctx, progress)
+		}
+	} else {
+		err = a.messageStore.DecryptExistingFields(a.ctx, progress)
+		if err == nil {
+			err = a.draftStore.DecryptExisting(a.ctx, progress)
+		}
+		if err == nil {
+			err = a.blobStore.DecryptExisting(a.ctx, progress)
+		}
+	}
+	if err == nil {
+		err = a.finishFieldRewrite(encrypt)
+	}
+	if err == nil {
+		if vacuumErr := a.db.Vacuum(); vacuumErr != nil {
+			log.Warn().Err(vacuumErr).Msg("Failed to compact database after re-key")
+		}
+	}
+
+	a.vaultMu.Lock()
+	a.vaultRewriting = false
+	a.vaultMu.Unlock()
+
+	errMsg := ""
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Error().Err(err).Bool("encrypt", encrypt).Msg("Failed to re-key existing data")
+		errMsg = err.Error()
+	} else if err == nil {
+		log.Info().Bool("encrypt", encrypt).Msg("Re-keyed existing data")
+	}
+	wailsRuntime.EventsEmit(a.ctx, "encryption:complete", map[string]interface{}{
+		"encrypt": encrypt,
+		"error":   errMsg,
+	})
+}
+
+// finishFieldRewrite records a completed re-key: after encrypting, the vault
+// is marked so the pass doesn't run again; after decrypting, the vault is
+// removed and the private key wiped.
+func (a *App) finishFieldRewrite(encrypt bool) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	if a.vault == nil {
+		return nil
+	}
+
+	if encrypt {
+		a.vault.ExistingEncrypted = true
+		return a.vault.Save(a.paths.Data)
+	}
+
+	if err := crypto.RemoveVault(a.paths.Data); err != nil {
+		return err
+	}
+	a.vault = nil
+	a.fieldCipher.Lock()
+	return nil
+}
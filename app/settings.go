@@ -4,8 +4,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/hkdb/aerion/internal/account"
-	"github.com/hkdb/aerion/internal/certificate"
+	"github.com/hkdb/aerion/internal/email"
 	"github.com/hkdb/aerion/internal/logging"
 	"github.com/hkdb/aerion/internal/settings"
 	"github.com/hkdb/aerion/internal/smtp"
@@ -98,6 +97,57 @@ func (a *App) SetTermsAccepted(accepted bool) error {
 	return a.settingsStore.SetTermsAccepted(accepted)
 }
 
+// GetLinkCleaningEnabled returns whether tracking links are cleaned when viewing messages
+func (a *App) GetLinkCleaningEnabled() (bool, error) {
+	return a.settingsStore.GetLinkCleaningEnabled()
+}
+
+// SetLinkCleaningEnabled sets whether tracking links are cleaned when viewing messages
+func (a *App) SetLinkCleaningEnabled(enabled bool) error {
+	return a.settingsStore.SetLinkCleaningEnabled(enabled)
+}
+
+// GetDefaultLinkCleanerRules returns the built-in tracking parameter and redirector rules
+func (a *App) GetDefaultLinkCleanerRules() email.LinkCleanerRules {
+	return email.DefaultLinkCleanerRules()
+}
+
+// GetCustomLinkCleanerRules returns the user-defined rules that extend the built-in defaults
+func (a *App) GetCustomLinkCleanerRules() (email.LinkCleanerRules, error) {
+	return a.settingsStore.GetCustomLinkCleanerRules()
+}
+
+// SetCustomLinkCleanerRules replaces the user-defined link cleaner rules
+func (a *App) SetCustomLinkCleanerRules(rules email.LinkCleanerRules) error {
+	return a.settingsStore.SetCustomLinkCleanerRules(rules)
+}
+
+// CleanLink strips tracking parameters and unwraps redirectors from a single URL
+// using the effective rules. Used by the frontend before opening a link.
+// URLs that cannot be parsed, or any URL when the rules can't be loaded, are
+// returned unchanged.
+func (a *App) CleanLink(rawURL string) string {
+	rules, err := a.settingsStore.GetLinkCleanerRules()
+	if err != nil {
+		log := logging.WithComponent("app")
+		log.Warn().Err(err).Msg("Failed to load link cleaner rules, leaving link unchanged")
+		return rawURL
+	}
+	cleaned, _ := email.NewLinkCleaner(rules).CleanURL(rawURL)
+	return cleaned
+}
+
+// GetNotificationPolicy returns the desktop notification policy (per-account and
+// per-folder switches, VIP senders, quiet hours and digest mode)
+func (a *App) GetNotificationPolicy() (*settings.NotificationPolicy, error) {
+	return a.settingsStore.GetNotificationPolicy()
+}
+
+// SetNotificationPolicy sets the desktop notification policy
+func (a *App) SetNotificationPolicy(policy settings.NotificationPolicy) error {
+	return a.settingsStore.SetNotificationPolicy(&policy)
+}
+
 // AddImageAllowlist adds a domain or sender to the image allowlist
 // entryType: "domain" or "sender"
 // value: the domain (e.g., "company.com") or email (e.g., "newsletter@company.com")
@@ -149,23 +199,16 @@ func (a *App) SendReadReceipt(accountID, messageID string) error {
 		return fmt.Errorf("failed to get account: %w", err)
 	}
 
-	// Get default identity for the account
+	// Answer from the alias the message was addressed to, else the default identity
 	identities, err := a.accountStore.GetIdentities(accountID)
 	if err != nil {
 		return fmt.Errorf("failed to get identities: %w", err)
 	}
 
 	var fromName, fromEmail string
-	for _, id := range identities {
-		if id.IsDefault {
-			fromName = id.Name
-			fromEmail = id.Email
-			break
-		}
-	}
-	if fromEmail == "" && len(identities) > 0 {
-		fromName = identities[0].Name
-		fromEmail = identities[0].Email
+	if id := replyIdentity(identities, msg); id != nil {
+		fromName = id.Name
+		fromEmail = id.Email
 	}
 	if fromEmail == "" {
 		fromEmail = acc.Email
@@ -178,40 +221,12 @@ func (a *App) SendReadReceipt(accountID, messageID string) error {
 		return fmt.Errorf("failed to build MDN: %w", err)
 	}
 
-	// Create SMTP config
-	smtpConfig := smtp.ClientConfig{
-		Host:      acc.SMTPHost,
-		Port:      acc.SMTPPort,
-		Username:  acc.Username,
-		Security:  smtp.SecurityType(acc.SMTPSecurity),
-		TLSConfig: certificate.BuildTLSConfig(acc.SMTPHost, a.certStore),
-	}
-
-	// Handle authentication based on auth type
-	if acc.AuthType == account.AuthOAuth2 {
-		// Get valid OAuth token (refreshing if needed)
-		tokens, err := a.getValidOAuthToken(accountID)
-		if err != nil {
-			return fmt.Errorf("failed to get OAuth token: %w", err)
-		}
-		smtpConfig.AuthType = smtp.AuthTypeOAuth2
-		smtpConfig.AccessToken = tokens.AccessToken
-	} else {
-		// Default to password authentication
-		password, err := a.credStore.GetPassword(accountID)
-		if err != nil {
-			return fmt.Errorf("failed to get password: %w", err)
-		}
-		smtpConfig.AuthType = smtp.AuthTypePassword
-		smtpConfig.Password = password
-	}
-
-	// Create SMTP client and connect
-	client := smtp.NewClient(smtpConfig)
-	if err := client.Connect(); err != nil {
-		return fmt.Errorf("failed to connect to SMTP: %w", err)
+	// Connect through the SMTP server of the identity sending the receipt
+	client, err := a.connectSMTP(accountID, acc, fromEmail)
+	if err != nil {
+		return err
 	}
-	defer client.Close()
+	defer a.smtpPool.Release(accountID, client)
 
 	// Extract recipient email
 	recipientEmail := extractEmailFromHeader(msg.ReadReceiptTo)
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"crypto/x509"
 	"fmt"
 	"os"
 
@@ -253,3 +254,98 @@ func (a *App) ImportRecipientCert(email, filePath string) error {
 
 	return a.smimeStore.ImportSenderCertFromFile(email, data)
 }
+
+// PickTrustAnchorFile opens a file picker for CA certificate files (.pem, .cer, .crt, .der)
+func (a *App) PickTrustAnchorFile() (string, error) {
+	path, err := wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Select Trusted CA Certificate",
+		Filters: []wailsRuntime.FileFilter{
+			{
+				DisplayName: "Certificate Files (*.pem, *.cer, *.crt, *.der)",
+				Pattern:     "*.pem;*.cer;*.crt;*.der",
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to open file dialog: %w", err)
+	}
+	return path, nil
+}
+
+// ImportSMIMETrustAnchors imports CA certificates from a file as trust anchors
+// for S/MIME signature verification (in addition to the system roots)
+func (a *App) ImportSMIMETrustAnchors(filePath string) ([]*smime.TrustAnchor, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("no file selected")
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+
+	// Accept a PEM bundle or a single DER certificate
+	certs, err := smime.ParseCertChainFromPEM(string(data))
+	if err != nil {
+		cert, derErr := x509.ParseCertificate(data)
+		if derErr != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = []*x509.Certificate{cert}
+	}
+
+	var anchors []*smime.TrustAnchor
+	for _, cert := range certs {
+		anchor, err := a.smimeStore.AddTrustAnchor(cert)
+		if err != nil {
+			return anchors, err
+		}
+		anchors = append(anchors, anchor)
+	}
+
+	// Cached results may have been computed without these anchors
+	if err := a.smimeStore.ClearRevocationCache(); err != nil {
+		return anchors, err
+	}
+	return anchors, nil
+}
+
+// ListSMIMETrustAnchors returns the user-imported S/MIME trust anchors
+func (a *App) ListSMIMETrustAnchors() ([]*smime.TrustAnchor, error) {
+	return a.smimeStore.ListTrustAnchors()
+}
+
+// DeleteSMIMETrustAnchor removes a user-imported S/MIME trust anchor
+func (a *App) DeleteSMIMETrustAnchor(id string) error {
+	return a.smimeStore.DeleteTrustAnchor(id)
+}
+
+// GetSMIMERevocationPolicy returns the S/MIME revocation checking policy
+// Values: "off", "soft_fail", "hard_fail"
+func (a *App) GetSMIMERevocationPolicy() (string, error) {
+	value, err := a.settingsStore.GetSMIMERevocationPolicy()
+	if err != nil {
+		return string(smime.RevocationPolicySoftFail), err
+	}
+	policy, err := smime.ParseRevocationPolicy(value)
+	return string(policy), err
+}
+
+// SetSMIMERevocationPolicy sets the S/MIME revocation checking policy
+// Valid values: "off", "soft_fail", "hard_fail"
+func (a *App) SetSMIMERevocationPolicy(policy string) error {
+	parsed, err := smime.ParseRevocationPolicy(policy)
+	if err != nil {
+		return err
+	}
+	if err := a.settingsStore.SetSMIMERevocationPolicy(string(parsed)); err != nil {
+		return err
+	}
+	a.smimeVerifier.SetRevocationPolicy(parsed)
+	return nil
+}
+
+// ClearSMIMERevocationCache forgets cached OCSP/CRL results so they are re-checked
+func (a *App) ClearSMIMERevocationCache() error {
+	return a.smimeStore.ClearRevocationCache()
+}
@@ -0,0 +1,218 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/hkdb/aerion/internal/folder"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/message"
+	"github.com/hkdb/aerion/internal/settings"
+	"github.com/hkdb/aerion/internal/spam"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ============================================================================
+// Spam Classifier API - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// SpamFilterSettings holds the spam filter configuration
+type SpamFilterSettings struct {
+	Enabled   bool    `json:"enabled"`
+	Action    string  `json:"action"`    // "tag" or "move"
+	Threshold float64 `json:"threshold"` // 0.5 - 1.0
+}
+
+// spamTrainingBatchLimit caps how many messages per folder are used for the initial training pass
+const spamTrainingBatchLimit = 500
+
+// GetSpamFilterSettings returns the spam filter configuration
+func (a *App) GetSpamFilterSettings() (*SpamFilterSettings, error) {
+	enabled, err := a.settingsStore.GetSpamFilterEnabled()
+	if err != nil {
+		return nil, err
+	}
+	action, err := a.settingsStore.GetSpamFilterAction()
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := a.settingsStore.GetSpamFilterThreshold()
+	if err != nil {
+		return nil, err
+	}
+	return &SpamFilterSettings{
+		Enabled:   enabled,
+		Action:    action,
+		Threshold: threshold,
+	}, nil
+}
+
+// SetSpamFilterSettings updates the spam filter configuration
+func (a *App) SetSpamFilterSettings(cfg SpamFilterSettings) error {
+	if err := a.settingsStore.SetSpamFilterAction(cfg.Action); err != nil {
+		return err
+	}
+	if err := a.settingsStore.SetSpamFilterThreshold(cfg.Threshold); err != nil {
+		return err
+	}
+	if err := a.settingsStore.SetSpamFilterEnabled(cfg.Enabled); err != nil {
+		return err
+	}
+	a.spamClassifier.SetEnabled(cfg.Enabled)
+	return nil
+}
+
+// GetSpamStats returns spam classifier training statistics
+func (a *App) GetSpamStats() (*spam.Stats, error) {
+	return a.spamClassifier.Stats()
+}
+
+// GetSpamScores returns the stored spam scores for the given messages.
+// Messages that were never scored are omitted.
+func (a *App) GetSpamScores(messageIDs []string) (map[string]float64, error) {
+	return a.spamClassifier.GetScores(messageIDs)
+}
+
+// ResetSpamClassifier forgets all spam training data
+func (a *App) ResetSpamClassifier() error {
+	return a.spamClassifier.Reset()
+}
+
+// TrainSpamFromFolders runs an initial training pass for an account, learning
+// the Spam folder as spam and read Inbox messages as ham
+func (a *App) TrainSpamFromFolders(accountID string) (*spam.Stats, error) {
+	log := logging.WithComponent("app.spam")
+
+	spamFolder, err := a.GetSpecialFolder(accountID, folder.TypeSpam)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spam folder: %w", err)
+	}
+	inboxFolder, err := a.folderStore.GetByType(accountID, folder.TypeInbox)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbox folder: %w", err)
+	}
+
+	trainFolder := func(f *folder.Folder, isSpam bool) int {
+		if f == nil {
+			return 0
+		}
+		headers, err := a.messageStore.ListByFolder(f.ID, 0, spamTrainingBatchLimit)
+		if err != nil {
+			log.Warn().Err(err).Str("folder", f.Path).Msg("Failed to list messages for spam training")
+			return 0
+		}
+		ids := make([]string, 0, len(headers))
+		for _, h := range headers {
+			// Unread inbox mail hasn't been looked at, so it isn't known to be ham
+			if !isSpam && !h.IsRead {
+				continue
+			}
+			ids = append(ids, h.ID)
+		}
+		messages, err := a.messageStore.GetByIDs(ids)
+		if err != nil {
+			log.Warn().Err(err).Str("folder", f.Path).Msg("Failed to load messages for spam training")
+			return 0
+		}
+		return a.trainSpamMessages(messages, isSpam)
+	}
+
+	spamCount := trainFolder(spamFolder, true)
+	hamCount := trainFolder(inboxFolder, false)
+
+	log.Info().
+		Str("accountID", accountID).
+		Int("spam", spamCount).
+		Int("ham", hamCount).
+		Msg("Initial spam training complete")
+
+	return a.spamClassifier.Stats()
+}
+
+// initSpamClassifier creates the spam classifier and wires it into the sync engine
+func (a *App) initSpamClassifier() {
+	log := logging.WithComponent("app.spam")
+
+	a.spamClassifier = spam.NewClassifier(a.db.DB)
+
+	enabled, err := a.settingsStore.GetSpamFilterEnabled()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to read spam filter setting")
+	}
+	a.spamClassifier.SetEnabled(enabled)
+
+	a.syncEngine.SetSpamClassifier(a.spamClassifier, a.handleSpamScores)
+}
+
+// handleSpamScores is called by the sync engine with scores of newly synced
+// inbox messages. Probable spam is moved to the Spam folder or tagged.
+func (a *App) handleSpamScores(accountID, folderID string, scores map[string]float64) {
+	log := logging.WithComponent("app.spam")
+
+	threshold, _ := a.settingsStore.GetSpamFilterThreshold()
+	action, _ := a.settingsStore.GetSpamFilterAction()
+
+	var probable []string
+	for id, score := range scores {
+		if score >= threshold {
+			probable = append(probable, id)
+		}
+	}
+	if len(probable) == 0 {
+		return
+	}
+
+	log.Info().
+		Str("accountID", accountID).
+		Int("count", len(probable)).
+		Str("action", action).
+		Msg("Detected probable spam")
+
+	wailsRuntime.EventsEmit(a.ctx, "spam:detected", map[string]interface{}{
+		"accountId":  accountID,
+		"folderId":   folderID,
+		"messageIds": probable,
+		"action":     action,
+	})
+
+	if action != settings.SpamActionMove {
+		return
+	}
+
+	// Move outside the sync call so the header sync isn't held up by IMAP MOVE
+	go func() {
+		spamFolder, err := a.GetSpecialFolder(accountID, folder.TypeSpam)
+		if err != nil || spamFolder == nil {
+			log.Warn().Err(err).Str("accountID", accountID).Msg("No spam folder to move probable spam to")
+			return
+		}
+		if err := a.MoveToFolder(probable, spamFolder.ID); err != nil {
+			log.Error().Err(err).Msg("Failed to move probable spam")
+		}
+	}()
+}
+
+// trainSpamMessages trains the classifier with the given messages and returns
+// how many were trained
+func (a *App) trainSpamMessages(messages []*message.Message, isSpam bool) int {
+	log := logging.WithComponent("app.spam")
+
+	trained := 0
+	for _, m := range messages {
+		if err := a.spamClassifier.Train(m, isSpam); err != nil {
+			log.Warn().Err(err).Str("messageID", m.ID).Msg("Failed to train spam classifier")
+			continue
+		}
+		trained++
+	}
+	return trained
+}
+
+// learnFromSpamAction trains the classifier from a user's Mark as (Not) Spam action.
+// Messages are loaded before the move so their content is still available.
+func (a *App) learnFromSpamAction(messageIDs []string, isSpam bool) {
+	messages, err := a.messageStore.GetByIDs(messageIDs)
+	if err != nil || len(messages) == 0 {
+		return
+	}
+	go a.trainSpamMessages(messages, isSpam)
+}
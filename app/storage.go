@@ -0,0 +1,107 @@
+package app
+
+import (
+	"time"
+
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/settings"
+	"github.com/hkdb/aerion/internal/storage"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ============================================================================
+// Storage Management - Exposed to frontend via Wails bindings
+// ============================================================================
+
+// GetStorageUsage returns disk usage of the database, search index, cached
+// bodies and attachments per account and folder
+func (a *App) GetStorageUsage() (*storage.Usage, error) {
+	return a.storageManager.Usage()
+}
+
+// GetStorageConfig returns the cache budget and maintenance interval
+func (a *App) GetStorageConfig() (*settings.StorageConfig, error) {
+	return a.settingsStore.GetStorageConfig()
+}
+
+// SetStorageConfig saves the cache budget and maintenance interval. A lower
+// budget is enforced in the background right away.
+func (a *App) SetStorageConfig(config settings.StorageConfig) error {
+	if err := a.settingsStore.SetStorageConfig(&config); err != nil {
+		return err
+	}
+	a.storageScheduler.CheckNow()
+	return nil
+}
+
+// EnforceStorageBudget evicts bodies and attachments of the oldest read
+// messages until the cache fits in the configured budget. Evicted bodies are
+// fetched again when the message is opened.
+func (a *App) EnforceStorageBudget() (*storage.EvictionResult, error) {
+	config, err := a.settingsStore.GetStorageConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.storageManager.EnforceBudget(a.ctx, config.CacheBudgetBytes())
+	if err != nil {
+		return nil, err
+	}
+	if result.Messages > 0 {
+		wailsRuntime.EventsEmit(a.ctx, "storage:evicted", result)
+	}
+	return result, nil
+}
+
+// RunStorageMaintenance enforces the cache budget, optimizes the search index
+// and compacts the database now
+func (a *App) RunStorageMaintenance() (*storage.MaintenanceResult, error) {
+	log := logging.WithComponent("app.storage")
+
+	config, err := a.settingsStore.GetStorageConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.storageManager.Maintain(a.ctx, config.CacheBudgetBytes())
+	if err != nil {
+		log.Error().Err(err).Msg("Storage maintenance failed")
+		return nil, err
+	}
+	if err := a.settingsStore.SetLastStorageMaintenance(time.Now()); err != nil {
+		log.Warn().Err(err).Msg("Failed to record maintenance time")
+	}
+	return result, nil
+}
+
+// ============================================================================
+// Attachment blob migration
+// ============================================================================
+
+// migrateAttachmentContent moves attachment content stored in the database
+// by earlier versions (or restored from a backup) to the blob store. Runs in
+// the background; encrypted content is left until the database is unlocked.
+func (a *App) migrateAttachmentContent() {
+	log := logging.WithComponent("app.storage")
+
+	// Another run will pick up whatever this one would have found
+	if !a.blobMigrationRunning.TryLock() {
+		return
+	}
+	defer a.blobMigrationRunning.Unlock()
+
+	migrated, skipped, err := a.attachmentStore.MigrateContentToBlobs(a.ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to move attachment content to blob store")
+		return
+	}
+	if skipped > 0 {
+		log.Info().Int("skipped", skipped).Msg("Attachment content left in database until unlocked")
+	}
+	if migrated > 0 {
+		// Give the space back to the file system
+		if _, err := a.storageManager.Maintain(a.ctx, 0); err != nil {
+			log.Warn().Err(err).Msg("Failed to compact database after moving attachment content")
+		}
+	}
+}
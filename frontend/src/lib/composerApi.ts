@@ -19,6 +19,9 @@ import { smtp, account, contact, app, draft } from '../../wailsjs/go/models'
 export interface ComposerApi {
   /** Send a composed email */
   sendMessage: (accountId: string, message: smtp.ComposeMessage) => Promise<void>
+
+  /** Send an oversized message as one message per group of attachments, resuming after the parts in resume */
+  sendMessageSplit: (accountId: string, message: smtp.ComposeMessage, resume: app.SplitSendProgress) => Promise<app.SplitSendProgress>
   
   /** Search contacts for autocomplete */
   searchContacts: (query: string, limit: number) => Promise<contact.Contact[]>
@@ -108,6 +111,11 @@ export function createMainWindowApi(): ComposerApi {
       const { SendMessage } = await import('../../wailsjs/go/app/App.js')
       return SendMessage(accountId, message)
     },
+
+    sendMessageSplit: async (accountId: string, message: smtp.ComposeMessage, resume: app.SplitSendProgress) => {
+      const { SendMessageSplit } = await import('../../wailsjs/go/app/App.js')
+      return SendMessageSplit(accountId, message, resume)
+    },
     
     searchContacts: async (query: string, limit: number) => {
       const { SearchContacts } = await import('../../wailsjs/go/app/App.js')
@@ -233,6 +241,11 @@ export function createComposerWindowApi(accountId: string): ComposerApi {
       // ComposerApp.SendMessage doesn't take accountId (it's set in config)
       return SendMessage(message)
     },
+
+    sendMessageSplit: async (_accountId: string, message: smtp.ComposeMessage, resume: app.SplitSendProgress) => {
+      const { SendMessageSplit } = await import('../../wailsjs/go/app/ComposerApp.js')
+      return SendMessageSplit(message, resume)
+    },
     
     searchContacts: async (query: string, limit: number) => {
       const { SearchContacts } = await import('../../wailsjs/go/app/ComposerApp.js')
@@ -210,6 +210,8 @@ export function GetUnifiedInboxCount():Promise<number>;
 
 export function GetUnifiedInboxUnreadCount():Promise<number>;
 
+export function GrantCalendarAccess(arg1:string):Promise<void>;
+
 export function HasPGPKey(arg1:string):Promise<boolean>;
 
 export function HasSMIMECertificate(arg1:string):Promise<boolean>;
@@ -340,6 +342,8 @@ export function SearchUnifiedInbox(arg1:string,arg2:number,arg3:number):Promise<
 
 export function SendMessage(arg1:string,arg2:smtp.ComposeMessage):Promise<void>;
 
+export function SendMessageSplit(arg1:string,arg2:smtp.ComposeMessage,arg3:app.SplitSendProgress):Promise<app.SplitSendProgress>;
+
 export function SendReadReceipt(arg1:string,arg2:string):Promise<void>;
 
 export function SetAccountEnabled(arg1:string,arg2:boolean):Promise<void>;
@@ -390,6 +390,10 @@ export function GetUnifiedInboxUnreadCount() {
   return window['go']['app']['App']['GetUnifiedInboxUnreadCount']();
 }
 
+export function GrantCalendarAccess(arg1) {
+  return window['go']['app']['App']['GrantCalendarAccess'](arg1);
+}
+
 export function HasPGPKey(arg1) {
   return window['go']['app']['App']['HasPGPKey'](arg1);
 }
@@ -650,6 +654,10 @@ export function SendMessage(arg1, arg2) {
   return window['go']['app']['App']['SendMessage'](arg1, arg2);
 }
 
+export function SendMessageSplit(arg1, arg2, arg3) {
+  return window['go']['app']['App']['SendMessageSplit'](arg1, arg2, arg3);
+}
+
 export function SendReadReceipt(arg1, arg2) {
   return window['go']['app']['App']['SendReadReceipt'](arg1, arg2);
 }
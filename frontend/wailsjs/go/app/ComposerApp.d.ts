@@ -68,6 +68,8 @@ export function SearchContacts(arg1:string,arg2:number):Promise<Array<contact.Co
 
 export function SendMessage(arg1:smtp.ComposeMessage):Promise<void>;
 
+export function SendMessageSplit(arg1:smtp.ComposeMessage,arg2:app.SplitSendProgress):Promise<app.SplitSendProgress>;
+
 export function Shutdown(arg1:context.Context):Promise<void>;
 
 export function Startup(arg1:context.Context):Promise<void>;
@@ -122,6 +122,10 @@ export function SendMessage(arg1) {
   return window['go']['app']['ComposerApp']['SendMessage'](arg1);
 }
 
+export function SendMessageSplit(arg1, arg2) {
+  return window['go']['app']['ComposerApp']['SendMessageSplit'](arg1, arg2);
+}
+
 export function Shutdown(arg1) {
   return window['go']['app']['ComposerApp']['Shutdown'](arg1);
 }
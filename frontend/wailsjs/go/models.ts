@@ -548,6 +548,24 @@ export namespace app {
 		    return a;
 		}
 	}
+	export class SplitSendProgress {
+	    messageId: string;
+	    sent: number;
+	    total: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SplitSendProgress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.messageId = source["messageId"];
+	        this.sent = source["sent"];
+	        this.total = source["total"];
+	        this.error = source["error"];
+	    }
+	}
 
 }
 
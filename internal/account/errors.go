@@ -10,6 +10,8 @@ var (
 	ErrIMAPHostRequired    = errors.New("IMAP host is required")
 	ErrSMTPHostRequired    = errors.New("SMTP host is required")
 	ErrUsernameRequired    = errors.New("username is required")
+	ErrInvalidSMTPPort     = errors.New("invalid SMTP port")
+	ErrInvalidSMTPSecurity = errors.New("invalid SMTP security type")
 
 	// Storage errors
 	ErrAccountNotFound = errors.New("account not found")
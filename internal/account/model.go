@@ -109,6 +109,13 @@ type Identity struct {
 	SignaturePlacement  string `json:"signaturePlacement"`  // "above" or "below" quoted text (default: "above")
 	SignatureSeparator  bool   `json:"signatureSeparator"`  // Add "-- " before signature (default: false)
 
+	// SMTP server override (empty host = send through the account's server).
+	// The password is stored in the credential store.
+	SMTPHost     string       `json:"smtpHost,omitempty"`
+	SMTPPort     int          `json:"smtpPort,omitempty"`
+	SMTPSecurity SecurityType `json:"smtpSecurity,omitempty"`
+	SMTPUsername string       `json:"smtpUsername,omitempty"` // Empty = relay without authentication
+
 	OrderIndex int       `json:"orderIndex"`
 	CreatedAt  time.Time `json:"createdAt"`
 	UpdatedAt  time.Time `json:"updatedAt"`
@@ -126,6 +133,17 @@ type IdentityConfig struct {
 	SignatureForForward bool   `json:"signatureForForward"`
 	SignaturePlacement  string `json:"signaturePlacement"`
 	SignatureSeparator  bool   `json:"signatureSeparator"`
+
+	SMTPHost     string       `json:"smtpHost,omitempty"`
+	SMTPPort     int          `json:"smtpPort,omitempty"`
+	SMTPSecurity SecurityType `json:"smtpSecurity,omitempty"`
+	SMTPUsername string       `json:"smtpUsername,omitempty"`
+	SMTPPassword string       `json:"smtpPassword,omitempty"` // Not stored in DB, goes to keyring; empty keeps the stored one
+}
+
+// HasSMTPServer reports whether the identity sends through its own SMTP server
+func (i *Identity) HasSMTPServer() bool {
+	return i.SMTPHost != ""
 }
 
 // Validate validates the identity configuration
@@ -143,6 +161,26 @@ func (c *IdentityConfig) Validate() error {
 	if c.SignaturePlacement != "above" && c.SignaturePlacement != "below" {
 		c.SignaturePlacement = "above"
 	}
+	// SMTP override: without a host the account's server is used
+	if c.SMTPHost == "" {
+		c.SMTPPort = 0
+		c.SMTPSecurity = ""
+		c.SMTPUsername = ""
+		c.SMTPPassword = ""
+		return nil
+	}
+	if c.SMTPPort <= 0 {
+		c.SMTPPort = 587
+	}
+	if c.SMTPPort > 65535 {
+		return ErrInvalidSMTPPort
+	}
+	if c.SMTPSecurity == "" {
+		c.SMTPSecurity = SecurityStartTLS
+	}
+	if c.SMTPSecurity != SecurityNone && c.SMTPSecurity != SecurityTLS && c.SMTPSecurity != SecurityStartTLS {
+		return ErrInvalidSMTPSecurity
+	}
 	return nil
 }
 
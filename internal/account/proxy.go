@@ -0,0 +1,56 @@
+package account
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hkdb/aerion/internal/netproxy"
+)
+
+// GetProxy returns the proxy of an account, or nil if it uses the global
+// proxy. The password is not included; it is kept in the credential store.
+func (s *Store) GetProxy(id string) (*netproxy.Config, error) {
+	var value sql.NullString
+	err := s.db.QueryRow("SELECT proxy FROM accounts WHERE id = ?", id).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account proxy: %w", err)
+	}
+	if !value.Valid || value.String == "" {
+		return nil, nil
+	}
+
+	config := &netproxy.Config{}
+	if err := json.Unmarshal([]byte(value.String), config); err != nil {
+		return nil, fmt.Errorf("failed to parse account proxy: %w", err)
+	}
+	return config, nil
+}
+
+// SetProxy sets the proxy of an account. nil or netproxy.TypeDefault makes
+// the account use the global proxy.
+func (s *Store) SetProxy(id string, config *netproxy.Config) error {
+	var value sql.NullString
+	if config != nil && config.Type != netproxy.TypeDefault {
+		if err := config.Validate(); err != nil {
+			return err
+		}
+		data, err := json.Marshal(config)
+		if err != nil {
+			return fmt.Errorf("failed to encode account proxy: %w", err)
+		}
+		value = sql.NullString{String: string(data), Valid: true}
+	}
+
+	result, err := s.db.Exec("UPDATE accounts SET proxy = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", value, id)
+	if err != nil {
+		return fmt.Errorf("failed to set account proxy: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package account
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hkdb/aerion/internal/database"
+)
+
+// Quota resources reported by servers (RFC 9208)
+const (
+	QuotaStorage  = "STORAGE" // Total size of messages, in bytes
+	QuotaMessages = "MESSAGE" // Number of messages
+)
+
+// Quota is the server-side usage and limit of a resource for an account
+type Quota struct {
+	AccountID string    `json:"accountId"`
+	Root      string    `json:"root"`
+	Resource  string    `json:"resource"`
+	Usage     int64     `json:"usage"`
+	Limit     int64     `json:"limit"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Percent returns the usage as a percentage of the limit, 0 if unlimited
+func (q *Quota) Percent() float64 {
+	if q.Limit <= 0 {
+		return 0
+	}
+	return float64(q.Usage) * 100 / float64(q.Limit)
+}
+
+// QuotaStore persists the last known server quotas of accounts
+type QuotaStore struct {
+	db *database.DB
+}
+
+// NewQuotaStore creates a new quota store
+func NewQuotaStore(db *database.DB) *QuotaStore {
+	return &QuotaStore{db: db}
+}
+
+// Replace stores the quotas of an account, dropping resources the server no
+// longer reports
+func (s *QuotaStore) Replace(accountID string, quotas []*Quota) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM account_quotas WHERE account_id = ?", accountID); err != nil {
+		return fmt.Errorf("failed to clear quotas: %w", err)
+	}
+
+	now := time.Now()
+	for _, q := range quotas {
+		_, err := tx.Exec(`
+			INSERT INTO account_quotas (account_id, quota_root, resource, usage, quota_limit, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(account_id, quota_root, resource) DO UPDATE SET
+				usage = excluded.usage,
+				quota_limit = excluded.quota_limit,
+				updated_at = excluded.updated_at
+		`, accountID, q.Root, q.Resource, q.Usage, q.Limit, now)
+		if err != nil {
+			return fmt.Errorf("failed to save quota: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetByAccount returns the last known quotas of an account, empty if the
+// server doesn't report any
+func (s *QuotaStore) GetByAccount(accountID string) ([]*Quota, error) {
+	rows, err := s.db.Query(`
+		SELECT account_id, quota_root, resource, usage, quota_limit, updated_at
+		FROM account_quotas WHERE account_id = ?
+		ORDER BY quota_root, resource
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quotas: %w", err)
+	}
+	defer rows.Close()
+
+	var quotas []*Quota
+	for rows.Next() {
+		q := &Quota{}
+		if err := rows.Scan(&q.AccountID, &q.Root, &q.Resource, &q.Usage, &q.Limit, &q.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan quota: %w", err)
+		}
+		quotas = append(quotas, q)
+	}
+	return quotas, rows.Err()
+}
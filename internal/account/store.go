@@ -380,7 +380,9 @@ func (s *Store) GetIdentities(accountID string) ([]*Identity, error) {
 	rows, err := s.db.Query(`
 		SELECT id, account_id, email, name, is_default, signature_html, signature_text,
 			signature_enabled, signature_for_new, signature_for_reply, signature_for_forward,
-			signature_placement, signature_separator, order_index, created_at, updated_at
+			signature_placement, signature_separator,
+			smtp_host, smtp_port, smtp_security, smtp_username,
+			order_index, created_at, updated_at
 		FROM identities WHERE account_id = ? ORDER BY order_index
 	`, accountID)
 	if err != nil {
@@ -391,13 +393,15 @@ func (s *Store) GetIdentities(accountID string) ([]*Identity, error) {
 	var identities []*Identity
 	for rows.Next() {
 		identity := &Identity{}
-		var sigHTML, sigText, placement sql.NullString
+		var sigHTML, sigText, placement, smtpHost, smtpSecurity, smtpUsername sql.NullString
 		var updatedAt sql.NullTime
 		err := rows.Scan(
 			&identity.ID, &identity.AccountID, &identity.Email, &identity.Name,
 			&identity.IsDefault, &sigHTML, &sigText,
 			&identity.SignatureEnabled, &identity.SignatureForNew, &identity.SignatureForReply, &identity.SignatureForForward,
-			&placement, &identity.SignatureSeparator, &identity.OrderIndex, &identity.CreatedAt, &updatedAt,
+			&placement, &identity.SignatureSeparator,
+			&smtpHost, &identity.SMTPPort, &smtpSecurity, &smtpUsername,
+			&identity.OrderIndex, &identity.CreatedAt, &updatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan identity: %w", err)
@@ -405,6 +409,9 @@ func (s *Store) GetIdentities(accountID string) ([]*Identity, error) {
 		identity.SignatureHTML = sigHTML.String
 		identity.SignatureText = sigText.String
 		identity.SignaturePlacement = placement.String
+		identity.SMTPHost = smtpHost.String
+		identity.SMTPSecurity = SecurityType(smtpSecurity.String)
+		identity.SMTPUsername = smtpUsername.String
 		if identity.SignaturePlacement == "" {
 			identity.SignaturePlacement = "above"
 		}
@@ -422,18 +429,22 @@ func (s *Store) GetIdentities(accountID string) ([]*Identity, error) {
 // GetIdentity retrieves a single identity by ID
 func (s *Store) GetIdentity(id string) (*Identity, error) {
 	identity := &Identity{}
-	var sigHTML, sigText, placement sql.NullString
+	var sigHTML, sigText, placement, smtpHost, smtpSecurity, smtpUsername sql.NullString
 	var updatedAt sql.NullTime
 	err := s.db.QueryRow(`
 		SELECT id, account_id, email, name, is_default, signature_html, signature_text,
 			signature_enabled, signature_for_new, signature_for_reply, signature_for_forward,
-			signature_placement, signature_separator, order_index, created_at, updated_at
+			signature_placement, signature_separator,
+			smtp_host, smtp_port, smtp_security, smtp_username,
+			order_index, created_at, updated_at
 		FROM identities WHERE id = ?
 	`, id).Scan(
 		&identity.ID, &identity.AccountID, &identity.Email, &identity.Name,
 		&identity.IsDefault, &sigHTML, &sigText,
 		&identity.SignatureEnabled, &identity.SignatureForNew, &identity.SignatureForReply, &identity.SignatureForForward,
-		&placement, &identity.SignatureSeparator, &identity.OrderIndex, &identity.CreatedAt, &updatedAt,
+		&placement, &identity.SignatureSeparator,
+		&smtpHost, &identity.SMTPPort, &smtpSecurity, &smtpUsername,
+		&identity.OrderIndex, &identity.CreatedAt, &updatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, ErrIdentityNotFound
@@ -444,6 +455,9 @@ func (s *Store) GetIdentity(id string) (*Identity, error) {
 	identity.SignatureHTML = sigHTML.String
 	identity.SignatureText = sigText.String
 	identity.SignaturePlacement = placement.String
+	identity.SMTPHost = smtpHost.String
+	identity.SMTPSecurity = SecurityType(smtpSecurity.String)
+	identity.SMTPUsername = smtpUsername.String
 	if identity.SignaturePlacement == "" {
 		identity.SignaturePlacement = "above"
 	}
@@ -480,6 +494,10 @@ func (s *Store) CreateIdentity(accountID string, config *IdentityConfig) (*Ident
 		SignatureForForward: config.SignatureForForward,
 		SignaturePlacement:  config.SignaturePlacement,
 		SignatureSeparator:  config.SignatureSeparator,
+		SMTPHost:            config.SMTPHost,
+		SMTPPort:            config.SMTPPort,
+		SMTPSecurity:        config.SMTPSecurity,
+		SMTPUsername:        config.SMTPUsername,
 		OrderIndex:          maxOrder + 1,
 		CreatedAt:           now,
 		UpdatedAt:           now,
@@ -489,13 +507,17 @@ func (s *Store) CreateIdentity(accountID string, config *IdentityConfig) (*Ident
 		INSERT INTO identities (
 			id, account_id, email, name, is_default, signature_html, signature_text,
 			signature_enabled, signature_for_new, signature_for_reply, signature_for_forward,
-			signature_placement, signature_separator, order_index, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			signature_placement, signature_separator,
+			smtp_host, smtp_port, smtp_security, smtp_username,
+			order_index, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		identity.ID, identity.AccountID, identity.Email, identity.Name, identity.IsDefault,
 		nullableString(identity.SignatureHTML), nullableString(identity.SignatureText),
 		identity.SignatureEnabled, identity.SignatureForNew, identity.SignatureForReply, identity.SignatureForForward,
-		identity.SignaturePlacement, identity.SignatureSeparator, identity.OrderIndex, identity.CreatedAt, identity.UpdatedAt,
+		identity.SignaturePlacement, identity.SignatureSeparator,
+		nullableString(identity.SMTPHost), identity.SMTPPort, nullableString(string(identity.SMTPSecurity)), nullableString(identity.SMTPUsername),
+		identity.OrderIndex, identity.CreatedAt, identity.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create identity: %w", err)
@@ -521,12 +543,15 @@ func (s *Store) UpdateIdentity(id string, config *IdentityConfig) (*Identity, er
 		UPDATE identities SET
 			email = ?, name = ?, signature_html = ?, signature_text = ?,
 			signature_enabled = ?, signature_for_new = ?, signature_for_reply = ?, signature_for_forward = ?,
-			signature_placement = ?, signature_separator = ?, updated_at = ?
+			signature_placement = ?, signature_separator = ?,
+			smtp_host = ?, smtp_port = ?, smtp_security = ?, smtp_username = ?, updated_at = ?
 		WHERE id = ?
 	`,
 		config.Email, config.Name, nullableString(config.SignatureHTML), nullableString(config.SignatureText),
 		config.SignatureEnabled, config.SignatureForNew, config.SignatureForReply, config.SignatureForForward,
-		config.SignaturePlacement, config.SignatureSeparator, now, id,
+		config.SignaturePlacement, config.SignatureSeparator,
+		nullableString(config.SMTPHost), config.SMTPPort, nullableString(string(config.SMTPSecurity)), nullableString(config.SMTPUsername),
+		now, id,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update identity: %w", err)
@@ -542,6 +567,10 @@ func (s *Store) UpdateIdentity(id string, config *IdentityConfig) (*Identity, er
 	existing.SignatureForForward = config.SignatureForForward
 	existing.SignaturePlacement = config.SignaturePlacement
 	existing.SignatureSeparator = config.SignatureSeparator
+	existing.SMTPHost = config.SMTPHost
+	existing.SMTPPort = config.SMTPPort
+	existing.SMTPSecurity = config.SMTPSecurity
+	existing.SMTPUsername = config.SMTPUsername
 	existing.UpdatedAt = now
 
 	return existing, nil
@@ -0,0 +1,253 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hkdb/aerion/internal/crypto"
+)
+
+// archiveMagic identifies an Aerion profile backup
+const archiveMagic = "AERIONBK"
+
+// archiveVersion is the current container format
+const archiveVersion = 1
+
+// chunkSize is the plaintext size of each encrypted chunk
+const chunkSize = 64 * 1024
+
+// noncePrefixSize is the random part of each chunk nonce; the rest is the
+// chunk counter and a final-chunk flag
+const noncePrefixSize = 7
+
+// ErrWrongPassphrase is returned when a backup can't be decrypted
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted backup")
+
+// archiveHeader is stored in plaintext at the start of a backup and
+// authenticated as associated data of every chunk
+type archiveHeader struct {
+	Version     int                    `json:"version"`
+	KDF         crypto.MasterKeyParams `json:"kdf"`
+	NoncePrefix []byte                 `json:"noncePrefix"`
+}
+
+// encryptWriter encrypts a stream in fixed-size AES-256-GCM chunks.
+// Each chunk nonce carries its index and whether it is the last one, so
+// reordered, dropped or truncated chunks are detected.
+type encryptWriter struct {
+	w       io.Writer
+	gcm     cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint32
+	buf     []byte
+}
+
+// newEncryptWriter writes the archive header and returns a writer that
+// encrypts everything written to it. Close must be called to write the final chunk.
+func newEncryptWriter(w io.Writer, passphrase string) (*encryptWriter, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase is required")
+	}
+
+	params, err := crypto.NewMasterKeyParams()
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	header, err := json.Marshal(archiveHeader{Version: archiveVersion, KDF: params, NoncePrefix: prefix})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode header: %w", err)
+	}
+
+	gcm, err := archiveGCM(params.DeriveKey(passphrase))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.WriteString(w, archiveMagic); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(header))); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	return &encryptWriter{w: w, gcm: gcm, header: header, prefix: prefix, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+// Write buffers data and encrypts every full chunk
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(chunkSize-len(e.buf), len(p))
+		e.buf = append(e.buf, p[:n]...)
+		p = p[n:]
+		written += n
+		if len(e.buf) == chunkSize {
+			if err := e.flush(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close encrypts the remaining data as the final chunk
+func (e *encryptWriter) Close() error {
+	return e.flush(true)
+}
+
+// flush encrypts and writes the buffered chunk
+func (e *encryptWriter) flush(final bool) error {
+	sealed := e.gcm.Seal(nil, chunkNonce(e.prefix, e.counter, final), e.buf, e.header)
+	if err := binary.Write(e.w, binary.BigEndian, uint32(len(sealed))); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if _, err := e.w.Write(sealed); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	e.counter++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// decryptReader reads a stream written by encryptWriter
+type decryptReader struct {
+	r       *bufio.Reader
+	gcm     cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	done    bool
+}
+
+// newDecryptReader reads the archive header and returns a reader of the
+// decrypted content
+func newDecryptReader(r io.Reader, passphrase string) (*decryptReader, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(archiveMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != archiveMagic {
+		return nil, fmt.Errorf("not an Aerion profile backup")
+	}
+
+	var headerLen uint32
+	if err := binary.Read(br, binary.BigEndian, &headerLen); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if headerLen > 4096 {
+		return nil, fmt.Errorf("invalid backup header")
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	var h archiveHeader
+	if err := json.Unmarshal(header, &h); err != nil {
+		return nil, fmt.Errorf("invalid backup header: %w", err)
+	}
+	if h.Version != archiveVersion {
+		return nil, fmt.Errorf("unsupported backup version: %d", h.Version)
+	}
+	if len(h.NoncePrefix) != noncePrefixSize {
+		return nil, fmt.Errorf("invalid backup header")
+	}
+	// The header is only authenticated after the key is derived, so its
+	// parameters are bounded before spending memory on them
+	if err := h.KDF.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid backup header: %w", err)
+	}
+
+	gcm, err := archiveGCM(h.KDF.DeriveKey(passphrase))
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{r: br, gcm: gcm, header: header, prefix: h.NoncePrefix}, nil
+}
+
+// Read returns decrypted data, reading and authenticating chunks as needed
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// next reads and decrypts the next chunk
+func (d *decryptReader) next() error {
+	var sealedLen uint32
+	if err := binary.Read(d.r, binary.BigEndian, &sealedLen); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("backup is truncated")
+		}
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	if sealedLen > chunkSize+uint32(d.gcm.Overhead()) {
+		return ErrWrongPassphrase
+	}
+	sealed := make([]byte, sealedLen)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return fmt.Errorf("backup is truncated")
+	}
+
+	// The final flag is part of the nonce, so try the common case first
+	plain, err := d.gcm.Open(nil, chunkNonce(d.prefix, d.counter, false), sealed, d.header)
+	if err != nil {
+		plain, err = d.gcm.Open(nil, chunkNonce(d.prefix, d.counter, true), sealed, d.header)
+		if err != nil {
+			return ErrWrongPassphrase
+		}
+		d.done = true
+	}
+	d.counter++
+	d.buf = plain
+	return nil
+}
+
+// chunkNonce builds the nonce for a chunk: prefix || counter || final flag
+func chunkNonce(prefix []byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// archiveGCM creates the AES-256-GCM cipher for a derived key
+func archiveGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,757 @@
+// Package backup exports and imports portable, passphrase-encrypted profile
+// archives so an Aerion setup can be moved to another machine
+package backup
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hkdb/aerion/internal/blobstore"
+	"github.com/hkdb/aerion/internal/credentials"
+	"github.com/hkdb/aerion/internal/crypto"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/rs/zerolog"
+)
+
+// formatVersion is the version of the record stream inside the archive
+const formatVersion = 1
+
+// FileExtension is the conventional extension of profile backups
+const FileExtension = ".aerionbackup"
+
+// ConflictPolicy decides what happens to backed up accounts whose ID or email
+// address already exists
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing account and ignores the backed up one
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictReplace deletes the existing account and imports the backed up one
+	ConflictReplace ConflictPolicy = "replace"
+)
+
+// Options controls what is exported
+type Options struct {
+	IncludeMail bool `json:"includeMail"` // Include folders, messages, attachments and drafts
+}
+
+// Manifest describes a backup
+type Manifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	SchemaVersion int       `json:"schemaVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	IncludesMail  bool      `json:"includesMail"`
+}
+
+// AccountSummary describes an account in a backup
+type AccountSummary struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Exists bool   `json:"exists"` // An account with this ID or email is already set up
+}
+
+// Summary describes a backup before importing it
+type Summary struct {
+	Manifest Manifest          `json:"manifest"`
+	Accounts []*AccountSummary `json:"accounts"`
+}
+
+// ImportResult reports what an import did
+type ImportResult struct {
+	ImportedAccounts []string       `json:"importedAccounts"` // Account IDs
+	SkippedAccounts  []string       `json:"skippedAccounts"`  // Account emails kept as they were
+	ReplacedAccounts []string       `json:"replacedAccounts"` // Account emails that were overwritten
+	Rows             map[string]int `json:"rows"`             // Imported rows per table
+	SkippedRows      int            `json:"skippedRows"`      // Rows belonging to skipped accounts or already present
+	Errors           []string       `json:"errors,omitempty"` // Non-fatal problems, e.g. secrets that couldn't be stored
+}
+
+// secrets holds credentials from the credential store. They are kept in
+// the keyring (or encrypted with a device-bound key) on the source machine,
+// so they travel in plaintext inside the encrypted archive and are stored
+// again on import.
+type secrets struct {
+	AccountPasswords        map[string]string                   `json:"accountPasswords,omitempty"`
+	AccountOAuth            map[string]*credentials.OAuthTokens `json:"accountOAuth,omitempty"`
+	ContactSourcePasswords  map[string]string                   `json:"contactSourcePasswords,omitempty"`
+	ContactSourceOAuth      map[string]*credentials.OAuthTokens `json:"contactSourceOAuth,omitempty"`
+	CalendarSourcePasswords map[string]string                   `json:"calendarSourcePasswords,omitempty"`
+	SMIMEPrivateKeys        map[string][]byte                   `json:"smimePrivateKeys,omitempty"`
+	PGPPrivateKeys          map[string][]byte                   `json:"pgpPrivateKeys,omitempty"`
+	ProxyPasswords          map[string]string                   `json:"proxyPasswords,omitempty"` // By account ID or "global"
+	IdentitySMTPPasswords   map[string]string                   `json:"identitySmtpPasswords,omitempty"`
+}
+
+// record is one entry of the archive's record stream: the manifest first,
+// then table rows in dependency order, then the secrets
+type record struct {
+	Manifest *Manifest              `json:"manifest,omitempty"`
+	Table    string                 `json:"table,omitempty"`
+	Row      map[string]interface{} `json:"row,omitempty"`
+	Secrets  *secrets               `json:"secrets,omitempty"`
+}
+
+// blobKey marks a BLOB value in a row so it isn't confused with TEXT
+const blobKey = "$blob"
+
+// Manager exports and imports profile backups
+type Manager struct {
+	db        *sql.DB
+	credStore *credentials.Store
+	cipher    *crypto.FieldCipher
+	blobs     *blobstore.Store
+	log       zerolog.Logger
+}
+
+// NewManager creates a backup manager
+func NewManager(db *sql.DB, credStore *credentials.Store) *Manager {
+	return &Manager{
+		db:        db,
+		credStore: credStore,
+		log:       logging.WithComponent("backup"),
+	}
+}
+
+// SetFieldCipher sets the master password cipher. Encrypted mail is
+// decrypted for export and re-encrypted on import.
+func (m *Manager) SetFieldCipher(c *crypto.FieldCipher) {
+	m.cipher = c
+}
+
+// SetBlobStore sets the store attachment content is read from on export
+func (m *Manager) SetBlobStore(b *blobstore.Store) {
+	m.blobs = b
+}
+
+// ============================================================================
+// Export
+// ============================================================================
+
+// Export writes an encrypted backup of the profile to w
+func (m *Manager) Export(w io.Writer, passphrase string, opts Options) error {
+	if opts.IncludeMail && m.cipher.Locked() {
+		return crypto.ErrLocked
+	}
+
+	schemaVersion, err := m.schemaVersion()
+	if err != nil {
+		return err
+	}
+
+	enc, err := newEncryptWriter(w, passphrase)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(enc)
+	out := json.NewEncoder(gz)
+
+	manifest := &Manifest{
+		FormatVersion: formatVersion,
+		SchemaVersion: schemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		IncludesMail:  opts.IncludeMail,
+	}
+	if err := out.Encode(record{Manifest: manifest}); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	// IDs of rows that have secrets in the credential store
+	ids := make(map[string][]string)
+	for _, spec := range profileTables {
+		if spec.mail && !opts.IncludeMail {
+			continue
+		}
+		count, err := m.exportTable(out, spec, ids)
+		if err != nil {
+			return err
+		}
+		m.log.Debug().Str("table", spec.name).Int("rows", count).Msg("Exported table")
+	}
+
+	sec, err := m.collectSecrets(ids)
+	if err != nil {
+		return err
+	}
+	if err := out.Encode(record{Secrets: sec}); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	m.log.Info().Bool("includeMail", opts.IncludeMail).Msg("Exported profile backup")
+	return nil
+}
+
+// exportTable writes every row of a table as a record
+func (m *Manager) exportTable(out *json.Encoder, spec tableSpec, ids map[string][]string) (int, error) {
+	columns, err := m.tableColumns(spec.name)
+	if err != nil {
+		return 0, err
+	}
+
+	var names, selects []string
+	for _, c := range columns {
+		if has(spec.omit, c.name) {
+			continue
+		}
+		names = append(names, c.name)
+		// Read timestamps as stored so they round-trip exactly instead of
+		// being reformatted by the driver
+		if c.isTime() {
+			selects = append(selects, fmt.Sprintf("CAST(%q AS TEXT)", c.name))
+		} else {
+			selects = append(selects, fmt.Sprintf("%q", c.name))
+		}
+	}
+
+	rows, err := m.db.Query(fmt.Sprintf("SELECT %s FROM %q", strings.Join(selects, ", "), spec.name))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", spec.name, err)
+	}
+	defer rows.Close()
+
+	count := 0
+	values := make([]interface{}, len(names))
+	ptrs := make([]interface{}, len(names))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return count, fmt.Errorf("failed to read %s: %w", spec.name, err)
+		}
+
+		row := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			value, err := m.openValue(spec, name, values[i])
+			if err != nil {
+				return count, fmt.Errorf("failed to decrypt %s.%s: %w", spec.name, name, err)
+			}
+			if b, ok := value.([]byte); ok {
+				value = map[string][]byte{blobKey: b}
+			}
+			row[name] = value
+		}
+
+		for name, target := range spec.blobRefs {
+			hash, _ := row[name].(string)
+			delete(row, name)
+			if hash == "" {
+				continue
+			}
+			content, err := m.blobs.Get(hash)
+			if err != nil {
+				m.log.Warn().Err(err).Str("table", spec.name).Msg("Skipping missing blob content")
+				continue
+			}
+			row[target] = map[string][]byte{blobKey: content}
+		}
+
+		if id, ok := row["id"].(string); ok && secretTables[spec.name] {
+			ids[spec.name] = append(ids[spec.name], id)
+		}
+
+		if err := out.Encode(record{Table: spec.name, Row: row}); err != nil {
+			return count, fmt.Errorf("failed to write backup: %w", err)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("failed to read %s: %w", spec.name, err)
+	}
+	return count, nil
+}
+
+// openValue decrypts a column encrypted with the master password
+func (m *Manager) openValue(spec tableSpec, column string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if has(spec.sealed, column) {
+			return m.cipher.OpenString(v)
+		}
+	case []byte:
+		if has(spec.sealedBlob, column) {
+			return m.cipher.OpenBytes(v)
+		}
+		if has(spec.sealed, column) {
+			return m.cipher.OpenString(string(v))
+		}
+	}
+	return value, nil
+}
+
+// secretTables are the tables whose rows have secrets in the credential store
+var secretTables = map[string]bool{
+	"accounts":           true,
+	"identities":         true,
+	"contact_sources":    true,
+	"calendar_sources":   true,
+	"smime_certificates": true,
+	"pgp_keys":           true,
+}
+
+// collectSecrets reads the credentials of exported rows from the credential store
+func (m *Manager) collectSecrets(ids map[string][]string) (*secrets, error) {
+	sec := &secrets{
+		AccountPasswords:        make(map[string]string),
+		AccountOAuth:            make(map[string]*credentials.OAuthTokens),
+		ContactSourcePasswords:  make(map[string]string),
+		ContactSourceOAuth:      make(map[string]*credentials.OAuthTokens),
+		CalendarSourcePasswords: make(map[string]string),
+		SMIMEPrivateKeys:        make(map[string][]byte),
+		PGPPrivateKeys:          make(map[string][]byte),
+		ProxyPasswords:          make(map[string]string),
+		IdentitySMTPPasswords:   make(map[string]string),
+	}
+
+	// The global proxy is part of the settings, which are always exported
+	if err := collectSecret(sec.ProxyPasswords, credentials.GlobalProxyScope, m.credStore.GetProxyPassword); err != nil {
+		return nil, fmt.Errorf("failed to read proxy password: %w", err)
+	}
+
+	for _, id := range ids["accounts"] {
+		if err := collectSecret(sec.AccountPasswords, id, m.credStore.GetPassword); err != nil {
+			return nil, fmt.Errorf("failed to read account password: %w", err)
+		}
+		if err := collectSecret(sec.AccountOAuth, id, m.credStore.GetOAuthTokens); err != nil {
+			return nil, fmt.Errorf("failed to read account OAuth tokens: %w", err)
+		}
+		if err := collectSecret(sec.ProxyPasswords, id, m.credStore.GetProxyPassword); err != nil {
+			return nil, fmt.Errorf("failed to read account proxy password: %w", err)
+		}
+	}
+	for _, id := range ids["identities"] {
+		if err := collectSecret(sec.IdentitySMTPPasswords, id, m.credStore.GetIdentitySMTPPassword); err != nil {
+			return nil, fmt.Errorf("failed to read identity SMTP password: %w", err)
+		}
+	}
+	for _, id := range ids["contact_sources"] {
+		if err := collectSecret(sec.ContactSourcePasswords, id, m.credStore.GetCardDAVPassword); err != nil {
+			return nil, fmt.Errorf("failed to read contact source password: %w", err)
+		}
+		if err := collectSecret(sec.ContactSourceOAuth, id, m.credStore.GetContactSourceOAuthTokens); err != nil {
+			return nil, fmt.Errorf("failed to read contact source OAuth tokens: %w", err)
+		}
+	}
+	for _, id := range ids["calendar_sources"] {
+		if err := collectSecret(sec.CalendarSourcePasswords, id, m.credStore.GetCalDAVPassword); err != nil {
+			return nil, fmt.Errorf("failed to read calendar source password: %w", err)
+		}
+	}
+	for _, id := range ids["smime_certificates"] {
+		if err := collectSecret(sec.SMIMEPrivateKeys, id, m.credStore.GetSMIMEPrivateKey); err != nil {
+			return nil, fmt.Errorf("failed to read S/MIME private key: %w", err)
+		}
+	}
+	for _, id := range ids["pgp_keys"] {
+		if err := collectSecret(sec.PGPPrivateKeys, id, m.credStore.GetPGPPrivateKey); err != nil {
+			return nil, fmt.Errorf("failed to read PGP private key: %w", err)
+		}
+	}
+	return sec, nil
+}
+
+// collectSecret stores a credential in dst, skipping ones that don't exist
+func collectSecret[T any](dst map[string]T, id string, get func(string) (T, error)) error {
+	value, err := get(id)
+	if errors.Is(err, credentials.ErrCredentialNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	dst[id] = value
+	return nil
+}
+
+// ============================================================================
+// Import
+// ============================================================================
+
+// recordReader reads records from a backup, with one record of lookahead
+type recordReader struct {
+	dec     *json.Decoder
+	pending *record
+}
+
+// openBackup decrypts a backup and reads its manifest
+func openBackup(r io.Reader, passphrase string) (*recordReader, *Manifest, error) {
+	dr, err := newDecryptReader(r, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	gz, err := gzip.NewReader(dr)
+	if err != nil {
+		if errors.Is(err, ErrWrongPassphrase) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	dec := json.NewDecoder(gz)
+	dec.UseNumber()
+	rr := &recordReader{dec: dec}
+
+	first, err := rr.next()
+	if err != nil {
+		return nil, nil, err
+	}
+	if first == nil || first.Manifest == nil {
+		return nil, nil, fmt.Errorf("backup has no manifest")
+	}
+	if first.Manifest.FormatVersion != formatVersion {
+		return nil, nil, fmt.Errorf("unsupported backup format: %d", first.Manifest.FormatVersion)
+	}
+	return rr, first.Manifest, nil
+}
+
+// next returns the next record, or nil at the end of the backup
+func (rr *recordReader) next() (*record, error) {
+	if rr.pending != nil {
+		rec := rr.pending
+		rr.pending = nil
+		return rec, nil
+	}
+	var rec record
+	if err := rr.dec.Decode(&rec); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	return &rec, nil
+}
+
+// unread pushes a record back to be returned by the next call to next
+func (rr *recordReader) unread(rec *record) {
+	rr.pending = rec
+}
+
+// readAccounts reads the leading accounts records
+func (rr *recordReader) readAccounts() ([]map[string]interface{}, error) {
+	var accounts []map[string]interface{}
+	for {
+		rec, err := rr.next()
+		if err != nil {
+			return nil, err
+		}
+		if rec == nil {
+			return accounts, nil
+		}
+		if rec.Table != "accounts" {
+			rr.unread(rec)
+			return accounts, nil
+		}
+		accounts = append(accounts, rec.Row)
+	}
+}
+
+// Inspect decrypts a backup and describes its accounts without importing anything
+func (m *Manager) Inspect(r io.Reader, passphrase string) (*Summary, error) {
+	rr, manifest, err := openBackup(r, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := rr.readAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{Manifest: *manifest, Accounts: []*AccountSummary{}}
+	for _, row := range accounts {
+		acc := &AccountSummary{
+			ID:    stringValue(row["id"]),
+			Name:  stringValue(row["name"]),
+			Email: stringValue(row["email"]),
+		}
+		existingID, err := m.existingAccount(acc.ID, acc.Email)
+		if err != nil {
+			return nil, err
+		}
+		acc.Exists = existingID != ""
+		summary.Accounts = append(summary.Accounts, acc)
+	}
+	return summary, nil
+}
+
+// Import restores a backup. Accounts that already exist are skipped or
+// replaced according to policy. Replaced accounts are deleted inside the
+// import transaction, so a failed import leaves them untouched; once it has
+// committed their credentials are deleted and releaseAccount is called to
+// close their connections. Everything else is merged, keeping existing rows
+// except for settings.
+func (m *Manager) Import(r io.Reader, passphrase string, policy ConflictPolicy, releaseAccount func(id string)) (*ImportResult, error) {
+	if policy != ConflictSkip && policy != ConflictReplace {
+		return nil, fmt.Errorf("invalid conflict policy: %s", policy)
+	}
+
+	rr, manifest, err := openBackup(r, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaVersion, err := m.schemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if manifest.SchemaVersion > schemaVersion {
+		return nil, fmt.Errorf("backup was made by a newer version of Aerion, please update first")
+	}
+	if manifest.IncludesMail && m.cipher.Locked() {
+		return nil, crypto.ErrLocked
+	}
+
+	accounts, err := rr.readAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{
+		ImportedAccounts: []string{},
+		SkippedAccounts:  []string{},
+		ReplacedAccounts: []string{},
+		Rows:             make(map[string]int),
+	}
+
+	// Resolve account conflicts. Identity IDs are collected up front because
+	// their SMTP passwords are keyed by identity and the rows go with the account.
+	var accountRows []map[string]interface{}
+	var replaced []replacedAccount
+	for _, row := range accounts {
+		id, email := stringValue(row["id"]), stringValue(row["email"])
+		existingID, err := m.existingAccount(id, email)
+		if err != nil {
+			return nil, err
+		}
+		if existingID == "" {
+			accountRows = append(accountRows, row)
+			continue
+		}
+		if policy == ConflictSkip {
+			result.SkippedAccounts = append(result.SkippedAccounts, email)
+			continue
+		}
+		identityIDs, err := m.identityIDs(existingID)
+		if err != nil {
+			return nil, err
+		}
+		replaced = append(replaced, replacedAccount{id: existingID, email: email, identityIDs: identityIDs})
+		accountRows = append(accountRows, row)
+	}
+
+	imp, err := m.newImporter(result)
+	if err != nil {
+		return nil, err
+	}
+	defer imp.tx.Rollback()
+
+	// Deleting cascades to the account's folders, messages, identities etc.
+	for _, acc := range replaced {
+		if _, err := imp.tx.Exec("DELETE FROM accounts WHERE id = ?", acc.id); err != nil {
+			return nil, fmt.Errorf("failed to remove existing account %s: %w", acc.email, err)
+		}
+		result.ReplacedAccounts = append(result.ReplacedAccounts, acc.email)
+	}
+
+	for _, row := range accountRows {
+		if err := imp.importRow("accounts", row); err != nil {
+			return nil, err
+		}
+	}
+
+	var sec *secrets
+	for {
+		rec, err := rr.next()
+		if err != nil {
+			return nil, err
+		}
+		if rec == nil {
+			break
+		}
+		if rec.Secrets != nil {
+			sec = rec.Secrets
+			continue
+		}
+		if rec.Table == "" {
+			continue
+		}
+		if err := imp.importRow(rec.Table, rec.Row); err != nil {
+			return nil, err
+		}
+	}
+	if err := imp.finishTable(); err != nil {
+		return nil, err
+	}
+
+	if err := imp.tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	// Clean up after the replaced accounts before restoring secrets, as the
+	// imported accounts may reuse their IDs
+	for _, acc := range replaced {
+		m.releaseReplacedAccount(acc, releaseAccount)
+	}
+
+	for _, row := range accountRows {
+		id := stringValue(row["id"])
+		if imp.imported("accounts", id) {
+			result.ImportedAccounts = append(result.ImportedAccounts, id)
+		}
+	}
+
+	if sec != nil {
+		m.restoreSecrets(sec, imp, result)
+	}
+
+	m.log.Info().
+		Int("accounts", len(result.ImportedAccounts)).
+		Int("skippedAccounts", len(result.SkippedAccounts)).
+		Int("replacedAccounts", len(result.ReplacedAccounts)).
+		Int("skippedRows", result.SkippedRows).
+		Msg("Imported profile backup")
+	return result, nil
+}
+
+// restoreSecrets stores the credentials of imported rows in the credential
+// store (keyring or encrypted database fallback)
+func (m *Manager) restoreSecrets(sec *secrets, imp *importer, result *ImportResult) {
+	restore := func(table, id, what string, err error) {
+		if err != nil {
+			m.log.Warn().Err(err).Str("table", table).Str("id", id).Msg("Failed to restore secret")
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to restore %s for %s: %v", what, id, err))
+		}
+	}
+
+	for id, password := range sec.AccountPasswords {
+		if imp.imported("accounts", id) {
+			restore("accounts", id, "password", m.credStore.SetPassword(id, password))
+		}
+	}
+	for id, tokens := range sec.AccountOAuth {
+		if imp.imported("accounts", id) {
+			restore("accounts", id, "OAuth tokens", m.credStore.SetOAuthTokens(id, tokens))
+		}
+	}
+	for id, password := range sec.IdentitySMTPPasswords {
+		if imp.imported("identities", id) {
+			restore("identities", id, "SMTP password", m.credStore.SetIdentitySMTPPassword(id, password))
+		}
+	}
+	for id, password := range sec.ContactSourcePasswords {
+		if imp.imported("contact_sources", id) {
+			restore("contact_sources", id, "password", m.credStore.SetCardDAVPassword(id, password))
+		}
+	}
+	for id, tokens := range sec.ContactSourceOAuth {
+		if imp.imported("contact_sources", id) {
+			restore("contact_sources", id, "OAuth tokens", m.credStore.SetContactSourceOAuthTokens(id, tokens))
+		}
+	}
+	for id, password := range sec.CalendarSourcePasswords {
+		if imp.imported("calendar_sources", id) {
+			restore("calendar_sources", id, "password", m.credStore.SetCalDAVPassword(id, password))
+		}
+	}
+	for id, key := range sec.SMIMEPrivateKeys {
+		if imp.imported("smime_certificates", id) {
+			restore("smime_certificates", id, "S/MIME private key", m.credStore.SetSMIMEPrivateKey(id, key))
+		}
+	}
+	for id, key := range sec.PGPPrivateKeys {
+		if imp.imported("pgp_keys", id) {
+			restore("pgp_keys", id, "PGP private key", m.credStore.SetPGPPrivateKey(id, key))
+		}
+	}
+	for scope, password := range sec.ProxyPasswords {
+		if scope == credentials.GlobalProxyScope {
+			// Settings, including the global proxy, replace existing ones
+			restore("settings", scope, "proxy password", m.credStore.SetProxyPassword(scope, password))
+		} else if imp.imported("accounts", scope) {
+			restore("accounts", scope, "proxy password", m.credStore.SetProxyPassword(scope, password))
+		}
+	}
+}
+
+// existingAccount returns the ID of an account with the same ID or email, or ""
+func (m *Manager) existingAccount(id, email string) (string, error) {
+	var existingID string
+	err := m.db.QueryRow(
+		"SELECT id FROM accounts WHERE id = ? OR email = ? COLLATE NOCASE LIMIT 1",
+		id, email,
+	).Scan(&existingID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check existing accounts: %w", err)
+	}
+	return existingID, nil
+}
+
+// replacedAccount is an existing account that an import replaces
+type replacedAccount struct {
+	id          string
+	email       string
+	identityIDs []string
+}
+
+// identityIDs returns the IDs of an account's identities
+func (m *Manager) identityIDs(accountID string) ([]string, error) {
+	rows, err := m.db.Query("SELECT id FROM identities WHERE account_id = ?", accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// releaseReplacedAccount deletes the credentials of an account removed by an
+// import and lets the app close its connections
+func (m *Manager) releaseReplacedAccount(acc replacedAccount, releaseAccount func(id string)) {
+	if err := m.credStore.DeleteAllCredentials(acc.id); err != nil {
+		m.log.Warn().Err(err).Str("accountID", acc.id).Msg("Failed to delete credentials of replaced account")
+	}
+	for _, id := range acc.identityIDs {
+		m.credStore.DeleteIdentitySMTPPassword(id)
+	}
+	if releaseAccount != nil {
+		releaseAccount(acc.id)
+	}
+}
+
+// schemaVersion returns the database's latest applied migration
+func (m *Manager) schemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := m.db.QueryRow("SELECT MAX(version) FROM migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// stringValue returns a row value as a string
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,329 @@
+package backup
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// column describes a column of the target database
+type column struct {
+	name     string
+	declType string
+}
+
+// isTime reports whether the driver would convert the column to time.Time
+func (c column) isTime() bool {
+	switch strings.ToUpper(c.declType) {
+	case "DATE", "DATETIME", "TIMESTAMP":
+		return true
+	}
+	return false
+}
+
+// foreignKey is a reference from a column to another table's column
+type foreignKey struct {
+	column string
+	table  string
+	to     string
+}
+
+// tableColumns returns the columns of a table in the target database
+func (m *Manager) tableColumns(table string) ([]column, error) {
+	rows, err := m.db.Query("SELECT name, type FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []column
+	for rows.Next() {
+		var c column
+		if err := rows.Scan(&c.name, &c.declType); err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+// tableForeignKeys returns the foreign keys of a table in the target database
+func (m *Manager) tableForeignKeys(table string) ([]foreignKey, error) {
+	rows, err := m.db.Query(`SELECT "table", "from", "to" FROM pragma_foreign_key_list(?)`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var fks []foreignKey
+	for rows.Next() {
+		var fk foreignKey
+		if err := rows.Scan(&fk.table, &fk.column, &fk.to); err != nil {
+			return nil, fmt.Errorf("failed to read foreign keys of %s: %w", table, err)
+		}
+		fks = append(fks, fk)
+	}
+	return fks, rows.Err()
+}
+
+// importer inserts backup rows in a single transaction. A row is only
+// imported if every row it references was imported (or already existed),
+// so data of skipped accounts is dropped along with the account.
+type importer struct {
+	m      *Manager
+	tx     *sql.Tx
+	result *ImportResult
+
+	specs   map[string]tableSpec
+	columns map[string]map[string]column
+	fks     map[string][]foreignKey
+
+	// keys holds the values present after import for every referenced
+	// column and every id column, keyed by "table.column"
+	keys map[string]map[string]bool
+
+	stmts    map[string]*sql.Stmt
+	current  string
+	deferred []map[string]interface{}
+}
+
+// newImporter loads the target schema and begins the import transaction
+func (m *Manager) newImporter(result *ImportResult) (*importer, error) {
+	imp := &importer{
+		m:       m,
+		result:  result,
+		specs:   make(map[string]tableSpec),
+		columns: make(map[string]map[string]column),
+		fks:     make(map[string][]foreignKey),
+		keys:    make(map[string]map[string]bool),
+		stmts:   make(map[string]*sql.Stmt),
+	}
+
+	for _, spec := range profileTables {
+		columns, err := m.tableColumns(spec.name)
+		if err != nil {
+			return nil, err
+		}
+		fks, err := m.tableForeignKeys(spec.name)
+		if err != nil {
+			return nil, err
+		}
+
+		imp.specs[spec.name] = spec
+		imp.columns[spec.name] = make(map[string]column, len(columns))
+		for _, c := range columns {
+			imp.columns[spec.name][c.name] = c
+			if c.name == "id" {
+				imp.keys[spec.name+".id"] = make(map[string]bool)
+			}
+		}
+		imp.fks[spec.name] = fks
+		for _, fk := range fks {
+			imp.keys[fk.table+"."+fk.to] = make(map[string]bool)
+		}
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin import: %w", err)
+	}
+	imp.tx = tx
+	return imp, nil
+}
+
+// imported reports whether a row with the given id is present after import
+func (imp *importer) imported(table, id string) bool {
+	return imp.keys[table+".id"][id]
+}
+
+// importRow decodes and inserts a row from the backup
+func (imp *importer) importRow(table string, raw map[string]interface{}) error {
+	if table != imp.current {
+		if err := imp.finishTable(); err != nil {
+			return err
+		}
+		imp.current = table
+	}
+
+	// Tables unknown to this version are ignored
+	spec, ok := imp.specs[table]
+	if !ok || len(imp.columns[table]) == 0 {
+		return nil
+	}
+
+	row, err := imp.decodeRow(spec, raw)
+	if err != nil {
+		return err
+	}
+	return imp.insertRow(table, row)
+}
+
+// finishTable retries rows that referenced rows of their own table which
+// hadn't been imported yet (e.g. subfolders listed before their parent)
+func (imp *importer) finishTable() error {
+	for len(imp.deferred) > 0 {
+		pending := imp.deferred
+		imp.deferred = nil
+		for _, row := range pending {
+			if err := imp.insertRow(imp.current, row); err != nil {
+				return err
+			}
+		}
+		if len(imp.deferred) == len(pending) {
+			// No progress: the parents aren't in the backup
+			imp.result.SkippedRows += len(imp.deferred)
+			imp.deferred = nil
+		}
+	}
+	return nil
+}
+
+// decodeRow converts JSON values back to database values, drops columns the
+// target doesn't have, clears reset columns and re-encrypts sealed columns
+func (imp *importer) decodeRow(spec tableSpec, raw map[string]interface{}) (map[string]interface{}, error) {
+	columns := imp.columns[spec.name]
+	row := make(map[string]interface{}, len(raw))
+	for name, value := range raw {
+		if _, ok := columns[name]; !ok || has(spec.omit, name) {
+			continue
+		}
+		// Content is imported inline, never as a reference into this blob store
+		if _, ok := spec.blobRefs[name]; ok {
+			continue
+		}
+		if has(spec.reset, name) {
+			row[name] = nil
+			continue
+		}
+
+		value, err := decodeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s.%s: %w", spec.name, name, err)
+		}
+
+		switch v := value.(type) {
+		case string:
+			if has(spec.sealed, name) {
+				if value, err = imp.m.cipher.SealString(v); err != nil {
+					return nil, err
+				}
+			}
+		case []byte:
+			if has(spec.sealedBlob, name) {
+				if value, err = imp.m.cipher.SealBytes(v); err != nil {
+					return nil, err
+				}
+			}
+		}
+		row[name] = value
+	}
+	return row, nil
+}
+
+// decodeValue converts a JSON value from the backup to a database value
+func decodeValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]interface{}:
+		encoded, ok := v[blobKey].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object")
+		}
+		return base64.StdEncoding.DecodeString(encoded)
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	return value, nil
+}
+
+// insertRow inserts a decoded row if everything it references is present
+func (imp *importer) insertRow(table string, row map[string]interface{}) error {
+	for _, fk := range imp.fks[table] {
+		value := row[fk.column]
+		if value == nil {
+			continue
+		}
+		if imp.keys[fk.table+"."+fk.to][fmt.Sprint(value)] {
+			continue
+		}
+		if fk.table == table {
+			imp.deferred = append(imp.deferred, row)
+			return nil
+		}
+		imp.result.SkippedRows++
+		return nil
+	}
+
+	names := make([]string, 0, len(row))
+	for name := range row {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil
+	}
+
+	stmt, err := imp.statement(table, names)
+	if err != nil {
+		return err
+	}
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = row[name]
+	}
+
+	res, err := stmt.Exec(args...)
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", table, err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		imp.result.Rows[table]++
+	} else {
+		// Already present; merged rather than duplicated
+		imp.result.SkippedRows++
+	}
+
+	for name, value := range row {
+		if keys, ok := imp.keys[table+"."+name]; ok && value != nil {
+			keys[fmt.Sprint(value)] = true
+		}
+	}
+	return nil
+}
+
+// statement returns a cached INSERT statement for a table and column set
+func (imp *importer) statement(table string, names []string) (*sql.Stmt, error) {
+	cacheKey := table + ":" + strings.Join(names, ",")
+	if stmt, ok := imp.stmts[cacheKey]; ok {
+		return stmt, nil
+	}
+
+	verb := "INSERT OR IGNORE"
+	if imp.specs[table].onConflict == replaceExisting {
+		verb = "INSERT OR REPLACE"
+	}
+
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+	query := fmt.Sprintf("%s INTO %q (%s) VALUES (%s)", verb, table, strings.Join(quoted, ", "), placeholders)
+
+	stmt, err := imp.tx.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare import of %s: %w", table, err)
+	}
+	imp.stmts[cacheKey] = stmt
+	return stmt, nil
+}
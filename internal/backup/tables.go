@@ -0,0 +1,106 @@
+package backup
+
+// conflictMode is how rows that already exist in the target database are handled
+type conflictMode int
+
+const (
+	// keepExisting leaves existing rows untouched (INSERT OR IGNORE)
+	keepExisting conflictMode = iota
+	// replaceExisting overwrites existing rows (INSERT OR REPLACE)
+	replaceExisting
+)
+
+// tableSpec describes how a table is exported and imported
+type tableSpec struct {
+	name string
+
+	// mail marks tables that are only exported with the mail cache
+	mail bool
+
+	// omit lists columns that are never exported: secrets encrypted with the
+	// device-bound key (exported separately in plaintext inside the encrypted
+	// archive) and autoincrement ids that would collide on import
+	omit []string
+
+	// reset lists columns cleared on import, e.g. sync tokens for data that
+	// isn't exported and must be synced again from scratch
+	reset []string
+
+	// sealed lists columns that may be encrypted with the master password
+	sealed []string
+
+	// sealedBlob lists BLOB columns that may be encrypted with the master password
+	sealedBlob []string
+
+	// blobRefs maps columns holding a blob store hash to the column the
+	// content is exported in. Imported content is moved back into the blob
+	// store by the attachment store's background migration.
+	blobRefs map[string]string
+
+	// onConflict applies to tables without an owning account; rows belonging
+	// to an account are governed by the account conflict policy instead
+	onConflict conflictMode
+}
+
+// profileTables are exported in dependency order so parents are always
+// imported before their children
+var profileTables = []tableSpec{
+	{name: "accounts", omit: []string{"encrypted_password", "encrypted_access_token", "encrypted_refresh_token"}},
+	{name: "identities", omit: []string{"encrypted_smtp_password"}},
+	{name: "oauth_tokens"},
+	{name: "settings", onConflict: replaceExisting},
+	{name: "image_allowlist", omit: []string{"id"}},
+	{name: "trusted_certificates"},
+	{name: "smime_certificates", omit: []string{"encrypted_private_key"}},
+	{name: "smime_trust_anchors"},
+	{name: "smime_sender_certs"},
+	{name: "pgp_keys", omit: []string{"encrypted_private_key"}},
+	{name: "pgp_sender_keys"},
+	{name: "pgp_keyservers", omit: []string{"id"}},
+	{name: "autocrypt_peers"},
+	{name: "contact_sources", omit: []string{"encrypted_password", "encrypted_access_token", "encrypted_refresh_token"}},
+	{name: "contact_source_oauth"},
+	{name: "contact_source_addressbooks", reset: []string{"sync_token", "last_synced_at"}},
+	{name: "calendar_sources", omit: []string{"encrypted_password"}},
+	{name: "calendar_source_calendars", reset: []string{"sync_token", "last_synced_at"}},
+	{name: "local_cards"},
+	{name: "local_card_emails"},
+	{name: "local_groups"},
+	{name: "local_group_members"},
+	{name: "muted_threads"},
+	{name: "spam_tokens", onConflict: replaceExisting},
+	{name: "spam_training"},
+
+	// Mail cache
+	{name: "folders", mail: true},
+	{
+		name:       "messages",
+		mail:       true,
+		sealed:     []string{"snippet", "body_text", "body_html", "calendar_data"},
+		sealedBlob: []string{"smime_raw_body", "pgp_raw_body"},
+	},
+	{
+		name:       "attachments",
+		mail:       true,
+		reset:      []string{"local_path"},
+		sealedBlob: []string{"content"},
+		blobRefs:   map[string]string{"content_hash": "content"},
+	},
+	{
+		name:       "drafts",
+		mail:       true,
+		sealed:     []string{"body_html", "body_text"},
+		sealedBlob: []string{"attachments_data"},
+	},
+	{name: "delivery_status", mail: true},
+}
+
+// has reports whether name is in list
+func has(list []string, name string) bool {
+	for _, v := range list {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,465 @@
+// Package blobstore stores attachment content on disk, addressed by the
+// SHA-256 of the content so identical attachments are stored once. With a
+// master password set, content is addressed by a keyed MAC instead.
+package blobstore
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hkdb/aerion/internal/crypto"
+	"github.com/hkdb/aerion/internal/database"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/rs/zerolog"
+)
+
+// gcGracePeriod protects unreferenced blobs written recently: a blob is
+// written before the attachment row referencing it is inserted
+const gcGracePeriod = 10 * time.Minute
+
+// gcDelay batches garbage collection after deletions
+const gcDelay = 30 * time.Second
+
+// rewriteBatchSize is the number of blobs listed per query while re-keying
+const rewriteBatchSize = 200
+
+// RewriteProgressCallback reports progress while encrypting or decrypting existing blobs
+type RewriteProgressCallback func(done, total int)
+
+// Store keeps blobs in files named by their hash (see name). Reference counts live in
+// the attachment_blobs table and are maintained by triggers on attachments.
+type Store struct {
+	db     *database.DB
+	dir    string
+	cipher *crypto.FieldCipher // Encrypts blob files when a master password is set
+	log    zerolog.Logger
+
+	// mu serializes writes and removals of blob files
+	mu sync.Mutex
+
+	gcTimer *time.Timer
+	gcMu    sync.Mutex
+}
+
+// NewStore creates a blob store keeping files under dir
+func NewStore(db *database.DB, dir string) *Store {
+	return &Store{
+		db:  db,
+		dir: dir,
+		log: logging.WithComponent("blobstore"),
+	}
+}
+
+// SetFieldCipher sets the cipher used for blob files
+func (s *Store) SetFieldCipher(c *crypto.FieldCipher) {
+	s.cipher = c
+}
+
+// Hash returns the key of content stored without a master password
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// name returns the key of new content. With a master password, a plaintext
+// hash would let anyone reading the blob directory test for known files, so
+// content is named by an HMAC keyed from the vault. While locked that key is
+// unavailable and content gets a random name, which isn't deduplicated.
+func (s *Store) name(data []byte) (string, error) {
+	if !s.cipher.Enabled() {
+		return Hash(data), nil
+	}
+	mac, err := s.cipher.ContentMAC(data)
+	if errors.Is(err, crypto.ErrLocked) {
+		mac = make([]byte, sha256.Size)
+		if _, err := rand.Read(mac); err != nil {
+			return "", fmt.Errorf("failed to generate blob name: %w", err)
+		}
+	} else if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(mac), nil
+}
+
+// path returns the file of a blob, sharded by the first byte of the hash
+func (s *Store) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// validHash reports whether hash is a hex SHA-256 sized key, so it is safe to use in a path
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// Put stores data and returns its hash. Data already stored is not written
+// again. The blob is kept until an attachment references it and is released
+// again, or for gcGracePeriod if it is never referenced.
+func (s *Store) Put(data []byte) (string, error) {
+	hash, err := s.name(data)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().Unix()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Touching the row keeps garbage collection away from an existing blob
+	res, err := s.db.Exec("UPDATE attachment_blobs SET last_used_at = ? WHERE hash = ?", now, hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to update blob: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		if _, err := os.Stat(s.path(hash)); err == nil {
+			return hash, nil
+		}
+		s.log.Warn().Str("hash", hash).Msg("Blob file missing, writing it again")
+	}
+
+	sealed, err := s.cipher.SealBytes(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt blob: %w", err)
+	}
+	if err := s.writeFile(hash, sealed); err != nil {
+		return "", err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO attachment_blobs (hash, size, last_used_at) VALUES (?, ?, ?)
+		ON CONFLICT(hash) DO UPDATE SET size = excluded.size, last_used_at = excluded.last_used_at
+	`, hash, len(sealed), now)
+	if err != nil {
+		return "", fmt.Errorf("failed to record blob: %w", err)
+	}
+	return hash, nil
+}
+
+// Get returns the content of a blob
+func (s *Store) Get(hash string) ([]byte, error) {
+	if !validHash(hash) {
+		return nil, fmt.Errorf("invalid blob hash: %q", hash)
+	}
+	data, err := os.ReadFile(s.path(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return s.cipher.OpenBytes(data)
+}
+
+// Size returns the total size of stored blobs
+func (s *Store) Size() (int64, error) {
+	var size int64
+	if err := s.db.QueryRow("SELECT COALESCE(SUM(size), 0) FROM attachment_blobs").Scan(&size); err != nil {
+		return 0, fmt.Errorf("failed to measure blobs: %w", err)
+	}
+	return size, nil
+}
+
+// writeFile atomically writes a blob file
+func (s *Store) writeFile(hash string, data []byte) error {
+	path := s.path(hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	return nil
+}
+
+// ============================================================================
+// Garbage collection
+// ============================================================================
+
+// CollectGarbage removes blobs no attachment references anymore. Returns the
+// number of blobs removed and their size.
+func (s *Store) CollectGarbage() (int, int64, error) {
+	cutoff := time.Now().Add(-gcGracePeriod).Unix()
+	rows, err := s.db.Query(
+		"SELECT hash FROM attachment_blobs WHERE ref_count <= 0 AND last_used_at < ?", cutoff,
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find unreferenced blobs: %w", err)
+	}
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan blob: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to find unreferenced blobs: %w", err)
+	}
+
+	removed := 0
+	var freed int64
+	for _, hash := range hashes {
+		size, ok, err := s.remove(hash, cutoff)
+		if err != nil {
+			return removed, freed, err
+		}
+		if ok {
+			removed++
+			freed += size
+		}
+	}
+
+	if removed > 0 {
+		s.log.Info().Int("blobs", removed).Int64("freed", freed).Msg("Removed unreferenced attachment blobs")
+	}
+	return removed, freed, nil
+}
+
+// remove deletes a blob if it is still unreferenced
+func (s *Store) remove(hash string, cutoff int64) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Re-check under the lock: the blob may have been referenced meanwhile
+	var size int64
+	err := s.db.QueryRow(`
+		DELETE FROM attachment_blobs WHERE hash = ? AND ref_count <= 0 AND last_used_at < ?
+		RETURNING size
+	`, hash, cutoff).Scan(&size)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to delete blob: %w", err)
+	}
+
+	if validHash(hash) {
+		if err := os.Remove(s.path(hash)); err != nil && !os.IsNotExist(err) {
+			s.log.Warn().Err(err).Str("hash", hash).Msg("Failed to remove blob file")
+		}
+	}
+	return size, true, nil
+}
+
+// ScheduleGC collects garbage shortly, batching bursts of deletions (non-blocking)
+func (s *Store) ScheduleGC() {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+
+	if s.gcTimer != nil {
+		s.gcTimer.Stop()
+	}
+	s.gcTimer = time.AfterFunc(gcDelay, func() {
+		if _, _, err := s.CollectGarbage(); err != nil {
+			s.log.Warn().Err(err).Msg("Failed to collect unreferenced blobs")
+		}
+	})
+}
+
+// Close cancels a scheduled garbage collection
+func (s *Store) Close() {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+
+	if s.gcTimer != nil {
+		s.gcTimer.Stop()
+		s.gcTimer = nil
+	}
+}
+
+// ============================================================================
+// Re-keying
+// ============================================================================
+
+// EncryptExisting encrypts all plaintext blob files with the store's cipher
+// and renames blobs named by their plaintext SHA-256 (see name)
+func (s *Store) EncryptExisting(ctx context.Context, progress RewriteProgressCallback) error {
+	return s.rewrite(ctx, true, progress)
+}
+
+// DecryptExisting decrypts all encrypted blob files and renames them back to
+// their SHA-256. The cipher must be unlocked.
+func (s *Store) DecryptExisting(ctx context.Context, progress RewriteProgressCallback) error {
+	return s.rewrite(ctx, false, progress)
+}
+
+// rewrite encrypts or decrypts every blob file. Renamed blobs may be listed
+// again later in the pass; they are left alone then.
+func (s *Store) rewrite(ctx context.Context, encrypt bool, progress RewriteProgressCallback) error {
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM attachment_blobs").Scan(&total); err != nil {
+		return fmt.Errorf("failed to count blobs: %w", err)
+	}
+
+	done := 0
+	last := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		hashes, err := s.listAfter(last)
+		if err != nil {
+			return err
+		}
+		if len(hashes) == 0 {
+			return nil
+		}
+
+		for _, hash := range hashes {
+			if err := s.rewriteBlob(hash, encrypt); err != nil {
+				return err
+			}
+		}
+		last = hashes[len(hashes)-1]
+		done += len(hashes)
+		if progress != nil {
+			progress(min(done, total), total)
+		}
+	}
+}
+
+// listAfter returns the next batch of blob hashes in order
+func (s *Store) listAfter(last string) ([]string, error) {
+	rows, err := s.db.Query(
+		"SELECT hash FROM attachment_blobs WHERE hash > ? ORDER BY hash LIMIT ?", last, rewriteBatchSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to scan blob: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// rewriteBlob encrypts or decrypts a single blob file, renaming it if its
+// name doesn't match the new state
+func (s *Store) rewriteBlob(hash string, encrypt bool) error {
+	if !validHash(hash) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(hash))
+	if os.IsNotExist(err) {
+		// Removed by garbage collection or lost; nothing to re-key
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read blob: %w", err)
+	}
+
+	// Encrypting only needs the public key; an encrypted blob can't be
+	// renamed while locked, but it was named when it was written
+	if encrypt && crypto.IsSealedBytes(data) && s.cipher.Locked() {
+		return nil
+	}
+	plaintext, err := s.cipher.OpenBytes(data)
+	if err != nil {
+		return err
+	}
+
+	rewritten, name := plaintext, Hash(plaintext)
+	if encrypt {
+		rewritten = data
+		if !crypto.IsSealedBytes(data) {
+			if rewritten, err = s.cipher.SealBytes(plaintext); err != nil {
+				return err
+			}
+		}
+		name = hash
+		if hash == Hash(plaintext) {
+			if name, err = s.name(plaintext); err != nil {
+				return err
+			}
+		}
+	}
+
+	if name != hash {
+		return s.renameLocked(hash, name, rewritten)
+	}
+	if string(rewritten) == string(data) {
+		return nil
+	}
+
+	if err := s.writeFile(hash, rewritten); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("UPDATE attachment_blobs SET size = ? WHERE hash = ?", len(rewritten), hash); err != nil {
+		return fmt.Errorf("failed to update blob: %w", err)
+	}
+	return nil
+}
+
+// renameLocked moves a blob to a new name, merging it into an existing blob
+// of that name. Attachments are repointed in one transaction; the triggers
+// move the reference count. Callers must hold mu.
+func (s *Store) renameLocked(oldHash, newHash string, data []byte) error {
+	if err := s.writeFile(newHash, data); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO attachment_blobs (hash, size, last_used_at) VALUES (?, ?, ?)
+		ON CONFLICT(hash) DO UPDATE SET size = excluded.size, last_used_at = excluded.last_used_at
+	`, newHash, len(data), time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to record blob: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE attachments SET content_hash = ? WHERE content_hash = ?", newHash, oldHash); err != nil {
+		return fmt.Errorf("failed to rename blob: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM attachment_blobs WHERE hash = ?", oldHash); err != nil {
+		return fmt.Errorf("failed to rename blob: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit blob rename: %w", err)
+	}
+
+	if err := os.Remove(s.path(oldHash)); err != nil && !os.IsNotExist(err) {
+		s.log.Warn().Err(err).Str("hash", oldHash).Msg("Failed to remove renamed blob file")
+	}
+	return nil
+}
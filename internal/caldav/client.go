@@ -0,0 +1,475 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/caldav"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
+	"github.com/rs/zerolog"
+)
+
+// googleCalDAVBase is the root of Google Calendar's CalDAV API
+const googleCalDAVBase = "https://apidata.googleusercontent.com/caldav/v2/"
+
+// GooglePrincipalURL returns the CalDAV principal URL of a Google account
+func GooglePrincipalURL(email string) string {
+	return googleCalDAVBase + url.PathEscape(email) + "/user"
+}
+
+// googlePrimaryCalendarPath returns the path of the primary calendar of the
+// Google account with the given principal URL
+func googlePrimaryCalendarPath(principalURL string) string {
+	u, err := url.Parse(strings.TrimSuffix(principalURL, "/user") + "/events")
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+// ErrInsufficientScope is returned when the OAuth token of a linked account
+// was granted without calendar access
+var ErrInsufficientScope = errors.New("calendar access has not been granted for this account")
+
+// bearerAuthHTTPClient adds an OAuth bearer token to every request
+type bearerAuthHTTPClient struct {
+	c     webdav.HTTPClient
+	token string
+}
+
+// Do implements webdav.HTTPClient
+func (c *bearerAuthHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusForbidden && insufficientScope(resp) {
+		resp.Body.Close()
+		return nil, ErrInsufficientScope
+	}
+	return resp, nil
+}
+
+// insufficientScope reports whether a 403 response means the token lacks a
+// scope. Google says so in WWW-Authenticate (error="insufficient_scope") or
+// only in the body (ACCESS_TOKEN_SCOPE_INSUFFICIENT); the peeked body is put
+// back for the caller.
+func insufficientScope(resp *http.Response) bool {
+	if strings.Contains(resp.Header.Get("WWW-Authenticate"), "insufficient_scope") {
+		return true
+	}
+
+	peek, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peek), resp.Body), resp.Body}
+
+	body := strings.ToLower(string(peek))
+	return strings.Contains(body, "insufficient_scope") ||
+		strings.Contains(body, "access_token_scope_insufficient") ||
+		strings.Contains(body, "insufficient authentication scopes")
+}
+
+// Client wraps the CalDAV client with discovery and convenience methods
+type Client struct {
+	httpClient webdav.HTTPClient // Authenticated HTTP client
+	baseURL    string
+	log        zerolog.Logger
+}
+
+// NewClient creates a new CalDAV client using basic auth. proxy is the proxy
+// of the linked account; nil uses the global proxy.
+func NewClient(baseURL, username, password string, proxy *netproxy.Config) (*Client, error) {
+	httpClient := webdav.HTTPClientWithBasicAuth(
+		netproxy.HTTPClientFor(proxy, 60*time.Second),
+		username, password,
+	)
+	return newClient(baseURL, httpClient)
+}
+
+// NewOAuthClient creates a new CalDAV client authenticating with an OAuth
+// access token, connecting through the linked account's proxy
+func NewOAuthClient(baseURL, accessToken string, proxy *netproxy.Config) (*Client, error) {
+	httpClient := &bearerAuthHTTPClient{
+		c:     netproxy.HTTPClientFor(proxy, 60*time.Second),
+		token: accessToken,
+	}
+	return newClient(baseURL, httpClient)
+}
+
+// newClient normalizes the base URL and creates the client
+func newClient(baseURL string, httpClient webdav.HTTPClient) (*Client, error) {
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	// Ensure scheme is present
+	if parsedURL.Scheme == "" {
+		parsedURL.Scheme = "https"
+	}
+
+	return &Client{
+		httpClient: httpClient,
+		baseURL:    parsedURL.String(),
+		log:        logging.WithComponent("caldav-client"),
+	}, nil
+}
+
+// calendarClient creates a go-webdav CalDAV client for a calendar
+func (c *Client) calendarClient(calendarPath string) (*caldav.Client, error) {
+	client, err := caldav.NewClient(c.httpClient, resolveURL(c.baseURL, calendarPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for calendar: %w", err)
+	}
+	return client, nil
+}
+
+// DiscoverCalendars discovers all calendars from a CalDAV server
+// It tries multiple discovery methods:
+// 1. Direct PROPFIND on the URL
+// 2. .well-known/caldav
+// 3. Common paths (/remote.php/dav for Nextcloud, etc.)
+func DiscoverCalendars(baseURL, username, password string) ([]CalendarInfo, error) {
+	client, err := NewClient(baseURL, username, password, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.DiscoverCalendars(username)
+}
+
+// DiscoverCalendars discovers the calendars reachable from the client's base
+// URL. username is used to try server-specific default paths.
+func (c *Client) DiscoverCalendars(username string) ([]CalendarInfo, error) {
+	ctx := context.Background()
+	log := logging.WithComponent("caldav-discovery")
+	log.Info().Str("url", c.baseURL).Msg("Starting calendar discovery")
+
+	parsedURL, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	// Method 1: Try the URL as-is (might be a direct calendar home or principal)
+	calendars, err := tryDiscoverFromURL(ctx, c.httpClient, c.baseURL, log)
+	if err == nil && len(calendars) > 0 {
+		return calendars, nil
+	}
+	if errors.Is(err, ErrInsufficientScope) {
+		return nil, err
+	}
+	log.Debug().Err(err).Msg("Direct URL discovery failed, trying .well-known")
+
+	// Method 2: Try .well-known/caldav
+	wellKnownURL := fmt.Sprintf("%s://%s/.well-known/caldav", parsedURL.Scheme, parsedURL.Host)
+	calendars, err = tryDiscoverFromURL(ctx, c.httpClient, wellKnownURL, log)
+	if err == nil && len(calendars) > 0 {
+		return calendars, nil
+	}
+	log.Debug().Err(err).Msg(".well-known discovery failed, trying common paths")
+
+	// Method 3: Try common CalDAV paths
+	commonPaths := []string{
+		"/remote.php/dav",    // Nextcloud/ownCloud
+		"/remote.php/caldav", // Older Nextcloud
+		fmt.Sprintf("/remote.php/dav/calendars/%s/", username), // Nextcloud direct
+		"/dav",                    // Generic
+		"/caldav",                 // Generic
+		"/principals/" + username, // Some servers
+	}
+
+	for _, path := range commonPaths {
+		tryURL := fmt.Sprintf("%s://%s%s", parsedURL.Scheme, parsedURL.Host, path)
+		calendars, err = tryDiscoverFromURL(ctx, c.httpClient, tryURL, log)
+		if err == nil && len(calendars) > 0 {
+			return calendars, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no calendars found at %s", c.baseURL)
+}
+
+// tryDiscoverFromURL attempts to discover calendars from a specific URL
+func tryDiscoverFromURL(ctx context.Context, httpClient webdav.HTTPClient, urlStr string, log zerolog.Logger) ([]CalendarInfo, error) {
+	log.Debug().Str("url", urlStr).Msg("Trying discovery from URL")
+
+	client, err := caldav.NewClient(httpClient, urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try to find the current user's principal
+	principal, err := client.FindCurrentUserPrincipal(ctx)
+	if err != nil {
+		log.Debug().Err(err).Msg("FindCurrentUserPrincipal failed")
+		// Try the URL directly as calendar home
+		return tryListCalendarsAt(ctx, httpClient, urlStr, log)
+	}
+
+	log.Debug().Str("principal", principal).Msg("Found principal")
+
+	// Find calendar home set
+	homeSet, err := client.FindCalendarHomeSet(ctx, principal)
+	if err != nil {
+		log.Debug().Err(err).Msg("FindCalendarHomeSet failed")
+		return nil, err
+	}
+
+	log.Debug().Str("homeSet", homeSet).Msg("Found calendar home set")
+
+	// List calendars in the home set
+	return tryListCalendarsAt(ctx, httpClient, resolveURL(urlStr, homeSet), log)
+}
+
+// tryListCalendarsAt lists the event and to-do calendars at a specific URL
+func tryListCalendarsAt(ctx context.Context, httpClient webdav.HTTPClient, urlStr string, log zerolog.Logger) ([]CalendarInfo, error) {
+	client, err := caldav.NewClient(httpClient, urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract path from URL - FindCalendars expects a path, not a full URL
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	log.Debug().Str("url", urlStr).Str("path", parsedURL.Path).Msg("Listing calendars")
+
+	calendars, err := client.FindCalendars(ctx, parsedURL.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []CalendarInfo
+	for _, cal := range calendars {
+		components := syncedComponents(cal.SupportedComponentSet)
+		if len(cal.SupportedComponentSet) > 0 && len(components) == 0 {
+			// e.g. a journal-only collection
+			continue
+		}
+
+		info := CalendarInfo{
+			Path:        cal.Path,
+			Name:        cal.Name,
+			Description: cal.Description,
+			Components:  components,
+		}
+		if info.Name == "" {
+			// Use the last path segment as the name
+			parts := strings.Split(strings.Trim(cal.Path, "/"), "/")
+			if len(parts) > 0 {
+				info.Name = parts[len(parts)-1]
+			}
+		}
+		result = append(result, info)
+		log.Debug().Str("path", cal.Path).Str("name", cal.Name).Msg("Found calendar")
+	}
+
+	return result, nil
+}
+
+// syncedComponents returns the components of a supported-calendar-component-set
+// that are synced
+func syncedComponents(supported []string) []string {
+	var components []string
+	for _, name := range supported {
+		switch strings.ToUpper(name) {
+		case ComponentEvent, ComponentTodo:
+			components = append(components, strings.ToUpper(name))
+		}
+	}
+	return components
+}
+
+// resolveURL resolves a potentially relative URL against a base URL
+func resolveURL(baseURL, path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return path
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		return path
+	}
+
+	return base.ResolveReference(ref).String()
+}
+
+// ParsedObject is a calendar object resource fetched from a calendar
+type ParsedObject struct {
+	Href string
+	ETag string
+	Data *ical.Calendar
+}
+
+// calendarDataRequest requests the complete iCalendar data of objects
+var calendarDataRequest = caldav.CalendarCompRequest{
+	Name:     ical.CompCalendar,
+	AllProps: true,
+	AllComps: true,
+}
+
+// FetchObjects fetches all events and to-dos of a calendar using calendar-query
+func (c *Client) FetchObjects(calendarPath string) ([]ParsedObject, error) {
+	ctx := context.Background()
+	c.log.Debug().Str("path", calendarPath).Msg("Fetching calendar objects")
+
+	client, err := c.calendarClient(calendarPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []ParsedObject
+	for _, component := range []string{ComponentEvent, ComponentTodo} {
+		query := &caldav.CalendarQuery{
+			CompRequest: calendarDataRequest,
+			CompFilter: caldav.CompFilter{
+				Name:  ical.CompCalendar,
+				Comps: []caldav.CompFilter{{Name: component}},
+			},
+		}
+
+		calObjects, err := client.QueryCalendar(ctx, calendarPath, query)
+		if err != nil {
+			if component == ComponentTodo {
+				// Event-only calendars may reject to-do queries
+				c.log.Debug().Err(err).Str("path", calendarPath).Msg("To-do query failed, skipping to-dos")
+				continue
+			}
+			return nil, fmt.Errorf("failed to query calendar: %w", err)
+		}
+		for _, obj := range calObjects {
+			objects = append(objects, ParsedObject{Href: obj.Path, ETag: obj.ETag, Data: obj.Data})
+		}
+	}
+
+	c.log.Info().Int("objects", len(objects)).Str("path", calendarPath).Msg("Fetched objects from calendar")
+	return objects, nil
+}
+
+// SyncResult represents the result of an incremental sync
+type SyncResult struct {
+	SyncToken string         // New sync token to store
+	Updated   []ParsedObject // Objects that were added/modified
+	Deleted   []string       // Hrefs of objects that were deleted
+}
+
+// SyncCalendar performs an incremental sync using sync-collection
+// If syncToken is empty, it performs a full sync
+// Returns the new sync token and the changes since the last sync
+func (c *Client) SyncCalendar(calendarPath, syncToken string) (*SyncResult, error) {
+	ctx := context.Background()
+	c.log.Debug().
+		Str("path", calendarPath).
+		Str("syncToken", syncToken).
+		Msg("Starting sync-collection")
+
+	changes, err := syncCollection(ctx, c.httpClient, resolveURL(c.baseURL, calendarPath), syncToken)
+	if err != nil {
+		// If sync-collection fails (e.g., invalid token), return error
+		// Caller should fall back to full sync
+		return nil, fmt.Errorf("sync-collection failed: %w", err)
+	}
+
+	c.log.Debug().
+		Int("updated", len(changes.Updated)).
+		Int("deleted", len(changes.Deleted)).
+		Str("newToken", changes.SyncToken).
+		Msg("Sync-collection completed")
+
+	result := &SyncResult{
+		SyncToken: changes.SyncToken,
+		Deleted:   changes.Deleted,
+	}
+
+	// sync-collection only reports hrefs and ETags; fetch the changed
+	// objects with calendar-multiget
+	if len(changes.Updated) > 0 {
+		client, err := c.calendarClient(calendarPath)
+		if err != nil {
+			return nil, err
+		}
+		result.Updated, err = c.fetchObjectsByPath(client, calendarPath, changes.Updated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch updated objects: %w", err)
+		}
+	}
+
+	c.log.Info().
+		Int("updated", len(result.Updated)).
+		Int("deleted", len(result.Deleted)).
+		Str("path", calendarPath).
+		Msg("Incremental sync completed")
+
+	return result, nil
+}
+
+// fetchObjectsByPath fetches calendar objects by their paths using
+// calendar-multiget. Objects other than events and to-dos are skipped.
+func (c *Client) fetchObjectsByPath(client *caldav.Client, calendarPath string, paths []string) ([]ParsedObject, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	ctx := context.Background()
+	c.log.Debug().
+		Int("count", len(paths)).
+		Msg("Fetching calendar objects by path using multiget")
+
+	multiGet := &caldav.CalendarMultiGet{
+		Paths:       paths,
+		CompRequest: calendarDataRequest,
+	}
+
+	calObjects, err := client.MultiGetCalendar(ctx, calendarPath, multiGet)
+	if err != nil {
+		return nil, fmt.Errorf("multiget failed: %w", err)
+	}
+
+	var objects []ParsedObject
+	for _, obj := range calObjects {
+		if objectComponent(obj.Data) == "" {
+			continue
+		}
+		objects = append(objects, ParsedObject{Href: obj.Path, ETag: obj.ETag, Data: obj.Data})
+	}
+
+	return objects, nil
+}
+
+// TestConnection tests the connection to the CalDAV server
+func TestConnection(baseURL, username, password string) error {
+	log := logging.WithComponent("caldav-test")
+	log.Info().Str("url", baseURL).Msg("Testing CalDAV connection")
+
+	// Try to discover calendars - this validates credentials and connectivity
+	calendars, err := DiscoverCalendars(baseURL, username, password)
+	if err != nil {
+		return fmt.Errorf("connection test failed: %w", err)
+	}
+
+	if len(calendars) == 0 {
+		return fmt.Errorf("connection successful but no calendars found")
+	}
+
+	log.Info().Int("calendars", len(calendars)).Msg("Connection test successful")
+	return nil
+}
@@ -0,0 +1,488 @@
+package caldav
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/google/uuid"
+	"github.com/hkdb/aerion/internal/calendar"
+	"github.com/teambition/rrule-go"
+)
+
+// maxBoundsInstances caps the instances iterated to find the end of a
+// finite recurrence; longer series are treated as endless
+const maxBoundsInstances = 10000
+
+// decodeCalendar parses stored iCalendar data
+func decodeCalendar(data string) (*ical.Calendar, error) {
+	cal, err := ical.NewDecoder(strings.NewReader(data)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse calendar data: %w", err)
+	}
+	return cal, nil
+}
+
+// encodeCalendar serializes a calendar for storage
+func encodeCalendar(cal *ical.Calendar) (string, error) {
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return "", fmt.Errorf("failed to encode calendar data: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// objectComponent returns the type of a calendar object resource (VEVENT or
+// VTODO), or "" for other objects such as journals
+func objectComponent(cal *ical.Calendar) string {
+	if cal == nil {
+		return ""
+	}
+	for _, child := range cal.Children {
+		if child.Name == ComponentEvent || child.Name == ComponentTodo {
+			return child.Name
+		}
+	}
+	return ""
+}
+
+// masterComponent returns the component of the given type that isn't an
+// overridden occurrence, or nil if the object only holds overrides
+func masterComponent(cal *ical.Calendar, name string) *ical.Component {
+	for _, child := range cal.Children {
+		if child.Name == name && child.Props.Get(ical.PropRecurrenceID) == nil {
+			return child
+		}
+	}
+	return nil
+}
+
+// overrideComponents returns the overridden occurrences (components with a
+// RECURRENCE-ID) of the given type
+func overrideComponents(cal *ical.Calendar, name string) []*ical.Component {
+	var overrides []*ical.Component
+	for _, child := range cal.Children {
+		if child.Name == name && child.Props.Get(ical.PropRecurrenceID) != nil {
+			overrides = append(overrides, child)
+		}
+	}
+	return overrides
+}
+
+// propText returns the text value of a property, or "" if it is missing
+func propText(props ical.Props, name string) string {
+	prop := props.Get(name)
+	if prop == nil {
+		return ""
+	}
+	if text, err := prop.Text(); err == nil {
+		return text
+	}
+	return prop.Value
+}
+
+// componentTimes returns the start and end of an event, or of a to-do's
+// DTSTART/DUE span. ok is false for to-dos without dates.
+func componentTimes(cal *ical.Calendar, comp *ical.Component) (start, end time.Time, allDay, ok bool) {
+	startProp := comp.Props.Get(ical.PropDateTimeStart)
+
+	if comp.Name == ComponentTodo {
+		dueProp := comp.Props.Get(ical.PropDue)
+		var err error
+		if startProp != nil {
+			if start, allDay, err = calendar.ParseDateTime(cal, startProp); err != nil {
+				return start, end, false, false
+			}
+		}
+		if dueProp != nil {
+			var dueAllDay bool
+			if end, dueAllDay, err = calendar.ParseDateTime(cal, dueProp); err != nil {
+				return start, end, false, false
+			}
+			if startProp == nil {
+				start, allDay = end, dueAllDay
+			}
+		} else if startProp != nil {
+			end = start
+		}
+		return start, end, allDay, startProp != nil || dueProp != nil
+	}
+
+	if startProp == nil {
+		return start, end, false, false
+	}
+	start, allDay, err := calendar.ParseDateTime(cal, startProp)
+	if err != nil {
+		return start, end, false, false
+	}
+	end, err = calendar.EventEnd(cal, comp, start, allDay)
+	if err != nil {
+		end = start
+	}
+	return start, end, allDay, true
+}
+
+// propDateTimes parses a multi-valued DATE/DATE-TIME property (RDATE, EXDATE)
+func propDateTimes(cal *ical.Calendar, comp *ical.Component, name string) []time.Time {
+	var times []time.Time
+	for _, prop := range comp.Props.Values(name) {
+		if strings.EqualFold(prop.Params.Get(ical.ParamValue), string(ical.ValuePeriod)) {
+			continue
+		}
+		for _, value := range strings.Split(prop.Value, ",") {
+			single := ical.Prop{Name: prop.Name, Params: prop.Params, Value: strings.TrimSpace(value)}
+			if t, _, err := calendar.ParseDateTime(cal, &single); err == nil {
+				times = append(times, t)
+			}
+		}
+	}
+	return times
+}
+
+// recurrenceSet builds the recurrence set of a component starting at start,
+// or nil if the component doesn't recur. go-ical's RecurrenceSet only
+// understands IANA TZIDs and mixes up RDATE and EXDATE.
+func recurrenceSet(cal *ical.Calendar, comp *ical.Component, start time.Time) (*rrule.Set, error) {
+	ruleProp := comp.Props.Get(ical.PropRecurrenceRule)
+	rdates := propDateTimes(cal, comp, ical.PropRecurrenceDates)
+	if ruleProp == nil && len(rdates) == 0 {
+		return nil, nil
+	}
+
+	set := &rrule.Set{}
+	set.DTStart(start)
+	if ruleProp != nil {
+		opt, err := rrule.StrToROptionInLocation(ruleProp.Value, start.Location())
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurrence rule: %w", err)
+		}
+		opt.Dtstart = start
+		rule, err := rrule.NewRRule(*opt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurrence rule: %w", err)
+		}
+		set.RRule(rule)
+	} else {
+		// Without a rule, DTSTART is the first instance
+		set.RDate(start)
+	}
+	for _, t := range rdates {
+		set.RDate(t)
+	}
+	for _, t := range propDateTimes(cal, comp, ical.PropExceptionDates) {
+		set.ExDate(t)
+	}
+	return set, nil
+}
+
+// isFinite reports whether a component's recurrence rule ends
+func isFinite(comp *ical.Component) bool {
+	prop := comp.Props.Get(ical.PropRecurrenceRule)
+	if prop == nil {
+		return true
+	}
+	rule := strings.ToUpper(prop.Value)
+	return strings.Contains(rule, "COUNT=") || strings.Contains(rule, "UNTIL=")
+}
+
+// eventFromCalendar summarizes a calendar object resource. rangeStart and
+// rangeEnd bound all its occurrences; rangeEnd is nil for endless
+// recurrences and both are nil for to-dos without dates.
+func eventFromCalendar(cal *ical.Calendar) (ev *Event, rangeStart, rangeEnd *time.Time) {
+	name := objectComponent(cal)
+	ev = &Event{Component: name}
+	if name == "" {
+		return ev, nil, nil
+	}
+
+	overrides := overrideComponents(cal, name)
+	master := masterComponent(cal, name)
+	summary := master
+	if summary == nil {
+		summary = overrides[0]
+	}
+
+	ev.UID = propText(summary.Props, ical.PropUID)
+	ev.Summary = propText(summary.Props, ical.PropSummary)
+	ev.Location = propText(summary.Props, ical.PropLocation)
+	ev.Description = strings.TrimSpace(propText(summary.Props, ical.PropDescription))
+	ev.Status = strings.ToUpper(propText(summary.Props, ical.PropStatus))
+	if prop := summary.Props.Get(ical.PropRecurrenceRule); prop != nil {
+		ev.RRule = prop.Value
+	}
+	ev.Recurring = ev.RRule != "" || len(overrides) > 0 || summary.Props.Get(ical.PropRecurrenceDates) != nil
+
+	start, end, allDay, ok := componentTimes(cal, summary)
+	if ok {
+		ev.Start, ev.End, ev.AllDay = &start, &end, allDay
+	}
+
+	// Bounds over the series and every override
+	var first, last time.Time
+	endless := false
+	extend := func(s, e time.Time) {
+		if first.IsZero() || s.Before(first) {
+			first = s
+		}
+		if e.After(last) {
+			last = e
+		}
+	}
+
+	if master != nil && ok {
+		extend(start, end)
+		set, err := recurrenceSet(cal, master, start)
+		switch {
+		case err != nil || set == nil:
+		case !isFinite(master):
+			endless = true
+		default:
+			duration := end.Sub(start)
+			next := set.Iterator()
+			n := 0
+			for t, more := next(); more; t, more = next() {
+				if n++; n > maxBoundsInstances {
+					endless = true
+					break
+				}
+				extend(t, t.Add(duration))
+			}
+		}
+	}
+	for _, override := range overrides {
+		if s, e, _, ok := componentTimes(cal, override); ok {
+			extend(s, e)
+		}
+	}
+
+	if first.IsZero() {
+		return ev, nil, nil
+	}
+	rangeStart = &first
+	if !endless {
+		rangeEnd = &last
+	}
+	return ev, rangeStart, rangeEnd
+}
+
+// overlaps reports whether [start, end) intersects [from, to). Instants
+// (start == end) overlap when they fall inside the range.
+func overlaps(start, end, from, to time.Time) bool {
+	if !start.Before(to) {
+		return false
+	}
+	if end.Equal(start) {
+		return !start.Before(from)
+	}
+	return end.After(from)
+}
+
+// expandOccurrences returns the occurrences of a stored event that overlap
+// [from, to), applying overridden and cancelled occurrences
+func expandOccurrences(ev *Event, cal *ical.Calendar, from, to time.Time) []Occurrence {
+	name := objectComponent(cal)
+	if name == "" {
+		return nil
+	}
+
+	occurrence := func(comp *ical.Component, start, end time.Time, allDay bool) Occurrence {
+		return Occurrence{
+			EventID:     ev.ID,
+			CalendarID:  ev.CalendarID,
+			UID:         ev.UID,
+			Component:   name,
+			Summary:     propText(comp.Props, ical.PropSummary),
+			Location:    propText(comp.Props, ical.PropLocation),
+			Description: strings.TrimSpace(propText(comp.Props, ical.PropDescription)),
+			Status:      strings.ToUpper(propText(comp.Props, ical.PropStatus)),
+			Start:       start,
+			End:         end,
+			AllDay:      allDay,
+		}
+	}
+
+	var result []Occurrence
+	overridden := make(map[int64]bool)
+	for _, override := range overrideComponents(cal, name) {
+		rid, _, err := calendar.ParseDateTime(cal, override.Props.Get(ical.PropRecurrenceID))
+		if err != nil {
+			continue
+		}
+		overridden[rid.Unix()] = true
+
+		if strings.EqualFold(propText(override.Props, ical.PropStatus), "CANCELLED") {
+			continue
+		}
+		start, end, allDay, ok := componentTimes(cal, override)
+		if !ok || !overlaps(start, end, from, to) {
+			continue
+		}
+		occ := occurrence(override, start, end, allDay)
+		occ.RecurrenceID = &rid
+		result = append(result, occ)
+	}
+
+	master := masterComponent(cal, name)
+	if master == nil {
+		return result
+	}
+	start, end, allDay, ok := componentTimes(cal, master)
+	if !ok {
+		return result
+	}
+
+	set, err := recurrenceSet(cal, master, start)
+	if err != nil || set == nil {
+		if overlaps(start, end, from, to) {
+			result = append(result, occurrence(master, start, end, allDay))
+		}
+		return result
+	}
+
+	duration := end.Sub(start)
+	for _, t := range set.Between(from.Add(-duration), to, true) {
+		if overridden[t.Unix()] || !overlaps(t, t.Add(duration), from, to) {
+			continue
+		}
+		if allDay {
+			// Keep all-day instances at local midnight across DST changes
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, start.Location())
+		}
+		occ := occurrence(master, t, t.Add(duration), allDay)
+		rid := t
+		occ.RecurrenceID = &rid
+		result = append(result, occ)
+	}
+	return result
+}
+
+// sortOccurrences orders occurrences by start time, all-day entries first
+func sortOccurrences(occurrences []Occurrence) {
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		a, b := occurrences[i], occurrences[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		return a.AllDay && !b.AllDay
+	})
+}
+
+// ============================================================================
+// Editing
+// ============================================================================
+
+// applyEventInput applies an app edit to an event's calendar data, or
+// creates a new calendar when base is nil. Properties the app doesn't model
+// (attendees, alarms, categories, ...) are kept.
+func applyEventInput(base *ical.Calendar, input *EventInput) (*ical.Calendar, error) {
+	if strings.TrimSpace(input.Summary) == "" {
+		return nil, fmt.Errorf("event title is required")
+	}
+	if input.Start.IsZero() {
+		return nil, fmt.Errorf("event start is required")
+	}
+	if input.End.Before(input.Start) {
+		return nil, fmt.Errorf("event ends before it starts")
+	}
+
+	now := time.Now().UTC()
+	cal := base
+	var event *ical.Component
+	if cal == nil {
+		cal = ical.NewCalendar()
+		cal.Props.SetText(ical.PropProductID, calendar.ProductID)
+		cal.Props.SetText(ical.PropVersion, "2.0")
+
+		event = ical.NewComponent(ical.CompEvent)
+		event.Props.SetText(ical.PropUID, uuid.New().String())
+		event.Props.SetDateTime(ical.PropCreated, now)
+		cal.Children = append(cal.Children, event)
+	} else {
+		event = masterComponent(cal, ical.CompEvent)
+		if event == nil {
+			return nil, fmt.Errorf("calendar object has no editable event")
+		}
+		sequence := 0
+		if prop := event.Props.Get(ical.PropSequence); prop != nil {
+			sequence, _ = prop.Int()
+		}
+		seq := ical.NewProp(ical.PropSequence)
+		seq.SetValueType(ical.ValueInt)
+		seq.Value = fmt.Sprintf("%d", sequence+1)
+		event.Props.Set(seq)
+	}
+
+	event.Props.SetDateTime(ical.PropDateTimeStamp, now)
+	event.Props.SetDateTime(ical.PropLastModified, now)
+	setOptionalText(event.Props, ical.PropSummary, strings.TrimSpace(input.Summary))
+	setOptionalText(event.Props, ical.PropLocation, input.Location)
+	setOptionalText(event.Props, ical.PropDescription, input.Description)
+
+	// Keep the event's time zone when it is a known IANA zone so recurring
+	// events follow DST; otherwise store UTC
+	loc := time.UTC
+	if prop := event.Props.Get(ical.PropDateTimeStart); prop != nil {
+		if tzid := prop.Params.Get(ical.ParamTimezoneID); tzid != "" {
+			if zone, err := time.LoadLocation(tzid); err == nil {
+				loc = zone
+			}
+		}
+	}
+
+	event.Props.Del(ical.PropDuration)
+	if input.AllDay {
+		start := dateOnly(input.Start)
+		end := dateOnly(input.End)
+		if !end.After(start) {
+			end = start.AddDate(0, 0, 1)
+		}
+		event.Props.SetDate(ical.PropDateTimeStart, start)
+		event.Props.SetDate(ical.PropDateTimeEnd, end)
+	} else {
+		event.Props.SetDateTime(ical.PropDateTimeStart, input.Start.In(loc))
+		event.Props.SetDateTime(ical.PropDateTimeEnd, input.End.In(loc))
+	}
+
+	if rule := strings.TrimPrefix(strings.TrimSpace(input.RRule), "RRULE:"); rule != "" {
+		if _, err := rrule.StrToROption(rule); err != nil {
+			return nil, fmt.Errorf("invalid recurrence rule: %w", err)
+		}
+		prop := ical.NewProp(ical.PropRecurrenceRule)
+		prop.SetValueType(ical.ValueRecurrence)
+		prop.Value = rule
+		event.Props.Set(prop)
+	} else if event.Props.Get(ical.PropRecurrenceRule) != nil || event.Props.Get(ical.PropRecurrenceDates) != nil {
+		// No longer recurring: drop the series' exceptions and overrides
+		event.Props.Del(ical.PropRecurrenceRule)
+		event.Props.Del(ical.PropRecurrenceDates)
+		event.Props.Del(ical.PropExceptionDates)
+		children := cal.Children[:0]
+		for _, child := range cal.Children {
+			if child.Name == ical.CompEvent && child.Props.Get(ical.PropRecurrenceID) != nil {
+				continue
+			}
+			children = append(children, child)
+		}
+		cal.Children = children
+	}
+
+	return cal, nil
+}
+
+// setOptionalText sets a text property, removing it when value is empty
+func setOptionalText(props ical.Props, name, value string) {
+	if value == "" {
+		props.Del(name)
+		return
+	}
+	props.SetText(name, value)
+}
+
+// dateOnly returns the calendar date of t as midnight UTC, the form
+// ical.Props.SetDate expects
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
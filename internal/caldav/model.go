@@ -0,0 +1,144 @@
+// Package caldav provides CalDAV calendar sync functionality
+package caldav
+
+import (
+	"time"
+)
+
+// SourceType represents the type of calendar source
+type SourceType string
+
+const (
+	SourceTypeCalDAV SourceType = "caldav"
+	SourceTypeGoogle SourceType = "google" // Google Calendar's CalDAV API with the linked account's OAuth token
+)
+
+// Component types stored from calendar collections
+const (
+	ComponentEvent = "VEVENT"
+	ComponentTodo  = "VTODO"
+)
+
+// Source represents a CalDAV server/account configuration
+type Source struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Type         SourceType `json:"type"`
+	URL          string     `json:"url"`                  // CalDAV server URL (principal URL for Google)
+	Username     string     `json:"username"`             // CalDAV username (empty for OAuth sources)
+	AccountID    *string    `json:"account_id,omitempty"` // Linked email account ID (for OAuth sources using account's token)
+	Enabled      bool       `json:"enabled"`
+	SyncInterval int        `json:"sync_interval"` // Minutes (0 = manual only)
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastErrorAt  *time.Time `json:"last_error_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	// Calendars associated with this source (populated by GetSourceWithCalendars)
+	Calendars []*Calendar `json:"calendars,omitempty"`
+}
+
+// SourceConfig is used for creating/updating a source
+type SourceConfig struct {
+	Name         string     `json:"name"`
+	Type         SourceType `json:"type"`
+	URL          string     `json:"url"`                  // CalDAV server URL
+	Username     string     `json:"username"`             // CalDAV username (empty for OAuth sources)
+	Password     string     `json:"password"`             // CalDAV password, only used for create/update, not stored in DB
+	AccountID    string     `json:"account_id,omitempty"` // Linked email account ID (for OAuth sources)
+	Enabled      bool       `json:"enabled"`
+	SyncInterval int        `json:"sync_interval"`
+
+	// Calendars to enable (paths) - discovered automatically for OAuth sources
+	EnabledCalendars []string `json:"enabled_calendars,omitempty"`
+}
+
+// Calendar represents a single calendar collection within a source
+type Calendar struct {
+	ID           string     `json:"id"`
+	SourceID     string     `json:"source_id"`
+	Path         string     `json:"path"`
+	Name         string     `json:"name"`
+	Components   []string   `json:"components,omitempty"` // Supported components (VEVENT, VTODO); empty = any
+	Enabled      bool       `json:"enabled"`
+	SyncToken    string     `json:"sync_token,omitempty"`
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty"`
+}
+
+// CalendarInfo is returned by discovery (before the calendar is saved to DB)
+type CalendarInfo struct {
+	Path        string   `json:"path"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Components  []string `json:"components,omitempty"`
+}
+
+// Event is a calendar object resource: a VEVENT or VTODO together with its
+// overridden occurrences. Times describe the master component.
+type Event struct {
+	ID          string     `json:"id"`
+	CalendarID  string     `json:"calendar_id"`
+	Href        string     `json:"href"` // CalDAV resource path
+	ETag        string     `json:"etag"` // For change detection and conditional writes
+	UID         string     `json:"uid"`
+	Component   string     `json:"component"` // VEVENT or VTODO
+	Summary     string     `json:"summary"`
+	Location    string     `json:"location,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Status      string     `json:"status,omitempty"` // CONFIRMED, TENTATIVE, CANCELLED, NEEDS-ACTION, COMPLETED, ...
+	Start       *time.Time `json:"start,omitempty"`
+	End         *time.Time `json:"end,omitempty"` // DTEND/DURATION for events, DUE for to-dos
+	AllDay      bool       `json:"all_day"`
+	RRule       string     `json:"rrule,omitempty"`
+	Recurring   bool       `json:"recurring"`
+	SyncedAt    time.Time  `json:"synced_at"`
+}
+
+// Occurrence is a single instance of an event within a queried date range
+type Occurrence struct {
+	EventID      string     `json:"event_id"`
+	CalendarID   string     `json:"calendar_id"`
+	UID          string     `json:"uid"`
+	Component    string     `json:"component"`
+	Summary      string     `json:"summary"`
+	Location     string     `json:"location,omitempty"`
+	Description  string     `json:"description,omitempty"`
+	Status       string     `json:"status,omitempty"`
+	Start        time.Time  `json:"start"`
+	End          time.Time  `json:"end"`
+	AllDay       bool       `json:"all_day"`
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty"` // Set for instances of a recurring event
+}
+
+// EventInput is an event created or edited in the app. Edits of a recurring
+// event apply to the whole series.
+type EventInput struct {
+	ID          string    `json:"id,omitempty"` // Empty for a new event
+	CalendarID  string    `json:"calendar_id"`
+	ETag        string    `json:"etag,omitempty"` // ETag the edit is based on
+	Summary     string    `json:"summary"`
+	Location    string    `json:"location,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	AllDay      bool      `json:"all_day"`
+	RRule       string    `json:"rrule,omitempty"` // e.g. "FREQ=WEEKLY;BYDAY=MO"; empty removes recurrence
+}
+
+// SaveEventResult is the outcome of writing an event back to its server
+type SaveEventResult struct {
+	Event *Event `json:"event"` // Saved event, or nil when the edit was rejected
+
+	// Conflict is the current server copy when the edit was rejected because
+	// the event changed on the server. It has already been stored locally;
+	// saving the edit again with Conflict.ETag overwrites the server copy.
+	Conflict *Event `json:"conflict,omitempty"`
+}
+
+// SourceError represents an error that occurred during sync
+type SourceError struct {
+	SourceID   string    `json:"source_id"`
+	SourceName string    `json:"source_name"`
+	Error      string    `json:"error"`
+	ErrorAt    time.Time `json:"error_at"`
+}
@@ -0,0 +1,791 @@
+package caldav
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/google/uuid"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/rs/zerolog"
+)
+
+// Store handles calendar source and event storage
+type Store struct {
+	db  *sql.DB
+	log zerolog.Logger
+}
+
+// NewStore creates a new CalDAV store
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db:  db,
+		log: logging.WithComponent("caldav-store"),
+	}
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ============================================================================
+// Source CRUD
+// ============================================================================
+
+// sourceColumns are the columns read by scanSource
+const sourceColumns = `id, name, type, url, username, account_id, enabled, sync_interval,
+	last_synced_at, last_error, last_error_at, created_at`
+
+// scanSource reads a calendar_sources row
+func scanSource(row rowScanner) (*Source, error) {
+	var source Source
+	var lastSyncedAt, lastErrorAt sql.NullTime
+	var username, lastError, accountID sql.NullString
+
+	err := row.Scan(
+		&source.ID, &source.Name, &source.Type, &source.URL, &username,
+		&accountID, &source.Enabled, &source.SyncInterval,
+		&lastSyncedAt, &lastError, &lastErrorAt, &source.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	source.Username = username.String
+	if accountID.Valid {
+		source.AccountID = &accountID.String
+	}
+	if lastSyncedAt.Valid {
+		source.LastSyncedAt = &lastSyncedAt.Time
+	}
+	if lastError.Valid {
+		source.LastError = lastError.String
+	}
+	if lastErrorAt.Valid {
+		source.LastErrorAt = &lastErrorAt.Time
+	}
+	return &source, nil
+}
+
+// CreateSource creates a new calendar source
+func (s *Store) CreateSource(config *SourceConfig) (*Source, error) {
+	id := uuid.New().String()
+	now := time.Now()
+
+	// Handle account_id (convert empty string to NULL)
+	var accountID *string
+	if config.AccountID != "" {
+		accountID = &config.AccountID
+	}
+
+	query := `
+		INSERT INTO calendar_sources (id, name, type, url, username, account_id, enabled, sync_interval, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(query,
+		id, config.Name, config.Type, config.URL, config.Username, accountID,
+		config.Enabled, config.SyncInterval, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create source: %w", err)
+	}
+
+	source := &Source{
+		ID:           id,
+		Name:         config.Name,
+		Type:         config.Type,
+		URL:          config.URL,
+		Username:     config.Username,
+		AccountID:    accountID,
+		Enabled:      config.Enabled,
+		SyncInterval: config.SyncInterval,
+		CreatedAt:    now,
+	}
+
+	s.log.Info().Str("id", id).Str("name", config.Name).Msg("Calendar source created")
+	return source, nil
+}
+
+// GetSource returns a source by ID
+func (s *Store) GetSource(id string) (*Source, error) {
+	row := s.db.QueryRow("SELECT "+sourceColumns+" FROM calendar_sources WHERE id = ?", id)
+
+	source, err := scanSource(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source: %w", err)
+	}
+	return source, nil
+}
+
+// GetSourceWithCalendars returns a source by ID with its calendars
+func (s *Store) GetSourceWithCalendars(id string) (*Source, error) {
+	source, err := s.GetSource(id)
+	if err != nil || source == nil {
+		return source, err
+	}
+
+	source.Calendars, err = s.ListCalendars(id)
+	if err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// GetSourceByAccountID returns a calendar source linked to an email account
+func (s *Store) GetSourceByAccountID(accountID string) (*Source, error) {
+	row := s.db.QueryRow("SELECT "+sourceColumns+" FROM calendar_sources WHERE account_id = ?", accountID)
+
+	source, err := scanSource(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source by account ID: %w", err)
+	}
+	return source, nil
+}
+
+// ListSources returns all calendar sources
+func (s *Store) ListSources() ([]*Source, error) {
+	rows, err := s.db.Query("SELECT " + sourceColumns + " FROM calendar_sources ORDER BY created_at ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sources: %w", err)
+	}
+	defer rows.Close()
+
+	var sources []*Source
+	for rows.Next() {
+		source, err := scanSource(rows)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Failed to scan source row")
+			continue
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// UpdateSource updates a source's configuration
+func (s *Store) UpdateSource(id string, config *SourceConfig) error {
+	query := `
+		UPDATE calendar_sources
+		SET name = ?, url = ?, username = ?, enabled = ?, sync_interval = ?
+		WHERE id = ?
+	`
+
+	result, err := s.db.Exec(query,
+		config.Name, config.URL, config.Username, config.Enabled, config.SyncInterval, id)
+	if err != nil {
+		return fmt.Errorf("failed to update source: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("source not found: %s", id)
+	}
+
+	s.log.Info().Str("id", id).Msg("Calendar source updated")
+	return nil
+}
+
+// DeleteSource deletes a source and all its calendars/events (via CASCADE)
+func (s *Store) DeleteSource(id string) error {
+	result, err := s.db.Exec("DELETE FROM calendar_sources WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete source: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("source not found: %s", id)
+	}
+
+	s.log.Info().Str("id", id).Msg("Calendar source deleted")
+	return nil
+}
+
+// UpdateSourceSyncStatus updates the sync status after a sync attempt
+func (s *Store) UpdateSourceSyncStatus(id string, syncError string) error {
+	now := time.Now()
+
+	var query string
+	var args []interface{}
+
+	if syncError == "" {
+		// Success: update last_synced_at, clear error
+		query = `
+			UPDATE calendar_sources
+			SET last_synced_at = ?, last_error = NULL, last_error_at = NULL
+			WHERE id = ?
+		`
+		args = []interface{}{now, id}
+	} else {
+		// Error: update error fields
+		query = `
+			UPDATE calendar_sources
+			SET last_error = ?, last_error_at = ?
+			WHERE id = ?
+		`
+		args = []interface{}{syncError, now, id}
+	}
+
+	_, err := s.db.Exec(query, args...)
+	return err
+}
+
+// ClearSourceError clears the error for a source
+func (s *Store) ClearSourceError(id string) error {
+	_, err := s.db.Exec(
+		"UPDATE calendar_sources SET last_error = NULL, last_error_at = NULL WHERE id = ?", id)
+	return err
+}
+
+// GetSourcesWithErrors returns all sources that have errors
+func (s *Store) GetSourcesWithErrors() ([]*SourceError, error) {
+	query := `
+		SELECT id, name, last_error, last_error_at
+		FROM calendar_sources
+		WHERE last_error IS NOT NULL AND last_error != ''
+		ORDER BY last_error_at DESC
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sources with errors: %w", err)
+	}
+	defer rows.Close()
+
+	var errors []*SourceError
+	for rows.Next() {
+		var se SourceError
+		var errorAt sql.NullTime
+
+		if err := rows.Scan(&se.SourceID, &se.SourceName, &se.Error, &errorAt); err != nil {
+			continue
+		}
+		if errorAt.Valid {
+			se.ErrorAt = errorAt.Time
+		}
+
+		errors = append(errors, &se)
+	}
+
+	return errors, nil
+}
+
+// ============================================================================
+// Calendar CRUD
+// ============================================================================
+
+// calendarColumns are the columns read by scanCalendar
+const calendarColumns = `id, source_id, path, name, components, enabled, sync_token, last_synced_at`
+
+// scanCalendar reads a calendar_source_calendars row
+func scanCalendar(row rowScanner) (*Calendar, error) {
+	var cal Calendar
+	var name, components, syncToken sql.NullString
+	var lastSyncedAt sql.NullTime
+
+	err := row.Scan(&cal.ID, &cal.SourceID, &cal.Path, &name, &components,
+		&cal.Enabled, &syncToken, &lastSyncedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	cal.Name = name.String
+	if components.String != "" {
+		cal.Components = strings.Split(components.String, ",")
+	}
+	cal.SyncToken = syncToken.String
+	if lastSyncedAt.Valid {
+		cal.LastSyncedAt = &lastSyncedAt.Time
+	}
+	return &cal, nil
+}
+
+// CreateCalendar creates a new calendar for a source
+func (s *Store) CreateCalendar(sourceID, path, name string, components []string, enabled bool) (*Calendar, error) {
+	id := uuid.New().String()
+
+	query := `
+		INSERT INTO calendar_source_calendars (id, source_id, path, name, components, enabled)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(query, id, sourceID, path, name, strings.Join(components, ","), enabled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calendar: %w", err)
+	}
+
+	return &Calendar{
+		ID:         id,
+		SourceID:   sourceID,
+		Path:       path,
+		Name:       name,
+		Components: components,
+		Enabled:    enabled,
+	}, nil
+}
+
+// GetCalendar returns a calendar by ID
+func (s *Store) GetCalendar(id string) (*Calendar, error) {
+	row := s.db.QueryRow("SELECT "+calendarColumns+" FROM calendar_source_calendars WHERE id = ?", id)
+
+	cal, err := scanCalendar(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar: %w", err)
+	}
+	return cal, nil
+}
+
+// ListCalendars returns all calendars for a source
+func (s *Store) ListCalendars(sourceID string) ([]*Calendar, error) {
+	return s.listCalendars(
+		"SELECT "+calendarColumns+" FROM calendar_source_calendars WHERE source_id = ? ORDER BY name ASC",
+		sourceID)
+}
+
+// ListEnabledCalendars returns all enabled calendars for a source
+func (s *Store) ListEnabledCalendars(sourceID string) ([]*Calendar, error) {
+	return s.listCalendars(
+		"SELECT "+calendarColumns+" FROM calendar_source_calendars WHERE source_id = ? AND enabled = 1 ORDER BY name ASC",
+		sourceID)
+}
+
+// listCalendars runs a calendar query
+func (s *Store) listCalendars(query string, args ...interface{}) ([]*Calendar, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendars: %w", err)
+	}
+	defer rows.Close()
+
+	var calendars []*Calendar
+	for rows.Next() {
+		cal, err := scanCalendar(rows)
+		if err != nil {
+			continue
+		}
+		calendars = append(calendars, cal)
+	}
+
+	return calendars, nil
+}
+
+// SetCalendarEnabled enables or disables a calendar
+func (s *Store) SetCalendarEnabled(id string, enabled bool) error {
+	_, err := s.db.Exec("UPDATE calendar_source_calendars SET enabled = ? WHERE id = ?", enabled, id)
+	return err
+}
+
+// UpdateCalendarSyncToken updates the sync token after a sync
+func (s *Store) UpdateCalendarSyncToken(id, syncToken string) error {
+	now := time.Now()
+	query := `
+		UPDATE calendar_source_calendars
+		SET sync_token = ?, last_synced_at = ?
+		WHERE id = ?
+	`
+	_, err := s.db.Exec(query, syncToken, now, id)
+	return err
+}
+
+// DeleteCalendarsForSource deletes all calendars for a source
+func (s *Store) DeleteCalendarsForSource(sourceID string) error {
+	_, err := s.db.Exec("DELETE FROM calendar_source_calendars WHERE source_id = ?", sourceID)
+	return err
+}
+
+// ============================================================================
+// Event CRUD (calendar object resources)
+// ============================================================================
+
+// objectRow holds the values stored for a calendar object
+type objectRow struct {
+	uid, component, summary string
+	rangeStart, rangeEnd    interface{}
+	recurring               bool
+	data                    string
+}
+
+// newObjectRow derives the stored columns of a calendar object. Range
+// bounds are stored in UTC so they compare correctly as text.
+func newObjectRow(cal *ical.Calendar) (*objectRow, error) {
+	data, err := encodeCalendar(cal)
+	if err != nil {
+		return nil, err
+	}
+
+	ev, rangeStart, rangeEnd := eventFromCalendar(cal)
+	row := &objectRow{
+		uid:       ev.UID,
+		component: ev.Component,
+		summary:   ev.Summary,
+		recurring: ev.Recurring,
+		data:      data,
+	}
+	if rangeStart != nil {
+		row.rangeStart = rangeStart.UTC()
+	}
+	if rangeEnd != nil {
+		row.rangeEnd = rangeEnd.UTC()
+	}
+	return row, nil
+}
+
+// upsertObjectSQL inserts or updates a single calendar object by href
+const upsertObjectSQL = `
+	INSERT INTO caldav_objects (id, calendar_id, href, etag, uid, component, summary,
+		range_start, range_end, recurring, ical, synced_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(calendar_id, href) DO UPDATE SET
+		etag = excluded.etag,
+		uid = excluded.uid,
+		component = excluded.component,
+		summary = excluded.summary,
+		range_start = excluded.range_start,
+		range_end = excluded.range_end,
+		recurring = excluded.recurring,
+		ical = excluded.ical,
+		synced_at = excluded.synced_at
+`
+
+// UpsertObjectsBatch stores the calendar objects fetched from a calendar
+func (s *Store) UpsertObjectsBatch(calendarID string, objects []ParsedObject) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := s.upsertObjects(tx, calendarID, objects); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceObjects makes the stored objects of a calendar match a full
+// listing from the server: objects are updated in place, keeping their IDs,
+// and objects the server no longer has are deleted, all in one transaction
+func (s *Store) ReplaceObjects(calendarID string, objects []ParsedObject) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	kept, err := s.upsertObjects(tx, calendarID, objects)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT id FROM caldav_objects WHERE calendar_id = ?", calendarID)
+	if err != nil {
+		return fmt.Errorf("failed to list calendar objects: %w", err)
+	}
+	var stale []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan calendar object: %w", err)
+		}
+		if !kept[id] {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list calendar objects: %w", err)
+	}
+
+	for _, id := range stale {
+		if _, err := tx.Exec("DELETE FROM caldav_objects WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete calendar object: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// upsertObjects stores calendar objects within a transaction and returns
+// the IDs of the stored rows. An object keeps the ID of the row with its
+// href, or of the row with its UID when the server moved it to a new href.
+func (s *Store) upsertObjects(tx *sql.Tx, calendarID string, objects []ParsedObject) (map[string]bool, error) {
+	byHref := make(map[string]string)
+	byUID := make(map[string]string)
+	hrefOf := make(map[string]string)
+	rows, err := tx.Query("SELECT id, href, uid FROM caldav_objects WHERE calendar_id = ?", calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendar objects: %w", err)
+	}
+	for rows.Next() {
+		var id, href string
+		var uid sql.NullString
+		if err := rows.Scan(&id, &href, &uid); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan calendar object: %w", err)
+		}
+		byHref[href] = id
+		hrefOf[id] = href
+		if uid.String != "" {
+			byUID[uid.String] = id
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list calendar objects: %w", err)
+	}
+
+	// Hrefs present in this batch keep their own rows
+	incoming := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		incoming[obj.Href] = true
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO caldav_objects (id, calendar_id, href, etag, uid, component, summary,
+			range_start, range_end, recurring, ical, synced_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			href = excluded.href,
+			etag = excluded.etag,
+			uid = excluded.uid,
+			component = excluded.component,
+			summary = excluded.summary,
+			range_start = excluded.range_start,
+			range_end = excluded.range_end,
+			recurring = excluded.recurring,
+			ical = excluded.ical,
+			synced_at = excluded.synced_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	kept := make(map[string]bool, len(objects))
+	now := time.Now()
+	for _, obj := range objects {
+		if objectComponent(obj.Data) == "" {
+			continue
+		}
+		row, err := newObjectRow(obj.Data)
+		if err != nil {
+			s.log.Warn().Err(err).Str("href", obj.Href).Msg("Failed to encode calendar object")
+			continue
+		}
+
+		id, ok := byHref[obj.Href]
+		if !ok {
+			if moved, found := byUID[row.uid]; found && row.uid != "" && !kept[moved] && !incoming[hrefOf[moved]] {
+				id = moved
+			} else {
+				id = uuid.New().String()
+			}
+		}
+
+		if _, err := stmt.Exec(id, calendarID, obj.Href, obj.ETag,
+			row.uid, row.component, row.summary, row.rangeStart, row.rangeEnd,
+			row.recurring, row.data, now); err != nil {
+			s.log.Warn().Err(err).Str("href", obj.Href).Msg("Failed to upsert calendar object in batch")
+			continue
+		}
+		kept[id] = true
+	}
+
+	return kept, nil
+}
+
+// DeleteObjectsByHrefs deletes objects by their hrefs
+func (s *Store) DeleteObjectsByHrefs(calendarID string, hrefs []string) error {
+	if len(hrefs) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, href := range hrefs {
+		if _, err := tx.Exec(
+			"DELETE FROM caldav_objects WHERE calendar_id = ? AND href = ?",
+			calendarID, href); err != nil {
+			return fmt.Errorf("failed to delete object with href %s: %w", href, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// objectColumns are the columns read by scanEvent
+const objectColumns = `o.id, o.calendar_id, o.href, o.etag, o.ical, o.synced_at`
+
+// scanEvent reads a caldav_objects row into an Event and its calendar data
+func scanEvent(row rowScanner) (*Event, *ical.Calendar, error) {
+	var id, calendarID, href, data string
+	var etag sql.NullString
+	var syncedAt sql.NullTime
+	if err := row.Scan(&id, &calendarID, &href, &etag, &data, &syncedAt); err != nil {
+		return nil, nil, err
+	}
+
+	cal, err := decodeCalendar(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ev, _, _ := eventFromCalendar(cal)
+	ev.ID = id
+	ev.CalendarID = calendarID
+	ev.Href = href
+	ev.ETag = etag.String
+	if syncedAt.Valid {
+		ev.SyncedAt = syncedAt.Time
+	}
+	return ev, cal, nil
+}
+
+// GetEvent returns an event and its calendar data by ID
+func (s *Store) GetEvent(id string) (*Event, *ical.Calendar, error) {
+	row := s.db.QueryRow("SELECT "+objectColumns+" FROM caldav_objects o WHERE o.id = ?", id)
+
+	ev, cal, err := scanEvent(row)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get event: %w", err)
+	}
+	return ev, cal, nil
+}
+
+// SaveObject stores an object after it was written to (or re-read from)
+// the server
+func (s *Store) SaveObject(calendarID, href, etag string, cal *ical.Calendar) (*Event, error) {
+	row, err := newObjectRow(cal)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.Exec(upsertObjectSQL, uuid.New().String(), calendarID, href, etag,
+		row.uid, row.component, row.summary, row.rangeStart, row.rangeEnd,
+		row.recurring, row.data, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to save event: %w", err)
+	}
+
+	ev, _, err := scanEvent(s.db.QueryRow(
+		"SELECT "+objectColumns+" FROM caldav_objects o WHERE o.calendar_id = ? AND o.href = ?",
+		calendarID, href))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+	return ev, nil
+}
+
+// DeleteObject removes a single object
+func (s *Store) DeleteObject(calendarID, href string) error {
+	_, err := s.db.Exec("DELETE FROM caldav_objects WHERE calendar_id = ? AND href = ?", calendarID, href)
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	return nil
+}
+
+// ListOccurrences returns the event and to-do occurrences of all enabled
+// calendars that overlap [from, to), with recurring events expanded.
+// calendarIDs optionally restricts the calendars.
+func (s *Store) ListOccurrences(from, to time.Time, calendarIDs ...string) ([]Occurrence, error) {
+	query := `
+		SELECT ` + objectColumns + `
+		FROM caldav_objects o
+		JOIN calendar_source_calendars c ON o.calendar_id = c.id
+		JOIN calendar_sources s ON c.source_id = s.id
+		WHERE s.enabled = 1 AND c.enabled = 1
+		  AND o.range_start < ?
+		  AND (o.range_end IS NULL OR o.range_end >= ?)
+	`
+	args := []interface{}{to.UTC(), from.UTC()}
+	if len(calendarIDs) > 0 {
+		query += " AND o.calendar_id IN (?" + strings.Repeat(", ?", len(calendarIDs)-1) + ")"
+		for _, id := range calendarIDs {
+			args = append(args, id)
+		}
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	defer rows.Close()
+
+	occurrences := []Occurrence{}
+	for rows.Next() {
+		ev, cal, err := scanEvent(rows)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Skipping unreadable calendar object")
+			continue
+		}
+		occurrences = append(occurrences, expandOccurrences(ev, cal, from, to)...)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sortOccurrences(occurrences)
+	return occurrences, nil
+}
+
+// ListTodos returns the to-dos of all enabled calendars, including those
+// without dates
+func (s *Store) ListTodos() ([]*Event, error) {
+	query := `
+		SELECT ` + objectColumns + `
+		FROM caldav_objects o
+		JOIN calendar_source_calendars c ON o.calendar_id = c.id
+		JOIN calendar_sources s ON c.source_id = s.id
+		WHERE s.enabled = 1 AND c.enabled = 1 AND o.component = ?
+		ORDER BY o.range_start IS NULL, o.range_start ASC, LOWER(o.summary) ASC
+	`
+
+	rows, err := s.db.Query(query, ComponentTodo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list to-dos: %w", err)
+	}
+	defer rows.Close()
+
+	var todos []*Event
+	for rows.Next() {
+		ev, _, err := scanEvent(rows)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Skipping unreadable calendar object")
+			continue
+		}
+		todos = append(todos, ev)
+	}
+
+	return todos, rows.Err()
+}
+
+// CountEvents returns the total number of synced calendar objects
+func (s *Store) CountEvents() (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM caldav_objects").Scan(&count)
+	return count, err
+}
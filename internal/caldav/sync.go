@@ -0,0 +1,507 @@
+package caldav
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/hkdb/aerion/internal/credentials"
+	"github.com/hkdb/aerion/internal/database"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
+	"github.com/rs/zerolog"
+)
+
+// AccessTokenGetter is a function that retrieves a valid OAuth access token for an account
+type AccessTokenGetter func(accountID string) (string, error)
+
+// ProxyGetter is a function that returns the proxy of an email account, nil
+// for the global proxy
+type ProxyGetter func(accountID string) *netproxy.Config
+
+// Syncer handles syncing calendars from CalDAV servers and Google accounts
+type Syncer struct {
+	store           *Store
+	credStore       *credentials.Store
+	getAccountToken AccessTokenGetter // Gets OAuth token from linked email account
+	getAccountProxy ProxyGetter       // Gets the proxy of a linked email account
+	onConsent       func(accountID string)
+	log             zerolog.Logger
+}
+
+// NewSyncer creates a new calendar syncer
+func NewSyncer(store *Store, credStore *credentials.Store) *Syncer {
+	return &Syncer{
+		store:     store,
+		credStore: credStore,
+		log:       logging.WithComponent("caldav-sync"),
+	}
+}
+
+// SetAccessTokenGetter sets the function for retrieving the OAuth access
+// token of a linked email account
+func (s *Syncer) SetAccessTokenGetter(accountTokenGetter AccessTokenGetter) {
+	s.getAccountToken = accountTokenGetter
+}
+
+// SetProxyGetter sets the function for retrieving the proxy of a linked
+// email account
+func (s *Syncer) SetProxyGetter(proxyGetter ProxyGetter) {
+	s.getAccountProxy = proxyGetter
+}
+
+// SetConsentRequiredHandler sets the function called when a linked
+// account's token doesn't grant calendar access, so the user can be asked
+// to grant it
+func (s *Syncer) SetConsentRequiredHandler(handler func(accountID string)) {
+	s.onConsent = handler
+}
+
+// SyncSource syncs all enabled calendars of a source
+func (s *Syncer) SyncSource(sourceID string) error {
+	s.log.Info().Str("sourceID", sourceID).Msg("Starting source sync")
+
+	source, err := s.store.GetSource(sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to get source: %w", err)
+	}
+	if source == nil {
+		return fmt.Errorf("source not found: %s", sourceID)
+	}
+
+	if !source.Enabled {
+		s.log.Debug().Str("sourceID", sourceID).Msg("Source is disabled, skipping sync")
+		return nil
+	}
+
+	client, err := s.sourceClient(source)
+	if err != nil {
+		s.store.UpdateSourceSyncStatus(source.ID, err.Error())
+		return err
+	}
+
+	// OAuth sources have their calendars discovered on first sync
+	if source.Type == SourceTypeGoogle {
+		if err := s.ensureGoogleCalendars(client, source); err != nil {
+			if errors.Is(err, ErrInsufficientScope) {
+				return s.consentRequired(source)
+			}
+			syncErr := fmt.Sprintf("failed to discover calendars: %v", err)
+			s.store.UpdateSourceSyncStatus(source.ID, syncErr)
+			return fmt.Errorf("failed to discover calendars: %w", err)
+		}
+	}
+
+	calendars, err := s.store.ListEnabledCalendars(source.ID)
+	if err != nil {
+		syncErr := fmt.Sprintf("failed to list calendars: %v", err)
+		s.store.UpdateSourceSyncStatus(source.ID, syncErr)
+		return fmt.Errorf("failed to list calendars: %w", err)
+	}
+
+	if len(calendars) == 0 {
+		s.log.Warn().Str("sourceID", source.ID).Msg("No enabled calendars for source")
+		s.store.UpdateSourceSyncStatus(source.ID, "")
+		return nil
+	}
+
+	var syncErrors []string
+	for _, cal := range calendars {
+		if err := s.syncCalendar(client, cal); err != nil {
+			if errors.Is(err, ErrInsufficientScope) {
+				return s.consentRequired(source)
+			}
+			s.log.Error().Err(err).Str("calendar", cal.Name).Msg("Failed to sync calendar")
+			syncErrors = append(syncErrors, fmt.Sprintf("%s: %v", cal.Name, err))
+		}
+	}
+
+	if len(syncErrors) > 0 {
+		syncErr := fmt.Sprintf("sync errors: %v", syncErrors)
+		s.store.UpdateSourceSyncStatus(source.ID, syncErr)
+		return fmt.Errorf("sync completed with errors: %v", syncErrors)
+	}
+
+	s.store.UpdateSourceSyncStatus(source.ID, "")
+	s.log.Info().Str("sourceID", source.ID).Msg("Calendar source sync completed successfully")
+	return nil
+}
+
+// consentRequired records that a linked account has to grant calendar
+// access. The user is asked once, not on every scheduled sync.
+func (s *Syncer) consentRequired(source *Source) error {
+	s.log.Warn().Str("sourceID", source.ID).Msg("Account has not granted calendar access")
+
+	if source.LastError != ErrInsufficientScope.Error() && s.onConsent != nil && source.AccountID != nil {
+		s.onConsent(*source.AccountID)
+	}
+	s.store.UpdateSourceSyncStatus(source.ID, ErrInsufficientScope.Error())
+	return ErrInsufficientScope
+}
+
+// sourceClient creates an authenticated client for a source
+func (s *Syncer) sourceClient(source *Source) (*Client, error) {
+	switch source.Type {
+	case SourceTypeCalDAV:
+		password, err := s.credStore.GetCalDAVPassword(source.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get credentials: %w", err)
+		}
+		client, err := NewClient(source.URL, source.Username, password, s.sourceProxy(source))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		return client, nil
+
+	case SourceTypeGoogle:
+		accessToken, err := s.getOAuthToken(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get OAuth token: %w", err)
+		}
+		client, err := NewOAuthClient(source.URL, accessToken, s.sourceProxy(source))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		return client, nil
+
+	default:
+		return nil, fmt.Errorf("unknown source type: %s", source.Type)
+	}
+}
+
+// sourceProxy returns the proxy for a source: the proxy of its linked
+// account, or nil for the global proxy
+func (s *Syncer) sourceProxy(source *Source) *netproxy.Config {
+	if source.AccountID == nil || *source.AccountID == "" || s.getAccountProxy == nil {
+		return nil
+	}
+	return s.getAccountProxy(*source.AccountID)
+}
+
+// getOAuthToken retrieves the OAuth access token of the account a source is linked to
+func (s *Syncer) getOAuthToken(source *Source) (string, error) {
+	if source.AccountID == nil || *source.AccountID == "" {
+		return "", fmt.Errorf("calendar source is not linked to an account")
+	}
+	if s.getAccountToken == nil {
+		return "", fmt.Errorf("account token getter not configured")
+	}
+	return s.getAccountToken(*source.AccountID)
+}
+
+// ensureGoogleCalendars discovers the calendars of a Google source the first
+// time it is synced. Google's principal may not list the calendar home; the
+// primary calendar is used then.
+func (s *Syncer) ensureGoogleCalendars(client *Client, source *Source) error {
+	existing, err := s.store.ListCalendars(source.ID)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+
+	calendars, err := client.DiscoverCalendars("")
+	if errors.Is(err, ErrInsufficientScope) {
+		return err
+	}
+	if err != nil {
+		s.log.Debug().Err(err).Str("sourceID", source.ID).Msg("Google calendar discovery failed, using primary calendar")
+		calendars = []CalendarInfo{{Path: googlePrimaryCalendarPath(source.URL), Name: source.Name}}
+	}
+
+	for _, info := range calendars {
+		if _, err := s.store.CreateCalendar(source.ID, info.Path, info.Name, info.Components, true); err != nil {
+			s.log.Warn().Err(err).Str("path", info.Path).Msg("Failed to create calendar")
+		}
+	}
+	return nil
+}
+
+// syncCalendar syncs a single calendar
+// Uses incremental sync (sync-collection) if a sync token exists, otherwise does a full sync
+func (s *Syncer) syncCalendar(client *Client, cal *Calendar) error {
+	s.log.Debug().Str("calendar", cal.Name).Str("path", cal.Path).Str("syncToken", cal.SyncToken).Msg("Syncing calendar")
+
+	// Try incremental sync if we have a sync token
+	if cal.SyncToken != "" {
+		if err := s.syncCalendarIncremental(client, cal); err != nil {
+			// Log the error and fall back to full sync
+			s.log.Warn().Err(err).Str("calendar", cal.Name).Msg("Incremental sync failed, falling back to full sync")
+		} else {
+			return nil
+		}
+	}
+
+	// Full sync (first sync or fallback)
+	return s.syncCalendarFull(client, cal)
+}
+
+// syncCalendarIncremental performs an incremental sync using sync-collection
+func (s *Syncer) syncCalendarIncremental(client *Client, cal *Calendar) error {
+	s.log.Debug().Str("calendar", cal.Name).Msg("Attempting incremental sync")
+
+	result, err := client.SyncCalendar(cal.Path, cal.SyncToken)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storeObjects(cal, result.Updated, result.Deleted, false); err != nil {
+		return err
+	}
+
+	s.store.UpdateCalendarSyncToken(cal.ID, result.SyncToken)
+
+	s.log.Info().
+		Str("calendar", cal.Name).
+		Int("updated", len(result.Updated)).
+		Int("deleted", len(result.Deleted)).
+		Msg("Incremental sync completed")
+
+	return nil
+}
+
+// syncCalendarFull performs a full sync (used for first sync or when incremental fails)
+func (s *Syncer) syncCalendarFull(client *Client, cal *Calendar) error {
+	s.log.Debug().Str("calendar", cal.Name).Msg("Performing full sync")
+
+	// First, try sync-collection with empty token to get all objects + sync token
+	result, err := client.SyncCalendar(cal.Path, "")
+	if err != nil {
+		// If sync-collection is not supported, fall back to calendar-query
+		s.log.Debug().Err(err).Msg("Sync-collection not supported, using calendar-query")
+		return s.syncCalendarLegacy(client, cal)
+	}
+
+	if err := s.storeObjects(cal, result.Updated, nil, true); err != nil {
+		return err
+	}
+
+	// Store the sync token for future incremental syncs
+	s.store.UpdateCalendarSyncToken(cal.ID, result.SyncToken)
+
+	s.log.Info().Str("calendar", cal.Name).Int("objects", len(result.Updated)).Msg("Full sync completed")
+	return nil
+}
+
+// syncCalendarLegacy performs a full sync using calendar-query
+// Used when the server doesn't support sync-collection
+func (s *Syncer) syncCalendarLegacy(client *Client, cal *Calendar) error {
+	s.log.Debug().Str("calendar", cal.Name).Msg("Performing legacy sync (calendar-query)")
+
+	objects, err := client.FetchObjects(cal.Path)
+	if err != nil {
+		return fmt.Errorf("failed to fetch events: %w", err)
+	}
+
+	if err := s.storeObjects(cal, objects, nil, true); err != nil {
+		return err
+	}
+
+	// No sync token available with legacy method
+	s.store.UpdateCalendarSyncToken(cal.ID, "")
+
+	s.log.Info().Str("calendar", cal.Name).Int("objects", len(objects)).Msg("Legacy sync completed")
+	return nil
+}
+
+// storeObjects stores the objects of a synced calendar. With replace set
+// (full sync), objects missing from the listing are removed.
+func (s *Syncer) storeObjects(cal *Calendar, objects []ParsedObject, deleted []string, replace bool) error {
+	if replace {
+		err := database.RetryOnBusy(func() error {
+			return s.store.ReplaceObjects(cal.ID, objects)
+		}, 5, 100*time.Millisecond, s.log)
+		if err != nil {
+			return fmt.Errorf("failed to replace calendar objects: %w", err)
+		}
+		return nil
+	}
+
+	err := database.RetryOnBusy(func() error {
+		return s.store.DeleteObjectsByHrefs(cal.ID, deleted)
+	}, 5, 100*time.Millisecond, s.log)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to delete calendar objects after retries")
+	}
+
+	err = database.RetryOnBusy(func() error {
+		return s.store.UpsertObjectsBatch(cal.ID, objects)
+	}, 5, 100*time.Millisecond, s.log)
+	if err != nil {
+		return fmt.Errorf("failed to upsert calendar objects: %w", err)
+	}
+	return nil
+}
+
+// SyncAllSources syncs all enabled sources
+func (s *Syncer) SyncAllSources() error {
+	sources, err := s.store.ListSources()
+	if err != nil {
+		return fmt.Errorf("failed to list sources: %w", err)
+	}
+
+	var syncErrors []string
+	for _, source := range sources {
+		if !source.Enabled {
+			continue
+		}
+
+		if err := s.SyncSource(source.ID); err != nil {
+			s.log.Error().Err(err).Str("source", source.Name).Msg("Failed to sync source")
+			syncErrors = append(syncErrors, fmt.Sprintf("%s: %v", source.Name, err))
+		}
+	}
+
+	if len(syncErrors) > 0 {
+		return fmt.Errorf("sync completed with errors: %v", syncErrors)
+	}
+
+	return nil
+}
+
+// ============================================================================
+// Write-back
+// ============================================================================
+
+// writableCalendar returns a calendar and a client for its source
+func (s *Syncer) writableCalendar(calendarID string) (*Calendar, *Client, error) {
+	cal, err := s.store.GetCalendar(calendarID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cal == nil {
+		return nil, nil, fmt.Errorf("calendar not found: %s", calendarID)
+	}
+
+	source, err := s.store.GetSource(cal.SourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if source == nil {
+		return nil, nil, fmt.Errorf("calendar source not found: %s", cal.SourceID)
+	}
+
+	client, err := s.sourceClient(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cal, client, nil
+}
+
+// containsComponent reports whether a supported component list has name
+func containsComponent(components []string, name string) bool {
+	for _, c := range components {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveEvent creates or updates an event on its CalDAV server. Updates are
+// conditional on input.ETag (or the synced ETag); if the server copy changed
+// in the meantime, the server copy is stored locally and returned as a
+// conflict.
+func (s *Syncer) SaveEvent(input *EventInput) (*SaveEventResult, error) {
+	cal, client, err := s.writableCalendar(input.CalendarID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cal.Components) > 0 && !containsComponent(cal.Components, ComponentEvent) {
+		return nil, fmt.Errorf("%s does not accept events", cal.Name)
+	}
+
+	// Apply the edit on top of the stored event to keep unmodelled properties
+	var base *ical.Calendar
+	href, etag := "", input.ETag
+	if input.ID != "" {
+		existing, data, err := s.store.GetEvent(input.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, fmt.Errorf("event not found: %s", input.ID)
+		}
+		if existing.CalendarID != input.CalendarID {
+			return nil, fmt.Errorf("moving events between calendars is not supported")
+		}
+		if existing.Component != ComponentEvent {
+			return nil, fmt.Errorf("only events can be edited")
+		}
+		base, href = data, existing.Href
+		if etag == "" {
+			etag = existing.ETag
+		}
+	}
+
+	data, err := applyEventInput(base, input)
+	if err != nil {
+		return nil, err
+	}
+	if href == "" {
+		uid := propText(masterComponent(data, ical.CompEvent).Props, ical.PropUID)
+		href = NewEventHref(cal.Path, uid)
+	}
+
+	saved, err := client.PutObject(cal.Path, href, etag, data)
+	if errors.Is(err, ErrConflict) {
+		return s.eventConflict(client, cal, href)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.store.SaveObject(cal.ID, saved.Href, saved.ETag, saved.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &SaveEventResult{Event: stored}, nil
+}
+
+// eventConflict stores the server copy of a conflicting event and reports it
+func (s *Syncer) eventConflict(client *Client, cal *Calendar, href string) (*SaveEventResult, error) {
+	s.log.Info().Str("href", href).Msg("Event changed on server, reporting conflict")
+
+	server, err := client.GetObject(cal.Path, href)
+	if errors.Is(err, ErrEventNotFound) {
+		// A new event's href was taken, or the event was deleted remotely
+		return nil, fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.store.SaveObject(cal.ID, server.Href, server.ETag, server.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &SaveEventResult{Conflict: stored}, nil
+}
+
+// DeleteEvent deletes an event from its CalDAV server, provided it hasn't
+// changed since etag was synced, and then from the local store
+func (s *Syncer) DeleteEvent(id, etag string) error {
+	ev, _, err := s.store.GetEvent(id)
+	if err != nil {
+		return err
+	}
+	if ev == nil {
+		return fmt.Errorf("event not found: %s", id)
+	}
+	if etag == "" {
+		etag = ev.ETag
+	}
+
+	cal, client, err := s.writableCalendar(ev.CalendarID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.DeleteObject(cal.Path, ev.Href, etag); err != nil {
+		return err
+	}
+
+	return s.store.DeleteObject(cal.ID, ev.Href)
+}
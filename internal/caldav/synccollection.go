@@ -0,0 +1,153 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/emersion/go-webdav"
+)
+
+// maxSyncRounds limits how often a truncated sync-collection response is
+// continued before giving up
+const maxSyncRounds = 50
+
+// syncCollectionRequest is an RFC 6578 sync-collection REPORT body. Only
+// ETags are requested; changed objects are fetched with calendar-multiget.
+type syncCollectionRequest struct {
+	XMLName   xml.Name `xml:"DAV: sync-collection"`
+	SyncToken string   `xml:"DAV: sync-token"`
+	SyncLevel string   `xml:"DAV: sync-level"`
+	Prop      struct {
+		GetETag struct{} `xml:"DAV: getetag"`
+	} `xml:"DAV: prop"`
+}
+
+// syncMultiStatus is the multistatus response to a sync-collection REPORT
+type syncMultiStatus struct {
+	XMLName   xml.Name `xml:"DAV: multistatus"`
+	Responses []struct {
+		Href   string `xml:"DAV: href"`
+		Status string `xml:"DAV: status"` // Only set for removed members and the truncated collection
+	} `xml:"DAV: response"`
+	SyncToken string `xml:"DAV: sync-token"`
+}
+
+// collectionChanges lists the members of a collection changed since a sync token
+type collectionChanges struct {
+	SyncToken string
+	Updated   []string // Paths of added/modified members
+	Deleted   []string // Paths of removed members
+}
+
+// syncCollection runs a sync-collection REPORT against a collection URL.
+// go-webdav only implements sync-collection for CardDAV. An empty syncToken
+// lists all members. Truncated responses (507 on the collection) are
+// continued with the returned token.
+func syncCollection(ctx context.Context, httpClient webdav.HTTPClient, collectionURL, syncToken string) (*collectionChanges, error) {
+	collection, err := url.Parse(collectionURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	changes := &collectionChanges{}
+	updated := make(map[string]bool)
+	deleted := make(map[string]bool)
+
+	for round := 0; ; round++ {
+		ms, err := doSyncCollection(ctx, httpClient, collectionURL, syncToken)
+		if err != nil {
+			return nil, err
+		}
+
+		truncated := false
+		for _, resp := range ms.Responses {
+			href, err := url.Parse(strings.TrimSpace(resp.Href))
+			if err != nil {
+				continue
+			}
+			path := href.Path
+
+			if strings.TrimSuffix(path, "/") == strings.TrimSuffix(collection.Path, "/") {
+				truncated = statusCode(resp.Status) == http.StatusInsufficientStorage
+				continue
+			}
+
+			if statusCode(resp.Status) == http.StatusNotFound {
+				delete(updated, path)
+				deleted[path] = true
+				continue
+			}
+			delete(deleted, path)
+			updated[path] = true
+		}
+
+		syncToken = ms.SyncToken
+		if !truncated || syncToken == "" {
+			break
+		}
+		if round >= maxSyncRounds {
+			return nil, fmt.Errorf("sync-collection did not complete after %d rounds", maxSyncRounds)
+		}
+	}
+
+	changes.SyncToken = syncToken
+	for path := range updated {
+		changes.Updated = append(changes.Updated, path)
+	}
+	for path := range deleted {
+		changes.Deleted = append(changes.Deleted, path)
+	}
+	return changes, nil
+}
+
+// doSyncCollection sends a single sync-collection REPORT
+func doSyncCollection(ctx context.Context, httpClient webdav.HTTPClient, collectionURL, syncToken string) (*syncMultiStatus, error) {
+	body := syncCollectionRequest{SyncToken: syncToken, SyncLevel: "1"}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(&body); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "REPORT", collectionURL, &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=\"utf-8\"")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		// 403/409 with DAV:valid-sync-token means the token expired
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var ms syncMultiStatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("failed to parse multistatus: %w", err)
+	}
+	return &ms, nil
+}
+
+// statusCode extracts the code from a DAV:status line ("HTTP/1.1 404 Not Found")
+func statusCode(status string) int {
+	fields := strings.Fields(status)
+	if len(fields) < 2 {
+		return 0
+	}
+	var code int
+	fmt.Sscanf(fields[1], "%d", &code)
+	return code
+}
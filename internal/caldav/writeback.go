@@ -0,0 +1,179 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/caldav"
+)
+
+var (
+	// ErrConflict is returned when the server copy of an event changed since
+	// it was last synced (HTTP 412 Precondition Failed)
+	ErrConflict = errors.New("event was changed on the server")
+
+	// ErrEventNotFound is returned when an event no longer exists on the server
+	ErrEventNotFound = errors.New("event no longer exists on the server")
+)
+
+// unsafeHrefChars matches characters that are not safe in a new event's resource name
+var unsafeHrefChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// conditionalClient adds If-Match / If-None-Match preconditions to write
+// requests and maps precondition failures to ErrConflict. go-webdav doesn't
+// support conditional requests itself.
+type conditionalClient struct {
+	client      webdav.HTTPClient
+	ifMatch     string
+	ifNoneMatch string
+}
+
+// Do implements webdav.HTTPClient
+func (c *conditionalClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPut || req.Method == http.MethodDelete {
+		if c.ifMatch != "" {
+			req.Header.Set("If-Match", c.ifMatch)
+		}
+		if c.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", c.ifNoneMatch)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPreconditionFailed:
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, ErrConflict
+	case http.StatusNotFound, http.StatusGone:
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, ErrEventNotFound
+	}
+	return resp, nil
+}
+
+// quoteETag formats a stored (unquoted) ETag for a precondition header
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return strconv.Quote(etag)
+}
+
+// newConditionalClient creates a CalDAV client for a calendar whose write
+// requests carry the given preconditions
+func (c *Client) newConditionalClient(calendarPath, ifMatch, ifNoneMatch string) (*caldav.Client, error) {
+	httpClient := &conditionalClient{
+		client:      c.httpClient,
+		ifMatch:     ifMatch,
+		ifNoneMatch: ifNoneMatch,
+	}
+
+	client, err := caldav.NewClient(httpClient, resolveURL(c.baseURL, calendarPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for calendar: %w", err)
+	}
+	return client, nil
+}
+
+// NewEventHref returns the resource path for a new event in a calendar
+func NewEventHref(calendarPath, uid string) string {
+	name := unsafeHrefChars.ReplaceAllString(uid, "-")
+	return strings.TrimSuffix(calendarPath, "/") + "/" + name + ".ics"
+}
+
+// PutObject writes a calendar object to the server. An empty etag creates a
+// new object and fails with ErrConflict if the href already exists;
+// otherwise the write only succeeds if the server copy still has the given
+// etag.
+func (c *Client) PutObject(calendarPath, href, etag string, cal *ical.Calendar) (*ParsedObject, error) {
+	ctx := context.Background()
+
+	ifMatch, ifNoneMatch := "", "*"
+	if etag != "" {
+		ifMatch, ifNoneMatch = quoteETag(etag), ""
+	}
+
+	client, err := c.newConditionalClient(calendarPath, ifMatch, ifNoneMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := client.PutCalendarObject(ctx, href, cal)
+	if err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, ErrConflict
+		}
+		return nil, fmt.Errorf("failed to save event: %w", err)
+	}
+
+	// Servers that rewrite the event (or omit the ETag) need a re-read so the
+	// stored copy matches what the server has
+	if obj.ETag == "" {
+		return c.GetObject(calendarPath, obj.Path)
+	}
+
+	c.log.Info().Str("href", obj.Path).Msg("Event saved to server")
+	return &ParsedObject{Href: obj.Path, ETag: obj.ETag, Data: cal}, nil
+}
+
+// GetObject fetches the current server copy of a calendar object
+func (c *Client) GetObject(calendarPath, href string) (*ParsedObject, error) {
+	client, err := c.newConditionalClient(calendarPath, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := client.GetCalendarObject(context.Background(), href)
+	if err != nil {
+		if errors.Is(err, ErrEventNotFound) {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch event: %w", err)
+	}
+	if obj.Path == "" {
+		obj.Path = href
+	}
+
+	return &ParsedObject{Href: obj.Path, ETag: obj.ETag, Data: obj.Data}, nil
+}
+
+// DeleteObject deletes a calendar object if the server copy still has the
+// given etag. Objects that are already gone are treated as deleted.
+func (c *Client) DeleteObject(calendarPath, href, etag string) error {
+	ifMatch := ""
+	if etag != "" {
+		ifMatch = quoteETag(etag)
+	}
+
+	client, err := c.newConditionalClient(calendarPath, ifMatch, "")
+	if err != nil {
+		return err
+	}
+
+	if err := client.RemoveAll(context.Background(), href); err != nil {
+		switch {
+		case errors.Is(err, ErrEventNotFound):
+			return nil
+		case errors.Is(err, ErrConflict):
+			return ErrConflict
+		}
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+
+	c.log.Info().Str("href", href).Msg("Event deleted from server")
+	return nil
+}
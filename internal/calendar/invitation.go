@@ -0,0 +1,339 @@
+// Package calendar provides iCalendar parsing for meeting invitations (iTIP/iMIP)
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/teambition/rrule-go"
+)
+
+// iTIP methods (RFC 5546)
+const (
+	MethodRequest = "REQUEST"
+	MethodCancel  = "CANCEL"
+	MethodReply   = "REPLY"
+	MethodCounter = "COUNTER"
+)
+
+// Participation statuses (PARTSTAT)
+const (
+	PartStatNeedsAction = "NEEDS-ACTION"
+	PartStatAccepted    = "ACCEPTED"
+	PartStatTentative   = "TENTATIVE"
+	PartStatDeclined    = "DECLINED"
+)
+
+// Participant is the organizer or an attendee of an event
+type Participant struct {
+	Email    string `json:"email"`
+	Name     string `json:"name,omitempty"`
+	Role     string `json:"role,omitempty"`     // CHAIR, REQ-PARTICIPANT, OPT-PARTICIPANT, NON-PARTICIPANT
+	PartStat string `json:"partStat,omitempty"` // NEEDS-ACTION, ACCEPTED, TENTATIVE, DECLINED, DELEGATED
+	RSVP     bool   `json:"rsvp,omitempty"`     // Organizer expects a reply
+}
+
+// Invitation is a summary of the event in an iTIP message
+type Invitation struct {
+	Method         string        `json:"method"` // REQUEST, CANCEL, REPLY, COUNTER
+	UID            string        `json:"uid"`
+	Sequence       int           `json:"sequence"`
+	Summary        string        `json:"summary"`
+	Location       string        `json:"location,omitempty"`
+	Description    string        `json:"description,omitempty"`
+	Comment        string        `json:"comment,omitempty"` // Reply or counter-proposal comment
+	Status         string        `json:"status,omitempty"`  // CONFIRMED, TENTATIVE, CANCELLED
+	Start          time.Time     `json:"start"`
+	End            time.Time     `json:"end"`
+	TimeZone       string        `json:"timeZone,omitempty"` // TZID of the start time as sent
+	AllDay         bool          `json:"allDay"`
+	Organizer      *Participant  `json:"organizer,omitempty"`
+	Attendees      []Participant `json:"attendees"`
+	Recurrence     string        `json:"recurrence,omitempty"`     // RRULE value
+	RecurrenceText string        `json:"recurrenceText,omitempty"` // e.g. "Every 2 weeks on Monday, 10 times"
+	RecurrenceID   *time.Time    `json:"recurrenceId,omitempty"`   // Set when a single occurrence is affected
+
+	// Filled in for the viewing account
+	Response   string `json:"response,omitempty"` // The user's own PARTSTAT
+	CanRespond bool   `json:"canRespond"`         // The user is an attendee of a REQUEST
+}
+
+// Attendee returns the attendee matching one of the given email addresses
+func (inv *Invitation) Attendee(emails ...string) *Participant {
+	for i := range inv.Attendees {
+		for _, email := range emails {
+			if strings.EqualFold(inv.Attendees[i].Email, strings.TrimSpace(email)) {
+				return &inv.Attendees[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ParseInvitation parses the iCalendar data of an iMIP message. When the
+// calendar holds a recurring event and overridden occurrences, the summary
+// describes the recurring event.
+func ParseInvitation(data string) (*Invitation, error) {
+	cal, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	event := mainEvent(cal)
+	if event == nil {
+		return nil, fmt.Errorf("calendar data contains no event")
+	}
+
+	inv := &Invitation{
+		Method:    strings.ToUpper(propText(cal.Props, ical.PropMethod)),
+		UID:       propText(event.Props, ical.PropUID),
+		Summary:   propText(event.Props, ical.PropSummary),
+		Location:  propText(event.Props, ical.PropLocation),
+		Comment:   propText(event.Props, ical.PropComment),
+		Status:    strings.ToUpper(propText(event.Props, ical.PropStatus)),
+		Attendees: []Participant{},
+	}
+	inv.Description = strings.TrimSpace(propText(event.Props, ical.PropDescription))
+	if prop := event.Props.Get(ical.PropSequence); prop != nil {
+		inv.Sequence, _ = prop.Int()
+	}
+
+	if prop := event.Props.Get(ical.PropDateTimeStart); prop != nil {
+		inv.Start, inv.AllDay, err = ParseDateTime(cal, prop)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event start: %w", err)
+		}
+		inv.TimeZone = prop.Params.Get(ical.ParamTimezoneID)
+	}
+	inv.End, err = EventEnd(cal, event, inv.Start, inv.AllDay)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event end: %w", err)
+	}
+	if prop := event.Props.Get(ical.PropRecurrenceID); prop != nil {
+		if t, _, err := ParseDateTime(cal, prop); err == nil {
+			inv.RecurrenceID = &t
+		}
+	}
+
+	if prop := event.Props.Get(ical.PropOrganizer); prop != nil {
+		organizer := participant(prop)
+		inv.Organizer = &organizer
+	}
+	for _, prop := range event.Props.Values(ical.PropAttendee) {
+		inv.Attendees = append(inv.Attendees, participant(&prop))
+	}
+
+	if prop := event.Props.Get(ical.PropRecurrenceRule); prop != nil {
+		inv.Recurrence = prop.Value
+		if opt, err := rrule.StrToROption(prop.Value); err == nil {
+			inv.RecurrenceText = describeRecurrence(opt)
+		}
+	}
+
+	return inv, nil
+}
+
+// decode parses iCalendar data
+func decode(data string) (*ical.Calendar, error) {
+	cal, err := ical.NewDecoder(strings.NewReader(data)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse calendar data: %w", err)
+	}
+	return cal, nil
+}
+
+// encode serializes a calendar
+func encode(cal *ical.Calendar) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return nil, fmt.Errorf("failed to encode calendar data: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// mainEvent returns the recurring (master) event, or the first event if all
+// events are single occurrences
+func mainEvent(cal *ical.Calendar) *ical.Component {
+	var first *ical.Component
+	for _, child := range cal.Children {
+		if child.Name != ical.CompEvent {
+			continue
+		}
+		if child.Props.Get(ical.PropRecurrenceID) == nil {
+			return child
+		}
+		if first == nil {
+			first = child
+		}
+	}
+	return first
+}
+
+// propText returns the text value of a property, or "" if it is missing
+func propText(props ical.Props, name string) string {
+	prop := props.Get(name)
+	if prop == nil {
+		return ""
+	}
+	if text, err := prop.Text(); err == nil {
+		return text
+	}
+	return prop.Value
+}
+
+// participant converts an ORGANIZER or ATTENDEE property
+func participant(prop *ical.Prop) Participant {
+	p := Participant{
+		Email:    calAddress(prop),
+		Name:     prop.Params.Get(ical.ParamCommonName),
+		Role:     strings.ToUpper(prop.Params.Get(ical.ParamRole)),
+		PartStat: strings.ToUpper(prop.Params.Get(ical.ParamParticipationStatus)),
+		RSVP:     strings.EqualFold(prop.Params.Get(ical.ParamRSVP), "TRUE"),
+	}
+	if prop.Name == ical.PropAttendee && p.PartStat == "" {
+		p.PartStat = PartStatNeedsAction
+	}
+	return p
+}
+
+// calAddress returns the email address of a calendar user ("mailto:" URI or
+// EMAIL parameter)
+func calAddress(prop *ical.Prop) string {
+	value := strings.TrimSpace(prop.Value)
+	if strings.HasPrefix(strings.ToLower(value), "mailto:") {
+		return value[len("mailto:"):]
+	}
+	if email := prop.Params.Get(ical.ParamEmail); email != "" {
+		return email
+	}
+	return value
+}
+
+// EventEnd returns the end of an event from DTEND or DURATION, defaulting
+// to the start (or the next day for all-day events)
+func EventEnd(cal *ical.Calendar, event *ical.Component, start time.Time, allDay bool) (time.Time, error) {
+	if prop := event.Props.Get(ical.PropDateTimeEnd); prop != nil {
+		end, _, err := ParseDateTime(cal, prop)
+		return end, err
+	}
+	if prop := event.Props.Get(ical.PropDuration); prop != nil {
+		dur, err := prop.Duration()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return start.Add(dur), nil
+	}
+	if allDay {
+		return start.AddDate(0, 0, 1), nil
+	}
+	return start, nil
+}
+
+// ParseDateTime parses a DATE or DATE-TIME property. Unlike ical.Prop.DateTime
+// it accepts TZIDs that aren't IANA names (e.g. Outlook's Windows zone names)
+// by resolving them through the calendar's VTIMEZONE definitions.
+func ParseDateTime(cal *ical.Calendar, prop *ical.Prop) (t time.Time, allDay bool, err error) {
+	if prop.ValueType() == ical.ValueDate || len(prop.Value) == len("20060102") {
+		t, err = time.ParseInLocation("20060102", prop.Value, time.Local)
+		return t, true, err
+	}
+
+	loc := time.Local // Floating time
+	tzid := prop.Params.Get(ical.ParamTimezoneID)
+	if strings.HasSuffix(prop.Value, "Z") {
+		loc = time.UTC
+	} else if tzid != "" {
+		loc = resolveTimezone(cal, tzid, prop.Value)
+	}
+
+	unzoned := ical.Prop{Name: prop.Name, Params: make(ical.Params), Value: prop.Value}
+	for name, values := range prop.Params {
+		if name != ical.ParamTimezoneID {
+			unzoned.Params[name] = values
+		}
+	}
+	t, err = unzoned.DateTime(loc)
+	return t, false, err
+}
+
+// describeRecurrence returns a short English description of a recurrence rule
+func describeRecurrence(opt *rrule.ROption) string {
+	units := map[rrule.Frequency][2]string{
+		rrule.DAILY:   {"Daily", "day"},
+		rrule.WEEKLY:  {"Weekly", "week"},
+		rrule.MONTHLY: {"Monthly", "month"},
+		rrule.YEARLY:  {"Yearly", "year"},
+	}
+	unit, ok := units[opt.Freq]
+	if !ok {
+		return ""
+	}
+
+	text := unit[0]
+	if opt.Interval > 1 {
+		text = fmt.Sprintf("Every %d %ss", opt.Interval, unit[1])
+	}
+
+	if len(opt.Byweekday) > 0 {
+		days := make([]string, 0, len(opt.Byweekday))
+		sorted := append([]rrule.Weekday(nil), opt.Byweekday...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Day() < sorted[j].Day() })
+		for _, wd := range sorted {
+			day := weekdayName(wd.Day())
+			if n := wd.N(); n != 0 {
+				day = ordinal(n) + " " + day
+			}
+			days = append(days, day)
+		}
+		if opt.Freq == rrule.WEEKLY || opt.Freq == rrule.DAILY {
+			text += " on " + strings.Join(days, ", ")
+		} else {
+			text += " on the " + strings.Join(days, ", ")
+		}
+	} else if len(opt.Bymonthday) > 0 {
+		days := make([]string, 0, len(opt.Bymonthday))
+		for _, d := range opt.Bymonthday {
+			days = append(days, ordinal(d))
+		}
+		text += " on the " + strings.Join(days, ", ")
+	}
+
+	switch {
+	case opt.Count > 0:
+		text += fmt.Sprintf(", %d times", opt.Count)
+	case !opt.Until.IsZero():
+		text += ", until " + opt.Until.Format("Jan 2, 2006")
+	}
+	return text
+}
+
+// weekdayName returns the name of an rrule weekday (0 = Monday)
+func weekdayName(day int) string {
+	return time.Weekday((day + 1) % 7).String()
+}
+
+// ordinal formats n as "1st", "2nd", "last", "2nd last", ...
+func ordinal(n int) string {
+	if n == -1 {
+		return "last"
+	}
+	if n < 0 {
+		return ordinal(-n) + " last"
+	}
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
@@ -0,0 +1,96 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+// ProductID identifies Aerion in the PRODID of generated calendars
+const ProductID = "-//Aerion//Aerion Email Client//EN"
+
+// BuildReply builds an iTIP REPLY (RFC 5546 section 3.2.3) to an invitation,
+// setting the participation status of attendeeEmail. Every event of the
+// invitation (the recurring event and any overridden occurrences) is answered.
+// An attendee missing from the invitation, e.g. one invited through a mailing
+// list, is added to the reply.
+func BuildReply(data, attendeeEmail, attendeeName, partStat string) ([]byte, error) {
+	switch partStat {
+	case PartStatAccepted, PartStatTentative, PartStatDeclined:
+	default:
+		return nil, fmt.Errorf("invalid participation status: %s", partStat)
+	}
+
+	cal, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := ical.NewCalendar()
+	reply.Props.SetText(ical.PropProductID, ProductID)
+	reply.Props.SetText(ical.PropVersion, "2.0")
+	reply.Props.SetText(ical.PropMethod, MethodReply)
+
+	stamp := time.Now().UTC()
+	usedZones := make(map[string]bool)
+	for _, event := range cal.Children {
+		if event.Name != ical.CompEvent {
+			continue
+		}
+
+		out := ical.NewEvent()
+		out.Props.SetDateTime(ical.PropDateTimeStamp, stamp)
+		for _, name := range []string{
+			ical.PropUID, ical.PropSequence, ical.PropRecurrenceID, ical.PropOrganizer,
+			ical.PropDateTimeStart, ical.PropDateTimeEnd, ical.PropDuration, ical.PropSummary,
+		} {
+			if prop := event.Props.Get(name); prop != nil {
+				out.Props.Set(prop)
+				if tzid := prop.Params.Get(ical.ParamTimezoneID); tzid != "" {
+					usedZones[tzid] = true
+				}
+			}
+		}
+		out.Props.Set(replyAttendee(event, attendeeEmail, attendeeName, partStat))
+		reply.Children = append(reply.Children, out.Component)
+	}
+	if len(reply.Children) == 0 {
+		return nil, fmt.Errorf("calendar data contains no event")
+	}
+
+	// Times with a TZID need the matching VTIMEZONE
+	for _, child := range cal.Children {
+		if child.Name == ical.CompTimezone && usedZones[propText(child.Props, ical.PropTimezoneID)] {
+			reply.Children = append([]*ical.Component{child}, reply.Children...)
+		}
+	}
+
+	return encode(reply)
+}
+
+// replyAttendee returns the ATTENDEE property for the replying user, keeping
+// the parameters the organizer sent (CN, ROLE, ...) except RSVP
+func replyAttendee(event *ical.Component, email, name, partStat string) *ical.Prop {
+	attendee := ical.NewProp(ical.PropAttendee)
+	attendee.SetValueType(ical.ValueCalendarAddress)
+	attendee.Value = "mailto:" + email
+
+	for _, prop := range event.Props.Values(ical.PropAttendee) {
+		if strings.EqualFold(calAddress(&prop), email) {
+			for param, values := range prop.Params {
+				attendee.Params[param] = values
+			}
+			attendee.Value = prop.Value
+			break
+		}
+	}
+
+	if attendee.Params.Get(ical.ParamCommonName) == "" && name != "" {
+		attendee.Params.Set(ical.ParamCommonName, name)
+	}
+	attendee.Params.Del(ical.ParamRSVP)
+	attendee.Params.Set(ical.ParamParticipationStatus, partStat)
+	return attendee
+}
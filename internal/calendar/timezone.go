@@ -0,0 +1,143 @@
+package calendar
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+// windowsZones maps the Windows time zone names Outlook and Exchange use as
+// TZIDs to IANA names
+var windowsZones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time":          "America/Denver",
+	"Central Standard Time":           "America/Chicago",
+	"Eastern Standard Time":           "America/New_York",
+	"Atlantic Standard Time":          "America/Halifax",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Buenos_Aires",
+	"UTC":                             "UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"FLE Standard Time":               "Europe/Kiev",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"Russian Standard Time":           "Europe/Moscow",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"India Standard Time":             "Asia/Calcutta",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"Canada Central Standard Time":    "America/Regina",
+	"Central America Standard Time":   "America/Guatemala",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Mountain Standard Time (Mexico)": "America/Chihuahua",
+}
+
+// resolveTimezone returns the location for a TZID: an IANA zone, a known
+// Windows zone, or a fixed offset taken from the calendar's VTIMEZONE that
+// applies at the given local time. Unknown zones fall back to local time.
+func resolveTimezone(cal *ical.Calendar, tzid, value string) *time.Location {
+	name := strings.Trim(tzid, `"`)
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	if iana, ok := windowsZones[name]; ok {
+		if loc, err := time.LoadLocation(iana); err == nil {
+			return loc
+		}
+	}
+
+	wall, err := time.ParseInLocation("20060102T150405", value, time.UTC)
+	if err != nil {
+		return time.Local
+	}
+	for _, child := range cal.Children {
+		if child.Name != ical.CompTimezone || propText(child.Props, ical.PropTimezoneID) != tzid {
+			continue
+		}
+		if offset, ok := observanceOffset(child, wall); ok {
+			return time.FixedZone(name, offset)
+		}
+	}
+	return time.Local
+}
+
+// observanceOffset finds the STANDARD or DAYLIGHT observance of a VTIMEZONE
+// that most recently started before wall (a local time expressed in UTC) and
+// returns its UTC offset in seconds
+func observanceOffset(tz *ical.Component, wall time.Time) (int, bool) {
+	var latest time.Time
+	offset, found := 0, false
+
+	for _, obs := range tz.Children {
+		if obs.Name != ical.CompTimezoneStandard && obs.Name != ical.CompTimezoneDaylight {
+			continue
+		}
+		to, ok := parseUTCOffset(propText(obs.Props, ical.PropTimezoneOffsetTo))
+		if !ok {
+			continue
+		}
+
+		var onset time.Time
+		if set, err := obs.RecurrenceSet(time.UTC); err == nil && set != nil {
+			onset = set.Before(wall, true)
+		} else if start, err := obs.Props.DateTime(ical.PropDateTimeStart, time.UTC); err == nil && !start.After(wall) {
+			onset = start
+		}
+
+		if !found || (!onset.IsZero() && onset.After(latest)) {
+			latest, offset, found = onset, to, true
+		}
+	}
+	return offset, found
+}
+
+// parseUTCOffset parses a UTC offset such as "+0100" or "-053000"
+func parseUTCOffset(s string) (int, bool) {
+	if len(s) != 5 && len(s) != 7 {
+		return 0, false
+	}
+	sign := 1
+	switch s[0] {
+	case '-':
+		sign = -1
+	case '+':
+	default:
+		return 0, false
+	}
+	hours, err1 := strconv.Atoi(s[1:3])
+	minutes, err2 := strconv.Atoi(s[3:5])
+	seconds := 0
+	var err3 error
+	if len(s) == 7 {
+		seconds, err3 = strconv.Atoi(s[5:7])
+	}
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, false
+	}
+	return sign * (hours*3600 + minutes*60 + seconds), true
+}
@@ -3,7 +3,6 @@ package carddav
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -12,6 +11,7 @@ import (
 	"github.com/emersion/go-webdav"
 	"github.com/emersion/go-webdav/carddav"
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/rs/zerolog"
 )
 
@@ -21,15 +21,15 @@ type Client struct {
 	baseURL  string
 	username string
 	password string
+	proxy    *netproxy.Config
 	log      zerolog.Logger
 }
 
-// NewClient creates a new CardDAV client
-func NewClient(baseURL, username, password string) (*Client, error) {
+// NewClient creates a new CardDAV client. proxy is the proxy of the linked
+// account; nil uses the global proxy.
+func NewClient(baseURL, username, password string, proxy *netproxy.Config) (*Client, error) {
 	// Create HTTP client with basic auth
-	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	httpClient := netproxy.HTTPClientFor(proxy, 30*time.Second)
 
 	// Parse and normalize the URL
 	parsedURL, err := url.Parse(baseURL)
@@ -55,6 +55,7 @@ func NewClient(baseURL, username, password string) (*Client, error) {
 		baseURL:  parsedURL.String(),
 		username: username,
 		password: password,
+		proxy:    proxy,
 		log:      logging.WithComponent("carddav-client"),
 	}, nil
 }
@@ -81,7 +82,7 @@ func DiscoverAddressbooks(baseURL, username, password string) ([]AddressbookInfo
 
 	// Create HTTP client with basic auth
 	httpClient := webdav.HTTPClientWithBasicAuth(
-		&http.Client{Timeout: 30 * time.Second},
+		netproxy.HTTPClient(30*time.Second),
 		username, password,
 	)
 
@@ -216,8 +217,9 @@ func resolveURL(baseURL, path string) string {
 	return base.ResolveReference(ref).String()
 }
 
-// FetchContacts fetches all contacts from an addressbook
-func (c *Client) FetchContacts(addressbookPath string) ([]ParsedContact, error) {
+// FetchContacts fetches all contacts from an addressbook, along with the
+// full vCards they were parsed from
+func (c *Client) FetchContacts(addressbookPath string) ([]ParsedContact, []ParsedCard, error) {
 	ctx := context.Background()
 	c.log.Debug().Str("path", addressbookPath).Msg("Fetching contacts")
 
@@ -226,13 +228,13 @@ func (c *Client) FetchContacts(addressbookPath string) ([]ParsedContact, error)
 
 	// Create a new client for this specific addressbook
 	httpClient := webdav.HTTPClientWithBasicAuth(
-		&http.Client{Timeout: 60 * time.Second},
+		netproxy.HTTPClientFor(c.proxy, 60*time.Second),
 		c.username, c.password,
 	)
 
 	abClient, err := carddav.NewClient(httpClient, fullPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create client for addressbook: %w", err)
+		return nil, nil, fmt.Errorf("failed to create client for addressbook: %w", err)
 	}
 
 	// Query all address objects
@@ -244,19 +246,23 @@ func (c *Client) FetchContacts(addressbookPath string) ([]ParsedContact, error)
 
 	addressObjects, err := abClient.QueryAddressBook(ctx, addressbookPath, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query addressbook: %w", err)
+		return nil, nil, fmt.Errorf("failed to query addressbook: %w", err)
 	}
 
 	c.log.Debug().Int("count", len(addressObjects)).Msg("Fetched address objects")
 
 	var contacts []ParsedContact
+	var cards []ParsedCard
 	for _, obj := range addressObjects {
 		parsed := parseVCard(obj)
 		contacts = append(contacts, parsed...)
+		if card := parseCard(obj); card != nil {
+			cards = append(cards, *card)
+		}
 	}
 
 	c.log.Info().Int("contacts", len(contacts)).Str("path", addressbookPath).Msg("Parsed contacts from addressbook")
-	return contacts, nil
+	return contacts, cards, nil
 }
 
 // ParsedContact represents a contact parsed from vCard data
@@ -267,6 +273,21 @@ type ParsedContact struct {
 	DisplayName string
 }
 
+// ParsedCard is a full vCard fetched from an addressbook
+type ParsedCard struct {
+	Href string
+	ETag string
+	Card vcard.Card
+}
+
+// parseCard keeps the full vCard of an address object
+func parseCard(obj carddav.AddressObject) *ParsedCard {
+	if obj.Card == nil {
+		return nil
+	}
+	return &ParsedCard{Href: obj.Path, ETag: obj.ETag, Card: obj.Card}
+}
+
 // parseVCard parses a vCard and extracts contacts (one per email address)
 func parseVCard(obj carddav.AddressObject) []ParsedContact {
 	if obj.Card == nil {
@@ -318,6 +339,7 @@ func parseVCard(obj carddav.AddressObject) []ParsedContact {
 type SyncResult struct {
 	SyncToken string          // New sync token to store
 	Updated   []ParsedContact // Contacts that were added/modified
+	Cards     []ParsedCard    // Full vCards of the added/modified contacts
 	Deleted   []string        // Hrefs of contacts that were deleted
 }
 
@@ -336,7 +358,7 @@ func (c *Client) SyncAddressbook(addressbookPath, syncToken string) (*SyncResult
 
 	// Create a new client for this specific addressbook
 	httpClient := webdav.HTTPClientWithBasicAuth(
-		&http.Client{Timeout: 60 * time.Second},
+		netproxy.HTTPClientFor(c.proxy, 60*time.Second),
 		c.username, c.password,
 	)
 
@@ -388,6 +410,9 @@ func (c *Client) SyncAddressbook(addressbookPath, syncToken string) (*SyncResult
 			for _, obj := range syncResp.Updated {
 				parsed := parseVCard(obj)
 				result.Updated = append(result.Updated, parsed...)
+				if card := parseCard(obj); card != nil {
+					result.Cards = append(result.Cards, *card)
+				}
 			}
 		} else {
 			// Need to fetch full card data using multiget
@@ -396,11 +421,12 @@ func (c *Client) SyncAddressbook(addressbookPath, syncToken string) (*SyncResult
 				paths[i] = obj.Path
 			}
 
-			contacts, err := c.fetchContactsByPath(abClient, addressbookPath, paths)
+			contacts, cards, err := c.fetchContactsByPath(abClient, addressbookPath, paths)
 			if err != nil {
 				return nil, fmt.Errorf("failed to fetch updated contacts: %w", err)
 			}
 			result.Updated = contacts
+			result.Cards = cards
 		}
 	}
 
@@ -414,9 +440,9 @@ func (c *Client) SyncAddressbook(addressbookPath, syncToken string) (*SyncResult
 }
 
 // fetchContactsByPath fetches contacts by their paths using addressbook-multiget
-func (c *Client) fetchContactsByPath(client *carddav.Client, addressbookPath string, paths []string) ([]ParsedContact, error) {
+func (c *Client) fetchContactsByPath(client *carddav.Client, addressbookPath string, paths []string) ([]ParsedContact, []ParsedCard, error) {
 	if len(paths) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	ctx := context.Background()
@@ -433,16 +459,20 @@ func (c *Client) fetchContactsByPath(client *carddav.Client, addressbookPath str
 
 	addressObjects, err := client.MultiGetAddressBook(ctx, addressbookPath, multiGet)
 	if err != nil {
-		return nil, fmt.Errorf("multiget failed: %w", err)
+		return nil, nil, fmt.Errorf("multiget failed: %w", err)
 	}
 
 	var contacts []ParsedContact
+	var cards []ParsedCard
 	for _, obj := range addressObjects {
 		parsed := parseVCard(obj)
 		contacts = append(contacts, parsed...)
+		if card := parseCard(obj); card != nil {
+			cards = append(cards, *card)
+		}
 	}
 
-	return contacts, nil
+	return contacts, cards, nil
 }
 
 // TestConnection tests the connection to the CardDAV server
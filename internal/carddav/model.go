@@ -1,7 +1,11 @@
 // Package carddav provides CardDAV contact sync functionality
 package carddav
 
-import "time"
+import (
+	"time"
+
+	"github.com/hkdb/aerion/internal/contact"
+)
 
 // SourceType represents the type of contact source
 type SourceType string
@@ -75,6 +79,16 @@ type Contact struct {
 	SyncedAt      time.Time `json:"synced_at"`
 }
 
+// SaveCardResult is the outcome of writing a card back to its server
+type SaveCardResult struct {
+	Card *contact.Card `json:"card"` // Saved card, or the unsaved local edit on conflict
+
+	// Conflict is the current server copy when the edit was rejected because
+	// the card changed on the server. It has already been stored locally;
+	// saving the edit again with Conflict.ETag overwrites the server copy.
+	Conflict *contact.Card `json:"conflict,omitempty"`
+}
+
 // SourceError represents an error that occurred during sync
 type SourceError struct {
 	SourceID   string    `json:"source_id"`
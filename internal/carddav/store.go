@@ -6,7 +6,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/emersion/go-vcard"
 	"github.com/google/uuid"
+	"github.com/hkdb/aerion/internal/contact"
 	"github.com/hkdb/aerion/internal/logging"
 	"github.com/rs/zerolog"
 )
@@ -686,3 +688,756 @@ func (s *Store) GetSourceByAccountID(accountID string) (*Source, error) {
 
 	return &source, nil
 }
+
+// ============================================================================
+// Card CRUD (full vCards)
+// ============================================================================
+
+// UpsertCardsBatch stores the full vCards fetched from an addressbook
+func (s *Store) UpsertCardsBatch(addressbookID string, cards []ParsedCard) error {
+	if len(cards) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := s.upsertCards(tx, addressbookID, cards); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceCards makes the stored cards of an addressbook match a full
+// listing from the server: cards are updated in place, keeping their IDs,
+// and cards the server no longer has are deleted, all in one transaction
+func (s *Store) ReplaceCards(addressbookID string, cards []ParsedCard) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	kept, err := s.upsertCards(tx, addressbookID, cards)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT id FROM carddav_cards WHERE addressbook_id = ?", addressbookID)
+	if err != nil {
+		return fmt.Errorf("failed to list cards: %w", err)
+	}
+	var stale []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan card: %w", err)
+		}
+		if !kept[id] {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list cards: %w", err)
+	}
+
+	for _, id := range stale {
+		if _, err := tx.Exec("DELETE FROM carddav_cards WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete card: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// upsertCards stores cards within a transaction and returns the IDs of the
+// stored rows. A card keeps the ID of the row with its href, or of the row
+// with its UID when the server moved it to a new href.
+func (s *Store) upsertCards(tx *sql.Tx, addressbookID string, cards []ParsedCard) (map[string]bool, error) {
+	byHref := make(map[string]string)
+	byUID := make(map[string]string)
+	hrefOf := make(map[string]string)
+	rows, err := tx.Query("SELECT id, href, uid FROM carddav_cards WHERE addressbook_id = ?", addressbookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cards: %w", err)
+	}
+	for rows.Next() {
+		var id, href string
+		var uid sql.NullString
+		if err := rows.Scan(&id, &href, &uid); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan card: %w", err)
+		}
+		byHref[href] = id
+		hrefOf[id] = href
+		if uid.String != "" {
+			byUID[uid.String] = id
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list cards: %w", err)
+	}
+
+	// Hrefs present in this batch keep their own rows
+	incoming := make(map[string]bool, len(cards))
+	for _, pc := range cards {
+		incoming[pc.Href] = true
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO carddav_cards (id, addressbook_id, href, etag, uid, full_name, kind, vcard, synced_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			href = excluded.href,
+			etag = excluded.etag,
+			uid = excluded.uid,
+			full_name = excluded.full_name,
+			kind = excluded.kind,
+			vcard = excluded.vcard,
+			synced_at = excluded.synced_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	kept := make(map[string]bool, len(cards))
+	now := time.Now()
+	for _, pc := range cards {
+		data, err := contact.EncodeVCard(pc.Card)
+		if err != nil {
+			s.log.Warn().Err(err).Str("href", pc.Href).Msg("Failed to encode vCard")
+			continue
+		}
+		card := contact.CardFromVCard(pc.Card)
+
+		id, ok := byHref[pc.Href]
+		if !ok {
+			if moved, found := byUID[card.UID]; found && card.UID != "" && !kept[moved] && !incoming[hrefOf[moved]] {
+				id = moved
+			} else {
+				id = uuid.New().String()
+			}
+		}
+
+		if _, err := stmt.Exec(id, addressbookID, pc.Href, pc.ETag,
+			card.UID, card.DisplayName(), cardKind(pc.Card), data, now); err != nil {
+			s.log.Warn().Err(err).Str("href", pc.Href).Msg("Failed to upsert card in batch")
+			continue
+		}
+		kept[id] = true
+	}
+
+	return kept, nil
+}
+
+// DeleteCardsByHrefs deletes cards by their hrefs
+func (s *Store) DeleteCardsByHrefs(addressbookID string, hrefs []string) error {
+	if len(hrefs) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, href := range hrefs {
+		if _, err := tx.Exec(
+			"DELETE FROM carddav_cards WHERE addressbook_id = ? AND href = ?",
+			addressbookID, href); err != nil {
+			return fmt.Errorf("failed to delete card with href %s: %w", href, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListCards returns the cards of all enabled addressbooks, or of a single
+// addressbook when addressbookID is set. Group vCards are excluded.
+func (s *Store) ListCards(addressbookID string) ([]*contact.Card, error) {
+	query := `
+		SELECT c.id, c.addressbook_id, c.href, c.etag, c.vcard, c.synced_at
+		FROM carddav_cards c
+		JOIN contact_source_addressbooks ab ON c.addressbook_id = ab.id
+		JOIN contact_sources s ON ab.source_id = s.id
+		WHERE s.enabled = 1 AND ab.enabled = 1
+		  AND (c.kind IS NULL OR c.kind != 'group')
+	`
+	var args []interface{}
+	if addressbookID != "" {
+		query += " AND c.addressbook_id = ?"
+		args = append(args, addressbookID)
+	}
+	query += " ORDER BY LOWER(c.full_name) ASC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cards: %w", err)
+	}
+	defer rows.Close()
+
+	var cards []*contact.Card
+	for rows.Next() {
+		card, _, err := scanCard(rows)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Skipping unreadable card")
+			continue
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rows.Err()
+}
+
+// GetCard returns a card and its underlying vCard by ID
+func (s *Store) GetCard(id string) (*contact.Card, vcard.Card, error) {
+	row := s.db.QueryRow(`
+		SELECT id, addressbook_id, href, etag, vcard, synced_at
+		FROM carddav_cards
+		WHERE id = ?
+	`, id)
+
+	card, vc, err := scanCard(row)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get card: %w", err)
+	}
+	return card, vc, nil
+}
+
+// IsCardReadOnly reports whether a card can't be written back, i.e. it
+// belongs to a source that isn't a CardDAV server
+func (s *Store) IsCardReadOnly(id string) (bool, error) {
+	var sourceType SourceType
+	err := s.db.QueryRow(`
+		SELECT s.type
+		FROM carddav_cards c
+		JOIN contact_source_addressbooks ab ON c.addressbook_id = ab.id
+		JOIN contact_sources s ON ab.source_id = s.id
+		WHERE c.id = ?
+	`, id).Scan(&sourceType)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("contact not found: %s", id)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get contact source: %w", err)
+	}
+	return sourceType != SourceTypeCardDAV, nil
+}
+
+// SaveCard stores a card after it was written to (or re-read from) the
+// server, and refreshes the email index used for autocomplete
+func (s *Store) SaveCard(addressbookID, href, etag string, vc vcard.Card) (*contact.Card, error) {
+	data, err := contact.EncodeVCard(vc)
+	if err != nil {
+		return nil, err
+	}
+	card := contact.CardFromVCard(vc)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.Exec(`
+		INSERT INTO carddav_cards (id, addressbook_id, href, etag, uid, full_name, kind, vcard, synced_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(addressbook_id, href) DO UPDATE SET
+			etag = excluded.etag,
+			uid = excluded.uid,
+			full_name = excluded.full_name,
+			kind = excluded.kind,
+			vcard = excluded.vcard,
+			synced_at = excluded.synced_at
+	`, uuid.New().String(), addressbookID, href, etag, card.UID, card.DisplayName(), cardKind(vc), data, now); err != nil {
+		return nil, fmt.Errorf("failed to save card: %w", err)
+	}
+
+	if _, err := tx.Exec(
+		"DELETE FROM carddav_contacts WHERE addressbook_id = ? AND href = ?",
+		addressbookID, href); err != nil {
+		return nil, fmt.Errorf("failed to clear contact emails: %w", err)
+	}
+	for _, email := range card.EmailAddresses() {
+		if _, err := tx.Exec(`
+			INSERT INTO carddav_contacts (id, addressbook_id, email, display_name, href, etag, synced_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, uuid.New().String(), addressbookID, email, card.DisplayName(), href, etag, now); err != nil {
+			return nil, fmt.Errorf("failed to index contact email: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit card: %w", err)
+	}
+
+	saved, _, err := s.getCardByHref(addressbookID, href)
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// DeleteCard removes a card and its email index entries
+func (s *Store) DeleteCard(addressbookID, href string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"DELETE FROM carddav_cards WHERE addressbook_id = ? AND href = ?",
+		addressbookID, href); err != nil {
+		return fmt.Errorf("failed to delete card: %w", err)
+	}
+	if _, err := tx.Exec(
+		"DELETE FROM carddav_contacts WHERE addressbook_id = ? AND href = ?",
+		addressbookID, href); err != nil {
+		return fmt.Errorf("failed to delete contact emails: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// getCardByHref returns a card by its addressbook and href
+func (s *Store) getCardByHref(addressbookID, href string) (*contact.Card, vcard.Card, error) {
+	row := s.db.QueryRow(`
+		SELECT id, addressbook_id, href, etag, vcard, synced_at
+		FROM carddav_cards
+		WHERE addressbook_id = ? AND href = ?
+	`, addressbookID, href)
+
+	card, vc, err := scanCard(row)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get card: %w", err)
+	}
+	return card, vc, nil
+}
+
+// cardScanner is satisfied by *sql.Row and *sql.Rows
+type cardScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCard reads a carddav_cards row into a Card and its vCard
+func scanCard(row cardScanner) (*contact.Card, vcard.Card, error) {
+	var id, addressbookID, href, data string
+	var etag sql.NullString
+	var syncedAt sql.NullTime
+	if err := row.Scan(&id, &addressbookID, &href, &etag, &data, &syncedAt); err != nil {
+		return nil, nil, err
+	}
+
+	vc, err := contact.DecodeVCard(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	card := contact.CardFromVCard(vc)
+	card.ID = id
+	card.Source = contact.CardSourceCardDAV
+	card.AddressbookID = addressbookID
+	card.Href = href
+	card.ETag = etag.String
+	if card.UpdatedAt.IsZero() && syncedAt.Valid {
+		card.UpdatedAt = syncedAt.Time
+	}
+	return card, vc, nil
+}
+
+// ============================================================================
+// Groups
+// ============================================================================
+
+// ReplaceGroups replaces the contact groups of an OAuth source's addressbook.
+// Groups keep their IDs: they are matched by remote ID, or by name when the
+// server gave a group a new remote ID. Groups the server no longer has are
+// deleted.
+func (s *Store) ReplaceGroups(addressbookID string, groups []contact.SyncedGroup) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	byRemoteID := make(map[string]string)
+	byName := make(map[string]string)
+	remoteIDOf := make(map[string]string)
+	rows, err := tx.Query("SELECT id, remote_id, name FROM carddav_groups WHERE addressbook_id = ?", addressbookID)
+	if err != nil {
+		return fmt.Errorf("failed to list groups: %w", err)
+	}
+	for rows.Next() {
+		var id, remoteID, name string
+		if err := rows.Scan(&id, &remoteID, &name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan group: %w", err)
+		}
+		byRemoteID[remoteID] = id
+		byName[strings.ToLower(name)] = id
+		remoteIDOf[id] = remoteID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	incoming := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		incoming[g.RemoteID] = true
+	}
+
+	kept := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		id, ok := byRemoteID[g.RemoteID]
+		if !ok {
+			if renamed, found := byName[strings.ToLower(g.Name)]; found && !kept[renamed] && !incoming[remoteIDOf[renamed]] {
+				id = renamed
+			} else {
+				id = uuid.New().String()
+			}
+		}
+		if kept[id] {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO carddav_groups (id, addressbook_id, remote_id, name) VALUES (?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET remote_id = excluded.remote_id, name = excluded.name`,
+			id, addressbookID, g.RemoteID, g.Name); err != nil {
+			return fmt.Errorf("failed to save group %s: %w", g.Name, err)
+		}
+		kept[id] = true
+	}
+
+	for id := range remoteIDOf {
+		if kept[id] {
+			continue
+		}
+		if _, err := tx.Exec("DELETE FROM carddav_groups WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete group: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteGroupMembersForAddressbook deletes all group memberships of an addressbook
+func (s *Store) DeleteGroupMembersForAddressbook(addressbookID string) error {
+	_, err := s.db.Exec("DELETE FROM carddav_group_members WHERE addressbook_id = ?", addressbookID)
+	return err
+}
+
+// UpdateGroupMembers replaces the group memberships of the given contacts.
+// Memberships of the removed hrefs are deleted.
+func (s *Store) UpdateGroupMembers(addressbookID string, contacts []contact.SyncedContact, removed []string) error {
+	if len(contacts) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	cleared := make(map[string]bool)
+	clearHref := func(href string) error {
+		if cleared[href] {
+			return nil
+		}
+		cleared[href] = true
+		_, err := tx.Exec(
+			"DELETE FROM carddav_group_members WHERE addressbook_id = ? AND href = ?",
+			addressbookID, href)
+		return err
+	}
+
+	for _, href := range removed {
+		if err := clearHref(href); err != nil {
+			return fmt.Errorf("failed to clear group memberships: %w", err)
+		}
+	}
+	for _, c := range contacts {
+		if err := clearHref(c.RemoteID); err != nil {
+			return fmt.Errorf("failed to clear group memberships: %w", err)
+		}
+		for _, groupID := range c.GroupIDs {
+			if _, err := tx.Exec(
+				"INSERT OR IGNORE INTO carddav_group_members (addressbook_id, group_remote_id, href) VALUES (?, ?, ?)",
+				addressbookID, groupID, c.RemoteID); err != nil {
+				return fmt.Errorf("failed to add group membership: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SearchGroups searches synced groups by name: contact groups of OAuth
+// sources and group vCards (KIND:group / Apple groups) of CardDAV addressbooks.
+// An empty query returns all groups.
+func (s *Store) SearchGroups(query string, limit int) ([]*contact.Group, error) {
+	pattern := "%" + strings.ToLower(query) + "%"
+
+	var groups []*contact.Group
+
+	// Contact groups of OAuth sources
+	rows, err := s.db.Query(`
+		SELECT g.id, g.addressbook_id, g.remote_id, g.name, s.type
+		FROM carddav_groups g
+		JOIN contact_source_addressbooks ab ON g.addressbook_id = ab.id
+		JOIN contact_sources s ON ab.source_id = s.id
+		WHERE s.enabled = 1 AND ab.enabled = 1 AND LOWER(g.name) LIKE ?
+		ORDER BY LOWER(g.name) ASC
+		LIMIT ?
+	`, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search groups: %w", err)
+	}
+	type remoteGroup struct {
+		group         *contact.Group
+		addressbookID string
+		remoteID      string
+	}
+	var remote []remoteGroup
+	for rows.Next() {
+		var rg remoteGroup
+		var sourceType string
+		rg.group = &contact.Group{}
+		if err := rows.Scan(&rg.group.ID, &rg.addressbookID, &rg.remoteID, &rg.group.Name, &sourceType); err != nil {
+			continue
+		}
+		rg.group.Source = sourceType
+		remote = append(remote, rg)
+	}
+	rows.Close()
+
+	for _, rg := range remote {
+		members, err := s.remoteGroupMembers(rg.addressbookID, rg.remoteID)
+		if err != nil {
+			return nil, err
+		}
+		rg.group.Members = members
+		groups = append(groups, rg.group)
+	}
+
+	// Group vCards of CardDAV addressbooks
+	rows, err = s.db.Query(`
+		SELECT c.id, c.addressbook_id, c.href, c.etag, c.vcard, c.synced_at
+		FROM carddav_cards c
+		JOIN contact_source_addressbooks ab ON c.addressbook_id = ab.id
+		JOIN contact_sources s ON ab.source_id = s.id
+		WHERE s.enabled = 1 AND ab.enabled = 1
+		  AND c.kind = 'group' AND LOWER(c.full_name) LIKE ?
+		ORDER BY LOWER(c.full_name) ASC
+		LIMIT ?
+	`, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search group cards: %w", err)
+	}
+	type cardGroup struct {
+		card *contact.Card
+		vc   vcard.Card
+	}
+	var cardGroups []cardGroup
+	for rows.Next() {
+		card, vc, err := scanCard(rows)
+		if err != nil {
+			continue
+		}
+		cardGroups = append(cardGroups, cardGroup{card, vc})
+	}
+	rows.Close()
+
+	for _, cg := range cardGroups {
+		members, err := s.cardGroupMembers(cg.card.AddressbookID, cg.vc)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, &contact.Group{
+			ID:        cg.card.ID,
+			Source:    contact.GroupSourceCardDAV,
+			Name:      cg.card.DisplayName(),
+			Members:   members,
+			UpdatedAt: cg.card.UpdatedAt,
+		})
+	}
+
+	return groups, nil
+}
+
+// remoteGroupMembers returns the members of an OAuth contact group, one
+// address (the first synced) per contact
+func (s *Store) remoteGroupMembers(addressbookID, groupRemoteID string) ([]contact.GroupMember, error) {
+	rows, err := s.db.Query(`
+		SELECT c.email, c.display_name
+		FROM carddav_contacts c
+		WHERE c.rowid IN (
+			SELECT MIN(c2.rowid)
+			FROM carddav_group_members m
+			JOIN carddav_contacts c2 ON c2.addressbook_id = m.addressbook_id AND c2.href = m.href
+			WHERE m.addressbook_id = ? AND m.group_remote_id = ?
+			GROUP BY c2.href
+		)
+		ORDER BY LOWER(COALESCE(NULLIF(c.display_name, ''), c.email)) ASC
+	`, addressbookID, groupRemoteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []contact.GroupMember{}
+	for rows.Next() {
+		var m contact.GroupMember
+		var displayName sql.NullString
+		if err := rows.Scan(&m.Email, &displayName); err != nil {
+			continue
+		}
+		m.DisplayName = displayName.String
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// cardGroupMembers resolves the members of a group vCard against the cards
+// of its addressbook, using each member's preferred address
+func (s *Store) cardGroupMembers(addressbookID string, vc vcard.Card) ([]contact.GroupMember, error) {
+	uids, emails := contact.GroupMemberRefs(vc)
+
+	members := []contact.GroupMember{}
+	for _, email := range emails {
+		members = append(members, contact.GroupMember{Email: email})
+	}
+
+	for _, uid := range uids {
+		var data string
+		err := s.db.QueryRow(`
+			SELECT vcard FROM carddav_cards
+			WHERE addressbook_id = ? AND (uid = ? OR uid = ?)
+			LIMIT 1
+		`, addressbookID, uid, "urn:uuid:"+uid).Scan(&data)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get group member: %w", err)
+		}
+
+		memberVC, err := contact.DecodeVCard(data)
+		if err != nil {
+			continue
+		}
+		member := contact.CardFromVCard(memberVC)
+		if addrs := member.EmailAddresses(); len(addrs) > 0 {
+			members = append(members, contact.GroupMember{Email: addrs[0], DisplayName: member.DisplayName()})
+		}
+	}
+
+	return members, nil
+}
+
+// cardKind returns the value stored in carddav_cards.kind for a vCard
+func cardKind(vc vcard.Card) string {
+	if contact.IsGroupVCard(vc) {
+		return string(vcard.KindGroup)
+	}
+	return strings.ToLower(vc.Value(vcard.FieldKind))
+}
+
+// ============================================================================
+// Export and merge
+// ============================================================================
+
+// ListVCards returns the vCards of an addressbook, including group vCards
+func (s *Store) ListVCards(addressbookID string) ([]vcard.Card, error) {
+	rows, err := s.db.Query(`
+		SELECT href, vcard FROM carddav_cards
+		WHERE addressbook_id = ?
+		ORDER BY LOWER(full_name) ASC
+	`, addressbookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cards: %w", err)
+	}
+	defer rows.Close()
+
+	var cards []vcard.Card
+	for rows.Next() {
+		var href, data string
+		if err := rows.Scan(&href, &data); err != nil {
+			continue
+		}
+		vc, err := contact.DecodeVCard(data)
+		if err != nil {
+			s.log.Warn().Err(err).Str("href", href).Msg("Skipping unparseable card")
+			continue
+		}
+		cards = append(cards, vc)
+	}
+	return cards, rows.Err()
+}
+
+// ListMergeEntries returns the synced contacts of all enabled addressbooks
+// as merge entries. CardDAV cards are editable; contacts of OAuth sources,
+// which only have an email index, are read-only.
+func (s *Store) ListMergeEntries() ([]*contact.MergeEntry, error) {
+	cards, err := s.ListCards("")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*contact.MergeEntry
+	for _, card := range cards {
+		entries = append(entries, contact.CardMergeEntry(card, false))
+	}
+
+	rows, err := s.db.Query(`
+		SELECT c.addressbook_id, c.href, c.email, c.display_name, s.type
+		FROM carddav_contacts c
+		JOIN contact_source_addressbooks ab ON c.addressbook_id = ab.id
+		JOIN contact_sources s ON ab.source_id = s.id
+		WHERE s.enabled = 1 AND ab.enabled = 1 AND s.type != ?
+		ORDER BY c.addressbook_id, c.href, c.rowid
+	`, SourceTypeCardDAV)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+	defer rows.Close()
+
+	// One entry per remote contact, which has one row per address
+	byHref := make(map[string]*contact.MergeEntry)
+	for rows.Next() {
+		var addressbookID, href, email, sourceType string
+		var displayName sql.NullString
+		if err := rows.Scan(&addressbookID, &href, &email, &displayName, &sourceType); err != nil {
+			continue
+		}
+		key := addressbookID + "\x00" + href
+		entry, ok := byHref[key]
+		if !ok {
+			entry = &contact.MergeEntry{
+				Source:      sourceType,
+				ID:          href,
+				DisplayName: displayName.String,
+				ReadOnly:    true,
+			}
+			byHref[key] = entry
+			entries = append(entries, entry)
+		}
+		entry.Emails = append(entry.Emails, email)
+	}
+
+	return entries, rows.Err()
+}
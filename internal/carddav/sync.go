@@ -1,44 +1,25 @@
 package carddav
 
 import (
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
+	"github.com/emersion/go-vcard"
 	"github.com/hkdb/aerion/internal/contact"
 	"github.com/hkdb/aerion/internal/credentials"
+	"github.com/hkdb/aerion/internal/database"
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/rs/zerolog"
 )
 
 // AccessTokenGetter is a function that retrieves a valid OAuth access token for an account
 type AccessTokenGetter func(accountID string) (string, error)
 
-// retryDBOperation retries a database operation with exponential backoff.
-// This handles SQLITE_BUSY errors that occur during concurrent database access.
-func retryDBOperation(operation func() error, maxRetries int, baseDelay time.Duration, log zerolog.Logger) error {
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		err = operation()
-		if err == nil {
-			return nil
-		}
-		// Check if it's a SQLITE_BUSY error (retryable)
-		if !strings.Contains(err.Error(), "database is locked") &&
-			!strings.Contains(err.Error(), "SQLITE_BUSY") {
-			return err // Non-retryable error, return immediately
-		}
-		// Exponential backoff: 100ms, 200ms, 400ms, 800ms, 1600ms
-		delay := baseDelay * time.Duration(1<<uint(i))
-		log.Debug().
-			Int("attempt", i+1).
-			Int("maxRetries", maxRetries).
-			Dur("delay", delay).
-			Msg("Database busy, retrying after delay")
-		time.Sleep(delay)
-	}
-	return fmt.Errorf("operation failed after %d retries: %w", maxRetries, err)
-}
+// ProxyGetter is a function that returns the proxy of an email account, nil
+// for the global proxy
+type ProxyGetter func(accountID string) *netproxy.Config
 
 // Syncer handles syncing contacts from CardDAV/Google/Microsoft sources
 type Syncer struct {
@@ -46,19 +27,16 @@ type Syncer struct {
 	credStore        *credentials.Store
 	getAccountToken  AccessTokenGetter // Gets OAuth token from linked email account
 	getSourceToken   AccessTokenGetter // Gets OAuth token from standalone contact source
-	googleSyncer     *contact.GoogleContactsSyncer
-	microsoftSyncer  *contact.MicrosoftContactsSyncer
+	getAccountProxy  ProxyGetter       // Gets the proxy of a linked email account
 	log              zerolog.Logger
 }
 
 // NewSyncer creates a new contact syncer
 func NewSyncer(store *Store, credStore *credentials.Store) *Syncer {
 	return &Syncer{
-		store:           store,
-		credStore:       credStore,
-		googleSyncer:    contact.NewGoogleContactsSyncer(),
-		microsoftSyncer: contact.NewMicrosoftContactsSyncer(),
-		log:             logging.WithComponent("carddav-sync"),
+		store:     store,
+		credStore: credStore,
+		log:       logging.WithComponent("carddav-sync"),
 	}
 }
 
@@ -68,6 +46,21 @@ func (s *Syncer) SetAccessTokenGetters(accountTokenGetter, sourceTokenGetter Acc
 	s.getSourceToken = sourceTokenGetter
 }
 
+// SetProxyGetter sets the function for retrieving the proxy of a linked
+// email account
+func (s *Syncer) SetProxyGetter(proxyGetter ProxyGetter) {
+	s.getAccountProxy = proxyGetter
+}
+
+// sourceProxy returns the proxy for a source: the proxy of its linked
+// account, or nil for the global proxy
+func (s *Syncer) sourceProxy(source *Source) *netproxy.Config {
+	if source.AccountID == nil || *source.AccountID == "" || s.getAccountProxy == nil {
+		return nil
+	}
+	return s.getAccountProxy(*source.AccountID)
+}
+
 // SyncSource syncs contacts for a source based on its type (CardDAV, Google, Microsoft)
 func (s *Syncer) SyncSource(sourceID string) error {
 	s.log.Info().Str("sourceID", sourceID).Msg("Starting source sync")
@@ -110,7 +103,7 @@ func (s *Syncer) syncCardDAV(source *Source) error {
 	}
 
 	// Create CardDAV client
-	client, err := NewClient(source.URL, source.Username, password)
+	client, err := NewClient(source.URL, source.Username, password, s.sourceProxy(source))
 	if err != nil {
 		syncErr := fmt.Sprintf("failed to connect: %v", err)
 		s.store.UpdateSourceSyncStatus(source.ID, syncErr)
@@ -171,7 +164,7 @@ func (s *Syncer) syncGoogle(source *Source) error {
 	}
 
 	// Sync contacts using Google syncer with delta sync
-	result, err := s.googleSyncer.SyncContactsDelta(accessToken, ab.SyncToken)
+	result, err := contact.NewGoogleContactsSyncer(s.sourceProxy(source)).SyncContactsDelta(accessToken, ab.SyncToken)
 	if err != nil {
 		syncErr := fmt.Sprintf("failed to sync: %v", err)
 		s.store.UpdateSourceSyncStatus(source.ID, syncErr)
@@ -213,7 +206,7 @@ func (s *Syncer) syncMicrosoft(source *Source) error {
 	}
 
 	// Sync contacts using Microsoft syncer with delta sync
-	result, err := s.microsoftSyncer.SyncContactsDelta(accessToken, ab.SyncToken)
+	result, err := contact.NewMicrosoftContactsSyncer(s.sourceProxy(source)).SyncContactsDelta(accessToken, ab.SyncToken)
 	if err != nil {
 		syncErr := fmt.Sprintf("failed to sync: %v", err)
 		s.store.UpdateSourceSyncStatus(source.ID, syncErr)
@@ -276,7 +269,7 @@ func (s *Syncer) getOrCreateOAuthAddressbook(source *Source) (*Addressbook, erro
 func (s *Syncer) storeOAuthContactsDelta(ab *Addressbook, result *contact.SyncResult) error {
 	// If this is a full sync, delete all existing contacts first
 	if result.IsFullSync {
-		deleteErr := retryDBOperation(func() error {
+		deleteErr := database.RetryOnBusy(func() error {
 			return s.store.DeleteContactsForAddressbook(ab.ID)
 		}, 5, 100*time.Millisecond, s.log)
 		if deleteErr != nil {
@@ -286,7 +279,7 @@ func (s *Syncer) storeOAuthContactsDelta(ab *Addressbook, result *contact.SyncRe
 		// Incremental sync: delete removed contacts
 		if len(result.DeletedIDs) > 0 {
 			s.log.Debug().Int("count", len(result.DeletedIDs)).Msg("Deleting removed OAuth contacts")
-			deleteErr := retryDBOperation(func() error {
+			deleteErr := database.RetryOnBusy(func() error {
 				return s.store.DeleteContactsByHrefs(ab.ID, result.DeletedIDs)
 			}, 5, 100*time.Millisecond, s.log)
 			if deleteErr != nil {
@@ -308,7 +301,7 @@ func (s *Syncer) storeOAuthContactsDelta(ab *Addressbook, result *contact.SyncRe
 			})
 		}
 
-		upsertErr := retryDBOperation(func() error {
+		upsertErr := database.RetryOnBusy(func() error {
 			return s.store.UpsertContactsBatch(contacts)
 		}, 5, 100*time.Millisecond, s.log)
 		if upsertErr != nil {
@@ -316,12 +309,39 @@ func (s *Syncer) storeOAuthContactsDelta(ab *Addressbook, result *contact.SyncRe
 		}
 	}
 
+	s.storeOAuthGroups(ab, result)
+
 	// Store the sync token for future incremental syncs
 	s.store.UpdateAddressbookSyncToken(ab.ID, result.NextSyncToken)
 
 	return nil
 }
 
+// storeOAuthGroups stores the contact groups and memberships of an OAuth source
+func (s *Syncer) storeOAuthGroups(ab *Addressbook, result *contact.SyncResult) {
+	if result.Groups != nil {
+		err := database.RetryOnBusy(func() error {
+			return s.store.ReplaceGroups(ab.ID, result.Groups)
+		}, 5, 100*time.Millisecond, s.log)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Failed to store contact groups after retries")
+		}
+	}
+
+	err := database.RetryOnBusy(func() error {
+		if result.IsFullSync {
+			if err := s.store.DeleteGroupMembersForAddressbook(ab.ID); err != nil {
+				return err
+			}
+			return s.store.UpdateGroupMembers(ab.ID, result.Contacts, nil)
+		}
+		return s.store.UpdateGroupMembers(ab.ID, result.Contacts, result.DeletedIDs)
+	}, 5, 100*time.Millisecond, s.log)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to store group memberships after retries")
+	}
+}
+
 // syncAddressbook syncs a single addressbook
 // Uses incremental sync (sync-collection) if a sync token exists, otherwise does a full sync
 func (s *Syncer) syncAddressbook(client *Client, ab *Addressbook) error {
@@ -354,7 +374,7 @@ func (s *Syncer) syncAddressbookIncremental(client *Client, ab *Addressbook) err
 	// Process deleted contacts
 	if len(result.Deleted) > 0 {
 		s.log.Debug().Int("count", len(result.Deleted)).Msg("Processing deleted contacts")
-		deleteErr := retryDBOperation(func() error {
+		deleteErr := database.RetryOnBusy(func() error {
 			return s.store.DeleteContactsByHrefs(ab.ID, result.Deleted)
 		}, 5, 100*time.Millisecond, s.log)
 		if deleteErr != nil {
@@ -376,7 +396,7 @@ func (s *Syncer) syncAddressbookIncremental(client *Client, ab *Addressbook) err
 			})
 		}
 
-		upsertErr := retryDBOperation(func() error {
+		upsertErr := database.RetryOnBusy(func() error {
 			return s.store.UpsertContactsBatch(contacts)
 		}, 5, 100*time.Millisecond, s.log)
 		if upsertErr != nil {
@@ -384,6 +404,8 @@ func (s *Syncer) syncAddressbookIncremental(client *Client, ab *Addressbook) err
 		}
 	}
 
+	s.storeCards(ab, result.Cards, result.Deleted, false)
+
 	// Update sync token
 	s.store.UpdateAddressbookSyncToken(ab.ID, result.SyncToken)
 
@@ -409,7 +431,7 @@ func (s *Syncer) syncAddressbookFull(client *Client, ab *Addressbook) error {
 	}
 
 	// Delete all existing contacts and replace with synced ones
-	deleteErr := retryDBOperation(func() error {
+	deleteErr := database.RetryOnBusy(func() error {
 		return s.store.DeleteContactsForAddressbook(ab.ID)
 	}, 5, 100*time.Millisecond, s.log)
 	if deleteErr != nil {
@@ -429,7 +451,7 @@ func (s *Syncer) syncAddressbookFull(client *Client, ab *Addressbook) error {
 			})
 		}
 
-		upsertErr := retryDBOperation(func() error {
+		upsertErr := database.RetryOnBusy(func() error {
 			return s.store.UpsertContactsBatch(contacts)
 		}, 5, 100*time.Millisecond, s.log)
 		if upsertErr != nil {
@@ -437,6 +459,8 @@ func (s *Syncer) syncAddressbookFull(client *Client, ab *Addressbook) error {
 		}
 	}
 
+	s.storeCards(ab, result.Cards, nil, true)
+
 	// Store the sync token for future incremental syncs
 	s.store.UpdateAddressbookSyncToken(ab.ID, result.SyncToken)
 
@@ -449,7 +473,7 @@ func (s *Syncer) syncAddressbookFull(client *Client, ab *Addressbook) error {
 func (s *Syncer) syncAddressbookLegacy(client *Client, ab *Addressbook) error {
 	s.log.Debug().Str("addressbook", ab.Name).Msg("Performing legacy sync (addressbook-query)")
 
-	parsedContacts, err := client.FetchContacts(ab.Path)
+	parsedContacts, parsedCards, err := client.FetchContacts(ab.Path)
 	if err != nil {
 		return fmt.Errorf("failed to fetch contacts: %w", err)
 	}
@@ -457,7 +481,7 @@ func (s *Syncer) syncAddressbookLegacy(client *Client, ab *Addressbook) error {
 	s.log.Debug().Int("count", len(parsedContacts)).Str("addressbook", ab.Name).Msg("Fetched contacts")
 
 	// Delete all existing contacts and re-add
-	deleteErr := retryDBOperation(func() error {
+	deleteErr := database.RetryOnBusy(func() error {
 		return s.store.DeleteContactsForAddressbook(ab.ID)
 	}, 5, 100*time.Millisecond, s.log)
 	if deleteErr != nil {
@@ -477,13 +501,15 @@ func (s *Syncer) syncAddressbookLegacy(client *Client, ab *Addressbook) error {
 	}
 
 	// Batch insert all contacts
-	upsertErr := retryDBOperation(func() error {
+	upsertErr := database.RetryOnBusy(func() error {
 		return s.store.UpsertContactsBatch(contacts)
 	}, 5, 100*time.Millisecond, s.log)
 	if upsertErr != nil {
 		s.log.Warn().Err(upsertErr).Msg("Failed to batch upsert contacts after retries")
 	}
 
+	s.storeCards(ab, parsedCards, nil, true)
+
 	// No sync token available with legacy method
 	s.store.UpdateAddressbookSyncToken(ab.ID, "")
 
@@ -491,6 +517,35 @@ func (s *Syncer) syncAddressbookLegacy(client *Client, ab *Addressbook) error {
 	return nil
 }
 
+// storeCards stores the full vCards of a synced addressbook. With replace
+// set, cards is the complete listing (full sync) and cards missing from it
+// are deleted; card IDs are kept either way.
+func (s *Syncer) storeCards(ab *Addressbook, cards []ParsedCard, deleted []string, replace bool) {
+	if replace {
+		err := database.RetryOnBusy(func() error {
+			return s.store.ReplaceCards(ab.ID, cards)
+		}, 5, 100*time.Millisecond, s.log)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Failed to replace cards after retries")
+		}
+		return
+	}
+
+	err := database.RetryOnBusy(func() error {
+		return s.store.DeleteCardsByHrefs(ab.ID, deleted)
+	}, 5, 100*time.Millisecond, s.log)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to delete cards after retries")
+	}
+
+	err = database.RetryOnBusy(func() error {
+		return s.store.UpsertCardsBatch(ab.ID, cards)
+	}, 5, 100*time.Millisecond, s.log)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to batch upsert cards after retries")
+	}
+}
+
 // SyncAllSources syncs all enabled sources
 func (s *Syncer) SyncAllSources() error {
 	sources, err := s.store.ListSources()
@@ -557,3 +612,135 @@ func (s *Syncer) GetSourcesDueForSync() ([]*Source, error) {
 
 	return dueForSync, nil
 }
+
+// ============================================================================
+// Write-back
+// ============================================================================
+
+// writableAddressbook returns a card's addressbook and a client for its
+// source. Only CardDAV sources are writable; OAuth sources are read-only.
+func (s *Syncer) writableAddressbook(addressbookID string) (*Addressbook, *Client, error) {
+	ab, err := s.store.GetAddressbook(addressbookID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ab == nil {
+		return nil, nil, fmt.Errorf("addressbook not found: %s", addressbookID)
+	}
+
+	source, err := s.store.GetSource(ab.SourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if source == nil {
+		return nil, nil, fmt.Errorf("contact source not found: %s", ab.SourceID)
+	}
+	if source.Type != SourceTypeCardDAV {
+		return nil, nil, fmt.Errorf("%s contacts are read-only", source.Name)
+	}
+
+	password, err := s.credStore.GetCardDAVPassword(source.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get password: %w", err)
+	}
+
+	client, err := NewClient(source.URL, source.Username, password, s.sourceProxy(source))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	return ab, client, nil
+}
+
+// SaveCard creates or updates a card on its CardDAV server. Updates are
+// conditional on card.ETag, or the synced etag when it is empty; if the
+// server copy changed in the meantime, the server copy is stored locally and
+// returned as a conflict.
+func (s *Syncer) SaveCard(card *contact.Card) (*SaveCardResult, error) {
+	ab, client, err := s.writableAddressbook(card.AddressbookID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the edit on top of the stored vCard to keep unmodelled properties
+	var base vcard.Card
+	href, etag := card.Href, card.ETag
+	if card.ID != "" {
+		existing, vc, err := s.store.GetCard(card.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, fmt.Errorf("contact not found: %s", card.ID)
+		}
+		base, href = vc, existing.Href
+		if etag == "" {
+			etag = existing.ETag
+		}
+	}
+
+	vc := contact.ApplyCard(card, base)
+	if href == "" {
+		href = NewCardHref(ab.Path, card.UID)
+	}
+
+	saved, err := client.PutCard(ab.Path, href, etag, vc)
+	if errors.Is(err, ErrConflict) {
+		return s.cardConflict(client, ab, card, href)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.store.SaveCard(ab.ID, saved.Href, saved.ETag, saved.Card)
+	if err != nil {
+		return nil, err
+	}
+	return &SaveCardResult{Card: stored}, nil
+}
+
+// cardConflict stores the server copy of a conflicting card and reports both versions
+func (s *Syncer) cardConflict(client *Client, ab *Addressbook, local *contact.Card, href string) (*SaveCardResult, error) {
+	s.log.Info().Str("href", href).Msg("Contact changed on server, reporting conflict")
+
+	server, err := client.GetCard(ab.Path, href)
+	if errors.Is(err, ErrCardNotFound) {
+		// A new card's href was taken, or the card was deleted remotely
+		return nil, fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.store.SaveCard(ab.ID, server.Href, server.ETag, server.Card)
+	if err != nil {
+		return nil, err
+	}
+	return &SaveCardResult{Card: local, Conflict: stored}, nil
+}
+
+// DeleteCard deletes a card from its CardDAV server, provided it hasn't
+// changed since etag was synced, and then from the local store
+func (s *Syncer) DeleteCard(id, etag string) error {
+	card, _, err := s.store.GetCard(id)
+	if err != nil {
+		return err
+	}
+	if card == nil {
+		return fmt.Errorf("contact not found: %s", id)
+	}
+	if etag == "" {
+		etag = card.ETag
+	}
+
+	ab, client, err := s.writableAddressbook(card.AddressbookID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.DeleteCard(ab.Path, card.Href, etag); err != nil {
+		return err
+	}
+
+	return s.store.DeleteCard(ab.ID, card.Href)
+}
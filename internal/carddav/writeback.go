@@ -0,0 +1,183 @@
+package carddav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/carddav"
+	"github.com/hkdb/aerion/internal/netproxy"
+)
+
+var (
+	// ErrConflict is returned when the server copy of a card changed since it
+	// was last synced (HTTP 412 Precondition Failed)
+	ErrConflict = errors.New("contact was changed on the server")
+
+	// ErrCardNotFound is returned when a card no longer exists on the server
+	ErrCardNotFound = errors.New("contact no longer exists on the server")
+)
+
+// unsafeHrefChars matches characters that are not safe in a new card's resource name
+var unsafeHrefChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// conditionalClient adds If-Match / If-None-Match preconditions to write
+// requests and maps precondition failures to ErrConflict. go-webdav doesn't
+// support conditional requests itself.
+type conditionalClient struct {
+	client      webdav.HTTPClient
+	ifMatch     string
+	ifNoneMatch string
+}
+
+// Do implements webdav.HTTPClient
+func (c *conditionalClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPut || req.Method == http.MethodDelete {
+		if c.ifMatch != "" {
+			req.Header.Set("If-Match", c.ifMatch)
+		}
+		if c.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", c.ifNoneMatch)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPreconditionFailed:
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, ErrConflict
+	case http.StatusNotFound, http.StatusGone:
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, ErrCardNotFound
+	}
+	return resp, nil
+}
+
+// quoteETag formats a stored (unquoted) ETag for a precondition header
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return strconv.Quote(etag)
+}
+
+// newConditionalClient creates a CardDAV client for an addressbook whose
+// write requests carry the given preconditions
+func (c *Client) newConditionalClient(addressbookPath, ifMatch, ifNoneMatch string) (*carddav.Client, error) {
+	httpClient := &conditionalClient{
+		client: webdav.HTTPClientWithBasicAuth(
+			netproxy.HTTPClientFor(c.proxy, 60*time.Second),
+			c.username, c.password,
+		),
+		ifMatch:     ifMatch,
+		ifNoneMatch: ifNoneMatch,
+	}
+
+	client, err := carddav.NewClient(httpClient, resolveURL(c.baseURL, addressbookPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for addressbook: %w", err)
+	}
+	return client, nil
+}
+
+// NewCardHref returns the resource path for a new card in an addressbook
+func NewCardHref(addressbookPath, uid string) string {
+	name := unsafeHrefChars.ReplaceAllString(strings.TrimPrefix(uid, "urn:uuid:"), "-")
+	return strings.TrimSuffix(addressbookPath, "/") + "/" + name + ".vcf"
+}
+
+// PutCard writes a card to the server. An empty etag creates a new card and
+// fails with ErrConflict if the href already exists; otherwise the write only
+// succeeds if the server copy still has the given etag.
+func (c *Client) PutCard(addressbookPath, href, etag string, card vcard.Card) (*ParsedCard, error) {
+	ctx := context.Background()
+
+	ifMatch, ifNoneMatch := "", "*"
+	if etag != "" {
+		ifMatch, ifNoneMatch = quoteETag(etag), ""
+	}
+
+	client, err := c.newConditionalClient(addressbookPath, ifMatch, ifNoneMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := client.PutAddressObject(ctx, href, card)
+	if err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, ErrConflict
+		}
+		return nil, fmt.Errorf("failed to save contact: %w", err)
+	}
+
+	// Servers that rewrite the card (or omit the ETag) need a re-read so the
+	// stored copy matches what the server has
+	if obj.ETag == "" {
+		return c.GetCard(addressbookPath, obj.Path)
+	}
+
+	c.log.Info().Str("href", obj.Path).Msg("Contact saved to server")
+	return &ParsedCard{Href: obj.Path, ETag: obj.ETag, Card: card}, nil
+}
+
+// GetCard fetches the current server copy of a card
+func (c *Client) GetCard(addressbookPath, href string) (*ParsedCard, error) {
+	client, err := c.newConditionalClient(addressbookPath, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := client.GetAddressObject(context.Background(), href)
+	if err != nil {
+		if errors.Is(err, ErrCardNotFound) {
+			return nil, ErrCardNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch contact: %w", err)
+	}
+	if obj.Path == "" {
+		obj.Path = href
+	}
+
+	return &ParsedCard{Href: obj.Path, ETag: obj.ETag, Card: obj.Card}, nil
+}
+
+// DeleteCard deletes a card if the server copy still has the given etag.
+// Cards that are already gone are treated as deleted.
+func (c *Client) DeleteCard(addressbookPath, href, etag string) error {
+	ifMatch := ""
+	if etag != "" {
+		ifMatch = quoteETag(etag)
+	}
+
+	client, err := c.newConditionalClient(addressbookPath, ifMatch, "")
+	if err != nil {
+		return err
+	}
+
+	if err := client.RemoveAll(context.Background(), href); err != nil {
+		switch {
+		case errors.Is(err, ErrCardNotFound):
+			return nil
+		case errors.Is(err, ErrConflict):
+			return ErrConflict
+		}
+		return fmt.Errorf("failed to delete contact: %w", err)
+	}
+
+	c.log.Info().Str("href", href).Msg("Contact deleted from server")
+	return nil
+}
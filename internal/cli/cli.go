@@ -0,0 +1,437 @@
+// Package cli implements the aerion command-line interface. Most commands
+// are forwarded to the running instance over the IPC socket.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hkdb/aerion/internal/ipc"
+	"github.com/hkdb/aerion/internal/platform"
+	"github.com/hkdb/aerion/internal/profile"
+)
+
+// Request timeouts. Sending and syncing talk to mail servers and can take a while.
+const (
+	defaultTimeout = 30 * time.Second
+	sendTimeout    = 5 * time.Minute
+	syncTimeout    = 30 * time.Minute
+)
+
+// command is a CLI subcommand
+type command struct {
+	usage string
+	help  string
+	run   func(args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"send": {
+			usage: "send [--account ACCOUNT] --to ADDR [--cc ADDR] [--bcc ADDR] [--subject TEXT] [--body TEXT] [--attach FILE]",
+			help:  "Send an email (body is read from stdin when --body is omitted and stdin is not a terminal)",
+			run:   runSend,
+		},
+		"search": {
+			usage: "search [--account ACCOUNT] [--folder FOLDER] [--limit N] [--json] QUERY",
+			help:  "Search conversations in all folders, or in one folder",
+			run:   runSearch,
+		},
+		"sync": {
+			usage: "sync [ACCOUNT]",
+			help:  "Sync one account, or all accounts",
+			run:   runSync,
+		},
+		"unread-count": {
+			usage: "unread-count [--json]",
+			help:  "Show inbox unread counts",
+			run:   runUnreadCount,
+		},
+		"open": {
+			usage: "open MESSAGE-ID",
+			help:  "Show a message in the main window",
+			run:   runOpen,
+		},
+		"profile": {
+			usage: "profile list [--json] | profile create NAME | profile delete NAME",
+			help:  "Manage profiles; open one with --profile NAME",
+			run:   runProfile,
+		},
+	}
+}
+
+// IsCommand reports whether an argument names a CLI command
+func IsCommand(arg string) bool {
+	_, ok := commands[arg]
+	return ok || arg == "help"
+}
+
+// Run executes a CLI command and returns the process exit code.
+// args[0] is the command name.
+func Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "aerion: unknown command %q\n", args[0])
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	if err := cmd.run(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "aerion %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: aerion COMMAND [OPTIONS]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands (require a running Aerion instance):")
+	for _, name := range []string{"send", "search", "sync", "unread-count", "open"} {
+		fmt.Fprintf(w, "  %s\n        %s\n", commands[name].usage, commands[name].help)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Local commands:")
+	for _, name := range []string{"profile"} {
+		fmt.Fprintf(w, "  %s\n        %s\n", commands[name].usage, commands[name].help)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global options (before COMMAND):")
+	fmt.Fprintln(w, "  --profile NAME    Use a profile other than the default")
+	fmt.Fprintln(w, "  --data-dir PATH   Use a portable data directory")
+}
+
+// newFlagSet creates the flag set of a command
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: aerion %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// stringList is a repeatable flag that also accepts comma-separated values
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// addressList is a repeatable flag holding recipients. Each value is parsed as
+// an RFC 5322 address list, so quoted names may contain commas.
+type addressList []string
+
+func (l *addressList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *addressList) Set(value string) error {
+	addrs, err := mail.ParseAddressList(value)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", value, err)
+	}
+	for _, addr := range addrs {
+		*l = append(*l, addr.String())
+	}
+	return nil
+}
+
+// ============================================================================
+// Commands
+// ============================================================================
+
+func runSend(args []string) error {
+	fs := newFlagSet("send")
+	account := fs.String("account", "", "Account ID, email address or name (default: first account)")
+	subject := fs.String("subject", "", "Subject")
+	body := fs.String("body", "", "Plain text body")
+	var to, cc, bcc addressList
+	var attach stringList
+	fs.Var(&to, "to", "Recipient (repeatable)")
+	fs.Var(&cc, "cc", "Cc recipient (repeatable)")
+	fs.Var(&bcc, "bcc", "Bcc recipient (repeatable)")
+	fs.Var(&attach, "attach", "File to attach (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(to)+len(cc)+len(bcc) == 0 {
+		return fmt.Errorf("at least one of --to, --cc or --bcc is required")
+	}
+
+	payload := ipc.CLISendPayload{
+		Account: *account,
+		To:      to,
+		Cc:      cc,
+		Bcc:     bcc,
+		Subject: *subject,
+		Body:    *body,
+	}
+
+	if payload.Body == "" && stdinIsPiped() {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read body from stdin: %w", err)
+		}
+		payload.Body = string(data)
+	}
+
+	for _, path := range attach {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read attachment: %w", err)
+		}
+		contentType := mime.TypeByExtension(filepath.Ext(path))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		payload.Attachments = append(payload.Attachments, ipc.CLIAttachment{
+			Filename:    filepath.Base(path),
+			ContentType: contentType,
+			Content:     content,
+		})
+	}
+
+	if err := request(ipc.TypeCLISend, payload, sendTimeout, nil); err != nil {
+		return err
+	}
+	fmt.Println("Message sent")
+	return nil
+}
+
+func runSearch(args []string) error {
+	fs := newFlagSet("search")
+	account := fs.String("account", "", "Only search this account")
+	folder := fs.String("folder", "", "Only search this folder (path, name or type such as inbox; default: all folders)")
+	limit := fs.Int("limit", 0, "Maximum number of results (default 20)")
+	asJSON := fs.Bool("json", false, "Print results as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("search query is required")
+	}
+
+	var result ipc.CLISearchResult
+	payload := ipc.CLISearchPayload{Query: query, Account: *account, Folder: *folder, Limit: *limit}
+	if err := request(ipc.TypeCLISearch, payload, defaultTimeout, &result); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(result.Results)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range result.Results {
+		unread := ""
+		if c.UnreadCount > 0 {
+			unread = "*"
+		}
+		fmt.Fprintf(w, "%s%s\t%s\t%s\t%d\n", unread, c.Date.Local().Format("2006-01-02 15:04"), c.From, c.Subject, c.MessageCount)
+	}
+	return w.Flush()
+}
+
+func runSync(args []string) error {
+	fs := newFlagSet("sync")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("at most one account can be given")
+	}
+
+	payload := ipc.CLISyncPayload{Account: fs.Arg(0)}
+	if err := request(ipc.TypeCLISync, payload, syncTimeout, nil); err != nil {
+		return err
+	}
+	fmt.Println("Sync complete")
+	return nil
+}
+
+func runUnreadCount(args []string) error {
+	fs := newFlagSet("unread-count")
+	asJSON := fs.Bool("json", false, "Print counts as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var result ipc.CLIUnreadCountResult
+	if err := request(ipc.TypeCLIUnreadCount, nil, defaultTimeout, &result); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(result)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, acc := range result.Accounts {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", acc.Name, acc.Email, acc.Unread)
+	}
+	fmt.Fprintf(w, "Total\t\t%d\n", result.Total)
+	return w.Flush()
+}
+
+func runOpen(args []string) error {
+	fs := newFlagSet("open")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("exactly one message ID is required")
+	}
+
+	return request(ipc.TypeCLIOpen, ipc.CLIOpenPayload{MessageID: fs.Arg(0)}, defaultTimeout, nil)
+}
+
+func runProfile(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand: list, create or delete")
+	}
+
+	switch args[0] {
+	case "list":
+		fs := newFlagSet("profile")
+		asJSON := fs.Bool("json", false, "Print profiles as JSON")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		profiles, err := profile.List()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(profiles)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, p := range profiles {
+			status := ""
+			if p.Running {
+				status = "running"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, status, p.DataDir)
+		}
+		return w.Flush()
+
+	case "create", "delete":
+		if len(args) != 2 {
+			return fmt.Errorf("exactly one profile name is required")
+		}
+		if args[0] == "create" {
+			p, err := profile.Create(args[1])
+			if err != nil {
+				return err
+			}
+			open := "aerion --profile " + p.Name
+			if platform.Portable() {
+				open += " --data-dir " + platform.DataRoot()
+			}
+			fmt.Printf("Created profile %s; open it with: %s\n", p.Name, open)
+			return nil
+		}
+		if err := profile.Delete(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted profile %s\n", args[1])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown subcommand %q: use list, create or delete", args[0])
+	}
+}
+
+// ============================================================================
+// IPC
+// ============================================================================
+
+// request sends a request to the running instance and decodes the reply
+// payload into result (if not nil)
+func request(msgType string, payload interface{}, timeout time.Duration, result interface{}) error {
+	address, err := ipc.DefaultAddress()
+	if err != nil {
+		return err
+	}
+	token, err := ipc.ReadTokenFile()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client := ipc.NewClient(address)
+	if err := client.Connect(ctx, token); err != nil {
+		return fmt.Errorf("failed to connect to aerion (is it running?): %w", err)
+	}
+	defer client.Close()
+
+	msg, err := ipc.NewMessage(msgType, payload)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	reply, err := client.SendAndWait(ctx, msg)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("timed out waiting for aerion")
+		}
+		return err
+	}
+	if reply.Type == ipc.TypeError {
+		return errors.New(reply.Error)
+	}
+
+	if result != nil {
+		if err := reply.ParsePayload(result); err != nil {
+			return fmt.Errorf("failed to parse reply: %w", err)
+		}
+	}
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// stdinIsPiped reports whether stdin is a pipe or file rather than a terminal
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
@@ -0,0 +1,282 @@
+package contact
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/google/uuid"
+)
+
+// ListLocalCards returns all local address book entries, sorted by name
+func (s *Store) ListLocalCards() ([]*Card, error) {
+	rows, err := s.db.Query(`
+		SELECT id, vcard, updated_at
+		FROM local_cards
+		ORDER BY LOWER(full_name) ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local cards: %w", err)
+	}
+	defer rows.Close()
+
+	var cards []*Card
+	for rows.Next() {
+		var id, data string
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&id, &data, &updatedAt); err != nil {
+			s.log.Warn().Err(err).Msg("Failed to scan local card row")
+			continue
+		}
+		_, card, err := localCardFromRow(id, data, updatedAt)
+		if err != nil {
+			s.log.Warn().Err(err).Str("id", id).Msg("Skipping unparseable local card")
+			continue
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rows.Err()
+}
+
+// GetLocalCard returns a local address book entry by ID
+func (s *Store) GetLocalCard(id string) (*Card, error) {
+	_, card, err := s.getLocalVCard(id)
+	return card, err
+}
+
+// getLocalVCard loads a local card and its underlying vCard
+func (s *Store) getLocalVCard(id string) (vcard.Card, *Card, error) {
+	var data string
+	var updatedAt sql.NullTime
+	err := s.db.QueryRow(
+		"SELECT vcard, updated_at FROM local_cards WHERE id = ?", id,
+	).Scan(&data, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get local card: %w", err)
+	}
+
+	return localCardFromRow(id, data, updatedAt)
+}
+
+// SaveLocalCard creates or updates a local address book entry. Properties of
+// an existing entry that the card doesn't model are preserved.
+func (s *Store) SaveLocalCard(card *Card) (*Card, error) {
+	var base vcard.Card
+	if card.ID != "" {
+		existing, _, err := s.getLocalVCard(card.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, fmt.Errorf("contact not found: %s", card.ID)
+		}
+		base = existing
+	} else {
+		card.ID = uuid.New().String()
+	}
+
+	return s.saveLocalVCard(card.ID, ApplyCard(card, base))
+}
+
+// saveLocalVCard writes a local card and refreshes its email index
+func (s *Store) saveLocalVCard(id string, vc vcard.Card) (*Card, error) {
+	data, err := EncodeVCard(vc)
+	if err != nil {
+		return nil, err
+	}
+
+	card := CardFromVCard(vc)
+	card.ID = id
+	card.Source = CardSourceLocal
+	now := time.Now()
+	card.UpdatedAt = now
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO local_cards (id, uid, full_name, vcard, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			uid = excluded.uid,
+			full_name = excluded.full_name,
+			vcard = excluded.vcard,
+			updated_at = excluded.updated_at
+	`, id, card.UID, card.DisplayName(), data, now, now); err != nil {
+		return nil, fmt.Errorf("failed to save local card: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM local_card_emails WHERE card_id = ?", id); err != nil {
+		return nil, fmt.Errorf("failed to clear card emails: %w", err)
+	}
+	for _, email := range card.EmailAddresses() {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO local_card_emails (card_id, email, display_name) VALUES (?, ?, ?)",
+			id, strings.ToLower(email), card.DisplayName(),
+		); err != nil {
+			return nil, fmt.Errorf("failed to index card email: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit local card: %w", err)
+	}
+
+	s.log.Debug().Str("id", id).Str("name", card.DisplayName()).Msg("Local card saved")
+	return card, nil
+}
+
+// DeleteLocalCard removes a local address book entry
+func (s *Store) DeleteLocalCard(id string) error {
+	result, err := s.db.Exec("DELETE FROM local_cards WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete local card: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("contact not found: %s", id)
+	}
+	return nil
+}
+
+// searchLocalCards searches the email index of local address book entries
+func (s *Store) searchLocalCards(query string, limit int) ([]*Contact, error) {
+	pattern := "%" + strings.ToLower(query) + "%"
+
+	rows, err := s.db.Query(`
+		SELECT e.email, e.display_name, c.created_at
+		FROM local_card_emails e
+		JOIN local_cards c ON c.id = e.card_id
+		WHERE e.email LIKE ? OR LOWER(e.display_name) LIKE ?
+		ORDER BY e.display_name ASC
+		LIMIT ?
+	`, pattern, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search local cards: %w", err)
+	}
+	defer rows.Close()
+
+	var contacts []*Contact
+	for rows.Next() {
+		var c Contact
+		var displayName sql.NullString
+		var createdAt sql.NullTime
+		if err := rows.Scan(&c.Email, &displayName, &createdAt); err != nil {
+			continue
+		}
+		c.DisplayName = displayName.String
+		if createdAt.Valid {
+			c.CreatedAt = createdAt.Time
+		}
+		c.Source = CardSourceLocal
+		contacts = append(contacts, &c)
+	}
+
+	return contacts, nil
+}
+
+// localCardFromRow decodes a local_cards row into its vCard and Card
+func localCardFromRow(id, data string, updatedAt sql.NullTime) (vcard.Card, *Card, error) {
+	vc, err := DecodeVCard(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	card := CardFromVCard(vc)
+	card.ID = id
+	card.Source = CardSourceLocal
+	if updatedAt.Valid {
+		card.UpdatedAt = updatedAt.Time
+	}
+	return vc, card, nil
+}
+
+// ListLocalVCards returns the vCards of all local address book entries
+func (s *Store) ListLocalVCards() ([]vcard.Card, error) {
+	rows, err := s.db.Query("SELECT id, vcard FROM local_cards ORDER BY LOWER(full_name) ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local cards: %w", err)
+	}
+	defer rows.Close()
+
+	var cards []vcard.Card
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			continue
+		}
+		vc, err := DecodeVCard(data)
+		if err != nil {
+			s.log.Warn().Err(err).Str("id", id).Msg("Skipping unparseable local card")
+			continue
+		}
+		cards = append(cards, vc)
+	}
+	return cards, rows.Err()
+}
+
+// ImportVCards adds vCards to the local address book. A card whose UID
+// matches an existing entry replaces it; groups and empty cards are skipped.
+func (s *Store) ImportVCards(cards []vcard.Card) (*ImportResult, error) {
+	result := &ImportResult{}
+	for i, vc := range cards {
+		card := CardFromVCard(vc)
+		if IsGroupVCard(vc) || card.DisplayName() == "" {
+			result.Skipped++
+			continue
+		}
+
+		if vc.Value(vcard.FieldVersion) == "" {
+			vc.SetValue(vcard.FieldVersion, "3.0")
+		}
+		if card.UID == "" {
+			vc.SetValue(vcard.FieldUID, NewCardUID())
+		}
+		if vc.Value(vcard.FieldFormattedName) == "" {
+			vc.SetValue(vcard.FieldFormattedName, card.DisplayName())
+		}
+
+		var id string
+		err := s.db.QueryRow("SELECT id FROM local_cards WHERE uid = ?", vc.Value(vcard.FieldUID)).Scan(&id)
+		if err != nil && err != sql.ErrNoRows {
+			return result, fmt.Errorf("failed to look up card: %w", err)
+		}
+		existing := id != ""
+		if !existing {
+			id = uuid.New().String()
+		}
+
+		if _, err := s.saveLocalVCard(id, vc); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("contact %d (%s): %v", i+1, card.DisplayName(), err))
+			continue
+		}
+		if existing {
+			result.Updated++
+		} else {
+			result.Imported++
+		}
+	}
+
+	s.log.Info().
+		Int("imported", result.Imported).
+		Int("updated", result.Updated).
+		Int("skipped", result.Skipped).
+		Msg("Contacts imported")
+	return result, nil
+}
+
+// ImportCards adds cards parsed from a CSV export to the local address book
+func (s *Store) ImportCards(cards []*Card) (*ImportResult, error) {
+	vcards := make([]vcard.Card, 0, len(cards))
+	for _, card := range cards {
+		vcards = append(vcards, ApplyCard(card, nil))
+	}
+	return s.ImportVCards(vcards)
+}
@@ -0,0 +1,320 @@
+package contact
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/google/uuid"
+)
+
+// Card sources
+const (
+	CardSourceLocal   = "local"   // Stored only in Aerion's database
+	CardSourceCardDAV = "carddav" // Synced with a CardDAV addressbook
+)
+
+// TypedValue is a multi-valued vCard property such as an email or phone number
+type TypedValue struct {
+	Value     string `json:"value"`
+	Type      string `json:"type,omitempty"` // "home", "work", "cell", ...
+	Preferred bool   `json:"preferred"`
+}
+
+// Card is a full address book entry backed by a vCard.
+// Properties Aerion doesn't edit are preserved in the underlying vCard.
+type Card struct {
+	ID            string       `json:"id"`
+	Source        string       `json:"source"`                   // "local" or "carddav"
+	AddressbookID string       `json:"addressbook_id,omitempty"` // CardDAV addressbook
+	Href          string       `json:"href,omitempty"`           // CardDAV resource path
+	ETag          string       `json:"etag,omitempty"`           // For If-Match on write-back
+	UID           string       `json:"uid"`
+	FullName      string       `json:"full_name"`
+	GivenName     string       `json:"given_name"`
+	FamilyName    string       `json:"family_name"`
+	Nickname      string       `json:"nickname,omitempty"`
+	Emails        []TypedValue `json:"emails"`
+	Phones        []TypedValue `json:"phones"`
+	Organization  string       `json:"organization,omitempty"`
+	Title         string       `json:"title,omitempty"`
+	Birthday      string       `json:"birthday,omitempty"`
+	Notes         string       `json:"notes,omitempty"`
+	Photo         string       `json:"photo,omitempty"` // data: URI or URL
+	Groups        []string     `json:"groups"`          // CATEGORIES
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// DisplayName returns the best name to show for the card
+func (c *Card) DisplayName() string {
+	if c.FullName != "" {
+		return c.FullName
+	}
+	name := strings.TrimSpace(c.GivenName + " " + c.FamilyName)
+	if name != "" {
+		return name
+	}
+	if c.Organization != "" {
+		return c.Organization
+	}
+	if len(c.Emails) > 0 {
+		return c.Emails[0].Value
+	}
+	return ""
+}
+
+// EmailAddresses returns the card's email addresses, preferred first
+func (c *Card) EmailAddresses() []string {
+	var preferred, rest []string
+	for _, e := range c.Emails {
+		addr := strings.TrimSpace(e.Value)
+		if addr == "" {
+			continue
+		}
+		if e.Preferred {
+			preferred = append(preferred, addr)
+		} else {
+			rest = append(rest, addr)
+		}
+	}
+	return append(preferred, rest...)
+}
+
+// NewCardUID returns a UID for a newly created vCard
+func NewCardUID() string {
+	return "urn:uuid:" + uuid.New().String()
+}
+
+// DecodeVCard parses a single vCard
+func DecodeVCard(data string) (vcard.Card, error) {
+	card, err := vcard.NewDecoder(strings.NewReader(data)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vCard: %w", err)
+	}
+	return card, nil
+}
+
+// EncodeVCard serializes a vCard
+func EncodeVCard(card vcard.Card) (string, error) {
+	var buf bytes.Buffer
+	if err := vcard.NewEncoder(&buf).Encode(card); err != nil {
+		return "", fmt.Errorf("failed to encode vCard: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// CardFromVCard extracts the editable properties of a vCard
+func CardFromVCard(vc vcard.Card) *Card {
+	c := &Card{
+		UID:      vc.Value(vcard.FieldUID),
+		FullName: vc.PreferredValue(vcard.FieldFormattedName),
+		Nickname: vc.PreferredValue(vcard.FieldNickname),
+		Title:    vc.PreferredValue(vcard.FieldTitle),
+		Birthday: vc.Value(vcard.FieldBirthday),
+		Notes:    vc.Value(vcard.FieldNote),
+		Groups:   vc.Categories(),
+		Emails:   typedValues(vc, vcard.FieldEmail),
+		Phones:   typedValues(vc, vcard.FieldTelephone),
+		Photo:    photoURI(vc),
+	}
+
+	if n := vc.Name(); n != nil {
+		c.GivenName = n.GivenName
+		c.FamilyName = n.FamilyName
+	}
+	if org := vc.PreferredValue(vcard.FieldOrganization); org != "" {
+		// ORG is "Organization;Unit;..." - keep the organization name
+		c.Organization = strings.SplitN(org, ";", 2)[0]
+	}
+	if t, err := vc.Revision(); err == nil {
+		c.UpdatedAt = t
+	}
+	if c.Groups == nil {
+		c.Groups = []string{}
+	}
+
+	return c
+}
+
+// ApplyCard writes the card's editable properties onto a vCard, keeping any
+// other properties of base intact. A nil base creates a new vCard 3.0, the
+// version most widely accepted by CardDAV servers.
+func ApplyCard(c *Card, base vcard.Card) vcard.Card {
+	vc := make(vcard.Card, len(base)+4)
+	for k, fields := range base {
+		vc[k] = fields
+	}
+	if vc.Value(vcard.FieldVersion) == "" {
+		vc.SetValue(vcard.FieldVersion, "3.0")
+	}
+	v4 := strings.HasPrefix(vc.Value(vcard.FieldVersion), "4")
+
+	if c.UID == "" {
+		c.UID = vc.Value(vcard.FieldUID)
+	}
+	if c.UID == "" {
+		c.UID = NewCardUID()
+	}
+	vc.SetValue(vcard.FieldUID, c.UID)
+
+	// FN is required; fall back to the structured name or an address
+	fullName := strings.TrimSpace(c.FullName)
+	if fullName == "" {
+		fullName = c.DisplayName()
+	}
+	vc.SetValue(vcard.FieldFormattedName, fullName)
+
+	name := &vcard.Name{}
+	if existing := base.Name(); existing != nil {
+		*name = *existing
+	}
+	name.GivenName = c.GivenName
+	name.FamilyName = c.FamilyName
+	vc.SetName(name)
+
+	setOptional(vc, vcard.FieldNickname, c.Nickname)
+	setOptional(vc, vcard.FieldTitle, c.Title)
+	setOptional(vc, vcard.FieldBirthday, c.Birthday)
+	setOptional(vc, vcard.FieldNote, c.Notes)
+
+	// Keep the organizational units when only the name changed
+	org := c.Organization
+	if existing := base.PreferredValue(vcard.FieldOrganization); existing != "" && org != "" {
+		if parts := strings.SplitN(existing, ";", 2); len(parts) == 2 {
+			org = org + ";" + parts[1]
+		}
+	}
+	setOptional(vc, vcard.FieldOrganization, org)
+
+	setTypedValues(vc, vcard.FieldEmail, c.Emails, v4)
+	setTypedValues(vc, vcard.FieldTelephone, c.Phones, v4)
+	setPhoto(vc, c.Photo, v4)
+
+	if len(c.Groups) > 0 {
+		vc.SetCategories(c.Groups)
+	} else {
+		delete(vc, vcard.FieldCategories)
+	}
+
+	c.UpdatedAt = time.Now().UTC()
+	vc.SetRevision(c.UpdatedAt)
+
+	return vc
+}
+
+// setOptional sets a single-valued property, removing it when empty
+func setOptional(vc vcard.Card, field, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		delete(vc, field)
+		return
+	}
+	vc.SetValue(field, value)
+}
+
+// typedValues reads a multi-valued property with its TYPE and preference
+func typedValues(vc vcard.Card, field string) []TypedValue {
+	values := []TypedValue{}
+	for _, f := range vc[field] {
+		value := strings.TrimSpace(f.Value)
+		if value == "" {
+			continue
+		}
+		tv := TypedValue{Value: value}
+		for _, t := range f.Params.Types() {
+			t = strings.ToLower(t)
+			switch t {
+			case "pref":
+				tv.Preferred = true
+			case "internet", "voice", "x400":
+				// Not meaningful to users
+			default:
+				if tv.Type == "" {
+					tv.Type = t
+				}
+			}
+		}
+		if f.Params.Get(vcard.ParamPreferred) != "" {
+			tv.Preferred = true
+		}
+		values = append(values, tv)
+	}
+	return values
+}
+
+// setTypedValues replaces a multi-valued property
+func setTypedValues(vc vcard.Card, field string, values []TypedValue, v4 bool) {
+	delete(vc, field)
+	for _, tv := range values {
+		value := strings.TrimSpace(tv.Value)
+		if value == "" {
+			continue
+		}
+		f := &vcard.Field{Value: value, Params: vcard.Params{}}
+		if tv.Type != "" {
+			f.Params.Add(vcard.ParamType, strings.ToLower(tv.Type))
+		}
+		if tv.Preferred {
+			if v4 {
+				f.Params.Set(vcard.ParamPreferred, "1")
+			} else {
+				f.Params.Add(vcard.ParamType, "pref")
+			}
+		}
+		vc.Add(field, f)
+	}
+}
+
+// photoURI returns the PHOTO property as a data: URI or URL
+func photoURI(vc vcard.Card) string {
+	f := vc.Get(vcard.FieldPhoto)
+	if f == nil || f.Value == "" {
+		return ""
+	}
+
+	// vCard 4 and URL-valued vCard 3 photos are already URIs
+	encoding := strings.ToLower(f.Params.Get("ENCODING"))
+	if encoding != "b" && encoding != "base64" {
+		return f.Value
+	}
+
+	// vCard 3 inline photo: TYPE carries the image format
+	mediaType := "image/jpeg"
+	if t := f.Params.Get(vcard.ParamType); t != "" {
+		mediaType = "image/" + strings.ToLower(t)
+	}
+	return "data:" + mediaType + ";base64," + f.Value
+}
+
+// setPhoto writes the PHOTO property in the form the vCard version expects
+func setPhoto(vc vcard.Card, photo string, v4 bool) {
+	delete(vc, vcard.FieldPhoto)
+	photo = strings.TrimSpace(photo)
+	if photo == "" {
+		return
+	}
+
+	if v4 || !strings.HasPrefix(photo, "data:") {
+		f := &vcard.Field{Value: photo, Params: vcard.Params{}}
+		if !v4 {
+			f.Params.Set(vcard.ParamValue, "uri")
+		}
+		vc.Add(vcard.FieldPhoto, f)
+		return
+	}
+
+	// data:image/png;base64,XXXX -> ENCODING=b;TYPE=PNG:XXXX
+	meta, data, ok := strings.Cut(strings.TrimPrefix(photo, "data:"), ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return
+	}
+	imageType := strings.TrimPrefix(strings.TrimSuffix(meta, ";base64"), "image/")
+	f := &vcard.Field{Value: data, Params: vcard.Params{}}
+	f.Params.Set("ENCODING", "b")
+	if imageType != "" {
+		f.Params.Set(vcard.ParamType, strings.ToUpper(imageType))
+	}
+	vc.Add(vcard.FieldPhoto, f)
+}
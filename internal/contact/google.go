@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/rs/zerolog"
 )
 
@@ -33,7 +34,7 @@ type cachedGoogleResult struct {
 // Results are cached for 15 minutes by default.
 func NewGoogleContactsClient() *GoogleContactsClient {
 	return &GoogleContactsClient{
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+		httpClient: netproxy.HTTPClient(10 * time.Second),
 		cache:      make(map[string]cachedGoogleResult),
 		cacheTTL:   15 * time.Minute,
 		log:        logging.WithComponent("google-contacts"),
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/rs/zerolog"
 )
 
@@ -16,7 +17,14 @@ import (
 type SyncedContact struct {
 	Email       string
 	DisplayName string
-	RemoteID    string // Provider-specific ID for change detection
+	RemoteID    string   // Provider-specific ID for change detection
+	GroupIDs    []string // Remote IDs of the groups the contact belongs to
+}
+
+// SyncedGroup represents a contact group fetched from a sync source (Google)
+type SyncedGroup struct {
+	RemoteID string // e.g., "contactGroups/abc123"
+	Name     string
 }
 
 // SyncResult represents the result of an incremental sync
@@ -25,6 +33,7 @@ type SyncResult struct {
 	DeletedIDs    []string        // Remote IDs of deleted contacts
 	NextSyncToken string          // Token for next incremental sync
 	IsFullSync    bool            // True if this was a full sync (no valid token)
+	Groups        []SyncedGroup   // All contact groups (nil if the source has none or they weren't fetched)
 }
 
 // GoogleContactsSyncer syncs contacts from Google People API.
@@ -34,10 +43,11 @@ type GoogleContactsSyncer struct {
 	log        zerolog.Logger
 }
 
-// NewGoogleContactsSyncer creates a new Google contacts syncer.
-func NewGoogleContactsSyncer() *GoogleContactsSyncer {
+// NewGoogleContactsSyncer creates a new Google contacts syncer connecting
+// through proxy (nil uses the global proxy).
+func NewGoogleContactsSyncer(proxy *netproxy.Config) *GoogleContactsSyncer {
 	return &GoogleContactsSyncer{
-		httpClient: &http.Client{Timeout: 60 * time.Second}, // Longer timeout for sync
+		httpClient: netproxy.HTTPClientFor(proxy, 60*time.Second), // Longer timeout for sync
 		log:        logging.WithComponent("google-contacts-sync"),
 	}
 }
@@ -71,7 +81,7 @@ func (s *GoogleContactsSyncer) SyncContactsDelta(accessToken, syncToken string)
 
 	for {
 		// Build API URL with pagination and sync token
-		apiURL := "https://people.googleapis.com/v1/people/me/connections?personFields=names,emailAddresses&pageSize=1000"
+		apiURL := "https://people.googleapis.com/v1/people/me/connections?personFields=names,emailAddresses,memberships&pageSize=1000"
 		if pageToken != "" {
 			apiURL += "&pageToken=" + pageToken
 		}
@@ -168,6 +178,13 @@ func (s *GoogleContactsSyncer) SyncContactsDelta(accessToken, syncToken string)
 				name = conn.Names[0].DisplayName
 			}
 
+			var groupIDs []string
+			for _, m := range conn.Memberships {
+				if m.ContactGroupMembership != nil && m.ContactGroupMembership.ContactGroupResourceName != "" {
+					groupIDs = append(groupIDs, m.ContactGroupMembership.ContactGroupResourceName)
+				}
+			}
+
 			// Create one contact entry per email address
 			for _, email := range conn.EmailAddresses {
 				if email.Value == "" {
@@ -177,6 +194,7 @@ func (s *GoogleContactsSyncer) SyncContactsDelta(accessToken, syncToken string)
 					Email:       email.Value,
 					DisplayName: name,
 					RemoteID:    conn.ResourceName, // e.g., "people/c12345"
+					GroupIDs:    groupIDs,
 				})
 			}
 		}
@@ -197,6 +215,14 @@ func (s *GoogleContactsSyncer) SyncContactsDelta(accessToken, syncToken string)
 				IsFullSync:    isFullSync,
 			}
 
+			// Group names are few and have no sync token, so fetch them every time
+			groups, err := s.fetchContactGroups(accessToken)
+			if err != nil {
+				s.log.Warn().Err(err).Msg("Failed to fetch Google contact groups")
+			} else {
+				syncResult.Groups = groups
+			}
+
 			if isFullSync {
 				s.log.Info().
 					Int("total_contacts", len(allContacts)).
@@ -215,6 +241,60 @@ func (s *GoogleContactsSyncer) SyncContactsDelta(accessToken, syncToken string)
 	}
 }
 
+// fetchContactGroups fetches the user's own contact groups (labels).
+// System groups such as "myContacts" and "starred" are skipped.
+func (s *GoogleContactsSyncer) fetchContactGroups(accessToken string) ([]SyncedGroup, error) {
+	groups := []SyncedGroup{}
+	pageToken := ""
+
+	for {
+		apiURL := "https://people.googleapis.com/v1/contactGroups?pageSize=1000"
+		if pageToken != "" {
+			apiURL += "&pageToken=" + pageToken
+		}
+
+		req, err := http.NewRequest("GET", apiURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("Google contact groups request failed: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("Google contact groups error %d: %s", resp.StatusCode, string(bodyBytes))
+		}
+
+		var result googleContactGroupsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to parse Google contact groups response: %w", err)
+		}
+		resp.Body.Close()
+
+		for _, g := range result.ContactGroups {
+			if g.GroupType != "USER_CONTACT_GROUP" {
+				continue
+			}
+			name := g.FormattedName
+			if name == "" {
+				name = g.Name
+			}
+			groups = append(groups, SyncedGroup{RemoteID: g.ResourceName, Name: name})
+		}
+
+		if result.NextPageToken == "" {
+			return groups, nil
+		}
+		pageToken = result.NextPageToken
+	}
+}
+
 // Google People API connections response structures
 
 type googleConnectionsResponse struct {
@@ -229,9 +309,32 @@ type googleConnection struct {
 	ResourceName   string                    `json:"resourceName"` // e.g., "people/c12345"
 	Names          []googleName              `json:"names"`
 	EmailAddresses []googleEmail             `json:"emailAddresses"`
+	Memberships    []googleMembership        `json:"memberships"`
 	Metadata       *googleConnectionMetadata `json:"metadata,omitempty"` // For detecting deleted contacts
 }
 
+type googleMembership struct {
+	ContactGroupMembership *googleContactGroupMembership `json:"contactGroupMembership,omitempty"`
+}
+
+type googleContactGroupMembership struct {
+	ContactGroupResourceName string `json:"contactGroupResourceName"` // e.g., "contactGroups/abc123"
+}
+
+// Google People API contact groups response structures
+
+type googleContactGroupsResponse struct {
+	ContactGroups []googleContactGroup `json:"contactGroups"`
+	NextPageToken string               `json:"nextPageToken"`
+}
+
+type googleContactGroup struct {
+	ResourceName  string `json:"resourceName"`
+	Name          string `json:"name"`
+	FormattedName string `json:"formattedName"`
+	GroupType     string `json:"groupType"` // "USER_CONTACT_GROUP" or "SYSTEM_CONTACT_GROUP"
+}
+
 type googleConnectionMetadata struct {
 	Deleted bool `json:"deleted"` // True if contact was deleted (in incremental sync)
 }
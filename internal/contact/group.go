@@ -0,0 +1,286 @@
+package contact
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/google/uuid"
+)
+
+// Group sources
+const (
+	GroupSourceLocal   = "local"   // Created in Aerion
+	GroupSourceCardDAV = "carddav" // KIND:group or Apple group vCard
+	GroupSourceGoogle  = "google"  // Google contact group
+)
+
+// Apple Contacts / iCloud represent groups with these vCard 3 extensions
+const (
+	fieldAppleKind   = "X-ADDRESSBOOKSERVER-KIND"
+	fieldAppleMember = "X-ADDRESSBOOKSERVER-MEMBER"
+)
+
+// GroupSearchFunc is a function type for searching synced contact groups
+// This avoids circular imports with the carddav package
+type GroupSearchFunc func(query string, limit int) ([]*Group, error)
+
+// GroupMember is an address that belongs to a group
+type GroupMember struct {
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+}
+
+// Group is a named list of recipients that expands to its members in compose
+type Group struct {
+	ID        string        `json:"id"`
+	Source    string        `json:"source"` // "local", "carddav", "google"
+	Name      string        `json:"name"`
+	Members   []GroupMember `json:"members"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// AsContact returns the group as an autocomplete entry that expands to its members
+func (g *Group) AsContact() *Contact {
+	members := make([]*Contact, 0, len(g.Members))
+	for _, m := range g.Members {
+		members = append(members, &Contact{
+			Email:       m.Email,
+			DisplayName: m.DisplayName,
+			Source:      g.Source,
+		})
+	}
+	return &Contact{
+		DisplayName: g.Name,
+		Source:      g.Source,
+		GroupID:     g.ID,
+		Members:     members,
+	}
+}
+
+// IsGroupVCard reports whether a vCard describes a group rather than a person
+func IsGroupVCard(vc vcard.Card) bool {
+	if strings.EqualFold(vc.Value(vcard.FieldKind), string(vcard.KindGroup)) {
+		return true
+	}
+	return strings.EqualFold(vc.Value(fieldAppleKind), "group")
+}
+
+// GroupMemberRefs returns the member references of a group vCard: UIDs of
+// member cards (with any urn:uuid: prefix removed) and direct email addresses
+func GroupMemberRefs(vc vcard.Card) (uids, emails []string) {
+	for _, field := range []string{vcard.FieldMember, fieldAppleMember} {
+		for _, f := range vc[field] {
+			ref := strings.TrimSpace(f.Value)
+			lower := strings.ToLower(ref)
+			switch {
+			case strings.HasPrefix(lower, "mailto:"):
+				emails = append(emails, ref[len("mailto:"):])
+			case strings.HasPrefix(lower, "urn:uuid:"):
+				uids = append(uids, ref[len("urn:uuid:"):])
+			case ref != "":
+				uids = append(uids, ref)
+			}
+		}
+	}
+	return uids, emails
+}
+
+// ============================================================================
+// Local groups
+// ============================================================================
+
+// ListLocalGroups returns all local groups with their members
+func (s *Store) ListLocalGroups() ([]*Group, error) {
+	return s.queryLocalGroups("SELECT id, name, updated_at FROM local_groups ORDER BY LOWER(name) ASC")
+}
+
+// GetLocalGroup returns a local group by ID
+func (s *Store) GetLocalGroup(id string) (*Group, error) {
+	groups, err := s.queryLocalGroups("SELECT id, name, updated_at FROM local_groups WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	return groups[0], nil
+}
+
+// GetGroup returns a local or synced group by source and ID, or nil if
+// there is no such group
+func (s *Store) GetGroup(source, id string) (*Group, error) {
+	if source == GroupSourceLocal {
+		return s.GetLocalGroup(id)
+	}
+	if s.groupSearchFn == nil {
+		return nil, nil
+	}
+	synced, err := s.groupSearchFn("", -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range synced {
+		if g.ID == id {
+			return g, nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveLocalGroup creates or updates a local group and replaces its members
+func (s *Store) SaveLocalGroup(group *Group) (*Group, error) {
+	group.Name = strings.TrimSpace(group.Name)
+	if group.Name == "" {
+		return nil, fmt.Errorf("group name cannot be empty")
+	}
+	if group.ID == "" {
+		group.ID = uuid.New().String()
+	}
+	group.Source = GroupSourceLocal
+	group.UpdatedAt = time.Now()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO local_groups (id, name, created_at, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			updated_at = excluded.updated_at
+	`, group.ID, group.Name, group.UpdatedAt, group.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save group: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM local_group_members WHERE group_id = ?", group.ID); err != nil {
+		return nil, fmt.Errorf("failed to clear group members: %w", err)
+	}
+	for _, m := range group.Members {
+		email := strings.ToLower(strings.TrimSpace(m.Email))
+		if email == "" {
+			continue
+		}
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO local_group_members (group_id, email, display_name) VALUES (?, ?, ?)",
+			group.ID, email, strings.TrimSpace(m.DisplayName),
+		); err != nil {
+			return nil, fmt.Errorf("failed to add group member: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit group: %w", err)
+	}
+
+	s.log.Debug().Str("id", group.ID).Str("name", group.Name).Msg("Local group saved")
+	return s.GetLocalGroup(group.ID)
+}
+
+// DeleteLocalGroup removes a local group
+func (s *Store) DeleteLocalGroup(id string) error {
+	result, err := s.db.Exec("DELETE FROM local_groups WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("group not found: %s", id)
+	}
+	return nil
+}
+
+// SearchGroups searches local and synced groups by name
+func (s *Store) SearchGroups(query string, limit int) ([]*Group, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	groups, err := s.queryLocalGroups(`
+		SELECT id, name, updated_at FROM local_groups
+		WHERE LOWER(name) LIKE ?
+		ORDER BY LOWER(name) ASC
+		LIMIT ?
+	`, "%"+strings.ToLower(query)+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.groupSearchFn != nil {
+		synced, err := s.groupSearchFn(query, limit)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Failed to search synced groups")
+		} else {
+			groups = append(groups, synced...)
+		}
+	}
+
+	// Empty groups can't be expanded into recipients
+	result := groups[:0]
+	for _, g := range groups {
+		if len(g.Members) > 0 {
+			result = append(result, g)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// queryLocalGroups loads local groups and their members
+func (s *Store) queryLocalGroups(query string, args ...interface{}) ([]*Group, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query groups: %w", err)
+	}
+
+	var groups []*Group
+	for rows.Next() {
+		g := &Group{Source: GroupSourceLocal, Members: []GroupMember{}}
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&g.ID, &g.Name, &updatedAt); err != nil {
+			s.log.Warn().Err(err).Msg("Failed to scan group row")
+			continue
+		}
+		if updatedAt.Valid {
+			g.UpdatedAt = updatedAt.Time
+		}
+		groups = append(groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query groups: %w", err)
+	}
+
+	for _, g := range groups {
+		members, err := s.db.Query(`
+			SELECT email, display_name FROM local_group_members
+			WHERE group_id = ?
+			ORDER BY LOWER(COALESCE(NULLIF(display_name, ''), email)) ASC
+		`, g.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query group members: %w", err)
+		}
+		for members.Next() {
+			var m GroupMember
+			var displayName sql.NullString
+			if err := members.Scan(&m.Email, &displayName); err != nil {
+				continue
+			}
+			m.DisplayName = displayName.String
+			g.Members = append(g.Members, m)
+		}
+		members.Close()
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,321 @@
+package contact
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MergeSourceSentHistory identifies recipients remembered from sent mail
+const MergeSourceSentHistory = "sent-history"
+
+// MergeEntry is one contact record taking part in duplicate detection
+type MergeEntry struct {
+	Source      string   `json:"source"` // "local", "carddav", "google", "microsoft", "sent-history"
+	ID          string   `json:"id"`     // Card ID, or the email for sent history
+	DisplayName string   `json:"display_name"`
+	Emails      []string `json:"emails"`
+	Card        *Card    `json:"card,omitempty"` // Full card for address book entries
+	ReadOnly    bool     `json:"read_only"`      // Can't be edited or deleted by a merge
+}
+
+// DuplicateGroup is a set of entries that share at least one email address
+type DuplicateGroup struct {
+	Emails  []string      `json:"emails"`
+	Entries []*MergeEntry `json:"entries"`
+}
+
+// MergePreview shows the outcome of merging a duplicate group
+type MergePreview struct {
+	Merged  *Card         `json:"merged"`
+	Target  *MergeEntry   `json:"target,omitempty"` // Entry that receives the merged card (nil: new local contact)
+	Removed []*MergeEntry `json:"removed"`          // Address book entries deleted by the merge
+	Kept    []*MergeEntry `json:"kept"`             // Read-only and sent-history entries left as they are
+}
+
+// FindDuplicates groups entries that share an email address. Entries are
+// linked transitively: A and C are duplicates if both share an address with B.
+// Sent-history entries are only listed in groups that have at least two
+// address book or synced entries; a contact the user has mailed isn't a
+// duplicate of its own history.
+func FindDuplicates(entries []*MergeEntry) []*DuplicateGroup {
+	parent := make([]int, len(entries))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	owner := make(map[string]int)
+	for i, e := range entries {
+		for _, email := range e.Emails {
+			key := strings.ToLower(strings.TrimSpace(email))
+			if key == "" {
+				continue
+			}
+			if j, ok := owner[key]; ok {
+				parent[find(i)] = find(j)
+			} else {
+				owner[key] = i
+			}
+		}
+	}
+
+	byRoot := make(map[int]*DuplicateGroup)
+	var roots []int
+	for i, e := range entries {
+		root := find(i)
+		group, ok := byRoot[root]
+		if !ok {
+			group = &DuplicateGroup{}
+			byRoot[root] = group
+			roots = append(roots, root)
+		}
+		group.Entries = append(group.Entries, e)
+	}
+
+	var groups []*DuplicateGroup
+	for _, root := range roots {
+		group := byRoot[root]
+		contacts := 0
+		for _, e := range group.Entries {
+			if e.Source != MergeSourceSentHistory {
+				contacts++
+			}
+		}
+		if contacts < 2 {
+			continue
+		}
+		group.Emails = sharedEmails(group.Entries)
+		groups = append(groups, group)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Emails[0] < groups[j].Emails[0]
+	})
+	return groups
+}
+
+// sharedEmails returns the addresses that appear in more than one entry
+func sharedEmails(entries []*MergeEntry) []string {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		seen := make(map[string]bool)
+		for _, email := range e.Emails {
+			key := strings.ToLower(strings.TrimSpace(email))
+			if key != "" && !seen[key] {
+				seen[key] = true
+				counts[key]++
+			}
+		}
+	}
+
+	var shared []string
+	for email, n := range counts {
+		if n > 1 {
+			shared = append(shared, email)
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
+
+// PreviewMerge computes the merged card for a set of duplicate entries. The
+// first editable address book entry is kept and receives the merged card;
+// other editable entries are removed. Without an editable entry the merge
+// creates a new local contact.
+func PreviewMerge(entries []*MergeEntry) (*MergePreview, error) {
+	if len(entries) < 2 {
+		return nil, fmt.Errorf("at least two contacts are required to merge")
+	}
+
+	preview := &MergePreview{Removed: []*MergeEntry{}, Kept: []*MergeEntry{}}
+	var cards []*Card
+	for _, e := range entries {
+		switch {
+		case e.ReadOnly || e.Card == nil:
+			preview.Kept = append(preview.Kept, e)
+		case preview.Target == nil:
+			preview.Target = e
+		default:
+			preview.Removed = append(preview.Removed, e)
+		}
+		cards = append(cards, entryCard(e))
+	}
+
+	// The target's own properties take precedence
+	if preview.Target != nil {
+		cards = append([]*Card{preview.Target.Card}, cards...)
+	}
+	preview.Merged = MergeCards(cards...)
+
+	if preview.Target != nil {
+		preview.Merged.ID = preview.Target.Card.ID
+		preview.Merged.Source = preview.Target.Card.Source
+		preview.Merged.AddressbookID = preview.Target.Card.AddressbookID
+		preview.Merged.Href = preview.Target.Card.Href
+		preview.Merged.ETag = preview.Target.Card.ETag
+		preview.Merged.UID = preview.Target.Card.UID
+	} else {
+		preview.Merged.Source = CardSourceLocal
+	}
+	return preview, nil
+}
+
+// entryCard returns the card of an entry, or a minimal card for entries
+// without one (sent history, OAuth contacts)
+func entryCard(e *MergeEntry) *Card {
+	if e.Card != nil {
+		return e.Card
+	}
+	c := &Card{FullName: e.DisplayName}
+	for _, email := range e.Emails {
+		c.Emails = append(c.Emails, TypedValue{Value: email})
+	}
+	return c
+}
+
+// MergeCards combines cards: single-valued properties come from the first
+// card that has them, emails, phones and groups are unioned, and differing
+// notes are concatenated
+func MergeCards(cards ...*Card) *Card {
+	merged := &Card{Emails: []TypedValue{}, Phones: []TypedValue{}, Groups: []string{}}
+	seenEmails := make(map[string]bool)
+	seenPhones := make(map[string]bool)
+	seenGroups := make(map[string]bool)
+	var notes []string
+
+	first := func(dst *string, v string) {
+		if *dst == "" {
+			*dst = strings.TrimSpace(v)
+		}
+	}
+
+	for _, c := range cards {
+		if c == nil {
+			continue
+		}
+		first(&merged.FullName, c.FullName)
+		first(&merged.GivenName, c.GivenName)
+		first(&merged.FamilyName, c.FamilyName)
+		first(&merged.Nickname, c.Nickname)
+		first(&merged.Organization, c.Organization)
+		first(&merged.Title, c.Title)
+		first(&merged.Birthday, c.Birthday)
+		first(&merged.Photo, c.Photo)
+
+		for _, e := range c.Emails {
+			key := strings.ToLower(strings.TrimSpace(e.Value))
+			if key == "" || seenEmails[key] {
+				continue
+			}
+			seenEmails[key] = true
+			merged.Emails = append(merged.Emails, e)
+		}
+		for _, p := range c.Phones {
+			key := normalizePhone(p.Value)
+			if key == "" || seenPhones[key] {
+				continue
+			}
+			seenPhones[key] = true
+			merged.Phones = append(merged.Phones, p)
+		}
+		for _, g := range c.Groups {
+			key := strings.ToLower(strings.TrimSpace(g))
+			if key == "" || seenGroups[key] {
+				continue
+			}
+			seenGroups[key] = true
+			merged.Groups = append(merged.Groups, g)
+		}
+		if n := strings.TrimSpace(c.Notes); n != "" && !containsString(notes, n) {
+			notes = append(notes, n)
+		}
+	}
+
+	merged.Notes = strings.Join(notes, "\n\n")
+	keepOnePreferred(merged.Emails)
+	keepOnePreferred(merged.Phones)
+	return merged
+}
+
+// keepOnePreferred clears the preference of all but the first preferred value
+func keepOnePreferred(values []TypedValue) {
+	found := false
+	for i := range values {
+		if values[i].Preferred {
+			if found {
+				values[i].Preferred = false
+			}
+			found = true
+		}
+	}
+}
+
+// normalizePhone reduces a phone number to its digits (and a leading +) for comparison
+func normalizePhone(phone string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		if (r >= '0' && r <= '9') || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ListMergeEntries returns the local address book entries and sent-history
+// recipients as merge entries
+func (s *Store) ListMergeEntries() ([]*MergeEntry, error) {
+	cards, err := s.ListLocalCards()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*MergeEntry
+	for _, card := range cards {
+		entries = append(entries, CardMergeEntry(card, false))
+	}
+
+	history, err := s.List(0)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range history {
+		entries = append(entries, &MergeEntry{
+			Source:      MergeSourceSentHistory,
+			ID:          c.Email,
+			DisplayName: c.DisplayName,
+			Emails:      []string{c.Email},
+			ReadOnly:    true,
+		})
+	}
+
+	return entries, nil
+}
+
+// CardMergeEntry wraps an address book card as a merge entry
+func CardMergeEntry(card *Card, readOnly bool) *MergeEntry {
+	return &MergeEntry{
+		Source:      card.Source,
+		ID:          card.ID,
+		DisplayName: card.DisplayName(),
+		Emails:      card.EmailAddresses(),
+		Card:        card,
+		ReadOnly:    readOnly,
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/rs/zerolog"
 )
 
@@ -19,10 +20,11 @@ type MicrosoftContactsSyncer struct {
 	log        zerolog.Logger
 }
 
-// NewMicrosoftContactsSyncer creates a new Microsoft contacts syncer.
-func NewMicrosoftContactsSyncer() *MicrosoftContactsSyncer {
+// NewMicrosoftContactsSyncer creates a new Microsoft contacts syncer
+// connecting through proxy (nil uses the global proxy).
+func NewMicrosoftContactsSyncer(proxy *netproxy.Config) *MicrosoftContactsSyncer {
 	return &MicrosoftContactsSyncer{
-		httpClient: &http.Client{Timeout: 60 * time.Second}, // Longer timeout for sync
+		httpClient: netproxy.HTTPClientFor(proxy, 60*time.Second), // Longer timeout for sync
 		log:        logging.WithComponent("microsoft-contacts-sync"),
 	}
 }
@@ -7,11 +7,15 @@ import "time"
 type Contact struct {
 	Email       string    `json:"email"`
 	DisplayName string    `json:"display_name"`
-	Source      string    `json:"source"` // "aerion", "google", "vcard", "sent-history"
+	Source      string    `json:"source"` // "local", "aerion", "google", "vcard", "sent-history"
 	AvatarURL   string    `json:"avatar_url,omitempty"`
 	SendCount   int       `json:"send_count"` // Number of times user sent to this address
 	LastUsed    time.Time `json:"last_used"`  // Last time this contact was used
 	CreatedAt   time.Time `json:"created_at"`
+
+	// Group entries have no email of their own and expand to Members
+	GroupID string     `json:"group_id,omitempty"`
+	Members []*Contact `json:"members,omitempty"`
 }
 
 // LocalContact represents a contact stored in Aerion's local database
@@ -21,6 +21,7 @@ type Store struct {
 	db              *sql.DB
 	vcardScanner    *VCardScanner
 	carddavSearchFn CardDAVSearchFunc
+	groupSearchFn   GroupSearchFunc
 	log             zerolog.Logger
 }
 
@@ -49,6 +50,11 @@ func (s *Store) SetCardDAVSearchFunc(fn CardDAVSearchFunc) {
 	s.carddavSearchFn = fn
 }
 
+// SetGroupSearchFunc sets the search function for synced contact groups
+func (s *Store) SetGroupSearchFunc(fn GroupSearchFunc) {
+	s.groupSearchFn = fn
+}
+
 // ensureTable creates the contacts table if it doesn't exist
 func (s *Store) ensureTable() error {
 	query := `
@@ -70,10 +76,12 @@ func (s *Store) ensureTable() error {
 
 // Search searches for contacts matching the query
 // Results are merged from multiple sources:
+// - Local address book (local - contacts created in Aerion)
 // - Local SQLite (aerion - sent recipients)
 // - vCard files (local .vcf files)
 // - CardDAV (synced from servers)
-// Ranked by: send count > recency > source priority (aerion > vcard > carddav > google)
+// Ranked by: send count > recency > source priority (local > aerion > vcard > carddav > google)
+// Groups whose name matches are listed first and expand to their members.
 func (s *Store) Search(query string, limit int) ([]*Contact, error) {
 	if limit <= 0 {
 		limit = 10
@@ -86,6 +94,13 @@ func (s *Store) Search(query string, limit int) ([]*Contact, error) {
 		aerionContacts = []*Contact{}
 	}
 
+	// Local address book entries
+	localCards, err := s.searchLocalCards(query, limit)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to search local address book")
+		localCards = []*Contact{}
+	}
+
 	// 2. Query vCard cache
 	var vcardContacts []*Contact
 	if s.vcardScanner != nil {
@@ -112,11 +127,24 @@ func (s *Store) Search(query string, limit int) ([]*Contact, error) {
 		}
 	}
 
-	// 4. Merge results (priority order: aerion > vcard > carddav)
+	// 4. Merge results (priority order: local > aerion > vcard > carddav)
 	// MergeResults handles deduplication by email
-	merged := MergeResults(aerionContacts, vcardContacts, carddavContacts)
+	merged := MergeResults(localCards, aerionContacts, vcardContacts, carddavContacts)
+
+	// 5. Prepend matching groups
+	groups, err := s.SearchGroups(query, limit)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to search contact groups")
+	}
+	if len(groups) > 0 {
+		entries := make([]*Contact, 0, len(groups)+len(merged))
+		for _, g := range groups {
+			entries = append(entries, g.AsContact())
+		}
+		merged = append(entries, merged...)
+	}
 
-	// 5. Apply limit
+	// 6. Apply limit
 	if len(merged) > limit {
 		merged = merged[:limit]
 	}
@@ -360,9 +388,11 @@ func MergeResults(sources ...[]*Contact) []*Contact {
 
 // sourcePriority returns the priority of a contact source
 // Higher is better
-// Priority order: aerion > vcard > carddav > google
+// Priority order: local > aerion > vcard > carddav > google
 func sourcePriority(source string) int {
 	switch source {
+	case CardSourceLocal:
+		return 5
 	case "aerion":
 		return 4
 	case "vcard":
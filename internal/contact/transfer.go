@@ -0,0 +1,371 @@
+package contact
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+)
+
+// Export formats
+const (
+	ExportFormatVCard3 = "vcard3"
+	ExportFormatVCard4 = "vcard4"
+	ExportFormatCSV    = "csv" // Google Contacts CSV, also accepted by Outlook
+)
+
+// ImportResult summarizes a contact import
+type ImportResult struct {
+	Imported int      `json:"imported"` // New contacts
+	Updated  int      `json:"updated"`  // Existing contacts (same UID) replaced
+	Skipped  int      `json:"skipped"`  // Empty entries and groups
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// ParseVCards parses every vCard in a .vcf file
+func ParseVCards(r io.Reader) ([]vcard.Card, error) {
+	dec := vcard.NewDecoder(r)
+	var cards []vcard.Card
+	for {
+		card, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return cards, fmt.Errorf("failed to parse vCard %d: %w", len(cards)+1, err)
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// ConvertVCard returns a copy of a vCard converted to the given version
+// ("3.0" or "4.0"), rewriting the properties whose syntax differs
+func ConvertVCard(vc vcard.Card, version string) vcard.Card {
+	out := make(vcard.Card, len(vc))
+	for k, fields := range vc {
+		out[k] = fields
+	}
+	out.SetValue(vcard.FieldVersion, version)
+	v4 := strings.HasPrefix(version, "4")
+
+	setTypedValues(out, vcard.FieldEmail, typedValues(vc, vcard.FieldEmail), v4)
+	setTypedValues(out, vcard.FieldTelephone, typedValues(vc, vcard.FieldTelephone), v4)
+	setPhoto(out, photoURI(vc), v4)
+
+	// N is required in vCard 3
+	if !v4 && out.Name() == nil {
+		out.SetName(&vcard.Name{})
+	}
+	return out
+}
+
+// ExportCards serializes vCards in one of the export formats
+func ExportCards(cards []vcard.Card, format string) (string, error) {
+	switch format {
+	case ExportFormatVCard3, ExportFormatVCard4:
+		version := "3.0"
+		if format == ExportFormatVCard4 {
+			version = "4.0"
+		}
+		var buf bytes.Buffer
+		enc := vcard.NewEncoder(&buf)
+		for _, vc := range cards {
+			if err := enc.Encode(ConvertVCard(vc, version)); err != nil {
+				return "", fmt.Errorf("failed to encode vCard: %w", err)
+			}
+		}
+		return buf.String(), nil
+	case ExportFormatCSV:
+		var parsed []*Card
+		for _, vc := range cards {
+			if IsGroupVCard(vc) {
+				continue
+			}
+			parsed = append(parsed, CardFromVCard(vc))
+		}
+		return EncodeContactsCSV(parsed)
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ============================================================================
+// CSV
+// ============================================================================
+
+// Google CSV columns are numbered ("E-mail 1 - Value"); Google's current
+// export uses "Label" where older exports used "Type"
+var (
+	csvEmailColumn = regexp.MustCompile(`^e-mail (\d+) - (type|label|value)$`)
+	csvPhoneColumn = regexp.MustCompile(`^phone (\d+) - (type|label|value)$`)
+)
+
+// Outlook CSV uses fixed columns per address and phone type
+var (
+	outlookEmailColumns = []string{"e-mail address", "e-mail 2 address", "e-mail 3 address"}
+	outlookPhoneColumns = map[string]string{
+		"primary phone":      "",
+		"mobile phone":       "cell",
+		"home phone":         "home",
+		"home phone 2":       "home",
+		"business phone":     "work",
+		"business phone 2":   "work",
+		"company main phone": "work",
+		"business fax":       "fax",
+		"home fax":           "fax",
+		"pager":              "pager",
+		"other phone":        "",
+	}
+)
+
+// ParseContactsCSV parses a contacts CSV export from Google Contacts or Outlook
+func ParseContactsCSV(r io.Reader) ([]*Card, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, exists := columns[name]; !exists {
+			columns[name] = i
+		}
+	}
+	if !hasAnyColumn(columns, "name", "given name", "first name", "e-mail address", "e-mail 1 - value") {
+		return nil, errors.New("unrecognized CSV format: expected a Google or Outlook contacts export")
+	}
+
+	var cards []*Card
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return cards, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+		if card := cardFromCSVRecord(header, columns, record); card != nil {
+			cards = append(cards, card)
+		}
+	}
+	return cards, nil
+}
+
+// cardFromCSVRecord maps one CSV row to a card; empty rows return nil
+func cardFromCSVRecord(header []string, columns map[string]int, record []string) *Card {
+	get := func(names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) {
+				if v := strings.TrimSpace(record[i]); v != "" {
+					return v
+				}
+			}
+		}
+		return ""
+	}
+
+	c := &Card{
+		FullName:     get("name"),
+		GivenName:    get("given name", "first name"),
+		FamilyName:   get("family name", "last name"),
+		Nickname:     get("nickname"),
+		Birthday:     get("birthday"),
+		Notes:        get("notes"),
+		Organization: get("organization 1 - name", "organization name", "company"),
+		Title:        get("organization 1 - title", "organization title", "job title"),
+		Emails:       []TypedValue{},
+		Phones:       []TypedValue{},
+		Groups:       []string{},
+	}
+	if c.FullName == "" {
+		parts := []string{c.GivenName, get("additional name", "middle name"), c.FamilyName}
+		c.FullName = strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+	}
+
+	// Numbered Google columns
+	emailTypes, emailValues := map[int]string{}, map[int]string{}
+	phoneTypes, phoneValues := map[int]string{}, map[int]string{}
+	for i, name := range header {
+		if i >= len(record) {
+			break
+		}
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		value := strings.TrimSpace(record[i])
+		if m := csvEmailColumn.FindStringSubmatch(name); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			if m[2] == "value" {
+				emailValues[n] = value
+			} else {
+				emailTypes[n] = value
+			}
+		} else if m := csvPhoneColumn.FindStringSubmatch(name); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			if m[2] == "value" {
+				phoneValues[n] = value
+			} else {
+				phoneTypes[n] = value
+			}
+		}
+	}
+	c.Emails = append(c.Emails, csvTypedValues(emailTypes, emailValues)...)
+	c.Phones = append(c.Phones, csvTypedValues(phoneTypes, phoneValues)...)
+
+	// Fixed Outlook columns
+	for _, column := range outlookEmailColumns {
+		if v := get(column); v != "" {
+			c.Emails = append(c.Emails, TypedValue{Value: v})
+		}
+	}
+	outlookPhones := make([]string, 0, len(outlookPhoneColumns))
+	for column := range outlookPhoneColumns {
+		outlookPhones = append(outlookPhones, column)
+	}
+	sort.Strings(outlookPhones)
+	for _, column := range outlookPhones {
+		if v := get(column); v != "" {
+			c.Phones = append(c.Phones, TypedValue{Value: v, Type: outlookPhoneColumns[column]})
+		}
+	}
+
+	// "Group Membership" / "Labels" use " ::: ", Outlook "Categories" uses ";"
+	if groups := get("group membership", "labels"); groups != "" {
+		c.Groups = csvGroups(strings.Split(groups, ":::"))
+	} else if groups := get("categories"); groups != "" {
+		c.Groups = csvGroups(strings.Split(groups, ";"))
+	}
+
+	if c.DisplayName() == "" {
+		return nil
+	}
+	return c
+}
+
+// csvTypedValues combines numbered type/value columns. Google puts several
+// values in one cell separated by " ::: " and marks the primary type with "* ".
+func csvTypedValues(types, values map[int]string) []TypedValue {
+	indexes := make([]int, 0, len(values))
+	for n := range values {
+		indexes = append(indexes, n)
+	}
+	sort.Ints(indexes)
+
+	var result []TypedValue
+	for _, n := range indexes {
+		typ := strings.TrimSpace(types[n])
+		preferred := strings.HasPrefix(typ, "*")
+		typ = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(typ, "*")))
+		if typ == "mobile" {
+			typ = "cell"
+		}
+		for _, v := range strings.Split(values[n], ":::") {
+			if v = strings.TrimSpace(v); v != "" {
+				result = append(result, TypedValue{Value: v, Type: typ, Preferred: preferred})
+				preferred = false
+			}
+		}
+	}
+	return result
+}
+
+// csvGroups cleans up group names, dropping Google's system groups ("* myContacts")
+func csvGroups(names []string) []string {
+	groups := []string{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || strings.HasPrefix(name, "*") {
+			continue
+		}
+		groups = append(groups, name)
+	}
+	return groups
+}
+
+// hasAnyColumn reports whether any of the named columns exist
+func hasAnyColumn(columns map[string]int, names ...string) bool {
+	for _, name := range names {
+		if _, ok := columns[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeContactsCSV writes cards in Google Contacts CSV format
+func EncodeContactsCSV(cards []*Card) (string, error) {
+	maxEmails, maxPhones := 1, 1
+	for _, c := range cards {
+		if len(c.Emails) > maxEmails {
+			maxEmails = len(c.Emails)
+		}
+		if len(c.Phones) > maxPhones {
+			maxPhones = len(c.Phones)
+		}
+	}
+
+	header := []string{
+		"Name", "Given Name", "Family Name", "Nickname", "Birthday", "Notes",
+		"Organization 1 - Name", "Organization 1 - Title", "Group Membership",
+	}
+	for i := 1; i <= maxEmails; i++ {
+		header = append(header, fmt.Sprintf("E-mail %d - Type", i), fmt.Sprintf("E-mail %d - Value", i))
+	}
+	for i := 1; i <= maxPhones; i++ {
+		header = append(header, fmt.Sprintf("Phone %d - Type", i), fmt.Sprintf("Phone %d - Value", i))
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return "", fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	for _, c := range cards {
+		record := []string{
+			c.DisplayName(), c.GivenName, c.FamilyName, c.Nickname, c.Birthday, c.Notes,
+			c.Organization, c.Title, strings.Join(c.Groups, " ::: "),
+		}
+		record = append(record, csvTypedColumns(c.Emails, maxEmails)...)
+		record = append(record, csvTypedColumns(c.Phones, maxPhones)...)
+		if err := w.Write(record); err != nil {
+			return "", fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// csvTypedColumns writes typed values as numbered type/value column pairs
+func csvTypedColumns(values []TypedValue, count int) []string {
+	columns := make([]string, 0, count*2)
+	for i := 0; i < count; i++ {
+		if i >= len(values) {
+			columns = append(columns, "", "")
+			continue
+		}
+		typ := values[i].Type
+		if typ != "" {
+			typ = strings.ToUpper(typ[:1]) + typ[1:]
+		}
+		if values[i].Preferred {
+			typ = strings.TrimSpace("* " + typ)
+		}
+		columns = append(columns, typ, values[i].Value)
+	}
+	return columns
+}
@@ -0,0 +1,98 @@
+package credentials
+
+import (
+	"database/sql"
+	"fmt"
+
+	gokeyring "github.com/zalando/go-keyring"
+)
+
+// SetIdentitySMTPPassword stores the password of an identity's own SMTP server
+func (s *Store) SetIdentitySMTPPassword(identityID, password string) error {
+	if password == "" {
+		return nil
+	}
+
+	// Try OS keyring first if available
+	if s.keyringEnabled {
+		err := gokeyring.Set(s.service, "identity-smtp:"+identityID, password)
+		if err == nil {
+			s.log.Debug().Str("identity_id", identityID).Msg("Identity SMTP password stored in OS keyring")
+			// Clear any fallback storage
+			s.clearIdentitySMTPDBPassword(identityID)
+			return nil
+		}
+		s.log.Warn().Err(err).Msg("Failed to store identity SMTP password in OS keyring, using fallback")
+	}
+
+	// Fallback to encrypted database storage
+	encrypted, err := s.encryptor.Encrypt(password)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	_, err = s.db.Exec(
+		"UPDATE identities SET encrypted_smtp_password = ? WHERE id = ?",
+		encrypted, identityID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store encrypted identity SMTP password: %w", err)
+	}
+
+	s.log.Debug().Str("identity_id", identityID).Msg("Identity SMTP password stored in encrypted database")
+	return nil
+}
+
+// GetIdentitySMTPPassword retrieves the password of an identity's own SMTP server
+func (s *Store) GetIdentitySMTPPassword(identityID string) (string, error) {
+	// Try OS keyring first if available
+	if s.keyringEnabled {
+		password, err := gokeyring.Get(s.service, "identity-smtp:"+identityID)
+		if err == nil {
+			return password, nil
+		}
+		if err != gokeyring.ErrNotFound {
+			s.log.Warn().Err(err).Msg("Error reading identity SMTP password from OS keyring, trying fallback")
+		}
+	}
+
+	// Try fallback encrypted database storage
+	var encrypted sql.NullString
+	err := s.db.QueryRow(
+		"SELECT encrypted_smtp_password FROM identities WHERE id = ?",
+		identityID,
+	).Scan(&encrypted)
+
+	if err == sql.ErrNoRows {
+		return "", ErrCredentialNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query identity SMTP password: %w", err)
+	}
+
+	if !encrypted.Valid || encrypted.String == "" {
+		return "", ErrCredentialNotFound
+	}
+
+	password, err := s.encryptor.Decrypt(encrypted.String)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt identity SMTP password: %w", err)
+	}
+
+	return password, nil
+}
+
+// DeleteIdentitySMTPPassword removes the password of an identity's own SMTP server
+func (s *Store) DeleteIdentitySMTPPassword(identityID string) error {
+	if s.keyringEnabled {
+		gokeyring.Delete(s.service, "identity-smtp:"+identityID)
+	}
+
+	s.clearIdentitySMTPDBPassword(identityID)
+	return nil
+}
+
+// clearIdentitySMTPDBPassword clears the encrypted identity SMTP password from the database
+func (s *Store) clearIdentitySMTPDBPassword(identityID string) {
+	s.db.Exec("UPDATE identities SET encrypted_smtp_password = NULL WHERE id = ?", identityID)
+}
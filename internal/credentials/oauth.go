@@ -131,8 +131,8 @@ func (s *Store) GetOAuthTokens(accountID string) (*OAuthTokens, error) {
 func (s *Store) DeleteOAuthTokens(accountID string) error {
 	// Delete from keyring
 	if s.keyringEnabled {
-		gokeyring.Delete(serviceName, accountID+":access_token")
-		gokeyring.Delete(serviceName, accountID+":refresh_token")
+		gokeyring.Delete(s.service, accountID+":access_token")
+		gokeyring.Delete(s.service, accountID+":refresh_token")
 	}
 
 	// Clear encrypted fallback storage
@@ -215,7 +215,7 @@ func (s *Store) setOAuthAccessToken(accountID, token string) error {
 
 	// Try OS keyring first
 	if s.keyringEnabled {
-		err := gokeyring.Set(serviceName, accountID+":access_token", token)
+		err := gokeyring.Set(s.service, accountID+":access_token", token)
 		if err == nil {
 			// Clear fallback storage
 			s.db.Exec("UPDATE accounts SET encrypted_access_token = NULL WHERE id = ?", accountID)
@@ -241,7 +241,7 @@ func (s *Store) setOAuthAccessToken(accountID, token string) error {
 func (s *Store) getOAuthAccessToken(accountID string) (string, error) {
 	// Try OS keyring first
 	if s.keyringEnabled {
-		token, err := gokeyring.Get(serviceName, accountID+":access_token")
+		token, err := gokeyring.Get(s.service, accountID+":access_token")
 		if err == nil {
 			return token, nil
 		}
@@ -275,7 +275,7 @@ func (s *Store) setOAuthRefreshToken(accountID, token string) error {
 
 	// Try OS keyring first
 	if s.keyringEnabled {
-		err := gokeyring.Set(serviceName, accountID+":refresh_token", token)
+		err := gokeyring.Set(s.service, accountID+":refresh_token", token)
 		if err == nil {
 			// Clear fallback storage
 			s.db.Exec("UPDATE accounts SET encrypted_refresh_token = NULL WHERE id = ?", accountID)
@@ -301,7 +301,7 @@ func (s *Store) setOAuthRefreshToken(accountID, token string) error {
 func (s *Store) getOAuthRefreshToken(accountID string) (string, error) {
 	// Try OS keyring first
 	if s.keyringEnabled {
-		token, err := gokeyring.Get(serviceName, accountID+":refresh_token")
+		token, err := gokeyring.Get(s.service, accountID+":refresh_token")
 		if err == nil {
 			return token, nil
 		}
@@ -434,8 +434,8 @@ func (s *Store) GetContactSourceOAuthTokens(sourceID string) (*OAuthTokens, erro
 func (s *Store) DeleteContactSourceOAuthTokens(sourceID string) error {
 	// Delete from keyring
 	if s.keyringEnabled {
-		gokeyring.Delete(serviceName, "contact_source:"+sourceID+":access_token")
-		gokeyring.Delete(serviceName, "contact_source:"+sourceID+":refresh_token")
+		gokeyring.Delete(s.service, "contact_source:"+sourceID+":access_token")
+		gokeyring.Delete(s.service, "contact_source:"+sourceID+":refresh_token")
 	}
 
 	// Clear encrypted fallback storage
@@ -500,7 +500,7 @@ func (s *Store) setContactSourceAccessToken(sourceID, token string) error {
 
 	// Try OS keyring first
 	if s.keyringEnabled {
-		err := gokeyring.Set(serviceName, "contact_source:"+sourceID+":access_token", token)
+		err := gokeyring.Set(s.service, "contact_source:"+sourceID+":access_token", token)
 		if err == nil {
 			// Clear fallback storage
 			s.db.Exec("UPDATE contact_sources SET encrypted_access_token = NULL WHERE id = ?", sourceID)
@@ -526,7 +526,7 @@ func (s *Store) setContactSourceAccessToken(sourceID, token string) error {
 func (s *Store) getContactSourceAccessToken(sourceID string) (string, error) {
 	// Try OS keyring first
 	if s.keyringEnabled {
-		token, err := gokeyring.Get(serviceName, "contact_source:"+sourceID+":access_token")
+		token, err := gokeyring.Get(s.service, "contact_source:"+sourceID+":access_token")
 		if err == nil {
 			return token, nil
 		}
@@ -560,7 +560,7 @@ func (s *Store) setContactSourceRefreshToken(sourceID, token string) error {
 
 	// Try OS keyring first
 	if s.keyringEnabled {
-		err := gokeyring.Set(serviceName, "contact_source:"+sourceID+":refresh_token", token)
+		err := gokeyring.Set(s.service, "contact_source:"+sourceID+":refresh_token", token)
 		if err == nil {
 			// Clear fallback storage
 			s.db.Exec("UPDATE contact_sources SET encrypted_refresh_token = NULL WHERE id = ?", sourceID)
@@ -586,7 +586,7 @@ func (s *Store) setContactSourceRefreshToken(sourceID, token string) error {
 func (s *Store) getContactSourceRefreshToken(sourceID string) (string, error) {
 	// Try OS keyring first
 	if s.keyringEnabled {
-		token, err := gokeyring.Get(serviceName, "contact_source:"+sourceID+":refresh_token")
+		token, err := gokeyring.Get(s.service, "contact_source:"+sourceID+":refresh_token")
 		if err == nil {
 			return token, nil
 		}
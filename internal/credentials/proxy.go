@@ -0,0 +1,102 @@
+package credentials
+
+import (
+	"database/sql"
+	"fmt"
+
+	gokeyring "github.com/zalando/go-keyring"
+)
+
+// GlobalProxyScope is the scope of the global proxy password. Account proxy
+// passwords use the account ID as scope.
+const GlobalProxyScope = "global"
+
+// SetProxyPassword stores a proxy password for a scope
+func (s *Store) SetProxyPassword(scope, password string) error {
+	if password == "" {
+		return nil
+	}
+
+	// Try OS keyring first if available
+	if s.keyringEnabled {
+		err := gokeyring.Set(s.service, "proxy:"+scope, password)
+		if err == nil {
+			s.log.Debug().Str("scope", scope).Msg("Proxy password stored in OS keyring")
+			// Clear any fallback storage
+			s.clearProxyDBPassword(scope)
+			return nil
+		}
+		s.log.Warn().Err(err).Msg("Failed to store proxy password in OS keyring, using fallback")
+	}
+
+	// Fallback to encrypted database storage
+	encrypted, err := s.encryptor.Encrypt(password)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO proxy_credentials (scope, encrypted_password) VALUES (?, ?)
+		ON CONFLICT(scope) DO UPDATE SET encrypted_password = excluded.encrypted_password
+	`, scope, encrypted)
+	if err != nil {
+		return fmt.Errorf("failed to store encrypted proxy password: %w", err)
+	}
+
+	s.log.Debug().Str("scope", scope).Msg("Proxy password stored in encrypted database")
+	return nil
+}
+
+// GetProxyPassword retrieves a proxy password for a scope
+func (s *Store) GetProxyPassword(scope string) (string, error) {
+	// Try OS keyring first if available
+	if s.keyringEnabled {
+		password, err := gokeyring.Get(s.service, "proxy:"+scope)
+		if err == nil {
+			return password, nil
+		}
+		if err != gokeyring.ErrNotFound {
+			s.log.Warn().Err(err).Msg("Error reading proxy password from OS keyring, trying fallback")
+		}
+	}
+
+	// Try fallback encrypted database storage
+	var encrypted sql.NullString
+	err := s.db.QueryRow(
+		"SELECT encrypted_password FROM proxy_credentials WHERE scope = ?",
+		scope,
+	).Scan(&encrypted)
+
+	if err == sql.ErrNoRows {
+		return "", ErrCredentialNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query proxy password: %w", err)
+	}
+
+	if !encrypted.Valid || encrypted.String == "" {
+		return "", ErrCredentialNotFound
+	}
+
+	password, err := s.encryptor.Decrypt(encrypted.String)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt proxy password: %w", err)
+	}
+
+	return password, nil
+}
+
+// DeleteProxyPassword removes a proxy password for a scope
+func (s *Store) DeleteProxyPassword(scope string) error {
+	if s.keyringEnabled {
+		gokeyring.Delete(s.service, "proxy:"+scope)
+	}
+
+	s.clearProxyDBPassword(scope)
+	return nil
+}
+
+// clearProxyDBPassword removes the encrypted proxy password from the database
+func (s *Store) clearProxyDBPassword(scope string) {
+	s.db.Exec("DELETE FROM proxy_credentials WHERE scope = ?", scope)
+}
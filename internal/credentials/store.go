@@ -7,35 +7,44 @@ import (
 
 	"github.com/hkdb/aerion/internal/crypto"
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/platform"
 	"github.com/rs/zerolog"
 	gokeyring "github.com/zalando/go-keyring"
 )
 
-const serviceName = "aerion"
-
 // Store provides credential storage with OS keyring and encrypted DB fallback
 type Store struct {
 	db             *sql.DB
 	encryptor      *crypto.Encryptor
+	service        string // OS keyring service name of the active profile
 	keyringEnabled bool
 	log            zerolog.Logger
 }
 
 // NewStore creates a new credential store
-// It tries to use the OS keyring, falling back to encrypted database storage
+// It tries to use the OS keyring, falling back to encrypted database storage.
+// In portable mode credentials always stay in the database so they move
+// with the data directory.
 func NewStore(db *sql.DB, dataDir string) (*Store, error) {
 	log := logging.WithComponent("credentials")
 
 	// Create encryptor for fallback storage
-	encryptor, err := crypto.NewEncryptor(dataDir)
+	newEncryptor := crypto.NewEncryptor
+	if platform.Portable() {
+		newEncryptor = crypto.NewPortableEncryptor
+	}
+	encryptor, err := newEncryptor(dataDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create encryptor: %w", err)
 	}
 
 	// Test if keyring is available
-	keyringEnabled := testKeyring()
-	if keyringEnabled {
-		log.Info().Msg("OS keyring available, using as primary credential storage")
+	service := platform.KeyringService()
+	keyringEnabled := false
+	if platform.Portable() {
+		log.Info().Msg("Portable mode, using encrypted database storage")
+	} else if keyringEnabled = testKeyring(service); keyringEnabled {
+		log.Info().Str("service", service).Msg("OS keyring available, using as primary credential storage")
 	} else {
 		log.Warn().Msg("OS keyring not available, using encrypted database storage")
 	}
@@ -43,24 +52,25 @@ func NewStore(db *sql.DB, dataDir string) (*Store, error) {
 	return &Store{
 		db:             db,
 		encryptor:      encryptor,
+		service:        service,
 		keyringEnabled: keyringEnabled,
 		log:            log,
 	}, nil
 }
 
 // testKeyring checks if the OS keyring is available and functional
-func testKeyring() bool {
+func testKeyring(service string) bool {
 	testKey := "aerion-test-keyring-check"
 	testValue := "test"
 
 	// Try to set a test value
-	err := gokeyring.Set(serviceName, testKey, testValue)
+	err := gokeyring.Set(service, testKey, testValue)
 	if err != nil {
 		return false
 	}
 
 	// Clean up test value
-	gokeyring.Delete(serviceName, testKey)
+	gokeyring.Delete(service, testKey)
 
 	return true
 }
@@ -73,7 +83,7 @@ func (s *Store) SetPassword(accountID, password string) error {
 
 	// Try OS keyring first if available
 	if s.keyringEnabled {
-		err := gokeyring.Set(serviceName, accountID, password)
+		err := gokeyring.Set(s.service, accountID, password)
 		if err == nil {
 			s.log.Debug().Str("account_id", accountID).Msg("Password stored in OS keyring")
 			// Clear any fallback storage
@@ -105,7 +115,7 @@ func (s *Store) SetPassword(accountID, password string) error {
 func (s *Store) GetPassword(accountID string) (string, error) {
 	// Try OS keyring first if available
 	if s.keyringEnabled {
-		password, err := gokeyring.Get(serviceName, accountID)
+		password, err := gokeyring.Get(s.service, accountID)
 		if err == nil {
 			return password, nil
 		}
@@ -145,7 +155,7 @@ func (s *Store) GetPassword(accountID string) (string, error) {
 func (s *Store) DeletePassword(accountID string) error {
 	// Delete from OS keyring
 	if s.keyringEnabled {
-		gokeyring.Delete(serviceName, accountID)
+		gokeyring.Delete(s.service, accountID)
 	}
 
 	// Delete from database
@@ -163,6 +173,7 @@ func (s *Store) clearDBPassword(accountID string) {
 func (s *Store) DeleteAllCredentials(accountID string) error {
 	s.DeletePassword(accountID)
 	s.DeleteOAuthTokens(accountID)
+	s.DeleteProxyPassword(accountID)
 	return nil
 }
 
@@ -181,7 +192,7 @@ func (s *Store) SetSMIMEPrivateKey(certID string, privateKeyPEM []byte) error {
 
 	// Try OS keyring first if available
 	if s.keyringEnabled {
-		err := gokeyring.Set(serviceName, keyringKey, string(privateKeyPEM))
+		err := gokeyring.Set(s.service, keyringKey, string(privateKeyPEM))
 		if err == nil {
 			s.log.Debug().Str("cert_id", certID).Msg("S/MIME private key stored in OS keyring")
 			s.clearSMIMEDBPrivateKey(certID)
@@ -214,7 +225,7 @@ func (s *Store) GetSMIMEPrivateKey(certID string) ([]byte, error) {
 
 	// Try OS keyring first if available
 	if s.keyringEnabled {
-		key, err := gokeyring.Get(serviceName, keyringKey)
+		key, err := gokeyring.Get(s.service, keyringKey)
 		if err == nil {
 			return []byte(key), nil
 		}
@@ -254,7 +265,7 @@ func (s *Store) DeleteSMIMEPrivateKey(certID string) error {
 	keyringKey := "smime:" + certID + ":private_key"
 
 	if s.keyringEnabled {
-		gokeyring.Delete(serviceName, keyringKey)
+		gokeyring.Delete(s.service, keyringKey)
 	}
 
 	s.clearSMIMEDBPrivateKey(certID)
@@ -276,7 +287,7 @@ func (s *Store) SetPGPPrivateKey(keyID string, armoredKey []byte) error {
 
 	// Try OS keyring first if available
 	if s.keyringEnabled {
-		err := gokeyring.Set(serviceName, keyringKey, string(armoredKey))
+		err := gokeyring.Set(s.service, keyringKey, string(armoredKey))
 		if err == nil {
 			s.log.Debug().Str("key_id", keyID).Msg("PGP private key stored in OS keyring")
 			s.clearPGPDBPrivateKey(keyID)
@@ -309,7 +320,7 @@ func (s *Store) GetPGPPrivateKey(keyID string) ([]byte, error) {
 
 	// Try OS keyring first if available
 	if s.keyringEnabled {
-		key, err := gokeyring.Get(serviceName, keyringKey)
+		key, err := gokeyring.Get(s.service, keyringKey)
 		if err == nil {
 			return []byte(key), nil
 		}
@@ -349,7 +360,7 @@ func (s *Store) DeletePGPPrivateKey(keyID string) error {
 	keyringKey := "pgp:" + keyID + ":private_key"
 
 	if s.keyringEnabled {
-		gokeyring.Delete(serviceName, keyringKey)
+		gokeyring.Delete(s.service, keyringKey)
 	}
 
 	s.clearPGPDBPrivateKey(keyID)
@@ -369,7 +380,7 @@ func (s *Store) SetCardDAVPassword(sourceID, password string) error {
 
 	// Try OS keyring first if available
 	if s.keyringEnabled {
-		err := gokeyring.Set(serviceName, "carddav:"+sourceID, password)
+		err := gokeyring.Set(s.service, "carddav:"+sourceID, password)
 		if err == nil {
 			s.log.Debug().Str("source_id", sourceID).Msg("CardDAV password stored in OS keyring")
 			// Clear any fallback storage
@@ -401,7 +412,7 @@ func (s *Store) SetCardDAVPassword(sourceID, password string) error {
 func (s *Store) GetCardDAVPassword(sourceID string) (string, error) {
 	// Try OS keyring first if available
 	if s.keyringEnabled {
-		password, err := gokeyring.Get(serviceName, "carddav:"+sourceID)
+		password, err := gokeyring.Get(s.service, "carddav:"+sourceID)
 		if err == nil {
 			return password, nil
 		}
@@ -441,7 +452,7 @@ func (s *Store) GetCardDAVPassword(sourceID string) (string, error) {
 func (s *Store) DeleteCardDAVPassword(sourceID string) error {
 	// Delete from OS keyring
 	if s.keyringEnabled {
-		gokeyring.Delete(serviceName, "carddav:"+sourceID)
+		gokeyring.Delete(s.service, "carddav:"+sourceID)
 	}
 
 	// Delete from database
@@ -454,3 +465,97 @@ func (s *Store) DeleteCardDAVPassword(sourceID string) error {
 func (s *Store) clearCardDAVDBPassword(sourceID string) {
 	s.db.Exec("UPDATE contact_sources SET encrypted_password = NULL WHERE id = ?", sourceID)
 }
+
+// SetCalDAVPassword stores a password for a CalDAV calendar source
+func (s *Store) SetCalDAVPassword(sourceID, password string) error {
+	if password == "" {
+		return nil
+	}
+
+	// Try OS keyring first if available
+	if s.keyringEnabled {
+		err := gokeyring.Set(s.service, "caldav:"+sourceID, password)
+		if err == nil {
+			s.log.Debug().Str("source_id", sourceID).Msg("CalDAV password stored in OS keyring")
+			// Clear any fallback storage
+			s.clearCalDAVDBPassword(sourceID)
+			return nil
+		}
+		s.log.Warn().Err(err).Msg("Failed to store CalDAV password in OS keyring, using fallback")
+	}
+
+	// Fallback to encrypted database storage
+	encrypted, err := s.encryptor.Encrypt(password)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	_, err = s.db.Exec(
+		"UPDATE calendar_sources SET encrypted_password = ? WHERE id = ?",
+		encrypted, sourceID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store encrypted password: %w", err)
+	}
+
+	s.log.Debug().Str("source_id", sourceID).Msg("CalDAV password stored in encrypted database")
+	return nil
+}
+
+// GetCalDAVPassword retrieves a password for a CalDAV calendar source
+func (s *Store) GetCalDAVPassword(sourceID string) (string, error) {
+	// Try OS keyring first if available
+	if s.keyringEnabled {
+		password, err := gokeyring.Get(s.service, "caldav:"+sourceID)
+		if err == nil {
+			return password, nil
+		}
+		if err != gokeyring.ErrNotFound {
+			s.log.Warn().Err(err).Msg("Error reading CalDAV password from OS keyring, trying fallback")
+		}
+	}
+
+	// Try fallback encrypted database storage
+	var encrypted sql.NullString
+	err := s.db.QueryRow(
+		"SELECT encrypted_password FROM calendar_sources WHERE id = ?",
+		sourceID,
+	).Scan(&encrypted)
+
+	if err == sql.ErrNoRows {
+		return "", ErrCredentialNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query password: %w", err)
+	}
+
+	if !encrypted.Valid || encrypted.String == "" {
+		return "", ErrCredentialNotFound
+	}
+
+	// Decrypt
+	password, err := s.encryptor.Decrypt(encrypted.String)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt password: %w", err)
+	}
+
+	return password, nil
+}
+
+// DeleteCalDAVPassword removes a password for a CalDAV calendar source
+func (s *Store) DeleteCalDAVPassword(sourceID string) error {
+	// Delete from OS keyring
+	if s.keyringEnabled {
+		gokeyring.Delete(s.service, "caldav:"+sourceID)
+	}
+
+	// Delete from database
+	s.clearCalDAVDBPassword(sourceID)
+
+	return nil
+}
+
+// clearCalDAVDBPassword clears the encrypted password from the calendar_sources table
+func (s *Store) clearCalDAVDBPassword(sourceID string) {
+	s.db.Exec("UPDATE calendar_sources SET encrypted_password = NULL WHERE id = ?", sourceID)
+}
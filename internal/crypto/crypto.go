@@ -47,6 +47,26 @@ func NewEncryptor(dataDir string) (*Encryptor, error) {
 	return &Encryptor{key: key}, nil
 }
 
+// NewPortableEncryptor creates a new Encryptor using the key stored in the
+// data directory as is, rather than re-deriving it from machine-specific
+// data, so the data directory can be moved between machines (portable mode).
+// Key files written by NewEncryptor are compatible.
+func NewPortableEncryptor(dataDir string) (*Encryptor, error) {
+	keyPath := filepath.Join(dataDir, keyFileName)
+
+	data, err := os.ReadFile(keyPath)
+	if err == nil && len(data) == keySize+saltSize {
+		return &Encryptor{key: data[saltSize:]}, nil
+	}
+
+	key, err := loadOrCreateKey(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load or create key: %w", err)
+	}
+
+	return &Encryptor{key: key}, nil
+}
+
 // loadOrCreateKey loads the encryption key from disk, or creates a new one
 func loadOrCreateKey(keyPath string) ([]byte, error) {
 	// Try to read existing key
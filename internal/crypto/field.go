@@ -0,0 +1,272 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// FieldPrefix marks an encrypted database field. Values without it are
+// plaintext, so a database can be encrypted (or decrypted) incrementally.
+const FieldPrefix = "aenc1:"
+
+// fieldKeyInfo is the HKDF info string binding derived keys to this format
+const fieldKeyInfo = "aerion field encryption v1"
+
+// contentMACInfo is the HKDF info string of the key used by ContentMAC
+const contentMACInfo = "aerion content names v1"
+
+// ErrLocked is returned when reading an encrypted field while the master
+// password hasn't been entered
+var ErrLocked = errors.New("database is locked")
+
+// FieldCipher encrypts sensitive database fields to the vault's public key
+// (ephemeral X25519 + HKDF-SHA256 + AES-256-GCM per field).
+// It is safe for concurrent use. A nil or disabled FieldCipher passes values
+// through unchanged, so stores work the same without a master password.
+type FieldCipher struct {
+	mu         sync.RWMutex
+	publicKey  *ecdh.PublicKey  // nil = encryption disabled
+	privateKey *ecdh.PrivateKey // nil = locked
+}
+
+// NewFieldCipher creates a disabled FieldCipher
+func NewFieldCipher() *FieldCipher {
+	return &FieldCipher{}
+}
+
+// Enable turns on encryption of new writes to the public key
+func (c *FieldCipher) Enable(publicKey *ecdh.PublicKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.publicKey = publicKey
+}
+
+// Disable turns off encryption of new writes. Encrypted fields stay readable
+// until Lock, so existing data can still be decrypted.
+func (c *FieldCipher) Disable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.publicKey = nil
+}
+
+// Unlock makes encrypted fields readable
+func (c *FieldCipher) Unlock(privateKey *ecdh.PrivateKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.privateKey = privateKey
+}
+
+// Lock forgets the private key. Writes are still encrypted.
+func (c *FieldCipher) Lock() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.privateKey = nil
+}
+
+// Enabled reports whether new writes are encrypted
+func (c *FieldCipher) Enabled() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.publicKey != nil
+}
+
+// Locked reports whether encryption is enabled but the private key is unavailable
+func (c *FieldCipher) Locked() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.publicKey != nil && c.privateKey == nil
+}
+
+// Unlocked reports whether the private key is available, so encrypted fields
+// can be read
+func (c *FieldCipher) Unlocked() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.privateKey != nil
+}
+
+// IsSealedString reports whether a string field is encrypted
+func IsSealedString(value string) bool {
+	return strings.HasPrefix(value, FieldPrefix)
+}
+
+// IsSealedBytes reports whether a BLOB field is encrypted
+func IsSealedBytes(value []byte) bool {
+	return bytes.HasPrefix(value, []byte(FieldPrefix))
+}
+
+// SealString encrypts a string field. Empty values, already encrypted
+// values and values written while encryption is disabled are returned as is.
+func (c *FieldCipher) SealString(value string) (string, error) {
+	if value == "" || IsSealedString(value) || !c.Enabled() {
+		return value, nil
+	}
+	sealed, err := c.seal([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	return FieldPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenString decrypts a string field. Plaintext values are returned as is.
+func (c *FieldCipher) OpenString(value string) (string, error) {
+	if !IsSealedString(value) {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value[len(FieldPrefix):])
+	if err != nil {
+		return "", fmt.Errorf("failed to decode field: %w", err)
+	}
+	plaintext, err := c.open(data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// SealBytes encrypts a BLOB field. Empty values, already encrypted values
+// and values written while encryption is disabled are returned as is.
+func (c *FieldCipher) SealBytes(value []byte) ([]byte, error) {
+	if len(value) == 0 || IsSealedBytes(value) || !c.Enabled() {
+		return value, nil
+	}
+	sealed, err := c.seal(value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(FieldPrefix), sealed...), nil
+}
+
+// OpenBytes decrypts a BLOB field. Plaintext values are returned as is.
+func (c *FieldCipher) OpenBytes(value []byte) ([]byte, error) {
+	if !IsSealedBytes(value) {
+		return value, nil
+	}
+	return c.open(value[len(FieldPrefix):])
+}
+
+// seal encrypts to the public key: ephemeral public key || nonce || ciphertext
+func (c *FieldCipher) seal(plaintext []byte) ([]byte, error) {
+	c.mu.RLock()
+	recipient := c.publicKey
+	c.mu.RUnlock()
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %w", err)
+	}
+
+	ephemeralPub := ephemeral.PublicKey().Bytes()
+	gcm, err := fieldGCM(shared, ephemeralPub, recipient.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(ephemeralPub)+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, ephemeralPub...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, nil), nil
+}
+
+// open decrypts a value produced by seal
+func (c *FieldCipher) open(data []byte) ([]byte, error) {
+	if c == nil {
+		return nil, ErrLocked
+	}
+	c.mu.RLock()
+	privateKey := c.privateKey
+	c.mu.RUnlock()
+	if privateKey == nil {
+		return nil, ErrLocked
+	}
+
+	const keyLen = 32
+	if len(data) < keyLen {
+		return nil, fmt.Errorf("encrypted field too short")
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(data[:keyLen])
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted field: %w", err)
+	}
+	shared, err := privateKey.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %w", err)
+	}
+
+	gcm, err := fieldGCM(shared, data[:keyLen], privateKey.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	rest := data[keyLen:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted field too short")
+	}
+	plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt field: %w", err)
+	}
+	return plaintext, nil
+}
+
+// ContentMAC returns an HMAC-SHA256 of data keyed from the vault's private
+// key, so stored content can be addressed without revealing a hash of the
+// plaintext. Returns ErrLocked while the private key is unavailable.
+func (c *FieldCipher) ContentMAC(data []byte) ([]byte, error) {
+	if c == nil {
+		return nil, ErrLocked
+	}
+	c.mu.RLock()
+	privateKey := c.privateKey
+	c.mu.RUnlock()
+	if privateKey == nil {
+		return nil, ErrLocked
+	}
+
+	key, err := hkdf.Key(sha256.New, privateKey.Bytes(), nil, contentMACInfo, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive content key: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// fieldGCM derives the per-field AES-256-GCM cipher from the ECDH shared secret
+func fieldGCM(shared, ephemeralPub, recipientPub []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephemeralPub...), recipientPub...)
+	key, err := hkdf.Key(sha256.New, shared, salt, fieldKeyInfo, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive field key: %w", err)
+	}
+	return newGCM(key)
+}
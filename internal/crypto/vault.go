@@ -0,0 +1,249 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// vaultFileName is the name of the file holding the master password vault
+	vaultFileName = "vault.json"
+
+	// vaultVersion is the current vault file format
+	vaultVersion = 1
+
+	// Bounds for Argon2id parameters read from files. Anything outside them
+	// would panic argon2 (zero time or threads) or exhaust memory.
+	minKDFTime      = 1
+	maxKDFTime      = 16
+	minKDFMemoryKiB = 8 * 1024
+	maxKDFMemoryKiB = 1024 * 1024
+	minKDFThreads   = 1
+	maxKDFThreads   = 64
+	minKDFSaltSize  = 16
+)
+
+// ErrWrongPassword is returned when a master password doesn't unlock the vault
+var ErrWrongPassword = errors.New("wrong master password")
+
+// MasterKeyParams are the Argon2id parameters used to derive the key that
+// wraps the vault's private key
+type MasterKeyParams struct {
+	Salt      []byte `json:"salt"`
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memoryKiB"`
+	Threads   uint8  `json:"threads"`
+}
+
+// Vault holds the keypair that protects sensitive database fields.
+// Fields are encrypted to the public key, so background sync can store new
+// mail while the app is locked; reading them needs the private key, which
+// is only available after unlocking with the master password. Changing the
+// password re-wraps the private key without touching the encrypted data.
+type Vault struct {
+	Version             int             `json:"version"`
+	KDF                 MasterKeyParams `json:"kdf"`
+	PublicKey           []byte          `json:"publicKey"`
+	EncryptedPrivateKey []byte          `json:"encryptedPrivateKey"` // AES-256-GCM, nonce prepended
+	AutoLockMinutes     int             `json:"autoLockMinutes"`     // Lock after this much inactivity, 0 = never
+	ExistingEncrypted   bool            `json:"existingEncrypted"`   // Data stored before enabling has been encrypted
+	Disabling           bool            `json:"disabling"`           // The password is being removed; existing data is still being decrypted
+}
+
+// NewMasterKeyParams returns the OWASP-recommended Argon2id parameters with a
+// fresh salt
+func NewMasterKeyParams() (MasterKeyParams, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return MasterKeyParams{}, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return MasterKeyParams{Salt: salt, Time: 3, MemoryKiB: 64 * 1024, Threads: 4}, nil
+}
+
+// DeriveKey derives a 256-bit key from a password
+func (params MasterKeyParams) DeriveKey(password string) []byte {
+	return argon2.IDKey([]byte(password), params.Salt, params.Time, params.MemoryKiB, params.Threads, keySize)
+}
+
+// Validate checks that parameters read from an untrusted file are safe to
+// derive a key with
+func (params MasterKeyParams) Validate() error {
+	if params.Time < minKDFTime || params.Time > maxKDFTime {
+		return fmt.Errorf("invalid key derivation time: %d", params.Time)
+	}
+	if params.MemoryKiB < minKDFMemoryKiB || params.MemoryKiB > maxKDFMemoryKiB {
+		return fmt.Errorf("invalid key derivation memory: %d KiB", params.MemoryKiB)
+	}
+	if params.Threads < minKDFThreads || params.Threads > maxKDFThreads {
+		return fmt.Errorf("invalid key derivation threads: %d", params.Threads)
+	}
+	if len(params.Salt) < minKDFSaltSize {
+		return fmt.Errorf("invalid key derivation salt")
+	}
+	return nil
+}
+
+// NewVault creates a vault with a new keypair protected by the password
+func NewVault(password string) (*Vault, *ecdh.PrivateKey, error) {
+	if password == "" {
+		return nil, nil, fmt.Errorf("master password is required")
+	}
+
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	v := &Vault{
+		Version:   vaultVersion,
+		PublicKey: privateKey.PublicKey().Bytes(),
+	}
+	if err := v.wrapPrivateKey(password, privateKey); err != nil {
+		return nil, nil, err
+	}
+	return v, privateKey, nil
+}
+
+// LoadVault reads the vault from the data directory.
+// Returns nil if no master password is set.
+func LoadVault(dataDir string) (*Vault, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, vaultFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+
+	v := &Vault{}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("failed to parse vault: %w", err)
+	}
+	if v.Version != vaultVersion {
+		return nil, fmt.Errorf("unsupported vault version: %d", v.Version)
+	}
+	return v, nil
+}
+
+// Save writes the vault to the data directory
+func (v *Vault) Save(dataDir string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %w", err)
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated vault
+	path := filepath.Join(dataDir, vaultFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	return nil
+}
+
+// RemoveVault deletes the vault from the data directory
+func RemoveVault(dataDir string) error {
+	if err := os.Remove(filepath.Join(dataDir, vaultFileName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove vault: %w", err)
+	}
+	return nil
+}
+
+// Public returns the vault's public key
+func (v *Vault) Public() (*ecdh.PublicKey, error) {
+	key, err := ecdh.X25519().NewPublicKey(v.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault public key: %w", err)
+	}
+	return key, nil
+}
+
+// Unlock decrypts the private key with the master password
+func (v *Vault) Unlock(password string) (*ecdh.PrivateKey, error) {
+	if err := v.KDF.Validate(); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(v.KDF.DeriveKey(password))
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(v.EncryptedPrivateKey) < nonceSize {
+		return nil, fmt.Errorf("invalid vault private key")
+	}
+	nonce, sealed := v.EncryptedPrivateKey[:nonceSize], v.EncryptedPrivateKey[nonceSize:]
+
+	raw, err := gcm.Open(nil, nonce, sealed, v.PublicKey)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+
+	privateKey, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault private key: %w", err)
+	}
+	return privateKey, nil
+}
+
+// ChangePassword re-wraps the private key with a new master password
+func (v *Vault) ChangePassword(oldPassword, newPassword string) error {
+	if newPassword == "" {
+		return fmt.Errorf("master password is required")
+	}
+	privateKey, err := v.Unlock(oldPassword)
+	if err != nil {
+		return err
+	}
+	return v.wrapPrivateKey(newPassword, privateKey)
+}
+
+// wrapPrivateKey encrypts the private key with a key derived from the
+// password and a fresh salt. The public key is authenticated as associated
+// data so the two can't be mixed up.
+func (v *Vault) wrapPrivateKey(password string, privateKey *ecdh.PrivateKey) error {
+	params, err := NewMasterKeyParams()
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(params.DeriveKey(password))
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	v.KDF = params
+	v.EncryptedPrivateKey = gcm.Seal(nonce, nonce, privateKey.Bytes(), v.PublicKey)
+	return nil
+}
+
+// newGCM creates an AES-256-GCM cipher
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
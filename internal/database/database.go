@@ -149,6 +149,21 @@ func (db *DB) StartCheckpointRoutine(ctx context.Context) {
 	}
 }
 
+// Vacuum checkpoints the WAL and rebuilds the database file, dropping freed
+// pages so deleted or rewritten data no longer lingers on disk
+func (db *DB) Vacuum() error {
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	if _, err := db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	return nil
+}
+
 // Path returns the database file path
 func (db *DB) Path() string {
 	return db.path
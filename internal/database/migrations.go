@@ -690,4 +690,469 @@ var migrations = []Migration{
 				('https://pgp.mit.edu', 2);
 		`,
 	},
+	{
+		Version: 26,
+		SQL: `
+			-- Spam classifier token counts
+			CREATE TABLE IF NOT EXISTS spam_tokens (
+				token TEXT PRIMARY KEY,
+				spam_count INTEGER NOT NULL DEFAULT 0,
+				ham_count INTEGER NOT NULL DEFAULT 0
+			);
+
+			-- Messages the classifier was trained on (keyed by Message-ID so moves don't retrain)
+			CREATE TABLE IF NOT EXISTS spam_training (
+				message_key TEXT PRIMARY KEY,
+				is_spam INTEGER NOT NULL,
+				tokens TEXT NOT NULL,
+				trained_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			-- Spam scores computed for newly synced messages
+			CREATE TABLE IF NOT EXISTS spam_scores (
+				message_id TEXT PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+				score REAL NOT NULL,
+				scored_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+		`,
+	},
+	{
+		Version: 27,
+		SQL: `
+			-- User-imported S/MIME trust anchors (roots trusted in addition to system roots)
+			CREATE TABLE IF NOT EXISTS smime_trust_anchors (
+				id TEXT PRIMARY KEY,
+				fingerprint TEXT NOT NULL UNIQUE,
+				subject TEXT NOT NULL,
+				issuer TEXT NOT NULL,
+				not_before DATETIME,
+				not_after DATETIME,
+				cert_pem TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			-- Intermediate CA certificates seen in signed messages or fetched via AIA
+			CREATE TABLE IF NOT EXISTS smime_intermediate_certs (
+				fingerprint TEXT PRIMARY KEY,
+				subject TEXT NOT NULL,
+				not_after DATETIME,
+				cert_pem TEXT NOT NULL,
+				collected_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			-- Cached OCSP/CRL revocation results per certificate
+			CREATE TABLE IF NOT EXISTS smime_revocation_cache (
+				fingerprint TEXT PRIMARY KEY,
+				status TEXT NOT NULL,
+				source TEXT,
+				revoked_at DATETIME,
+				checked_at DATETIME NOT NULL,
+				next_update DATETIME NOT NULL
+			);
+
+			-- Cached CRLs by distribution point URL
+			CREATE TABLE IF NOT EXISTS smime_crl_cache (
+				url TEXT PRIMARY KEY,
+				crl_der BLOB NOT NULL,
+				this_update DATETIME,
+				next_update DATETIME,
+				fetched_at DATETIME NOT NULL
+			);
+		`,
+	},
+	{
+		Version: 28,
+		SQL: `
+			-- Autocrypt Level 1 peer state, per account
+			CREATE TABLE IF NOT EXISTS autocrypt_peers (
+				account_id TEXT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+				addr TEXT NOT NULL,
+				last_seen DATETIME NOT NULL,
+				autocrypt_timestamp DATETIME,
+				public_key TEXT,
+				key_fingerprint TEXT,
+				prefer_encrypt TEXT NOT NULL DEFAULT 'nopreference',
+				gossip_timestamp DATETIME,
+				gossip_key TEXT,
+				gossip_fingerprint TEXT,
+				PRIMARY KEY (account_id, addr)
+			);
+
+			-- Per-account Autocrypt configuration
+			ALTER TABLE accounts ADD COLUMN autocrypt_enabled INTEGER NOT NULL DEFAULT 1;
+			ALTER TABLE accounts ADD COLUMN autocrypt_prefer_encrypt TEXT NOT NULL DEFAULT 'nopreference';
+		`,
+	},
+	{
+		Version: 29,
+		SQL: `
+			-- Revocation state of the user's own PGP keys
+			ALTER TABLE pgp_keys ADD COLUMN revoked_at DATETIME;
+			ALTER TABLE pgp_keys ADD COLUMN revocation_reason TEXT;
+		`,
+	},
+	{
+		Version: 30,
+		SQL: `
+			-- Full vCards of CardDAV contacts (carddav_contacts stays the email index)
+			CREATE TABLE IF NOT EXISTS carddav_cards (
+				id TEXT PRIMARY KEY,
+				addressbook_id TEXT NOT NULL REFERENCES contact_source_addressbooks(id) ON DELETE CASCADE,
+				href TEXT NOT NULL,
+				etag TEXT,
+				uid TEXT,
+				full_name TEXT,
+				vcard TEXT NOT NULL,
+				synced_at DATETIME,
+				UNIQUE(addressbook_id, href)
+			);
+			CREATE INDEX IF NOT EXISTS idx_carddav_cards_addressbook ON carddav_cards(addressbook_id);
+
+			-- Local address book entries (not synced to any server)
+			CREATE TABLE IF NOT EXISTS local_cards (
+				id TEXT PRIMARY KEY,
+				uid TEXT NOT NULL UNIQUE,
+				full_name TEXT,
+				vcard TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			-- Email index of local cards for autocomplete
+			CREATE TABLE IF NOT EXISTS local_card_emails (
+				card_id TEXT NOT NULL REFERENCES local_cards(id) ON DELETE CASCADE,
+				email TEXT NOT NULL,
+				display_name TEXT,
+				PRIMARY KEY (card_id, email)
+			);
+			CREATE INDEX IF NOT EXISTS idx_local_card_emails_email ON local_card_emails(email);
+		`,
+	},
+	{
+		Version: 31,
+		SQL: `
+			-- Contact groups created in Aerion
+			CREATE TABLE IF NOT EXISTS local_groups (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TABLE IF NOT EXISTS local_group_members (
+				group_id TEXT NOT NULL REFERENCES local_groups(id) ON DELETE CASCADE,
+				email TEXT NOT NULL,
+				display_name TEXT,
+				PRIMARY KEY (group_id, email)
+			);
+
+			-- vCard kind of CardDAV cards ("group" for KIND:group / Apple group vCards)
+			ALTER TABLE carddav_cards ADD COLUMN kind TEXT;
+
+			-- Contact groups of OAuth sources (Google contact groups)
+			CREATE TABLE IF NOT EXISTS carddav_groups (
+				id TEXT PRIMARY KEY,
+				addressbook_id TEXT NOT NULL REFERENCES contact_source_addressbooks(id) ON DELETE CASCADE,
+				remote_id TEXT NOT NULL,
+				name TEXT NOT NULL,
+				UNIQUE(addressbook_id, remote_id)
+			);
+
+			-- Group memberships by contact remote ID (carddav_contacts.href)
+			CREATE TABLE IF NOT EXISTS carddav_group_members (
+				addressbook_id TEXT NOT NULL REFERENCES contact_source_addressbooks(id) ON DELETE CASCADE,
+				group_remote_id TEXT NOT NULL,
+				href TEXT NOT NULL,
+				PRIMARY KEY (addressbook_id, group_remote_id, href)
+			);
+			CREATE INDEX IF NOT EXISTS idx_carddav_group_members_href ON carddav_group_members(addressbook_id, href);
+		`,
+	},
+	{
+		Version: 32,
+		SQL: `
+			-- Raw iCalendar data of meeting invitations (text/calendar parts)
+			ALTER TABLE messages ADD COLUMN calendar_data TEXT;
+
+			-- Participation status sent in reply to an invitation (ACCEPTED, TENTATIVE, DECLINED)
+			ALTER TABLE messages ADD COLUMN calendar_response TEXT;
+		`,
+	},
+	{
+		Version: 33,
+		SQL: `
+			-- Calendar sources (CalDAV servers, or Google accounts via CalDAV)
+			CREATE TABLE IF NOT EXISTS calendar_sources (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				type TEXT NOT NULL,
+				url TEXT NOT NULL,
+				username TEXT,
+				account_id TEXT REFERENCES accounts(id) ON DELETE CASCADE,
+				enabled INTEGER DEFAULT 1,
+				sync_interval INTEGER DEFAULT 30,
+				last_synced_at DATETIME,
+				last_error TEXT,
+				last_error_at DATETIME,
+				encrypted_password TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			-- Calendar collections of each source
+			CREATE TABLE IF NOT EXISTS calendar_source_calendars (
+				id TEXT PRIMARY KEY,
+				source_id TEXT NOT NULL REFERENCES calendar_sources(id) ON DELETE CASCADE,
+				path TEXT NOT NULL,
+				name TEXT,
+				components TEXT,
+				enabled INTEGER DEFAULT 1,
+				sync_token TEXT,
+				last_synced_at DATETIME
+			);
+			CREATE INDEX IF NOT EXISTS idx_calendar_source_calendars_source ON calendar_source_calendars(source_id);
+
+			-- Calendar object resources (one iCalendar file per href: a VEVENT or
+			-- VTODO with its overridden occurrences). range_start/range_end bound
+			-- all occurrences; range_end is NULL for endless recurrences.
+			CREATE TABLE IF NOT EXISTS caldav_objects (
+				id TEXT PRIMARY KEY,
+				calendar_id TEXT NOT NULL REFERENCES calendar_source_calendars(id) ON DELETE CASCADE,
+				href TEXT NOT NULL,
+				etag TEXT,
+				uid TEXT,
+				component TEXT NOT NULL,
+				summary TEXT,
+				range_start DATETIME,
+				range_end DATETIME,
+				recurring INTEGER DEFAULT 0,
+				ical TEXT NOT NULL,
+				synced_at DATETIME,
+				UNIQUE(calendar_id, href)
+			);
+			CREATE INDEX IF NOT EXISTS idx_caldav_objects_calendar ON caldav_objects(calendar_id);
+			CREATE INDEX IF NOT EXISTS idx_caldav_objects_range ON caldav_objects(range_start, range_end);
+		`,
+	},
+	{
+		Version: 34,
+		SQL: `
+			-- Muted conversations: new messages are never notified and are
+			-- optionally marked read and/or archived as they arrive
+			CREATE TABLE IF NOT EXISTS muted_threads (
+				account_id TEXT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+				thread_id TEXT NOT NULL,
+				subject TEXT,
+				mark_read INTEGER DEFAULT 1,
+				archive INTEGER DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (account_id, thread_id)
+			);
+		`,
+	},
+	{
+		Version: 35,
+		SQL: `
+			-- Keep fields encrypted with the master password out of the
+			-- full-text index: ciphertext is useless to search and would
+			-- produce random prefix matches. The same expression is used on
+			-- delete so the index sees exactly the values it was given.
+			DROP TRIGGER IF EXISTS messages_fts_insert;
+			DROP TRIGGER IF EXISTS messages_fts_delete;
+			DROP TRIGGER IF EXISTS messages_fts_update;
+
+			CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+				INSERT INTO messages_fts(rowid, subject, from_name, from_email, to_list, cc_list, snippet, body_text)
+				VALUES (NEW.rowid, NEW.subject, NEW.from_name, NEW.from_email, NEW.to_list, NEW.cc_list,
+					CASE WHEN NEW.snippet LIKE 'aenc1:%' THEN NULL ELSE NEW.snippet END,
+					CASE WHEN NEW.body_text LIKE 'aenc1:%' THEN NULL ELSE NEW.body_text END);
+			END;
+
+			CREATE TRIGGER messages_fts_delete AFTER DELETE ON messages BEGIN
+				INSERT INTO messages_fts(messages_fts, rowid, subject, from_name, from_email, to_list, cc_list, snippet, body_text)
+				VALUES ('delete', OLD.rowid, OLD.subject, OLD.from_name, OLD.from_email, OLD.to_list, OLD.cc_list,
+					CASE WHEN OLD.snippet LIKE 'aenc1:%' THEN NULL ELSE OLD.snippet END,
+					CASE WHEN OLD.body_text LIKE 'aenc1:%' THEN NULL ELSE OLD.body_text END);
+			END;
+
+			CREATE TRIGGER messages_fts_update AFTER UPDATE ON messages BEGIN
+				INSERT INTO messages_fts(messages_fts, rowid, subject, from_name, from_email, to_list, cc_list, snippet, body_text)
+				VALUES ('delete', OLD.rowid, OLD.subject, OLD.from_name, OLD.from_email, OLD.to_list, OLD.cc_list,
+					CASE WHEN OLD.snippet LIKE 'aenc1:%' THEN NULL ELSE OLD.snippet END,
+					CASE WHEN OLD.body_text LIKE 'aenc1:%' THEN NULL ELSE OLD.body_text END);
+				INSERT INTO messages_fts(rowid, subject, from_name, from_email, to_list, cc_list, snippet, body_text)
+				VALUES (NEW.rowid, NEW.subject, NEW.from_name, NEW.from_email, NEW.to_list, NEW.cc_list,
+					CASE WHEN NEW.snippet LIKE 'aenc1:%' THEN NULL ELSE NEW.snippet END,
+					CASE WHEN NEW.body_text LIKE 'aenc1:%' THEN NULL ELSE NEW.body_text END);
+			END;
+		`,
+	},
+	{
+		Version: 36,
+		SQL: `
+			-- Bodies removed by storage management to stay within the cache
+			-- budget. Background body sync skips these; opening the message
+			-- fetches the body again and clears the flag.
+			ALTER TABLE messages ADD COLUMN body_evicted INTEGER NOT NULL DEFAULT 0;
+		`,
+	},
+	{
+		Version: 37,
+		SQL: `
+			-- Content-addressed attachment blobs stored on disk, keyed by
+			-- SHA-256 of the content. ref_count is maintained by the triggers
+			-- below so every way of deleting attachments (including cascades
+			-- from messages, folders and accounts) releases the blob.
+			-- last_used_at (unix seconds) protects blobs that were just written
+			-- but not yet referenced from garbage collection.
+			CREATE TABLE attachment_blobs (
+				hash TEXT PRIMARY KEY,
+				size INTEGER NOT NULL DEFAULT 0,
+				ref_count INTEGER NOT NULL DEFAULT 0,
+				last_used_at INTEGER NOT NULL DEFAULT 0
+			);
+
+			CREATE INDEX idx_attachment_blobs_unreferenced ON attachment_blobs(last_used_at) WHERE ref_count <= 0;
+
+			ALTER TABLE attachments ADD COLUMN content_hash TEXT;
+
+			CREATE INDEX idx_attachments_content_hash ON attachments(content_hash) WHERE content_hash IS NOT NULL;
+
+			CREATE TRIGGER attachments_blob_insert AFTER INSERT ON attachments
+			WHEN new.content_hash IS NOT NULL BEGIN
+				UPDATE attachment_blobs SET ref_count = ref_count + 1 WHERE hash = new.content_hash;
+			END;
+
+			CREATE TRIGGER attachments_blob_delete AFTER DELETE ON attachments
+			WHEN old.content_hash IS NOT NULL BEGIN
+				UPDATE attachment_blobs SET ref_count = ref_count - 1 WHERE hash = old.content_hash;
+			END;
+
+			CREATE TRIGGER attachments_blob_update AFTER UPDATE OF content_hash ON attachments
+			WHEN old.content_hash IS NOT new.content_hash BEGIN
+				UPDATE attachment_blobs SET ref_count = ref_count - 1 WHERE hash = old.content_hash;
+				UPDATE attachment_blobs SET ref_count = ref_count + 1 WHERE hash = new.content_hash;
+			END;
+		`,
+	},
+	{
+		Version: 38,
+		SQL: `
+			-- Server quotas (IMAP QUOTA, RFC 9208) per account, refreshed on
+			-- folder sync. STORAGE usage and limit are stored in bytes.
+			CREATE TABLE account_quotas (
+				account_id TEXT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+				quota_root TEXT NOT NULL DEFAULT '',
+				resource TEXT NOT NULL,
+				usage INTEGER NOT NULL DEFAULT 0,
+				quota_limit INTEGER NOT NULL DEFAULT 0,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (account_id, quota_root, resource)
+			);
+
+			-- Finding the largest messages to free server space
+			CREATE INDEX idx_messages_account_size ON messages(account_id, size);
+		`,
+	},
+	{
+		Version: 39,
+		SQL: `
+			-- Per-account proxy as JSON (netproxy.Config); NULL uses the
+			-- global proxy setting
+			ALTER TABLE accounts ADD COLUMN proxy TEXT;
+
+			-- Encrypted proxy passwords when the system keyring is not
+			-- available. scope is an account ID or 'global'.
+			CREATE TABLE proxy_credentials (
+				scope TEXT PRIMARY KEY,
+				encrypted_password TEXT
+			);
+		`,
+	},
+	{
+		Version: 40,
+		SQL: `
+			-- Per-identity SMTP server for aliases that must be sent through
+			-- a different relay. A NULL smtp_host sends through the account's
+			-- SMTP server. encrypted_smtp_password is the fallback when the
+			-- system keyring is not available.
+			ALTER TABLE identities ADD COLUMN smtp_host TEXT;
+			ALTER TABLE identities ADD COLUMN smtp_port INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE identities ADD COLUMN smtp_security TEXT;
+			ALTER TABLE identities ADD COLUMN smtp_username TEXT;
+			ALTER TABLE identities ADD COLUMN encrypted_smtp_password TEXT;
+
+			-- Delivered-To / X-Original-To addresses (JSON array), to reply
+			-- from the alias a message was delivered to
+			ALTER TABLE messages ADD COLUMN delivered_to TEXT;
+		`,
+	},
+	{
+		Version: 41,
+		SQL: `
+			-- Per-recipient delivery state of sent messages, from SMTP
+			-- acceptance and delivery status notifications (RFC 3464).
+			-- message_id is the Message-ID header without angle brackets.
+			CREATE TABLE delivery_status (
+				account_id TEXT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+				message_id TEXT NOT NULL,
+				recipient TEXT NOT NULL,
+				state TEXT NOT NULL,
+				status TEXT,
+				diagnostic TEXT,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (account_id, message_id, recipient)
+			);
+		`,
+	},
+	{
+		Version: 42,
+		SQL: `
+			-- Forget the delivery states of a sent message once no copy of
+			-- it is left in the account. messages.message_id may keep the
+			-- angle brackets; delivery_status.message_id never does.
+			CREATE TRIGGER delivery_status_prune AFTER DELETE ON messages
+			WHEN OLD.message_id IS NOT NULL AND OLD.message_id != '' BEGIN
+				DELETE FROM delivery_status
+				WHERE account_id = OLD.account_id
+					AND message_id = trim(OLD.message_id, '<> ' || char(9, 10, 13))
+					AND NOT EXISTS (
+						SELECT 1 FROM messages
+						WHERE account_id = OLD.account_id
+							AND message_id IN (delivery_status.message_id, '<' || delivery_status.message_id || '>')
+					);
+			END;
+		`,
+	},
+	{
+		Version: 43,
+		SQL: `
+			-- Threads unmuted locally. Flag sync doesn't re-mute them from a
+			-- $Muted keyword until removing the keyword has reached the
+			-- server and a grace period has passed.
+			CREATE TABLE IF NOT EXISTS thread_unmutes (
+				account_id TEXT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+				thread_id TEXT NOT NULL,
+				keyword_cleared INTEGER NOT NULL DEFAULT 0,
+				unmuted_at DATETIME NOT NULL,
+				PRIMARY KEY (account_id, thread_id)
+			);
+		`,
+	},
+	{
+		Version: 44,
+		SQL: `
+			-- New inbox messages waiting for their body before the spam
+			-- classifier scores them. Deleting a message (including expiry
+			-- from the sync period) or moving it out of its folder drops it.
+			CREATE TABLE IF NOT EXISTS spam_pending (
+				message_id TEXT PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+				queued_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE TRIGGER spam_pending_move AFTER UPDATE OF folder_id ON messages
+			WHEN NEW.folder_id != OLD.folder_id BEGIN
+				DELETE FROM spam_pending WHERE message_id = NEW.id;
+			END;
+		`,
+	},
 }
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// RetryOnBusy retries a database operation with exponential backoff.
+// This handles SQLITE_BUSY errors that occur during concurrent database access.
+func RetryOnBusy(operation func() error, maxRetries int, baseDelay time.Duration, log zerolog.Logger) error {
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		err = operation()
+		if err == nil {
+			return nil
+		}
+		// Check if it's a SQLITE_BUSY error (retryable)
+		if !strings.Contains(err.Error(), "database is locked") &&
+			!strings.Contains(err.Error(), "SQLITE_BUSY") {
+			return err // Non-retryable error, return immediately
+		}
+		// Exponential backoff: 100ms, 200ms, 400ms, 800ms, 1600ms
+		delay := baseDelay * time.Duration(1<<uint(i))
+		log.Debug().
+			Int("attempt", i+1).
+			Int("maxRetries", maxRetries).
+			Dur("delay", delay).
+			Msg("Database busy, retrying after delay")
+		time.Sleep(delay)
+	}
+	return fmt.Errorf("operation failed after %d retries: %w", maxRetries, err)
+}
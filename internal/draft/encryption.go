@@ -0,0 +1,169 @@
+package draft
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hkdb/aerion/internal/crypto"
+)
+
+// rewriteBatchSize is the number of drafts encrypted or decrypted per transaction
+const rewriteBatchSize = 200
+
+// RewriteProgressCallback reports progress while encrypting or decrypting existing drafts
+type RewriteProgressCallback func(done, total int)
+
+// SetFieldCipher sets the cipher used for draft bodies and attachment data
+func (s *Store) SetFieldCipher(c *crypto.FieldCipher) {
+	s.cipher = c
+}
+
+// seal encrypts the sensitive fields of a draft for writing
+func (s *Store) seal(d *Draft) (bodyHTML, bodyText string, attachments []byte, err error) {
+	if bodyHTML, err = s.cipher.SealString(d.BodyHTML); err != nil {
+		return "", "", nil, fmt.Errorf("failed to encrypt draft: %w", err)
+	}
+	if bodyText, err = s.cipher.SealString(d.BodyText); err != nil {
+		return "", "", nil, fmt.Errorf("failed to encrypt draft: %w", err)
+	}
+	if attachments, err = s.cipher.SealBytes(d.AttachmentsData); err != nil {
+		return "", "", nil, fmt.Errorf("failed to encrypt draft: %w", err)
+	}
+	return bodyHTML, bodyText, attachments, nil
+}
+
+// open decrypts the sensitive fields of a draft after reading. Fails with
+// crypto.ErrLocked while locked, so a draft is never synced or edited with
+// its body missing.
+func (s *Store) open(d *Draft) error {
+	var err error
+	if d.BodyHTML, err = s.cipher.OpenString(d.BodyHTML); err != nil {
+		return err
+	}
+	if d.BodyText, err = s.cipher.OpenString(d.BodyText); err != nil {
+		return err
+	}
+	if d.AttachmentsData, err = s.cipher.OpenBytes(d.AttachmentsData); err != nil {
+		return err
+	}
+	return nil
+}
+
+// EncryptExisting encrypts all plaintext draft bodies and attachment data
+// with the store's cipher
+func (s *Store) EncryptExisting(ctx context.Context, progress RewriteProgressCallback) error {
+	return s.rewrite(ctx, true, progress)
+}
+
+// DecryptExisting decrypts all encrypted draft fields back to plaintext.
+// The cipher must be unlocked.
+func (s *Store) DecryptExisting(ctx context.Context, progress RewriteProgressCallback) error {
+	return s.rewrite(ctx, false, progress)
+}
+
+// rewrite encrypts or decrypts the fields of every draft in batches.
+// Fields already in the target state are left alone, so an interrupted
+// pass can be repeated.
+func (s *Store) rewrite(ctx context.Context, encrypt bool, progress RewriteProgressCallback) error {
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM drafts").Scan(&total); err != nil {
+		return fmt.Errorf("failed to count drafts: %w", err)
+	}
+
+	transformString := s.cipher.OpenString
+	transformBytes := s.cipher.OpenBytes
+	if encrypt {
+		transformString = s.cipher.SealString
+		transformBytes = s.cipher.SealBytes
+	}
+
+	done := 0
+	var lastRowID int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, next, err := s.rewriteBatch(lastRowID, transformString, transformBytes)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		lastRowID = next
+		done += n
+		if progress != nil {
+			progress(min(done, total), total)
+		}
+	}
+}
+
+// rewriteBatch rewrites the fields of the next batch of drafts after
+// lastRowID. Returns the number of rows read and the last rowid.
+func (s *Store) rewriteBatch(lastRowID int64, transformString func(string) (string, error), transformBytes func([]byte) ([]byte, error)) (int, int64, error) {
+	rows, err := s.db.Query(`
+		SELECT rowid, COALESCE(body_html, ''), COALESCE(body_text, ''), attachments_data
+		FROM drafts WHERE rowid > ? ORDER BY rowid LIMIT ?
+	`, lastRowID, rewriteBatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query drafts: %w", err)
+	}
+
+	type draftFields struct {
+		rowid              int64
+		bodyHTML, bodyText string
+		attachments        []byte
+	}
+	var batch []draftFields
+	for rows.Next() {
+		var f draftFields
+		if err := rows.Scan(&f.rowid, &f.bodyHTML, &f.bodyText, &f.attachments); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan draft: %w", err)
+		}
+		batch = append(batch, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read drafts: %w", err)
+	}
+	if len(batch) == 0 {
+		return 0, lastRowID, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, f := range batch {
+		bodyHTML, err := transformString(f.bodyHTML)
+		if err != nil {
+			return 0, 0, err
+		}
+		bodyText, err := transformString(f.bodyText)
+		if err != nil {
+			return 0, 0, err
+		}
+		attachments, err := transformBytes(f.attachments)
+		if err != nil {
+			return 0, 0, err
+		}
+		if bodyHTML == f.bodyHTML && bodyText == f.bodyText && string(attachments) == string(f.attachments) {
+			continue
+		}
+
+		if _, err := tx.Exec(`
+			UPDATE drafts SET body_html = ?, body_text = ?, attachments_data = ? WHERE rowid = ?
+		`, bodyHTML, bodyText, nullBytes(attachments), f.rowid); err != nil {
+			return 0, 0, fmt.Errorf("failed to rewrite draft: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(batch), batch[len(batch)-1].rowid, nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hkdb/aerion/internal/crypto"
 	"github.com/hkdb/aerion/internal/database"
 	"github.com/hkdb/aerion/internal/logging"
 	"github.com/rs/zerolog"
@@ -13,8 +14,9 @@ import (
 
 // Store provides draft persistence operations
 type Store struct {
-	db  *database.DB
-	log zerolog.Logger
+	db     *database.DB
+	cipher *crypto.FieldCipher
+	log    zerolog.Logger
 }
 
 // NewStore creates a new draft store
@@ -36,6 +38,11 @@ func (s *Store) Create(d *Draft) error {
 		d.SyncStatus = SyncStatusPending
 	}
 
+	bodyHTML, bodyText, attachmentsData, err := s.seal(d)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO drafts (
 			id, account_id, to_list, cc_list, bcc_list, subject,
@@ -48,12 +55,12 @@ func (s *Store) Create(d *Draft) error {
 		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.Exec(query,
+	_, err = s.db.Exec(query,
 		d.ID, d.AccountID, d.ToList, d.CcList, d.BccList, d.Subject,
-		d.BodyHTML, d.BodyText, nullString(d.InReplyToID), nullString(d.ReplyType), nullString(d.ReferencesList),
+		bodyHTML, bodyText, nullString(d.InReplyToID), nullString(d.ReplyType), nullString(d.ReferencesList),
 		nullString(d.IdentityID), d.SignMessage, d.Encrypted, nullBytes(d.EncryptedBody),
 		d.PGPSignMessage, d.PGPEncrypted, nullBytes(d.PGPEncryptedBody),
-		nullBytes(d.AttachmentsData),
+		nullBytes(attachmentsData),
 		d.SyncStatus, nullUint32(d.IMAPUID), nullString(d.FolderID),
 		nullTime(d.LastSyncAttempt), nullString(d.SyncError), d.CreatedAt, d.UpdatedAt,
 	)
@@ -73,6 +80,11 @@ func (s *Store) Create(d *Draft) error {
 func (s *Store) Update(d *Draft) error {
 	d.UpdatedAt = time.Now()
 
+	bodyHTML, bodyText, attachmentsData, err := s.seal(d)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE drafts SET
 			to_list = ?, cc_list = ?, bcc_list = ?, subject = ?,
@@ -86,13 +98,13 @@ func (s *Store) Update(d *Draft) error {
 		WHERE id = ?
 	`
 
-	_, err := s.db.Exec(query,
+	_, err = s.db.Exec(query,
 		d.ToList, d.CcList, d.BccList, d.Subject,
-		d.BodyHTML, d.BodyText, nullString(d.InReplyToID), nullString(d.ReplyType),
+		bodyHTML, bodyText, nullString(d.InReplyToID), nullString(d.ReplyType),
 		nullString(d.ReferencesList), nullString(d.IdentityID), d.SignMessage,
 		d.Encrypted, nullBytes(d.EncryptedBody),
 		d.PGPSignMessage, d.PGPEncrypted, nullBytes(d.PGPEncryptedBody),
-		nullBytes(d.AttachmentsData),
+		nullBytes(attachmentsData),
 		d.SyncStatus, nullUint32(d.IMAPUID),
 		nullString(d.FolderID), nullTime(d.LastSyncAttempt), nullString(d.SyncError), d.UpdatedAt,
 		d.ID,
@@ -160,6 +172,9 @@ func (s *Store) Get(id string) (*Draft, error) {
 	if lastSyncAttempt.Valid {
 		d.LastSyncAttempt = &lastSyncAttempt.Time
 	}
+	if err := s.open(d); err != nil {
+		return nil, err
+	}
 
 	return d, nil
 }
@@ -215,6 +230,9 @@ func (s *Store) GetByIMAPUID(folderID string, imapUID uint32) (*Draft, error) {
 	if lastSyncAttempt.Valid {
 		d.LastSyncAttempt = &lastSyncAttempt.Time
 	}
+	if err := s.open(d); err != nil {
+		return nil, err
+	}
 
 	return d, nil
 }
@@ -354,6 +372,9 @@ func (s *Store) scanDrafts(rows *sql.Rows) ([]*Draft, error) {
 		if lastSyncAttempt.Valid {
 			d.LastSyncAttempt = &lastSyncAttempt.Time
 		}
+		if err := s.open(d); err != nil {
+			return nil, err
+		}
 
 		drafts = append(drafts, d)
 	}
@@ -0,0 +1,270 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxRedirectorDepth limits how many nested redirector wrappers are unwrapped
+// from a single link (e.g. a Safe Links URL wrapping a newsletter click tracker)
+const maxRedirectorDepth = 5
+
+// Redirector describes a click-tracking redirect URL whose real target is
+// embedded in one of its query parameters, e.g. https://www.google.com/url?q=<target>
+type Redirector struct {
+	Host       string `json:"host"`                 // Host to match (subdomains also match)
+	PathPrefix string `json:"pathPrefix,omitempty"` // Optional path prefix to match (e.g. "/url")
+	Param      string `json:"param"`                // Query parameter holding the target URL
+}
+
+// LinkCleanerRules configures which tracking parameters are removed and
+// which redirectors are unwrapped
+type LinkCleanerRules struct {
+	TrackingParams        []string     `json:"trackingParams"`        // Exact parameter names (case-insensitive)
+	TrackingParamPrefixes []string     `json:"trackingParamPrefixes"` // Parameter name prefixes (e.g. "utm_")
+	Redirectors           []Redirector `json:"redirectors"`
+}
+
+// DefaultLinkCleanerRules returns the built-in tracking parameter and redirector rules
+func DefaultLinkCleanerRules() LinkCleanerRules {
+	return LinkCleanerRules{
+		TrackingParams: []string{
+			// Ad networks / social
+			"fbclid", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "msclkid",
+			"yclid", "twclid", "ttclid", "li_fat_id", "igshid", "epik",
+			// Mailchimp
+			"mc_cid", "mc_eid",
+			// HubSpot
+			"_hsenc", "_hsmi", "__hstc", "__hssc", "__hsfp", "hsctatracking",
+			// Marketo
+			"mkt_tok",
+			// Other newsletter / ESP trackers
+			"oly_anon_id", "oly_enc_id", "vero_id", "vero_conv", "_openstat",
+			"wickedid", "rb_clickid", "s_cid", "ml_subscriber", "ml_subscriber_hash",
+			"ss_source", "ss_campaign_id", "sc_cid", "trk_contact", "trk_msg", "trk_module", "trk_sid",
+			"_ke", "_kx", "mbid", "cmpid",
+		},
+		TrackingParamPrefixes: []string{
+			"utm_",
+			"pk_",  // Piwik/Matomo campaign
+			"mtm_", // Matomo campaign
+		},
+		Redirectors: []Redirector{
+			{Host: "google.com", PathPrefix: "/url", Param: "q"},
+			{Host: "google.com", PathPrefix: "/url", Param: "url"},
+			{Host: "safelinks.protection.outlook.com", Param: "url"},
+			{Host: "l.facebook.com", PathPrefix: "/l.php", Param: "u"},
+			{Host: "lm.facebook.com", PathPrefix: "/l.php", Param: "u"},
+			{Host: "l.instagram.com", Param: "u"},
+			{Host: "youtube.com", PathPrefix: "/redirect", Param: "q"},
+			{Host: "slack-redir.net", PathPrefix: "/link", Param: "url"},
+			{Host: "steamcommunity.com", PathPrefix: "/linkfilter", Param: "url"},
+			{Host: "duckduckgo.com", PathPrefix: "/l", Param: "uddg"},
+			{Host: "linkedin.com", PathPrefix: "/redir/redirect", Param: "url"},
+			{Host: "exit.sc", Param: "url"},
+		},
+	}
+}
+
+// Merge returns a copy of the rules extended with the given additional rules
+func (r LinkCleanerRules) Merge(extra LinkCleanerRules) LinkCleanerRules {
+	merged := LinkCleanerRules{
+		TrackingParams:        append(append([]string{}, r.TrackingParams...), extra.TrackingParams...),
+		TrackingParamPrefixes: append(append([]string{}, r.TrackingParamPrefixes...), extra.TrackingParamPrefixes...),
+		Redirectors:           append(append([]Redirector{}, r.Redirectors...), extra.Redirectors...),
+	}
+	return merged
+}
+
+// Validate checks the rules for incomplete redirectors
+func (r LinkCleanerRules) Validate() error {
+	for _, rd := range r.Redirectors {
+		if rd.Host == "" || rd.Param == "" {
+			return fmt.Errorf("invalid redirector: host and param are required")
+		}
+	}
+	return nil
+}
+
+// LinkCleaner strips tracking parameters and unwraps click-tracking redirectors
+type LinkCleaner struct {
+	params      map[string]bool
+	prefixes    []string
+	redirectors []Redirector
+}
+
+// NewLinkCleaner creates a link cleaner from the given rules
+func NewLinkCleaner(rules LinkCleanerRules) *LinkCleaner {
+	c := &LinkCleaner{
+		params: make(map[string]bool, len(rules.TrackingParams)),
+	}
+	for _, p := range rules.TrackingParams {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p != "" {
+			c.params[p] = true
+		}
+	}
+	for _, p := range rules.TrackingParamPrefixes {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p != "" {
+			c.prefixes = append(c.prefixes, p)
+		}
+	}
+	for _, r := range rules.Redirectors {
+		host := strings.ToLower(strings.TrimSpace(r.Host))
+		if host == "" || r.Param == "" {
+			continue
+		}
+		c.redirectors = append(c.redirectors, Redirector{
+			Host:       host,
+			PathPrefix: r.PathPrefix,
+			Param:      r.Param,
+		})
+	}
+	return c
+}
+
+// CleanURL unwraps known redirectors and removes tracking parameters from a URL.
+// Returns the cleaned URL and the number of trackers removed. Non-http(s) URLs
+// and URLs that cannot be parsed are returned unchanged.
+func (c *LinkCleaner) CleanURL(rawURL string) (string, int) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return rawURL, 0
+	}
+
+	removed := 0
+
+	// Unwrap nested redirectors
+	for depth := 0; depth < maxRedirectorDepth; depth++ {
+		target := c.redirectTarget(u)
+		if target == nil {
+			break
+		}
+		u = target
+		removed++
+	}
+
+	// Strip tracking parameters while preserving the order of the rest
+	if u.RawQuery != "" {
+		query, n := c.stripParams(u.RawQuery)
+		if n > 0 {
+			u.RawQuery = query
+			u.ForceQuery = false
+			removed += n
+		}
+	}
+
+	if removed == 0 {
+		return rawURL, 0
+	}
+	return u.String(), removed
+}
+
+// CleanHTML rewrites the href of every link in sanitized HTML content.
+// Returns the rewritten HTML and the total number of trackers removed.
+func (c *LinkCleaner) CleanHTML(content string) (string, int) {
+	total := 0
+	result := hrefRe.ReplaceAllStringFunc(content, func(match string) string {
+		parts := hrefRe.FindStringSubmatch(match)
+		if len(parts) < 4 {
+			return match
+		}
+		quote := parts[2]
+		original := html.UnescapeString(parts[3])
+		cleaned, n := c.CleanURL(original)
+		if n == 0 {
+			return match
+		}
+		total += n
+		return parts[1] + quote + html.EscapeString(cleaned) + quote
+	})
+	return result, total
+}
+
+// CleanText rewrites bare http(s) URLs in plain text content.
+// Returns the rewritten text and the total number of trackers removed.
+func (c *LinkCleaner) CleanText(text string) (string, int) {
+	total := 0
+	result := textURLRe.ReplaceAllStringFunc(text, func(match string) string {
+		// Keep trailing punctuation outside the URL
+		trimmed := strings.TrimRight(match, ".,;:!?)]'\"")
+		suffix := match[len(trimmed):]
+		cleaned, n := c.CleanURL(trimmed)
+		if n == 0 {
+			return match
+		}
+		total += n
+		return cleaned + suffix
+	})
+	return result, total
+}
+
+var (
+	hrefRe    = regexp.MustCompile(`(?i)(<a\b[^>]*?\shref=)(["'])([^"']*)["']`)
+	textURLRe = regexp.MustCompile(`https?://[^\s<>"]+`)
+)
+
+// redirectTarget returns the embedded target URL if u matches a known redirector
+func (c *LinkCleaner) redirectTarget(u *url.URL) *url.URL {
+	host := strings.ToLower(u.Hostname())
+	for _, r := range c.redirectors {
+		if host != r.Host && !strings.HasSuffix(host, "."+r.Host) {
+			continue
+		}
+		if r.PathPrefix != "" && !strings.HasPrefix(u.Path, r.PathPrefix) {
+			continue
+		}
+		value := u.Query().Get(r.Param)
+		if value == "" {
+			continue
+		}
+		target, err := url.Parse(value)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			continue
+		}
+		return target
+	}
+	return nil
+}
+
+// stripParams removes tracking parameters from a raw query string
+func (c *LinkCleaner) stripParams(rawQuery string) (string, int) {
+	pairs := strings.Split(rawQuery, "&")
+	kept := pairs[:0]
+	removed := 0
+	for _, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+		key := pair
+		if i := strings.Index(pair, "="); i >= 0 {
+			key = pair[:i]
+		}
+		if decoded, err := url.QueryUnescape(key); err == nil {
+			key = decoded
+		}
+		if c.isTrackingParam(strings.ToLower(key)) {
+			removed++
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	return strings.Join(kept, "&"), removed
+}
+
+// isTrackingParam checks a lowercased parameter name against the rules
+func (c *LinkCleaner) isTrackingParam(key string) bool {
+	if c.params[key] {
+		return true
+	}
+	for _, p := range c.prefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,12 +6,15 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/emersion/go-imap/v2"
 	"github.com/emersion/go-imap/v2/imapclient"
 	"github.com/emersion/go-sasl"
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/rs/zerolog"
 )
 
@@ -72,6 +75,9 @@ type ClientConfig struct {
 
 	// TLS config (optional, used for certificate TOFU verification)
 	TLSConfig *tls.Config
+
+	// Proxy (optional, nil uses the global proxy)
+	Proxy *netproxy.Config
 }
 
 // DefaultConfig returns a ClientConfig with sensible defaults
@@ -103,7 +109,7 @@ func NewClient(config ClientConfig) *Client {
 
 // Connect establishes a connection to the IMAP server and logs in
 func (c *Client) Connect() error {
-	addr := fmt.Sprintf("%s:%d", c.config.Host, c.config.Port)
+	addr := net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port))
 
 	c.log.Debug().
 		Str("host", c.config.Host).
@@ -116,10 +122,8 @@ func (c *Client) Connect() error {
 	var err error
 	options := &imapclient.Options{}
 
-	// Create a dialer with connect timeout
-	dialer := &net.Dialer{
-		Timeout: c.config.ConnectTimeout,
-	}
+	// Dial through the configured proxy (or directly) with connect timeout
+	ctx := context.Background()
 
 	switch c.config.Security {
 	case SecurityTLS:
@@ -129,7 +133,7 @@ func (c *Client) Connect() error {
 		if tlsConfig == nil {
 			tlsConfig = &tls.Config{ServerName: c.config.Host}
 		}
-		rawConn, dialErr := tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+		rawConn, dialErr := netproxy.DialTLS(ctx, c.config.Proxy, c.config.ConnectTimeout, addr, tlsConfig)
 		if dialErr != nil {
 			return fmt.Errorf("failed to connect with TLS: %w", dialErr)
 		}
@@ -146,17 +150,25 @@ func (c *Client) Connect() error {
 	case SecurityStartTLS:
 		// Connect plain first, then upgrade (port 143)
 		// Use custom TLSConfig if provided (for certificate TOFU)
+		options.TLSConfig = &tls.Config{ServerName: c.config.Host}
 		if c.config.TLSConfig != nil {
-			options.TLSConfig = c.config.TLSConfig
+			options.TLSConfig = c.config.TLSConfig.Clone()
+			if options.TLSConfig.ServerName == "" {
+				options.TLSConfig.ServerName = c.config.Host
+			}
+		}
+		rawConn, dialErr := netproxy.Dial(ctx, c.config.Proxy, c.config.ConnectTimeout, addr)
+		if dialErr != nil {
+			return fmt.Errorf("failed to connect with STARTTLS: %w", dialErr)
 		}
-		c.client, err = imapclient.DialStartTLS(addr, options)
+		c.client, err = imapclient.NewStartTLS(rawConn, options)
 		if err != nil {
 			return fmt.Errorf("failed to connect with STARTTLS: %w", err)
 		}
 
 	case SecurityNone:
 		// Plain connection (not recommended)
-		rawConn, dialErr := dialer.Dial("tcp", addr)
+		rawConn, dialErr := netproxy.Dial(ctx, c.config.Proxy, c.config.ConnectTimeout, addr)
 		if dialErr != nil {
 			return fmt.Errorf("failed to connect: %w", dialErr)
 		}
@@ -645,6 +657,64 @@ func (c *Client) GetMailboxStatus(ctx context.Context, name string) (*Mailbox, e
 	}
 }
 
+// SupportsQuota returns true if the server supports QUOTA (RFC 9208)
+func (c *Client) SupportsQuota() bool {
+	return c.HasCap(imap.CapQuota)
+}
+
+// Quota is the usage and limit of one resource under a quota root
+type Quota struct {
+	Root     string // Quota root, "" on most servers
+	Resource string // "STORAGE", "MESSAGE", "MAILBOX", ...
+	Usage    int64  // In KiB for STORAGE, otherwise a count
+	Limit    int64
+}
+
+// GetQuota returns the quotas that apply to INBOX, which on most servers
+// cover the whole mailbox. Returns nil if the server doesn't support QUOTA.
+// Uses a goroutine to allow context cancellation since Wait() blocks indefinitely.
+func (c *Client) GetQuota(ctx context.Context) ([]Quota, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	if !c.SupportsQuota() {
+		return nil, nil
+	}
+
+	type quotaResult struct {
+		data []imapclient.QuotaData
+		err  error
+	}
+	resultCh := make(chan quotaResult, 1)
+	go func() {
+		data, err := c.client.GetQuotaRoot("INBOX").Wait()
+		resultCh <- quotaResult{data, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		c.log.Debug().Msg("Quota request cancelled by context")
+		return nil, ctx.Err()
+	case result := <-resultCh:
+		if result.err != nil {
+			return nil, fmt.Errorf("failed to get quota: %w", result.err)
+		}
+
+		var quotas []Quota
+		for _, data := range result.data {
+			for resource, usage := range data.Resources {
+				quotas = append(quotas, Quota{
+					Root:     data.Root,
+					Resource: strings.ToUpper(string(resource)),
+					Usage:    usage.Usage,
+					Limit:    usage.Limit,
+				})
+			}
+		}
+		return quotas, nil
+	}
+}
+
 // RawClient returns the underlying imapclient.Client
 // Use with caution - mainly for advanced operations
 func (c *Client) RawClient() *imapclient.Client {
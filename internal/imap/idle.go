@@ -5,12 +5,14 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/emersion/go-imap/v2/imapclient"
 	"github.com/emersion/go-sasl"
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/rs/zerolog"
 )
 
@@ -266,42 +268,35 @@ func (ic *IdleConnection) ensureConnected(ctx context.Context) error {
 		},
 	}
 
-	addr := fmt.Sprintf("%s:%d", creds.Host, creds.Port)
+	addr := net.JoinHostPort(creds.Host, strconv.Itoa(creds.Port))
 	var client *imapclient.Client
 
+	// Dial through the account's proxy (or the global one, or directly)
 	switch SecurityType(creds.Security) {
-	case SecurityTLS:
-		if creds.TLSConfig != nil {
-			// Use custom TLS config (certificate TOFU) with manual dial
-			dialer := &net.Dialer{Timeout: 30 * time.Second}
-			rawConn, dialErr := tls.DialWithDialer(dialer, "tcp", addr, creds.TLSConfig)
-			if dialErr != nil {
-				return fmt.Errorf("failed to connect with TLS: %w", dialErr)
-			}
-			client = imapclient.New(rawConn, options)
-		} else {
-			client, err = imapclient.DialTLS(addr, options)
-		}
 	case SecurityStartTLS:
 		if creds.TLSConfig != nil {
 			options.TLSConfig = creds.TLSConfig
 		} else {
 			options.TLSConfig = &tls.Config{ServerName: creds.Host}
 		}
-		client, err = imapclient.DialStartTLS(addr, options)
+		rawConn, dialErr := netproxy.Dial(ctx, creds.Proxy, 30*time.Second, addr)
+		if dialErr != nil {
+			return fmt.Errorf("failed to connect: %w", dialErr)
+		}
+		client, err = imapclient.NewStartTLS(rawConn, options)
 	case SecurityNone:
-		client, err = imapclient.DialInsecure(addr, options)
+		rawConn, dialErr := netproxy.Dial(ctx, creds.Proxy, 30*time.Second, addr)
+		if dialErr != nil {
+			return fmt.Errorf("failed to connect: %w", dialErr)
+		}
+		client = imapclient.New(rawConn, options)
 	default:
-		if creds.TLSConfig != nil {
-			dialer := &net.Dialer{Timeout: 30 * time.Second}
-			rawConn, dialErr := tls.DialWithDialer(dialer, "tcp", addr, creds.TLSConfig)
-			if dialErr != nil {
-				return fmt.Errorf("failed to connect with TLS: %w", dialErr)
-			}
-			client = imapclient.New(rawConn, options)
-		} else {
-			client, err = imapclient.DialTLS(addr, options)
+		// TLS, using the custom TLS config (certificate TOFU) if provided
+		rawConn, dialErr := netproxy.DialTLS(ctx, creds.Proxy, 30*time.Second, addr, creds.TLSConfig)
+		if dialErr != nil {
+			return fmt.Errorf("failed to connect with TLS: %w", dialErr)
 		}
+		client = imapclient.New(rawConn, options)
 	}
 
 	if err != nil {
@@ -71,6 +71,16 @@ func (c *UnixClient) Ping(ctx context.Context) error {
 	return nil
 }
 
+// IsListening reports whether a server accepts connections at address
+func IsListening(address string) bool {
+	conn, err := net.DialTimeout("unix", address, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
 // NewClient creates a new platform-appropriate IPC client.
 // On Unix systems (Linux/macOS), this returns a UnixClient.
 func NewClient(address string) Client {
@@ -74,6 +74,17 @@ func (c *PipeClient) Ping(ctx context.Context) error {
 	return nil
 }
 
+// IsListening reports whether a server accepts connections at address
+func IsListening(address string) bool {
+	timeout := time.Second
+	conn, err := winio.DialPipe(address, &timeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
 // NewClient creates a new platform-appropriate IPC client.
 // On Windows, this returns a PipeClient.
 func NewClient(address string) Client {
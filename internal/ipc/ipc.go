@@ -92,3 +92,13 @@ type ClientInfo struct {
 	// Authenticated indicates whether the client has successfully authenticated
 	Authenticated bool
 }
+
+// instanceFileName namespaces a runtime file per instance so several profiles
+// can run side by side, e.g. "ipc.sock" becomes "ipc-work.sock". The default
+// instance keeps the plain name.
+func instanceFileName(name, instanceID, ext string) string {
+	if instanceID == "" {
+		return name + ext
+	}
+	return name + "-" + instanceID + ext
+}
@@ -2,6 +2,7 @@ package ipc
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -39,6 +40,28 @@ const (
 	TypeShutdown = "shutdown"
 )
 
+// Message type constants for CLI -> Main requests.
+// The main app answers each request with a TypeCLIResult or TypeError reply.
+const (
+	// TypeCLISend sends an email
+	TypeCLISend = "cli_send"
+
+	// TypeCLISearch searches conversations
+	TypeCLISearch = "cli_search"
+
+	// TypeCLISync syncs one or all accounts
+	TypeCLISync = "cli_sync"
+
+	// TypeCLIUnreadCount returns inbox unread counts
+	TypeCLIUnreadCount = "cli_unread_count"
+
+	// TypeCLIOpen shows a message in the main window
+	TypeCLIOpen = "cli_open"
+
+	// TypeCLIResult is the successful reply to a CLI request
+	TypeCLIResult = "cli_result"
+)
+
 // Bidirectional message type constants
 const (
 	// TypePing is a health check request
@@ -110,6 +133,77 @@ type AuthResponsePayload struct {
 	Error   string `json:"error,omitempty"`
 }
 
+// CLISendPayload is the payload for TypeCLISend messages.
+type CLISendPayload struct {
+	Account     string          `json:"account"` // Account ID, email or name; empty for the first account
+	To          []string        `json:"to"`
+	Cc          []string        `json:"cc,omitempty"`
+	Bcc         []string        `json:"bcc,omitempty"`
+	Subject     string          `json:"subject"`
+	Body        string          `json:"body"`
+	Attachments []CLIAttachment `json:"attachments,omitempty"`
+}
+
+// CLIAttachment is a file attached by the CLI. The CLI reads the file so the
+// main app doesn't need access to the caller's working directory.
+type CLIAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"`
+}
+
+// CLISearchPayload is the payload for TypeCLISearch messages.
+type CLISearchPayload struct {
+	Query   string `json:"query"`
+	Account string `json:"account,omitempty"` // Limit to this account; empty searches all accounts
+	Folder  string `json:"folder,omitempty"`  // Limit to this folder (path, name or type); empty searches all folders
+	Limit   int    `json:"limit,omitempty"`
+}
+
+// CLISearchResult is the reply payload for TypeCLISearch messages.
+type CLISearchResult struct {
+	Results []CLIConversation `json:"results"`
+}
+
+// CLIConversation is a conversation matching a CLI search.
+type CLIConversation struct {
+	AccountID    string    `json:"account_id"`
+	FolderID     string    `json:"folder_id"`
+	FolderName   string    `json:"folder_name,omitempty"`
+	ThreadID     string    `json:"thread_id"`
+	Subject      string    `json:"subject"`
+	From         string    `json:"from"`
+	Snippet      string    `json:"snippet"`
+	Date         time.Time `json:"date"`
+	MessageCount int       `json:"message_count"`
+	UnreadCount  int       `json:"unread_count"`
+	MessageIDs   []string  `json:"message_ids"`
+}
+
+// CLISyncPayload is the payload for TypeCLISync messages.
+type CLISyncPayload struct {
+	Account string `json:"account,omitempty"` // Empty syncs all accounts
+}
+
+// CLIUnreadCountResult is the reply payload for TypeCLIUnreadCount messages.
+type CLIUnreadCountResult struct {
+	Total    int                `json:"total"`
+	Accounts []CLIAccountUnread `json:"accounts"`
+}
+
+// CLIAccountUnread is the inbox unread count of one account.
+type CLIAccountUnread struct {
+	AccountID string `json:"account_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Unread    int    `json:"unread"`
+}
+
+// CLIOpenPayload is the payload for TypeCLIOpen messages.
+type CLIOpenPayload struct {
+	MessageID string `json:"message_id"`
+}
+
 // NewMessage creates a new message with a unique ID.
 func NewMessage(msgType string, payload interface{}) (Message, error) {
 	msg := Message{
@@ -4,12 +4,20 @@ package ipc
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+
+	"github.com/hkdb/aerion/internal/platform"
 )
 
+// maxSocketPathLen is the longest socket path all supported platforms accept:
+// sun_path holds 104 bytes on macOS (108 on Linux) including the trailing NUL
+const maxSocketPathLen = 103
+
 // UnixServer implements the Server interface using Unix domain sockets.
 type UnixServer struct {
 	*BaseServer
@@ -71,6 +79,12 @@ func (s *UnixServer) Stop() error {
 
 // createSocketPath creates the socket directory and returns the socket path.
 func (s *UnixServer) createSocketPath() (string, error) {
+	return DefaultAddress()
+}
+
+// runtimeDir creates the per-user runtime directory holding the socket and
+// the CLI token file, and returns its path.
+func runtimeDir() (string, error) {
 	// Use /tmp/aerion-{uid}/ directory
 	uid := os.Getuid()
 	socketDir := filepath.Join(os.TempDir(), fmt.Sprintf("aerion-%d", uid))
@@ -92,7 +106,43 @@ func (s *UnixServer) createSocketPath() (string, error) {
 		}
 	}
 
-	return filepath.Join(socketDir, "ipc.sock"), nil
+	return socketDir, nil
+}
+
+// DefaultAddress returns the address of the running instance's server, for
+// clients that aren't spawned by it (such as the command-line interface).
+func DefaultAddress() (string, error) {
+	return AddressFor(platform.InstanceID())
+}
+
+// AddressFor returns the server address of the instance with the given ID
+// (see platform.InstanceID)
+func AddressFor(instanceID string) (string, error) {
+	socketDir, err := runtimeDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(socketDir, instanceFileName("ipc", instanceID, ".sock"))
+	if len(path) > maxSocketPathLen {
+		// Long profile names (and temp directories) overflow sun_path; a hash
+		// of the instance ID keeps the socket unique per instance
+		sum := sha256.Sum256([]byte(instanceID))
+		path = filepath.Join(socketDir, instanceFileName("ipc", hex.EncodeToString(sum[:8]), ".sock"))
+	}
+	if len(path) > maxSocketPathLen {
+		return "", fmt.Errorf("socket path is too long: %s", path)
+	}
+	return path, nil
+}
+
+// tokenFilePath returns the path of the file holding the token for
+// command-line clients
+func tokenFilePath() (string, error) {
+	socketDir, err := runtimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(socketDir, instanceFileName("cli", platform.InstanceID(), ".token")), nil
 }
 
 // NewServer creates a new platform-appropriate IPC server.
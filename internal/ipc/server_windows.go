@@ -5,9 +5,12 @@ package ipc
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/user"
+	"path/filepath"
 
 	"github.com/Microsoft/go-winio"
+	"github.com/hkdb/aerion/internal/platform"
 )
 
 // PipeServer implements the Server interface using Windows named pipes.
@@ -63,6 +66,12 @@ func (s *PipeServer) Stop() error {
 
 // createPipeName generates the named pipe path.
 func (s *PipeServer) createPipeName() (string, error) {
+	return AddressFor(platform.InstanceID())
+}
+
+// AddressFor returns the server address of the instance with the given ID
+// (see platform.InstanceID)
+func AddressFor(instanceID string) (string, error) {
 	currentUser, err := user.Current()
 	if err != nil {
 		return "", fmt.Errorf("failed to get current user: %w", err)
@@ -71,10 +80,33 @@ func (s *PipeServer) createPipeName() (string, error) {
 	// Use \\.\pipe\aerion-{username} format
 	// The username is sanitized to remove any characters that might cause issues
 	pipeName := fmt.Sprintf(`\\.\pipe\aerion-%s`, currentUser.Username)
+	if instanceID != "" {
+		pipeName += "-" + instanceID
+	}
 
 	return pipeName, nil
 }
 
+// DefaultAddress returns the address of the running instance's server, for
+// clients that aren't spawned by it (such as the command-line interface).
+func DefaultAddress() (string, error) {
+	return (&PipeServer{}).createPipeName()
+}
+
+// tokenFilePath returns the path of the file holding the token for
+// command-line clients
+func tokenFilePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	dir := filepath.Join(configDir, "Aerion")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return filepath.Join(dir, instanceFileName("cli", platform.InstanceID(), ".token")), nil
+}
+
 // NewServer creates a new platform-appropriate IPC server.
 // On Windows, this returns a PipeServer.
 func NewServer(tokenMgr *TokenManager) Server {
@@ -0,0 +1,44 @@
+package ipc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// WriteTokenFile stores the token where command-line clients can read it.
+// The file is readable by the current user only.
+func WriteTokenFile(token string) error {
+	path, err := tokenFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(token), 0600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0600)
+}
+
+// ReadTokenFile reads the token written by the running instance
+func ReadTokenFile() (string, error) {
+	path, err := tokenFilePath()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("aerion is not running")
+		}
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// RemoveTokenFile removes the token file on shutdown
+func RemoveTokenFile() {
+	if path, err := tokenFilePath(); err == nil {
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,63 @@
+package localapi
+
+import (
+	"sync"
+)
+
+// Event types published on the /v1/events stream
+const (
+	EventNewMail      = "new-mail"      // New messages arrived in a folder
+	EventSyncProgress = "sync-progress" // Folder sync progress
+	EventSyncComplete = "sync-complete" // Folder sync finished
+	EventSyncError    = "sync-error"    // Folder sync failed
+	EventMailbox      = "mailbox"       // IDLE push notification (expunge, flags changed)
+)
+
+// subscriberBuffer is the number of events buffered per stream before
+// events are dropped for a slow client
+const subscriberBuffer = 64
+
+// Event is a server-sent event
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// hub fans events out to the connected event streams
+type hub struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subscribers: make(map[chan Event]struct{})}
+}
+
+// subscribe registers a new event stream
+func (h *hub) subscribe() chan Event {
+	ch := make(chan Event, subscriberBuffer)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+// unsubscribe removes an event stream
+func (h *hub) unsubscribe(ch chan Event) {
+	h.mu.Lock()
+	delete(h.subscribers, ch)
+	h.mu.Unlock()
+}
+
+// publish sends an event to every stream without blocking the caller
+func (h *hub) publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Slow client, drop the event
+		}
+	}
+}
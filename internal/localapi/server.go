@@ -0,0 +1,377 @@
+// Package localapi provides an opt-in, read-only HTTP API on the loopback
+// interface for scripts and status bar widgets (waybar, polybar, ...).
+//
+// Every request must carry the bearer token from the token file:
+//
+//	curl -H "Authorization: Bearer $(cat ~/.local/share/aerion/local-api.token)" \
+//	     http://127.0.0.1:7468/v1/unread
+package localapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hkdb/aerion/internal/account"
+	"github.com/hkdb/aerion/internal/folder"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/message"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// defaultSearchLimit is the number of search results returned without a limit parameter
+	defaultSearchLimit = 20
+
+	// maxSearchLimit caps the limit parameter
+	maxSearchLimit = 200
+
+	// keepAliveInterval is how often an idle event stream receives a comment
+	// so proxies and clients don't time out
+	keepAliveInterval = 30 * time.Second
+)
+
+// Server is the local API HTTP server
+type Server struct {
+	accountStore *account.Store
+	folderStore  *folder.Store
+	messageStore *message.Store
+	token        string
+	hub          *hub
+	httpServer   *http.Server
+	listener     net.Listener
+	log          zerolog.Logger
+}
+
+// NewServer creates a new local API server
+func NewServer(accountStore *account.Store, folderStore *folder.Store, messageStore *message.Store, token string) *Server {
+	return &Server{
+		accountStore: accountStore,
+		folderStore:  folderStore,
+		messageStore: messageStore,
+		token:        token,
+		hub:          newHub(),
+		log:          logging.WithComponent("localapi"),
+	}
+}
+
+// Start listens on the loopback interface and serves requests in the background
+func (s *Server) Start(port int) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	s.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/accounts", s.handleAccounts)
+	mux.HandleFunc("GET /v1/accounts/{id}/folders", s.handleFolders)
+	mux.HandleFunc("GET /v1/unread", s.handleUnread)
+	mux.HandleFunc("GET /v1/search", s.handleSearch)
+	mux.HandleFunc("GET /v1/conversation", s.handleConversation)
+	mux.HandleFunc("GET /v1/events", s.handleEvents)
+
+	s.httpServer = &http.Server{
+		Handler:           s.authenticate(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error().Err(err).Msg("Local API server error")
+		}
+	}()
+
+	s.log.Info().Str("address", listener.Addr().String()).Msg("Local API server started")
+	return nil
+}
+
+// Stop shuts the server down, closing open event streams
+func (s *Server) Stop() {
+	if s.httpServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		// Event streams don't finish on their own
+		s.httpServer.Close()
+	}
+	s.log.Info().Msg("Local API server stopped")
+}
+
+// Address returns the address the server listens on
+func (s *Server) Address() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Publish sends an event to all connected event streams
+func (s *Server) Publish(eventType string, data interface{}) {
+	s.hub.publish(Event{Type: eventType, Data: data})
+}
+
+// authenticate rejects requests without the bearer token, and requests
+// from browsers on other origins
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, "cross-origin requests are not allowed")
+			return
+		}
+
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="aerion"`)
+			writeError(w, http.StatusUnauthorized, "invalid or missing bearer token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ============================================================================
+// Handlers
+// ============================================================================
+
+// accountInfo is the public part of an account
+type accountInfo struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Color   string `json:"color"`
+	Enabled bool   `json:"enabled"`
+}
+
+// unreadCounts is the response of /v1/unread
+type unreadCounts struct {
+	Total    int             `json:"total"`
+	Accounts []accountUnread `json:"accounts"`
+}
+
+// accountUnread is the inbox unread count of one account
+type accountUnread struct {
+	AccountID string `json:"accountId"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Unread    int    `json:"unread"`
+}
+
+func (s *Server) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := s.accountStore.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	infos := []accountInfo{}
+	for _, acc := range accounts {
+		infos = append(infos, accountInfo{
+			ID:      acc.ID,
+			Name:    acc.Name,
+			Email:   acc.Email,
+			Color:   acc.Color,
+			Enabled: acc.Enabled,
+		})
+	}
+	writeJSON(w, infos)
+}
+
+func (s *Server) handleFolders(w http.ResponseWriter, r *http.Request) {
+	accountID := r.PathValue("id")
+	if !s.requireAccount(w, accountID) {
+		return
+	}
+
+	folders, err := s.folderStore.List(accountID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if folders == nil {
+		folders = []*folder.Folder{}
+	}
+	writeJSON(w, folders)
+}
+
+func (s *Server) handleUnread(w http.ResponseWriter, r *http.Request) {
+	accounts, err := s.accountStore.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	counts := unreadCounts{Accounts: []accountUnread{}}
+	for _, acc := range accounts {
+		if !acc.Enabled {
+			continue
+		}
+		unread := 0
+		if inbox, err := s.folderStore.GetByType(acc.ID, folder.TypeInbox); err == nil && inbox != nil {
+			unread = inbox.UnreadCount
+		}
+		counts.Accounts = append(counts.Accounts, accountUnread{
+			AccountID: acc.ID,
+			Name:      acc.Name,
+			Email:     acc.Email,
+			Unread:    unread,
+		})
+		counts.Total += unread
+	}
+	writeJSON(w, counts)
+}
+
+// handleSearch searches a folder (folder=ID), every folder of an account
+// (account=ID) or every folder of all enabled accounts
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "missing q parameter")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	var results []*message.ConversationSearchResult
+	var err error
+	if folderID := query.Get("folder"); folderID != "" {
+		f, ferr := s.folderStore.Get(folderID)
+		if ferr != nil {
+			writeError(w, http.StatusInternalServerError, ferr.Error())
+			return
+		}
+		if f == nil {
+			writeError(w, http.StatusNotFound, "folder not found")
+			return
+		}
+		results, _, err = s.messageStore.SearchConversations(f.ID, q, 0, limit)
+		for _, res := range results {
+			res.AccountID = f.AccountID
+			res.FolderID = f.ID
+		}
+	} else {
+		accountID := query.Get("account")
+		if accountID != "" && !s.requireAccount(w, accountID) {
+			return
+		}
+		results, _, err = s.messageStore.SearchConversationsAllFolders(accountID, q, 0, limit)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if results == nil {
+		results = []*message.ConversationSearchResult{}
+	}
+	writeJSON(w, results)
+}
+
+// requireAccount reports whether an account exists, writing a 404 (or 500)
+// response when it doesn't
+func (s *Server) requireAccount(w http.ResponseWriter, accountID string) bool {
+	if _, err := s.accountStore.Get(accountID); err != nil {
+		if errors.Is(err, account.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, "account not found")
+		} else {
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+// handleConversation returns the messages of a thread in a folder
+// (thread=ID&folder=ID)
+func (s *Server) handleConversation(w http.ResponseWriter, r *http.Request) {
+	threadID := r.URL.Query().Get("thread")
+	folderID := r.URL.Query().Get("folder")
+	if threadID == "" || folderID == "" {
+		writeError(w, http.StatusBadRequest, "thread and folder parameters are required")
+		return
+	}
+
+	conv, err := s.messageStore.GetConversation(threadID, folderID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if conv == nil || len(conv.Messages) == 0 {
+		writeError(w, http.StatusNotFound, "conversation not found")
+		return
+	}
+	writeJSON(w, conv)
+}
+
+// handleEvents streams events as server-sent events until the client
+// disconnects or the server stops
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	events := s.hub.subscribe()
+	defer s.hub.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event := <-events:
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				s.log.Warn().Err(err).Str("type", event.Type).Msg("Failed to encode event")
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,45 @@
+package localapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// tokenSize is the number of random bytes in the bearer token (256 bits)
+const tokenSize = 32
+
+// LoadOrCreateToken reads the bearer token from path, creating the file with
+// a new token if it doesn't exist. The token persists across restarts so
+// scripts and widgets don't need to be reconfigured.
+func LoadOrCreateToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	return RegenerateToken(path)
+}
+
+// RegenerateToken writes a new bearer token to path and returns it.
+// The file is readable by the current user only.
+func RegenerateToken(path string) (string, error) {
+	bytes := make([]byte, tokenSize)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(bytes)
+
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write token file: %w", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		return "", fmt.Errorf("failed to set token file permissions: %w", err)
+	}
+	return token, nil
+}
@@ -1,28 +1,36 @@
 package message
 
 import (
+	"context"
 	"database/sql"
 	"encoding/base64"
+	"errors"
 	"fmt"
 
+	"github.com/hkdb/aerion/internal/blobstore"
+	"github.com/hkdb/aerion/internal/crypto"
 	"github.com/hkdb/aerion/internal/database"
 	"github.com/hkdb/aerion/internal/logging"
 )
 
-// AttachmentStore handles attachment metadata persistence
+// AttachmentStore handles attachment metadata persistence. Stored content
+// lives in the blob store, deduplicated across messages and accounts.
 type AttachmentStore struct {
-	db *database.DB
+	db     *database.DB
+	blobs  *blobstore.Store
+	cipher *crypto.FieldCipher
 }
 
 // NewAttachmentStore creates a new attachment store
-func NewAttachmentStore(db *database.DB) *AttachmentStore {
-	store := &AttachmentStore{db: db}
+func NewAttachmentStore(db *database.DB, blobs *blobstore.Store) *AttachmentStore {
+	store := &AttachmentStore{db: db, blobs: blobs}
 	store.ensureContentColumn()
 	return store
 }
 
 // ensureContentColumn adds the content column if it doesn't exist
-// This handles migration for existing databases
+// This handles migration for existing databases. The column is only read
+// until MigrateContentToBlobs has moved its content to the blob store.
 func (s *AttachmentStore) ensureContentColumn() {
 	log := logging.WithComponent("attachment_store")
 
@@ -51,15 +59,19 @@ func (s *AttachmentStore) ensureContentColumn() {
 // For inline attachments, also stores the content for offline access
 func (s *AttachmentStore) Create(a *Attachment) error {
 	query := `
-		INSERT INTO attachments (id, message_id, filename, content_type, size, content_id, is_inline, local_path, content)
+		INSERT INTO attachments (id, message_id, filename, content_type, size, content_id, is_inline, local_path, content_hash)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	// Only store content for inline attachments to save space
-	var content []byte
+	var contentHash string
 	if a.IsInline && len(a.Content) > 0 {
-		content = a.Content
+		hash, err := s.blobs.Put(a.Content)
+		if err != nil {
+			return fmt.Errorf("failed to store attachment content: %w", err)
+		}
+		contentHash = hash
 	}
-	_, err := s.db.Exec(query, a.ID, a.MessageID, a.Filename, a.ContentType, a.Size, nullString(a.ContentID), boolToInt(a.IsInline), nullString(a.LocalPath), content)
+	_, err := s.db.Exec(query, a.ID, a.MessageID, a.Filename, a.ContentType, a.Size, nullString(a.ContentID), boolToInt(a.IsInline), nullString(a.LocalPath), nullString(contentHash))
 	if err != nil {
 		return fmt.Errorf("failed to create attachment: %w", err)
 	}
@@ -137,6 +149,68 @@ func (s *AttachmentStore) Get(id string) (*Attachment, error) {
 	return a, nil
 }
 
+// SaveFetched stores the attachments parsed from a fetched body. Rows kept
+// by storage eviction are matched by filename, content ID and disposition and
+// get their content back, so attachment IDs stay stable; others are created.
+func (s *AttachmentStore) SaveFetched(messageID string, attachments []*Attachment) error {
+	rows, err := s.db.Query(`
+		SELECT id, filename, IFNULL(content_id, ''), is_inline FROM attachments
+		WHERE message_id = ? ORDER BY rowid
+	`, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to list attachments: %w", err)
+	}
+	type existingRow struct {
+		id, filename, contentID string
+		isInline                bool
+	}
+	var existing []*existingRow
+	for rows.Next() {
+		r := &existingRow{}
+		if err := rows.Scan(&r.id, &r.filename, &r.contentID, &r.isInline); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		existing = append(existing, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list attachments: %w", err)
+	}
+
+	for _, a := range attachments {
+		var match *existingRow
+		for i, r := range existing {
+			if r != nil && r.filename == a.Filename && r.contentID == a.ContentID && r.isInline == a.IsInline {
+				match, existing[i] = r, nil
+				break
+			}
+		}
+		if match == nil {
+			if err := s.Create(a); err != nil {
+				return err
+			}
+			continue
+		}
+
+		a.ID = match.id
+		var contentHash string
+		if a.IsInline && len(a.Content) > 0 {
+			hash, err := s.blobs.Put(a.Content)
+			if err != nil {
+				return fmt.Errorf("failed to store attachment content: %w", err)
+			}
+			contentHash = hash
+		}
+		_, err := s.db.Exec("UPDATE attachments SET content_type = ?, size = ?, content_hash = ? WHERE id = ?",
+			a.ContentType, a.Size, nullString(contentHash), a.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update attachment: %w", err)
+		}
+	}
+	return nil
+}
+
 // UpdateLocalPath updates the local path for a downloaded attachment
 func (s *AttachmentStore) UpdateLocalPath(id, localPath string) error {
 	query := `UPDATE attachments SET local_path = ? WHERE id = ?`
@@ -153,6 +227,7 @@ func (s *AttachmentStore) DeleteByMessage(messageID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete attachments: %w", err)
 	}
+	s.blobs.ScheduleGC()
 	return nil
 }
 
@@ -160,9 +235,9 @@ func (s *AttachmentStore) DeleteByMessage(messageID string) error {
 // Returns a map of content_id -> data URL (e.g., "data:image/png;base64,...")
 func (s *AttachmentStore) GetInlineByMessage(messageID string) (map[string]string, error) {
 	query := `
-		SELECT content_id, content_type, content
+		SELECT content_id, content_type, content_hash, content
 		FROM attachments
-		WHERE message_id = ? AND is_inline = 1 AND content IS NOT NULL AND content_id IS NOT NULL
+		WHERE message_id = ? AND is_inline = 1 AND (content_hash IS NOT NULL OR content IS NOT NULL) AND content_id IS NOT NULL
 	`
 	rows, err := s.db.Query(query, messageID)
 	if err != nil {
@@ -173,13 +248,24 @@ func (s *AttachmentStore) GetInlineByMessage(messageID string) (map[string]strin
 	result := make(map[string]string)
 	for rows.Next() {
 		var contentID, contentType string
+		var contentHash sql.NullString
 		var content []byte
 
-		err := rows.Scan(&contentID, &contentType, &content)
+		err := rows.Scan(&contentID, &contentType, &contentHash, &content)
 		if err != nil {
 			continue // Skip malformed rows
 		}
 
+		if contentHash.Valid {
+			content, err = s.blobs.Get(contentHash.String)
+		} else {
+			// Not yet moved to the blob store
+			content, err = s.cipher.OpenBytes(content)
+		}
+		if err != nil {
+			return nil, err
+		}
+
 		if len(content) > 0 && contentID != "" {
 			// Build data URL
 			dataURL := fmt.Sprintf("data:%s;base64,%s", contentType, base64.StdEncoding.EncodeToString(content))
@@ -196,6 +282,22 @@ func (s *AttachmentStore) CreateBatch(attachments []*Attachment) error {
 		return nil
 	}
 
+	log := logging.WithComponent("attachment_store")
+
+	// Store content before the transaction; the blob store writes on its own
+	// connection. Only inline attachments are stored to save space.
+	contentHashes := make([]string, len(attachments))
+	for i, a := range attachments {
+		if a.IsInline && len(a.Content) > 0 {
+			hash, err := s.blobs.Put(a.Content)
+			if err != nil {
+				log.Warn().Err(err).Str("filename", a.Filename).Msg("Failed to store attachment content in batch")
+				continue
+			}
+			contentHashes[i] = hash
+		}
+	}
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -203,7 +305,7 @@ func (s *AttachmentStore) CreateBatch(attachments []*Attachment) error {
 	defer tx.Rollback()
 
 	stmt, err := tx.Prepare(`
-		INSERT INTO attachments (id, message_id, filename, content_type, size, content_id, is_inline, local_path, content)
+		INSERT INTO attachments (id, message_id, filename, content_type, size, content_id, is_inline, local_path, content_hash)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
@@ -211,14 +313,8 @@ func (s *AttachmentStore) CreateBatch(attachments []*Attachment) error {
 	}
 	defer stmt.Close()
 
-	log := logging.WithComponent("attachment_store")
-	for _, a := range attachments {
-		// Only store content for inline attachments to save space
-		var content []byte
-		if a.IsInline && len(a.Content) > 0 {
-			content = a.Content
-		}
-		_, err := stmt.Exec(a.ID, a.MessageID, a.Filename, a.ContentType, a.Size, nullString(a.ContentID), boolToInt(a.IsInline), nullString(a.LocalPath), content)
+	for i, a := range attachments {
+		_, err := stmt.Exec(a.ID, a.MessageID, a.Filename, a.ContentType, a.Size, nullString(a.ContentID), boolToInt(a.IsInline), nullString(a.LocalPath), nullString(contentHashes[i]))
 		if err != nil {
 			log.Debug().Err(err).Str("filename", a.Filename).Msg("Failed to create attachment in batch")
 			// Continue with other attachments
@@ -251,9 +347,85 @@ func (s *AttachmentStore) DeleteAttachmentsForFolder(folderID string) (int64, er
 
 	affected, _ := result.RowsAffected()
 	log.Info().Str("folderID", folderID).Int64("deleted", affected).Msg("Deleted attachments for folder")
+	s.blobs.ScheduleGC()
 	return affected, nil
 }
 
+// MigrateContentToBlobs moves attachment content stored in the database to
+// the blob store in batches. Encrypted content can't be hashed while the
+// database is locked; those rows are left for a later run. Returns the number
+// of attachments moved and left behind.
+func (s *AttachmentStore) MigrateContentToBlobs(ctx context.Context) (int, int, error) {
+	log := logging.WithComponent("attachment_store")
+
+	migrated, skipped := 0, 0
+	var lastRowID int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return migrated, skipped, err
+		}
+
+		rows, err := s.db.Query(`
+			SELECT rowid, content FROM attachments
+			WHERE rowid > ? AND content IS NOT NULL ORDER BY rowid LIMIT ?
+		`, lastRowID, fieldRewriteBatchSize)
+		if err != nil {
+			return migrated, skipped, fmt.Errorf("failed to query attachment content: %w", err)
+		}
+
+		type storedContent struct {
+			rowid   int64
+			content []byte
+		}
+		var batch []storedContent
+		for rows.Next() {
+			var c storedContent
+			if err := rows.Scan(&c.rowid, &c.content); err != nil {
+				rows.Close()
+				return migrated, skipped, fmt.Errorf("failed to scan attachment: %w", err)
+			}
+			batch = append(batch, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return migrated, skipped, fmt.Errorf("failed to read attachment content: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastRowID = batch[len(batch)-1].rowid
+
+		for _, c := range batch {
+			content, err := s.cipher.OpenBytes(c.content)
+			if errors.Is(err, crypto.ErrLocked) {
+				skipped++
+				continue
+			}
+			if err != nil {
+				return migrated, skipped, err
+			}
+
+			var hash interface{}
+			if len(content) > 0 {
+				if hash, err = s.blobs.Put(content); err != nil {
+					return migrated, skipped, err
+				}
+			}
+			if _, err := s.db.Exec(
+				"UPDATE attachments SET content_hash = ?, content = NULL WHERE rowid = ?", hash, c.rowid,
+			); err != nil {
+				return migrated, skipped, fmt.Errorf("failed to update attachment: %w", err)
+			}
+			migrated++
+		}
+	}
+
+	if migrated > 0 || skipped > 0 {
+		log.Info().Int("migrated", migrated).Int("skipped", skipped).Msg("Moved attachment content to blob store")
+	}
+	return migrated, skipped, nil
+}
+
 // boolToInt converts bool to int for SQLite storage
 func boolToInt(b bool) int {
 	if b {
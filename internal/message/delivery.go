@@ -0,0 +1,349 @@
+package message
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hkdb/aerion/internal/database"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/rs/zerolog"
+)
+
+// Delivery states of a sent message for one recipient
+const (
+	DeliverySent      = "sent"      // Accepted by the outgoing server, no report yet
+	DeliveryDelayed   = "delayed"   // Delivery is still being retried
+	DeliveryRelayed   = "relayed"   // Passed on to a server that doesn't send reports
+	DeliveryExpanded  = "expanded"  // Delivered to a list or alias that forwarded it
+	DeliveryDelivered = "delivered" // Delivered to the recipient's mailbox
+	DeliveryFailed    = "failed"    // Delivery failed permanently
+)
+
+// deliveryStateRank orders delivery states so that a late report never
+// replaces a more final one (e.g. "delayed" arriving after "delivered")
+func deliveryStateRank(state string) int {
+	switch state {
+	case DeliveryDelayed:
+		return 1
+	case DeliveryRelayed, DeliveryExpanded:
+		return 2
+	case DeliveryDelivered, DeliveryFailed:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// DeliveryStatus is the delivery state of a sent message for one recipient
+type DeliveryStatus struct {
+	AccountID  string    `json:"accountId"`
+	MessageID  string    `json:"messageId"` // Message-ID header of the sent message, without <>
+	Recipient  string    `json:"recipient"`
+	State      string    `json:"state"`
+	Status     string    `json:"status,omitempty"`     // Enhanced status code, e.g. "5.1.1"
+	Diagnostic string    `json:"diagnostic,omitempty"` // Server reply, e.g. "550 5.1.1 user unknown"
+	UpdatedAt  time.Time `json:"updatedAt"`
+
+	finalRecipient string // Final-Recipient of a report, tried when Recipient isn't known
+}
+
+// DeliveryReport is a parsed delivery status notification (RFC 3464)
+type DeliveryReport struct {
+	EnvelopeID        string            // Original-Envelope-Id (the ENVID given when sending)
+	OriginalMessageID string            // Message-ID of the returned original headers
+	Recipients        []*DeliveryStatus // One per recipient, without account and message
+}
+
+// SentMessageID returns the Message-ID of the message the report is about.
+// Aerion sends the Message-ID as envelope ID; the returned headers are used
+// for reports of messages sent by other clients.
+func (r *DeliveryReport) SentMessageID() string {
+	if r.EnvelopeID != "" {
+		return NormalizeMessageID(r.EnvelopeID)
+	}
+	return NormalizeMessageID(r.OriginalMessageID)
+}
+
+// NormalizeMessageID strips the angle brackets and whitespace of a Message-ID
+func NormalizeMessageID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
+
+// ParseDeliveryStatus parses the body of a message/delivery-status part: a
+// group of per-message fields followed by a group per recipient, separated
+// by blank lines
+func ParseDeliveryStatus(body []byte) (*DeliveryReport, error) {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(body)))
+
+	perMessage, err := r.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse delivery status: %w", err)
+	}
+	report := &DeliveryReport{
+		EnvelopeID: decodeXText(perMessage.Get("Original-Envelope-Id")),
+	}
+
+	for err == nil {
+		var fields textproto.MIMEHeader
+		fields, err = r.ReadMIMEHeader()
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to parse delivery status: %w", err)
+		}
+		if status := parseRecipientFields(fields); status != nil {
+			report.Recipients = append(report.Recipients, status)
+		}
+	}
+
+	if len(report.Recipients) == 0 {
+		return nil, fmt.Errorf("delivery status has no recipients")
+	}
+	return report, nil
+}
+
+// parseRecipientFields builds the status of one recipient, nil if the group
+// doesn't name a recipient or an action
+func parseRecipientFields(fields textproto.MIMEHeader) *DeliveryStatus {
+	// The recipient as given when sending (ORCPT, sent as xtext), else as
+	// finally delivered to
+	finalRecipient := addressField(fields.Get("Final-Recipient"))
+	recipient := addressField(decodeXText(fields.Get("Original-Recipient")))
+	if recipient == "" {
+		recipient = finalRecipient
+	}
+	action := strings.ToLower(strings.TrimSpace(fields.Get("Action")))
+	if recipient == "" || action == "" {
+		return nil
+	}
+
+	var state string
+	switch action {
+	case "failed":
+		state = DeliveryFailed
+	case "delayed":
+		state = DeliveryDelayed
+	case "delivered":
+		state = DeliveryDelivered
+	case "relayed":
+		state = DeliveryRelayed
+	case "expanded":
+		state = DeliveryExpanded
+	default:
+		return nil
+	}
+
+	status := strings.TrimSpace(fields.Get("Status"))
+	if i := strings.IndexAny(status, " \t("); i >= 0 {
+		status = status[:i]
+	}
+
+	return &DeliveryStatus{
+		Recipient:      recipient,
+		State:          state,
+		Status:         status,
+		Diagnostic:     strings.Join(strings.Fields(typedValue(fields.Get("Diagnostic-Code"))), " "),
+		finalRecipient: finalRecipient,
+	}
+}
+
+// addressField returns the lowercased address of a typed address field such
+// as "rfc822; user@example.com"
+func addressField(value string) string {
+	return strings.ToLower(strings.Trim(typedValue(value), "<> "))
+}
+
+// typedValue strips the type of a "type; value" field
+func typedValue(value string) string {
+	if i := strings.Index(value, ";"); i >= 0 {
+		value = value[i+1:]
+	}
+	return strings.TrimSpace(value)
+}
+
+// decodeXText decodes an RFC 3461 xtext value ("+XX" hex escapes)
+func decodeXText(s string) string {
+	s = strings.TrimSpace(s)
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '+' && i+2 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// ============================================================================
+// Delivery status persistence
+// ============================================================================
+
+// DeliveryStore persists the delivery states of sent messages
+type DeliveryStore struct {
+	db  *database.DB
+	log zerolog.Logger
+}
+
+// NewDeliveryStore creates a new delivery status store
+func NewDeliveryStore(db *database.DB) *DeliveryStore {
+	return &DeliveryStore{
+		db:  db,
+		log: logging.WithComponent("delivery-store"),
+	}
+}
+
+// RecordSent records that the outgoing server accepted a message for its
+// recipients. Recipients that already have a state keep it.
+func (s *DeliveryStore) RecordSent(accountID, messageID string, recipients []string) error {
+	messageID = NormalizeMessageID(messageID)
+	if messageID == "" {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, recipient := range recipients {
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO delivery_status (account_id, message_id, recipient, state)
+			VALUES (?, ?, ?, ?)
+		`, accountID, messageID, strings.ToLower(strings.TrimSpace(recipient)), DeliverySent)
+		if err != nil {
+			return fmt.Errorf("failed to record sent message: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ApplyReport updates the delivery states of the message a report is about
+// and returns the states that changed. Only recipients recorded by
+// RecordSent are updated; reports about other messages are ignored.
+func (s *DeliveryStore) ApplyReport(accountID string, report *DeliveryReport) ([]*DeliveryStatus, error) {
+	messageID := report.SentMessageID()
+	if messageID == "" {
+		return nil, fmt.Errorf("delivery report does not identify the sent message")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var changed []*DeliveryStatus
+	now := time.Now()
+	for _, r := range report.Recipients {
+		// Match the recipient as sent, falling back to the final recipient
+		// for servers that rewrite or drop the original one
+		candidates := []string{r.Recipient}
+		if r.finalRecipient != "" && r.finalRecipient != r.Recipient {
+			candidates = append(candidates, r.finalRecipient)
+		}
+
+		var recipient, current string
+		var status, diagnostic sql.NullString
+		for _, candidate := range candidates {
+			err := tx.QueryRow(`
+				SELECT state, status, diagnostic FROM delivery_status
+				WHERE account_id = ? AND message_id = ? AND recipient = ?
+			`, accountID, messageID, candidate).Scan(&current, &status, &diagnostic)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get delivery status: %w", err)
+			}
+			recipient = candidate
+			break
+		}
+		if recipient == "" {
+			// Not a recipient of a message sent from here
+			continue
+		}
+
+		// Skip reports seen before (bodies can be fetched again) and reports
+		// older than the current state
+		if current == r.State && status.String == r.Status && diagnostic.String == r.Diagnostic {
+			continue
+		}
+		if deliveryStateRank(r.State) < deliveryStateRank(current) {
+			continue
+		}
+
+		_, err = tx.Exec(`
+			UPDATE delivery_status SET state = ?, status = ?, diagnostic = ?, updated_at = ?
+			WHERE account_id = ? AND message_id = ? AND recipient = ?
+		`, r.State, nullString(r.Status), nullString(r.Diagnostic), now, accountID, messageID, recipient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save delivery status: %w", err)
+		}
+
+		changed = append(changed, &DeliveryStatus{
+			AccountID:  accountID,
+			MessageID:  messageID,
+			Recipient:  recipient,
+			State:      r.State,
+			Status:     r.Status,
+			Diagnostic: r.Diagnostic,
+			UpdatedAt:  now,
+		})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log.Debug().
+		Str("account", accountID).
+		Str("messageID", messageID).
+		Int("changed", len(changed)).
+		Msg("Delivery report applied")
+	return changed, nil
+}
+
+// GetByMessageID returns the delivery states of a sent message by recipient,
+// empty if nothing is known about its delivery
+func (s *DeliveryStore) GetByMessageID(accountID, messageID string) ([]*DeliveryStatus, error) {
+	rows, err := s.db.Query(`
+		SELECT account_id, message_id, recipient, state, status, diagnostic, updated_at
+		FROM delivery_status
+		WHERE account_id = ? AND message_id = ?
+		ORDER BY recipient
+	`, accountID, NormalizeMessageID(messageID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery status: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := []*DeliveryStatus{}
+	for rows.Next() {
+		d := &DeliveryStatus{}
+		var status, diagnostic, updatedAt sql.NullString
+		if err := rows.Scan(&d.AccountID, &d.MessageID, &d.Recipient, &d.State, &status, &diagnostic, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery status: %w", err)
+		}
+		d.Status = status.String
+		d.Diagnostic = diagnostic.String
+		if updatedAt.Valid {
+			d.UpdatedAt = parseTimeString(updatedAt.String)
+		}
+		statuses = append(statuses, d)
+	}
+	return statuses, rows.Err()
+}
@@ -0,0 +1,294 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/hkdb/aerion/internal/crypto"
+)
+
+// fieldRewriteBatchSize is the number of rows encrypted or decrypted per transaction
+const fieldRewriteBatchSize = 200
+
+// RewriteProgressCallback reports progress while encrypting or decrypting existing data
+type RewriteProgressCallback func(done, total int)
+
+// SetFieldCipher sets the cipher used for message bodies, snippets, raw
+// S/MIME/PGP bodies and invitation calendar data
+func (s *Store) SetFieldCipher(c *crypto.FieldCipher) {
+	s.cipher = c
+}
+
+// SetFieldCipher sets the cipher used for stored attachment content
+func (s *AttachmentStore) SetFieldCipher(c *crypto.FieldCipher) {
+	s.cipher = c
+}
+
+// sealBody encrypts the sensitive text fields of a message for writing
+func (s *Store) sealBody(snippet, bodyText, bodyHTML string) (string, string, string, error) {
+	var err error
+	if snippet, err = s.cipher.SealString(snippet); err != nil {
+		return "", "", "", err
+	}
+	if bodyText, err = s.cipher.SealString(bodyText); err != nil {
+		return "", "", "", err
+	}
+	if bodyHTML, err = s.cipher.SealString(bodyHTML); err != nil {
+		return "", "", "", err
+	}
+	return snippet, bodyText, bodyHTML, nil
+}
+
+// openFields decrypts the sensitive text fields of a message after reading
+func (s *Store) openFields(m *Message) error {
+	var err error
+	if m.Snippet, err = s.cipher.OpenString(m.Snippet); err != nil {
+		return err
+	}
+	if m.BodyText, err = s.cipher.OpenString(m.BodyText); err != nil {
+		return err
+	}
+	if m.BodyHTML, err = s.cipher.OpenString(m.BodyHTML); err != nil {
+		return err
+	}
+	return nil
+}
+
+// openSnippet decrypts a snippet for list views. While locked the snippet is
+// left empty rather than failing the whole list.
+func (s *Store) openSnippet(snippet string) string {
+	opened, err := s.cipher.OpenString(snippet)
+	if err != nil {
+		return ""
+	}
+	return opened
+}
+
+// EncryptExistingFields encrypts all plaintext bodies, snippets, raw
+// S/MIME/PGP bodies, calendar data and attachment content with the store's cipher
+func (s *Store) EncryptExistingFields(ctx context.Context, progress RewriteProgressCallback) error {
+	return s.rewriteFields(ctx, true, progress)
+}
+
+// DecryptExistingFields decrypts all encrypted fields back to plaintext.
+// The cipher must be unlocked.
+func (s *Store) DecryptExistingFields(ctx context.Context, progress RewriteProgressCallback) error {
+	return s.rewriteFields(ctx, false, progress)
+}
+
+// rewriteFields encrypts or decrypts every sensitive field in batches.
+// Rows are tracked by rowid so new messages synced meanwhile don't disturb
+// the iteration; they are written with the current cipher state anyway.
+func (s *Store) rewriteFields(ctx context.Context, encrypt bool, progress RewriteProgressCallback) error {
+	var messageCount, attachmentCount int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM messages").Scan(&messageCount); err != nil {
+		return fmt.Errorf("failed to count messages: %w", err)
+	}
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM attachments WHERE content IS NOT NULL").Scan(&attachmentCount); err != nil {
+		return fmt.Errorf("failed to count attachments: %w", err)
+	}
+	total := messageCount + attachmentCount
+	done := 0
+
+	transformString := s.cipher.OpenString
+	transformBytes := s.cipher.OpenBytes
+	if encrypt {
+		transformString = s.cipher.SealString
+		transformBytes = s.cipher.SealBytes
+	}
+
+	// Messages
+	var lastRowID int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, next, err := s.rewriteMessageBatch(lastRowID, transformString, transformBytes)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		lastRowID = next
+		done += n
+		if progress != nil {
+			progress(min(done, total), total)
+		}
+	}
+
+	// Attachments
+	lastRowID = 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, next, err := s.rewriteAttachmentBatch(lastRowID, transformBytes)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		lastRowID = next
+		done += n
+		if progress != nil {
+			progress(min(done, total), total)
+		}
+	}
+
+	return nil
+}
+
+// rewriteMessageBatch rewrites the fields of the next batch of messages after
+// lastRowID. Returns the number of rows read and the last rowid.
+func (s *Store) rewriteMessageBatch(lastRowID int64, transformString func(string) (string, error), transformBytes func([]byte) ([]byte, error)) (int, int64, error) {
+	rows, err := s.db.Query(`
+		SELECT rowid, snippet, body_text, body_html, calendar_data, smime_raw_body, pgp_raw_body
+		FROM messages WHERE rowid > ? ORDER BY rowid LIMIT ?
+	`, lastRowID, fieldRewriteBatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query messages: %w", err)
+	}
+
+	type messageFields struct {
+		rowid                                     int64
+		snippet, bodyText, bodyHTML, calendarData sql.NullString
+		smimeRaw, pgpRaw                          []byte
+	}
+	var batch []messageFields
+	for rows.Next() {
+		var f messageFields
+		if err := rows.Scan(&f.rowid, &f.snippet, &f.bodyText, &f.bodyHTML, &f.calendarData, &f.smimeRaw, &f.pgpRaw); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan message: %w", err)
+		}
+		batch = append(batch, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read messages: %w", err)
+	}
+	if len(batch) == 0 {
+		return 0, lastRowID, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, f := range batch {
+		snippet, err := transformString(f.snippet.String)
+		if err != nil {
+			return 0, 0, err
+		}
+		bodyText, err := transformString(f.bodyText.String)
+		if err != nil {
+			return 0, 0, err
+		}
+		bodyHTML, err := transformString(f.bodyHTML.String)
+		if err != nil {
+			return 0, 0, err
+		}
+		calendarData, err := transformString(f.calendarData.String)
+		if err != nil {
+			return 0, 0, err
+		}
+		smimeRaw, err := transformBytes(f.smimeRaw)
+		if err != nil {
+			return 0, 0, err
+		}
+		pgpRaw, err := transformBytes(f.pgpRaw)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if snippet == f.snippet.String && bodyText == f.bodyText.String && bodyHTML == f.bodyHTML.String &&
+			calendarData == f.calendarData.String && string(smimeRaw) == string(f.smimeRaw) && string(pgpRaw) == string(f.pgpRaw) {
+			continue
+		}
+
+		_, err = tx.Exec(`
+			UPDATE messages SET snippet = ?, body_text = ?, body_html = ?, calendar_data = ?, smime_raw_body = ?, pgp_raw_body = ?
+			WHERE rowid = ?
+		`, nullString(snippet), nullString(bodyText), nullString(bodyHTML), nullString(calendarData), nullBytes(smimeRaw), nullBytes(pgpRaw), f.rowid)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to rewrite message: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(batch), batch[len(batch)-1].rowid, nil
+}
+
+// rewriteAttachmentBatch rewrites the content of the next batch of stored
+// attachments after lastRowID. Returns the number of rows read and the last rowid.
+func (s *Store) rewriteAttachmentBatch(lastRowID int64, transformBytes func([]byte) ([]byte, error)) (int, int64, error) {
+	rows, err := s.db.Query(`
+		SELECT rowid, content FROM attachments
+		WHERE rowid > ? AND content IS NOT NULL ORDER BY rowid LIMIT ?
+	`, lastRowID, fieldRewriteBatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query attachments: %w", err)
+	}
+
+	type attachmentContent struct {
+		rowid   int64
+		content []byte
+	}
+	var batch []attachmentContent
+	for rows.Next() {
+		var a attachmentContent
+		if err := rows.Scan(&a.rowid, &a.content); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		batch = append(batch, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read attachments: %w", err)
+	}
+	if len(batch) == 0 {
+		return 0, lastRowID, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, a := range batch {
+		content, err := transformBytes(a.content)
+		if err != nil {
+			return 0, 0, err
+		}
+		if string(content) == string(a.content) {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE attachments SET content = ? WHERE rowid = ?", content, a.rowid); err != nil {
+			return 0, 0, fmt.Errorf("failed to rewrite attachment: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(batch), batch[len(batch)-1].rowid, nil
+}
+
+// nullBytes returns nil for empty byte slices so the column stays NULL
+func nullBytes(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
@@ -164,7 +164,9 @@ func (f *FTSIndexer) IndexFolder(ctx context.Context, folderID string) error {
 
 		// Get batch of message rowids that need indexing
 		rows, err := f.db.QueryContext(ctx, `
-			SELECT m.rowid, m.subject, m.from_name, m.from_email, m.to_list, m.cc_list, m.snippet, m.body_text
+			SELECT m.rowid, m.subject, m.from_name, m.from_email, m.to_list, m.cc_list,
+			       CASE WHEN m.snippet LIKE 'aenc1:%' THEN NULL ELSE m.snippet END,
+			       CASE WHEN m.body_text LIKE 'aenc1:%' THEN NULL ELSE m.body_text END
 			FROM messages m
 			WHERE m.folder_id = ?
 			ORDER BY m.rowid
@@ -50,10 +50,17 @@ type Message struct {
 	BodyHTML    string `json:"bodyHtml,omitempty"`
 	BodyFetched bool   `json:"bodyFetched"` // Whether full body has been downloaded
 
+	// Link cleaning (computed on view, not stored)
+	TrackersRemoved int `json:"trackersRemoved,omitempty"` // Tracking parameters/redirectors stripped from links
+
 	// Read receipt
 	ReadReceiptTo      string `json:"readReceiptTo,omitempty"` // Email requesting receipt (from Disposition-Notification-To header)
 	ReadReceiptHandled bool   `json:"readReceiptHandled"`      // Whether user has responded (sent or ignored)
 
+	// Addresses the message was delivered to (JSON array, from Delivered-To and
+	// X-Original-To headers), used to reply from the alias that received it
+	DeliveredTo string `json:"deliveredTo,omitempty"`
+
 	// S/MIME status (empty = not S/MIME)
 	SMIMEStatus        string `json:"smimeStatus,omitempty"`
 	SMIMESignerEmail   string `json:"smimeSignerEmail,omitempty"`
@@ -72,6 +79,10 @@ type Message struct {
 	PGPEncrypted bool `json:"pgpEncrypted,omitempty"` // Whether the message is PGP encrypted
 	HasPGP       bool `json:"hasPGP,omitempty"`       // Computed: pgp_raw_body IS NOT NULL
 
+	// Meeting invitation (text/calendar part)
+	HasCalendar      bool   `json:"hasCalendar,omitempty"`      // Computed: calendar_data IS NOT NULL
+	CalendarResponse string `json:"calendarResponse,omitempty"` // PARTSTAT sent in reply (ACCEPTED, TENTATIVE, DECLINED)
+
 	// Timestamps
 	ReceivedAt time.Time `json:"receivedAt"`
 }
@@ -88,6 +99,7 @@ type MessageHeader struct {
 	AccountID string `json:"accountId"`
 	FolderID  string `json:"folderId"`
 	UID       uint32 `json:"uid"`
+	ThreadID  string `json:"threadId,omitempty"`
 
 	Subject   string    `json:"subject"`
 	FromName  string    `json:"fromName"`
@@ -107,6 +119,7 @@ func (m *Message) ToHeader() *MessageHeader {
 		AccountID:      m.AccountID,
 		FolderID:       m.FolderID,
 		UID:            m.UID,
+		ThreadID:       m.ThreadID,
 		Subject:        m.Subject,
 		FromName:       m.FromName,
 		FromEmail:      m.FromEmail,
@@ -0,0 +1,204 @@
+package message
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hkdb/aerion/internal/database"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/rs/zerolog"
+)
+
+// MutedKeyword is the IMAP keyword that marks messages of a muted thread
+const MutedKeyword = "$Muted"
+
+// unmuteGracePeriod is how long after the $Muted keyword was removed from an
+// unmuted thread a keyword seen by flag sync is still ignored. A flag sync
+// that started before the removal can report the old flags.
+const unmuteGracePeriod = 10 * time.Minute
+
+// MutedThread is a conversation whose new messages never notify
+type MutedThread struct {
+	AccountID string    `json:"accountId"`
+	ThreadID  string    `json:"threadId"`
+	Subject   string    `json:"subject"`
+	MarkRead  bool      `json:"markRead"` // Mark new messages as read
+	Archive   bool      `json:"archive"`  // Archive new messages
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ThreadMuteStore handles muted thread persistence
+type ThreadMuteStore struct {
+	db  *database.DB
+	log zerolog.Logger
+}
+
+// NewThreadMuteStore creates a new muted thread store
+func NewThreadMuteStore(db *database.DB) *ThreadMuteStore {
+	return &ThreadMuteStore{
+		db:  db,
+		log: logging.WithComponent("thread-mute-store"),
+	}
+}
+
+// Mute mutes a thread, or updates the options of an already muted thread
+func (s *ThreadMuteStore) Mute(t *MutedThread) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO muted_threads (account_id, thread_id, subject, mark_read, archive)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(account_id, thread_id) DO UPDATE SET
+			mark_read = excluded.mark_read,
+			archive = excluded.archive
+	`, t.AccountID, t.ThreadID, t.Subject, t.MarkRead, t.Archive); err != nil {
+		return fmt.Errorf("failed to mute thread: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM thread_unmutes WHERE account_id = ? AND thread_id = ?`, t.AccountID, t.ThreadID); err != nil {
+		return fmt.Errorf("failed to mute thread: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit mute: %w", err)
+	}
+
+	s.log.Debug().Str("account", t.AccountID).Str("thread", t.ThreadID).Msg("Thread muted")
+	return nil
+}
+
+// Unmute unmutes a thread and remembers the unmute until the $Muted keyword
+// removal is confirmed with KeywordCleared, so flag sync doesn't re-mute it
+func (s *ThreadMuteStore) Unmute(accountID, threadID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM muted_threads WHERE account_id = ? AND thread_id = ?`, accountID, threadID); err != nil {
+		return fmt.Errorf("failed to unmute thread: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO thread_unmutes (account_id, thread_id, keyword_cleared, unmuted_at)
+		VALUES (?, ?, 0, ?)
+		ON CONFLICT(account_id, thread_id) DO UPDATE SET
+			keyword_cleared = 0,
+			unmuted_at = excluded.unmuted_at
+	`, accountID, threadID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to unmute thread: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit unmute: %w", err)
+	}
+
+	s.log.Debug().Str("account", accountID).Str("thread", threadID).Msg("Thread unmuted")
+	return nil
+}
+
+// KeywordCleared records that the $Muted keyword was removed from the
+// server copies of an unmuted thread
+func (s *ThreadMuteStore) KeywordCleared(accountID, threadID string) error {
+	_, err := s.db.Exec(`
+		UPDATE thread_unmutes SET keyword_cleared = 1, unmuted_at = ?
+		WHERE account_id = ? AND thread_id = ?
+	`, time.Now().UTC(), accountID, threadID)
+	if err != nil {
+		return fmt.Errorf("failed to record cleared keyword: %w", err)
+	}
+	return nil
+}
+
+// RecentlyUnmuted reports whether a thread was unmuted locally and the
+// $Muted keyword removal is still pending or was only just done, so a
+// keyword seen on the server is stale
+func (s *ThreadMuteStore) RecentlyUnmuted(accountID, threadID string) bool {
+	var cleared bool
+	var unmutedAt string
+	err := s.db.QueryRow(`
+		SELECT keyword_cleared, unmuted_at FROM thread_unmutes
+		WHERE account_id = ? AND thread_id = ?
+	`, accountID, threadID).Scan(&cleared, &unmutedAt)
+	if err != nil {
+		return false
+	}
+	return !cleared || time.Since(parseTimeString(unmutedAt)) < unmuteGracePeriod
+}
+
+// LatestOptions returns the mark read/archive options of the thread muted
+// most recently in an account, used for threads muted from another device.
+// Without a local mute, new messages are neither marked read nor archived.
+func (s *ThreadMuteStore) LatestOptions(accountID string) (markRead, archive bool) {
+	err := s.db.QueryRow(`
+		SELECT mark_read, archive FROM muted_threads
+		WHERE account_id = ? ORDER BY created_at DESC, rowid DESC LIMIT 1
+	`, accountID).Scan(&markRead, &archive)
+	if err != nil {
+		return false, false
+	}
+	return markRead, archive
+}
+
+// Get returns a muted thread, or nil if the thread isn't muted
+func (s *ThreadMuteStore) Get(accountID, threadID string) (*MutedThread, error) {
+	row := s.db.QueryRow(`
+		SELECT account_id, thread_id, subject, mark_read, archive, created_at
+		FROM muted_threads WHERE account_id = ? AND thread_id = ?
+	`, accountID, threadID)
+
+	t, err := scanMutedThread(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get muted thread: %w", err)
+	}
+	return t, nil
+}
+
+// IsMuted reports whether a thread is muted
+func (s *ThreadMuteStore) IsMuted(accountID, threadID string) bool {
+	if threadID == "" {
+		return false
+	}
+	t, err := s.Get(accountID, threadID)
+	return err == nil && t != nil
+}
+
+// List returns all muted threads, most recently muted first
+func (s *ThreadMuteStore) List() ([]*MutedThread, error) {
+	rows, err := s.db.Query(`
+		SELECT account_id, thread_id, subject, mark_read, archive, created_at
+		FROM muted_threads ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list muted threads: %w", err)
+	}
+	defer rows.Close()
+
+	var threads []*MutedThread
+	for rows.Next() {
+		t, err := scanMutedThread(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan muted thread: %w", err)
+		}
+		threads = append(threads, t)
+	}
+	return threads, rows.Err()
+}
+
+func scanMutedThread(row interface{ Scan(...interface{}) error }) (*MutedThread, error) {
+	t := &MutedThread{}
+	var subject, createdAt sql.NullString
+	if err := row.Scan(&t.AccountID, &t.ThreadID, &subject, &t.MarkRead, &t.Archive, &createdAt); err != nil {
+		return nil, err
+	}
+	t.Subject = subject.String
+	if createdAt.Valid {
+		t.CreatedAt = parseTimeString(createdAt.String)
+	}
+	return t, nil
+}
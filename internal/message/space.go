@@ -0,0 +1,96 @@
+package message
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SizedMessage is a message with its size on the server, for finding what
+// to delete when the mailbox is nearly full
+type SizedMessage struct {
+	MessageHeader
+	FolderName string `json:"folderName"`
+	Size       int    `json:"size"` // RFC822.SIZE reported by the server
+}
+
+// FolderSize is the total server size of the messages in a folder
+type FolderSize struct {
+	FolderID   string `json:"folderId"`
+	FolderName string `json:"folderName"`
+	FolderPath string `json:"folderPath"`
+	Messages   int    `json:"messages"`
+	Size       int64  `json:"size"`
+}
+
+// ListLargest returns the largest messages of an account, largest first
+func (s *Store) ListLargest(accountID string, limit int) ([]*SizedMessage, error) {
+	rows, err := s.db.Query(`
+		SELECT m.id, m.account_id, m.folder_id, m.uid, COALESCE(m.thread_id, ''), m.subject, m.from_name, m.from_email,
+		       m.date, m.snippet, m.is_read, m.is_starred, m.has_attachments, f.name, m.size
+		FROM messages m
+		JOIN folders f ON f.id = m.folder_id
+		WHERE m.account_id = ? AND m.size > 0
+		ORDER BY m.size DESC
+		LIMIT ?
+	`, accountID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query largest messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*SizedMessage
+	for rows.Next() {
+		m := &SizedMessage{}
+		var dateStr sql.NullString
+		var snippet sql.NullString
+
+		err := rows.Scan(
+			&m.ID, &m.AccountID, &m.FolderID, &m.UID, &m.ThreadID,
+			&m.Subject, &m.FromName, &m.FromEmail,
+			&dateStr, &snippet,
+			&m.IsRead, &m.IsStarred, &m.HasAttachments,
+			&m.FolderName, &m.Size,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+
+		if dateStr.Valid && dateStr.String != "" {
+			m.Date = parseTimeString(dateStr.String)
+		}
+		if snippet.Valid {
+			m.Snippet = s.openSnippet(snippet.String)
+		}
+
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
+
+// FolderSizes returns the folders of an account by total message size,
+// largest first. Only synced messages are counted.
+func (s *Store) FolderSizes(accountID string) ([]*FolderSize, error) {
+	rows, err := s.db.Query(`
+		SELECT f.id, f.name, f.path, COUNT(m.id), COALESCE(SUM(m.size), 0) AS total
+		FROM folders f
+		JOIN messages m ON m.folder_id = f.id
+		WHERE f.account_id = ?
+		GROUP BY f.id
+		ORDER BY total DESC
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query folder sizes: %w", err)
+	}
+	defer rows.Close()
+
+	var folders []*FolderSize
+	for rows.Next() {
+		f := &FolderSize{}
+		if err := rows.Scan(&f.FolderID, &f.FolderName, &f.FolderPath, &f.Messages, &f.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan folder size: %w", err)
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
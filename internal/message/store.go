@@ -10,6 +10,7 @@ import (
 	"unicode"
 
 	"github.com/google/uuid"
+	"github.com/hkdb/aerion/internal/crypto"
 	"github.com/hkdb/aerion/internal/database"
 	"github.com/hkdb/aerion/internal/logging"
 	"github.com/rs/zerolog"
@@ -17,8 +18,11 @@ import (
 
 // Store provides message persistence operations
 type Store struct {
-	db  *database.DB
-	log zerolog.Logger
+	db     *database.DB
+	cipher *crypto.FieldCipher // Encrypts sensitive fields when a master password is set
+	log    zerolog.Logger
+
+	onDelete func() // Called after messages are deleted
 }
 
 // NewStore creates a new message store
@@ -29,10 +33,23 @@ func NewStore(db *database.DB) *Store {
 	}
 }
 
+// SetDeleteCallback sets the callback for message deletion, e.g. to release
+// attachment content no longer referenced
+func (s *Store) SetDeleteCallback(cb func()) {
+	s.onDelete = cb
+}
+
+// notifyDeleted calls the delete callback, if set
+func (s *Store) notifyDeleted() {
+	if s.onDelete != nil {
+		s.onDelete()
+	}
+}
+
 // ListByFolder returns message headers for a folder with pagination
 func (s *Store) ListByFolder(folderID string, offset, limit int) ([]*MessageHeader, error) {
 	query := `
-		SELECT id, account_id, folder_id, uid, subject, from_name, from_email,
+		SELECT id, account_id, folder_id, uid, COALESCE(thread_id, ''), subject, from_name, from_email,
 		       date, snippet, is_read, is_starred, has_attachments
 		FROM messages
 		WHERE folder_id = ?
@@ -53,7 +70,7 @@ func (s *Store) ListByFolder(folderID string, offset, limit int) ([]*MessageHead
 		var snippet sql.NullString
 
 		err := rows.Scan(
-			&m.ID, &m.AccountID, &m.FolderID, &m.UID,
+			&m.ID, &m.AccountID, &m.FolderID, &m.UID, &m.ThreadID,
 			&m.Subject, &m.FromName, &m.FromEmail,
 			&dateStr, &snippet,
 			&m.IsRead, &m.IsStarred, &m.HasAttachments,
@@ -66,7 +83,7 @@ func (s *Store) ListByFolder(folderID string, offset, limit int) ([]*MessageHead
 			m.Date = parseTimeString(dateStr.String)
 		}
 		if snippet.Valid {
-			m.Snippet = snippet.String
+			m.Snippet = s.openSnippet(snippet.String)
 		}
 
 		messages = append(messages, m)
@@ -75,6 +92,57 @@ func (s *Store) ListByFolder(folderID string, offset, limit int) ([]*MessageHead
 	return messages, nil
 }
 
+// ListReceivedSince returns the messages of a folder that were stored
+// since a time, newest first. Unlike the Date header, received_at is set
+// when a sync stores a message, so these are the messages that arrived.
+// At most limit messages are returned.
+func (s *Store) ListReceivedSince(folderID string, since time.Time, limit int) ([]*MessageHeader, error) {
+	query := `
+		SELECT id, account_id, folder_id, uid, COALESCE(thread_id, ''), subject, from_name, from_email,
+		       date, snippet, is_read, is_starred, has_attachments, received_at
+		FROM messages
+		WHERE folder_id = ?
+		ORDER BY rowid DESC
+	`
+
+	rows, err := s.db.Query(query, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*MessageHeader
+	for len(messages) < limit && rows.Next() {
+		m := &MessageHeader{}
+		var dateStr, snippet, receivedAtStr sql.NullString
+
+		err := rows.Scan(
+			&m.ID, &m.AccountID, &m.FolderID, &m.UID, &m.ThreadID,
+			&m.Subject, &m.FromName, &m.FromEmail,
+			&dateStr, &snippet,
+			&m.IsRead, &m.IsStarred, &m.HasAttachments, &receivedAtStr,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+
+		// Stored times don't sort reliably as text; compare them parsed
+		if parseTimeString(receivedAtStr.String).Before(since) {
+			continue
+		}
+		if dateStr.Valid && dateStr.String != "" {
+			m.Date = parseTimeString(dateStr.String)
+		}
+		if snippet.Valid {
+			m.Snippet = s.openSnippet(snippet.String)
+		}
+
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
+
 // ListConversationsUnifiedInbox returns conversations from all inbox folders across all accounts
 // This is used for the unified inbox view
 func (s *Store) ListConversationsUnifiedInbox(offset, limit int, sortOrder string) ([]*Conversation, error) {
@@ -141,7 +209,7 @@ func (s *Store) ListConversationsUnifiedInbox(offset, limit int, sortOrder strin
 		}
 
 		if snippet.Valid {
-			c.Snippet = snippet.String
+			c.Snippet = s.openSnippet(snippet.String)
 		}
 		if latestDateStr.Valid && latestDateStr.String != "" {
 			c.LatestDate = parseTimeString(latestDateStr.String)
@@ -317,20 +385,22 @@ func (s *Store) Get(id string) (*Message, error) {
 		       subject, from_name, from_email, to_list, cc_list, bcc_list, reply_to, date,
 		       snippet, is_read, is_starred, is_answered, is_forwarded, is_draft, is_deleted,
 		       size, has_attachments, body_text, body_html, body_fetched,
-		       read_receipt_to, read_receipt_handled,
+		       read_receipt_to, read_receipt_handled, delivered_to,
 		       smime_status, smime_signer_email, smime_signer_subject,
 		       smime_encrypted, (smime_raw_body IS NOT NULL) as has_smime,
 		       pgp_status, pgp_signer_email, pgp_signer_key_id,
 		       pgp_encrypted, (pgp_raw_body IS NOT NULL) as has_pgp,
+		       (calendar_data IS NOT NULL) as has_calendar, calendar_response,
 		       received_at
 		FROM messages
 		WHERE id = ?
 	`
 
 	m := &Message{}
-	var messageID, inReplyTo, threadID, toList, ccList, bccList, replyTo, snippet, bodyText, bodyHTML, readReceiptTo sql.NullString
+	var messageID, inReplyTo, threadID, toList, ccList, bccList, replyTo, snippet, bodyText, bodyHTML, readReceiptTo, deliveredTo sql.NullString
 	var smimeStatus, smimeSignerEmail, smimeSignerSubject sql.NullString
 	var pgpStatus, pgpSignerEmail, pgpSignerKeyID sql.NullString
+	var calendarResponse sql.NullString
 	var dateStr, receivedAtStr sql.NullString
 
 	err := s.db.QueryRow(query, id).Scan(
@@ -338,11 +408,12 @@ func (s *Store) Get(id string) (*Message, error) {
 		&m.Subject, &m.FromName, &m.FromEmail, &toList, &ccList, &bccList, &replyTo, &dateStr,
 		&snippet, &m.IsRead, &m.IsStarred, &m.IsAnswered, &m.IsForwarded, &m.IsDraft, &m.IsDeleted,
 		&m.Size, &m.HasAttachments, &bodyText, &bodyHTML, &m.BodyFetched,
-		&readReceiptTo, &m.ReadReceiptHandled,
+		&readReceiptTo, &m.ReadReceiptHandled, &deliveredTo,
 		&smimeStatus, &smimeSignerEmail, &smimeSignerSubject,
 		&m.SMIMEEncrypted, &m.HasSMIME,
 		&pgpStatus, &pgpSignerEmail, &pgpSignerKeyID,
 		&m.PGPEncrypted, &m.HasPGP,
+		&m.HasCalendar, &calendarResponse,
 		&receivedAtStr,
 	)
 	if err == sql.ErrNoRows {
@@ -377,7 +448,7 @@ func (s *Store) Get(id string) (*Message, error) {
 		m.Date = parseTimeString(dateStr.String)
 	}
 	if snippet.Valid {
-		m.Snippet = snippet.String
+		m.Snippet = s.openSnippet(snippet.String)
 	}
 	if bodyText.Valid {
 		m.BodyText = bodyText.String
@@ -388,6 +459,7 @@ func (s *Store) Get(id string) (*Message, error) {
 	if readReceiptTo.Valid {
 		m.ReadReceiptTo = readReceiptTo.String
 	}
+	m.DeliveredTo = deliveredTo.String
 	if smimeStatus.Valid {
 		m.SMIMEStatus = smimeStatus.String
 	}
@@ -406,10 +478,15 @@ func (s *Store) Get(id string) (*Message, error) {
 	if pgpSignerKeyID.Valid {
 		m.PGPSignerKeyID = pgpSignerKeyID.String
 	}
+	m.CalendarResponse = calendarResponse.String
 	if receivedAtStr.Valid && receivedAtStr.String != "" {
 		m.ReceivedAt = parseTimeString(receivedAtStr.String)
 	}
 
+	if err := s.openFields(m); err != nil {
+		return nil, err
+	}
+
 	return m, nil
 }
 
@@ -420,20 +497,22 @@ func (s *Store) GetByUID(folderID string, uid uint32) (*Message, error) {
 		       subject, from_name, from_email, to_list, cc_list, bcc_list, reply_to, date,
 		       snippet, is_read, is_starred, is_answered, is_forwarded, is_draft, is_deleted,
 		       size, has_attachments, body_text, body_html, body_fetched,
-		       read_receipt_to, read_receipt_handled,
+		       read_receipt_to, read_receipt_handled, delivered_to,
 		       smime_status, smime_signer_email, smime_signer_subject,
 		       smime_encrypted, (smime_raw_body IS NOT NULL) as has_smime,
 		       pgp_status, pgp_signer_email, pgp_signer_key_id,
 		       pgp_encrypted, (pgp_raw_body IS NOT NULL) as has_pgp,
+		       (calendar_data IS NOT NULL) as has_calendar, calendar_response,
 		       received_at
 		FROM messages
 		WHERE folder_id = ? AND uid = ?
 	`
 
 	m := &Message{}
-	var messageID, inReplyTo, threadID, toList, ccList, bccList, replyTo, snippet, bodyText, bodyHTML, readReceiptTo sql.NullString
+	var messageID, inReplyTo, threadID, toList, ccList, bccList, replyTo, snippet, bodyText, bodyHTML, readReceiptTo, deliveredTo sql.NullString
 	var smimeStatus, smimeSignerEmail, smimeSignerSubject sql.NullString
 	var pgpStatus, pgpSignerEmail, pgpSignerKeyID sql.NullString
+	var calendarResponse sql.NullString
 	var dateStr, receivedAtStr sql.NullString
 
 	err := s.db.QueryRow(query, folderID, uid).Scan(
@@ -441,11 +520,12 @@ func (s *Store) GetByUID(folderID string, uid uint32) (*Message, error) {
 		&m.Subject, &m.FromName, &m.FromEmail, &toList, &ccList, &bccList, &replyTo, &dateStr,
 		&snippet, &m.IsRead, &m.IsStarred, &m.IsAnswered, &m.IsForwarded, &m.IsDraft, &m.IsDeleted,
 		&m.Size, &m.HasAttachments, &bodyText, &bodyHTML, &m.BodyFetched,
-		&readReceiptTo, &m.ReadReceiptHandled,
+		&readReceiptTo, &m.ReadReceiptHandled, &deliveredTo,
 		&smimeStatus, &smimeSignerEmail, &smimeSignerSubject,
 		&m.SMIMEEncrypted, &m.HasSMIME,
 		&pgpStatus, &pgpSignerEmail, &pgpSignerKeyID,
 		&m.PGPEncrypted, &m.HasPGP,
+		&m.HasCalendar, &calendarResponse,
 		&receivedAtStr,
 	)
 	if err == sql.ErrNoRows {
@@ -481,7 +561,7 @@ func (s *Store) GetByUID(folderID string, uid uint32) (*Message, error) {
 		m.Date = parseTimeString(dateStr.String)
 	}
 	if snippet.Valid {
-		m.Snippet = snippet.String
+		m.Snippet = s.openSnippet(snippet.String)
 	}
 	if bodyText.Valid {
 		m.BodyText = bodyText.String
@@ -492,6 +572,7 @@ func (s *Store) GetByUID(folderID string, uid uint32) (*Message, error) {
 	if readReceiptTo.Valid {
 		m.ReadReceiptTo = readReceiptTo.String
 	}
+	m.DeliveredTo = deliveredTo.String
 	if smimeStatus.Valid {
 		m.SMIMEStatus = smimeStatus.String
 	}
@@ -510,10 +591,15 @@ func (s *Store) GetByUID(folderID string, uid uint32) (*Message, error) {
 	if pgpSignerKeyID.Valid {
 		m.PGPSignerKeyID = pgpSignerKeyID.String
 	}
+	m.CalendarResponse = calendarResponse.String
 	if receivedAtStr.Valid && receivedAtStr.String != "" {
 		m.ReceivedAt = parseTimeString(receivedAtStr.String)
 	}
 
+	if err := s.openFields(m); err != nil {
+		return nil, err
+	}
+
 	return m, nil
 }
 
@@ -542,20 +628,25 @@ func (s *Store) Create(m *Message) error {
 			subject, from_name, from_email, to_list, cc_list, bcc_list, reply_to, date,
 			snippet, is_read, is_starred, is_answered, is_forwarded, is_draft, is_deleted,
 			size, has_attachments, body_text, body_html, body_fetched,
-			read_receipt_to, read_receipt_handled, received_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			read_receipt_to, read_receipt_handled, delivered_to, received_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.Exec(query,
+	snippet, bodyText, bodyHTML, err := s.sealBody(m.Snippet, m.BodyText, m.BodyHTML)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt message: %w", err)
+	}
+
+	_, err = s.db.Exec(query,
 		m.ID, m.AccountID, m.FolderID, m.UID,
 		nullString(m.MessageID), nullString(m.InReplyTo), nullString(m.References), nullString(m.ThreadID),
 		m.Subject, m.FromName, m.FromEmail,
 		nullString(m.ToList), nullString(m.CcList), nullString(m.BccList), nullString(m.ReplyTo),
-		m.Date, nullString(m.Snippet),
+		m.Date, nullString(snippet),
 		m.IsRead, m.IsStarred, m.IsAnswered, m.IsForwarded, m.IsDraft, m.IsDeleted,
 		m.Size, m.HasAttachments,
-		nullString(m.BodyText), nullString(m.BodyHTML), m.BodyFetched,
-		nullString(m.ReadReceiptTo), m.ReadReceiptHandled,
+		nullString(bodyText), nullString(bodyHTML), m.BodyFetched,
+		nullString(m.ReadReceiptTo), m.ReadReceiptHandled, nullString(m.DeliveredTo),
 		m.ReceivedAt,
 	)
 	if err != nil {
@@ -574,19 +665,26 @@ func (s *Store) Update(m *Message) error {
 			to_list = ?, cc_list = ?, bcc_list = ?, reply_to = ?, date = ?,
 			snippet = ?, is_read = ?, is_starred = ?, is_answered = ?, is_forwarded = ?,
 			is_draft = ?, is_deleted = ?, size = ?, has_attachments = ?,
-			body_text = ?, body_html = ?, read_receipt_to = ?, read_receipt_handled = ?
+			body_text = ?, body_html = ?, read_receipt_to = ?, read_receipt_handled = ?,
+			delivered_to = ?
 		WHERE id = ?
 	`
 
-	_, err := s.db.Exec(query,
+	snippet, bodyText, bodyHTML, err := s.sealBody(m.Snippet, m.BodyText, m.BodyHTML)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt message: %w", err)
+	}
+
+	_, err = s.db.Exec(query,
 		nullString(m.MessageID), nullString(m.InReplyTo), nullString(m.References), nullString(m.ThreadID),
 		m.Subject, m.FromName, m.FromEmail,
 		nullString(m.ToList), nullString(m.CcList), nullString(m.BccList), nullString(m.ReplyTo),
-		m.Date, nullString(m.Snippet),
+		m.Date, nullString(snippet),
 		m.IsRead, m.IsStarred, m.IsAnswered, m.IsForwarded,
 		m.IsDraft, m.IsDeleted, m.Size, m.HasAttachments,
-		nullString(m.BodyText), nullString(m.BodyHTML),
+		nullString(bodyText), nullString(bodyHTML),
 		nullString(m.ReadReceiptTo), m.ReadReceiptHandled,
+		nullString(m.DeliveredTo),
 		m.ID,
 	)
 	if err != nil {
@@ -694,6 +792,7 @@ func (s *Store) Delete(id string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete message: %w", err)
 	}
+	s.notifyDeleted()
 	return nil
 }
 
@@ -703,6 +802,7 @@ func (s *Store) DeleteByUID(folderID string, uid uint32) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete message: %w", err)
 	}
+	s.notifyDeleted()
 	return nil
 }
 
@@ -712,6 +812,7 @@ func (s *Store) DeleteByFolder(folderID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete messages: %w", err)
 	}
+	s.notifyDeleted()
 	return nil
 }
 
@@ -752,10 +853,14 @@ func (s *Store) GetHighestUID(folderID string) (uint32, error) {
 func (s *Store) UpdateBody(messageID, bodyHTML, bodyText, snippet string) error {
 	query := `
 		UPDATE messages 
-		SET body_html = ?, body_text = ?, snippet = ?, body_fetched = 1
+		SET body_html = ?, body_text = ?, snippet = ?, body_fetched = 1, body_evicted = 0
 		WHERE id = ?
 	`
-	_, err := s.db.Exec(query, nullString(bodyHTML), nullString(bodyText), nullString(snippet), messageID)
+	snippet, bodyText, bodyHTML, err := s.sealBody(snippet, bodyText, bodyHTML)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt body: %w", err)
+	}
+	_, err = s.db.Exec(query, nullString(bodyHTML), nullString(bodyText), nullString(snippet), messageID)
 	if err != nil {
 		return fmt.Errorf("failed to update body: %w", err)
 	}
@@ -773,10 +878,11 @@ func (s *Store) GetMessagesWithoutBody(folderID string, limit int, sinceDate tim
 
 	// Include messages where body_fetched=0 OR body was fetched but is empty (needs re-fetch)
 	// Exclude encrypted messages which intentionally have empty body (decrypted on-view)
+	// Skip bodies evicted by storage management; they are fetched again when opened
 	if sinceDate.IsZero() {
 		query = `
 			SELECT id FROM messages
-			WHERE folder_id = ? AND (
+			WHERE folder_id = ? AND body_evicted = 0 AND (
 				body_fetched = 0 OR
 				(body_fetched = 1 AND smime_encrypted = 0 AND pgp_encrypted = 0 AND (body_text IS NULL OR body_text = '') AND (body_html IS NULL OR body_html = ''))
 			)
@@ -787,7 +893,7 @@ func (s *Store) GetMessagesWithoutBody(folderID string, limit int, sinceDate tim
 	} else {
 		query = `
 			SELECT id FROM messages
-			WHERE folder_id = ? AND (
+			WHERE folder_id = ? AND body_evicted = 0 AND (
 				body_fetched = 0 OR
 				(body_fetched = 1 AND smime_encrypted = 0 AND pgp_encrypted = 0 AND (body_text IS NULL OR body_text = '') AND (body_html IS NULL OR body_html = ''))
 			) AND date >= ?
@@ -829,10 +935,11 @@ func (s *Store) GetMessagesWithoutBodyAndSize(folderID string, limit int, sinceD
 
 	// Include messages where body_fetched=0 OR body was fetched but is empty (needs re-fetch)
 	// Exclude encrypted messages which intentionally have empty body (decrypted on-view)
+	// Skip bodies evicted by storage management; they are fetched again when opened
 	if sinceDate.IsZero() {
 		query = `
 			SELECT id, size FROM messages
-			WHERE folder_id = ? AND (
+			WHERE folder_id = ? AND body_evicted = 0 AND (
 				body_fetched = 0 OR
 				(body_fetched = 1 AND smime_encrypted = 0 AND pgp_encrypted = 0 AND (body_text IS NULL OR body_text = '') AND (body_html IS NULL OR body_html = ''))
 			)
@@ -843,7 +950,7 @@ func (s *Store) GetMessagesWithoutBodyAndSize(folderID string, limit int, sinceD
 	} else {
 		query = `
 			SELECT id, size FROM messages
-			WHERE folder_id = ? AND (
+			WHERE folder_id = ? AND body_evicted = 0 AND (
 				body_fetched = 0 OR
 				(body_fetched = 1 AND smime_encrypted = 0 AND pgp_encrypted = 0 AND (body_text IS NULL OR body_text = '') AND (body_html IS NULL OR body_html = ''))
 			) AND date >= ?
@@ -878,9 +985,10 @@ func (s *Store) CountMessagesWithoutBody(folderID string, sinceDate time.Time) (
 
 	// Include messages where body_fetched=0 OR body was fetched but is empty (needs re-fetch)
 	// Exclude encrypted messages which intentionally have empty body (decrypted on-view)
+	// Skip bodies evicted by storage management; they are fetched again when opened
 	if sinceDate.IsZero() {
 		err = s.db.QueryRow(
-			`SELECT COUNT(*) FROM messages WHERE folder_id = ? AND (
+			`SELECT COUNT(*) FROM messages WHERE folder_id = ? AND body_evicted = 0 AND (
 				body_fetched = 0 OR
 				(body_fetched = 1 AND smime_encrypted = 0 AND pgp_encrypted = 0 AND (body_text IS NULL OR body_text = '') AND (body_html IS NULL OR body_html = ''))
 			)`,
@@ -888,7 +996,7 @@ func (s *Store) CountMessagesWithoutBody(folderID string, sinceDate time.Time) (
 		).Scan(&count)
 	} else {
 		err = s.db.QueryRow(
-			`SELECT COUNT(*) FROM messages WHERE folder_id = ? AND (
+			`SELECT COUNT(*) FROM messages WHERE folder_id = ? AND body_evicted = 0 AND (
 				body_fetched = 0 OR
 				(body_fetched = 1 AND smime_encrypted = 0 AND pgp_encrypted = 0 AND (body_text IS NULL OR body_text = '') AND (body_html IS NULL OR body_html = ''))
 			) AND date >= ?`,
@@ -934,6 +1042,7 @@ func (s *Store) DeleteOlderThan(accountID string, before time.Time) (int, error)
 			Time("before", before).
 			Int64("deleted", affected).
 			Msg("Deleted old messages based on sync period")
+		s.notifyDeleted()
 	}
 
 	return int(affected), nil
@@ -1014,6 +1123,7 @@ type BodyUpdate struct {
 	SMIMEEncrypted     bool
 	PGPRawBody         []byte
 	PGPEncrypted       bool
+	CalendarData       string
 }
 
 // UpdateBodiesBatch updates body content for multiple messages in a single transaction
@@ -1030,10 +1140,11 @@ func (s *Store) UpdateBodiesBatch(updates []BodyUpdate) error {
 
 	stmt, err := tx.Prepare(`
 		UPDATE messages
-		SET body_html = ?, body_text = ?, snippet = ?, body_fetched = 1,
+		SET body_html = ?, body_text = ?, snippet = ?, body_fetched = 1, body_evicted = 0,
 		    smime_status = ?, smime_signer_email = ?, smime_signer_subject = ?,
 		    smime_raw_body = ?, smime_encrypted = ?,
-		    pgp_raw_body = ?, pgp_encrypted = ?
+		    pgp_raw_body = ?, pgp_encrypted = ?,
+		    calendar_data = ?
 		WHERE id = ?
 	`)
 	if err != nil {
@@ -1042,19 +1153,41 @@ func (s *Store) UpdateBodiesBatch(updates []BodyUpdate) error {
 	defer stmt.Close()
 
 	for _, u := range updates {
+		snippet, bodyText, bodyHTML, err := s.sealBody(u.Snippet, u.BodyText, u.BodyHTML)
+		if err != nil {
+			s.log.Warn().Err(err).Str("messageID", u.MessageID).Msg("Failed to encrypt body in batch")
+			continue
+		}
+		smimeRaw, err := s.cipher.SealBytes(u.SMIMERawBody)
+		if err != nil {
+			s.log.Warn().Err(err).Str("messageID", u.MessageID).Msg("Failed to encrypt S/MIME body in batch")
+			continue
+		}
+		pgpRaw, err := s.cipher.SealBytes(u.PGPRawBody)
+		if err != nil {
+			s.log.Warn().Err(err).Str("messageID", u.MessageID).Msg("Failed to encrypt PGP body in batch")
+			continue
+		}
+		calendarData, err := s.cipher.SealString(u.CalendarData)
+		if err != nil {
+			s.log.Warn().Err(err).Str("messageID", u.MessageID).Msg("Failed to encrypt calendar data in batch")
+			continue
+		}
+
 		var smimeRawBody interface{}
-		if len(u.SMIMERawBody) > 0 {
-			smimeRawBody = u.SMIMERawBody
+		if len(smimeRaw) > 0 {
+			smimeRawBody = smimeRaw
 		}
 		var pgpRawBody interface{}
-		if len(u.PGPRawBody) > 0 {
-			pgpRawBody = u.PGPRawBody
+		if len(pgpRaw) > 0 {
+			pgpRawBody = pgpRaw
 		}
-		_, err := stmt.Exec(
-			nullString(u.BodyHTML), nullString(u.BodyText), nullString(u.Snippet),
+		_, err = stmt.Exec(
+			nullString(bodyHTML), nullString(bodyText), nullString(snippet),
 			nullString(u.SMIMEStatus), nullString(u.SMIMESignerEmail), nullString(u.SMIMESignerSubject),
 			smimeRawBody, u.SMIMEEncrypted,
 			pgpRawBody, u.PGPEncrypted,
+			nullString(calendarData),
 			u.MessageID,
 		)
 		if err != nil {
@@ -1080,7 +1213,7 @@ func (s *Store) GetSMIMERawBody(messageID string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get S/MIME raw body: %w", err)
 	}
-	return rawBody, nil
+	return s.cipher.OpenBytes(rawBody)
 }
 
 // GetPGPRawBody returns the raw PGP body bytes for a message (for on-view decryption/verification)
@@ -1093,7 +1226,42 @@ func (s *Store) GetPGPRawBody(messageID string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get PGP raw body: %w", err)
 	}
-	return rawBody, nil
+	return s.cipher.OpenBytes(rawBody)
+}
+
+// UpdateCalendarData stores the raw iCalendar data of a meeting invitation
+func (s *Store) UpdateCalendarData(messageID, data string) error {
+	data, err := s.cipher.SealString(data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt calendar data: %w", err)
+	}
+	_, err = s.db.Exec("UPDATE messages SET calendar_data = ? WHERE id = ?", nullString(data), messageID)
+	if err != nil {
+		return fmt.Errorf("failed to update calendar data: %w", err)
+	}
+	return nil
+}
+
+// GetCalendarData returns the raw iCalendar data of a message ("" if it has none)
+func (s *Store) GetCalendarData(messageID string) (string, error) {
+	var data sql.NullString
+	err := s.db.QueryRow("SELECT calendar_data FROM messages WHERE id = ?", messageID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get calendar data: %w", err)
+	}
+	return s.cipher.OpenString(data.String)
+}
+
+// SetCalendarResponse records the participation status sent in reply to an invitation
+func (s *Store) SetCalendarResponse(messageID, partStat string) error {
+	_, err := s.db.Exec("UPDATE messages SET calendar_response = ? WHERE id = ?", nullString(partStat), messageID)
+	if err != nil {
+		return fmt.Errorf("failed to update calendar response: %w", err)
+	}
+	return nil
 }
 
 // ClearBodiesForFolder clears body content for all messages in a folder.
@@ -1102,7 +1270,7 @@ func (s *Store) GetPGPRawBody(messageID string) ([]byte, error) {
 func (s *Store) ClearBodiesForFolder(folderID string) (int64, error) {
 	query := `
 		UPDATE messages
-		SET body_html = NULL, body_text = NULL, snippet = NULL, body_fetched = 0
+		SET body_html = NULL, body_text = NULL, snippet = NULL, body_fetched = 0, body_evicted = 0
 		WHERE folder_id = ?
 	`
 	result, err := s.db.Exec(query, folderID)
@@ -1201,7 +1369,7 @@ func (s *Store) ListConversationsByFolder(folderID string, offset, limit int, so
 		}
 
 		if snippet.Valid {
-			c.Snippet = snippet.String
+			c.Snippet = s.openSnippet(snippet.String)
 		}
 		if latestDateStr.Valid && latestDateStr.String != "" {
 			c.LatestDate = parseTimeString(latestDateStr.String)
@@ -1368,6 +1536,7 @@ func (s *Store) GetConversation(threadID, folderID string) (*Conversation, error
 		       m.smime_encrypted, (m.smime_raw_body IS NOT NULL) as has_smime,
 		       m.pgp_status, m.pgp_signer_email, m.pgp_signer_key_id,
 		       m.pgp_encrypted, (m.pgp_raw_body IS NOT NULL) as has_pgp,
+		       (m.calendar_data IS NOT NULL) as has_calendar, m.calendar_response,
 		       m.received_at
 		FROM messages m
 		INNER JOIN folders f ON m.folder_id = f.id
@@ -1391,6 +1560,7 @@ func (s *Store) GetConversation(threadID, folderID string) (*Conversation, error
 		var messageID, inReplyTo, references, threadIDVal, toList, ccList, bccList, replyTo, snippetVal, bodyText, bodyHTML, readReceiptTo sql.NullString
 		var smimeStatus, smimeSignerEmail, smimeSignerSubject sql.NullString
 		var pgpStatus, pgpSignerEmail, pgpSignerKeyID sql.NullString
+		var calendarResponse sql.NullString
 		var dateStr, receivedAtStr sql.NullString
 
 		err := rows.Scan(
@@ -1403,6 +1573,7 @@ func (s *Store) GetConversation(threadID, folderID string) (*Conversation, error
 			&m.SMIMEEncrypted, &m.HasSMIME,
 			&pgpStatus, &pgpSignerEmail, &pgpSignerKeyID,
 			&m.PGPEncrypted, &m.HasPGP,
+			&m.HasCalendar, &calendarResponse,
 			&receivedAtStr,
 		)
 		if err != nil {
@@ -1437,7 +1608,7 @@ func (s *Store) GetConversation(threadID, folderID string) (*Conversation, error
 			m.Date = parseTimeString(dateStr.String)
 		}
 		if snippetVal.Valid {
-			m.Snippet = snippetVal.String
+			m.Snippet = s.openSnippet(snippetVal.String)
 		}
 		if bodyText.Valid {
 			m.BodyText = bodyText.String
@@ -1466,6 +1637,7 @@ func (s *Store) GetConversation(threadID, folderID string) (*Conversation, error
 		if pgpSignerKeyID.Valid {
 			m.PGPSignerKeyID = pgpSignerKeyID.String
 		}
+		m.CalendarResponse = calendarResponse.String
 		if receivedAtStr.Valid && receivedAtStr.String != "" {
 			m.ReceivedAt = parseTimeString(receivedAtStr.String)
 		}
@@ -1479,6 +1651,9 @@ func (s *Store) GetConversation(threadID, folderID string) (*Conversation, error
 			Int("bodyHTMLLen", len(m.BodyHTML)).
 			Msg("GetConversation found message")
 
+		if err := s.openFields(m); err != nil {
+			return nil, err
+		}
 		c.Messages = append(c.Messages, m)
 	}
 
@@ -1556,6 +1731,83 @@ func (s *Store) FindThreadID(accountID, messageID, inReplyTo string, references
 	return messageID, nil
 }
 
+// GetThreadMessageIDs returns the IDs of all messages of a thread in an account
+func (s *Store) GetThreadMessageIDs(accountID, threadID string) ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT id FROM messages WHERE account_id = ? AND thread_id = ?
+	`, accountID, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query thread messages: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan message id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// FindIDByMessageID returns the ID of a message with the given Message-ID
+// header in any account, or "" if there is none
+func (s *Store) FindIDByMessageID(messageID string) (string, error) {
+	bare := strings.TrimPrefix(strings.TrimSuffix(messageID, ">"), "<")
+
+	var id string
+	err := s.db.QueryRow(
+		"SELECT id FROM messages WHERE message_id = ? OR message_id = ? LIMIT 1",
+		bare, "<"+bare+">",
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find message: %w", err)
+	}
+	return id, nil
+}
+
+// GetThreadSubjectsByUIDs returns the threads of messages in a folder, by
+// UID, with a subject for each thread
+func (s *Store) GetThreadSubjectsByUIDs(folderID string, uids []uint32) (map[string]string, error) {
+	threads := make(map[string]string)
+	if len(uids) == 0 {
+		return threads, nil
+	}
+
+	placeholders := make([]string, len(uids))
+	args := make([]interface{}, 0, len(uids)+1)
+	args = append(args, folderID)
+	for i, uid := range uids {
+		placeholders[i] = "?"
+		args = append(args, uid)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT thread_id, MAX(subject) FROM messages
+		WHERE folder_id = ? AND uid IN (`+strings.Join(placeholders, ",")+`) AND thread_id IS NOT NULL AND thread_id != ''
+		GROUP BY thread_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message threads: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var threadID string
+		var subject sql.NullString
+		if err := rows.Scan(&threadID, &subject); err != nil {
+			return nil, fmt.Errorf("failed to scan message thread: %w", err)
+		}
+		threads[threadID] = subject.String
+	}
+	return threads, rows.Err()
+}
+
 // UpdateThreadID updates the thread_id for a message
 func (s *Store) UpdateThreadID(id, threadID string) error {
 	_, err := s.db.Exec("UPDATE messages SET thread_id = ? WHERE id = ?", threadID, id)
@@ -1765,6 +2017,7 @@ func (s *Store) DeleteBatch(ids []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete messages batch: %w", err)
 	}
+	s.notifyDeleted()
 	return nil
 }
 
@@ -1786,11 +2039,12 @@ func (s *Store) GetByIDs(ids []string) ([]*Message, error) {
 		       subject, from_name, from_email, to_list, cc_list, bcc_list, reply_to, date,
 		       snippet, is_read, is_starred, is_answered, is_forwarded, is_draft, is_deleted,
 		       size, has_attachments, body_text, body_html, body_fetched,
-		       read_receipt_to, read_receipt_handled,
+		       read_receipt_to, read_receipt_handled, delivered_to,
 		       smime_status, smime_signer_email, smime_signer_subject,
 		       smime_encrypted, (smime_raw_body IS NOT NULL) as has_smime,
 		       pgp_status, pgp_signer_email, pgp_signer_key_id,
 		       pgp_encrypted, (pgp_raw_body IS NOT NULL) as has_pgp,
+		       (calendar_data IS NOT NULL) as has_calendar, calendar_response,
 		       received_at
 		FROM messages WHERE id IN (%s)
 	`, strings.Join(placeholders, ", "))
@@ -1804,9 +2058,10 @@ func (s *Store) GetByIDs(ids []string) ([]*Message, error) {
 	var messages []*Message
 	for rows.Next() {
 		m := &Message{}
-		var messageID, inReplyTo, references, threadID, toList, ccList, bccList, replyTo, snippet, bodyText, bodyHTML, readReceiptTo sql.NullString
+		var messageID, inReplyTo, references, threadID, toList, ccList, bccList, replyTo, snippet, bodyText, bodyHTML, readReceiptTo, deliveredTo sql.NullString
 		var smimeStatus, smimeSignerEmail, smimeSignerSubject sql.NullString
 		var pgpStatus, pgpSignerEmail, pgpSignerKeyID sql.NullString
+		var calendarResponse sql.NullString
 		var dateStr, receivedAtStr sql.NullString
 
 		err := rows.Scan(
@@ -1814,11 +2069,12 @@ func (s *Store) GetByIDs(ids []string) ([]*Message, error) {
 			&m.Subject, &m.FromName, &m.FromEmail, &toList, &ccList, &bccList, &replyTo, &dateStr,
 			&snippet, &m.IsRead, &m.IsStarred, &m.IsAnswered, &m.IsForwarded, &m.IsDraft, &m.IsDeleted,
 			&m.Size, &m.HasAttachments, &bodyText, &bodyHTML, &m.BodyFetched,
-			&readReceiptTo, &m.ReadReceiptHandled,
+			&readReceiptTo, &m.ReadReceiptHandled, &deliveredTo,
 			&smimeStatus, &smimeSignerEmail, &smimeSignerSubject,
 			&m.SMIMEEncrypted, &m.HasSMIME,
 			&pgpStatus, &pgpSignerEmail, &pgpSignerKeyID,
 			&m.PGPEncrypted, &m.HasPGP,
+			&m.HasCalendar, &calendarResponse,
 			&receivedAtStr,
 		)
 		if err != nil {
@@ -1853,7 +2109,7 @@ func (s *Store) GetByIDs(ids []string) ([]*Message, error) {
 			m.Date = parseTimeString(dateStr.String)
 		}
 		if snippet.Valid {
-			m.Snippet = snippet.String
+			m.Snippet = s.openSnippet(snippet.String)
 		}
 		if bodyText.Valid {
 			m.BodyText = bodyText.String
@@ -1864,6 +2120,7 @@ func (s *Store) GetByIDs(ids []string) ([]*Message, error) {
 		if readReceiptTo.Valid {
 			m.ReadReceiptTo = readReceiptTo.String
 		}
+		m.DeliveredTo = deliveredTo.String
 		if smimeStatus.Valid {
 			m.SMIMEStatus = smimeStatus.String
 		}
@@ -1882,10 +2139,15 @@ func (s *Store) GetByIDs(ids []string) ([]*Message, error) {
 		if pgpSignerKeyID.Valid {
 			m.PGPSignerKeyID = pgpSignerKeyID.String
 		}
+		m.CalendarResponse = calendarResponse.String
 		if receivedAtStr.Valid && receivedAtStr.String != "" {
 			m.ReceivedAt = parseTimeString(receivedAtStr.String)
 		}
 
+		if err := s.openFields(m); err != nil {
+			// Locked: callers of GetByIDs act on flags and folders, not content
+			m.Snippet, m.BodyText, m.BodyHTML = "", "", ""
+		}
 		messages = append(messages, m)
 	}
 
@@ -1979,7 +2241,7 @@ func (s *Store) SearchConversations(folderID, query string, offset, limit int) (
 		}
 
 		if snippet.Valid {
-			c.Snippet = snippet.String
+			c.Snippet = s.openSnippet(snippet.String)
 		}
 		if latestDateStr.Valid && latestDateStr.String != "" {
 			c.LatestDate = parseTimeString(latestDateStr.String)
@@ -2102,7 +2364,7 @@ func (s *Store) SearchConversationsUnifiedInbox(query string, offset, limit int)
 		}
 
 		if snippet.Valid {
-			c.Snippet = snippet.String
+			c.Snippet = s.openSnippet(snippet.String)
 		}
 		if latestDateStr.Valid && latestDateStr.String != "" {
 			c.LatestDate = parseTimeString(latestDateStr.String)
@@ -2128,6 +2390,125 @@ func (s *Store) SearchConversationsUnifiedInbox(query string, offset, limit int)
 	return results, totalCount, nil
 }
 
+// SearchConversationsAllFolders searches every folder of an account, or of all
+// enabled accounts when accountID is empty. Conversations are grouped per folder.
+func (s *Store) SearchConversationsAllFolders(accountID, query string, offset, limit int) ([]*ConversationSearchResult, int, error) {
+	if query == "" {
+		return nil, 0, nil
+	}
+
+	ftsQuery := prepareFTSQuery(query)
+
+	// Count total results across all folders
+	countQuery := `
+		SELECT COUNT(DISTINCT COALESCE(m.thread_id, m.id) || '-' || f.id)
+		FROM messages m
+		JOIN messages_fts fts ON m.rowid = fts.rowid
+		INNER JOIN folders f ON m.folder_id = f.id
+		INNER JOIN accounts a ON f.account_id = a.id AND a.enabled = 1
+		WHERE messages_fts MATCH ? AND (? = '' OR a.id = ?)
+	`
+	var totalCount int
+	err := s.db.QueryRow(countQuery, ftsQuery, accountID, accountID).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count all-folder search results: %w", err)
+	}
+
+	if totalCount == 0 {
+		return nil, 0, nil
+	}
+
+	searchQuery := `
+		SELECT 
+			COALESCE(m.thread_id, m.id) as conv_thread_id,
+			MIN(m.subject) as subject,
+			MAX(m.snippet) as snippet,
+			MIN(m.from_name) as from_name,
+			COUNT(*) as message_count,
+			SUM(CASE WHEN m.is_read = 0 THEN 1 ELSE 0 END) as unread_count,
+			MAX(CASE WHEN m.has_attachments = 1 THEN 1 ELSE 0 END) as has_attachments,
+			MAX(CASE WHEN m.is_starred = 1 THEN 1 ELSE 0 END) as is_starred,
+			MAX(m.date) as latest_date,
+			GROUP_CONCAT(m.id) as message_ids,
+			a.id as account_id,
+			a.name as account_name,
+			a.color as account_color,
+			f.id as folder_id,
+			f.name as folder_name,
+			f.folder_type as folder_type
+		FROM messages m
+		JOIN messages_fts fts ON m.rowid = fts.rowid
+		INNER JOIN folders f ON m.folder_id = f.id
+		INNER JOIN accounts a ON f.account_id = a.id AND a.enabled = 1
+		WHERE messages_fts MATCH ? AND (? = '' OR a.id = ?)
+		GROUP BY COALESCE(m.thread_id, m.id), f.id
+		ORDER BY latest_date DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := s.db.Query(searchQuery, ftsQuery, accountID, accountID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search all folders: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*ConversationSearchResult
+	for rows.Next() {
+		c := &ConversationSearchResult{}
+		var latestDateStr sql.NullString
+		var snippet sql.NullString
+		var fromName sql.NullString
+		var messageIDsStr sql.NullString
+
+		err := rows.Scan(
+			&c.ThreadID,
+			&c.Subject,
+			&snippet,
+			&fromName,
+			&c.MessageCount,
+			&c.UnreadCount,
+			&c.HasAttachments,
+			&c.IsStarred,
+			&latestDateStr,
+			&messageIDsStr,
+			&c.AccountID,
+			&c.AccountName,
+			&c.AccountColor,
+			&c.FolderID,
+			&c.FolderName,
+			&c.FolderType,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan all-folder search result: %w", err)
+		}
+
+		if snippet.Valid {
+			c.Snippet = s.openSnippet(snippet.String)
+		}
+		if latestDateStr.Valid && latestDateStr.String != "" {
+			c.LatestDate = parseTimeString(latestDateStr.String)
+		}
+		if messageIDsStr.Valid && messageIDsStr.String != "" {
+			c.MessageIDs = strings.Split(messageIDsStr.String, ",")
+		}
+
+		// Apply highlighting
+		c.HighlightedSubject = highlightMatches(c.Subject, query)
+		c.HighlightedSnippet = highlightMatches(c.Snippet, query)
+		if fromName.Valid {
+			c.HighlightedFromName = highlightMatches(fromName.String, query)
+		}
+
+		// Get participants
+		participants, _ := s.getConversationParticipants(c.ThreadID, c.FolderID)
+		c.Participants = participants
+
+		results = append(results, c)
+	}
+
+	return results, totalCount, nil
+}
+
 // prepareFTSQuery prepares a user query for FTS5
 // Handles special characters and adds prefix matching for better UX
 func prepareFTSQuery(query string) string {
@@ -0,0 +1,238 @@
+// Package netproxy dials network connections directly or through a SOCKS5
+// or HTTP CONNECT proxy. Every outgoing connection (IMAP, SMTP, CardDAV,
+// CalDAV, OAuth, key lookups, contact sync) is made through this package.
+package netproxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Type is the kind of proxy
+type Type string
+
+const (
+	TypeDefault Type = ""       // Use the global proxy (account settings only)
+	TypeNone    Type = "none"   // Connect directly
+	TypeSOCKS5  Type = "socks5" // SOCKS5; host names are resolved by the proxy
+	TypeHTTP    Type = "http"   // HTTP CONNECT tunnel
+)
+
+// Config describes a proxy
+type Config struct {
+	Type     Type   `json:"type"`
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"-"` // Kept in the credential store, never serialized
+}
+
+// Enabled reports whether connections go through a proxy
+func (c *Config) Enabled() bool {
+	return c != nil && (c.Type == TypeSOCKS5 || c.Type == TypeHTTP)
+}
+
+// Address returns the host:port of the proxy
+func (c *Config) Address() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// Validate checks the configuration for invalid values
+func (c *Config) Validate() error {
+	switch c.Type {
+	case TypeDefault, TypeNone:
+		return nil
+	case TypeSOCKS5, TypeHTTP:
+	default:
+		return fmt.Errorf("invalid proxy type: %q", c.Type)
+	}
+	if c.Host == "" {
+		return fmt.Errorf("proxy host is required")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid proxy port: %d", c.Port)
+	}
+	return nil
+}
+
+// ============================================================================
+// Global proxy
+// ============================================================================
+
+// defaultConfig is the global proxy used by connections without their own
+var defaultConfig atomic.Pointer[Config]
+
+// SetDefault sets the global proxy. nil connects directly.
+func SetDefault(config *Config) {
+	if config != nil {
+		c := *config
+		config = &c
+	}
+	defaultConfig.Store(config)
+}
+
+// Default returns the global proxy, or nil if connections are direct
+func Default() *Config {
+	return defaultConfig.Load()
+}
+
+// resolve returns the proxy to use for config: the global proxy for nil or
+// TypeDefault, otherwise config itself
+func resolve(config *Config) *Config {
+	if config == nil || config.Type == TypeDefault {
+		return Default()
+	}
+	return config
+}
+
+// ============================================================================
+// Dialing
+// ============================================================================
+
+// Dialer makes TCP connections, directly or through a proxy
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// NewDialer returns a dialer for the given proxy. nil (or TypeDefault) uses
+// the global proxy. timeout limits connecting, including the proxy handshake;
+// 0 means no limit.
+func NewDialer(config *Config, timeout time.Duration) Dialer {
+	direct := &net.Dialer{Timeout: timeout}
+
+	config = resolve(config)
+	if !config.Enabled() {
+		return direct
+	}
+
+	var d Dialer
+	switch config.Type {
+	case TypeSOCKS5:
+		var auth *proxy.Auth
+		if config.Username != "" {
+			auth = &proxy.Auth{User: config.Username, Password: config.Password}
+		}
+		socks, err := proxy.SOCKS5("tcp", config.Address(), auth, direct)
+		if err != nil {
+			return &failingDialer{err: fmt.Errorf("failed to configure SOCKS5 proxy: %w", err)}
+		}
+		d = socks.(proxy.ContextDialer)
+	case TypeHTTP:
+		d = &httpConnectDialer{
+			proxyAddr: config.Address(),
+			username:  config.Username,
+			password:  config.Password,
+			forward:   direct,
+		}
+	}
+	return &timeoutDialer{dialer: d, timeout: timeout}
+}
+
+// Dial connects to address using the given proxy (see NewDialer)
+func Dial(ctx context.Context, config *Config, timeout time.Duration, address string) (net.Conn, error) {
+	return NewDialer(config, timeout).DialContext(ctx, "tcp", address)
+}
+
+// DialTLS connects to address using the given proxy and performs a TLS
+// handshake. tlsConfig may be nil; the server name defaults to the host of
+// address.
+func DialTLS(ctx context.Context, config *Config, timeout time.Duration, address string, tlsConfig *tls.Config) (*tls.Conn, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	conn, err := NewDialer(config, 0).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	if tlsConfig.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// HTTPClient returns an HTTP client whose connections use the global proxy.
+// The proxy is looked up on every new connection, so long-lived clients
+// follow changes to the global setting. Without a global proxy the proxy
+// environment variables (HTTPS_PROXY etc.) apply as usual.
+func HTTPClient(timeout time.Duration) *http.Client {
+	return HTTPClientFor(nil, timeout)
+}
+
+// HTTPClientFor returns an HTTP client whose connections use the given
+// proxy, e.g. an account's own proxy. nil (or TypeDefault) behaves like
+// HTTPClient; TypeNone connects directly, ignoring the environment.
+func HTTPClientFor(config *Config, timeout time.Duration) *http.Client {
+	if config != nil {
+		c := *config
+		config = &c
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	environment := transport.Proxy
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		// The environment would bypass the configured proxy
+		if resolve(config).Enabled() || (config != nil && config.Type == TypeNone) || environment == nil {
+			return nil, nil
+		}
+		return environment(req)
+	}
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return NewDialer(config, 30*time.Second).DialContext(ctx, network, address)
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// timeoutDialer limits the time to connect through a proxy
+type timeoutDialer struct {
+	dialer  Dialer
+	timeout time.Duration
+}
+
+func (d *timeoutDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	conn, err := d.dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	return conn, nil
+}
+
+// failingDialer reports a proxy configuration error on every dial
+type failingDialer struct {
+	err error
+}
+
+func (d *failingDialer) DialContext(context.Context, string, string) (net.Conn, error) {
+	return nil, d.err
+}
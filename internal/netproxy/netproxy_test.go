@@ -0,0 +1,374 @@
+package netproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testUser     = "alice"
+	testPassword = "s3cret"
+)
+
+// testProxy is a local proxy that records the targets it was asked to reach
+type testProxy struct {
+	host string
+	port int
+
+	mu      sync.Mutex
+	targets []string
+}
+
+// config returns the proxy configuration of p with the given password
+func (p *testProxy) config(proxyType Type, password string) *Config {
+	return &Config{Type: proxyType, Host: p.host, Port: p.port, Username: testUser, Password: password}
+}
+
+// record notes a requested target
+func (p *testProxy) record(target string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targets = append(p.targets, target)
+}
+
+// requested returns the targets requested so far
+func (p *testProxy) requested() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.targets...)
+}
+
+// startProxy listens on a local port and serves each connection with handle
+func startProxy(t *testing.T, handle func(p *testProxy, conn net.Conn)) *testProxy {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	addr := ln.Addr().(*net.TCPAddr)
+	p := &testProxy{host: addr.IP.String(), port: addr.Port}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(p, conn)
+			}()
+		}
+	}()
+	return p
+}
+
+// tunnel copies data both ways until the upstream side closes
+func tunnel(client, upstream net.Conn) {
+	defer upstream.Close()
+	go io.Copy(upstream, client)
+	io.Copy(client, upstream)
+}
+
+// serveSOCKS5 is a minimal SOCKS5 server (RFC 1928) requiring
+// username/password authentication (RFC 1929). Host names are resolved here,
+// on the proxy side.
+func serveSOCKS5(p *testProxy, conn net.Conn) {
+	r := bufio.NewReader(conn)
+
+	var greeting [2]byte
+	if _, err := io.ReadFull(r, greeting[:]); err != nil || greeting[0] != 5 {
+		return
+	}
+	methods := make([]byte, greeting[1])
+	if _, err := io.ReadFull(r, methods); err != nil {
+		return
+	}
+	if !strings.ContainsRune(string(methods), 2) {
+		conn.Write([]byte{5, 0xff})
+		return
+	}
+	conn.Write([]byte{5, 2})
+
+	readField := func() string {
+		n, err := r.ReadByte()
+		if err != nil {
+			return ""
+		}
+		b := make([]byte, n)
+		io.ReadFull(r, b)
+		return string(b)
+	}
+	if v, err := r.ReadByte(); err != nil || v != 1 {
+		return
+	}
+	user, password := readField(), readField()
+	if user != testUser || password != testPassword {
+		conn.Write([]byte{1, 1})
+		return
+	}
+	conn.Write([]byte{1, 0})
+
+	var req [4]byte
+	if _, err := io.ReadFull(r, req[:]); err != nil || req[1] != 1 {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	case 3:
+		host = readField()
+	case 4:
+		ip := make([]byte, 16)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	default:
+		return
+	}
+	var port uint16
+	if err := binary.Read(r, binary.BigEndian, &port); err != nil {
+		return
+	}
+	p.record(host)
+
+	target := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		conn.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	tunnel(conn, upstream)
+}
+
+// serveHTTPConnect is a minimal HTTP CONNECT proxy requiring basic auth
+func serveHTTPConnect(p *testProxy, conn net.Conn) {
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || req.Method != http.MethodConnect {
+		return
+	}
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(testUser+":"+testPassword))
+	if req.Header.Get("Proxy-Authorization") != want {
+		io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+		return
+	}
+	p.record(req.Host)
+
+	upstream, err := net.Dial("tcp", req.Host)
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	tunnel(conn, upstream)
+}
+
+// startGreeter starts a server that greets first, like IMAP and SMTP
+// servers do, and returns its port
+func startGreeter(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			io.WriteString(conn, "* OK ready\r\n")
+			conn.Close()
+		}
+	}()
+	return strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func TestNewDialer(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxyType Type
+		serve     func(p *testProxy, conn net.Conn)
+		target    string // What the proxy must have been asked for
+	}{
+		// The host name reaches the proxy unresolved (remote DNS)
+		{"SOCKS5", TypeSOCKS5, serveSOCKS5, "localhost"},
+		{"HTTP CONNECT", TypeHTTP, serveHTTPConnect, "localhost:"},
+	}
+
+	port := startGreeter(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := startProxy(t, tt.serve)
+
+			conn, err := NewDialer(p.config(tt.proxyType, testPassword), 5*time.Second).
+				DialContext(context.Background(), "tcp", "localhost:"+port)
+			if err != nil {
+				t.Fatalf("dial through proxy: %v", err)
+			}
+			defer conn.Close()
+
+			greeting, err := bufio.NewReader(conn).ReadString('\n')
+			if err != nil {
+				t.Fatalf("failed to read greeting: %v", err)
+			}
+			if greeting != "* OK ready\r\n" {
+				t.Errorf("greeting = %q", greeting)
+			}
+
+			targets := p.requested()
+			if len(targets) != 1 || !strings.HasPrefix(targets[0], tt.target) {
+				t.Errorf("proxy targets = %v, want %q", targets, tt.target)
+			}
+
+			if _, err := NewDialer(p.config(tt.proxyType, "wrong"), 5*time.Second).
+				DialContext(context.Background(), "tcp", "localhost:"+port); err == nil {
+				t.Errorf("dial with a wrong proxy password succeeded")
+			}
+		})
+	}
+}
+
+func TestNewDialerUsesDefault(t *testing.T) {
+	p := startProxy(t, serveSOCKS5)
+	SetDefault(p.config(TypeSOCKS5, testPassword))
+	t.Cleanup(func() { SetDefault(nil) })
+
+	port := startGreeter(t)
+	conn, err := NewDialer(nil, 5*time.Second).DialContext(context.Background(), "tcp", "localhost:"+port)
+	if err != nil {
+		t.Fatalf("dial through global proxy: %v", err)
+	}
+	conn.Close()
+	if len(p.requested()) != 1 {
+		t.Errorf("global proxy was not used")
+	}
+
+	conn, err = NewDialer(&Config{Type: TypeNone}, 5*time.Second).DialContext(context.Background(), "tcp", "localhost:"+port)
+	if err != nil {
+		t.Fatalf("direct dial: %v", err)
+	}
+	conn.Close()
+	if len(p.requested()) != 1 {
+		t.Errorf("TypeNone went through the global proxy")
+	}
+}
+
+func TestDialTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "tunneled")
+	}))
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	for _, tt := range []struct {
+		name      string
+		proxyType Type
+		serve     func(p *testProxy, conn net.Conn)
+	}{
+		{"SOCKS5", TypeSOCKS5, serveSOCKS5},
+		{"HTTP CONNECT", TypeHTTP, serveHTTPConnect},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := startProxy(t, tt.serve)
+
+			// httptest's certificate is issued for example.com
+			tlsConfig := &tls.Config{RootCAs: roots, ServerName: "example.com"}
+			conn, err := DialTLS(context.Background(), p.config(tt.proxyType, testPassword), 5*time.Second, "localhost:"+port, tlsConfig)
+			if err != nil {
+				t.Fatalf("DialTLS through proxy: %v", err)
+			}
+			defer conn.Close()
+
+			io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n")
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if err != nil {
+				t.Fatalf("failed to read response: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "tunneled" {
+				t.Errorf("body = %q", body)
+			}
+			if len(p.requested()) != 1 {
+				t.Errorf("proxy targets = %v, want one", p.requested())
+			}
+		})
+	}
+}
+
+func TestHTTPClientEnvironmentProxy(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://env-proxy.invalid:3128")
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	proxyFor := func() *url.URL {
+		t.Helper()
+		u, err := HTTPClient(time.Second).Transport.(*http.Transport).Proxy(req)
+		if err != nil {
+			t.Fatalf("proxy lookup: %v", err)
+		}
+		return u
+	}
+
+	// Without a configured proxy the environment applies
+	if u := proxyFor(); u == nil || u.Host != "env-proxy.invalid:3128" {
+		t.Errorf("proxy without configuration = %v, want the environment proxy", u)
+	}
+
+	// A configured proxy replaces it; the dialer tunnels through it instead
+	SetDefault(&Config{Type: TypeSOCKS5, Host: "127.0.0.1", Port: 1080})
+	t.Cleanup(func() { SetDefault(nil) })
+	if u := proxyFor(); u != nil {
+		t.Errorf("proxy with configuration = %v, want none", u)
+	}
+}
+
+func TestHTTPClientForAccountProxy(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://env-proxy.invalid:3128")
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	proxyFor := func(config *Config) *url.URL {
+		t.Helper()
+		u, err := HTTPClientFor(config, time.Second).Transport.(*http.Transport).Proxy(req)
+		if err != nil {
+			t.Fatalf("proxy lookup: %v", err)
+		}
+		return u
+	}
+
+	// An account using the global proxy follows HTTPClient
+	if u := proxyFor(&Config{Type: TypeDefault}); u == nil || u.Host != "env-proxy.invalid:3128" {
+		t.Errorf("proxy for default = %v, want the environment proxy", u)
+	}
+
+	// Connecting directly or through the account's proxy ignores the environment
+	if u := proxyFor(&Config{Type: TypeNone}); u != nil {
+		t.Errorf("proxy for none = %v, want none", u)
+	}
+	if u := proxyFor(&Config{Type: TypeHTTP, Host: "127.0.0.1", Port: 3128}); u != nil {
+		t.Errorf("proxy for account proxy = %v, want none", u)
+	}
+}
@@ -1,5 +1,5 @@
 // Package notification provides cross-platform desktop notification support
-// with click handling for navigating to specific content.
+// with click and action-button handling for acting on specific content.
 package notification
 
 import "context"
@@ -7,11 +7,43 @@ import "context"
 // ClickHandler is called when a notification is clicked
 type ClickHandler func(data NotificationData)
 
+// ActionHandler is called when a notification action button is invoked.
+// replyText holds the text of an inline reply, or is empty when the
+// notification server doesn't support inline replies.
+type ActionHandler func(action Action, data NotificationData, replyText string)
+
+// Action identifies a notification action button
+type Action string
+
+const (
+	ActionMarkRead Action = "mark-read"
+	ActionArchive  Action = "archive"
+	ActionDelete   Action = "delete"
+	ActionReply    Action = "reply"
+)
+
+// Label returns the button label for an action
+func (a Action) Label() string {
+	switch a {
+	case ActionMarkRead:
+		return "Mark as Read"
+	case ActionArchive:
+		return "Archive"
+	case ActionDelete:
+		return "Delete"
+	case ActionReply:
+		return "Reply"
+	default:
+		return string(a)
+	}
+}
+
 // NotificationData contains the context for a notification click
 type NotificationData struct {
-	AccountID string
-	FolderID  string
-	ThreadID  string
+	AccountID  string
+	FolderID   string
+	ThreadID   string
+	MessageIDs []string // Messages the action buttons apply to
 }
 
 // Notification represents a desktop notification to be shown
@@ -20,6 +52,7 @@ type Notification struct {
 	Body    string
 	Icon    string
 	Data    NotificationData
+	Actions []Action // Action buttons, shown where the platform supports them
 }
 
 // Notifier provides cross-platform notification support with click handling
@@ -35,6 +68,9 @@ type Notifier interface {
 
 	// SetClickHandler sets the callback for notification clicks
 	SetClickHandler(handler ClickHandler)
+
+	// SetActionHandler sets the callback for notification action buttons
+	SetActionHandler(handler ActionHandler)
 }
 
 // New creates a platform-specific Notifier
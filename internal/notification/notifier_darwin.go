@@ -13,9 +13,10 @@ import (
 // darwinNotifier uses osascript for notifications on macOS.
 // TODO: Implement using UNUserNotificationCenter for click handling.
 type darwinNotifier struct {
-	appName      string
-	clickHandler ClickHandler
-	log          zerolog.Logger
+	appName       string
+	clickHandler  ClickHandler
+	actionHandler ActionHandler
+	log           zerolog.Logger
 }
 
 func newPlatformNotifier(appName string, useDirectDBus bool) Notifier {
@@ -50,3 +51,8 @@ func (n *darwinNotifier) SetClickHandler(handler ClickHandler) {
 	n.clickHandler = handler
 	// TODO: Implement click handling via UNUserNotificationCenter
 }
+
+func (n *darwinNotifier) SetActionHandler(handler ActionHandler) {
+	n.actionHandler = handler
+	// TODO: Implement action buttons via UNUserNotificationCenter categories
+}
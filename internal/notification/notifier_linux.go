@@ -23,6 +23,14 @@ const (
 	dbusNotifyDest      = "org.freedesktop.Notifications"
 	dbusNotifyPath      = "/org/freedesktop/Notifications"
 	dbusNotifyInterface = "org.freedesktop.Notifications"
+
+	// KDE extension: an action with this key renders an inline reply field
+	// and the reply is delivered via the NotificationReplied signal
+	directInlineReplyAction = "inline-reply"
+
+	// Portal v2 button purpose for a reply text field; the reply is passed
+	// as the ActionInvoked parameter
+	portalReplyPurpose = "im.reply-with-text"
 )
 
 type notificationBackend int
@@ -36,6 +44,7 @@ type linuxNotifier struct {
 	appName       string
 	conn          *dbus.Conn
 	clickHandler  ClickHandler
+	actionHandler ActionHandler
 	notifications map[string]NotificationData // Portal uses string IDs
 	notifyIDs     map[uint32]NotificationData // Direct uses uint32 IDs
 	mu            sync.RWMutex
@@ -46,6 +55,10 @@ type linuxNotifier struct {
 	// Backend selection
 	backend       notificationBackend
 	backendTested bool
+
+	// Inline reply support of the selected backend
+	portalVersion     uint32
+	directInlineReply bool
 }
 
 func newPlatformNotifier(appName string, useDirectDBus bool) Notifier {
@@ -93,6 +106,13 @@ func (n *linuxNotifier) tryPortalBackend(ctx context.Context) error {
 		return fmt.Errorf("portal not available: %w", call.Err)
 	}
 
+	// Inline reply buttons need portal version 2
+	if v, err := obj.GetProperty(dbusPortalInterface + ".version"); err == nil {
+		if version, ok := v.Value().(uint32); ok {
+			n.portalVersion = version
+		}
+	}
+
 	// Subscribe to portal signals
 	if err := n.conn.AddMatchSignal(
 		dbus.WithMatchObjectPath(dbusPortalPath),
@@ -118,6 +138,8 @@ func (n *linuxNotifier) tryPortalBackend(ctx context.Context) error {
 }
 
 func (n *linuxNotifier) tryDirectBackend(ctx context.Context) error {
+	n.directInlineReply = n.hasDirectCapability(directInlineReplyAction)
+
 	// Subscribe to notification signals
 	if err := n.conn.AddMatchSignal(
 		dbus.WithMatchObjectPath(dbusNotifyPath),
@@ -138,10 +160,29 @@ func (n *linuxNotifier) tryDirectBackend(ctx context.Context) error {
 
 	n.backend = backendDirect
 	n.backendTested = true
-	n.log.Info().Msg("Linux notification listener started (using direct D-Bus)")
+	n.log.Info().
+		Bool("inlineReply", n.directInlineReply).
+		Msg("Linux notification listener started (using direct D-Bus)")
 	return nil
 }
 
+// hasDirectCapability reports whether the notification server advertises a capability
+func (n *linuxNotifier) hasDirectCapability(capability string) bool {
+	obj := n.conn.Object(dbusNotifyDest, dbusNotifyPath)
+
+	var capabilities []string
+	if err := obj.Call(dbusNotifyInterface+".GetCapabilities", 0).Store(&capabilities); err != nil {
+		n.log.Debug().Err(err).Msg("Failed to get notification server capabilities")
+		return false
+	}
+	for _, c := range capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *linuxNotifier) Stop() {
 	if n.cancel != nil {
 		n.cancel()
@@ -214,6 +255,9 @@ func (n *linuxNotifier) showPortal(notif Notification) (uint32, error) {
 		"priority":       dbus.MakeVariant("normal"),
 		"default-action": dbus.MakeVariant("default"),
 	}
+	if len(notif.Actions) > 0 {
+		notification["buttons"] = dbus.MakeVariant(n.portalButtons(notif.Actions))
+	}
 
 	// Call AddNotification method with ID and notification vardict
 	call := obj.Call(
@@ -242,10 +286,27 @@ func (n *linuxNotifier) showPortal(notif Notification) (uint32, error) {
 	return numericID, nil
 }
 
+// portalButtons builds the portal button list for notification actions
+func (n *linuxNotifier) portalButtons(actions []Action) []map[string]dbus.Variant {
+	buttons := make([]map[string]dbus.Variant, 0, len(actions))
+	for _, action := range actions {
+		button := map[string]dbus.Variant{
+			"label":  dbus.MakeVariant(action.Label()),
+			"action": dbus.MakeVariant(string(action)),
+		}
+		// Older portals reject unknown button keys
+		if action == ActionReply && n.portalVersion >= 2 {
+			button["purpose"] = dbus.MakeVariant(portalReplyPurpose)
+		}
+		buttons = append(buttons, button)
+	}
+	return buttons
+}
+
 func (n *linuxNotifier) showDirect(notif Notification) (uint32, error) {
 	obj := n.conn.Object(dbusNotifyDest, dbusNotifyPath)
 
-	// Actions: "default" is the click action
+	// Actions: "default" is the click action, followed by key/label pairs
 	actions := []string{"default", "Open"}
 
 	// Hints for better notification behavior
@@ -254,6 +315,15 @@ func (n *linuxNotifier) showDirect(notif Notification) (uint32, error) {
 		"category": dbus.MakeVariant("email.arrived"),
 	}
 
+	for _, action := range notif.Actions {
+		if action == ActionReply && n.directInlineReply {
+			actions = append(actions, directInlineReplyAction, action.Label())
+			hints["x-kde-reply-placeholder-text"] = dbus.MakeVariant("Reply…")
+			continue
+		}
+		actions = append(actions, string(action), action.Label())
+	}
+
 	// Icon - use mail icon
 	icon := notif.Icon
 	if icon == "" {
@@ -298,6 +368,12 @@ func (n *linuxNotifier) SetClickHandler(handler ClickHandler) {
 	n.mu.Unlock()
 }
 
+func (n *linuxNotifier) SetActionHandler(handler ActionHandler) {
+	n.mu.Lock()
+	n.actionHandler = handler
+	n.mu.Unlock()
+}
+
 func (n *linuxNotifier) handleSignals(ctx context.Context, signals chan *dbus.Signal) {
 	for {
 		select {
@@ -320,7 +396,7 @@ func (n *linuxNotifier) handleSignal(signal *dbus.Signal) {
 			id, ok1 := signal.Body[0].(string)
 			action, ok2 := signal.Body[1].(string)
 			if ok1 && ok2 {
-				n.handlePortalAction(id, action)
+				n.handlePortalAction(id, action, portalReplyText(signal.Body))
 			}
 		}
 
@@ -330,7 +406,17 @@ func (n *linuxNotifier) handleSignal(signal *dbus.Signal) {
 			id, ok1 := signal.Body[0].(uint32)
 			action, ok2 := signal.Body[1].(string)
 			if ok1 && ok2 {
-				n.handleDirectAction(id, action)
+				n.handleDirectAction(id, action, "")
+			}
+		}
+
+	case dbusNotifyInterface + ".NotificationReplied":
+		// KDE NotificationReplied(id uint32, text string)
+		if len(signal.Body) >= 2 {
+			id, ok1 := signal.Body[0].(uint32)
+			text, ok2 := signal.Body[1].(string)
+			if ok1 && ok2 {
+				n.handleDirectAction(id, string(ActionReply), text)
 			}
 		}
 
@@ -346,13 +432,65 @@ func (n *linuxNotifier) handleSignal(signal *dbus.Signal) {
 	}
 }
 
-func (n *linuxNotifier) handlePortalAction(id string, action string) {
+// portalReplyText extracts the reply text from a portal ActionInvoked
+// parameter, which holds it as the first value for reply buttons
+func portalReplyText(body []interface{}) string {
+	if len(body) < 3 {
+		return ""
+	}
+	params, ok := body[2].([]dbus.Variant)
+	if !ok || len(params) == 0 {
+		return ""
+	}
+	text, _ := params[0].Value().(string)
+	return text
+}
+
+// dispatchAction invokes the handler for a notification action
+func (n *linuxNotifier) dispatchAction(data NotificationData, action, replyText string) {
+	n.mu.RLock()
+	clickHandler := n.clickHandler
+	actionHandler := n.actionHandler
+	n.mu.RUnlock()
+
+	// Handle "default" action (click on notification or Open button)
+	if action == "default" {
+		if clickHandler != nil {
+			n.log.Info().
+				Str("accountId", data.AccountID).
+				Str("folderId", data.FolderID).
+				Str("threadId", data.ThreadID).
+				Msg("Notification clicked, invoking handler")
+			clickHandler(data)
+		}
+		return
+	}
+
+	if action == directInlineReplyAction {
+		action = string(ActionReply)
+	}
+
+	switch Action(action) {
+	case ActionMarkRead, ActionArchive, ActionDelete, ActionReply:
+		if actionHandler != nil {
+			n.log.Info().
+				Str("action", action).
+				Str("accountId", data.AccountID).
+				Str("threadId", data.ThreadID).
+				Msg("Notification action invoked, invoking handler")
+			actionHandler(Action(action), data, replyText)
+		}
+	default:
+		n.log.Debug().Str("action", action).Msg("Unknown notification action")
+	}
+}
+
+func (n *linuxNotifier) handlePortalAction(id string, action string, replyText string) {
 	n.log.Debug().Str("id", id).Str("action", action).Msg("Portal notification action invoked")
 
 	// Get notification data
 	n.mu.RLock()
 	data, exists := n.notifications[id]
-	handler := n.clickHandler
 	n.mu.RUnlock()
 
 	if !exists {
@@ -360,15 +498,7 @@ func (n *linuxNotifier) handlePortalAction(id string, action string) {
 		return
 	}
 
-	// Handle "default" action (click on notification or Open button)
-	if action == "default" && handler != nil {
-		n.log.Info().
-			Str("accountId", data.AccountID).
-			Str("folderId", data.FolderID).
-			Str("threadId", data.ThreadID).
-			Msg("Notification clicked, invoking handler")
-		handler(data)
-	}
+	n.dispatchAction(data, action, replyText)
 
 	// Clean up
 	n.mu.Lock()
@@ -382,13 +512,12 @@ func (n *linuxNotifier) handlePortalAction(id string, action string) {
 	}
 }
 
-func (n *linuxNotifier) handleDirectAction(id uint32, action string) {
+func (n *linuxNotifier) handleDirectAction(id uint32, action string, replyText string) {
 	n.log.Debug().Uint32("id", id).Str("action", action).Msg("Direct D-Bus notification action invoked")
 
 	// Get notification data
 	n.mu.RLock()
 	data, exists := n.notifyIDs[id]
-	handler := n.clickHandler
 	n.mu.RUnlock()
 
 	if !exists {
@@ -396,16 +525,14 @@ func (n *linuxNotifier) handleDirectAction(id uint32, action string) {
 		return
 	}
 
-	// Handle "default" action (click on notification body)
-	if action == "default" && handler != nil {
-		n.log.Info().
-			Str("accountId", data.AccountID).
-			Str("folderId", data.FolderID).
-			Str("threadId", data.ThreadID).
-			Msg("Notification clicked, invoking handler")
-		handler(data)
+	// Selecting the inline reply action only opens the reply field; the
+	// text arrives later via NotificationReplied
+	if action == directInlineReplyAction {
+		return
 	}
 
+	n.dispatchAction(data, action, replyText)
+
 	// Clean up
 	n.mu.Lock()
 	delete(n.notifyIDs, id)
@@ -12,9 +12,10 @@ import (
 // windowsNotifier is a stub for Windows notification support.
 // TODO: Implement using Windows Toast notifications with activation handling.
 type windowsNotifier struct {
-	appName      string
-	clickHandler ClickHandler
-	log          zerolog.Logger
+	appName       string
+	clickHandler  ClickHandler
+	actionHandler ActionHandler
+	log           zerolog.Logger
 }
 
 func newPlatformNotifier(appName string, useDirectDBus bool) Notifier {
@@ -44,3 +45,8 @@ func (n *windowsNotifier) SetClickHandler(handler ClickHandler) {
 	n.clickHandler = handler
 	// TODO: Implement click handling via Toast activation
 }
+
+func (n *windowsNotifier) SetActionHandler(handler ActionHandler) {
+	n.actionHandler = handler
+	// TODO: Implement action buttons via Toast activation
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/rs/zerolog"
 )
 
@@ -54,10 +55,8 @@ type Manager struct {
 // NewManager creates a new OAuth2 manager
 func NewManager() *Manager {
 	return &Manager{
-		log: logging.WithComponent("oauth2"),
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		log:        logging.WithComponent("oauth2"),
+		httpClient: netproxy.HTTPClient(30 * time.Second),
 	}
 }
 
@@ -203,8 +202,9 @@ func (m *Manager) CancelAuthFlow() {
 	m.activeSession = nil
 }
 
-// RefreshToken uses a refresh token to obtain a new access token
-func (m *Manager) RefreshToken(providerName, refreshToken string) (*TokenResponse, error) {
+// RefreshToken uses a refresh token to obtain a new access token. proxy is
+// the proxy of the account the token belongs to; nil uses the global proxy.
+func (m *Manager) RefreshToken(providerName, refreshToken string, proxy *netproxy.Config) (*TokenResponse, error) {
 	provider, err := GetProvider(providerName)
 	if err != nil {
 		return nil, err
@@ -227,7 +227,11 @@ func (m *Manager) RefreshToken(providerName, refreshToken string) (*TokenRespons
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := m.httpClient.Do(req)
+	httpClient := m.httpClient
+	if proxy != nil {
+		httpClient = netproxy.HTTPClientFor(proxy, 30*time.Second)
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
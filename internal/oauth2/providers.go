@@ -32,6 +32,18 @@ func GoogleProvider() ProviderConfig {
 	}
 }
 
+// GoogleCalendarScope grants read/write access to Google Calendar (CalDAV
+// sync). It is only requested once calendar sync is enabled for an account.
+const GoogleCalendarScope = "https://www.googleapis.com/auth/calendar"
+
+// GoogleCalendarProvider returns the Google configuration with calendar
+// access, for accounts that sync their calendars
+func GoogleCalendarProvider() ProviderConfig {
+	provider := GoogleProvider()
+	provider.Scopes = append(provider.Scopes, GoogleCalendarScope)
+	return provider
+}
+
 // MicrosoftProvider returns the OAuth2 configuration for Microsoft/Outlook
 func MicrosoftProvider() ProviderConfig {
 	return ProviderConfig{
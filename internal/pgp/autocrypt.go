@@ -0,0 +1,575 @@
+package pgp
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/rs/zerolog"
+)
+
+// Autocrypt prefer-encrypt values (Autocrypt Level 1)
+const (
+	PreferEncryptMutual       = "mutual"
+	PreferEncryptNoPreference = "nopreference"
+)
+
+// Recommendation is the Autocrypt Level 1 encryption recommendation
+type Recommendation string
+
+const (
+	RecommendDisable    Recommendation = "disable"    // No usable key for a recipient
+	RecommendDiscourage Recommendation = "discourage" // Keys are stale or only gossiped
+	RecommendAvailable  Recommendation = "available"  // Encryption possible, user may opt in
+	RecommendEncrypt    Recommendation = "encrypt"    // All parties prefer encryption
+)
+
+// autocryptStaleAfter is how much older the last Autocrypt header may be than
+// the last message seen from a peer before encryption is discouraged
+const autocryptStaleAfter = 35 * 24 * time.Hour
+
+// AutocryptHeader is a parsed Autocrypt or Autocrypt-Gossip header
+type AutocryptHeader struct {
+	Addr          string
+	PreferEncrypt string
+	KeyData       []byte // Binary OpenPGP transferable public key
+}
+
+// AutocryptPeer is the stored Autocrypt state for a peer address
+type AutocryptPeer struct {
+	AccountID          string     `json:"accountId"`
+	Addr               string     `json:"addr"`
+	LastSeen           time.Time  `json:"lastSeen"`
+	AutocryptTimestamp *time.Time `json:"autocryptTimestamp,omitempty"`
+	KeyFingerprint     string     `json:"keyFingerprint,omitempty"`
+	PreferEncrypt      string     `json:"preferEncrypt"`
+	GossipTimestamp    *time.Time `json:"gossipTimestamp,omitempty"`
+	GossipFingerprint  string     `json:"gossipFingerprint,omitempty"`
+
+	publicKey string // Armored
+	gossipKey string // Armored
+}
+
+// AutocryptRecommendation holds the overall and per-recipient recommendation
+type AutocryptRecommendation struct {
+	Recommendation Recommendation            `json:"recommendation"`
+	Recipients     map[string]Recommendation `json:"recipients"`
+}
+
+// AutocryptSettings holds the per-account Autocrypt configuration
+type AutocryptSettings struct {
+	Enabled       bool   `json:"enabled"`
+	PreferEncrypt string `json:"preferEncrypt"` // "mutual" or "nopreference"
+}
+
+// Autocrypt maintains Autocrypt peer state and computes recommendations
+type Autocrypt struct {
+	store *Store
+	log   zerolog.Logger
+}
+
+// NewAutocrypt creates a new Autocrypt handler
+func NewAutocrypt(store *Store, log zerolog.Logger) *Autocrypt {
+	return &Autocrypt{
+		store: store,
+		log:   log,
+	}
+}
+
+// ParseAutocryptHeader parses the value of an Autocrypt or Autocrypt-Gossip header.
+// Headers with unknown critical attributes (not starting with "_") are rejected.
+func ParseAutocryptHeader(value string) (*AutocryptHeader, error) {
+	h := &AutocryptHeader{PreferEncrypt: PreferEncryptNoPreference}
+	var keydata string
+
+	for _, attr := range strings.Split(value, ";") {
+		attr = strings.TrimSpace(attr)
+		if attr == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(attr, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed attribute %q", attr)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		val = strings.TrimSpace(val)
+
+		switch name {
+		case "addr":
+			h.Addr = strings.ToLower(val)
+		case "prefer-encrypt":
+			if val == PreferEncryptMutual {
+				h.PreferEncrypt = PreferEncryptMutual
+			}
+		case "keydata":
+			keydata = val
+		default:
+			if !strings.HasPrefix(name, "_") {
+				return nil, fmt.Errorf("unknown critical attribute %q", name)
+			}
+		}
+	}
+
+	if h.Addr == "" || keydata == "" {
+		return nil, fmt.Errorf("missing addr or keydata")
+	}
+
+	cleaned := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, keydata)
+	data, err := base64.StdEncoding.DecodeString(cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keydata: %w", err)
+	}
+	h.KeyData = data
+	return h, nil
+}
+
+// FormatAutocryptHeader builds a folded Autocrypt header value
+func FormatAutocryptHeader(addr, preferEncrypt string, keyData []byte) string {
+	var b strings.Builder
+	b.WriteString("addr=" + strings.ToLower(addr) + ";")
+	if preferEncrypt == PreferEncryptMutual {
+		b.WriteString(" prefer-encrypt=mutual;")
+	}
+	b.WriteString(" keydata=")
+
+	encoded := base64.StdEncoding.EncodeToString(keyData)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		b.WriteString("\r\n " + encoded[:n])
+		encoded = encoded[n:]
+	}
+	return b.String()
+}
+
+// OutgoingHeader returns the Autocrypt header value for a message sent from
+// the account, or "" when Autocrypt is disabled or there is no usable key
+func (ac *Autocrypt) OutgoingHeader(accountID, fromAddr string) (string, error) {
+	settings, err := ac.store.GetAutocryptSettings(accountID)
+	if err != nil {
+		return "", err
+	}
+	if !settings.Enabled {
+		return "", nil
+	}
+
+	key, armoredPublic, err := ac.store.GetDefaultKey(accountID)
+	if err != nil || key == nil || key.IsExpired || key.RevokedAt != nil || armoredPublic == "" {
+		return "", nil
+	}
+
+	entities, err := ParseArmoredKey(armoredPublic)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse default key: %w", err)
+	}
+
+	var keyData bytes.Buffer
+	if err := entities[0].Serialize(&keyData); err != nil {
+		return "", fmt.Errorf("failed to serialize default key: %w", err)
+	}
+
+	return FormatAutocryptHeader(fromAddr, settings.PreferEncrypt, keyData.Bytes()), nil
+}
+
+// ProcessIncoming updates peer state from an incoming message's headers
+// following the Autocrypt Level 1 update algorithm. rawHeaders is the header
+// block of the message; fromAddr and date come from its envelope.
+func (ac *Autocrypt) ProcessIncoming(accountID, fromAddr string, date time.Time, rawHeaders []byte) {
+	fromAddr = strings.ToLower(strings.TrimSpace(fromAddr))
+	if fromAddr == "" {
+		return
+	}
+
+	// Ensure the header block is terminated so it parses as a message
+	block := make([]byte, 0, len(rawHeaders)+4)
+	block = append(block, bytes.TrimRight(rawHeaders, "\r\n")...)
+	block = append(block, "\r\n\r\n"...)
+	header, err := mail.ReadMessage(bytes.NewReader(block))
+	if err != nil {
+		return
+	}
+
+	// Only consider messages with a single sender
+	if from, err := mail.ParseAddressList(header.Header.Get("From")); err != nil || len(from) != 1 {
+		return
+	}
+
+	// Effective date can't be in the future
+	now := time.Now()
+	if date.IsZero() || date.After(now) {
+		date = now
+	}
+
+	// Exactly one valid Autocrypt header whose addr matches From is accepted
+	var parsed *AutocryptHeader
+	values := header.Header["Autocrypt"]
+	if len(values) == 1 {
+		if h, err := ParseAutocryptHeader(values[0]); err == nil && h.Addr == fromAddr {
+			parsed = h
+		} else if err != nil {
+			ac.log.Debug().Err(err).Str("from", fromAddr).Msg("Ignoring invalid Autocrypt header")
+		}
+	}
+
+	var armored string
+	if parsed != nil {
+		armored, err = armorKeyData(parsed.KeyData)
+		if err != nil {
+			ac.log.Debug().Err(err).Str("from", fromAddr).Msg("Ignoring Autocrypt header with unusable key")
+			parsed = nil
+		}
+	}
+
+	updated, err := ac.store.updateAutocryptPeer(accountID, fromAddr, date, parsed, armored)
+	if err != nil {
+		ac.log.Warn().Err(err).Str("from", fromAddr).Msg("Failed to update Autocrypt peer state")
+		return
+	}
+
+	// Make the key available to the encryptor like WKD/HKP keys
+	if updated && armored != "" {
+		if err := ac.store.CacheSenderKey(fromAddr, armored, "autocrypt"); err != nil {
+			ac.log.Warn().Err(err).Str("from", fromAddr).Msg("Failed to cache Autocrypt key")
+		}
+	}
+}
+
+// ProcessGossip updates peer gossip state from Autocrypt-Gossip headers found
+// in the decrypted inner part of an encrypted message. Gossip is only
+// accepted for the message's To/Cc recipients, so a message can't plant
+// keys for unrelated addresses.
+func (ac *Autocrypt) ProcessGossip(accountID string, date time.Time, recipients []string, innerMessage []byte) {
+	msg, err := mail.ReadMessage(bytes.NewReader(innerMessage))
+	if err != nil {
+		return
+	}
+
+	isRecipient := make(map[string]bool, len(recipients))
+	for _, addr := range recipients {
+		isRecipient[strings.ToLower(strings.TrimSpace(addr))] = true
+	}
+
+	now := time.Now()
+	if date.IsZero() || date.After(now) {
+		date = now
+	}
+
+	for _, value := range msg.Header["Autocrypt-Gossip"] {
+		h, err := ParseAutocryptHeader(value)
+		if err != nil {
+			continue
+		}
+		if !isRecipient[h.Addr] {
+			ac.log.Debug().Str("addr", h.Addr).Msg("Ignoring Autocrypt gossip for an address that isn't a recipient")
+			continue
+		}
+		armored, err := armorKeyData(h.KeyData)
+		if err != nil {
+			continue
+		}
+		updated, err := ac.store.updateAutocryptGossip(accountID, h.Addr, date, armored)
+		if err != nil {
+			ac.log.Warn().Err(err).Str("addr", h.Addr).Msg("Failed to update Autocrypt gossip state")
+			continue
+		}
+		if updated {
+			if err := ac.store.CacheSenderKey(h.Addr, armored, "gossip"); err != nil {
+				ac.log.Warn().Err(err).Str("addr", h.Addr).Msg("Failed to cache gossiped key")
+			}
+		}
+	}
+}
+
+// Recommend computes the Autocrypt Level 1 recommendation for encrypting a
+// message from the account to the given recipients. It is "disable" for
+// every recipient when Autocrypt is turned off for the account.
+func (ac *Autocrypt) Recommend(accountID string, recipients []string) (*AutocryptRecommendation, error) {
+	settings, err := ac.store.GetAutocryptSettings(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AutocryptRecommendation{
+		Recommendation: RecommendDisable,
+		Recipients:     make(map[string]Recommendation),
+	}
+	if !settings.Enabled {
+		for _, addr := range recipients {
+			result.Recipients[strings.ToLower(addr)] = RecommendDisable
+		}
+		return result, nil
+	}
+	if len(recipients) == 0 {
+		return result, nil
+	}
+
+	allMutual := settings.PreferEncrypt == PreferEncryptMutual
+	anyDisable, anyDiscourage := false, false
+
+	for _, addr := range recipients {
+		addr = strings.ToLower(addr)
+		peer, err := ac.store.GetAutocryptPeer(accountID, addr)
+		if err != nil {
+			return nil, err
+		}
+		rec := preliminaryRecommendation(peer)
+		result.Recipients[addr] = rec
+
+		switch rec {
+		case RecommendDisable:
+			anyDisable = true
+		case RecommendDiscourage:
+			anyDiscourage = true
+		}
+		if peer == nil || peer.PreferEncrypt != PreferEncryptMutual {
+			allMutual = false
+		}
+	}
+
+	switch {
+	case anyDisable:
+		result.Recommendation = RecommendDisable
+	case !anyDiscourage && allMutual:
+		result.Recommendation = RecommendEncrypt
+	case anyDiscourage:
+		result.Recommendation = RecommendDiscourage
+	default:
+		result.Recommendation = RecommendAvailable
+	}
+	return result, nil
+}
+
+// preliminaryRecommendation computes the per-recipient recommendation
+func preliminaryRecommendation(peer *AutocryptPeer) Recommendation {
+	if peer == nil {
+		return RecommendDisable
+	}
+
+	if usableArmoredKey(peer.publicKey) {
+		if peer.AutocryptTimestamp != nil && peer.LastSeen.Sub(*peer.AutocryptTimestamp) > autocryptStaleAfter {
+			return RecommendDiscourage
+		}
+		return RecommendAvailable
+	}
+
+	if usableArmoredKey(peer.gossipKey) {
+		return RecommendDiscourage
+	}
+	return RecommendDisable
+}
+
+// usableArmoredKey reports whether an armored key parses and has a valid
+// encryption key
+func usableArmoredKey(armored string) bool {
+	if armored == "" {
+		return false
+	}
+	entities, err := ParseArmoredKey(armored)
+	if err != nil || len(entities) == 0 {
+		return false
+	}
+	_, ok := entities[0].EncryptionKey(time.Now())
+	return ok
+}
+
+// armorKeyData validates binary key data and returns it ASCII-armored
+func armorKeyData(keyData []byte) (string, error) {
+	entities, err := openpgp.ReadKeyRing(bytes.NewReader(keyData))
+	if err != nil {
+		return "", err
+	}
+	if len(entities) == 0 {
+		return "", fmt.Errorf("no key in keydata")
+	}
+	if _, ok := entities[0].EncryptionKey(time.Now()); !ok {
+		return "", fmt.Errorf("key has no usable encryption subkey")
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := entities[0].Serialize(w); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// GetAutocryptSettings returns the Autocrypt configuration for an account
+func (s *Store) GetAutocryptSettings(accountID string) (*AutocryptSettings, error) {
+	settings := &AutocryptSettings{}
+	err := s.db.QueryRow(
+		"SELECT autocrypt_enabled, autocrypt_prefer_encrypt FROM accounts WHERE id = ?", accountID,
+	).Scan(&settings.Enabled, &settings.PreferEncrypt)
+	return settings, err
+}
+
+// SetAutocryptSettings updates the Autocrypt configuration for an account
+func (s *Store) SetAutocryptSettings(accountID string, settings AutocryptSettings) error {
+	if settings.PreferEncrypt != PreferEncryptMutual && settings.PreferEncrypt != PreferEncryptNoPreference {
+		return fmt.Errorf("invalid prefer-encrypt value: %s", settings.PreferEncrypt)
+	}
+	_, err := s.db.Exec(
+		"UPDATE accounts SET autocrypt_enabled = ?, autocrypt_prefer_encrypt = ? WHERE id = ?",
+		settings.Enabled, settings.PreferEncrypt, accountID,
+	)
+	return err
+}
+
+// GetAutocryptPeer returns the Autocrypt state for a peer, or nil if unknown
+func (s *Store) GetAutocryptPeer(accountID, addr string) (*AutocryptPeer, error) {
+	rows, err := s.db.Query(`
+		SELECT account_id, addr, last_seen, autocrypt_timestamp, public_key, key_fingerprint,
+			prefer_encrypt, gossip_timestamp, gossip_key, gossip_fingerprint
+		FROM autocrypt_peers WHERE account_id = ? AND addr = ?`, accountID, strings.ToLower(addr),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	peers, err := scanAutocryptPeers(rows)
+	if err != nil || len(peers) == 0 {
+		return nil, err
+	}
+	return peers[0], nil
+}
+
+// ListAutocryptPeers returns all Autocrypt peers for an account
+func (s *Store) ListAutocryptPeers(accountID string) ([]*AutocryptPeer, error) {
+	rows, err := s.db.Query(`
+		SELECT account_id, addr, last_seen, autocrypt_timestamp, public_key, key_fingerprint,
+			prefer_encrypt, gossip_timestamp, gossip_key, gossip_fingerprint
+		FROM autocrypt_peers WHERE account_id = ?
+		ORDER BY last_seen DESC`, accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAutocryptPeers(rows)
+}
+
+// DeleteAutocryptPeer forgets the Autocrypt state for a peer
+func (s *Store) DeleteAutocryptPeer(accountID, addr string) error {
+	_, err := s.db.Exec("DELETE FROM autocrypt_peers WHERE account_id = ? AND addr = ?", accountID, strings.ToLower(addr))
+	return err
+}
+
+func scanAutocryptPeers(rows *sql.Rows) ([]*AutocryptPeer, error) {
+	var peers []*AutocryptPeer
+	for rows.Next() {
+		p := &AutocryptPeer{}
+		var autocryptTS, gossipTS sql.NullTime
+		var publicKey, keyFP, gossipKey, gossipFP sql.NullString
+
+		if err := rows.Scan(
+			&p.AccountID, &p.Addr, &p.LastSeen, &autocryptTS, &publicKey, &keyFP,
+			&p.PreferEncrypt, &gossipTS, &gossipKey, &gossipFP,
+		); err != nil {
+			return nil, err
+		}
+
+		if autocryptTS.Valid {
+			p.AutocryptTimestamp = &autocryptTS.Time
+		}
+		if gossipTS.Valid {
+			p.GossipTimestamp = &gossipTS.Time
+		}
+		p.publicKey = publicKey.String
+		p.KeyFingerprint = keyFP.String
+		p.gossipKey = gossipKey.String
+		p.GossipFingerprint = gossipFP.String
+
+		peers = append(peers, p)
+	}
+	return peers, rows.Err()
+}
+
+// updateAutocryptPeer applies the Level 1 update for a message from addr.
+// Returns whether the peer's key was replaced.
+func (s *Store) updateAutocryptPeer(accountID, addr string, date time.Time, header *AutocryptHeader, armored string) (bool, error) {
+	peer, err := s.GetAutocryptPeer(accountID, addr)
+	if err != nil {
+		return false, err
+	}
+
+	// Older than what we've already seen: nothing to do
+	if peer != nil && date.Before(peer.LastSeen) {
+		return false, nil
+	}
+
+	if header == nil {
+		// No (valid) header: only record that we saw a message
+		_, err = s.db.Exec(`
+			INSERT INTO autocrypt_peers (account_id, addr, last_seen) VALUES (?, ?, ?)
+			ON CONFLICT(account_id, addr) DO UPDATE SET last_seen = excluded.last_seen`,
+			accountID, addr, date,
+		)
+		return false, err
+	}
+
+	fingerprint := ""
+	if entities, err := ParseArmoredKey(armored); err == nil {
+		fingerprint = KeyFingerprint(entities[0])
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO autocrypt_peers (account_id, addr, last_seen, autocrypt_timestamp,
+			public_key, key_fingerprint, prefer_encrypt)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(account_id, addr) DO UPDATE SET
+			last_seen = excluded.last_seen,
+			autocrypt_timestamp = excluded.autocrypt_timestamp,
+			public_key = excluded.public_key,
+			key_fingerprint = excluded.key_fingerprint,
+			prefer_encrypt = excluded.prefer_encrypt`,
+		accountID, addr, date, date, armored, fingerprint, header.PreferEncrypt,
+	)
+	return err == nil, err
+}
+
+// updateAutocryptGossip records a gossiped key for addr if it is newer than
+// the stored gossip. Returns whether the gossip key was replaced.
+func (s *Store) updateAutocryptGossip(accountID, addr string, date time.Time, armored string) (bool, error) {
+	peer, err := s.GetAutocryptPeer(accountID, addr)
+	if err != nil {
+		return false, err
+	}
+	if peer != nil && peer.GossipTimestamp != nil && !date.After(*peer.GossipTimestamp) {
+		return false, nil
+	}
+
+	fingerprint := ""
+	if entities, err := ParseArmoredKey(armored); err == nil {
+		fingerprint = KeyFingerprint(entities[0])
+	}
+
+	// A peer first learned through gossip hasn't been "seen" yet; use the
+	// zero time so a later direct message always updates last_seen
+	_, err = s.db.Exec(`
+		INSERT INTO autocrypt_peers (account_id, addr, last_seen, gossip_timestamp, gossip_key, gossip_fingerprint)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(account_id, addr) DO UPDATE SET
+			gossip_timestamp = excluded.gossip_timestamp,
+			gossip_key = excluded.gossip_key,
+			gossip_fingerprint = excluded.gossip_fingerprint`,
+		accountID, addr, time.Time{}, date, armored, fingerprint,
+	)
+	return err == nil, err
+}
@@ -158,23 +158,25 @@ func (d *Decryptor) buildPrivateKeyring(accountID string) (openpgp.EntityList, e
 	}
 
 	var keyring openpgp.EntityList
+	locked := false
 	for _, key := range keys {
-		armoredPrivate, keyErr := d.credStore.GetPGPPrivateKey(key.ID)
-		if keyErr != nil {
-			d.log.Debug().Err(keyErr).Str("keyID", key.ID).Msg("Failed to get PGP private key")
+		entity, keyErr := LoadPrivateKey(d.credStore, key.ID)
+		if keyErr == ErrKeyLocked {
+			locked = true
 			continue
 		}
-
-		entities, parseErr := ParseArmoredKey(string(armoredPrivate))
-		if parseErr != nil {
-			d.log.Debug().Err(parseErr).Str("keyID", key.ID).Msg("Failed to parse PGP private key")
+		if keyErr != nil {
+			d.log.Debug().Err(keyErr).Str("keyID", key.ID).Msg("Failed to get PGP private key")
 			continue
 		}
 
-		keyring = append(keyring, entities...)
+		keyring = append(keyring, entity)
 	}
 
 	if len(keyring) == 0 {
+		if locked {
+			return nil, ErrKeyLocked
+		}
 		return nil, fmt.Errorf("no private keys found for account")
 	}
 
@@ -0,0 +1,224 @@
+package pgp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// KeyIdentity is a name/email pair bound to a generated key as a user ID
+type KeyIdentity struct {
+	Name  string
+	Email string
+}
+
+// GenerateKey creates a new keypair bound to the given identities. The first
+// identity becomes the primary user ID. A zero expiry creates a key that
+// never expires.
+func GenerateKey(identities []KeyIdentity, algorithm string, expiry time.Duration) (*openpgp.Entity, error) {
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("at least one identity is required")
+	}
+
+	lifetime, err := lifetimeSecs(expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &packet.Config{KeyLifetimeSecs: lifetime}
+	switch algorithm {
+	case AlgorithmEd25519:
+		config.Algorithm = packet.PubKeyAlgoEdDSA
+		config.Curve = packet.Curve25519
+	case AlgorithmRSA4096:
+		config.Algorithm = packet.PubKeyAlgoRSA
+		config.RSABits = 4096
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm: %s", algorithm)
+	}
+
+	primary := identities[0]
+	entity, err := openpgp.NewEntity(primary.Name, "", primary.Email, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	for _, ident := range identities[1:] {
+		if err := entity.AddUserId(ident.Name, "", ident.Email, config); err != nil {
+			return nil, fmt.Errorf("failed to add user ID %s: %w", ident.Email, err)
+		}
+	}
+
+	return entity, nil
+}
+
+// SetKeyExpiry re-signs every identity (and any subkey that carries its own
+// expiry) so that the key expires at the given time. A nil expiry removes
+// the expiry. The entity's private key must be unlocked.
+func SetKeyExpiry(entity *openpgp.Entity, expiresAt *time.Time) error {
+	if entity.PrivateKey == nil {
+		return fmt.Errorf("private key is required to change expiry")
+	}
+	if entity.PrivateKey.Encrypted {
+		return fmt.Errorf("private key is locked")
+	}
+
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return fmt.Errorf("expiry must be in the future")
+	}
+
+	config := &packet.Config{}
+	for _, ident := range entity.Identities {
+		sig := ident.SelfSignature
+		if sig == nil {
+			continue
+		}
+		lifetime, err := lifetimeUntil(entity.PrimaryKey.CreationTime, expiresAt)
+		if err != nil {
+			return err
+		}
+		sig.KeyLifetimeSecs = &lifetime
+		sig.CreationTime = now
+		if err := sig.SignUserId(ident.UserId.Id, entity.PrimaryKey, entity.PrivateKey, config); err != nil {
+			return fmt.Errorf("failed to re-sign user ID %s: %w", ident.Name, err)
+		}
+	}
+
+	// Subkeys without a lifetime follow the primary key's expiry
+	for i := range entity.Subkeys {
+		sub := &entity.Subkeys[i]
+		if sub.Sig == nil || sub.Sig.KeyLifetimeSecs == nil || *sub.Sig.KeyLifetimeSecs == 0 {
+			continue
+		}
+		lifetime, err := lifetimeUntil(sub.PublicKey.CreationTime, expiresAt)
+		if err != nil {
+			return err
+		}
+		sub.Sig.KeyLifetimeSecs = &lifetime
+		sub.Sig.CreationTime = now
+		if err := sub.Sig.SignKey(sub.PublicKey, entity.PrivateKey, config); err != nil {
+			return fmt.Errorf("failed to re-sign subkey %016X: %w", sub.PublicKey.KeyId, err)
+		}
+	}
+
+	return nil
+}
+
+// GenerateRevocationCertificate creates an armored revocation certificate for
+// the entity without revoking it. The certificate can be kept offline and
+// published later if the private key is lost or compromised.
+func GenerateRevocationCertificate(entity *openpgp.Entity, reason, text string) (string, error) {
+	if entity.PrivateKey == nil {
+		return "", fmt.Errorf("private key is required to create a revocation certificate")
+	}
+
+	code, err := revocationReasonCode(reason)
+	if err != nil {
+		return "", err
+	}
+
+	// Revoke a shallow copy so the entity itself stays valid
+	revoked := *entity
+	revoked.Revocations = nil
+	if err := revoked.RevokeKey(code, text, nil); err != nil {
+		return "", fmt.Errorf("failed to create revocation signature: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, "PGP PUBLIC KEY BLOCK", map[string]string{
+		"Comment": "This is a revocation certificate",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create armor writer: %w", err)
+	}
+	if err := revoked.Revocations[0].Serialize(w); err != nil {
+		return "", fmt.Errorf("failed to serialize revocation: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to close armor writer: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// RevokeKey adds a revocation signature to the entity
+func RevokeKey(entity *openpgp.Entity, reason, text string) error {
+	if entity.PrivateKey == nil {
+		return fmt.Errorf("private key is required to revoke a key")
+	}
+
+	code, err := revocationReasonCode(reason)
+	if err != nil {
+		return err
+	}
+
+	if err := entity.RevokeKey(code, text, nil); err != nil {
+		return fmt.Errorf("failed to revoke key: %w", err)
+	}
+	return nil
+}
+
+// ArmorProtectedPrivateKey armors a copy of an unlocked private key encrypted
+// with a passphrase, for storage, backups or moving the key to another
+// client. The entity itself stays unlocked.
+func ArmorProtectedPrivateKey(entity *openpgp.Entity, passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", fmt.Errorf("a passphrase is required to protect a private key")
+	}
+	if entity.PrivateKey == nil {
+		return "", fmt.Errorf("key has no private part")
+	}
+
+	protected, err := copyEntity(entity)
+	if err != nil {
+		return "", err
+	}
+	if err := protected.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+		return "", fmt.Errorf("failed to protect private key: %w", err)
+	}
+
+	// Encrypted keys can't re-sign, and the stored signatures are current
+	return armorPrivateEntity(protected)
+}
+
+// revocationReasonCode maps a user-facing revocation reason to its OpenPGP code
+func revocationReasonCode(reason string) (packet.ReasonForRevocation, error) {
+	switch reason {
+	case RevocationNoReason, "":
+		return packet.NoReason, nil
+	case RevocationSuperseded:
+		return packet.KeySuperseded, nil
+	case RevocationCompromised:
+		return packet.KeyCompromised, nil
+	case RevocationRetired:
+		return packet.KeyRetired, nil
+	default:
+		return 0, fmt.Errorf("invalid revocation reason: %s", reason)
+	}
+}
+
+// lifetimeSecs converts an expiry duration into a key lifetime in seconds
+func lifetimeSecs(expiry time.Duration) (uint32, error) {
+	if expiry < 0 {
+		return 0, fmt.Errorf("expiry must not be negative")
+	}
+	secs := expiry / time.Second
+	if secs > math.MaxUint32 {
+		return 0, fmt.Errorf("expiry is too far in the future")
+	}
+	return uint32(secs), nil
+}
+
+// lifetimeUntil returns the lifetime of a key created at createdAt that
+// expires at expiresAt (0 for no expiry)
+func lifetimeUntil(createdAt time.Time, expiresAt *time.Time) (uint32, error) {
+	if expiresAt == nil {
+		return 0, nil
+	}
+	return lifetimeSecs(expiresAt.Sub(createdAt))
+}
@@ -7,6 +7,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/hkdb/aerion/internal/netproxy"
 )
 
 // DefaultHKPServers is the default list of HKP key servers to query.
@@ -29,7 +31,7 @@ func LookupHKP(email string, servers []string) (string, error) {
 		servers = DefaultHKPServers
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := netproxy.HTTPClient(5 * time.Second)
 
 	for _, server := range servers {
 		armored, err := fetchHKP(client, server, email)
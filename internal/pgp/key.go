@@ -62,17 +62,16 @@ func ExtractKeyMetadata(entity *openpgp.Entity) *Key {
 	key.CreatedAtKey = &createdAt
 
 	// Extract user ID and email
-	for _, ident := range entity.Identities {
+	if ident := entity.PrimaryIdentity(); ident != nil {
 		key.UserID = ident.Name
 		if ident.UserId != nil && ident.UserId.Email != "" {
 			key.Email = ident.UserId.Email
 		}
-		// Check expiration from self-signature
-		if ident.SelfSignature != nil && ident.SelfSignature.KeyLifetimeSecs != nil {
+		// Check expiration from self-signature (a zero lifetime never expires)
+		if ident.SelfSignature != nil && ident.SelfSignature.KeyLifetimeSecs != nil && *ident.SelfSignature.KeyLifetimeSecs != 0 {
 			expiry := pk.CreationTime.Add(time.Duration(*ident.SelfSignature.KeyLifetimeSecs) * time.Second)
 			key.ExpiresAtKey = &expiry
 		}
-		break // Use first identity
 	}
 
 	// Check if key is expired
@@ -102,8 +101,8 @@ func ExtractEmailFromKey(entity *openpgp.Entity) string {
 // IsKeyExpired checks if a PGP entity's primary key is expired
 func IsKeyExpired(entity *openpgp.Entity) bool {
 	now := time.Now()
-	for _, ident := range entity.Identities {
-		if ident.SelfSignature != nil && ident.SelfSignature.KeyLifetimeSecs != nil {
+	if ident := entity.PrimaryIdentity(); ident != nil {
+		if ident.SelfSignature != nil && ident.SelfSignature.KeyLifetimeSecs != nil && *ident.SelfSignature.KeyLifetimeSecs != 0 {
 			expiry := entity.PrimaryKey.CreationTime.Add(
 				time.Duration(*ident.SelfSignature.KeyLifetimeSecs) * time.Second,
 			)
@@ -111,7 +110,6 @@ func IsKeyExpired(entity *openpgp.Entity) bool {
 				return true
 			}
 		}
-		break
 	}
 	return false
 }
@@ -30,6 +30,9 @@ type Key struct {
 	IsDefault    bool      `json:"isDefault"`
 	IsExpired    bool      `json:"isExpired"` // Computed, not stored
 	HasPrivate   bool      `json:"hasPrivate"` // Computed, not stored
+	IsProtected  bool      `json:"isProtected"` // Computed: private key needs a passphrase
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+	RevokeReason string    `json:"revokeReason,omitempty"`
 	CreatedAt    time.Time `json:"createdAt"`
 }
 
@@ -44,7 +47,7 @@ type SenderKey struct {
 	KeySize      int       `json:"keySize"`
 	CreatedAtKey *time.Time `json:"createdAtKey,omitempty"`
 	ExpiresAtKey *time.Time `json:"expiresAtKey,omitempty"`
-	Source       string    `json:"source"` // "message", "wkd", "manual"
+	Source       string    `json:"source"` // "manual", "wkd", "hkp", "message", "autocrypt", "gossip"
 	CollectedAt  time.Time `json:"collectedAt"`
 	LastSeenAt   time.Time `json:"lastSeenAt"`
 }
@@ -70,3 +73,26 @@ type ImportResult struct {
 	HasPrivate  bool `json:"hasPrivate"`
 	SubkeyCount int  `json:"subkeyCount"`
 }
+
+// Key generation algorithms
+const (
+	AlgorithmEd25519 = "ed25519" // Ed25519 signing key with a Cv25519 encryption subkey
+	AlgorithmRSA4096 = "rsa4096" // RSA-4096 signing key with an RSA-4096 encryption subkey
+)
+
+// Revocation reasons, as offered to the user
+const (
+	RevocationNoReason    = "no_reason"
+	RevocationSuperseded  = "superseded"
+	RevocationCompromised = "compromised"
+	RevocationRetired     = "retired"
+)
+
+// GenerateRequest describes a new keypair to generate
+type GenerateRequest struct {
+	AccountID   string   `json:"accountId"`
+	IdentityIDs []string `json:"identityIds"` // Identities bound as user IDs; first is primary
+	Algorithm   string   `json:"algorithm"`   // "ed25519" or "rsa4096"
+	ExpiryDays  int      `json:"expiryDays"`  // 0 = never expires
+	Passphrase  string   `json:"passphrase"`  // Protects the stored private key; empty leaves it unprotected
+}
@@ -35,20 +35,12 @@ func (s *Signer) SignMessage(accountID string, rawMsg []byte) ([]byte, error) {
 		return nil, fmt.Errorf("no default PGP key for account: %w", err)
 	}
 
-	// Get the private key
-	armoredPrivate, err := s.credStore.GetPGPPrivateKey(key.ID)
+	// Get the private key, unlocked if it is protected
+	entity, err := LoadPrivateKey(s.credStore, key.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get PGP private key: %w", err)
 	}
 
-	// Parse the private key
-	entities, err := ParseArmoredKey(string(armoredPrivate))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse PGP private key: %w", err)
-	}
-
-	entity := entities[0]
-
 	// Split the raw message into headers and body
 	headerEnd := bytes.Index(rawMsg, []byte("\r\n\r\n"))
 	if headerEnd == -1 {
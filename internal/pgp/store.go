@@ -49,17 +49,18 @@ func (s *Store) SaveKey(key *Key, publicKeyArmored string) error {
 func (s *Store) GetKey(id string) (*Key, string, error) {
 	key := &Key{}
 	var publicKeyArmored string
-	var createdAtKey, expiresAtKey sql.NullTime
+	var createdAtKey, expiresAtKey, revokedAt sql.NullTime
+	var revokeReason sql.NullString
 
 	err := s.db.QueryRow(`
 		SELECT id, account_id, email, key_id, fingerprint, user_id,
 			algorithm, key_size, created_at_key, expires_at_key, public_key_armored,
-			is_default, created_at
+			is_default, revoked_at, revocation_reason, created_at
 		FROM pgp_keys WHERE id = ?`, id,
 	).Scan(
 		&key.ID, &key.AccountID, &key.Email, &key.KeyID, &key.Fingerprint, &key.UserID,
 		&key.Algorithm, &key.KeySize, &createdAtKey, &expiresAtKey,
-		&publicKeyArmored, &key.IsDefault, &key.CreatedAt,
+		&publicKeyArmored, &key.IsDefault, &revokedAt, &revokeReason, &key.CreatedAt,
 	)
 	if err != nil {
 		return nil, "", err
@@ -72,6 +73,10 @@ func (s *Store) GetKey(id string) (*Key, string, error) {
 		key.ExpiresAtKey = &expiresAtKey.Time
 		key.IsExpired = time.Now().After(expiresAtKey.Time)
 	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+		key.RevokeReason = revokeReason.String
+	}
 
 	return key, publicKeyArmored, nil
 }
@@ -81,7 +86,7 @@ func (s *Store) ListKeys(accountID string) ([]*Key, error) {
 	rows, err := s.db.Query(`
 		SELECT id, account_id, email, key_id, fingerprint, user_id,
 			algorithm, key_size, created_at_key, expires_at_key,
-			is_default, created_at
+			is_default, revoked_at, revocation_reason, created_at
 		FROM pgp_keys WHERE account_id = ?
 		ORDER BY is_default DESC, created_at DESC`, accountID,
 	)
@@ -93,12 +98,13 @@ func (s *Store) ListKeys(accountID string) ([]*Key, error) {
 	var keys []*Key
 	for rows.Next() {
 		key := &Key{}
-		var createdAtKey, expiresAtKey sql.NullTime
+		var createdAtKey, expiresAtKey, revokedAt sql.NullTime
+		var revokeReason sql.NullString
 
 		if err := rows.Scan(
 			&key.ID, &key.AccountID, &key.Email, &key.KeyID, &key.Fingerprint, &key.UserID,
 			&key.Algorithm, &key.KeySize, &createdAtKey, &expiresAtKey,
-			&key.IsDefault, &key.CreatedAt,
+			&key.IsDefault, &revokedAt, &revokeReason, &key.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -110,12 +116,77 @@ func (s *Store) ListKeys(accountID string) ([]*Key, error) {
 			key.ExpiresAtKey = &expiresAtKey.Time
 			key.IsExpired = time.Now().After(expiresAtKey.Time)
 		}
+		if revokedAt.Valid {
+			key.RevokedAt = &revokedAt.Time
+			key.RevokeReason = revokeReason.String
+		}
 
 		keys = append(keys, key)
 	}
 	return keys, rows.Err()
 }
 
+// UpdateKeyMaterial replaces a key's public key and expiry after it has been
+// re-signed (expiry change or revocation)
+func (s *Store) UpdateKeyMaterial(id, publicKeyArmored string, expiresAtKey *time.Time) error {
+	_, err := s.db.Exec(
+		"UPDATE pgp_keys SET public_key_armored = ?, expires_at_key = ? WHERE id = ?",
+		publicKeyArmored, expiresAtKey, id,
+	)
+	return err
+}
+
+// MarkKeyRevoked records that a key has been revoked. A revoked default key
+// is replaced by the newest usable key of the account, or the account is
+// left without a default key.
+func (s *Store) MarkKeyRevoked(id, reason string, revokedAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var accountID string
+	var isDefault bool
+	if err := tx.QueryRow(
+		"SELECT account_id, is_default FROM pgp_keys WHERE id = ?", id,
+	).Scan(&accountID, &isDefault); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE pgp_keys SET revoked_at = ?, revocation_reason = ?, is_default = 0 WHERE id = ?",
+		revokedAt, reason, id,
+	); err != nil {
+		return err
+	}
+
+	if isDefault {
+		var replacement sql.NullString
+		err := tx.QueryRow(`
+			SELECT id FROM pgp_keys
+			WHERE account_id = ? AND id != ? AND revoked_at IS NULL
+				AND (expires_at_key IS NULL OR expires_at_key > ?)
+			ORDER BY created_at DESC LIMIT 1`, accountID, id, revokedAt,
+		).Scan(&replacement)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if replacement.Valid {
+			if _, err := tx.Exec("UPDATE pgp_keys SET is_default = 1 WHERE id = ?", replacement.String); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(
+			"UPDATE accounts SET pgp_default_key_id = ? WHERE id = ?", replacement, accountID,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
 // DeleteKey removes a PGP key by ID
 func (s *Store) DeleteKey(id string) error {
 	_, err := s.db.Exec("DELETE FROM pgp_keys WHERE id = ?", id)
@@ -158,18 +229,19 @@ func (s *Store) SetDefaultKey(accountID, keyID string) error {
 func (s *Store) GetDefaultKey(accountID string) (*Key, string, error) {
 	key := &Key{}
 	var publicKeyArmored string
-	var createdAtKey, expiresAtKey sql.NullTime
+	var createdAtKey, expiresAtKey, revokedAt sql.NullTime
+	var revokeReason sql.NullString
 
 	err := s.db.QueryRow(`
 		SELECT id, account_id, email, key_id, fingerprint, user_id,
 			algorithm, key_size, created_at_key, expires_at_key, public_key_armored,
-			is_default, created_at
+			is_default, revoked_at, revocation_reason, created_at
 		FROM pgp_keys
 		WHERE account_id = ? AND is_default = 1`, accountID,
 	).Scan(
 		&key.ID, &key.AccountID, &key.Email, &key.KeyID, &key.Fingerprint, &key.UserID,
 		&key.Algorithm, &key.KeySize, &createdAtKey, &expiresAtKey,
-		&publicKeyArmored, &key.IsDefault, &key.CreatedAt,
+		&publicKeyArmored, &key.IsDefault, &revokedAt, &revokeReason, &key.CreatedAt,
 	)
 	if err != nil {
 		return nil, "", err
@@ -182,6 +254,10 @@ func (s *Store) GetDefaultKey(accountID string) (*Key, string, error) {
 		key.ExpiresAtKey = &expiresAtKey.Time
 		key.IsExpired = time.Now().After(expiresAtKey.Time)
 	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+		key.RevokeReason = revokeReason.String
+	}
 
 	return key, publicKeyArmored, nil
 }
@@ -284,8 +360,21 @@ func (s *Store) GetSenderKeyArmored(id string) (string, error) {
 	return armored, err
 }
 
+// senderKeyRank orders cached keys by how much their source is trusted: keys
+// the user imported first, then keys published by the address's provider or
+// a keyserver, then keys learned from mail. A newer key from a less trusted
+// source never replaces one from a more trusted source.
+const senderKeyRank = `CASE source
+	WHEN 'manual' THEN 0
+	WHEN 'wkd' THEN 1
+	WHEN 'hkp' THEN 2
+	WHEN 'message' THEN 3
+	WHEN 'autocrypt' THEN 4
+	ELSE 5 END`
+
 // GetSenderKeyArmoreds returns armored public keys for multiple email addresses (batch lookup for encryption).
 // Returns a map of email -> armoredPublicKey for emails that have a valid (non-expired) key.
+// The most trusted source wins (see senderKeyRank), the most recently seen key within it.
 func (s *Store) GetSenderKeyArmoreds(emails []string) (map[string]string, error) {
 	result := make(map[string]string)
 	if len(emails) == 0 {
@@ -298,7 +387,7 @@ func (s *Store) GetSenderKeyArmoreds(emails []string) (map[string]string, error)
 		var expiresAtKey sql.NullTime
 		err := s.db.QueryRow(`
 			SELECT public_key_armored, expires_at_key FROM pgp_sender_keys
-			WHERE email = ? ORDER BY last_seen_at DESC LIMIT 1`, email,
+			WHERE email = ? ORDER BY `+senderKeyRank+`, last_seen_at DESC LIMIT 1`, email,
 		).Scan(&armored, &expiresAtKey)
 		if err != nil {
 			continue
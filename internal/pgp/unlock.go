@@ -0,0 +1,148 @@
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/hkdb/aerion/internal/credentials"
+)
+
+// ErrKeyLocked is returned when a passphrase-protected private key is needed
+// but hasn't been unlocked in this session
+var ErrKeyLocked = errors.New("PGP key is locked, unlock it with its passphrase first")
+
+// passphrases holds the passphrases of protected keys unlocked in this
+// session, by key ID. They are only kept in memory.
+var passphrases sync.Map
+
+// UnlockKey checks a passphrase against one of the user's protected keys and
+// remembers it for the rest of the session
+func UnlockKey(credStore *credentials.Store, keyID, passphrase string) error {
+	entity, err := readPrivateKey(credStore, keyID)
+	if err != nil {
+		return err
+	}
+	if !isProtected(entity) {
+		return nil
+	}
+	if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+		return fmt.Errorf("wrong passphrase")
+	}
+	passphrases.Store(keyID, passphrase)
+	return nil
+}
+
+// LockKey forgets the passphrase of an unlocked key
+func LockKey(keyID string) {
+	passphrases.Delete(keyID)
+}
+
+// LockAllKeys forgets the passphrases of all unlocked keys
+func LockAllKeys() {
+	passphrases.Clear()
+}
+
+// IsKeyProtected reports whether one of the user's stored private keys is
+// protected with a passphrase
+func IsKeyProtected(credStore *credentials.Store, keyID string) bool {
+	entity, err := readPrivateKey(credStore, keyID)
+	return err == nil && isProtected(entity)
+}
+
+// LoadPrivateKey returns one of the user's private keys ready for signing and
+// decryption. Protected keys are decrypted with the passphrase given to
+// UnlockKey; ErrKeyLocked is returned if there is none.
+func LoadPrivateKey(credStore *credentials.Store, keyID string) (*openpgp.Entity, error) {
+	entity, err := readPrivateKey(credStore, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if !isProtected(entity) {
+		return entity, nil
+	}
+
+	passphrase, ok := passphrases.Load(keyID)
+	if !ok {
+		return nil, ErrKeyLocked
+	}
+	if err := entity.DecryptPrivateKeys([]byte(passphrase.(string))); err != nil {
+		passphrases.Delete(keyID)
+		return nil, ErrKeyLocked
+	}
+	return entity, nil
+}
+
+// ArmorStoredPrivateKey armors an unlocked private key for the credential
+// store. Keys that were protected (unlocked this session) are protected
+// again with the same passphrase.
+func ArmorStoredPrivateKey(keyID string, entity *openpgp.Entity) (string, error) {
+	if passphrase, ok := passphrases.Load(keyID); ok {
+		return ArmorProtectedPrivateKey(entity, passphrase.(string))
+	}
+	return ArmorPrivateKey(entity)
+}
+
+// readPrivateKey parses a stored private key without decrypting it
+func readPrivateKey(credStore *credentials.Store, keyID string) (*openpgp.Entity, error) {
+	armoredPrivate, err := credStore.GetPGPPrivateKey(keyID)
+	if err != nil || len(armoredPrivate) == 0 {
+		return nil, fmt.Errorf("private key not available")
+	}
+	entities, err := ParseArmoredKey(string(armoredPrivate))
+	if err != nil {
+		return nil, err
+	}
+	if entities[0].PrivateKey == nil {
+		return nil, fmt.Errorf("private key not available")
+	}
+	return entities[0], nil
+}
+
+// isProtected reports whether any private key of an entity is encrypted
+func isProtected(entity *openpgp.Entity) bool {
+	if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+		return true
+	}
+	for _, sub := range entity.Subkeys {
+		if sub.PrivateKey != nil && sub.PrivateKey.Encrypted {
+			return true
+		}
+	}
+	return false
+}
+
+// copyEntity returns an independent copy of an unlocked entity, so that
+// encrypting the copy's private keys leaves the original usable
+func copyEntity(entity *openpgp.Entity) (*openpgp.Entity, error) {
+	var buf bytes.Buffer
+	if err := entity.SerializePrivateWithoutSigning(&buf, nil); err != nil {
+		return nil, fmt.Errorf("failed to serialize private key: %w", err)
+	}
+	copied, err := openpgp.ReadEntity(packet.NewReader(&buf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy private key: %w", err)
+	}
+	return copied, nil
+}
+
+// armorPrivateEntity armors an entity's private key as stored (without
+// re-signing)
+func armorPrivateEntity(entity *openpgp.Entity) (string, error) {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, "PGP PRIVATE KEY BLOCK", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create armor writer: %w", err)
+	}
+	if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		return "", fmt.Errorf("failed to serialize private key: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to close armor writer: %w", err)
+	}
+	return buf.String(), nil
+}
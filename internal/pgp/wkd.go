@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/hkdb/aerion/internal/netproxy"
 )
 
 // LookupWKD performs a Web Key Directory lookup for a given email address.
@@ -24,7 +26,7 @@ func LookupWKD(email string) (string, error) {
 	hash := sha1.Sum([]byte(localpart))
 	encoded := zBase32Encode(hash[:])
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := netproxy.HTTPClient(5 * time.Second)
 
 	// Try direct method first: https://<domain>/.well-known/openpgpkey/hu/<hash>?l=<localpart>
 	directURL := fmt.Sprintf("https://%s/.well-known/openpgpkey/hu/%s?l=%s", domain, encoded, localpart)
@@ -16,8 +16,22 @@ type Paths struct {
 	Cache  string // Cached data (can be deleted)
 }
 
-// GetPaths returns platform-specific paths for the application
+// GetPaths returns the paths of the selected profile (see SelectProfile)
 func GetPaths() (*Paths, error) {
+	return GetProfilePaths(activeProfile)
+}
+
+// basePaths returns the paths of the default profile: the data directory
+// given for portable mode, or the platform-specific locations
+func basePaths() (*Paths, error) {
+	if dataRoot != "" {
+		return &Paths{
+			Config: filepath.Join(dataRoot, "config"),
+			Data:   filepath.Join(dataRoot, "data"),
+			Cache:  filepath.Join(dataRoot, "cache"),
+		}, nil
+	}
+
 	switch runtime.GOOS {
 	case "linux":
 		return getLinuxPaths()
@@ -159,3 +173,8 @@ func (p *Paths) ConfigFilePath() string {
 func (p *Paths) AttachmentsPath() string {
 	return filepath.Join(p.Data, "attachments")
 }
+
+// AttachmentBlobsPath returns the path to the content-addressed attachment store
+func (p *Paths) AttachmentBlobsPath() string {
+	return filepath.Join(p.AttachmentsPath(), "blobs")
+}
@@ -0,0 +1,149 @@
+package platform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultProfile is the profile used when no --profile is given. Its data
+// lives at the platform locations, as before profiles existed.
+const DefaultProfile = "default"
+
+// profileNamePattern limits profile names to what is safe in paths, socket
+// names and keyring service names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// Selected profile and data root. Set once at startup by SelectProfile,
+// before GetPaths is called.
+var (
+	activeProfile string // "" = DefaultProfile
+	dataRoot      string // "" = platform locations
+)
+
+// ValidateProfileName checks that a profile name is usable
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_' (up to 64 characters)", name)
+	}
+	return nil
+}
+
+// SelectProfile selects the profile and, for portable mode, the directory
+// holding all config, data and cache instead of the platform locations.
+// Empty values keep the defaults.
+func SelectProfile(name, dataDir string) error {
+	if name != "" && name != DefaultProfile {
+		if err := ValidateProfileName(name); err != nil {
+			return err
+		}
+		activeProfile = name
+	}
+	if dataDir != "" {
+		abs, err := filepath.Abs(dataDir)
+		if err != nil {
+			return fmt.Errorf("invalid data directory: %w", err)
+		}
+		dataRoot = abs
+	}
+	return nil
+}
+
+// ActiveProfile returns the name of the selected profile
+func ActiveProfile() string {
+	if activeProfile == "" {
+		return DefaultProfile
+	}
+	return activeProfile
+}
+
+// Portable reports whether a data directory was given. Secrets are then
+// kept in the data directory rather than the OS keyring so they move with it.
+func Portable() bool {
+	return dataRoot != ""
+}
+
+// DataRoot returns the data directory given for portable mode, or ""
+func DataRoot() string {
+	return dataRoot
+}
+
+// InstanceID identifies the selected profile and data root, for namespacing
+// resources shared by all instances such as the IPC socket and keyring.
+// It is empty for the default profile at the platform locations.
+func InstanceID() string {
+	return InstanceIDFor(activeProfile)
+}
+
+// InstanceIDFor returns the instance ID of a profile under the selected data root
+func InstanceIDFor(profile string) string {
+	var parts []string
+	if dataRoot != "" {
+		sum := sha256.Sum256([]byte(dataRoot))
+		parts = append(parts, "portable-"+hex.EncodeToString(sum[:4]))
+	}
+	if profile != "" && profile != DefaultProfile {
+		parts = append(parts, profile)
+	}
+	return strings.Join(parts, "-")
+}
+
+// KeyringService returns the OS keyring service name of the selected profile
+func KeyringService() string {
+	return KeyringServiceFor(activeProfile)
+}
+
+// KeyringServiceFor returns the OS keyring service name of a profile
+func KeyringServiceFor(profile string) string {
+	if id := InstanceIDFor(profile); id != "" {
+		return appName + "-" + id
+	}
+	return appName
+}
+
+// ProfileArgs returns the command-line flags selecting the current profile,
+// for spawning processes (e.g. detached composers) that share its data
+func ProfileArgs() []string {
+	var args []string
+	if activeProfile != "" {
+		args = append(args, "--profile", activeProfile)
+	}
+	if dataRoot != "" {
+		args = append(args, "--data-dir", dataRoot)
+	}
+	return args
+}
+
+// GetProfilePaths returns the paths of a profile under the selected data root
+func GetProfilePaths(profile string) (*Paths, error) {
+	base, err := basePaths()
+	if err != nil {
+		return nil, err
+	}
+	if profile == "" || profile == DefaultProfile {
+		return base, nil
+	}
+	if err := ValidateProfileName(profile); err != nil {
+		return nil, err
+	}
+	return &Paths{
+		Config: filepath.Join(base.Config, profilesDir, profile),
+		Data:   filepath.Join(base.Data, profilesDir, profile),
+		Cache:  filepath.Join(base.Cache, profilesDir, profile),
+	}, nil
+}
+
+// ProfilesPath returns the directory holding the data of named profiles
+func ProfilesPath() (string, error) {
+	base, err := basePaths()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base.Data, profilesDir), nil
+}
+
+// profilesDir is the subdirectory of each root holding named profiles
+const profilesDir = "profiles"
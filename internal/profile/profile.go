@@ -0,0 +1,156 @@
+// Package profile manages profiles: isolated sets of accounts, settings and
+// data selected with --profile
+package profile
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/hkdb/aerion/internal/ipc"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/platform"
+	gokeyring "github.com/zalando/go-keyring"
+)
+
+// Profile describes a profile
+type Profile struct {
+	Name    string `json:"name"`
+	DataDir string `json:"dataDir"`
+	Active  bool   `json:"active"`  // The profile selected for this process
+	Running bool   `json:"running"` // Open in an Aerion instance
+}
+
+// List returns the default profile followed by the named profiles under the
+// selected data root, sorted by name
+func List() ([]*Profile, error) {
+	names := []string{platform.DefaultProfile}
+
+	dir, err := platform.ProfilesPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profiles directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	var named []string
+	for _, entry := range entries {
+		if !entry.IsDir() || platform.ValidateProfileName(entry.Name()) != nil || entry.Name() == platform.DefaultProfile {
+			continue
+		}
+		named = append(named, entry.Name())
+	}
+	sort.Strings(named)
+	names = append(names, named...)
+
+	profiles := make([]*Profile, 0, len(names))
+	for _, name := range names {
+		p, err := describe(name)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+// Create creates the directories of a new profile. It can then be opened
+// with --profile NAME.
+func Create(name string) (*Profile, error) {
+	if err := platform.ValidateProfileName(name); err != nil {
+		return nil, err
+	}
+	if name == platform.DefaultProfile {
+		return nil, fmt.Errorf("profile %q already exists", name)
+	}
+
+	paths, err := platform.GetProfilePaths(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(paths.Data); err == nil {
+		return nil, fmt.Errorf("profile %q already exists", name)
+	}
+	if err := paths.EnsureDirectories(); err != nil {
+		return nil, fmt.Errorf("failed to create profile directories: %w", err)
+	}
+
+	log := logging.WithComponent("profile")
+	log.Info().Str("profile", name).Msg("Created profile")
+	return describe(name)
+}
+
+// Delete removes a profile's data, configuration, cache and keyring entries.
+// The default profile, the active profile and profiles open in another
+// instance can't be deleted.
+func Delete(name string) error {
+	log := logging.WithComponent("profile")
+
+	if err := platform.ValidateProfileName(name); err != nil {
+		return err
+	}
+	if name == platform.DefaultProfile {
+		return fmt.Errorf("the default profile can't be deleted")
+	}
+	if name == platform.ActiveProfile() {
+		return fmt.Errorf("profile %q is in use by this instance", name)
+	}
+
+	p, err := describe(name)
+	if err != nil {
+		return err
+	}
+	if p.Running {
+		return fmt.Errorf("profile %q is open in another instance; close it first", name)
+	}
+
+	paths, err := platform.GetProfilePaths(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(paths.Data); os.IsNotExist(err) {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+
+	// Config and data share a directory on some platforms
+	removed := make(map[string]bool)
+	for _, dir := range []string{paths.Config, paths.Data, paths.Cache} {
+		if removed[dir] {
+			continue
+		}
+		removed[dir] = true
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove profile directory: %w", err)
+		}
+	}
+
+	// Credentials kept in the OS keyring. Portable profiles never use it.
+	if !platform.Portable() {
+		if err := gokeyring.DeleteAll(platform.KeyringServiceFor(name)); err != nil {
+			log.Warn().Err(err).Str("profile", name).Msg("Failed to remove profile credentials from keyring")
+		}
+	}
+
+	log.Info().Str("profile", name).Msg("Deleted profile")
+	return nil
+}
+
+// describe returns the details of a profile
+func describe(name string) (*Profile, error) {
+	paths, err := platform.GetProfilePaths(name)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Profile{
+		Name:    name,
+		DataDir: paths.Data,
+		Active:  name == platform.ActiveProfile(),
+	}
+	if address, err := ipc.AddressFor(platform.InstanceIDFor(name)); err == nil {
+		p.Running = ipc.IsListening(address)
+	}
+	return p, nil
+}
@@ -0,0 +1,44 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hkdb/aerion/internal/email"
+)
+
+// GetCustomLinkCleanerRules returns the user-defined link cleaner rules that
+// extend the built-in defaults
+func (s *Store) GetCustomLinkCleanerRules() (email.LinkCleanerRules, error) {
+	var rules email.LinkCleanerRules
+	value, err := s.Get(KeyLinkCleanerRules)
+	if err != nil || value == "" {
+		return rules, err
+	}
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return email.LinkCleanerRules{}, fmt.Errorf("failed to parse link cleaner rules: %w", err)
+	}
+	return rules, nil
+}
+
+// SetCustomLinkCleanerRules sets the user-defined link cleaner rules
+func (s *Store) SetCustomLinkCleanerRules(rules email.LinkCleanerRules) error {
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return fmt.Errorf("failed to encode link cleaner rules: %w", err)
+	}
+	return s.Set(KeyLinkCleanerRules, string(data))
+}
+
+// GetLinkCleanerRules returns the effective link cleaner rules (built-in defaults
+// extended with the user-defined rules)
+func (s *Store) GetLinkCleanerRules() (email.LinkCleanerRules, error) {
+	custom, err := s.GetCustomLinkCleanerRules()
+	if err != nil {
+		return email.DefaultLinkCleanerRules(), err
+	}
+	return email.DefaultLinkCleanerRules().Merge(custom), nil
+}
@@ -0,0 +1,60 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DefaultLocalAPIPort is the loopback port the local API listens on by default
+const DefaultLocalAPIPort = 7468
+
+// LocalAPIConfig controls the read-only local HTTP API for scripts and
+// status bar widgets
+type LocalAPIConfig struct {
+	Enabled bool `json:"enabled"`
+	Port    int  `json:"port"` // Loopback port, 0 = default
+}
+
+// ListenPort returns the configured port, or the default
+func (c *LocalAPIConfig) ListenPort() int {
+	if c.Port == 0 {
+		return DefaultLocalAPIPort
+	}
+	return c.Port
+}
+
+// Validate checks the configuration for invalid values
+func (c *LocalAPIConfig) Validate() error {
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("invalid local API port: %d", c.Port)
+	}
+	if c.Port != 0 && c.Port < 1024 {
+		return fmt.Errorf("invalid local API port: %d (must be 1024 or higher)", c.Port)
+	}
+	return nil
+}
+
+// GetLocalAPIConfig returns the local API configuration (disabled by default)
+func (s *Store) GetLocalAPIConfig() (*LocalAPIConfig, error) {
+	config := &LocalAPIConfig{}
+	value, err := s.Get(KeyLocalAPI)
+	if err != nil || value == "" {
+		return config, err
+	}
+	if err := json.Unmarshal([]byte(value), config); err != nil {
+		return &LocalAPIConfig{}, fmt.Errorf("failed to parse local API config: %w", err)
+	}
+	return config, nil
+}
+
+// SetLocalAPIConfig sets the local API configuration
+func (s *Store) SetLocalAPIConfig(config *LocalAPIConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode local API config: %w", err)
+	}
+	return s.Set(KeyLocalAPI, string(data))
+}
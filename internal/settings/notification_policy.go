@@ -0,0 +1,209 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Digest window bounds in seconds
+const (
+	DefaultDigestWindow = 120
+	minDigestWindow     = 30
+	maxDigestWindow     = 3600
+)
+
+// NotificationPolicy controls which new messages raise desktop notifications
+type NotificationPolicy struct {
+	DisabledAccounts []string   `json:"disabledAccounts"` // Account IDs that never notify
+	DisabledFolders  []string   `json:"disabledFolders"`  // Folder IDs that never notify
+	VIPSenders       []string   `json:"vipSenders"`       // Addresses or domains that always notify
+	QuietHours       QuietHours `json:"quietHours"`
+	Digest           Digest     `json:"digest"`
+}
+
+// QuietHours is a daily do-not-disturb period in local time
+type QuietHours struct {
+	Enabled bool   `json:"enabled"`
+	Start   string `json:"start"`          // "HH:MM"
+	End     string `json:"end"`            // "HH:MM", earlier than Start for overnight periods
+	Days    []int  `json:"days,omitempty"` // Weekdays the period starts on (0 = Sunday), empty = every day
+}
+
+// Digest batches bursts of new mail into one summary notification
+type Digest struct {
+	Enabled       bool `json:"enabled"`
+	WindowSeconds int  `json:"windowSeconds"` // How long to collect messages before notifying
+}
+
+// NotificationDecision is the outcome of evaluating a message against the policy
+type NotificationDecision int
+
+const (
+	NotificationSuppress NotificationDecision = iota // Don't notify
+	NotificationNow                                  // Notify immediately
+	NotificationDigest                               // Add to the next digest
+)
+
+// Window returns the digest collection window
+func (d Digest) Window() time.Duration {
+	if d.WindowSeconds <= 0 {
+		return DefaultDigestWindow * time.Second
+	}
+	return time.Duration(d.WindowSeconds) * time.Second
+}
+
+// Evaluate decides how a new message is notified. VIP senders always
+// notify immediately; everything else is subject to the account/folder
+// switches, quiet hours and digest mode.
+func (p *NotificationPolicy) Evaluate(accountID, folderID, fromEmail string, now time.Time) NotificationDecision {
+	if p.IsVIP(fromEmail) {
+		return NotificationNow
+	}
+	if containsString(p.DisabledAccounts, accountID) || containsString(p.DisabledFolders, folderID) {
+		return NotificationSuppress
+	}
+	if p.QuietHours.Active(now) {
+		return NotificationSuppress
+	}
+	if p.Digest.Enabled {
+		return NotificationDigest
+	}
+	return NotificationNow
+}
+
+// IsVIP reports whether a sender address or its domain is on the VIP list
+func (p *NotificationPolicy) IsVIP(fromEmail string) bool {
+	email := strings.ToLower(strings.TrimSpace(fromEmail))
+	if email == "" {
+		return false
+	}
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at != -1 {
+		domain = email[at+1:]
+	}
+
+	for _, vip := range p.VIPSenders {
+		vip = strings.ToLower(strings.TrimSpace(vip))
+		if vip == "" {
+			continue
+		}
+		if strings.Contains(vip, "@") && !strings.HasPrefix(vip, "@") {
+			if vip == email {
+				return true
+			}
+			continue
+		}
+		if strings.TrimPrefix(vip, "@") == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the quiet period covers the given time
+func (q QuietHours) Active(now time.Time) bool {
+	if !q.Enabled {
+		return false
+	}
+	start, err1 := parseClock(q.Start)
+	end, err2 := parseClock(q.End)
+	if err1 != nil || err2 != nil || start == end {
+		return false
+	}
+
+	minutes := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+
+	var inside bool
+	if start < end {
+		inside = minutes >= start && minutes < end
+	} else {
+		// Overnight: the early-morning part belongs to the previous day's period
+		switch {
+		case minutes >= start:
+			inside = true
+		case minutes < end:
+			inside = true
+			day = (day + 6) % 7
+		}
+	}
+	if !inside {
+		return false
+	}
+
+	if len(q.Days) == 0 {
+		return true
+	}
+	for _, d := range q.Days {
+		if time.Weekday(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the policy for invalid values
+func (p *NotificationPolicy) Validate() error {
+	if p.QuietHours.Enabled {
+		if _, err := parseClock(p.QuietHours.Start); err != nil {
+			return fmt.Errorf("invalid quiet hours start: %w", err)
+		}
+		if _, err := parseClock(p.QuietHours.End); err != nil {
+			return fmt.Errorf("invalid quiet hours end: %w", err)
+		}
+	}
+	for _, d := range p.QuietHours.Days {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("invalid quiet hours day: %d (must be 0-6)", d)
+		}
+	}
+	if p.Digest.WindowSeconds != 0 && (p.Digest.WindowSeconds < minDigestWindow || p.Digest.WindowSeconds > maxDigestWindow) {
+		return fmt.Errorf("invalid digest window: %d (must be %d-%d seconds)", p.Digest.WindowSeconds, minDigestWindow, maxDigestWindow)
+	}
+	return nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNotificationPolicy returns the desktop notification policy
+func (s *Store) GetNotificationPolicy() (*NotificationPolicy, error) {
+	policy := &NotificationPolicy{}
+	value, err := s.Get(KeyNotificationPolicy)
+	if err != nil || value == "" {
+		return policy, err
+	}
+	if err := json.Unmarshal([]byte(value), policy); err != nil {
+		return &NotificationPolicy{}, fmt.Errorf("failed to parse notification policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SetNotificationPolicy sets the desktop notification policy
+func (s *Store) SetNotificationPolicy(policy *NotificationPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification policy: %w", err)
+	}
+	return s.Set(KeyNotificationPolicy, string(data))
+}
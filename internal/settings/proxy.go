@@ -0,0 +1,40 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hkdb/aerion/internal/netproxy"
+)
+
+// GetProxyConfig returns the global proxy. Connections are direct by default.
+// The password is not included; it is kept in the credential store.
+func (s *Store) GetProxyConfig() (*netproxy.Config, error) {
+	value, err := s.Get(KeyProxy)
+	if err != nil || value == "" {
+		return &netproxy.Config{Type: netproxy.TypeNone}, err
+	}
+	config := &netproxy.Config{}
+	if err := json.Unmarshal([]byte(value), config); err != nil {
+		return &netproxy.Config{Type: netproxy.TypeNone}, fmt.Errorf("failed to parse proxy config: %w", err)
+	}
+	if config.Type == netproxy.TypeDefault {
+		config.Type = netproxy.TypeNone
+	}
+	return config, nil
+}
+
+// SetProxyConfig sets the global proxy
+func (s *Store) SetProxyConfig(config *netproxy.Config) error {
+	if config.Type == netproxy.TypeDefault {
+		return fmt.Errorf("global proxy type is required")
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode proxy config: %w", err)
+	}
+	return s.Set(KeyProxy, string(data))
+}
@@ -0,0 +1,84 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Default quota warning thresholds in percent of the server limit
+const (
+	DefaultQuotaWarningPercent  = 80
+	DefaultQuotaCriticalPercent = 95
+)
+
+// Quota warning levels
+const (
+	QuotaLevelOK       = ""
+	QuotaLevelWarning  = "warning"
+	QuotaLevelCritical = "critical"
+)
+
+// QuotaWarningConfig controls when a nearly full server mailbox is reported
+type QuotaWarningConfig struct {
+	WarningPercent  int `json:"warningPercent"`  // Warn at this usage, 0 = never
+	CriticalPercent int `json:"criticalPercent"` // Warn urgently at this usage, 0 = never
+}
+
+// Level returns the warning level of a usage percentage
+func (c *QuotaWarningConfig) Level(percent float64) string {
+	switch {
+	case c.CriticalPercent > 0 && percent >= float64(c.CriticalPercent):
+		return QuotaLevelCritical
+	case c.WarningPercent > 0 && percent >= float64(c.WarningPercent):
+		return QuotaLevelWarning
+	default:
+		return QuotaLevelOK
+	}
+}
+
+// Validate checks the configuration for invalid values
+func (c *QuotaWarningConfig) Validate() error {
+	if c.WarningPercent < 0 || c.WarningPercent > 100 {
+		return fmt.Errorf("invalid quota warning threshold: %d", c.WarningPercent)
+	}
+	if c.CriticalPercent < 0 || c.CriticalPercent > 100 {
+		return fmt.Errorf("invalid critical quota threshold: %d", c.CriticalPercent)
+	}
+	if c.WarningPercent > 0 && c.CriticalPercent > 0 && c.WarningPercent > c.CriticalPercent {
+		return fmt.Errorf("quota warning threshold must not exceed the critical threshold")
+	}
+	return nil
+}
+
+// defaultQuotaWarningConfig returns the default thresholds
+func defaultQuotaWarningConfig() *QuotaWarningConfig {
+	return &QuotaWarningConfig{
+		WarningPercent:  DefaultQuotaWarningPercent,
+		CriticalPercent: DefaultQuotaCriticalPercent,
+	}
+}
+
+// GetQuotaWarningConfig returns the quota warning thresholds
+func (s *Store) GetQuotaWarningConfig() (*QuotaWarningConfig, error) {
+	value, err := s.Get(KeyQuotaWarnings)
+	if err != nil || value == "" {
+		return defaultQuotaWarningConfig(), err
+	}
+	config := defaultQuotaWarningConfig()
+	if err := json.Unmarshal([]byte(value), config); err != nil {
+		return defaultQuotaWarningConfig(), fmt.Errorf("failed to parse quota warning config: %w", err)
+	}
+	return config, nil
+}
+
+// SetQuotaWarningConfig sets the quota warning thresholds
+func (s *Store) SetQuotaWarningConfig(config *QuotaWarningConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode quota warning config: %w", err)
+	}
+	return s.Set(KeyQuotaWarnings, string(data))
+}
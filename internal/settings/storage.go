@@ -0,0 +1,77 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultMaintenanceIntervalHours is how often database maintenance runs by default
+const DefaultMaintenanceIntervalHours = 24
+
+// StorageConfig controls the local mail cache size and database maintenance
+type StorageConfig struct {
+	CacheBudgetMB            int `json:"cacheBudgetMB"`            // Max size of cached bodies and attachments, 0 = unlimited
+	MaintenanceIntervalHours int `json:"maintenanceIntervalHours"` // Hours between scheduled maintenance runs, 0 = only on demand
+}
+
+// CacheBudgetBytes returns the cache budget in bytes, 0 = unlimited
+func (c *StorageConfig) CacheBudgetBytes() int64 {
+	return int64(c.CacheBudgetMB) * 1024 * 1024
+}
+
+// Validate checks the configuration for invalid values
+func (c *StorageConfig) Validate() error {
+	if c.CacheBudgetMB < 0 {
+		return fmt.Errorf("invalid cache budget: %d", c.CacheBudgetMB)
+	}
+	if c.MaintenanceIntervalHours < 0 {
+		return fmt.Errorf("invalid maintenance interval: %d", c.MaintenanceIntervalHours)
+	}
+	return nil
+}
+
+// GetStorageConfig returns the storage configuration (unlimited cache and
+// daily maintenance by default)
+func (s *Store) GetStorageConfig() (*StorageConfig, error) {
+	config := &StorageConfig{MaintenanceIntervalHours: DefaultMaintenanceIntervalHours}
+	value, err := s.Get(KeyStorage)
+	if err != nil || value == "" {
+		return config, err
+	}
+	if err := json.Unmarshal([]byte(value), config); err != nil {
+		return &StorageConfig{MaintenanceIntervalHours: DefaultMaintenanceIntervalHours}, fmt.Errorf("failed to parse storage config: %w", err)
+	}
+	return config, nil
+}
+
+// SetStorageConfig sets the storage configuration
+func (s *Store) SetStorageConfig(config *StorageConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode storage config: %w", err)
+	}
+	return s.Set(KeyStorage, string(data))
+}
+
+// GetLastStorageMaintenance returns when database maintenance last ran, or
+// the zero time if it never has
+func (s *Store) GetLastStorageMaintenance() (time.Time, error) {
+	value, err := s.Get(KeyStorageLastMaintenance)
+	if err != nil || value == "" {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse last maintenance time: %w", err)
+	}
+	return t, nil
+}
+
+// SetLastStorageMaintenance records when database maintenance last ran
+func (s *Store) SetLastStorageMaintenance(t time.Time) error {
+	return s.Set(KeyStorageLastMaintenance, t.UTC().Format(time.RFC3339))
+}
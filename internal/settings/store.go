@@ -20,6 +20,18 @@ const (
 	KeyThemeMode                 = "theme_mode"
 	KeyShowTitleBar              = "show_title_bar"
 	KeyTermsAccepted             = "terms_accepted"
+	KeyLinkCleaningEnabled       = "link_cleaning_enabled"
+	KeyLinkCleanerRules          = "link_cleaner_rules"
+	KeySpamFilterEnabled         = "spam_filter_enabled"
+	KeySpamFilterAction          = "spam_filter_action"
+	KeySpamFilterThreshold       = "spam_filter_threshold"
+	KeySMIMERevocationPolicy     = "smime_revocation_policy"
+	KeyNotificationPolicy        = "notification_policy"
+	KeyLocalAPI                  = "local_api"
+	KeyStorage                   = "storage"
+	KeyStorageLastMaintenance    = "storage_last_maintenance"
+	KeyQuotaWarnings             = "quota_warnings"
+	KeyProxy                     = "proxy"
 )
 
 // Density values for message list
@@ -62,6 +74,15 @@ const (
 	PolicyAlways = "always"
 )
 
+// Spam filter actions for probable spam
+const (
+	SpamActionTag  = "tag"  // Leave in inbox, flag as probable junk
+	SpamActionMove = "move" // Move to the account's Spam folder
+)
+
+// DefaultSpamFilterThreshold is the default score at or above which a message is probable spam
+const DefaultSpamFilterThreshold = 0.9
+
 // Default mark as read delay in milliseconds (1 second)
 const DefaultMarkAsReadDelay = 1000
 
@@ -259,3 +280,99 @@ func (s *Store) SetTermsAccepted(accepted bool) error {
 	}
 	return s.Set(KeyTermsAccepted, value)
 }
+
+// GetLinkCleaningEnabled returns whether tracking links should be cleaned when displaying messages
+func (s *Store) GetLinkCleaningEnabled() (bool, error) {
+	value, err := s.Get(KeyLinkCleaningEnabled)
+	if err != nil {
+		return true, err // Default to true (enabled)
+	}
+	if value == "" {
+		return true, nil // Default to true (enabled)
+	}
+	return value == "true", nil
+}
+
+// SetLinkCleaningEnabled sets whether tracking links should be cleaned
+func (s *Store) SetLinkCleaningEnabled(enabled bool) error {
+	value := "false"
+	if enabled {
+		value = "true"
+	}
+	return s.Set(KeyLinkCleaningEnabled, value)
+}
+
+// GetSpamFilterEnabled returns whether new inbox messages are scored by the spam classifier
+func (s *Store) GetSpamFilterEnabled() (bool, error) {
+	value, err := s.Get(KeySpamFilterEnabled)
+	if err != nil {
+		return false, err
+	}
+	return value == "true", nil
+}
+
+// SetSpamFilterEnabled sets whether new inbox messages are scored by the spam classifier
+func (s *Store) SetSpamFilterEnabled(enabled bool) error {
+	value := "false"
+	if enabled {
+		value = "true"
+	}
+	return s.Set(KeySpamFilterEnabled, value)
+}
+
+// GetSpamFilterAction returns what happens to probable spam ("tag" or "move")
+func (s *Store) GetSpamFilterAction() (string, error) {
+	value, err := s.Get(KeySpamFilterAction)
+	if err != nil {
+		return SpamActionTag, err
+	}
+	if value == "" {
+		return SpamActionTag, nil
+	}
+	return value, nil
+}
+
+// SetSpamFilterAction sets what happens to probable spam
+func (s *Store) SetSpamFilterAction(action string) error {
+	if action != SpamActionTag && action != SpamActionMove {
+		return fmt.Errorf("invalid spam action: %s (must be 'tag' or 'move')", action)
+	}
+	return s.Set(KeySpamFilterAction, action)
+}
+
+// GetSpamFilterThreshold returns the score at or above which a message is probable spam
+func (s *Store) GetSpamFilterThreshold() (float64, error) {
+	value, err := s.Get(KeySpamFilterThreshold)
+	if err != nil {
+		return DefaultSpamFilterThreshold, err
+	}
+	if value == "" {
+		return DefaultSpamFilterThreshold, nil
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return DefaultSpamFilterThreshold, nil
+	}
+	return threshold, nil
+}
+
+// SetSpamFilterThreshold sets the probable spam threshold
+// Valid values: 0.5 - 1.0
+func (s *Store) SetSpamFilterThreshold(threshold float64) error {
+	if threshold < 0.5 || threshold > 1 {
+		return fmt.Errorf("invalid spam threshold: %v (must be between 0.5 and 1.0)", threshold)
+	}
+	return s.Set(KeySpamFilterThreshold, strconv.FormatFloat(threshold, 'f', -1, 64))
+}
+
+// GetSMIMERevocationPolicy returns how S/MIME certificate revocation checking
+// is applied, as stored ("" when unset). Parse it with smime.ParseRevocationPolicy.
+func (s *Store) GetSMIMERevocationPolicy() (string, error) {
+	return s.Get(KeySMIMERevocationPolicy)
+}
+
+// SetSMIMERevocationPolicy sets how S/MIME certificate revocation checking is
+// applied. The caller validates it with smime.ParseRevocationPolicy.
+func (s *Store) SetSMIMERevocationPolicy(policy string) error {
+	return s.Set(KeySMIMERevocationPolicy, policy)
+}
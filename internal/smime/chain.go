@@ -0,0 +1,198 @@
+package smime
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mozilla.org/pkcs7"
+)
+
+// maxAIAFetches limits how many issuer certificates are downloaded while
+// completing a single chain
+const maxAIAFetches = 3
+
+var (
+	systemRootsOnce sync.Once
+	systemRoots     *x509.CertPool
+)
+
+// loadSystemRoots returns the system root pool, or an empty pool on platforms
+// where it can't be loaded
+func loadSystemRoots() *x509.CertPool {
+	systemRootsOnce.Do(func() {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		systemRoots = pool
+	})
+	return systemRoots.Clone()
+}
+
+// signerCertificate returns the certificate that produced the signature:
+// the only signer's cert when available, otherwise the first end-entity cert
+func signerCertificate(p7 *pkcs7.PKCS7) *x509.Certificate {
+	if cert := p7.GetOnlySigner(); cert != nil {
+		return cert
+	}
+	for _, cert := range p7.Certificates {
+		if !cert.IsCA {
+			return cert
+		}
+	}
+	if len(p7.Certificates) > 0 {
+		return p7.Certificates[0]
+	}
+	return nil
+}
+
+// signingTime returns the authenticated signing time attribute, if present
+func signingTime(p7 *pkcs7.PKCS7) (time.Time, bool) {
+	var t time.Time
+	if err := p7.UnmarshalSignedAttribute(pkcs7.OIDAttributeSigningTime, &t); err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// verificationTime returns the time the signer's chain is verified at: the
+// signing time from the signed attributes, or now if there is none. A
+// signature made while the certificate was valid stays valid after it
+// expires.
+func verificationTime(p7 *pkcs7.PKCS7) time.Time {
+	if t, ok := signingTime(p7); ok {
+		return t
+	}
+	return time.Now()
+}
+
+// isSelfIssued reports whether a certificate's issuer is its own subject
+func isSelfIssued(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject)
+}
+
+// buildChain builds a chain from leaf to a system root or a user-imported
+// trust anchor, valid at time at (the signing time when the signature has
+// one). Intermediates come from the message, the local intermediate cache,
+// and (when online) the AIA issuer URLs. Returns the chain (leaf first) and
+// its trust source.
+func (v *Verifier) buildChain(leaf *x509.Certificate, embedded []*x509.Certificate, online bool, at time.Time) ([]*x509.Certificate, string, error) {
+	intermediates := x509.NewCertPool()
+	for _, cert := range embedded {
+		if cert.Equal(leaf) {
+			continue
+		}
+		intermediates.AddCert(cert)
+		v.cacheIntermediate(cert)
+	}
+	cached, err := v.store.IntermediateCerts()
+	if err != nil {
+		v.log.Warn().Err(err).Msg("Failed to load cached intermediates")
+	}
+	for _, cert := range cached {
+		intermediates.AddCert(cert)
+	}
+
+	roots := loadSystemRoots()
+	userAnchors := make(map[string]bool)
+	anchors, err := v.store.TrustAnchorCerts()
+	if err != nil {
+		v.log.Warn().Err(err).Msg("Failed to load trust anchors")
+	}
+	for _, cert := range anchors {
+		roots.AddCert(cert)
+		userAnchors[certificateFingerprint(cert.Raw)] = true
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	}
+
+	chains, err := leaf.Verify(opts)
+
+	// Missing intermediate: follow the AIA issuer URLs and retry
+	var unknownAuthority x509.UnknownAuthorityError
+	if err != nil && online && errors.As(err, &unknownAuthority) && !isSelfIssued(leaf) {
+		current := leaf
+		fetched := false
+		for i := 0; i < maxAIAFetches; i++ {
+			issuer := v.fetchIssuer(current)
+			if issuer == nil {
+				break
+			}
+			intermediates.AddCert(issuer)
+			v.cacheIntermediate(issuer)
+			fetched = true
+			if isSelfIssued(issuer) {
+				break
+			}
+			current = issuer
+		}
+		if fetched {
+			chains, err = leaf.Verify(opts)
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	chain := chains[0]
+	trustSource := TrustSourceSystem
+	if userAnchors[certificateFingerprint(chain[len(chain)-1].Raw)] {
+		trustSource = TrustSourceUser
+	}
+	return chain, trustSource, nil
+}
+
+// cacheIntermediate stores a CA certificate that isn't a root
+func (v *Verifier) cacheIntermediate(cert *x509.Certificate) {
+	if !cert.IsCA || isSelfIssued(cert) {
+		return
+	}
+	if err := v.store.CacheIntermediate(cert); err != nil {
+		v.log.Warn().Err(err).Msg("Failed to cache intermediate certificate")
+	}
+}
+
+// chainStatus classifies a chain building error into a signature status
+func chainStatus(leaf *x509.Certificate, err error) (SignatureStatus, string) {
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) && invalid.Reason == x509.Expired {
+		return StatusExpiredCert, "signer certificate has expired"
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	if errors.As(err, &unknownAuthority) {
+		if isSelfIssued(leaf) {
+			return StatusSelfSigned, "self-signed certificate"
+		}
+		return StatusUnknownSigner, "signer certificate is not issued by a trusted authority"
+	}
+
+	return StatusUnknownSigner, fmt.Sprintf("unverified signer: %v", err)
+}
+
+// describeChain converts a certificate chain into its display form
+func describeChain(chain []*x509.Certificate, anchored bool) []ChainCert {
+	result := make([]ChainCert, len(chain))
+	for i, cert := range chain {
+		result[i] = ChainCert{
+			Subject:       cert.Subject.String(),
+			Issuer:        cert.Issuer.String(),
+			SerialNumber:  cert.SerialNumber.Text(16),
+			Fingerprint:   certificateFingerprint(cert.Raw),
+			NotBefore:     cert.NotBefore,
+			NotAfter:      cert.NotAfter,
+			IsTrustAnchor: anchored && i == len(chain)-1,
+			Revocation:    RevocationNotChecked,
+		}
+	}
+	return result
+}
@@ -0,0 +1,321 @@
+package smime
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mozilla.org/pkcs7"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/hkdb/aerion/internal/database"
+)
+
+// testCert is a generated certificate with its key
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+var testSerial int64
+
+// newTestCert issues a certificate from template, signed by parent (self-signed if nil)
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	testSerial++
+	template.SerialNumber = big.NewInt(testSerial)
+	if template.Subject.CommonName == "" {
+		template.Subject.CommonName = fmt.Sprintf("Test %d", testSerial)
+	}
+
+	signer, signerKey := template, crypto.Signer(key)
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+// caTemplate returns a CA certificate template valid between notBefore and notAfter
+func caTemplate(notBefore, notAfter time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+}
+
+// leafTemplate returns an S/MIME signer certificate template valid between notBefore and notAfter
+func leafTemplate(notBefore, notAfter time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "Alice"},
+		EmailAddresses: []string{"alice@example.com"},
+		NotBefore:      notBefore,
+		NotAfter:       notAfter,
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	}
+}
+
+// fakeFetcher serves OCSP responses and CRLs from memory
+type fakeFetcher struct {
+	responses map[string][]byte
+}
+
+func (f *fakeFetcher) Get(url string) ([]byte, error) {
+	if data, ok := f.responses[url]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("not found: %s", url)
+}
+
+func (f *fakeFetcher) Post(url, contentType string, body []byte) ([]byte, error) {
+	return f.Get(url)
+}
+
+// newTestVerifier creates a verifier with a fresh database that trusts root
+func newTestVerifier(t *testing.T, root *testCert, fetcher Fetcher) *Verifier {
+	t.Helper()
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "aerion.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	store := NewStore(db.DB, zerolog.Nop())
+	if _, err := store.AddTrustAnchor(root.cert); err != nil {
+		t.Fatalf("failed to add trust anchor: %v", err)
+	}
+
+	v := NewVerifier(store, zerolog.Nop())
+	v.SetFetcher(fetcher)
+	return v
+}
+
+func TestBuildChainAtSigningTime(t *testing.T) {
+	now := time.Now()
+	root := newTestCert(t, caTemplate(now.Add(-5*365*24*time.Hour), now.Add(5*365*24*time.Hour)), nil)
+	intermediate := newTestCert(t, caTemplate(now.Add(-4*365*24*time.Hour), now.Add(4*365*24*time.Hour)), root)
+	// Expired a year ago
+	leaf := newTestCert(t, leafTemplate(now.Add(-2*365*24*time.Hour), now.Add(-365*24*time.Hour)), intermediate)
+
+	v := newTestVerifier(t, root, &fakeFetcher{})
+	embedded := []*x509.Certificate{leaf.cert, intermediate.cert}
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		wantErr bool
+	}{
+		{"signed while valid", now.Add(-18 * 30 * 24 * time.Hour), false},
+		{"signed after expiry", now, true},
+		{"signed before validity", now.Add(-3 * 365 * 24 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, source, err := v.buildChain(leaf.cert, embedded, false, tt.at)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected chain verification to fail")
+				}
+				if status, _ := chainStatus(leaf.cert, err); status != StatusExpiredCert {
+					t.Errorf("status = %q, want %q", status, StatusExpiredCert)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildChain: %v", err)
+			}
+			if len(chain) != 3 {
+				t.Errorf("chain length = %d, want 3", len(chain))
+			}
+			if source != TrustSourceUser {
+				t.Errorf("trust source = %q, want %q", source, TrustSourceUser)
+			}
+		})
+	}
+}
+
+func TestVerifyPKCS7(t *testing.T) {
+	now := time.Now()
+	root := newTestCert(t, caTemplate(now.Add(-time.Hour), now.Add(365*24*time.Hour)), nil)
+	intermediate := newTestCert(t, caTemplate(now.Add(-time.Hour), now.Add(365*24*time.Hour)), root)
+	leaf := newTestCert(t, leafTemplate(now.Add(-time.Hour), now.Add(30*24*time.Hour)), intermediate)
+	untrusted := newTestCert(t, caTemplate(now.Add(-time.Hour), now.Add(365*24*time.Hour)), nil)
+	stranger := newTestCert(t, leafTemplate(now.Add(-time.Hour), now.Add(30*24*time.Hour)), untrusted)
+
+	v := newTestVerifier(t, root, &fakeFetcher{})
+	v.SetRevocationPolicy(RevocationPolicyOff)
+
+	sign := func(signer, parent *testCert) *pkcs7.PKCS7 {
+		sd, err := pkcs7.NewSignedData([]byte("Content-Type: text/plain\r\n\r\nHello\r\n"))
+		if err != nil {
+			t.Fatalf("failed to create signed data: %v", err)
+		}
+		if err := sd.AddSignerChain(signer.cert, signer.key, []*x509.Certificate{parent.cert}, pkcs7.SignerInfoConfig{}); err != nil {
+			t.Fatalf("failed to add signer: %v", err)
+		}
+		der, err := sd.Finish()
+		if err != nil {
+			t.Fatalf("failed to finish signed data: %v", err)
+		}
+		p7, err := pkcs7.Parse(der)
+		if err != nil {
+			t.Fatalf("failed to parse signed data: %v", err)
+		}
+		return p7
+	}
+
+	result := v.verifyPKCS7(sign(leaf, intermediate), true)
+	if result.Status != StatusSigned {
+		t.Fatalf("status = %q (%s), want %q", result.Status, result.ErrorMessage, StatusSigned)
+	}
+	if len(result.Chain) != 3 || !result.Chain[2].IsTrustAnchor {
+		t.Errorf("chain = %+v, want leaf, intermediate and trust anchor", result.Chain)
+	}
+	if result.SignerEmail != "alice@example.com" {
+		t.Errorf("signer email = %q", result.SignerEmail)
+	}
+
+	result = v.verifyPKCS7(sign(stranger, untrusted), true)
+	if result.Status != StatusUnknownSigner {
+		t.Errorf("untrusted chain status = %q, want %q", result.Status, StatusUnknownSigner)
+	}
+}
+
+func TestCheckRevocationOCSP(t *testing.T) {
+	now := time.Now()
+	root := newTestCert(t, caTemplate(now.Add(-time.Hour), now.Add(365*24*time.Hour)), nil)
+	issuer := newTestCert(t, caTemplate(now.Add(-time.Hour), now.Add(365*24*time.Hour)), root)
+
+	const responder = "http://ocsp.example.com/"
+	template := leafTemplate(now.Add(-time.Hour), now.Add(30*24*time.Hour))
+	template.OCSPServer = []string{responder}
+	leaf := newTestCert(t, template, issuer)
+
+	respond := func(status int, thisUpdate, nextUpdate time.Time) []byte {
+		der, err := ocsp.CreateResponse(issuer.cert, issuer.cert, ocsp.Response{
+			Status:       status,
+			SerialNumber: leaf.cert.SerialNumber,
+			ThisUpdate:   thisUpdate,
+			NextUpdate:   nextUpdate,
+			RevokedAt:    now.Add(-30 * time.Minute),
+		}, issuer.key)
+		if err != nil {
+			t.Fatalf("failed to create OCSP response: %v", err)
+		}
+		return der
+	}
+
+	tests := []struct {
+		name     string
+		response []byte
+		want     RevocationStatus
+	}{
+		{"good", respond(ocsp.Good, now.Add(-time.Hour), now.Add(24*time.Hour)), RevocationGood},
+		{"revoked", respond(ocsp.Revoked, now.Add(-time.Hour), now.Add(24*time.Hour)), RevocationRevoked},
+		{"past next update", respond(ocsp.Good, now.Add(-48*time.Hour), now.Add(-24*time.Hour)), RevocationUnknown},
+		{"issued in the future", respond(ocsp.Good, now.Add(time.Hour), now.Add(24*time.Hour)), RevocationUnknown},
+		{"old without next update", respond(ocsp.Good, now.Add(-30*24*time.Hour), time.Time{}), RevocationUnknown},
+		{"no response", nil, RevocationUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := &fakeFetcher{responses: map[string][]byte{}}
+			if tt.response != nil {
+				fetcher.responses[responder] = tt.response
+			}
+			v := newTestVerifier(t, root, fetcher)
+
+			result := v.checkRevocation(leaf.cert, issuer.cert)
+			if result.Status != tt.want {
+				t.Fatalf("status = %q, want %q", result.Status, tt.want)
+			}
+			if tt.want == RevocationRevoked && result.RevokedAt == nil {
+				t.Error("revoked result has no revocation time")
+			}
+		})
+	}
+}
+
+func TestCheckRevocationCRL(t *testing.T) {
+	now := time.Now()
+	root := newTestCert(t, caTemplate(now.Add(-time.Hour), now.Add(365*24*time.Hour)), nil)
+	issuer := newTestCert(t, caTemplate(now.Add(-time.Hour), now.Add(365*24*time.Hour)), root)
+	other := newTestCert(t, caTemplate(now.Add(-time.Hour), now.Add(365*24*time.Hour)), root)
+
+	const distributionPoint = "http://crl.example.com/issuer.crl"
+	template := leafTemplate(now.Add(-time.Hour), now.Add(30*24*time.Hour))
+	template.CRLDistributionPoints = []string{distributionPoint}
+	leaf := newTestCert(t, template, issuer)
+
+	crl := func(signer *testCert, revoked bool, thisUpdate, nextUpdate time.Time) []byte {
+		list := &x509.RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: thisUpdate,
+			NextUpdate: nextUpdate,
+		}
+		if revoked {
+			list.RevokedCertificateEntries = []x509.RevocationListEntry{
+				{SerialNumber: leaf.cert.SerialNumber, RevocationTime: now.Add(-30 * time.Minute)},
+			}
+		}
+		der, err := x509.CreateRevocationList(rand.Reader, list, signer.cert, signer.key)
+		if err != nil {
+			t.Fatalf("failed to create CRL: %v", err)
+		}
+		return der
+	}
+
+	tests := []struct {
+		name string
+		crl  []byte
+		want RevocationStatus
+	}{
+		{"not listed", crl(issuer, false, now.Add(-time.Hour), now.Add(24*time.Hour)), RevocationGood},
+		{"listed", crl(issuer, true, now.Add(-time.Hour), now.Add(24*time.Hour)), RevocationRevoked},
+		{"past next update", crl(issuer, true, now.Add(-48*time.Hour), now.Add(-24*time.Hour)), RevocationUnknown},
+		{"signed by another CA", crl(other, true, now.Add(-time.Hour), now.Add(24*time.Hour)), RevocationUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVerifier(t, root, &fakeFetcher{responses: map[string][]byte{distributionPoint: tt.crl}})
+
+			result := v.checkRevocation(leaf.cert, issuer.cert)
+			if result.Status != tt.want {
+				t.Fatalf("status = %q, want %q", result.Status, tt.want)
+			}
+			if tt.want != RevocationUnknown && result.Source != "crl" {
+				t.Errorf("source = %q, want crl", result.Source)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 // Package smime provides S/MIME signing and verification for email messages
 package smime
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // SignatureStatus represents the S/MIME verification result
 type SignatureStatus string
@@ -13,6 +16,44 @@ const (
 	StatusUnknownSigner SignatureStatus = "unknown_signer" // Valid sig, untrusted CA
 	StatusSelfSigned    SignatureStatus = "self_signed"   // Valid sig, self-signed cert
 	StatusExpiredCert   SignatureStatus = "expired_cert"  // Valid sig, expired cert
+	StatusRevoked       SignatureStatus = "revoked"       // Valid sig, certificate in chain revoked
+)
+
+// RevocationStatus represents the revocation state of a certificate
+type RevocationStatus string
+
+const (
+	RevocationNotChecked RevocationStatus = "not_checked" // Revocation checking disabled or skipped
+	RevocationGood       RevocationStatus = "good"        // OCSP/CRL confirms not revoked
+	RevocationRevoked    RevocationStatus = "revoked"     // OCSP/CRL reports the certificate revoked
+	RevocationUnknown    RevocationStatus = "unknown"     // Responder unreachable or no revocation info
+)
+
+// RevocationPolicy controls how revocation checking affects the signature status
+type RevocationPolicy string
+
+const (
+	RevocationPolicyOff      RevocationPolicy = "off"       // Don't check revocation
+	RevocationPolicySoftFail RevocationPolicy = "soft_fail" // Unknown status still verifies (default)
+	RevocationPolicyHardFail RevocationPolicy = "hard_fail" // Unknown status is treated as untrusted
+)
+
+// ParseRevocationPolicy returns the policy named by value. An empty value is
+// the default, RevocationPolicySoftFail.
+func ParseRevocationPolicy(value string) (RevocationPolicy, error) {
+	switch policy := RevocationPolicy(value); policy {
+	case "":
+		return RevocationPolicySoftFail, nil
+	case RevocationPolicyOff, RevocationPolicySoftFail, RevocationPolicyHardFail:
+		return policy, nil
+	}
+	return RevocationPolicySoftFail, fmt.Errorf("invalid revocation policy: %s (must be 'off', 'soft_fail', or 'hard_fail')", value)
+}
+
+// Trust sources for a verified chain
+const (
+	TrustSourceSystem = "system" // Chain ends at a system root
+	TrustSourceUser   = "user"   // Chain ends at a user-imported trust anchor
 )
 
 // Certificate represents a user's imported S/MIME certificate
@@ -46,12 +87,45 @@ type SenderCert struct {
 	LastSeenAt   time.Time `json:"lastSeenAt"`
 }
 
+// TrustAnchor represents a user-imported root certificate trusted for S/MIME
+type TrustAnchor struct {
+	ID          string    `json:"id"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	Fingerprint string    `json:"fingerprint"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ChainCert describes one certificate in a verified signer chain (leaf first)
+type ChainCert struct {
+	Subject          string           `json:"subject"`
+	Issuer           string           `json:"issuer"`
+	SerialNumber     string           `json:"serialNumber"`
+	Fingerprint      string           `json:"fingerprint"`
+	NotBefore        time.Time        `json:"notBefore"`
+	NotAfter         time.Time        `json:"notAfter"`
+	IsTrustAnchor    bool             `json:"isTrustAnchor"`
+	Revocation       RevocationStatus `json:"revocation,omitempty"`
+	RevocationSource string           `json:"revocationSource,omitempty"` // "ocsp" or "crl"
+}
+
 // SignatureResult holds the verification result for a message
 type SignatureResult struct {
 	Status       SignatureStatus `json:"status"`
 	SignerEmail  string          `json:"signerEmail"`
 	SignerName   string          `json:"signerName"`
 	ErrorMessage string          `json:"errorMessage,omitempty"`
+
+	// Chain validation details (populated when a chain could be built)
+	Chain       []ChainCert `json:"chain,omitempty"`
+	TrustSource string      `json:"trustSource,omitempty"` // "system" or "user"
+
+	// Revocation details (worst status across the chain)
+	RevocationStatus RevocationStatus `json:"revocationStatus,omitempty"`
+	RevocationSource string           `json:"revocationSource,omitempty"`
+	RevokedAt        *time.Time       `json:"revokedAt,omitempty"`
 }
 
 // ImportResult holds the result of a PKCS#12 certificate import
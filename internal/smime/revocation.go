@@ -0,0 +1,302 @@
+package smime
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/hkdb/aerion/internal/netproxy"
+)
+
+const (
+	// fetchTimeout bounds each OCSP/CRL/AIA request so a slow responder
+	// doesn't hold up message display
+	fetchTimeout = 10 * time.Second
+
+	// maxFetchSize caps the size of fetched CRLs and certificates
+	maxFetchSize = 20 << 20
+
+	// defaultRevocationTTL is how long a result is cached when the responder
+	// doesn't say when it will next update
+	defaultRevocationTTL = 24 * time.Hour
+
+	// unknownRevocationTTL is how long an "unknown" result is cached so an
+	// unreachable responder isn't retried on every message view
+	unknownRevocationTTL = time.Hour
+
+	// maxClockSkew tolerates responders whose clock runs slightly ahead
+	maxClockSkew = 5 * time.Minute
+
+	// maxResponseAge is how old an OCSP response or CRL without a next
+	// update time may be
+	maxResponseAge = 7 * 24 * time.Hour
+)
+
+// Fetcher retrieves OCSP responses, CRLs and issuer certificates over the
+// network. It is injectable so verification can run against local fixtures.
+type Fetcher interface {
+	Get(url string) ([]byte, error)
+	Post(url, contentType string, body []byte) ([]byte, error)
+}
+
+// HTTPFetcher is the default Fetcher using net/http
+type HTTPFetcher struct {
+	client *http.Client
+}
+
+// NewHTTPFetcher creates a Fetcher with a request timeout
+func NewHTTPFetcher() *HTTPFetcher {
+	return &HTTPFetcher{
+		client: netproxy.HTTPClient(fetchTimeout),
+	}
+}
+
+// Get performs an HTTP GET and returns the response body
+func (f *HTTPFetcher) Get(url string) ([]byte, error) {
+	resp, err := f.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	return readFetchResponse(resp)
+}
+
+// Post performs an HTTP POST and returns the response body
+func (f *HTTPFetcher) Post(url, contentType string, body []byte) ([]byte, error) {
+	resp, err := f.client.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return readFetchResponse(resp)
+}
+
+// readFetchResponse reads a size-limited response body, failing on non-2xx
+func readFetchResponse(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected HTTP status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFetchSize {
+		return nil, fmt.Errorf("response exceeds %d bytes", maxFetchSize)
+	}
+	return data, nil
+}
+
+// revocationResult is the revocation state of a single certificate
+type revocationResult struct {
+	Status    RevocationStatus
+	Source    string // "ocsp" or "crl"
+	RevokedAt *time.Time
+}
+
+// checkRevocation determines whether cert (issued by issuer) is revoked.
+// OCSP is tried first, then CRL distribution points. Results are cached in
+// the store until the responder's next update time.
+func (v *Verifier) checkRevocation(cert, issuer *x509.Certificate) revocationResult {
+	fingerprint := certificateFingerprint(cert.Raw)
+
+	if cached, err := v.store.getCachedRevocation(fingerprint); err != nil {
+		v.log.Warn().Err(err).Msg("Failed to read revocation cache")
+	} else if cached != nil {
+		return revocationResult{Status: cached.Status, Source: cached.Source, RevokedAt: cached.RevokedAt}
+	}
+
+	result, nextUpdate := v.checkOCSP(cert, issuer)
+	if result.Status == RevocationUnknown {
+		result, nextUpdate = v.checkCRL(cert, issuer)
+	}
+
+	if nextUpdate.IsZero() {
+		ttl := defaultRevocationTTL
+		if result.Status == RevocationUnknown {
+			ttl = unknownRevocationTTL
+		}
+		nextUpdate = time.Now().Add(ttl)
+	}
+
+	if err := v.store.saveCachedRevocation(fingerprint, &revocationCacheEntry{
+		Status:     result.Status,
+		Source:     result.Source,
+		RevokedAt:  result.RevokedAt,
+		NextUpdate: nextUpdate,
+	}); err != nil {
+		v.log.Warn().Err(err).Msg("Failed to cache revocation result")
+	}
+
+	return result
+}
+
+// checkOCSP queries the certificate's OCSP responders
+func (v *Verifier) checkOCSP(cert, issuer *x509.Certificate) (revocationResult, time.Time) {
+	unknown := revocationResult{Status: RevocationUnknown}
+	if len(cert.OCSPServer) == 0 {
+		return unknown, time.Time{}
+	}
+
+	req, err := ocsp.CreateRequest(cert, issuer, &ocsp.RequestOptions{Hash: crypto.SHA1})
+	if err != nil {
+		v.log.Debug().Err(err).Msg("Failed to create OCSP request")
+		return unknown, time.Time{}
+	}
+
+	for _, server := range cert.OCSPServer {
+		if !isHTTPURL(server) {
+			continue
+		}
+		body, err := v.fetcher.Post(server, "application/ocsp-request", req)
+		if err != nil {
+			v.log.Debug().Err(err).Str("server", server).Msg("OCSP request failed")
+			continue
+		}
+		resp, err := ocsp.ParseResponseForCert(body, cert, issuer)
+		if err != nil {
+			v.log.Debug().Err(err).Str("server", server).Msg("Invalid OCSP response")
+			continue
+		}
+		if err := checkFreshness(resp.ThisUpdate, resp.NextUpdate, time.Now()); err != nil {
+			v.log.Debug().Err(err).Str("server", server).Msg("Stale OCSP response")
+			continue
+		}
+
+		switch resp.Status {
+		case ocsp.Good:
+			return revocationResult{Status: RevocationGood, Source: "ocsp"}, resp.NextUpdate
+		case ocsp.Revoked:
+			revokedAt := resp.RevokedAt
+			return revocationResult{Status: RevocationRevoked, Source: "ocsp", RevokedAt: &revokedAt}, resp.NextUpdate
+		}
+		// ocsp.Unknown: the responder doesn't know this cert, try the next one
+	}
+
+	return unknown, time.Time{}
+}
+
+// checkCRL looks the certificate up in its CRL distribution points
+func (v *Verifier) checkCRL(cert, issuer *x509.Certificate) (revocationResult, time.Time) {
+	unknown := revocationResult{Status: RevocationUnknown}
+
+	for _, url := range cert.CRLDistributionPoints {
+		if !isHTTPURL(url) {
+			continue
+		}
+		crl, err := v.loadCRL(url, issuer)
+		if err != nil {
+			v.log.Debug().Err(err).Str("url", url).Msg("Failed to load CRL")
+			continue
+		}
+
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				revokedAt := entry.RevocationTime
+				return revocationResult{Status: RevocationRevoked, Source: "crl", RevokedAt: &revokedAt}, crl.NextUpdate
+			}
+		}
+		return revocationResult{Status: RevocationGood, Source: "crl"}, crl.NextUpdate
+	}
+
+	return unknown, time.Time{}
+}
+
+// loadCRL returns the CRL at url, from the cache when still current, and
+// verifies it was signed by issuer
+func (v *Verifier) loadCRL(url string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	der, err := v.store.getCachedCRL(url)
+	if err != nil {
+		v.log.Warn().Err(err).Msg("Failed to read CRL cache")
+	}
+
+	fetched := false
+	if der == nil {
+		data, err := v.fetcher.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		der = data
+		if block, _ := pem.Decode(data); block != nil {
+			der = block.Bytes
+		}
+		fetched = true
+	}
+
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL: %w", err)
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("CRL signature check failed: %w", err)
+	}
+	if err := checkFreshness(crl.ThisUpdate, crl.NextUpdate, time.Now()); err != nil {
+		return nil, fmt.Errorf("stale CRL: %w", err)
+	}
+
+	if fetched {
+		if err := v.store.saveCachedCRL(url, crl); err != nil {
+			v.log.Warn().Err(err).Msg("Failed to cache CRL")
+		}
+	}
+	return crl, nil
+}
+
+// checkFreshness rejects an OCSP response or CRL that isn't valid yet or
+// is past its next update, so an old "good" answer replayed by a responder
+// or cache can't hide a revocation
+func checkFreshness(thisUpdate, nextUpdate, now time.Time) error {
+	if thisUpdate.After(now.Add(maxClockSkew)) {
+		return fmt.Errorf("issued in the future (%s)", thisUpdate.Format(time.RFC3339))
+	}
+	if nextUpdate.IsZero() {
+		if now.Sub(thisUpdate) > maxResponseAge {
+			return fmt.Errorf("issued %s and has no next update", thisUpdate.Format(time.RFC3339))
+		}
+		return nil
+	}
+	if now.After(nextUpdate.Add(maxClockSkew)) {
+		return fmt.Errorf("expired %s", nextUpdate.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// fetchIssuer downloads the issuer certificate from the AIA "CA Issuers" URLs.
+// Responses may be a DER or PEM certificate.
+func (v *Verifier) fetchIssuer(cert *x509.Certificate) *x509.Certificate {
+	for _, url := range cert.IssuingCertificateURL {
+		if !isHTTPURL(url) {
+			continue
+		}
+		data, err := v.fetcher.Get(url)
+		if err != nil {
+			v.log.Debug().Err(err).Str("url", url).Msg("Failed to fetch issuer certificate")
+			continue
+		}
+		if block, _ := pem.Decode(data); block != nil {
+			data = block.Bytes
+		}
+		issuer, err := parseCertificateFromDER(data)
+		if err != nil {
+			v.log.Debug().Err(err).Str("url", url).Msg("Failed to parse issuer certificate")
+			continue
+		}
+		if cert.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+		return issuer
+	}
+	return nil
+}
+
+// isHTTPURL reports whether a URL uses http or https (LDAP is not supported)
+func isHTTPURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
@@ -0,0 +1,224 @@
+package smime
+
+import (
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AddTrustAnchor stores a user-imported root certificate. Importing the same
+// certificate twice returns the existing anchor.
+func (s *Store) AddTrustAnchor(cert *x509.Certificate) (*TrustAnchor, error) {
+	if !cert.IsCA && !isSelfIssued(cert) {
+		return nil, fmt.Errorf("certificate %q is not a CA certificate", cert.Subject.String())
+	}
+
+	fingerprint := certificateFingerprint(cert.Raw)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+
+	anchor := &TrustAnchor{
+		ID:          uuid.New().String(),
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		Fingerprint: fingerprint,
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		CreatedAt:   time.Now(),
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO smime_trust_anchors (id, fingerprint, subject, issuer, not_before, not_after, cert_pem, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(fingerprint) DO NOTHING`,
+		anchor.ID, anchor.Fingerprint, anchor.Subject, anchor.Issuer,
+		anchor.NotBefore, anchor.NotAfter, string(certPEM), anchor.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save trust anchor: %w", err)
+	}
+
+	// Return the stored row (the existing one if this was a duplicate)
+	err = s.db.QueryRow(`
+		SELECT id, created_at FROM smime_trust_anchors WHERE fingerprint = ?`, fingerprint,
+	).Scan(&anchor.ID, &anchor.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trust anchor: %w", err)
+	}
+	return anchor, nil
+}
+
+// ListTrustAnchors returns all user-imported trust anchors
+func (s *Store) ListTrustAnchors() ([]*TrustAnchor, error) {
+	rows, err := s.db.Query(`
+		SELECT id, fingerprint, subject, issuer, not_before, not_after, created_at
+		FROM smime_trust_anchors ORDER BY subject`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var anchors []*TrustAnchor
+	for rows.Next() {
+		a := &TrustAnchor{}
+		if err := rows.Scan(&a.ID, &a.Fingerprint, &a.Subject, &a.Issuer,
+			&a.NotBefore, &a.NotAfter, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		anchors = append(anchors, a)
+	}
+	return anchors, rows.Err()
+}
+
+// DeleteTrustAnchor removes a user-imported trust anchor
+func (s *Store) DeleteTrustAnchor(id string) error {
+	_, err := s.db.Exec("DELETE FROM smime_trust_anchors WHERE id = ?", id)
+	return err
+}
+
+// TrustAnchorCerts returns the parsed user-imported trust anchor certificates
+func (s *Store) TrustAnchorCerts() ([]*x509.Certificate, error) {
+	return s.loadCerts("SELECT cert_pem FROM smime_trust_anchors")
+}
+
+// CacheIntermediate stores an intermediate CA certificate so chains can be
+// built for later messages that omit it
+func (s *Store) CacheIntermediate(cert *x509.Certificate) error {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	_, err := s.db.Exec(`
+		INSERT INTO smime_intermediate_certs (fingerprint, subject, not_after, cert_pem, collected_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(fingerprint) DO NOTHING`,
+		certificateFingerprint(cert.Raw), cert.Subject.String(), cert.NotAfter,
+		string(certPEM), time.Now(),
+	)
+	return err
+}
+
+// IntermediateCerts returns the cached intermediate CA certificates that
+// haven't expired
+func (s *Store) IntermediateCerts() ([]*x509.Certificate, error) {
+	return s.loadCerts("SELECT cert_pem FROM smime_intermediate_certs WHERE not_after > ?", time.Now())
+}
+
+// loadCerts parses the PEM certificates returned by a single-column query
+func (s *Store) loadCerts(query string, args ...interface{}) ([]*x509.Certificate, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var certs []*x509.Certificate
+	for rows.Next() {
+		var certPEM string
+		if err := rows.Scan(&certPEM); err != nil {
+			return nil, err
+		}
+		cert, err := parseCertificateFromPEM(certPEM)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("Skipping unparseable stored certificate")
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	return certs, rows.Err()
+}
+
+// revocationCacheEntry is a cached revocation result for one certificate
+type revocationCacheEntry struct {
+	Status     RevocationStatus
+	Source     string
+	RevokedAt  *time.Time
+	NextUpdate time.Time
+}
+
+// getCachedRevocation returns the cached revocation result for a certificate
+// fingerprint, or nil if there is none or it is stale
+func (s *Store) getCachedRevocation(fingerprint string) (*revocationCacheEntry, error) {
+	entry := &revocationCacheEntry{}
+	var source sql.NullString
+	var revokedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT status, source, revoked_at, next_update
+		FROM smime_revocation_cache WHERE fingerprint = ?`, fingerprint,
+	).Scan(&entry.Status, &source, &revokedAt, &entry.NextUpdate)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(entry.NextUpdate) {
+		return nil, nil
+	}
+	entry.Source = source.String
+	if revokedAt.Valid {
+		entry.RevokedAt = &revokedAt.Time
+	}
+	return entry, nil
+}
+
+// saveCachedRevocation stores a revocation result for a certificate fingerprint
+func (s *Store) saveCachedRevocation(fingerprint string, entry *revocationCacheEntry) error {
+	_, err := s.db.Exec(`
+		INSERT INTO smime_revocation_cache (fingerprint, status, source, revoked_at, checked_at, next_update)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(fingerprint) DO UPDATE SET
+			status = excluded.status,
+			source = excluded.source,
+			revoked_at = excluded.revoked_at,
+			checked_at = excluded.checked_at,
+			next_update = excluded.next_update`,
+		fingerprint, string(entry.Status), entry.Source, entry.RevokedAt, time.Now(), entry.NextUpdate,
+	)
+	return err
+}
+
+// getCachedCRL returns the cached DER-encoded CRL for a distribution point,
+// or nil if there is none or it has passed its next update time
+func (s *Store) getCachedCRL(url string) ([]byte, error) {
+	var der []byte
+	var nextUpdate sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT crl_der, next_update FROM smime_crl_cache WHERE url = ?`, url,
+	).Scan(&der, &nextUpdate)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !nextUpdate.Valid || time.Now().After(nextUpdate.Time) {
+		return nil, nil
+	}
+	return der, nil
+}
+
+// saveCachedCRL stores a DER-encoded CRL for a distribution point
+func (s *Store) saveCachedCRL(url string, crl *x509.RevocationList) error {
+	_, err := s.db.Exec(`
+		INSERT INTO smime_crl_cache (url, crl_der, this_update, next_update, fetched_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET
+			crl_der = excluded.crl_der,
+			this_update = excluded.this_update,
+			next_update = excluded.next_update,
+			fetched_at = excluded.fetched_at`,
+		url, crl.Raw, crl.ThisUpdate, crl.NextUpdate, time.Now(),
+	)
+	return err
+}
+
+// ClearRevocationCache forgets all cached OCSP/CRL results
+func (s *Store) ClearRevocationCache() error {
+	for _, table := range []string{"smime_revocation_cache", "smime_crl_cache"} {
+		if _, err := s.db.Exec("DELETE FROM " + table); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -10,7 +10,7 @@ import (
 	"mime"
 	"mime/multipart"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/rs/zerolog"
 	"go.mozilla.org/pkcs7"
@@ -18,23 +18,62 @@ import (
 
 // Verifier handles S/MIME signature verification
 type Verifier struct {
-	store *Store
-	log   zerolog.Logger
+	store   *Store
+	log     zerolog.Logger
+	fetcher Fetcher
+
+	mu     sync.RWMutex
+	policy RevocationPolicy
 }
 
 // NewVerifier creates a new S/MIME verifier
 func NewVerifier(store *Store, log zerolog.Logger) *Verifier {
 	return &Verifier{
-		store: store,
-		log:   log,
+		store:   store,
+		log:     log,
+		fetcher: NewHTTPFetcher(),
+		policy:  RevocationPolicySoftFail,
 	}
 }
 
-// VerifyAndUnwrap detects S/MIME signed content, verifies the signature,
-// caches the sender cert, and returns the verification result plus the
-// unwrapped inner body (if any). If the message is not S/MIME signed or
-// verification encounters a fatal parse error, it returns (nil, nil).
+// SetFetcher replaces the network fetcher used for OCSP, CRL and AIA requests
+func (v *Verifier) SetFetcher(fetcher Fetcher) {
+	v.fetcher = fetcher
+}
+
+// SetRevocationPolicy sets how revocation checking affects verification
+func (v *Verifier) SetRevocationPolicy(policy RevocationPolicy) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.policy = policy
+}
+
+// RevocationPolicy returns the current revocation policy
+func (v *Verifier) RevocationPolicy() RevocationPolicy {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.policy
+}
+
+// VerifyAndUnwrap detects S/MIME signed content, verifies the signature and
+// certificate chain, checks revocation, caches the sender cert, and returns
+// the verification result plus the unwrapped inner body (if any). If the
+// message is not S/MIME signed or verification encounters a fatal parse
+// error, it returns (nil, nil).
 func (v *Verifier) VerifyAndUnwrap(raw []byte) (*SignatureResult, []byte) {
+	return v.verifyAndUnwrap(raw, true)
+}
+
+// Unwrap is like VerifyAndUnwrap but makes no network requests (no AIA,
+// OCSP or CRL fetches). Used during sync where only the inner body is needed.
+func (v *Verifier) Unwrap(raw []byte) []byte {
+	_, inner := v.verifyAndUnwrap(raw, false)
+	return inner
+}
+
+// verifyAndUnwrap implements VerifyAndUnwrap; online controls whether
+// network requests may be made
+func (v *Verifier) verifyAndUnwrap(raw []byte, online bool) (*SignatureResult, []byte) {
 	// Parse the message to find Content-Type
 	headerEnd := bytes.Index(raw, []byte("\r\n\r\n"))
 	if headerEnd == -1 {
@@ -62,7 +101,7 @@ func (v *Verifier) VerifyAndUnwrap(raw []byte) (*SignatureResult, []byte) {
 			!strings.EqualFold(protocol, "application/x-pkcs7-signature") {
 			return nil, nil
 		}
-		return v.verifyMultipartSigned(raw, params)
+		return v.verifyMultipartSigned(raw, params, online)
 	}
 
 	// Handle application/pkcs7-mime (opaque signed)
@@ -70,7 +109,7 @@ func (v *Verifier) VerifyAndUnwrap(raw []byte) (*SignatureResult, []byte) {
 		strings.EqualFold(mediaType, "application/x-pkcs7-mime") {
 		smimeType := params["smime-type"]
 		if strings.EqualFold(smimeType, "signed-data") {
-			return v.verifyOpaqueSigned(raw)
+			return v.verifyOpaqueSigned(raw, online)
 		}
 	}
 
@@ -78,7 +117,7 @@ func (v *Verifier) VerifyAndUnwrap(raw []byte) (*SignatureResult, []byte) {
 }
 
 // verifyMultipartSigned handles clear-signed messages (multipart/signed)
-func (v *Verifier) verifyMultipartSigned(raw []byte, params map[string]string) (*SignatureResult, []byte) {
+func (v *Verifier) verifyMultipartSigned(raw []byte, params map[string]string, online bool) (*SignatureResult, []byte) {
 	boundary := params["boundary"]
 	if boundary == "" {
 		return &SignatureResult{
@@ -206,13 +245,13 @@ func (v *Verifier) verifyMultipartSigned(raw []byte, params map[string]string) (
 	p7.Content = signedContent
 
 	// Verify the signature
-	result := v.verifyPKCS7(p7)
+	result := v.verifyPKCS7(p7, online)
 
 	return result, signedContent
 }
 
 // verifyOpaqueSigned handles opaque signed messages (application/pkcs7-mime)
-func (v *Verifier) verifyOpaqueSigned(raw []byte) (*SignatureResult, []byte) {
+func (v *Verifier) verifyOpaqueSigned(raw []byte, online bool) (*SignatureResult, []byte) {
 	// Find body after headers
 	headerEnd := bytes.Index(raw, []byte("\r\n\r\n"))
 	bodyStart := headerEnd + 4
@@ -254,82 +293,118 @@ func (v *Verifier) verifyOpaqueSigned(raw []byte) (*SignatureResult, []byte) {
 		}
 	}
 
-	result := v.verifyPKCS7(p7)
+	result := v.verifyPKCS7(p7, online)
 
 	// For opaque signed, the inner content is embedded in p7.Content
 	return result, p7.Content
 }
 
-// verifyPKCS7 verifies a parsed PKCS#7 object and caches the signer cert
-func (v *Verifier) verifyPKCS7(p7 *pkcs7.PKCS7) *SignatureResult {
-	// Try verification against system trust roots
+// verifyPKCS7 verifies a parsed PKCS#7 object, validates the signer's
+// certificate chain and revocation status, and caches the signer cert
+func (v *Verifier) verifyPKCS7(p7 *pkcs7.PKCS7, online bool) *SignatureResult {
+	// Signature integrity only: Verify() does no chain validation
 	err := p7.Verify()
 
 	// Extract signer information regardless of verification result
 	signerEmail, signerName := v.extractSignerInfo(p7)
+	result := &SignatureResult{
+		SignerEmail: signerEmail,
+		SignerName:  signerName,
+	}
+
+	leaf := signerCertificate(p7)
+	if leaf == nil {
+		result.Status = StatusInvalid
+		result.ErrorMessage = "no signer certificate in signature"
+		return result
+	}
 
 	if err != nil {
-		// Distinguish between "untrusted CA" and "truly invalid signature"
-		// If we can verify without trust check, the signature is valid but signer is unknown
-		errStr := err.Error()
-
-		// Common certificate verification errors indicate untrusted/expired certs
-		if strings.Contains(errStr, "certificate signed by unknown authority") ||
-			strings.Contains(errStr, "x509: certificate") {
-			// Try to determine if cert is expired
-			if v.isSignerCertExpired(p7) {
-				v.cacheSenderCert(p7, signerEmail)
-				return &SignatureResult{
-					Status:       StatusExpiredCert,
-					SignerEmail:  signerEmail,
-					SignerName:   signerName,
-					ErrorMessage: "signer certificate has expired",
-				}
-			}
-			// Check if the leaf cert is self-signed (Issuer == Subject)
+		// Verify() also rejects a signing time outside the cert's validity
+		if t, ok := signingTime(p7); ok && (t.Before(leaf.NotBefore) || t.After(leaf.NotAfter)) {
 			v.cacheSenderCert(p7, signerEmail)
-			if v.isSignerCertSelfSigned(p7) {
-				return &SignatureResult{
-					Status:       StatusSelfSigned,
-					SignerEmail:  signerEmail,
-					SignerName:   signerName,
-					ErrorMessage: "self-signed certificate",
-				}
+			result.Status = StatusExpiredCert
+			result.ErrorMessage = "signer certificate was not valid at signing time"
+			return result
+		}
+		result.Status = StatusInvalid
+		result.ErrorMessage = fmt.Sprintf("signature verification failed: %v", err)
+		return result
+	}
+
+	v.cacheSenderCert(p7, signerEmail)
+
+	chain, trustSource, err := v.buildChain(leaf, p7.Certificates, online, verificationTime(p7))
+	if err != nil {
+		result.Status, result.ErrorMessage = chainStatus(leaf, err)
+		result.Chain = describeChain([]*x509.Certificate{leaf}, false)
+		result.RevocationStatus = RevocationNotChecked
+		return result
+	}
+
+	result.Status = StatusSigned
+	result.Chain = describeChain(chain, true)
+	result.TrustSource = trustSource
+	v.applyRevocation(result, chain, online)
+	return result
+}
+
+// applyRevocation checks every non-root certificate in the chain and folds
+// the worst outcome into the result according to the revocation policy
+func (v *Verifier) applyRevocation(result *SignatureResult, chain []*x509.Certificate, online bool) {
+	policy := v.RevocationPolicy()
+	result.RevocationStatus = RevocationNotChecked
+	if policy == RevocationPolicyOff || !online || len(chain) < 2 {
+		return
+	}
+
+	result.RevocationStatus = RevocationGood
+	for i := 0; i < len(chain)-1; i++ {
+		r := v.checkRevocation(chain[i], chain[i+1])
+		result.Chain[i].Revocation = r.Status
+		result.Chain[i].RevocationSource = r.Source
+
+		switch r.Status {
+		case RevocationRevoked:
+			if result.RevocationStatus != RevocationRevoked {
+				result.RevocationStatus = RevocationRevoked
+				result.RevocationSource = r.Source
+				result.RevokedAt = r.RevokedAt
 			}
-			return &SignatureResult{
-				Status:       StatusUnknownSigner,
-				SignerEmail:  signerEmail,
-				SignerName:   signerName,
-				ErrorMessage: fmt.Sprintf("unverified signer: %v", err),
+		case RevocationUnknown:
+			if result.RevocationStatus == RevocationGood {
+				result.RevocationStatus = RevocationUnknown
+				result.RevocationSource = ""
+			}
+		case RevocationGood:
+			if result.RevocationStatus == RevocationGood && result.RevocationSource == "" {
+				result.RevocationSource = r.Source
 			}
 		}
+	}
 
-		// Truly invalid signature
-		return &SignatureResult{
-			Status:       StatusInvalid,
-			SignerEmail:  signerEmail,
-			SignerName:   signerName,
-			ErrorMessage: fmt.Sprintf("signature verification failed: %v", err),
+	switch {
+	case result.RevocationStatus == RevocationRevoked:
+		result.Status = StatusRevoked
+		if i := revokedIndex(result.Chain); i == 0 {
+			result.ErrorMessage = "signer certificate has been revoked"
+		} else {
+			result.ErrorMessage = "an issuing certificate in the chain has been revoked"
 		}
+	case result.RevocationStatus == RevocationUnknown && policy == RevocationPolicyHardFail:
+		result.Status = StatusUnknownSigner
+		result.ErrorMessage = "revocation status could not be determined"
 	}
+}
 
-	// Signature verified successfully — but pkcs7.Verify() trusts certs
-	// embedded in the PKCS7 structure, so a self-signed cert will pass.
-	// Check for self-signed before reporting as fully trusted.
-	v.cacheSenderCert(p7, signerEmail)
-	if v.isSignerCertSelfSigned(p7) {
-		return &SignatureResult{
-			Status:       StatusSelfSigned,
-			SignerEmail:  signerEmail,
-			SignerName:   signerName,
-			ErrorMessage: "self-signed certificate",
+// revokedIndex returns the position of the first revoked certificate in a chain
+func revokedIndex(chain []ChainCert) int {
+	for i, c := range chain {
+		if c.Revocation == RevocationRevoked {
+			return i
 		}
 	}
-	return &SignatureResult{
-		Status:      StatusSigned,
-		SignerEmail: signerEmail,
-		SignerName:  signerName,
-	}
+	return -1
 }
 
 // extractSignerInfo gets the email and common name from the first signer certificate
@@ -349,31 +424,6 @@ func (v *Verifier) extractSignerInfo(p7 *pkcs7.PKCS7) (email, name string) {
 	return "", p7.Certificates[0].Subject.CommonName
 }
 
-// isSignerCertExpired checks if any signer certificate in the PKCS#7 is expired
-func (v *Verifier) isSignerCertExpired(p7 *pkcs7.PKCS7) bool {
-	now := time.Now()
-	for _, cert := range p7.Certificates {
-		if !cert.IsCA && now.After(cert.NotAfter) {
-			return true
-		}
-	}
-	return false
-}
-
-// isSignerCertSelfSigned checks if the leaf signer certificate is self-signed
-func (v *Verifier) isSignerCertSelfSigned(p7 *pkcs7.PKCS7) bool {
-	for _, cert := range p7.Certificates {
-		if !cert.IsCA {
-			return bytes.Equal(cert.RawIssuer, cert.RawSubject)
-		}
-	}
-	if len(p7.Certificates) > 0 {
-		c := p7.Certificates[0]
-		return bytes.Equal(c.RawIssuer, c.RawSubject)
-	}
-	return false
-}
-
 // cacheSenderCert stores the signer's leaf certificate for future reference
 func (v *Verifier) cacheSenderCert(p7 *pkcs7.PKCS7, email string) {
 	if email == "" || len(p7.Certificates) == 0 {
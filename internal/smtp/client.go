@@ -3,15 +3,20 @@ package smtp
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/smtp"
+	"net/textproto"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/netproxy"
 	"github.com/rs/zerolog"
 )
 
@@ -43,6 +48,9 @@ type ClientConfig struct {
 
 	// TLS config (optional)
 	TLSConfig *tls.Config
+
+	// Proxy (optional, nil uses the global proxy)
+	Proxy *netproxy.Config
 }
 
 // DefaultConfig returns a ClientConfig with sensible defaults
@@ -60,6 +68,7 @@ func DefaultConfig() ClientConfig {
 type Client struct {
 	config ClientConfig
 	client *smtp.Client
+	conn   net.Conn
 	log    zerolog.Logger
 }
 
@@ -73,7 +82,7 @@ func NewClient(config ClientConfig) *Client {
 
 // Connect establishes a connection to the SMTP server
 func (c *Client) Connect() error {
-	addr := fmt.Sprintf("%s:%d", c.config.Host, c.config.Port)
+	addr := net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port))
 
 	c.log.Debug().
 		Str("host", c.config.Host).
@@ -92,25 +101,27 @@ func (c *Client) Connect() error {
 		}
 	}
 
+	// Dial through the configured proxy (or directly) with connect timeout
+	ctx := context.Background()
+
 	switch c.config.Security {
 	case SecurityTLS:
 		// Connect with TLS directly (port 465)
-		dialer := &net.Dialer{Timeout: c.config.ConnectTimeout}
-		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+		conn, err = netproxy.DialTLS(ctx, c.config.Proxy, c.config.ConnectTimeout, addr, tlsConfig)
 		if err != nil {
 			return fmt.Errorf("failed to connect with TLS: %w", err)
 		}
 
 	case SecurityStartTLS, SecurityNone:
 		// Connect plain first
-		dialer := &net.Dialer{Timeout: c.config.ConnectTimeout}
-		conn, err = dialer.Dial("tcp", addr)
+		conn, err = netproxy.Dial(ctx, c.config.Proxy, c.config.ConnectTimeout, addr)
 		if err != nil {
 			return fmt.Errorf("failed to connect: %w", err)
 		}
 	}
 
 	// Create SMTP client
+	c.conn = conn
 	c.client, err = smtp.NewClient(conn, c.config.Host)
 	if err != nil {
 		conn.Close()
@@ -230,29 +241,187 @@ func (c *Client) Close() error {
 
 // SendMail sends an email message
 func (c *Client) SendMail(from string, to []string, msg []byte) error {
+	return c.SendMailDSN(from, to, msg, nil)
+}
+
+// SendMailDSN sends an email message requesting delivery status
+// notifications. The request is dropped if dsn is nil or the server
+// doesn't support DSN.
+//
+// Messages over the server's announced size limit are rejected before
+// anything is uploaded. The envelope is pipelined and the body sent with
+// BDAT when the server supports it.
+func (c *Client) SendMailDSN(from string, to []string, msg []byte, dsn *DSN) error {
 	if c.client == nil {
 		return fmt.Errorf("not connected")
 	}
 
+	if dsn != nil && !c.SupportsDSN() {
+		c.log.Debug().Msg("DSN not supported by server, sending without delivery notifications")
+		dsn = nil
+	}
+
+	// Binary content goes through untouched with BINARYMIME; otherwise line
+	// endings are made canonical so SIZE is exact and BDAT sends valid lines
+	chunking := c.hasExtension("CHUNKING")
+	binary := chunking && c.hasExtension("BINARYMIME") && bytes.IndexByte(msg, 0) >= 0
+	if !binary {
+		msg = canonicalLineEndings(msg)
+	}
+
+	// Check the size limit up front instead of failing after the upload
+	if limit := c.MaxMessageSize(); limit > 0 && int64(len(msg)) > limit {
+		return &MessageTooLargeError{Size: int64(len(msg)), Limit: limit}
+	}
+
 	c.log.Debug().
 		Str("from", from).
 		Strs("to", to).
 		Int("size", len(msg)).
+		Bool("dsn", dsn != nil).
+		Bool("pipelining", c.hasExtension("PIPELINING")).
+		Bool("chunking", chunking).
 		Msg("Sending message")
 
-	// Set the sender
-	if err := c.client.Mail(from); err != nil {
-		return fmt.Errorf("failed to set sender: %w", err)
+	// Set the sender and recipients
+	if err := c.envelope(from, to, int64(len(msg)), binary, dsn); err != nil {
+		return err
+	}
+
+	// Send the message body
+	if chunking {
+		if err := c.bdat(msg); err != nil {
+			return fmt.Errorf("failed to write message: %w", err)
+		}
+	} else if err := c.data(msg); err != nil {
+		return err
+	}
+
+	c.log.Info().
+		Str("from", from).
+		Int("recipients", len(to)).
+		Msg("Message sent successfully")
+
+	return nil
+}
+
+// MaxMessageSize returns the message size limit the server announced
+// (EHLO SIZE, RFC 1870), 0 if there is none
+func (c *Client) MaxMessageSize() int64 {
+	if c.client == nil {
+		return 0
+	}
+	ok, param := c.client.Extension("SIZE")
+	if !ok {
+		return 0
 	}
+	limit, _ := strconv.ParseInt(strings.TrimSpace(param), 10, 64)
+	return limit
+}
 
-	// Set recipients
+// hasExtension reports whether the server announced an ESMTP extension
+func (c *Client) hasExtension(name string) bool {
+	ok, _ := c.client.Extension(name)
+	return ok
+}
+
+// envelope issues MAIL FROM and a RCPT TO per recipient. With PIPELINING
+// (RFC 2920) all commands are sent before the replies are read, saving a
+// round trip per recipient.
+func (c *Client) envelope(from string, to []string, size int64, binary bool, dsn *DSN) error {
+	if strings.ContainsAny(from, "\r\n") {
+		return fmt.Errorf("failed to set sender: invalid address")
+	}
 	for _, recipient := range to {
-		if err := c.client.Rcpt(recipient); err != nil {
-			return fmt.Errorf("failed to add recipient %s: %w", recipient, err)
+		if strings.ContainsAny(recipient, "\r\n") {
+			return fmt.Errorf("failed to add recipient %s: invalid address", recipient)
 		}
 	}
 
-	// Send the message body
+	params := ""
+	switch {
+	case binary:
+		params += " BODY=BINARYMIME"
+	case c.hasExtension("8BITMIME"):
+		params += " BODY=8BITMIME"
+	}
+	if c.hasExtension("SMTPUTF8") {
+		params += " SMTPUTF8"
+	}
+	if c.hasExtension("SIZE") {
+		params += " SIZE=" + strconv.FormatInt(size, 10)
+	}
+	if dsn != nil {
+		params += dsn.mailParams()
+	}
+
+	commands := []string{fmt.Sprintf("MAIL FROM:<%s>%s", from, params)}
+	for _, recipient := range to {
+		rcptParams := ""
+		if dsn != nil {
+			rcptParams = dsn.rcptParams(recipient)
+		}
+		commands = append(commands, fmt.Sprintf("RCPT TO:<%s>%s", recipient, rcptParams))
+	}
+
+	// Without pipelining each command waits for its reply
+	text := c.client.Text
+	pipelining := c.hasExtension("PIPELINING")
+	ids := make([]uint, 0, len(commands))
+	var firstErr error
+	for i, command := range commands {
+		id, err := text.Cmd("%s", command)
+		if err != nil {
+			return fmt.Errorf("failed to send envelope: %w", err)
+		}
+		ids = append(ids, id)
+		if !pipelining {
+			if err := c.envelopeReply(text, id, i, from, to, size); err != nil {
+				return err
+			}
+		}
+	}
+	if !pipelining {
+		return nil
+	}
+
+	// Read every reply (in order) even after a failure, so the session
+	// stays in sync for RSET
+	for i, id := range ids {
+		if err := c.envelopeReply(text, id, i, from, to, size); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// envelopeReply reads the reply to the i-th envelope command: MAIL FROM,
+// then the RCPT TO of each recipient
+func (c *Client) envelopeReply(text *textproto.Conn, id uint, i int, from string, to []string, size int64) error {
+	text.StartResponse(id)
+	defer text.EndResponse(id)
+
+	if i == 0 {
+		_, _, err := text.ReadResponse(250)
+		if err != nil {
+			// 552: the declared SIZE is over the limit
+			var tpErr *textproto.Error
+			if errors.As(err, &tpErr) && tpErr.Code == 552 {
+				return &MessageTooLargeError{Size: size, Limit: c.MaxMessageSize()}
+			}
+			return fmt.Errorf("failed to set sender: %w", err)
+		}
+		return nil
+	}
+
+	if _, _, err := text.ReadResponse(25); err != nil {
+		return fmt.Errorf("failed to add recipient %s: %w", to[i-1], err)
+	}
+	return nil
+}
+
+// data sends the message body with DATA
+func (c *Client) data(msg []byte) error {
 	w, err := c.client.Data()
 	if err != nil {
 		return fmt.Errorf("failed to start data transfer: %w", err)
@@ -265,13 +434,97 @@ func (c *Client) SendMail(from string, to []string, msg []byte) error {
 	if err := w.Close(); err != nil {
 		return fmt.Errorf("failed to complete message: %w", err)
 	}
+	return nil
+}
 
-	c.log.Info().
-		Str("from", from).
-		Int("recipients", len(to)).
-		Msg("Message sent successfully")
+// bdatChunkSize is the size of the chunks a message is sent in with BDAT
+const bdatChunkSize = 1024 * 1024
+
+// bdat sends the message body with BDAT (CHUNKING, RFC 3030). Unlike DATA
+// the body is sent as is: no dot-stuffing and no scanning for the end.
+func (c *Client) bdat(msg []byte) error {
+	text := c.client.Text
+	for offset := 0; ; offset += bdatChunkSize {
+		end := offset + bdatChunkSize
+		last := end >= len(msg)
+		if last {
+			end = len(msg)
+		}
+		chunk := msg[offset:end]
 
-	return nil
+		id := text.Next()
+		text.StartRequest(id)
+		command := fmt.Sprintf("BDAT %d", len(chunk))
+		if last {
+			command += " LAST"
+		}
+		err := text.PrintfLine("%s", command)
+		if err == nil {
+			_, err = text.W.Write(chunk)
+		}
+		if err == nil {
+			err = text.W.Flush()
+		}
+		text.EndRequest(id)
+		if err != nil {
+			return err
+		}
+
+		text.StartResponse(id)
+		_, _, err = text.ReadResponse(250)
+		text.EndResponse(id)
+		if err != nil {
+			return err
+		}
+
+		if last {
+			return nil
+		}
+	}
+}
+
+// canonicalLineEndings converts bare LF line endings to CRLF and makes the
+// message end with a line break, as DATA's dot-writer does
+func canonicalLineEndings(msg []byte) []byte {
+	bare := false
+	for i, b := range msg {
+		if b == '\n' && (i == 0 || msg[i-1] != '\r') {
+			bare = true
+			break
+		}
+	}
+	if !bare && bytes.HasSuffix(msg, []byte("\r\n")) {
+		return msg
+	}
+
+	out := make([]byte, 0, len(msg)+len(msg)/50+2)
+	for i, b := range msg {
+		if b == '\n' && (i == 0 || msg[i-1] != '\r') {
+			out = append(out, '\r')
+		}
+		out = append(out, b)
+	}
+	if !bytes.HasSuffix(out, []byte("\r\n")) {
+		out = append(out, '\r', '\n')
+	}
+	return out
+}
+
+// setDeadline bounds the commands of a pooled session, so a connection
+// that died while idle (e.g. over sleep) can't block. Zero clears it.
+func (c *Client) setDeadline(t time.Time) {
+	if c.conn != nil {
+		c.conn.SetDeadline(t)
+	}
+}
+
+// Noop checks that the session is still alive
+func (c *Client) Noop() error {
+	if c.client == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	return c.client.Noop()
 }
 
 // Reset resets the SMTP session, allowing a new message to be sent
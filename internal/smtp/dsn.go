@@ -0,0 +1,98 @@
+// Package smtp provides SMTP client functionality for Aerion
+package smtp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DSNNotify is a condition for which a delivery status notification is requested
+type DSNNotify string
+
+const (
+	DSNNotifySuccess DSNNotify = "SUCCESS"
+	DSNNotifyFailure DSNNotify = "FAILURE"
+	DSNNotifyDelay   DSNNotify = "DELAY"
+)
+
+// DSNReturn is how much of the original message a failure report returns
+type DSNReturn string
+
+const (
+	DSNReturnHeaders DSNReturn = "HDRS"
+	DSNReturnFull    DSNReturn = "FULL"
+)
+
+// DSN requests delivery status notifications for a message (RFC 3461)
+type DSN struct {
+	Notify     []DSNNotify // Empty leaves it to the server (usually failures and delays)
+	Return     DSNReturn   // Empty leaves it to the server
+	EnvelopeID string      // ENVID, returned in the reports to identify the message
+}
+
+// DSN returns the delivery status notifications to request for a message
+// built with ToRFC822: failures and delays always, successful delivery if
+// the sender asked for a delivery report. The Message-ID is the envelope ID
+// so reports can be matched to the sent message.
+func (m *ComposeMessage) DSN() *DSN {
+	notify := []DSNNotify{DSNNotifyFailure, DSNNotifyDelay}
+	if m.RequestDeliveryReport {
+		notify = append([]DSNNotify{DSNNotifySuccess}, notify...)
+	}
+	return &DSN{
+		Notify:     notify,
+		Return:     DSNReturnHeaders,
+		EnvelopeID: strings.Trim(m.MessageID, "<>"),
+	}
+}
+
+// SupportsDSN reports whether the server accepts delivery status
+// notification requests
+func (c *Client) SupportsDSN() bool {
+	if c.client == nil {
+		return false
+	}
+	ok, _ := c.client.Extension("DSN")
+	return ok
+}
+
+// mailParams returns the DSN parameters of the MAIL FROM command
+func (d *DSN) mailParams() string {
+	var params string
+	if d.Return != "" {
+		params += " RET=" + string(d.Return)
+	}
+	if d.EnvelopeID != "" {
+		params += " ENVID=" + encodeXText(d.EnvelopeID)
+	}
+	return params
+}
+
+// rcptParams returns the DSN parameters of the RCPT TO command for a
+// recipient. ORCPT makes reports name the recipient as it was given here.
+func (d *DSN) rcptParams(recipient string) string {
+	var params string
+	if len(d.Notify) > 0 {
+		notify := make([]string, len(d.Notify))
+		for i, n := range d.Notify {
+			notify[i] = string(n)
+		}
+		params += " NOTIFY=" + strings.Join(notify, ",")
+	}
+	return params + " ORCPT=rfc822;" + encodeXText(recipient)
+}
+
+// encodeXText encodes a value as RFC 3461 xtext: printable ASCII except "+"
+// and "=" as is, everything else as "+XX"
+func encodeXText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '!' || c > '~' || c == '+' || c == '=' {
+			fmt.Fprintf(&b, "+%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -1,7 +1,10 @@
 // Package smtp provides SMTP client functionality for Aerion
 package smtp
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrNotConnected indicates the client is not connected
@@ -25,3 +28,34 @@ var (
 	// ErrTimeout indicates a timeout occurred
 	ErrTimeout = errors.New("operation timed out")
 )
+
+// MessageTooLargeError is returned when a message is over the size limit the
+// server announced, before any of it is uploaded. Limit is 0 if the server
+// rejected the declared size without announcing a limit.
+type MessageTooLargeError struct {
+	Size  int64
+	Limit int64
+}
+
+func (e *MessageTooLargeError) Error() string {
+	if e.Limit <= 0 {
+		return fmt.Sprintf("message too large: the server does not accept %s", formatSize(e.Size))
+	}
+	return fmt.Sprintf("message too large: %s exceeds the server limit of %s", formatSize(e.Size), formatSize(e.Limit))
+}
+
+func (e *MessageTooLargeError) Unwrap() error {
+	return ErrMessageTooLarge
+}
+
+// formatSize formats a byte count for messages shown to the user
+func formatSize(size int64) string {
+	const mb = 1024 * 1024
+	if size < 1024 {
+		return fmt.Sprintf("%d bytes", size)
+	}
+	if size < mb {
+		return fmt.Sprintf("%d KB", (size+1023)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(size)/mb)
+}
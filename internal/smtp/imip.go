@@ -0,0 +1,63 @@
+// Package smtp provides SMTP client functionality
+package smtp
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BuildIMIP creates an RFC 6047 iMIP message carrying an iTIP object
+// (e.g. a meeting REPLY). The message has a plain text summary and the
+// iCalendar data as multipart/alternative parts; the iTIP method is repeated
+// on the text/calendar Content-Type as RFC 6047 requires.
+//
+// Returns the complete message as bytes ready to be sent via SMTP
+func BuildIMIP(from, to Address, subject, text, method string, ics []byte) ([]byte, error) {
+	if from.Address == "" || to.Address == "" {
+		return nil, fmt.Errorf("from and to addresses are required")
+	}
+	if method == "" {
+		return nil, fmt.Errorf("iTIP method is required")
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from.String())
+	writeHeader(&buf, "To", to.String())
+	writeHeader(&buf, "Subject", encodeSubject(subject))
+	writeHeader(&buf, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", fmt.Sprintf("<%s@aerion>", uuid.New().String()))
+	writeHeader(&buf, "MIME-Version", "1.0")
+	writeHeader(&buf, "User-Agent", "Aerion Email Client")
+
+	mpWriter := multipart.NewWriter(&buf)
+	writeHeader(&buf, "Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", mpWriter.Boundary()))
+	buf.WriteString("\r\n")
+
+	textHeader := textproto.MIMEHeader{}
+	textHeader.Set("Content-Type", "text/plain; charset=utf-8")
+	textHeader.Set("Content-Transfer-Encoding", "quoted-printable")
+	textPart, err := mpWriter.CreatePart(textHeader)
+	if err != nil {
+		return nil, err
+	}
+	writeQuotedPrintable(textPart, text)
+
+	calHeader := textproto.MIMEHeader{}
+	calHeader.Set("Content-Type", fmt.Sprintf("text/calendar; charset=utf-8; method=%s", method))
+	calHeader.Set("Content-Transfer-Encoding", "quoted-printable")
+	calPart, err := mpWriter.CreatePart(calHeader)
+	if err != nil {
+		return nil, err
+	}
+	writeQuotedPrintable(calPart, string(ics))
+
+	if err := mpWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
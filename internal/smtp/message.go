@@ -23,6 +23,14 @@ type Address struct {
 	Address string `json:"address"`
 }
 
+// GroupRecipient is a contact group a message is sent to as Bcc. The
+// backend expands it to the group's members before sending, so members
+// don't see each other's addresses.
+type GroupRecipient struct {
+	Source string `json:"source"` // "local", "carddav" or "google"
+	ID     string `json:"id"`
+}
+
 // String returns the RFC 5322 formatted address
 func (a Address) String() string {
 	if a.Name == "" {
@@ -49,6 +57,7 @@ type ComposeMessage struct {
 	To      []Address `json:"to"`
 	Cc      []Address `json:"cc"`
 	Bcc     []Address `json:"bcc"`
+	BccGroups []GroupRecipient `json:"bcc_groups,omitempty"` // Expanded into Bcc when sending
 	ReplyTo *Address  `json:"reply_to,omitempty"`
 	Subject string    `json:"subject"`
 
@@ -62,9 +71,12 @@ type ComposeMessage struct {
 	// Headers
 	InReplyTo  string   `json:"in_reply_to,omitempty"` // Message-ID of the message being replied to
 	References []string `json:"references,omitempty"`  // Thread references
+	Autocrypt  string   `json:"-"`                     // Pre-folded Autocrypt header value (set by the backend)
+	MessageID  string   `json:"-"`                     // Message-ID header, generated by ToRFC822 if empty
 
 	// Options
 	RequestReadReceipt bool `json:"request_read_receipt"`
+	RequestDeliveryReport bool `json:"request_delivery_report"` // Also report successful delivery (DSN)
 	SignMessage         bool `json:"sign_message"`    // S/MIME sign this message
 	EncryptMessage      bool `json:"encrypt_message"` // S/MIME encrypt this message
 	PGPSignMessage      bool `json:"pgp_sign_message"`    // PGP sign this message
@@ -91,11 +103,19 @@ func (m *ComposeMessage) ToRFC822() ([]byte, error) {
 	var buf bytes.Buffer
 
 	// Generate Message-ID
-	messageID := fmt.Sprintf("<%s@aerion>", uuid.New().String())
+	if m.MessageID == "" {
+		m.MessageID = fmt.Sprintf("<%s@aerion>", uuid.New().String())
+	}
+	messageID := m.MessageID
 
 	// Write headers
 	writeHeader(&buf, "From", m.From.String())
-	writeHeader(&buf, "To", formatAddresses(m.To))
+	if len(m.To) > 0 {
+		writeHeader(&buf, "To", formatAddresses(m.To))
+	} else {
+		// Bcc-only messages (e.g. sent to a group as Bcc) still need a To header
+		writeHeader(&buf, "To", "undisclosed-recipients:;")
+	}
 	if len(m.Cc) > 0 {
 		writeHeader(&buf, "Cc", formatAddresses(m.Cc))
 	}
@@ -122,6 +142,11 @@ func (m *ComposeMessage) ToRFC822() ([]byte, error) {
 		writeHeader(&buf, "Disposition-Notification-To", m.From.String())
 	}
 
+	// Autocrypt key advertisement
+	if m.Autocrypt != "" {
+		writeHeader(&buf, "Autocrypt", m.Autocrypt)
+	}
+
 	// Determine message structure
 	hasHTML := m.HTMLBody != ""
 	hasText := m.TextBody != ""
@@ -0,0 +1,194 @@
+// Package smtp provides SMTP client functionality for Aerion
+package smtp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/rs/zerolog"
+)
+
+// PoolConfig configures the SMTP session pool
+type PoolConfig struct {
+	// IdleTimeout is how long an authenticated session is kept open for the
+	// next message. Servers usually drop idle sessions after 5 minutes.
+	IdleTimeout time.Duration
+
+	// CommandTimeout limits the commands checking, resetting and closing
+	// idle sessions
+	CommandTimeout time.Duration
+}
+
+// DefaultPoolConfig returns sensible defaults for the pool
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		IdleTimeout:    2 * time.Minute,
+		CommandTimeout: 10 * time.Second,
+	}
+}
+
+// idleSession is an authenticated session waiting for the next message
+type idleSession struct {
+	accountID string
+	client    *Client
+	lastUsed  time.Time
+}
+
+// Pool keeps authenticated SMTP sessions open between messages, so that
+// messages sent in quick succession reuse one session instead of connecting,
+// negotiating TLS and logging in for each. A session is used by one sender
+// at a time; concurrent sends open their own.
+type Pool struct {
+	config  PoolConfig
+	idle    map[string]*idleSession // server and user -> session
+	connect func(config ClientConfig) (*Client, error)
+	mu      sync.Mutex
+	log     zerolog.Logger
+}
+
+// NewPool creates a new SMTP session pool. connect opens and authenticates
+// a session for a config.
+func NewPool(config PoolConfig, connect func(config ClientConfig) (*Client, error)) *Pool {
+	return &Pool{
+		config:  config,
+		idle:    make(map[string]*idleSession),
+		connect: connect,
+		log:     logging.WithComponent("smtp-pool"),
+	}
+}
+
+// sessionKey identifies the sessions that can be reused for a config
+func sessionKey(accountID string, config ClientConfig) string {
+	return fmt.Sprintf("%s|%s|%d|%s|%s|%s", accountID, config.Host, config.Port, config.Security, config.AuthType, config.Username)
+}
+
+// Get returns an authenticated session for a config, reusing an idle one
+// that is still alive. Give it back with Release when the message is sent.
+func (p *Pool) Get(accountID string, config ClientConfig) (*Client, error) {
+	key := sessionKey(accountID, config)
+
+	p.mu.Lock()
+	session := p.idle[key]
+	delete(p.idle, key)
+	p.mu.Unlock()
+
+	if session != nil {
+		if time.Since(session.lastUsed) < p.config.IdleTimeout && p.check(session.client) == nil {
+			p.log.Debug().Str("account", accountID).Str("host", config.Host).Msg("Reusing SMTP session")
+			return session.client, nil
+		}
+		p.log.Debug().Str("account", accountID).Str("host", config.Host).Msg("Idle SMTP session expired, reconnecting")
+		p.close(session.client)
+	}
+
+	return p.connect(config)
+}
+
+// Release returns a session after sending. It is kept for the next message
+// if it can be reset, and closed otherwise.
+func (p *Pool) Release(accountID string, client *Client) {
+	if client == nil {
+		return
+	}
+
+	client.setDeadline(time.Now().Add(p.config.CommandTimeout))
+	if err := client.Reset(); err != nil {
+		p.log.Debug().Err(err).Str("account", accountID).Msg("SMTP session can't be reused, closing")
+		client.Close()
+		return
+	}
+	client.setDeadline(time.Time{})
+
+	key := sessionKey(accountID, client.config)
+
+	p.mu.Lock()
+	previous := p.idle[key]
+	p.idle[key] = &idleSession{accountID: accountID, client: client, lastUsed: time.Now()}
+	p.mu.Unlock()
+
+	// Keep one idle session per server; a concurrent send's is closed
+	if previous != nil {
+		p.close(previous.client)
+	}
+}
+
+// check sends NOOP to an idle session before it is reused
+func (p *Pool) check(client *Client) error {
+	client.setDeadline(time.Now().Add(p.config.CommandTimeout))
+	if err := client.Noop(); err != nil {
+		return err
+	}
+	client.setDeadline(time.Time{})
+	return nil
+}
+
+// close quits an idle session without waiting long for a dead server
+func (p *Pool) close(client *Client) {
+	client.setDeadline(time.Now().Add(p.config.CommandTimeout))
+	client.Close()
+}
+
+// CloseAccount closes the idle sessions of an account, e.g. after its
+// server settings or credentials changed
+func (p *Pool) CloseAccount(accountID string) {
+	p.closeWhere(func(s *idleSession) bool {
+		return s.accountID == accountID
+	})
+}
+
+// CloseAll closes all idle sessions
+func (p *Pool) CloseAll() {
+	p.closeWhere(func(*idleSession) bool {
+		return true
+	})
+}
+
+// CleanupIdle closes sessions that have been idle too long
+func (p *Pool) CleanupIdle() {
+	now := time.Now()
+	p.closeWhere(func(s *idleSession) bool {
+		return now.Sub(s.lastUsed) > p.config.IdleTimeout
+	})
+}
+
+// closeWhere closes and removes the idle sessions matching a condition
+func (p *Pool) closeWhere(match func(s *idleSession) bool) {
+	p.mu.Lock()
+	var closing []*idleSession
+	for key, session := range p.idle {
+		if match(session) {
+			closing = append(closing, session)
+			delete(p.idle, key)
+		}
+	}
+	p.mu.Unlock()
+
+	// QUIT outside the lock, it waits for the server
+	for _, session := range closing {
+		p.close(session.client)
+	}
+
+	if len(closing) > 0 {
+		p.log.Debug().Int("closed", len(closing)).Msg("Closed idle SMTP sessions")
+	}
+}
+
+// StartCleanupRoutine starts a background goroutine that periodically closes idle sessions
+func (p *Pool) StartCleanupRoutine(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.CleanupIdle()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,114 @@
+package smtp
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// maxSplitParts bounds the number of messages a message is split into; the
+// part labels are measured at this width
+const maxSplitParts = 99
+
+// SplitMessage splits a message that is over a server's size limit into
+// messages of at most limit bytes, one per group of attachments. The first
+// part keeps the body and the inline attachments; the others carry the
+// remaining attachments with a short note, reply to the first part so they
+// thread with it, and are labeled "(n/total)" in the subject.
+//
+// Attachments are grouped in order. A message that already fits is returned
+// as is; a MessageTooLargeError is returned if the body or a single
+// attachment doesn't fit on its own.
+func SplitMessage(msg ComposeMessage, limit int64) ([]ComposeMessage, error) {
+	size, err := encodedSize(msg)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || size <= limit {
+		return []ComposeMessage{msg}, nil
+	}
+
+	var inline, files []Attachment
+	for _, att := range msg.Attachments {
+		if att.Inline {
+			inline = append(inline, att)
+		} else {
+			files = append(files, att)
+		}
+	}
+
+	if msg.MessageID == "" {
+		msg.MessageID = fmt.Sprintf("<%s@aerion>", uuid.New().String())
+	}
+
+	// Fill each part with as many attachments as fit, measuring the part as
+	// it will be sent
+	groups := [][]Attachment{inline}
+	for _, att := range files {
+		last := len(groups) - 1
+		candidate := append(append([]Attachment(nil), groups[last]...), att)
+		size, err := encodedSize(splitPart(msg, candidate, last, maxSplitParts))
+		if err != nil {
+			return nil, err
+		}
+		if size <= limit {
+			groups[last] = candidate
+			continue
+		}
+
+		// Start a new part; an attachment that doesn't fit on its own can't
+		// be sent to this server at all
+		size, err = encodedSize(splitPart(msg, []Attachment{att}, last+1, maxSplitParts))
+		if err != nil {
+			return nil, err
+		}
+		if size > limit {
+			return nil, &MessageTooLargeError{Size: size, Limit: limit}
+		}
+		if len(groups) == maxSplitParts {
+			return nil, fmt.Errorf("message would need more than %d parts", maxSplitParts)
+		}
+		groups = append(groups, []Attachment{att})
+	}
+
+	// The body with the inline attachments has to fit on its own
+	if size, err := encodedSize(splitPart(msg, groups[0], 0, maxSplitParts)); err != nil {
+		return nil, err
+	} else if size > limit {
+		return nil, &MessageTooLargeError{Size: size, Limit: limit}
+	}
+
+	parts := make([]ComposeMessage, len(groups))
+	for i, group := range groups {
+		parts[i] = splitPart(msg, group, i, len(groups))
+	}
+	return parts, nil
+}
+
+// splitPart builds part index (0-based) of a split message with the given
+// attachments
+func splitPart(msg ComposeMessage, attachments []Attachment, index, total int) ComposeMessage {
+	part := msg
+	part.Attachments = attachments
+	part.Subject = fmt.Sprintf("%s (%d/%d)", msg.Subject, index+1, total)
+	if index == 0 {
+		return part
+	}
+
+	part.MessageID = ""
+	part.TextBody = fmt.Sprintf("Attachments of \"%s\", part %d of %d.\r\n", msg.Subject, index+1, total)
+	part.HTMLBody = ""
+	part.InReplyTo = msg.MessageID
+	part.References = append(append([]string(nil), msg.References...), msg.MessageID)
+	part.RequestReadReceipt = false
+	return part
+}
+
+// encodedSize returns the size of a message as ToRFC822 encodes it
+func encodedSize(msg ComposeMessage) (int64, error) {
+	raw, err := msg.ToRFC822()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build message: %w", err)
+	}
+	return int64(len(raw)), nil
+}
@@ -0,0 +1,414 @@
+package spam
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/message"
+	"github.com/rs/zerolog"
+)
+
+// Classifier tuning constants (Robinson/Fisher combining as used by SpamBayes)
+const (
+	// unknownWordStrength is how strongly the prior is weighted against the
+	// observed counts for a token (Robinson's "s")
+	unknownWordStrength = 0.45
+
+	// unknownWordProb is the prior probability for a token we know little about
+	unknownWordProb = 0.5
+
+	// minProbStrength ignores tokens whose probability is close to neutral
+	minProbStrength = 0.1
+
+	// maxDiscriminators limits how many of the most extreme tokens are combined
+	maxDiscriminators = 150
+
+	// MinTrainedMessages is how many spam and ham messages must be trained
+	// before the classifier produces a score other than neutral
+	MinTrainedMessages = 5
+
+	// maxPending bounds the queue of new messages waiting for their bodies;
+	// the oldest entries are dropped beyond it
+	maxPending = 1000
+)
+
+// Stats holds training statistics
+type Stats struct {
+	SpamMessages int  `json:"spamMessages"`
+	HamMessages  int  `json:"hamMessages"`
+	Tokens       int  `json:"tokens"`
+	Ready        bool `json:"ready"` // Whether enough messages are trained to score
+}
+
+// Classifier is a token-based naive Bayes spam classifier backed by SQLite.
+// Tokens are stored in plaintext even with a master password set: mail is
+// scored during background sync while the database is locked, so they can't
+// be keyed from the vault's private key. The token table therefore reveals
+// words seen in trained and scored mail; clearing training data removes it.
+type Classifier struct {
+	db  *sql.DB
+	log zerolog.Logger
+
+	mu      sync.RWMutex
+	enabled bool
+}
+
+// NewClassifier creates a new spam classifier
+func NewClassifier(db *sql.DB) *Classifier {
+	return &Classifier{
+		db:  db,
+		log: logging.WithComponent("spam"),
+	}
+}
+
+// SetEnabled enables or disables scoring of new messages during sync
+func (c *Classifier) SetEnabled(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enabled = enabled
+}
+
+// Enabled returns whether scoring of new messages is enabled
+func (c *Classifier) Enabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.enabled
+}
+
+// messageKey returns the key used to remember which messages were trained.
+// The RFC Message-ID survives moves between folders (which change the local ID).
+func messageKey(m *message.Message) string {
+	if m.MessageID != "" {
+		return m.MessageID
+	}
+	return m.ID
+}
+
+// Train teaches the classifier that a message is spam (isSpam=true) or ham.
+// Retraining a message with the opposite class first removes its previous
+// contribution, so users changing their mind doesn't skew the counts.
+func (c *Classifier) Train(m *message.Message, isSpam bool) error {
+	key := messageKey(m)
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Check for an earlier training of the same message
+	var prevSpam bool
+	var prevTokensJSON string
+	err = tx.QueryRow("SELECT is_spam, tokens FROM spam_training WHERE message_key = ?", key).Scan(&prevSpam, &prevTokensJSON)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check previous training: %w", err)
+	}
+	if err == nil {
+		if prevSpam == isSpam {
+			return nil // Already trained as this class
+		}
+		var prevTokens []string
+		if err := json.Unmarshal([]byte(prevTokensJSON), &prevTokens); err == nil {
+			if err := updateTokenCounts(tx, prevTokens, prevSpam, -1); err != nil {
+				return err
+			}
+		}
+	}
+
+	tokens := Tokenize(m)
+	if err := updateTokenCounts(tx, tokens, isSpam, 1); err != nil {
+		return err
+	}
+
+	tokensJSON, _ := json.Marshal(tokens)
+	if _, err := tx.Exec(`
+		INSERT INTO spam_training (message_key, is_spam, tokens, trained_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(message_key) DO UPDATE SET is_spam = excluded.is_spam, tokens = excluded.tokens, trained_at = excluded.trained_at
+	`, key, isSpam, string(tokensJSON), time.Now()); err != nil {
+		return fmt.Errorf("failed to record training: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit training: %w", err)
+	}
+
+	c.log.Debug().
+		Str("messageKey", key).
+		Bool("spam", isSpam).
+		Int("tokens", len(tokens)).
+		Msg("Trained message")
+	return nil
+}
+
+// updateTokenCounts adds delta to the spam or ham count of each token
+func updateTokenCounts(tx *sql.Tx, tokens []string, isSpam bool, delta int) error {
+	column := "ham_count"
+	if isSpam {
+		column = "spam_count"
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO spam_tokens (token, %[1]s) VALUES (?, MAX(?, 0))
+		ON CONFLICT(token) DO UPDATE SET %[1]s = MAX(%[1]s + ?, 0)
+	`, column))
+	if err != nil {
+		return fmt.Errorf("failed to prepare token update: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, t := range tokens {
+		if _, err := stmt.Exec(t, delta, delta); err != nil {
+			return fmt.Errorf("failed to update token %q: %w", t, err)
+		}
+	}
+
+	// Drop tokens that no longer carry any counts
+	if delta < 0 {
+		if _, err := tx.Exec("DELETE FROM spam_tokens WHERE spam_count = 0 AND ham_count = 0"); err != nil {
+			return fmt.Errorf("failed to prune tokens: %w", err)
+		}
+	}
+	return nil
+}
+
+// Score returns the spam probability of a message in [0, 1].
+// Returns 0.5 (unsure) until enough spam and ham have been trained.
+func (c *Classifier) Score(m *message.Message) (float64, error) {
+	stats, err := c.Stats()
+	if err != nil {
+		return unknownWordProb, err
+	}
+	return c.score(m, stats)
+}
+
+// score computes the spam probability using already-loaded training statistics
+func (c *Classifier) score(m *message.Message, stats *Stats) (float64, error) {
+	if !stats.Ready {
+		return unknownWordProb, nil
+	}
+
+	tokens := Tokenize(m)
+	counts, err := c.tokenCounts(tokens)
+	if err != nil {
+		return unknownWordProb, err
+	}
+
+	return combine(tokenProbs(counts, stats.SpamMessages, stats.HamMessages)), nil
+}
+
+// ScoreAndStore scores messages and records the scores for later display.
+// Returns the scores keyed by message ID.
+func (c *Classifier) ScoreAndStore(msgs []*message.Message) (map[string]float64, error) {
+	scores := make(map[string]float64, len(msgs))
+	stats, err := c.Stats()
+	if err != nil {
+		return scores, err
+	}
+	for _, m := range msgs {
+		score, err := c.score(m, stats)
+		if err != nil {
+			return scores, err
+		}
+		scores[m.ID] = score
+		if _, err := c.db.Exec(`
+			INSERT INTO spam_scores (message_id, score, scored_at) VALUES (?, ?, ?)
+			ON CONFLICT(message_id) DO UPDATE SET score = excluded.score, scored_at = excluded.scored_at
+		`, m.ID, score, time.Now()); err != nil {
+			c.log.Warn().Err(err).Str("messageId", m.ID).Msg("Failed to store spam score")
+		}
+	}
+	return scores, nil
+}
+
+// GetScores returns the stored spam scores for the given message IDs
+func (c *Classifier) GetScores(messageIDs []string) (map[string]float64, error) {
+	scores := make(map[string]float64)
+	for _, id := range messageIDs {
+		var score float64
+		err := c.db.QueryRow("SELECT score FROM spam_scores WHERE message_id = ?", id).Scan(&score)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get spam score: %w", err)
+		}
+		scores[id] = score
+	}
+	return scores, nil
+}
+
+// Queue remembers new messages to score once their bodies are fetched.
+// The queue is kept in the database so it survives restarts.
+func (c *Classifier) Queue(messageIDs []string) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT OR IGNORE INTO spam_pending (message_id, queued_at) VALUES (?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare spam queue insert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, id := range messageIDs {
+		if _, err := stmt.Exec(id, now); err != nil {
+			return fmt.Errorf("failed to queue message for spam scoring: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM spam_pending WHERE rowid NOT IN (
+			SELECT rowid FROM spam_pending ORDER BY rowid DESC LIMIT ?
+		)
+	`, maxPending); err != nil {
+		return fmt.Errorf("failed to trim spam queue: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// TakeQueued removes the given messages from the scoring queue and returns
+// the ones that were queued
+func (c *Classifier) TakeQueued(messageIDs []string) ([]string, error) {
+	var queued []string
+	for _, id := range messageIDs {
+		res, err := c.db.Exec("DELETE FROM spam_pending WHERE message_id = ?", id)
+		if err != nil {
+			return queued, fmt.Errorf("failed to dequeue message: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			queued = append(queued, id)
+		}
+	}
+	return queued, nil
+}
+
+// Stats returns training statistics
+func (c *Classifier) Stats() (*Stats, error) {
+	stats := &Stats{}
+	err := c.db.QueryRow(`
+		SELECT COALESCE(SUM(is_spam), 0), COALESCE(SUM(1 - is_spam), 0) FROM spam_training
+	`).Scan(&stats.SpamMessages, &stats.HamMessages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get training stats: %w", err)
+	}
+	if err := c.db.QueryRow("SELECT COUNT(*) FROM spam_tokens").Scan(&stats.Tokens); err != nil {
+		return nil, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	stats.Ready = stats.SpamMessages >= MinTrainedMessages && stats.HamMessages >= MinTrainedMessages
+	return stats, nil
+}
+
+// Reset forgets all training data and scores
+func (c *Classifier) Reset() error {
+	for _, table := range []string{"spam_tokens", "spam_training", "spam_scores"} {
+		if _, err := c.db.Exec("DELETE FROM " + table); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	c.log.Info().Msg("Spam classifier reset")
+	return nil
+}
+
+// tokenCount holds the training counts of a single token
+type tokenCount struct {
+	spam int
+	ham  int
+}
+
+// tokenCounts loads the counts for the given tokens (unknown tokens are omitted)
+func (c *Classifier) tokenCounts(tokens []string) (map[string]tokenCount, error) {
+	counts := make(map[string]tokenCount, len(tokens))
+	stmt, err := c.db.Prepare("SELECT spam_count, ham_count FROM spam_tokens WHERE token = ?")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare token query: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, t := range tokens {
+		var tc tokenCount
+		err := stmt.QueryRow(t).Scan(&tc.spam, &tc.ham)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token counts: %w", err)
+		}
+		counts[t] = tc
+	}
+	return counts, nil
+}
+
+// tokenProbs computes Robinson's f(w) for each known token and returns the most
+// discriminating ones
+func tokenProbs(counts map[string]tokenCount, nspam, nham int) []float64 {
+	probs := make([]float64, 0, len(counts))
+	for _, tc := range counts {
+		spamRatio := float64(tc.spam) / float64(nspam)
+		hamRatio := float64(tc.ham) / float64(nham)
+		if spamRatio+hamRatio == 0 {
+			continue
+		}
+		p := spamRatio / (spamRatio + hamRatio)
+		n := float64(tc.spam + tc.ham)
+		f := (unknownWordStrength*unknownWordProb + n*p) / (unknownWordStrength + n)
+		if math.Abs(f-0.5) >= minProbStrength {
+			probs = append(probs, f)
+		}
+	}
+
+	// Keep the most extreme probabilities
+	sort.Slice(probs, func(i, j int) bool {
+		return math.Abs(probs[i]-0.5) > math.Abs(probs[j]-0.5)
+	})
+	if len(probs) > maxDiscriminators {
+		probs = probs[:maxDiscriminators]
+	}
+	return probs
+}
+
+// combine merges token probabilities using Fisher's method (chi-squared)
+func combine(probs []float64) float64 {
+	if len(probs) == 0 {
+		return unknownWordProb
+	}
+
+	var sumLnSpam, sumLnHam float64
+	for _, f := range probs {
+		// Clamp to avoid log(0)
+		f = math.Min(math.Max(f, 1e-6), 1-1e-6)
+		sumLnHam += math.Log(f)
+		sumLnSpam += math.Log(1 - f)
+	}
+
+	n := len(probs)
+	s := 1 - chi2Q(-2*sumLnSpam, 2*n)
+	h := 1 - chi2Q(-2*sumLnHam, 2*n)
+	return (s - h + 1) / 2
+}
+
+// chi2Q returns the probability that a chi-squared value with v (even) degrees
+// of freedom is >= x2
+func chi2Q(x2 float64, v int) float64 {
+	m := x2 / 2
+	sum := math.Exp(-m)
+	term := sum
+	for i := 1; i < v/2; i++ {
+		term *= m / float64(i)
+		sum += term
+	}
+	return math.Min(sum, 1)
+}
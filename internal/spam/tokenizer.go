@@ -0,0 +1,179 @@
+// Package spam provides a local naive Bayes spam classifier trained from user actions
+package spam
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hkdb/aerion/internal/message"
+)
+
+const (
+	// minTokenLen and maxTokenLen bound word tokens; very short words carry no
+	// signal and very long ones are usually encoded junk (base64, hashes)
+	minTokenLen = 3
+	maxTokenLen = 24
+
+	// maxBodyTokens caps how much body text contributes to a message's tokens
+	maxBodyTokens = 1000
+)
+
+// Tokenize extracts the set of classifier tokens from a message.
+// Header-derived tokens are prefixed (e.g. "subj:", "from:") so the same word
+// in the subject and the body is learned separately. Body tokens are only
+// produced when the body has been fetched; otherwise the snippet is used.
+func Tokenize(m *message.Message) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(t string) {
+		if t != "" && !seen[t] {
+			seen[t] = true
+			tokens = append(tokens, t)
+		}
+	}
+
+	// Subject words
+	for _, w := range words(m.Subject) {
+		add("subj:" + w)
+	}
+	if m.Subject != "" && strings.ToUpper(m.Subject) == m.Subject && strings.ToLower(m.Subject) != m.Subject {
+		add("subj:ALLCAPS")
+	}
+
+	// Sender
+	fromEmail := strings.ToLower(m.FromEmail)
+	add("from:" + fromEmail)
+	fromDomain := domainOf(fromEmail)
+	add("fromdomain:" + fromDomain)
+	for _, w := range words(m.FromName) {
+		add("fromname:" + w)
+	}
+
+	// Reply-To pointing to a different domain is a common spam trait
+	if m.ReplyTo != "" {
+		replyDomain := domainOf(strings.ToLower(m.ReplyTo))
+		add("replytodomain:" + replyDomain)
+		if replyDomain != fromDomain {
+			add("replyto:mismatch")
+		}
+	}
+
+	// Recipient count bucket
+	add("rcpts:" + recipientBucket(m.ToList, m.CcList))
+
+	if m.HasAttachments {
+		add("has:attachments")
+	}
+
+	// Body words (or snippet when the body isn't available yet)
+	body := m.BodyText
+	if body == "" {
+		body = m.Snippet
+	}
+	count := 0
+	for _, w := range words(body) {
+		if count >= maxBodyTokens {
+			break
+		}
+		if strings.HasPrefix(w, "http") {
+			continue
+		}
+		add(w)
+		count++
+	}
+	for _, d := range urlDomains(body) {
+		add("url:" + d)
+	}
+
+	return tokens
+}
+
+// words splits text into lowercased word tokens
+func words(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '$' && r != '\'' && r != '-'
+	})
+	result := make([]string, 0, len(fields))
+	for _, f := range fields {
+		f = strings.Trim(strings.ToLower(f), "'-")
+		if len(f) < minTokenLen {
+			continue
+		}
+		if len(f) > maxTokenLen {
+			first, _ := utf8.DecodeRuneInString(f)
+			result = append(result, "skip:"+string(first)+lengthBucket(len(f)))
+			continue
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+// urlDomains returns the host names of http(s) URLs found in text
+func urlDomains(text string) []string {
+	var domains []string
+	for _, field := range strings.Fields(text) {
+		idx := strings.Index(field, "://")
+		if idx < 0 || !strings.HasPrefix(strings.ToLower(field), "http") {
+			continue
+		}
+		host := field[idx+3:]
+		if end := strings.IndexAny(host, "/?#:>\"'"); end >= 0 {
+			host = host[:end]
+		}
+		host = strings.ToLower(host)
+		if host != "" {
+			domains = append(domains, host)
+		}
+	}
+	return domains
+}
+
+// domainOf returns the domain part of an email address
+func domainOf(email string) string {
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		return email[at+1:]
+	}
+	return ""
+}
+
+// recipientBucket groups the number of To/Cc recipients into coarse buckets
+func recipientBucket(toList, ccList string) string {
+	n := countAddresses(toList) + countAddresses(ccList)
+	switch {
+	case n == 0:
+		return "none"
+	case n == 1:
+		return "one"
+	case n <= 5:
+		return "few"
+	default:
+		return "many"
+	}
+}
+
+// countAddresses counts the entries in a JSON-encoded address list
+func countAddresses(list string) int {
+	if list == "" {
+		return 0
+	}
+	var addrs []json.RawMessage
+	if err := json.Unmarshal([]byte(list), &addrs); err != nil {
+		return 0
+	}
+	return len(addrs)
+}
+
+// lengthBucket groups the length of an over-long token into coarse buckets
+func lengthBucket(n int) string {
+	switch {
+	case n < 40:
+		return "20"
+	case n < 80:
+		return "40"
+	default:
+		return "80"
+	}
+}
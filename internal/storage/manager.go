@@ -0,0 +1,594 @@
+// Package storage reports local disk usage, keeps the mail cache within a
+// budget and runs database maintenance
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkdb/aerion/internal/blobstore"
+	"github.com/hkdb/aerion/internal/database"
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/rs/zerolog"
+)
+
+// evictionBatchSize is the number of messages considered per eviction transaction
+const evictionBatchSize = 200
+
+// cachedBodyBytes is the SQL expression for the cached body size of a message
+const cachedBodyBytes = "IFNULL(octet_length(m.body_text), 0) + IFNULL(octet_length(m.body_html), 0)"
+
+// evictableBodyBytes is the SQL expression for the part of the body eviction
+// removes. The plain-text body stays: the full-text index is an external
+// content index over it, so clearing it would drop the message from body search.
+const evictableBodyBytes = "IFNULL(octet_length(m.body_html), 0)"
+
+// rawBodyBytes is the SQL expression for the raw S/MIME/PGP bodies of a
+// message. They are needed to verify or decrypt on view and aren't restored
+// by an on-demand body fetch, so they are reported but never evicted.
+const rawBodyBytes = "IFNULL(octet_length(m.smime_raw_body), 0) + IFNULL(octet_length(m.pgp_raw_body), 0)"
+
+// Usage reports local disk usage
+type Usage struct {
+	DatabaseBytes   int64 `json:"databaseBytes"`   // Database file size
+	WALBytes        int64 `json:"walBytes"`        // Write-ahead log size
+	FreeBytes       int64 `json:"freeBytes"`       // Unused pages reclaimable by maintenance
+	FTSBytes        int64 `json:"ftsBytes"`        // Full-text search index
+	BodyBytes       int64 `json:"bodyBytes"`       // Cached message bodies
+	RawBodyBytes    int64 `json:"rawBodyBytes"`    // Raw S/MIME/PGP bodies kept for verification
+	AttachmentBytes int64 `json:"attachmentBytes"` // Stored inline attachments and downloaded files
+	CacheBytes      int64 `json:"cacheBytes"`      // HTML bodies and attachments counted against the cache budget
+
+	// Per-account and per-folder attachment figures count content shared by
+	// several messages once for each; the totals above count it once
+	Accounts []*AccountUsage `json:"accounts"`
+}
+
+// AccountUsage reports disk usage of an account
+type AccountUsage struct {
+	AccountID       string         `json:"accountId"`
+	Name            string         `json:"name"`
+	Email           string         `json:"email"`
+	Messages        int            `json:"messages"`
+	BodyBytes       int64          `json:"bodyBytes"`
+	RawBodyBytes    int64          `json:"rawBodyBytes"`
+	AttachmentBytes int64          `json:"attachmentBytes"`
+	Folders         []*FolderUsage `json:"folders"`
+}
+
+// FolderUsage reports disk usage of a folder
+type FolderUsage struct {
+	FolderID        string `json:"folderId"`
+	Name            string `json:"name"`
+	Path            string `json:"path"`
+	Messages        int    `json:"messages"`
+	BodyBytes       int64  `json:"bodyBytes"`
+	RawBodyBytes    int64  `json:"rawBodyBytes"`
+	AttachmentBytes int64  `json:"attachmentBytes"`
+}
+
+// EvictionResult reports what enforcing the cache budget removed
+type EvictionResult struct {
+	Messages   int   `json:"messages"`   // Messages whose body was evicted
+	FreedBytes int64 `json:"freedBytes"` // Body and attachment bytes removed
+}
+
+// MaintenanceResult reports what a maintenance run did
+type MaintenanceResult struct {
+	Eviction   *EvictionResult `json:"eviction,omitempty"`
+	FreedBytes int64           `json:"freedBytes"` // Reduction of database, WAL and attachment blob size
+	Duration   time.Duration   `json:"duration"`
+}
+
+// Manager measures and manages local storage
+type Manager struct {
+	db             *database.DB
+	blobs          *blobstore.Store
+	attachmentsDir string
+	log            zerolog.Logger
+
+	// mu serializes eviction and maintenance runs
+	mu sync.Mutex
+}
+
+// NewManager creates a storage manager. attachmentsDir is where downloaded
+// attachments are saved.
+func NewManager(db *database.DB, blobs *blobstore.Store, attachmentsDir string) *Manager {
+	return &Manager{
+		db:             db,
+		blobs:          blobs,
+		attachmentsDir: attachmentsDir,
+		log:            logging.WithComponent("storage"),
+	}
+}
+
+// ============================================================================
+// Usage
+// ============================================================================
+
+// Usage reports disk usage of the database, FTS index, cached bodies and
+// attachments, broken down per account and folder
+func (m *Manager) Usage() (*Usage, error) {
+	usage := &Usage{Accounts: []*AccountUsage{}}
+
+	dbBytes, freeBytes, err := m.databaseSize()
+	if err != nil {
+		return nil, err
+	}
+	usage.DatabaseBytes = dbBytes
+	usage.FreeBytes = freeBytes
+	usage.WALBytes = fileSize(m.db.Path() + "-wal")
+
+	// dbstat is optional in SQLite builds; the index size is informational
+	if err := m.db.QueryRow(
+		"SELECT COALESCE(SUM(pgsize), 0) FROM dbstat WHERE name LIKE 'messages_fts%'",
+	).Scan(&usage.FTSBytes); err != nil {
+		m.log.Debug().Err(err).Msg("Failed to measure FTS index size")
+	}
+
+	accounts := make(map[string]*AccountUsage)
+	rows, err := m.db.Query("SELECT id, name, email FROM accounts ORDER BY order_index, name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	for rows.Next() {
+		acc := &AccountUsage{Folders: []*FolderUsage{}}
+		if err := rows.Scan(&acc.AccountID, &acc.Name, &acc.Email); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		accounts[acc.AccountID] = acc
+		usage.Accounts = append(usage.Accounts, acc)
+	}
+	rows.Close()
+
+	folders := make(map[string]*FolderUsage)
+	folderAccount := make(map[string]string)
+	rows, err = m.db.Query("SELECT id, account_id, name, path FROM folders ORDER BY path")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+	for rows.Next() {
+		f := &FolderUsage{}
+		var accountID string
+		if err := rows.Scan(&f.FolderID, &accountID, &f.Name, &f.Path); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan folder: %w", err)
+		}
+		folders[f.FolderID] = f
+		folderAccount[f.FolderID] = accountID
+		if acc := accounts[accountID]; acc != nil {
+			acc.Folders = append(acc.Folders, f)
+		}
+	}
+	rows.Close()
+
+	// Message bodies per folder
+	rows, err = m.db.Query(fmt.Sprintf(`
+		SELECT m.folder_id, COUNT(*), COALESCE(SUM(%s), 0), COALESCE(SUM(%s), 0), COALESCE(SUM(%s), 0)
+		FROM messages m GROUP BY m.folder_id
+	`, cachedBodyBytes, rawBodyBytes, evictableBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure message bodies: %w", err)
+	}
+	var evictableBytes int64
+	for rows.Next() {
+		var folderID string
+		var count int
+		var bodyBytes, rawBytes, evictable int64
+		if err := rows.Scan(&folderID, &count, &bodyBytes, &rawBytes, &evictable); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan message usage: %w", err)
+		}
+		evictableBytes += evictable
+		if f := folders[folderID]; f != nil {
+			f.Messages = count
+			f.BodyBytes = bodyBytes
+			f.RawBodyBytes = rawBytes
+		}
+	}
+	rows.Close()
+
+	// Stored attachment content and downloaded files per folder
+	rows, err = m.db.Query(`
+		SELECT m.folder_id, IFNULL(octet_length(a.content), 0), IFNULL(b.size, 0), a.local_path
+		FROM attachments a JOIN messages m ON m.id = a.message_id
+		LEFT JOIN attachment_blobs b ON b.hash = a.content_hash
+		WHERE a.content IS NOT NULL OR a.content_hash IS NOT NULL OR a.local_path IS NOT NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure attachments: %w", err)
+	}
+	for rows.Next() {
+		var folderID string
+		var contentBytes, blobBytes int64
+		var localPath sql.NullString
+		if err := rows.Scan(&folderID, &contentBytes, &blobBytes, &localPath); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan attachment usage: %w", err)
+		}
+		downloaded := m.downloadedSize(localPath.String)
+		if f := folders[folderID]; f != nil {
+			f.AttachmentBytes += contentBytes + blobBytes + downloaded
+		}
+		usage.AttachmentBytes += contentBytes + downloaded
+	}
+	rows.Close()
+
+	blobBytes, err := m.blobs.Size()
+	if err != nil {
+		return nil, err
+	}
+	usage.AttachmentBytes += blobBytes
+
+	for folderID, f := range folders {
+		if acc := accounts[folderAccount[folderID]]; acc != nil {
+			acc.Messages += f.Messages
+			acc.BodyBytes += f.BodyBytes
+			acc.RawBodyBytes += f.RawBodyBytes
+			acc.AttachmentBytes += f.AttachmentBytes
+		}
+		usage.BodyBytes += f.BodyBytes
+		usage.RawBodyBytes += f.RawBodyBytes
+	}
+	usage.CacheBytes = evictableBytes + usage.AttachmentBytes
+
+	return usage, nil
+}
+
+// cacheBytes returns the size of evictable bodies and attachments
+func (m *Manager) cacheBytes() (int64, error) {
+	var bodyBytes, contentBytes int64
+	if err := m.db.QueryRow(fmt.Sprintf(
+		"SELECT COALESCE(SUM(%s), 0) FROM messages m", evictableBodyBytes,
+	)).Scan(&bodyBytes); err != nil {
+		return 0, fmt.Errorf("failed to measure message bodies: %w", err)
+	}
+	if err := m.db.QueryRow(
+		"SELECT COALESCE(SUM(octet_length(content)), 0) FROM attachments",
+	).Scan(&contentBytes); err != nil {
+		return 0, fmt.Errorf("failed to measure attachments: %w", err)
+	}
+
+	rows, err := m.db.Query("SELECT local_path FROM attachments WHERE local_path IS NOT NULL")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list downloaded attachments: %w", err)
+	}
+	defer rows.Close()
+	var fileBytes int64
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return 0, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		fileBytes += m.downloadedSize(path)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list downloaded attachments: %w", err)
+	}
+
+	blobBytes, err := m.blobs.Size()
+	if err != nil {
+		return 0, err
+	}
+	return bodyBytes + contentBytes + blobBytes + fileBytes, nil
+}
+
+// databaseSize returns the database file size and its reclaimable free space
+func (m *Manager) databaseSize() (int64, int64, error) {
+	var pageCount, pageSize, freePages int64
+	if err := m.db.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, 0, fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := m.db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, 0, fmt.Errorf("failed to read page size: %w", err)
+	}
+	if err := m.db.QueryRow("PRAGMA freelist_count").Scan(&freePages); err != nil {
+		return 0, 0, fmt.Errorf("failed to read free pages: %w", err)
+	}
+	return pageCount * pageSize, freePages * pageSize, nil
+}
+
+// downloadedSize returns the size of a downloaded attachment file. Files
+// outside the attachments directory belong to the user and aren't counted.
+func (m *Manager) downloadedSize(path string) int64 {
+	if !m.ownsFile(path) {
+		return 0
+	}
+	return fileSize(path)
+}
+
+// ownsFile reports whether path is inside the attachments directory
+func (m *Manager) ownsFile(path string) bool {
+	if path == "" || m.attachmentsDir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(m.attachmentsDir, path)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// fileSize returns the size of a file, or 0 if it doesn't exist
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// ============================================================================
+// Cache budget
+// ============================================================================
+
+// EnforceBudget evicts HTML bodies and attachment content of the oldest read
+// messages until cached content fits in budget bytes. Headers, flags,
+// snippets, plain-text bodies and attachment metadata are kept so the message
+// stays listed and searchable; opening it fetches the body again.
+// Starred, draft and encrypted messages are never evicted.
+func (m *Manager) EnforceBudget(ctx context.Context, budget int64) (*EvictionResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enforceBudget(ctx, budget)
+}
+
+// enforceBudget is EnforceBudget without locking
+func (m *Manager) enforceBudget(ctx context.Context, budget int64) (*EvictionResult, error) {
+	result := &EvictionResult{}
+	if budget <= 0 {
+		return result, nil
+	}
+
+	used, err := m.cacheBytes()
+	if err != nil {
+		return nil, err
+	}
+	excess := used - budget
+	if excess <= 0 {
+		return result, nil
+	}
+
+	m.log.Info().Int64("used", used).Int64("budget", budget).Msg("Mail cache over budget, evicting old bodies")
+
+	for result.FreedBytes < excess {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		evicted, freed, err := m.evictBatch(excess - result.FreedBytes)
+		if err != nil {
+			return result, err
+		}
+		if evicted == 0 {
+			break
+		}
+		result.Messages += evicted
+		result.FreedBytes += freed
+	}
+
+	// Remove attachment blobs only the evicted messages referenced
+	if _, _, err := m.blobs.CollectGarbage(); err != nil {
+		m.log.Warn().Err(err).Msg("Failed to remove unreferenced attachment blobs")
+	}
+
+	m.log.Info().Int("messages", result.Messages).Int64("freed", result.FreedBytes).Msg("Evicted cached bodies")
+	return result, nil
+}
+
+// evictBatch evicts the oldest candidates until want bytes are freed or the
+// batch is exhausted. Returns the number of messages evicted and bytes freed.
+func (m *Manager) evictBatch(want int64) (int, int64, error) {
+	rows, err := m.db.Query(fmt.Sprintf(`
+		SELECT m.id, %s + (
+			-- Blobs shared with other messages stay stored
+			SELECT IFNULL(SUM(IFNULL(octet_length(a.content), 0) + IFNULL(b.size, 0)), 0)
+			FROM attachments a LEFT JOIN attachment_blobs b ON b.hash = a.content_hash AND b.ref_count = 1
+			WHERE a.message_id = m.id
+		)
+		FROM messages m
+		WHERE m.body_fetched = 1 AND m.is_read = 1 AND m.is_starred = 0 AND m.is_draft = 0
+		  AND m.smime_encrypted = 0 AND m.pgp_encrypted = 0
+		  AND (m.body_html IS NOT NULL OR EXISTS (
+			SELECT 1 FROM attachments a
+			WHERE a.message_id = m.id AND (a.content IS NOT NULL OR a.content_hash IS NOT NULL OR a.local_path IS NOT NULL)
+		  ))
+		ORDER BY m.date ASC
+		LIMIT ?
+	`, evictableBodyBytes), evictionBatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find messages to evict: %w", err)
+	}
+
+	type candidate struct {
+		id    string
+		bytes int64
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.bytes); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan message: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to find messages to evict: %w", err)
+	}
+	if len(candidates) == 0 {
+		return 0, 0, nil
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var files []string
+	var freed int64
+	evicted := 0
+	for _, c := range candidates {
+		if freed >= want {
+			break
+		}
+
+		paths, err := m.downloadedFiles(tx, c.id)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		// Attachment metadata stays listed; the content is restored into the
+		// same rows when the body is fetched again
+		if _, err := tx.Exec("UPDATE attachments SET content = NULL, content_hash = NULL WHERE message_id = ?", c.id); err != nil {
+			return 0, 0, fmt.Errorf("failed to evict attachments: %w", err)
+		}
+		for _, path := range paths {
+			if _, err := tx.Exec("UPDATE attachments SET local_path = NULL WHERE message_id = ? AND local_path = ?", c.id, path); err != nil {
+				return 0, 0, fmt.Errorf("failed to evict attachments: %w", err)
+			}
+		}
+		if _, err := tx.Exec(`
+			UPDATE messages SET body_html = NULL, body_fetched = 0, body_evicted = 1
+			WHERE id = ?
+		`, c.id); err != nil {
+			return 0, 0, fmt.Errorf("failed to evict body: %w", err)
+		}
+
+		freed += c.bytes
+		for _, path := range paths {
+			freed += fileSize(path)
+		}
+		files = append(files, paths...)
+		evicted++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit eviction: %w", err)
+	}
+
+	for _, path := range files {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			m.log.Warn().Err(err).Str("path", path).Msg("Failed to remove downloaded attachment")
+		}
+	}
+	return evicted, freed, nil
+}
+
+// downloadedFiles returns the downloaded attachment files of a message that
+// live in the attachments directory
+func (m *Manager) downloadedFiles(tx *sql.Tx, messageID string) ([]string, error) {
+	rows, err := tx.Query("SELECT local_path FROM attachments WHERE message_id = ? AND local_path IS NOT NULL", messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list downloaded attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		if m.ownsFile(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths, rows.Err()
+}
+
+// ============================================================================
+// Maintenance
+// ============================================================================
+
+// Maintain enforces the cache budget (if any), removes unreferenced
+// attachment blobs, merges the FTS index segments and returns free pages to
+// the file system with an incremental vacuum. The first run converts the
+// database to incremental auto-vacuum, which needs one full VACUUM.
+func (m *Manager) Maintain(ctx context.Context, budget int64) (*MaintenanceResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	start := time.Now()
+	result := &MaintenanceResult{}
+
+	before, _, err := m.databaseSize()
+	if err != nil {
+		return nil, err
+	}
+	before += fileSize(m.db.Path() + "-wal")
+
+	if budget > 0 {
+		eviction, err := m.enforceBudget(ctx, budget)
+		if err != nil {
+			return nil, err
+		}
+		result.Eviction = eviction
+	}
+
+	_, blobBytes, err := m.blobs.CollectGarbage()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := m.db.ExecContext(ctx, "INSERT INTO messages_fts(messages_fts) VALUES('optimize')"); err != nil {
+		return nil, fmt.Errorf("failed to optimize search index: %w", err)
+	}
+
+	if err := m.vacuum(ctx); err != nil {
+		return nil, err
+	}
+
+	if _, err := m.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+
+	after, _, err := m.databaseSize()
+	if err != nil {
+		return nil, err
+	}
+	after += fileSize(m.db.Path() + "-wal")
+
+	result.FreedBytes = max(before-after, 0) + blobBytes
+	result.Duration = time.Since(start)
+	m.log.Info().Int64("freed", result.FreedBytes).Dur("duration", result.Duration).Msg("Storage maintenance complete")
+	return result, nil
+}
+
+// vacuum frees unused pages. auto_vacuum has to be changed and the full
+// VACUUM run on the same connection for the change to take effect.
+func (m *Manager) vacuum(ctx context.Context) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var mode int
+	if err := conn.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return fmt.Errorf("failed to read auto_vacuum mode: %w", err)
+	}
+
+	const incremental = 2
+	if mode != incremental {
+		m.log.Info().Msg("Converting database to incremental auto-vacuum")
+		if _, err := conn.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+			return fmt.Errorf("failed to set auto_vacuum mode: %w", err)
+		}
+		if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+			return fmt.Errorf("failed to vacuum database: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA incremental_vacuum"); err != nil {
+		return fmt.Errorf("failed to run incremental vacuum: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hkdb/aerion/internal/logging"
+	"github.com/hkdb/aerion/internal/settings"
+	"github.com/rs/zerolog"
+)
+
+// Scheduler enforces the cache budget and runs database maintenance in the background
+type Scheduler struct {
+	manager  *Manager
+	settings *settings.Store
+	log      zerolog.Logger
+
+	// Control
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	running       bool
+	runningMu     sync.Mutex
+	checkInterval time.Duration
+	trigger       chan struct{}
+}
+
+// NewScheduler creates a new storage scheduler
+func NewScheduler(manager *Manager, settingsStore *settings.Store) *Scheduler {
+	return &Scheduler{
+		manager:       manager,
+		settings:      settingsStore,
+		log:           logging.WithComponent("storage-scheduler"),
+		checkInterval: 15 * time.Minute, // Check the cache budget every 15 minutes
+		trigger:       make(chan struct{}, 1),
+	}
+}
+
+// Start starts the background storage scheduler
+func (s *Scheduler) Start(ctx context.Context) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	if s.running {
+		s.log.Warn().Msg("Scheduler already running")
+		return
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.run()
+
+	s.log.Info().Msg("Storage scheduler started")
+}
+
+// Stop stops the background storage scheduler, waiting for a running check to finish
+func (s *Scheduler) Stop() {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	s.running = false
+
+	s.log.Info().Msg("Storage scheduler stopped")
+}
+
+// CheckNow runs a check as soon as possible, e.g. after the configuration
+// changed (non-blocking)
+func (s *Scheduler) CheckNow() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// run is the main scheduler loop
+func (s *Scheduler) run() {
+	defer s.wg.Done()
+
+	// Initial check after startup (delayed so it doesn't compete with the first sync)
+	select {
+	case <-time.After(2 * time.Minute):
+		s.check()
+	case <-s.trigger:
+		s.check()
+	case <-s.ctx.Done():
+		return
+	}
+
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.check()
+		case <-s.trigger:
+			s.check()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// check runs maintenance if it is due, otherwise only enforces the cache budget
+func (s *Scheduler) check() {
+	config, err := s.settings.GetStorageConfig()
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to load storage config")
+		return
+	}
+
+	if s.isMaintenanceDue(config) {
+		if _, err := s.manager.Maintain(s.ctx, config.CacheBudgetBytes()); err != nil {
+			if s.ctx.Err() == nil {
+				s.log.Error().Err(err).Msg("Scheduled maintenance failed")
+			}
+			return
+		}
+		if err := s.settings.SetLastStorageMaintenance(time.Now()); err != nil {
+			s.log.Warn().Err(err).Msg("Failed to record maintenance time")
+		}
+		return
+	}
+
+	if config.CacheBudgetMB > 0 {
+		if _, err := s.manager.EnforceBudget(s.ctx, config.CacheBudgetBytes()); err != nil && s.ctx.Err() == nil {
+			s.log.Error().Err(err).Msg("Failed to enforce cache budget")
+		}
+	}
+}
+
+// isMaintenanceDue returns true if the maintenance interval has elapsed
+func (s *Scheduler) isMaintenanceDue(config *settings.StorageConfig) bool {
+	// Manual-only maintenance
+	if config.MaintenanceIntervalHours <= 0 {
+		return false
+	}
+
+	last, err := s.settings.GetLastStorageMaintenance()
+	if err != nil {
+		s.log.Warn().Err(err).Msg("Failed to load last maintenance time")
+	}
+	interval := time.Duration(config.MaintenanceIntervalHours) * time.Hour
+	return time.Since(last) >= interval
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"mime"
 	"mime/quotedprintable"
 	"regexp"
@@ -21,6 +22,7 @@ import (
 	"github.com/emersion/go-imap/v2/imapclient"
 	gomessage "github.com/emersion/go-message"
 	msgcharset "github.com/emersion/go-message/charset"
+	"github.com/hkdb/aerion/internal/account"
 	"github.com/hkdb/aerion/internal/email"
 	"github.com/hkdb/aerion/internal/folder"
 	imapPkg "github.com/hkdb/aerion/internal/imap"
@@ -28,6 +30,7 @@ import (
 	"github.com/hkdb/aerion/internal/message"
 	"github.com/hkdb/aerion/internal/pgp"
 	"github.com/hkdb/aerion/internal/smime"
+	"github.com/hkdb/aerion/internal/spam"
 	"github.com/rs/zerolog"
 	"golang.org/x/net/html/charset"
 	"golang.org/x/text/encoding/htmlindex"
@@ -67,12 +70,14 @@ type ParsedBody struct {
 	BodyText       string
 	BodyHTML       string
 	HasAttachments bool
-	Attachments    []*message.Attachment  // Extracted attachment metadata (content only for inline)
-	SMIMEResult    *smime.SignatureResult // S/MIME verification result (nil if not S/MIME)
-	SMIMERawBody   []byte                // Raw S/MIME body for on-view processing
-	SMIMEEncrypted bool                  // Whether the message is encrypted
-	PGPRawBody     []byte                // Raw PGP body for on-view processing
-	PGPEncrypted   bool                  // Whether the message is PGP encrypted
+	Attachments    []*message.Attachment   // Extracted attachment metadata (content only for inline)
+	SMIMEResult    *smime.SignatureResult  // S/MIME verification result (nil if not S/MIME)
+	SMIMERawBody   []byte                  // Raw S/MIME body for on-view processing
+	SMIMEEncrypted bool                    // Whether the message is encrypted
+	PGPRawBody     []byte                  // Raw PGP body for on-view processing
+	PGPEncrypted   bool                    // Whether the message is PGP encrypted
+	CalendarData   string                  // Raw iCalendar of a meeting invitation (text/calendar part)
+	DeliveryReport *message.DeliveryReport // Delivery status notification (message/delivery-status part)
 }
 
 // Retry limits for error recovery
@@ -93,6 +98,22 @@ type SyncProgress struct {
 // ProgressCallback is called with sync progress updates
 type ProgressCallback func(progress SyncProgress)
 
+// SpamCallback is called with the spam scores (keyed by message ID) of newly
+// synced inbox messages so the caller can move or tag probable spam
+type SpamCallback func(accountID, folderID string, scores map[string]float64)
+
+// MutedCallback is called with newly synced inbox messages (by ID) that
+// belong to a muted thread so the caller can mark them read or archive them
+type MutedCallback func(accountID, folderID string, thread *message.MutedThread, messageIDs []string)
+
+// QuotaCallback is called with the server quotas of an account after they
+// are refreshed during folder sync
+type QuotaCallback func(accountID string, quotas []*account.Quota)
+
+// DeliveryCallback is called with the delivery states of a sent message
+// (by Message-ID) that changed after a delivery report was synced
+type DeliveryCallback func(accountID, messageID string, statuses []*message.DeliveryStatus)
+
 // Engine handles synchronization between IMAP server and local storage
 type Engine struct {
 	pool             *imapPkg.Pool
@@ -105,6 +126,15 @@ type Engine struct {
 	progressCallback ProgressCallback
 	smimeVerifier    *smime.Verifier
 	pgpVerifier      *pgp.Verifier
+	autocrypt        *pgp.Autocrypt
+	spamClassifier   *spam.Classifier
+	spamCallback     SpamCallback
+	threadMutes      *message.ThreadMuteStore
+	mutedCallback    MutedCallback
+	quotaStore       *account.QuotaStore
+	quotaCallback    QuotaCallback
+	deliveryStore    *message.DeliveryStore
+	deliveryCallback DeliveryCallback
 }
 
 // NewEngine creates a new sync engine
@@ -135,6 +165,36 @@ func (e *Engine) SetPGPVerifier(verifier *pgp.Verifier) {
 	e.pgpVerifier = verifier
 }
 
+// SetAutocrypt sets the Autocrypt handler that learns peer keys from synced headers
+func (e *Engine) SetAutocrypt(autocrypt *pgp.Autocrypt) {
+	e.autocrypt = autocrypt
+}
+
+// SetSpamClassifier sets the classifier used to score new inbox messages during sync
+func (e *Engine) SetSpamClassifier(classifier *spam.Classifier, callback SpamCallback) {
+	e.spamClassifier = classifier
+	e.spamCallback = callback
+}
+
+// SetThreadMutes sets the muted thread store consulted for new inbox messages
+func (e *Engine) SetThreadMutes(store *message.ThreadMuteStore, callback MutedCallback) {
+	e.threadMutes = store
+	e.mutedCallback = callback
+}
+
+// SetQuotaStore sets the store for server quotas fetched during folder sync
+func (e *Engine) SetQuotaStore(store *account.QuotaStore, callback QuotaCallback) {
+	e.quotaStore = store
+	e.quotaCallback = callback
+}
+
+// SetDeliveryStore sets the store updated from delivery status notifications
+// found while fetching message bodies
+func (e *Engine) SetDeliveryStore(store *message.DeliveryStore, callback DeliveryCallback) {
+	e.deliveryStore = store
+	e.deliveryCallback = callback
+}
+
 // ParseRawBody parses raw message bytes into body text/HTML.
 // This is a convenience wrapper around ParseDecryptedBody for callers that only need text.
 func (e *Engine) ParseRawBody(raw []byte) (bodyHTML, bodyText string) {
@@ -304,11 +364,53 @@ func (e *Engine) SyncFolders(ctx context.Context, accountID string) error {
 		}
 	}
 
+	// Refresh server quotas while the connection is at hand
+	e.syncQuota(ctx, conn.Client(), accountID)
+
 	e.log.Info().Str("account", accountID).Int("folders", len(mailboxes)).Msg("Folder sync complete")
 
 	return nil
 }
 
+// syncQuota fetches and stores the server quotas of an account. Failures are
+// logged only; quotas are informational and must not fail the sync.
+func (e *Engine) syncQuota(ctx context.Context, client *imapPkg.Client, accountID string) {
+	if e.quotaStore == nil || !client.SupportsQuota() {
+		return
+	}
+
+	serverQuotas, err := client.GetQuota(ctx)
+	if err != nil {
+		e.log.Debug().Err(err).Str("account", accountID).Msg("Failed to get quota")
+		return
+	}
+
+	quotas := make([]*account.Quota, 0, len(serverQuotas))
+	for _, q := range serverQuotas {
+		quota := &account.Quota{
+			AccountID: accountID,
+			Root:      q.Root,
+			Resource:  q.Resource,
+			Usage:     q.Usage,
+			Limit:     q.Limit,
+		}
+		// STORAGE is reported in units of 1024 octets
+		if q.Resource == account.QuotaStorage {
+			quota.Usage *= 1024
+			quota.Limit *= 1024
+		}
+		quotas = append(quotas, quota)
+	}
+
+	if err := e.quotaStore.Replace(accountID, quotas); err != nil {
+		e.log.Warn().Err(err).Str("account", accountID).Msg("Failed to save quota")
+		return
+	}
+	if e.quotaCallback != nil {
+		e.quotaCallback(accountID, quotas)
+	}
+}
+
 // fetchFolderStatusParallel fetches STATUS for multiple folders concurrently
 func (e *Engine) fetchFolderStatusParallel(ctx context.Context, accountID string, mailboxes []*imapPkg.Mailbox) []folderStatusResult {
 	results := make([]folderStatusResult, len(mailboxes))
@@ -413,6 +515,7 @@ func (e *Engine) SyncMessages(ctx context.Context, accountID, folderID string, s
 			return fmt.Errorf("failed to delete messages: %w", err)
 		}
 		f.UIDValidity = mailbox.UIDValidity
+		f.LastSync = nil
 	}
 
 	// Calculate sync date cutoff
@@ -439,8 +542,19 @@ func (e *Engine) SyncMessages(ctx context.Context, accountID, folderID string, s
 		return fmt.Errorf("failed to get local UIDs: %w", err)
 	}
 	localUIDSet := make(map[uint32]bool)
+	var lastKnownUID uint32
 	for _, uid := range localUIDs {
 		localUIDSet[uid] = true
+		if uid > lastKnownUID {
+			lastKnownUID = uid
+		}
+	}
+
+	// Only mail that arrived since the last sync is scored for spam. The
+	// first sync of a folder fetches existing mail, which is left alone.
+	spamAfterUID := lastKnownUID
+	if f.LastSync == nil {
+		spamAfterUID = math.MaxUint32
 	}
 
 	// Check context before fetching UIDs
@@ -575,7 +689,7 @@ func (e *Engine) SyncMessages(ctx context.Context, accountID, folderID string, s
 			// Fetch headers for this batch with retry on connection error
 			batchRetries := 0
 			for {
-				err := e.fetchMessageHeaders(ctx, conn.Client().RawClient(), accountID, folderID, batch)
+				err := e.fetchMessageHeaders(ctx, conn.Client().RawClient(), accountID, folderID, batch, spamAfterUID)
 				if err == nil {
 					break // Success
 				}
@@ -707,6 +821,7 @@ func (e *Engine) syncMessageFlags(ctx context.Context, client *imapclient.Client
 
 		// Collect all flag updates for batch DB update
 		var flagUpdates []message.FlagUpdate
+		var mutedUIDs []uint32
 
 		for {
 			msg := fetchCmd.Next()
@@ -716,7 +831,7 @@ func (e *Engine) syncMessageFlags(ctx context.Context, client *imapclient.Client
 
 			// Collect the fetch data
 			var fetchedUID uint32
-			var isRead, isStarred, isAnswered, isForwarded, isDraft, isDeleted bool
+			var isRead, isStarred, isAnswered, isForwarded, isDraft, isDeleted, isMuted bool
 
 			for {
 				item := msg.Next()
@@ -743,10 +858,17 @@ func (e *Engine) syncMessageFlags(ctx context.Context, client *imapclient.Client
 						case "$Forwarded", "\\Forwarded":
 							isForwarded = true
 						}
+						if strings.EqualFold(string(flag), message.MutedKeyword) {
+							isMuted = true
+						}
 					}
 				}
 			}
 
+			if isMuted && fetchedUID > 0 {
+				mutedUIDs = append(mutedUIDs, fetchedUID)
+			}
+
 			// Collect flag update for batch processing
 			if fetchedUID > 0 {
 				flagUpdates = append(flagUpdates, message.FlagUpdate{
@@ -771,6 +893,8 @@ func (e *Engine) syncMessageFlags(ctx context.Context, client *imapclient.Client
 				e.log.Warn().Err(err).Int("count", len(flagUpdates)).Msg("Failed to batch update message flags")
 			}
 		}
+
+		e.importMutedKeyword(folderID, mutedUIDs)
 	}
 
 	e.log.Debug().Int("count", len(uids)).Msg("Synced message flags")
@@ -821,7 +945,8 @@ func (e *Engine) fetchUIDsSince(ctx context.Context, client *imapclient.Client,
 
 // fetchMessageHeaders fetches only headers (envelope, flags) for the given UIDs.
 // Messages are saved with BodyFetched=false, bodies to be fetched later.
-func (e *Engine) fetchMessageHeaders(ctx context.Context, client *imapclient.Client, accountID, folderID string, uids []uint32) error {
+// Messages above spamAfterUID are queued for spam scoring.
+func (e *Engine) fetchMessageHeaders(ctx context.Context, client *imapclient.Client, accountID, folderID string, uids []uint32, spamAfterUID uint32) error {
 	if len(uids) == 0 {
 		return nil
 	}
@@ -851,6 +976,8 @@ func (e *Engine) fetchMessageHeaders(ctx context.Context, client *imapclient.Cli
 
 	fetchCmd := client.Fetch(uidSet, fetchOptions)
 
+	learnAutocrypt := e.autocryptAppliesToFolder(folderID)
+
 	// Stream messages one at a time instead of blocking on Collect()
 	// This allows cancellation between messages and prevents indefinite blocking
 	var savedMessages []*message.Message
@@ -958,6 +1085,11 @@ func (e *Engine) fetchMessageHeaders(ctx context.Context, client *imapclient.Cli
 		if len(headerBytes) > 0 {
 			references = e.extractReferences(headerBytes)
 			m.ReadReceiptTo = e.extractDispositionNotificationTo(headerBytes)
+			m.DeliveredTo = e.extractDeliveredTo(headerBytes)
+
+			if learnAutocrypt {
+				e.autocrypt.ProcessIncoming(accountID, m.FromEmail, m.Date, headerBytes)
+			}
 
 			// Check for attachments from Content-Type header (heuristic)
 			headerStr := string(headerBytes)
@@ -1030,9 +1162,178 @@ func (e *Engine) fetchMessageHeaders(ctx context.Context, client *imapclient.Cli
 		}
 	}
 
+	e.classifyNewMessages(folderID, savedMessages, spamAfterUID)
+	e.applyThreadMutes(accountID, folderID, savedMessages)
+
 	return nil
 }
 
+// autocryptAppliesToFolder reports whether Autocrypt headers should be learned
+// from messages in a folder. Our own sent mail and spam are skipped.
+func (e *Engine) autocryptAppliesToFolder(folderID string) bool {
+	if e.autocrypt == nil {
+		return false
+	}
+	f, err := e.folderStore.Get(folderID)
+	if err != nil || f == nil {
+		return false
+	}
+	switch f.Type {
+	case folder.TypeSent, folder.TypeDrafts, folder.TypeSpam:
+		return false
+	}
+	return true
+}
+
+// classifyNewMessages queues newly synced inbox messages above spamAfterUID
+// for the spam classifier. Headers are synced without bodies, so the messages
+// are scored by classifyFetchedBodies once their bodies are stored; the
+// classifier is trained on full bodies and must score the same text.
+func (e *Engine) classifyNewMessages(folderID string, msgs []*message.Message, spamAfterUID uint32) {
+	if e.spamClassifier == nil || !e.spamClassifier.Enabled() || len(msgs) == 0 {
+		return
+	}
+
+	f, err := e.folderStore.Get(folderID)
+	if err != nil || f == nil || f.Type != folder.TypeInbox {
+		return
+	}
+
+	var ids []string
+	for _, m := range msgs {
+		if m.UID > spamAfterUID {
+			ids = append(ids, m.ID)
+		}
+	}
+	if err := e.spamClassifier.Queue(ids); err != nil {
+		e.log.Warn().Err(err).Str("folderId", folderID).Msg("Failed to queue new messages for spam scoring")
+	}
+}
+
+// classifyFetchedBodies scores the queued new messages among those whose
+// bodies were just stored and hands the scores to the spam callback
+func (e *Engine) classifyFetchedBodies(messageIDs []string) {
+	if e.spamClassifier == nil {
+		return
+	}
+
+	ids, err := e.spamClassifier.TakeQueued(messageIDs)
+	if err != nil {
+		e.log.Warn().Err(err).Msg("Failed to take messages from the spam queue")
+	}
+	if len(ids) == 0 || !e.spamClassifier.Enabled() {
+		return
+	}
+
+	msgs, err := e.messageStore.GetByIDs(ids)
+	if err != nil {
+		e.log.Warn().Err(err).Msg("Failed to load new messages for spam scoring")
+		return
+	}
+
+	type folderKey struct{ accountID, folderID string }
+	byFolder := make(map[folderKey][]*message.Message)
+	for _, m := range msgs {
+		key := folderKey{m.AccountID, m.FolderID}
+		byFolder[key] = append(byFolder[key], m)
+	}
+
+	for key, folderMsgs := range byFolder {
+		scores, err := e.spamClassifier.ScoreAndStore(folderMsgs)
+		if err != nil {
+			e.log.Warn().Err(err).Str("folderId", key.folderID).Msg("Failed to score new messages for spam")
+			continue
+		}
+		if e.spamCallback != nil && len(scores) > 0 {
+			e.spamCallback(key.accountID, key.folderID, scores)
+		}
+	}
+}
+
+// bodyUpdateIDs returns the message IDs of a batch of body updates
+func bodyUpdateIDs(updates []message.BodyUpdate) []string {
+	ids := make([]string, len(updates))
+	for i, u := range updates {
+		ids[i] = u.MessageID
+	}
+	return ids
+}
+
+// applyThreadMutes hands newly synced inbox messages of muted threads to the
+// muted callback
+func (e *Engine) applyThreadMutes(accountID, folderID string, msgs []*message.Message) {
+	if e.threadMutes == nil || e.mutedCallback == nil || len(msgs) == 0 {
+		return
+	}
+
+	f, err := e.folderStore.Get(folderID)
+	if err != nil || f == nil || f.Type != folder.TypeInbox {
+		return
+	}
+
+	threads := make(map[string]*message.MutedThread)
+	byThread := make(map[string][]string)
+	for _, m := range msgs {
+		if m.ThreadID == "" {
+			continue
+		}
+		if _, seen := threads[m.ThreadID]; !seen {
+			t, err := e.threadMutes.Get(accountID, m.ThreadID)
+			if err != nil {
+				e.log.Warn().Err(err).Str("thread", m.ThreadID).Msg("Failed to check muted thread")
+			}
+			threads[m.ThreadID] = t
+		}
+		if threads[m.ThreadID] != nil {
+			byThread[m.ThreadID] = append(byThread[m.ThreadID], m.ID)
+		}
+	}
+
+	for threadID, ids := range byThread {
+		e.mutedCallback(accountID, folderID, threads[threadID], ids)
+	}
+}
+
+// importMutedKeyword mutes the threads of messages tagged $Muted on the
+// server, e.g. by another device, that aren't muted here yet. They get the
+// options the user last chose when muting a thread of the account. Threads
+// unmuted here whose keyword removal hasn't reached the server (or only just
+// did) are skipped, so a stale keyword doesn't undo the unmute.
+func (e *Engine) importMutedKeyword(folderID string, uids []uint32) {
+	if e.threadMutes == nil || len(uids) == 0 {
+		return
+	}
+
+	f, err := e.folderStore.Get(folderID)
+	if err != nil || f == nil {
+		return
+	}
+
+	threads, err := e.messageStore.GetThreadSubjectsByUIDs(folderID, uids)
+	if err != nil {
+		e.log.Warn().Err(err).Msg("Failed to get threads of muted messages")
+		return
+	}
+
+	markRead, archive := e.threadMutes.LatestOptions(f.AccountID)
+	for threadID, subject := range threads {
+		if e.threadMutes.IsMuted(f.AccountID, threadID) || e.threadMutes.RecentlyUnmuted(f.AccountID, threadID) {
+			continue
+		}
+		if err := e.threadMutes.Mute(&message.MutedThread{
+			AccountID: f.AccountID,
+			ThreadID:  threadID,
+			Subject:   subject,
+			MarkRead:  markRead,
+			Archive:   archive,
+		}); err != nil {
+			e.log.Warn().Err(err).Str("thread", threadID).Msg("Failed to mute thread from server keyword")
+			continue
+		}
+		e.log.Info().Str("thread", threadID).Msg("Muted thread tagged $Muted on the server")
+	}
+}
+
 // parseMessageHeaderBuffer parses an IMAP FetchMessageBuffer containing only headers
 func (e *Engine) parseMessageHeaderBuffer(accountID, folderID string, buf *imapclient.FetchMessageBuffer) (*message.Message, error) {
 	m := &message.Message{
@@ -1081,6 +1382,7 @@ func (e *Engine) parseMessageHeaderBuffer(accountID, folderID string, buf *imapc
 		if len(section.Bytes) > 0 {
 			references = e.extractReferences(section.Bytes)
 			m.ReadReceiptTo = e.extractDispositionNotificationTo(section.Bytes)
+			m.DeliveredTo = e.extractDeliveredTo(section.Bytes)
 
 			// Check for attachments from Content-Type header
 			// This is a heuristic - we'll confirm when fetching body
@@ -1175,13 +1477,18 @@ func (e *Engine) FetchMessageBody(ctx context.Context, accountID, messageID stri
 	if err := e.messageStore.UpdateBody(messageID, result.BodyHTML, result.BodyText, result.Snippet); err != nil {
 		return nil, fmt.Errorf("failed to update message body: %w", err)
 	}
+	e.classifyFetchedBodies([]string{messageID})
+	if result.CalendarData != "" {
+		if err := e.messageStore.UpdateCalendarData(messageID, result.CalendarData); err != nil {
+			e.log.Debug().Err(err).Msg("Failed to save calendar data")
+		}
+	}
+	e.applyDeliveryReport(accountID, result.DeliveryReport)
 
-	// Store attachments if present
+	// Store attachments if present, reusing rows kept by storage eviction
 	if result.HasAttachments && e.attachmentStore != nil {
-		for _, att := range result.Attachments {
-			if err := e.attachmentStore.Create(att); err != nil {
-				e.log.Debug().Err(err).Str("filename", att.Filename).Msg("Failed to save attachment metadata")
-			}
+		if err := e.attachmentStore.SaveFetched(messageID, result.Attachments); err != nil {
+			e.log.Debug().Err(err).Str("messageID", messageID).Msg("Failed to save attachment metadata")
 		}
 	}
 
@@ -1226,13 +1533,17 @@ func (e *Engine) fetchMessageBodyWithConn(ctx context.Context, client *imapclien
 	if err := e.messageStore.UpdateBody(messageID, result.BodyHTML, result.BodyText, result.Snippet); err != nil {
 		return fmt.Errorf("failed to update message body: %w", err)
 	}
+	e.classifyFetchedBodies([]string{messageID})
+	if result.CalendarData != "" {
+		if err := e.messageStore.UpdateCalendarData(messageID, result.CalendarData); err != nil {
+			e.log.Debug().Err(err).Msg("Failed to save calendar data")
+		}
+	}
 
-	// Store attachments if present
+	// Store attachments if present, reusing rows kept by storage eviction
 	if result.HasAttachments && e.attachmentStore != nil {
-		for _, att := range result.Attachments {
-			if err := e.attachmentStore.Create(att); err != nil {
-				e.log.Debug().Err(err).Str("filename", att.Filename).Msg("Failed to save attachment metadata")
-			}
+		if err := e.attachmentStore.SaveFetched(messageID, result.Attachments); err != nil {
+			e.log.Debug().Err(err).Str("messageID", messageID).Msg("Failed to save attachment metadata")
 		}
 	}
 
@@ -1246,13 +1557,15 @@ type ProcessedBody struct {
 	BodyText       string
 	Snippet        string
 	HasAttachments bool
-	Attachments    []*message.Attachment  // Extracted during parsing (no re-parse needed)
-	RawBytes       []byte                 // For on-demand attachment content fetch
+	Attachments    []*message.Attachment   // Extracted during parsing (no re-parse needed)
+	RawBytes       []byte                  // For on-demand attachment content fetch
 	SMIMEResult    *smime.SignatureResult  // S/MIME verification result
-	SMIMERawBody   []byte                 // Raw S/MIME body for on-view processing
-	SMIMEEncrypted bool                   // Whether the message is encrypted
-	PGPRawBody     []byte                 // Raw PGP body for on-view processing
-	PGPEncrypted   bool                   // Whether the message is PGP encrypted
+	SMIMERawBody   []byte                  // Raw S/MIME body for on-view processing
+	SMIMEEncrypted bool                    // Whether the message is encrypted
+	PGPRawBody     []byte                  // Raw PGP body for on-view processing
+	PGPEncrypted   bool                    // Whether the message is PGP encrypted
+	CalendarData   string                  // Raw iCalendar of a meeting invitation
+	DeliveryReport *message.DeliveryReport // Delivery status notification
 }
 
 // fetchMessageBodiesBatch fetches bodies for multiple messages in a single IMAP command
@@ -1417,6 +1730,8 @@ func (e *Engine) fetchMessageBodiesBatch(ctx context.Context, client *imapclient
 			SMIMEEncrypted: parsed.SMIMEEncrypted,
 			PGPRawBody:     parsed.PGPRawBody,
 			PGPEncrypted:   parsed.PGPEncrypted,
+			CalendarData:   parsed.CalendarData,
+			DeliveryReport: parsed.DeliveryReport,
 		}
 	}
 
@@ -1599,6 +1914,7 @@ func (e *Engine) FetchBodiesInBackground(ctx context.Context, accountID, folderI
 					failed += result.fetchedCount
 				} else {
 					fetched += result.fetchedCount
+					e.classifyFetchedBodies(bodyUpdateIDs(result.bodyUpdates))
 					e.log.Debug().Int("fetched", fetched).Int("total", totalWithoutBody).Msg("DB update successful")
 				}
 			} else {
@@ -1822,10 +2138,14 @@ func (e *Engine) FetchBodiesInBackground(ctx context.Context, accountID, folderI
 					SMIMEEncrypted: pb.SMIMEEncrypted,
 					PGPRawBody:     pb.PGPRawBody,
 					PGPEncrypted:   pb.PGPEncrypted,
+					CalendarData:   pb.CalendarData,
 				}
 				// Don't cache S/MIME or PGP verification status — computed fresh on each view
 				bodyUpdates = append(bodyUpdates, bu)
 
+				// Bounces and delivery reports update the sent message
+				e.applyDeliveryReport(accountID, pb.DeliveryReport)
+
 				// Use pre-extracted attachments (no re-parsing!)
 				if len(pb.Attachments) > 0 {
 					allAttachments = append(allAttachments, pb.Attachments...)
@@ -1872,6 +2192,7 @@ func (e *Engine) FetchBodiesInBackground(ctx context.Context, accountID, folderI
 				failed += result.fetchedCount
 			} else {
 				fetched += result.fetchedCount
+				e.classifyFetchedBodies(bodyUpdateIDs(result.bodyUpdates))
 			}
 		}
 		if len(result.attachments) > 0 {
@@ -2148,6 +2469,7 @@ func (e *Engine) buildMessageFromStreamedData(accountID, folderID string, uid im
 	if len(rawBytes) > 0 {
 		references = e.extractReferences(rawBytes)
 		m.ReadReceiptTo = e.extractDispositionNotificationTo(rawBytes)
+		m.DeliveredTo = e.extractDeliveredTo(rawBytes)
 	}
 
 	// Store references as JSON array
@@ -2243,6 +2565,7 @@ func (e *Engine) parseMessageBuffer(accountID, folderID string, buf *imapclient.
 		if len(section.Bytes) > 0 {
 			references = e.extractReferences(section.Bytes)
 			m.ReadReceiptTo = e.extractDispositionNotificationTo(section.Bytes)
+			m.DeliveredTo = e.extractDeliveredTo(section.Bytes)
 			break
 		}
 	}
@@ -2599,7 +2922,7 @@ func (e *Engine) parseMessageBodyInternal(raw []byte, messageID string) *ParsedB
 
 		// Signed-only: still parse body for FTS, but don't cache verification status
 		if e.smimeVerifier != nil {
-			_, innerBody := e.smimeVerifier.VerifyAndUnwrap(raw)
+			innerBody := e.smimeVerifier.Unwrap(raw)
 			// Use the unwrapped inner body for parsing (not the S/MIME wrapper)
 			if innerBody != nil {
 				raw = innerBody
@@ -2693,6 +3016,20 @@ func (e *Engine) parseMultipartBody(mr gomessage.MultipartReader, result *Parsed
 			Str("charset", params["charset"]).
 			Msg("Processing multipart part")
 
+		// Meeting invitations: keep the iCalendar data, whether it's sent as an
+		// alternative body part or as an .ics attachment
+		if isCalendarContentType(contentType) {
+			part = e.captureCalendarPart(part, params, result)
+		}
+
+		// Delivery status notifications: the report, then the returned
+		// original identifying the sent message
+		if isDeliveryStatusContentType(contentType) {
+			part = e.captureDeliveryStatusPart(part, result)
+		} else if result.DeliveryReport != nil && isReturnedMessageContentType(contentType) {
+			part = e.captureReturnedMessagePart(part, result)
+		}
+
 		// Handle file attachments
 		if disposition == "attachment" {
 			result.HasAttachments = true
@@ -2797,11 +3134,115 @@ func (e *Engine) parseSinglePartBody(entity *gomessage.Entity, result *ParsedBod
 	switch contentType {
 	case "text/html":
 		result.BodyHTML = decodedContent
+	case "text/calendar":
+		result.CalendarData = decodedContent
 	default:
 		result.BodyText = decodedContent
 	}
 }
 
+// isCalendarContentType reports whether a MIME type carries iCalendar data
+func isCalendarContentType(contentType string) bool {
+	return contentType == "text/calendar" || contentType == "application/ics"
+}
+
+// captureCalendarPart stores the first iCalendar part of a message as its
+// calendar data. Reading consumes the part body, so a copy of the part with
+// the buffered body is returned for the caller to keep processing (e.g. as an
+// attachment).
+func (e *Engine) captureCalendarPart(part *gomessage.Entity, params map[string]string, result *ParsedBody) *gomessage.Entity {
+	body, err := io.ReadAll(io.LimitReader(part.Body, maxPartSize))
+	if err != nil && len(body) == 0 {
+		e.log.Debug().Err(err).Msg("Failed to read calendar part")
+		return part
+	}
+
+	if result.CalendarData == "" {
+		result.CalendarData = decodeCharset(body, params["charset"])
+	}
+
+	copied := *part
+	copied.Body = bytes.NewReader(body)
+	return &copied
+}
+
+// isDeliveryStatusContentType reports whether a MIME type carries a delivery
+// status notification (RFC 3464, RFC 6533)
+func isDeliveryStatusContentType(contentType string) bool {
+	return contentType == "message/delivery-status" || contentType == "message/global-delivery-status"
+}
+
+// isReturnedMessageContentType reports whether a MIME type carries the
+// original message (or its headers) returned with a delivery report
+func isReturnedMessageContentType(contentType string) bool {
+	switch contentType {
+	case "text/rfc822-headers", "message/rfc822-headers", "message/global-headers", "message/rfc822", "message/global":
+		return true
+	}
+	return false
+}
+
+// captureDeliveryStatusPart parses the first delivery status part of a
+// message. Like captureCalendarPart, it returns a copy of the part with the
+// buffered body.
+func (e *Engine) captureDeliveryStatusPart(part *gomessage.Entity, result *ParsedBody) *gomessage.Entity {
+	body, err := io.ReadAll(io.LimitReader(part.Body, maxPartSize))
+	if err != nil && len(body) == 0 {
+		e.log.Debug().Err(err).Msg("Failed to read delivery status part")
+		return part
+	}
+
+	if result.DeliveryReport == nil {
+		report, err := message.ParseDeliveryStatus(body)
+		if err != nil {
+			e.log.Debug().Err(err).Msg("Failed to parse delivery status")
+		} else {
+			result.DeliveryReport = report
+		}
+	}
+
+	copied := *part
+	copied.Body = bytes.NewReader(body)
+	return &copied
+}
+
+// captureReturnedMessagePart reads the Message-ID of the original returned
+// with a delivery report, for reports without an envelope ID
+func (e *Engine) captureReturnedMessagePart(part *gomessage.Entity, result *ParsedBody) *gomessage.Entity {
+	body, err := io.ReadAll(io.LimitReader(part.Body, maxPartSize))
+	if err != nil && len(body) == 0 {
+		e.log.Debug().Err(err).Msg("Failed to read returned message part")
+		return part
+	}
+
+	if result.DeliveryReport.OriginalMessageID == "" {
+		if original, err := gomessage.Read(bytes.NewReader(body)); err == nil || original != nil {
+			result.DeliveryReport.OriginalMessageID = message.NormalizeMessageID(original.Header.Get("Message-Id"))
+		}
+	}
+
+	copied := *part
+	copied.Body = bytes.NewReader(body)
+	return &copied
+}
+
+// applyDeliveryReport updates the delivery states of the sent message a
+// delivery report is about
+func (e *Engine) applyDeliveryReport(accountID string, report *message.DeliveryReport) {
+	if e.deliveryStore == nil || report == nil {
+		return
+	}
+
+	statuses, err := e.deliveryStore.ApplyReport(accountID, report)
+	if err != nil {
+		e.log.Debug().Err(err).Msg("Failed to apply delivery report")
+		return
+	}
+	if len(statuses) > 0 && e.deliveryCallback != nil {
+		e.deliveryCallback(accountID, report.SentMessageID(), statuses)
+	}
+}
+
 // decodeMIMEWord decodes RFC 2047 encoded words (e.g., =?UTF-8?B?5Lit5paH?=)
 // used for non-ASCII filenames and headers
 func decodeMIMEWord(s string) string {
@@ -3159,6 +3600,39 @@ func (e *Engine) extractDispositionNotificationTo(raw []byte) string {
 	return strings.TrimSpace(dntHeader)
 }
 
+// extractDeliveredTo extracts the addresses from Delivered-To and X-Original-To
+// headers as a JSON array. They name the alias a message was delivered to when
+// it isn't in To or Cc (e.g. Bcc or mailing lists).
+func (e *Engine) extractDeliveredTo(raw []byte) string {
+	reader := bytes.NewReader(raw)
+
+	entity, err := gomessage.Read(reader)
+	if err != nil {
+		return ""
+	}
+
+	var addresses []string
+	seen := make(map[string]bool)
+	for _, key := range []string{"Delivered-To", "X-Original-To"} {
+		for _, value := range entity.Header.Values(key) {
+			for _, addr := range strings.Split(value, ",") {
+				addr = strings.ToLower(strings.Trim(strings.TrimSpace(addr), "<>"))
+				if addr == "" || !strings.Contains(addr, "@") || seen[addr] {
+					continue
+				}
+				seen[addr] = true
+				addresses = append(addresses, addr)
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return ""
+	}
+
+	data, _ := json.Marshal(addresses)
+	return string(data)
+}
+
 // computeThreadID determines the thread ID for a message
 func (e *Engine) computeThreadID(accountID string, m *message.Message) string {
 	// Parse references from JSON
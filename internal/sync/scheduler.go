@@ -20,6 +20,10 @@ type NewMailInfo struct {
 	FromName    string `json:"fromName"`
 	FromEmail   string `json:"fromEmail"`
 	Count       int    `json:"count"` // Number of new messages
+
+	// Since is when the sync that found the messages started; the new
+	// messages are the ones stored since then
+	Since time.Time `json:"since"`
 }
 
 // NewMailCallback is called when new mail arrives
@@ -264,6 +268,7 @@ func (s *Scheduler) syncAccountInbox(acc *account.Account) {
 
 	// Get current message count before sync
 	previousCount := inbox.TotalCount
+	syncStart := time.Now()
 
 	// Sync messages (use account's sync period setting)
 	if err := s.engine.SyncMessages(ctx, acc.ID, inbox.ID, acc.SyncPeriodDays); err != nil {
@@ -309,6 +314,7 @@ func (s *Scheduler) syncAccountInbox(acc *account.Account) {
 				AccountName: acc.Name,
 				FolderID:    inbox.ID,
 				Count:       newCount,
+				Since:       syncStart,
 			})
 		}
 	}
@@ -416,6 +422,7 @@ func (s *Scheduler) SyncAccountInboxBlocking(accountID string) (*NewMailInfo, er
 
 	// Get current message count before sync
 	previousCount := inbox.TotalCount
+	syncStart := time.Now()
 
 	// Sync messages (use account's sync period setting)
 	if err := s.engine.SyncMessages(ctx, acc.ID, inbox.ID, acc.SyncPeriodDays); err != nil {
@@ -440,6 +447,7 @@ func (s *Scheduler) SyncAccountInboxBlocking(accountID string) (*NewMailInfo, er
 			AccountName: acc.Name,
 			FolderID:    inbox.ID,
 			Count:       newCount,
+			Since:       syncStart,
 		}, nil
 	}
 
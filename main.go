@@ -3,6 +3,7 @@ package main
 import (
 	"embed"
 	"flag"
+	"fmt"
 	"io/fs"
 	"net/http"
 	"net/url"
@@ -10,6 +11,8 @@ import (
 	"strings"
 
 	"github.com/hkdb/aerion/app"
+	"github.com/hkdb/aerion/internal/cli"
+	"github.com/hkdb/aerion/internal/platform"
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
@@ -29,6 +32,8 @@ var (
 	messageID   = flag.String("message-id", "", "Original message ID for reply/forward")
 	draftID     = flag.String("draft-id", "", "Draft ID to resume editing")
 	dbusNotify  = flag.Bool("dbus-notify", false, "Use direct D-Bus notifications instead of portal (Linux only)")
+	profileName = flag.String("profile", "", "Profile to use; each profile has its own accounts, settings and data")
+	dataDir     = flag.String("data-dir", "", "Keep all config, data and cache in this directory (portable mode)")
 )
 
 // DebugMode returns whether debug logging is enabled
@@ -109,9 +114,20 @@ func parseMailtoURL(rawURL string) *app.MailtoData {
 func main() {
 	flag.Parse()
 
+	// Select the profile before anything resolves data paths
+	if err := platform.SelectProfile(*profileName, *dataDir); err != nil {
+		fmt.Fprintf(os.Stderr, "aerion: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Command-line interface: forward the command to the running instance
+	args := flag.Args()
+	if len(args) > 0 && cli.IsCommand(args[0]) {
+		os.Exit(cli.Run(args))
+	}
+
 	// Check for mailto: URL in non-flag arguments
 	var mailtoData *app.MailtoData
-	args := flag.Args()
 	for _, arg := range args {
 		if strings.HasPrefix(strings.ToLower(arg), "mailto:") {
 			mailtoData = parseMailtoURL(arg)
@@ -141,9 +157,15 @@ func runMainMode(mailtoData *app.MailtoData) {
 	// We need ComposerApp bindings for the detached composer window.
 	dummyComposerApp := app.NewComposerApp(app.ComposerConfig{}, DebugMode)
 
+	// Tell profiles apart when several are open
+	title := "Aerion"
+	if profile := platform.ActiveProfile(); profile != platform.DefaultProfile {
+		title += " (" + profile + ")"
+	}
+
 	// Create application with options
 	err := wails.Run(&options.App{
-		Title:       "Aerion",
+		Title:       title,
 		Width:       1280,
 		Height:      800,
 		MinWidth:    800,